@@ -0,0 +1,595 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/encfs"
+)
+
+// Backups are stored in remote storage under a locator, which is the prefix of
+// the names of the backups' objects:
+//
+//	<locator>/BACKUPS                   the IDs of the completed backups
+//	<locator>/backups/<id>/BACKUP       the backup manifest
+//	<locator>/backups/<id>/<filename>   the MANIFEST, OPTIONS, WALs and markers
+//	<locator>/objects/<filename>        sstables and blob files
+//
+// The filenames are relative to the DB directory and use forward slashes, as
+// the files of keyspaces (see Options.Keyspaces) are stored in subdirectories
+// of the DB directory.
+//
+// Sstables and blob files are never modified once written, and their file
+// numbers are never reused by a DB, so each is uploaded once and shared by all
+// the backups that contain it. A backup manifest lists every file of the backup
+// and the object holding it, which is used to reference count the shared
+// objects when a backup is deleted.
+//
+// If the DB's files are encrypted at rest (see encfs.FS), the encrypted files
+// are uploaded as they are stored, without being decrypted, and the backup
+// manifest records the ID of the key each file is encrypted with.
+const (
+	backupIndexObject    = "BACKUPS"
+	backupManifestObject = "BACKUP"
+	backupsPrefix        = "backups"
+	backupObjectsPrefix  = "objects"
+
+	backupDownloadBufferSize = 1 << 20 // 1 MB
+)
+
+// BackupInfo describes a backup created by DB.Backup.
+type BackupInfo struct {
+	// ID identifies the backup among the backups with the same locator. IDs
+	// are assigned in increasing order.
+	ID uint64
+	// CreateTime is the time at which the backup was created.
+	CreateTime time.Time
+	// Size is the total size of the backup's files, including the files
+	// shared with other backups.
+	Size int64
+	// UploadedSize is the size of the files uploaded when the backup was
+	// created. It excludes the files that were shared with earlier backups.
+	UploadedSize int64
+}
+
+// backupFile is a file of a backup.
+type backupFile struct {
+	// object is the name of the object holding the file, relative to the
+	// locator.
+	object string
+	// name is the name of the file within the DB directory.
+	name string
+	size int64
+	// keyID is the ID of the key the file is encrypted with, or empty if the
+	// file is not encrypted.
+	keyID string
+}
+
+// backupManifest describes a backup and the files it contains.
+type backupManifest struct {
+	BackupInfo
+	files []backupFile
+}
+
+// Backup uploads a backup of the DB to the provided storage, under the
+// provided locator. Only the sstables and blob files that are not already
+// contained in a previous backup with the same locator are uploaded, along
+// with the MANIFEST, OPTIONS and the WALs that have not been flushed. The
+// backup may be restored with Restore.
+//
+// The backup is created from a checkpoint of the DB (see DB.Checkpoint),
+// which is constructed in a temporary directory within the DB's directory and
+// removed once the backup has been uploaded. Writes committed before Backup is
+// called are contained in the backup.
+//
+// Backup, ListBackups and DeleteBackup must not be called concurrently for
+// the same storage and locator.
+func (d *DB) Backup(
+	ctx context.Context, storage remote.Storage, locator string,
+) (_ BackupInfo, retErr error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return BackupInfo{}, ErrReadOnly
+	}
+	backups, err := readBackups(ctx, storage, locator)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	m := backupManifest{BackupInfo: BackupInfo{ID: 1, CreateTime: d.timeNow().UTC().Round(0)}}
+	sharedSizes := make(map[string]int64)
+	for _, b := range backups {
+		m.ID = max(m.ID, b.ID+1)
+		for _, f := range b.files {
+			sharedSizes[f.object] = f.size
+		}
+	}
+
+	d.mu.Lock()
+	jobID := d.newJobIDLocked()
+	d.mu.Unlock()
+	fs := d.opts.FS
+	tmpDir := fs.PathJoin(d.dirname, fmt.Sprintf("backup-%d.tmp", jobID))
+	// A directory may have been left behind by a backup that was interrupted
+	// by a crash.
+	if err := fs.RemoveAll(tmpDir); err != nil {
+		return BackupInfo{}, err
+	}
+	if err := d.Checkpoint(tmpDir, WithFlushedWAL()); err != nil {
+		return BackupInfo{}, err
+	}
+	defer func() { _ = fs.RemoveAll(tmpDir) }()
+	names, err := listBackupFiles(fs, tmpDir, "")
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	efs := encfs.Find(fs)
+
+	// If the backup fails, delete the objects it uploaded.
+	var uploaded []string
+	defer func() {
+		if retErr != nil {
+			for _, obj := range uploaded {
+				_ = storage.Delete(backupObjectName(locator, obj))
+			}
+		}
+	}()
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return BackupInfo{}, err
+		}
+		path := backupFilePath(fs, tmpDir, name)
+		// Encrypted files are read from the FS below the encfs.FS, so that
+		// they are uploaded encrypted.
+		fileFS := fs
+		var keyID string
+		if efs != nil {
+			if keyID, err = efs.FileKeyID(path); err != nil {
+				return BackupInfo{}, err
+			}
+			if keyID != "" {
+				fileFS = efs.Unwrap()
+			}
+		}
+		stat, err := fileFS.Stat(path)
+		if err != nil {
+			return BackupInfo{}, err
+		}
+		f := backupFile{
+			object: backupPrivateObject(m.ID, name),
+			name:   name,
+			size:   stat.Size(),
+			keyID:  keyID,
+		}
+		m.Size += f.size
+		if fileType, _, ok := base.ParseFilename(fs, name); ok &&
+			(fileType == base.FileTypeTable || fileType == base.FileTypeBlob) {
+			f.object = backupObjectsPrefix + "/" + name
+			if size, ok := sharedSizes[f.object]; ok {
+				if size != f.size {
+					return BackupInfo{}, errors.Errorf(
+						"pebble: backup %q contains a different %s of size %d; backups of different DBs must use different locators",
+						errors.Safe(locator), errors.Safe(name), errors.Safe(size))
+				}
+				m.files = append(m.files, f)
+				continue
+			}
+		}
+		if err := uploadBackupFile(storage, backupObjectName(locator, f.object), fileFS, path); err != nil {
+			return BackupInfo{}, err
+		}
+		uploaded = append(uploaded, f.object)
+		m.files = append(m.files, f)
+		m.UploadedSize += f.size
+	}
+
+	manifestObj := backupPrivateObject(m.ID, backupManifestObject)
+	if err := writeBackupObject(storage, backupObjectName(locator, manifestObj), m.encode()); err != nil {
+		return BackupInfo{}, err
+	}
+	uploaded = append(uploaded, manifestObj)
+	ids := make([]uint64, 0, len(backups)+1)
+	for _, b := range backups {
+		ids = append(ids, b.ID)
+	}
+	ids = append(ids, m.ID)
+	if err := writeBackupIndex(storage, locator, ids); err != nil {
+		return BackupInfo{}, err
+	}
+	return m.BackupInfo, nil
+}
+
+// ListBackups returns the backups stored in the provided storage under the
+// provided locator, in increasing order of ID.
+func ListBackups(
+	ctx context.Context, storage remote.Storage, locator string,
+) ([]BackupInfo, error) {
+	backups, err := readBackups(ctx, storage, locator)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]BackupInfo, len(backups))
+	for i := range backups {
+		infos[i] = backups[i].BackupInfo
+	}
+	return infos, nil
+}
+
+// DeleteBackup deletes the backup with the provided ID, along with the
+// sstables and blob files that are not contained in any other backup.
+func DeleteBackup(ctx context.Context, storage remote.Storage, locator string, id uint64) error {
+	backups, err := readBackups(ctx, storage, locator)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(backups, func(m backupManifest) bool { return m.ID == id })
+	if i < 0 {
+		return errors.Errorf("pebble: backup %d not found", errors.Safe(id))
+	}
+	deleted := backups[i]
+	backups = slices.Delete(backups, i, i+1)
+
+	refs := make(map[string]int)
+	ids := make([]uint64, len(backups))
+	for j, b := range backups {
+		ids[j] = b.ID
+		for _, f := range b.files {
+			refs[f.object]++
+		}
+	}
+	// Remove the backup from the index first, so that the backup is not
+	// listed if its deletion is interrupted.
+	if err := writeBackupIndex(storage, locator, ids); err != nil {
+		return err
+	}
+	for _, f := range deleted.files {
+		if refs[f.object] > 0 {
+			continue
+		}
+		if err := deleteBackupObject(storage, backupObjectName(locator, f.object)); err != nil {
+			return err
+		}
+	}
+	return deleteBackupObject(storage, backupObjectName(locator, backupPrivateObject(id, backupManifestObject)))
+}
+
+// Restore restores the backup with the provided ID to a new directory, which
+// must not exist. The restored directory may be opened with Open.
+//
+// If the backup contains encrypted files, fs must wrap an encfs.FS that has
+// the keys the files are encrypted with. The files are restored as they were
+// uploaded, without being re-encrypted.
+func Restore(
+	ctx context.Context, storage remote.Storage, locator string, id uint64, fs vfs.FS, dirname string,
+) (retErr error) {
+	m, err := readBackupManifest(ctx, storage, locator, id)
+	if err != nil {
+		return err
+	}
+	efs := encfs.Find(fs)
+	for _, f := range m.files {
+		if f.keyID == "" {
+			continue
+		}
+		if efs == nil {
+			return errors.Errorf("pebble: backup %d is encrypted, but the FS does not encrypt files", errors.Safe(id))
+		}
+		if err := efs.CheckKey(f.keyID); err != nil {
+			return errors.Wrapf(err, "pebble: restoring %s of backup %d", errors.Safe(f.name), errors.Safe(id))
+		}
+	}
+	if _, err := fs.Stat(dirname); !oserror.IsNotExist(err) {
+		if err == nil {
+			return &os.PathError{
+				Op:   "restore",
+				Path: dirname,
+				Err:  oserror.ErrExist,
+			}
+		}
+		return err
+	}
+	dir, err := mkdirAllAndSyncParents(fs, dirname)
+	if err != nil {
+		return err
+	}
+	// dirs holds the directories that files were restored to, which are synced
+	// once all the files have been restored.
+	dirs := map[string]vfs.File{dirname: dir}
+	defer func() {
+		for _, dir := range dirs {
+			_ = dir.Close()
+		}
+		if retErr != nil {
+			// Attempt to cleanup on error.
+			_ = fs.RemoveAll(dirname)
+		}
+	}()
+	for _, f := range m.files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path := backupFilePath(fs, dirname, f.name)
+		if parent := fs.PathDir(path); dirs[parent] == nil {
+			dir, err := mkdirAllAndSyncParents(fs, parent)
+			if err != nil {
+				return err
+			}
+			dirs[parent] = dir
+		}
+		fileFS := fs
+		if f.keyID != "" {
+			fileFS = efs.Unwrap()
+		}
+		if err := downloadBackupFile(ctx, storage, backupObjectName(locator, f.object), f.size, fileFS, path); err != nil {
+			return err
+		}
+	}
+	for path, dir := range dirs {
+		delete(dirs, path)
+		if err := errors.CombineErrors(dir.Sync(), dir.Close()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBackups reads the manifests of the backups listed in the index.
+func readBackups(
+	ctx context.Context, storage remote.Storage, locator string,
+) ([]backupManifest, error) {
+	data, err := readBackupObject(ctx, storage, backupObjectName(locator, backupIndexObject))
+	if err != nil {
+		if storage.IsNotExistError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var backups []backupManifest
+	for _, line := range strings.Fields(string(data)) {
+		id, err := strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return nil, base.CorruptionErrorf("pebble: invalid backup index: %q", line)
+		}
+		m, err := readBackupManifest(ctx, storage, locator, id)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, m)
+	}
+	return backups, nil
+}
+
+func writeBackupIndex(storage remote.Storage, locator string, ids []uint64) error {
+	var buf bytes.Buffer
+	for _, id := range ids {
+		fmt.Fprintf(&buf, "%d\n", id)
+	}
+	return writeBackupObject(storage, backupObjectName(locator, backupIndexObject), buf.Bytes())
+}
+
+func readBackupManifest(
+	ctx context.Context, storage remote.Storage, locator string, id uint64,
+) (backupManifest, error) {
+	data, err := readBackupObject(ctx, storage, backupObjectName(locator, backupPrivateObject(id, backupManifestObject)))
+	if err != nil {
+		if storage.IsNotExistError(err) {
+			return backupManifest{}, errors.Errorf("pebble: backup %d not found", errors.Safe(id))
+		}
+		return backupManifest{}, err
+	}
+	m, err := decodeBackupManifest(data)
+	if err != nil {
+		return backupManifest{}, err
+	}
+	if m.ID != id {
+		return backupManifest{}, base.CorruptionErrorf("pebble: backup %d has manifest of backup %d",
+			errors.Safe(id), errors.Safe(m.ID))
+	}
+	return m, nil
+}
+
+// encode encodes the manifest as text, one field per line.
+func (m *backupManifest) encode() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "id %d\n", m.ID)
+	fmt.Fprintf(&buf, "create-time %s\n", m.CreateTime.Format(time.RFC3339Nano))
+	fmt.Fprintf(&buf, "uploaded-size %d\n", m.UploadedSize)
+	for _, f := range m.files {
+		fmt.Fprintf(&buf, "file %s %s %d", f.object, f.name, f.size)
+		if f.keyID != "" {
+			fmt.Fprintf(&buf, " %s", strconv.Quote(f.keyID))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func decodeBackupManifest(data []byte) (backupManifest, error) {
+	var m backupManifest
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch {
+		case fields[0] == "id" && len(fields) == 2:
+			m.ID, err = strconv.ParseUint(fields[1], 10, 64)
+		case fields[0] == "create-time" && len(fields) == 2:
+			m.CreateTime, err = time.Parse(time.RFC3339Nano, fields[1])
+		case fields[0] == "uploaded-size" && len(fields) == 2:
+			m.UploadedSize, err = strconv.ParseInt(fields[1], 10, 64)
+		case fields[0] == "file" && len(fields) >= 4:
+			f := backupFile{object: fields[1], name: fields[2]}
+			f.size, err = strconv.ParseInt(fields[3], 10, 64)
+			if err == nil && len(fields) > 4 {
+				// The key ID is quoted, and may contain spaces.
+				f.keyID, err = strconv.Unquote(strings.SplitN(s.Text(), " ", 5)[4])
+			}
+			m.files = append(m.files, f)
+			m.Size += f.size
+		default:
+			err = errors.Errorf("unknown field %q", fields[0])
+		}
+		if err != nil {
+			return backupManifest{}, base.CorruptionErrorf("pebble: invalid backup manifest: %v", err)
+		}
+	}
+	return m, s.Err()
+}
+
+// backupObjectName returns the name of the object with the provided name
+// relative to the locator.
+func backupObjectName(locator, obj string) string {
+	if locator == "" {
+		return obj
+	}
+	return locator + "/" + obj
+}
+
+// backupPrivateObject returns the name, relative to the locator, of the object
+// holding a file that belongs to a single backup.
+func backupPrivateObject(id uint64, name string) string {
+	return fmt.Sprintf("%s/%06d/%s", backupsPrefix, id, name)
+}
+
+// listBackupFiles returns the paths, relative to root, of the files in the
+// directory root/rel and its subdirectories, in sorted order. The elements of
+// the returned paths are separated by forward slashes.
+func listBackupFiles(fs vfs.FS, root, rel string) ([]string, error) {
+	dir := backupFilePath(fs, root, rel)
+	names, err := fs.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	var files []string
+	for _, name := range names {
+		if rel != "" {
+			name = rel + "/" + name
+		}
+		stat, err := fs.Stat(backupFilePath(fs, root, name))
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			files = append(files, name)
+			continue
+		}
+		sub, err := listBackupFiles(fs, root, name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// backupFilePath returns the path of the file of a backup with the provided
+// name within the directory dirname.
+func backupFilePath(fs vfs.FS, dirname, name string) string {
+	if name == "" {
+		return dirname
+	}
+	return fs.PathJoin(append([]string{dirname}, strings.Split(name, "/")...)...)
+}
+
+func uploadBackupFile(storage remote.Storage, objName string, fs vfs.FS, path string) error {
+	f, err := fs.Open(path, vfs.SequentialReadsOption)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := storage.CreateObject(objName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func downloadBackupFile(
+	ctx context.Context, storage remote.Storage, objName string, size int64, fs vfs.FS, path string,
+) error {
+	r, objSize, err := storage.ReadObject(ctx, objName)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if objSize != size {
+		return base.CorruptionErrorf("pebble: backup object %q has size %d, expected %d",
+			errors.Safe(objName), errors.Safe(objSize), errors.Safe(size))
+	}
+	f, err := fs.Create(path, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, min(size, backupDownloadBufferSize))
+	for off := int64(0); off < size; {
+		n := min(int64(len(buf)), size-off)
+		if err := r.ReadAt(ctx, buf[:n], off); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			_ = f.Close()
+			return err
+		}
+		off += n
+	}
+	return errors.CombineErrors(f.Sync(), f.Close())
+}
+
+func readBackupObject(ctx context.Context, storage remote.Storage, objName string) ([]byte, error) {
+	r, size, err := storage.ReadObject(ctx, objName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data := make([]byte, size)
+	if err := r.ReadAt(ctx, data, 0); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func writeBackupObject(storage remote.Storage, objName string, data []byte) error {
+	w, err := storage.CreateObject(objName)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func deleteBackupObject(storage remote.Storage, objName string) error {
+	if err := storage.Delete(objName); err != nil && !storage.IsNotExistError(err) {
+		return err
+	}
+	return nil
+}
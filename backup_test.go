@@ -0,0 +1,190 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/encfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	fs := vfs.NewMem()
+	storage := remote.NewInMem()
+	d, err := Open("db", &Options{FS: fs, DisableAutomaticCompactions: true})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	set := func(keys ...string) {
+		for _, k := range keys {
+			require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+		}
+	}
+	restore := func(id uint64) string {
+		dirname := fmt.Sprintf("restore-%d", id)
+		require.NoError(t, Restore(ctx, storage, "backups", id, fs, dirname))
+		rd, err := Open(dirname, &Options{FS: fs})
+		require.NoError(t, err)
+		defer func() { require.NoError(t, rd.Close()) }()
+		iter, err := rd.NewIter(nil)
+		require.NoError(t, err)
+		var keys []string
+		for valid := iter.First(); valid; valid = iter.Next() {
+			keys = append(keys, string(iter.Key()))
+		}
+		require.NoError(t, iter.Close())
+		require.NoError(t, fs.RemoveAll(dirname))
+		return strings.Join(keys, " ")
+	}
+
+	set("a", "b")
+	require.NoError(t, d.Flush())
+	b1, err := d.Backup(ctx, storage, "backups")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), b1.ID)
+	require.Equal(t, b1.Size, b1.UploadedSize)
+
+	// The second backup shares the first backup's sstable, and contains a
+	// write that has not been flushed.
+	set("c")
+	require.NoError(t, d.Flush())
+	set("d")
+	b2, err := d.Backup(ctx, storage, "backups")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), b2.ID)
+	require.Less(t, b2.UploadedSize, b2.Size)
+
+	infos, err := ListBackups(ctx, storage, "backups")
+	require.NoError(t, err)
+	require.Equal(t, []BackupInfo{b1, b2}, infos)
+	require.Equal(t, "a b", restore(1))
+	require.Equal(t, "a b c d", restore(2))
+
+	// Deleting the first backup retains the sstable it shares with the second.
+	require.NoError(t, DeleteBackup(ctx, storage, "backups", 1))
+	infos, err = ListBackups(ctx, storage, "backups")
+	require.NoError(t, err)
+	require.Equal(t, []BackupInfo{b2}, infos)
+	require.Equal(t, "a b c d", restore(2))
+	require.Error(t, Restore(ctx, storage, "backups", 1, fs, "restore-1"))
+
+	// Deleting the last backup deletes all of its objects.
+	require.NoError(t, DeleteBackup(ctx, storage, "backups", 2))
+	objs, err := storage.List("backups/", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"backups/BACKUPS"}, objs)
+
+	// The temporary checkpoints were removed.
+	ls, err := fs.List("db")
+	require.NoError(t, err)
+	for _, name := range ls {
+		require.False(t, strings.HasPrefix(name, "backup-"), name)
+	}
+}
+
+func TestBackupEncrypted(t *testing.T) {
+	ctx := context.Background()
+	mem := vfs.NewMem()
+	keys := &encfs.StaticKeyProvider{
+		ActiveKeyID: "k1",
+		Keys:        map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)},
+	}
+	fs := encfs.New(mem, keys)
+	storage := remote.NewInMem()
+	d, err := Open("db", &Options{FS: fs, DisableAutomaticCompactions: true})
+	require.NoError(t, err)
+	value := bytes.Repeat([]byte("secret-value."), 10)
+	require.NoError(t, d.Set([]byte("a"), value, nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("b"), value, nil))
+	b, err := d.Backup(ctx, storage, "backups")
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+
+	// The uploaded objects are encrypted.
+	objs, err := storage.List("backups/", "")
+	require.NoError(t, err)
+	for _, obj := range objs {
+		r, size, err := storage.ReadObject(ctx, obj)
+		require.NoError(t, err)
+		buf := make([]byte, size)
+		require.NoError(t, r.ReadAt(ctx, buf, 0))
+		require.NoError(t, r.Close())
+		require.False(t, bytes.Contains(buf, []byte("secret-value")), obj)
+	}
+
+	// Restoring requires the keys the files are encrypted with.
+	require.Error(t, Restore(ctx, storage, "backups", b.ID, mem, "restore"))
+	require.Error(t, Restore(ctx, storage, "backups", b.ID, encfs.New(mem, &encfs.StaticKeyProvider{
+		ActiveKeyID: "k2",
+		Keys:        map[string][]byte{"k2": bytes.Repeat([]byte{2}, 32)},
+	}), "restore"))
+
+	require.NoError(t, Restore(ctx, storage, "backups", b.ID, fs, "restore"))
+	rd, err := Open("restore", &Options{FS: fs})
+	require.NoError(t, err)
+	for _, k := range []string{"a", "b"} {
+		v, closer, err := rd.Get([]byte(k))
+		require.NoError(t, err)
+		require.Equal(t, value, v)
+		require.NoError(t, closer.Close())
+	}
+	require.NoError(t, rd.Close())
+
+	// The restored files are stored encrypted.
+	ls, err := mem.List("restore")
+	require.NoError(t, err)
+	for _, name := range ls {
+		f, err := mem.Open(mem.PathJoin("restore", name))
+		require.NoError(t, err)
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		require.False(t, bytes.Contains(data, []byte("secret-value")), name)
+	}
+}
+
+func TestBackupKeyspaces(t *testing.T) {
+	ctx := context.Background()
+	mem := vfs.NewMem()
+	storage := remote.NewInMem()
+	d, versions, meta := openKeyspaces(t, "db", testKeyspaceOptions(t, mem))
+	require.NoError(t, d.Set([]byte("a"), []byte("db"), nil))
+	require.NoError(t, versions.Set([]byte("a@1"), []byte("v1"), nil))
+	require.NoError(t, meta.Set([]byte("m"), []byte("meta"), nil))
+	require.NoError(t, versions.Flush())
+	require.NoError(t, meta.Flush())
+	// Unflushed keyspace writes are recovered from the backup's WAL.
+	require.NoError(t, versions.Set([]byte("a@2"), []byte("v2"), nil))
+	b1, err := d.Backup(ctx, storage, "backups")
+	require.NoError(t, err)
+	require.Equal(t, b1.Size, b1.UploadedSize)
+	b2, err := d.Backup(ctx, storage, "backups")
+	require.NoError(t, err)
+	require.Less(t, b2.UploadedSize, b2.Size)
+	require.NoError(t, d.Close())
+
+	for _, id := range []uint64{b1.ID, b2.ID} {
+		dirname := fmt.Sprintf("restore-%d", id)
+		require.NoError(t, Restore(ctx, storage, "backups", id, mem, dirname))
+		rd, versions, meta := openKeyspaces(t, dirname, testKeyspaceOptions(t, mem))
+		v, closer, err := rd.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Equal(t, "db", string(v))
+		require.NoError(t, closer.Close())
+		require.Equal(t, "a@2=v2 a@1=v1", keyspaceScan(t, versions, nil))
+		require.Equal(t, "m=meta", keyspaceScan(t, meta, nil))
+		require.NoError(t, rd.Close())
+	}
+}
@@ -361,6 +361,19 @@ type batchInternal struct {
 
 	commitErr error
 
+	// validateCommit, if set, is invoked by the commit pipeline before the
+	// batch is assigned a sequence number. It must validate the batch against
+	// the state of the DB at the provided visible sequence number. If it
+	// returns an error, the batch is not committed and the error is returned
+	// from the commit. It is used by Txn to detect conflicts.
+	validateCommit func(seqNum base.SeqNum) error
+
+	// keyspaces holds the writes to the keyspaces of the DB (see
+	// Keyspace.InBatch), indexed by the position of the keyspace in
+	// Options.Keyspaces. Each batch belongs to the DB of its keyspace. The
+	// batches are logged along with the batch when it is committed.
+	keyspaces []*Batch
+
 	// Position bools together to reduce the sizeof the struct.
 
 	// ingestedSSTBatch indicates that the batch contains one or more key kinds
@@ -1573,6 +1586,11 @@ func (b *Batch) Reset() {
 }
 
 func (b *Batch) reset() {
+	for _, kb := range b.keyspaces {
+		if kb != nil {
+			_ = kb.Close()
+		}
+	}
 	// Zero out the struct, retaining only the fields necessary for manual
 	// reuse.
 	b.batchInternal = batchInternal{
@@ -0,0 +1,447 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/batchrepr"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/record"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/wal"
+)
+
+// ErrChangefeedTooOld is returned by DB.NewChangefeed and Changefeed.Next when
+// a WAL containing batches that have not yet been returned by the changefeed
+// has been deleted. WALs are retained for open changefeeds for at most
+// Options.ChangefeedWALRetention after their contents have been flushed.
+var ErrChangefeedTooOld = errors.New("pebble: changefeed position is too old; required WALs have been deleted")
+
+const (
+	// changefeedMinPollInterval and changefeedMaxPollInterval bound the
+	// interval at which a changefeed that has read all committed batches
+	// polls the WAL. A changefeed is also woken when a batch is committed, but
+	// the batch may not yet have been written to the WAL file.
+	changefeedMinPollInterval = time.Millisecond
+	changefeedMaxPollInterval = 100 * time.Millisecond
+)
+
+// A Changefeed streams the batches committed to a DB in sequence number order,
+// by reading the DB's WALs. Changefeeds are created by DB.NewChangefeed.
+//
+// While a changefeed is open, the WALs it has not yet read are retained after
+// their contents have been flushed, for at most
+// Options.ChangefeedWALRetention. A changefeed that falls further behind
+// returns ErrChangefeedTooOld.
+//
+// Batches containing only LogData are not returned. Sstables ingested without
+// being written to the WAL (see DB.Ingest) are not returned either, although
+// they consume sequence numbers.
+//
+// A Changefeed is not safe for concurrent use.
+type Changefeed struct {
+	d *DB
+	// nextSeqNum is the sequence number following the last batch returned.
+	// Batches that end before nextSeqNum are skipped.
+	nextSeqNum base.SeqNum
+
+	// The position of the changefeed within the WALs. walTail.walNum is
+	// protected by DB.mu, and is only modified by the changefeed.
+	walTail
+	// tooOld is set, while holding DB.mu, when the WAL being read by the
+	// changefeed is deleted.
+	tooOld bool
+
+	pollInterval time.Duration
+	closed       bool
+}
+
+// walTail reads the records of a WAL that may still be written to. Its
+// position persists while the WAL segment being read is closed, so that
+// reading resumes where it left off once more records have been written.
+type walTail struct {
+	walNum wal.NumWAL
+	// segment is the index of the WAL segment being read, and offset is the
+	// offset of the next record within the segment.
+	segment int
+	offset  int64
+	// file and rr read the WAL segment, if it's open.
+	file vfs.File
+	rr   *record.Reader
+	buf  bytes.Buffer
+}
+
+// changefeedWAL records the sequence number of the first batch written to a
+// WAL.
+type changefeedWAL struct {
+	num    wal.NumWAL
+	seqNum base.SeqNum
+	// obsoleteTime is the time at which the WAL's contents were found to have
+	// been flushed, or zero.
+	obsoleteTime time.Time
+}
+
+// changefeedState is the state of the changefeeds of a DB. It is protected by
+// DB.mu.
+type changefeedState struct {
+	feeds map[*Changefeed]struct{}
+	// wals holds the WALs that have not been deleted, in increasing order.
+	// WALs to which no batch was written may be absent.
+	wals []changefeedWAL
+}
+
+// changefeedNotifier wakes changefeeds waiting for new batches to be
+// committed.
+type changefeedNotifier struct {
+	waiters atomic.Int32
+	mu      sync.Mutex
+	// ch is closed and replaced when a batch is committed while there are
+	// waiters.
+	ch chan struct{}
+}
+
+// wait returns a channel that is closed when a batch is next committed. The
+// caller must call done when it's no longer waiting.
+func (n *changefeedNotifier) wait() <-chan struct{} {
+	n.waiters.Add(1)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	return n.ch
+}
+
+func (n *changefeedNotifier) done() {
+	n.waiters.Add(-1)
+}
+
+// notify wakes any changefeeds waiting for a batch to be committed.
+func (n *changefeedNotifier) notify() {
+	if n.waiters.Load() == 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
+
+// recordWALLocked records the sequence number of the first batch written to
+// a newly created WAL. WALs replayed by Open are not recorded, so changefeeds
+// cannot return the batches committed before the DB was opened.
+//
+// d.mu must be held.
+func (d *DB) recordWALLocked(logNum base.DiskFileNum, seqNum base.SeqNum) {
+	cf := &d.mu.changefeeds
+	if logNum == 0 || (len(cf.wals) > 0 && cf.wals[len(cf.wals)-1].num >= wal.NumWAL(logNum)) {
+		return
+	}
+	cf.wals = append(cf.wals, changefeedWAL{num: wal.NumWAL(logNum), seqNum: seqNum})
+}
+
+// retainWALsForChangefeedsLocked returns the WAL number below which WALs may be
+// deleted, given that the contents of the WALs below minUnflushedLogNum have
+// been flushed. WALs that have not been read by open changefeeds are retained
+// for at most Options.ChangefeedWALRetention after they are first found to
+// have been flushed. Changefeeds positioned within deleted WALs are marked as
+// too old.
+//
+// d.mu must be held.
+func (d *DB) retainWALsForChangefeedsLocked(minUnflushedLogNum wal.NumWAL) wal.NumWAL {
+	cf := &d.mu.changefeeds
+	minNum := minUnflushedLogNum
+	for f := range cf.feeds {
+		minNum = min(minNum, f.walNum)
+	}
+	if minNum < minUnflushedLogNum {
+		now := d.timeNow()
+		for i := range cf.wals {
+			w := &cf.wals[i]
+			if w.num >= minUnflushedLogNum {
+				break
+			}
+			if w.obsoleteTime.IsZero() {
+				w.obsoleteTime = now
+			}
+			if now.Sub(w.obsoleteTime) >= d.opts.ChangefeedWALRetention {
+				minNum = max(minNum, w.num+1)
+			}
+		}
+		for f := range cf.feeds {
+			if f.walNum < minNum {
+				f.tooOld = true
+			}
+		}
+	}
+	i := 0
+	for i < len(cf.wals) && cf.wals[i].num < minNum {
+		i++
+	}
+	cf.wals = append(cf.wals[:0], cf.wals[i:]...)
+	return minNum
+}
+
+// NewChangefeed returns a Changefeed that returns the batches committed to the
+// DB, in sequence number order, starting with the batch containing fromSeqNum
+// or, if no batch contains it, the first batch committed after it. Passing the
+// sequence number that follows the last batch returned by a previous
+// changefeed (Batch.SeqNum()+Batch.Count()) resumes that changefeed.
+//
+// NewChangefeed returns ErrChangefeedTooOld if the WAL containing fromSeqNum
+// has been deleted.
+func (d *DB) NewChangefeed(fromSeqNum base.SeqNum) (*Changefeed, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.DisableWAL {
+		return nil, errors.New("pebble: changefeeds require the WAL")
+	}
+	if d.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cf := &d.mu.changefeeds
+	if len(cf.wals) == 0 || fromSeqNum < cf.wals[0].seqNum {
+		return nil, ErrChangefeedTooOld
+	}
+	// Start reading from the last WAL whose first batch precedes fromSeqNum.
+	i := len(cf.wals) - 1
+	for i > 0 && cf.wals[i].seqNum > fromSeqNum {
+		i--
+	}
+	c := &Changefeed{
+		d:            d,
+		nextSeqNum:   fromSeqNum,
+		walTail:      walTail{walNum: cf.wals[i].num},
+		pollInterval: changefeedMinPollInterval,
+	}
+	if cf.feeds == nil {
+		cf.feeds = make(map[*Changefeed]struct{})
+	}
+	cf.feeds[c] = struct{}{}
+	return c, nil
+}
+
+// Next returns the next committed batch, blocking until one is committed or
+// the context is canceled. The returned batch may be read with Batch.Reader,
+// and its sequence number is returned by Batch.SeqNum. The caller owns the
+// batch, and may Close it when done.
+//
+// Next returns ErrChangefeedTooOld if the WAL containing the next batch was
+// deleted before it was read, and ErrClosed if the DB has been closed.
+func (c *Changefeed) Next(ctx context.Context) (*Batch, error) {
+	if c.closed {
+		return nil, errors.New("pebble: changefeed is closed")
+	}
+	for {
+		if err := c.d.closed.Load(); err != nil {
+			return nil, err.(error)
+		}
+		repr, err := c.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		if repr == nil {
+			if err := c.wait(ctx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		h, ok := batchrepr.ReadHeader(repr)
+		if !ok {
+			return nil, base.CorruptionErrorf("pebble: corrupt log file %s: invalid batch", c.walNum)
+		}
+		// Skip batches containing only LogData, and batches that have already
+		// been returned, which may be repeated in the WAL segments written
+		// during a failover.
+		if h.Count == 0 || h.SeqNum+base.SeqNum(h.Count) <= c.nextSeqNum {
+			continue
+		}
+		// The batch may have been written to the WAL, but not yet applied to
+		// the memtable.
+		for h.SeqNum+base.SeqNum(h.Count) > c.d.mu.versions.visibleSeqNum.Load() {
+			if err := c.wait(ctx); err != nil {
+				return nil, err
+			}
+			if err := c.d.closed.Load(); err != nil {
+				return nil, err.(error)
+			}
+		}
+		c.nextSeqNum = h.SeqNum + base.SeqNum(h.Count)
+		c.pollInterval = changefeedMinPollInterval
+		b := newBatch(nil)
+		if err := b.SetRepr(append([]byte(nil), repr...)); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+}
+
+// readRecord returns the next record in the WALs, or nil if the changefeed
+// has read every record that has been written.
+func (c *Changefeed) readRecord() ([]byte, error) {
+	for {
+		if c.rr != nil {
+			if repr, err := c.readSegmentRecord(); repr != nil || err != nil {
+				return repr, err
+			}
+		}
+		c.d.mu.Lock()
+		tooOld := c.tooOld
+		logs := c.d.mu.log.manager.List()
+		c.d.mu.Unlock()
+		if tooOld {
+			return nil, ErrChangefeedTooOld
+		}
+		ll, ok := logs.Get(c.walNum)
+		if !ok {
+			return nil, ErrChangefeedTooOld
+		}
+		// If a newer WAL exists, the WAL being read is no longer written to,
+		// and reaching its end means the changefeed should continue with the
+		// next WAL.
+		sealed := logs[len(logs)-1].Num > c.walNum
+		if c.rr == nil && c.segment < ll.NumSegments() {
+			fs, path := ll.SegmentLocation(c.segment)
+			if err := c.openSegment(fs, path); err != nil {
+				if oserror.IsNotExist(err) {
+					return nil, ErrChangefeedTooOld
+				}
+				return nil, err
+			}
+			if repr, err := c.readSegmentRecord(); repr != nil || err != nil {
+				return repr, err
+			}
+		}
+		switch {
+		case c.segment < ll.NumSegments()-1:
+			// The end of a segment that is followed by another may contain an
+			// invalid record, written when the WAL failed over.
+			c.segment++
+			c.offset = 0
+		case sealed:
+			c.d.mu.Lock()
+			for i := range logs {
+				if logs[i].Num > c.walNum {
+					c.walNum = logs[i].Num
+					break
+				}
+			}
+			c.segment, c.offset = 0, 0
+			// Allow the WAL that was read to be deleted.
+			c.d.deleteObsoleteFiles(c.d.newJobIDLocked())
+			c.d.mu.Unlock()
+		default:
+			return nil, nil
+		}
+	}
+}
+
+// openSegment opens the provided WAL segment, positioned at the tail's offset
+// within it.
+func (t *walTail) openSegment(fs vfs.FS, path string) error {
+	f, err := fs.Open(path)
+	if err != nil {
+		return err
+	}
+	t.file = f
+	// The record reader seeks to the offset, which vfs.File doesn't support.
+	t.rr = record.NewReader(io.NewSectionReader(f, 0, math.MaxInt64), base.DiskFileNum(t.walNum))
+	if t.offset > 0 {
+		if err := t.rr.SeekRecord(t.offset); err != nil {
+			t.closeSegment()
+			return ignoreIncompleteRecord(err)
+		}
+	}
+	return nil
+}
+
+// readSegmentRecord reads the next record from the open WAL segment. If the
+// segment has no complete record at the tail's offset, it closes the segment
+// and returns nil; the segment is reopened once more records may have been
+// written to it.
+func (t *walTail) readSegmentRecord() ([]byte, error) {
+	if t.rr == nil {
+		return nil, nil
+	}
+	r, err := t.rr.Next()
+	if err == nil {
+		t.buf.Reset()
+		_, err = io.Copy(&t.buf, r)
+	}
+	if err != nil {
+		t.closeSegment()
+		return nil, ignoreIncompleteRecord(err)
+	}
+	t.offset = t.rr.Offset()
+	return t.buf.Bytes(), nil
+}
+
+func (t *walTail) closeSegment() {
+	if t.file != nil {
+		_ = t.file.Close()
+	}
+	t.file, t.rr = nil, nil
+}
+
+// ignoreIncompleteRecord returns nil if err indicates that the end of the
+// records written to a WAL segment was reached.
+func ignoreIncompleteRecord(err error) error {
+	if errors.Is(err, io.EOF) || record.IsInvalidRecord(err) {
+		return nil
+	}
+	return err
+}
+
+// wait waits for a batch to be committed, or for the current poll interval to
+// elapse.
+func (c *Changefeed) wait(ctx context.Context) error {
+	n := &c.d.changefeedNotifier
+	ch := n.wait()
+	defer n.done()
+	t := time.NewTimer(c.pollInterval)
+	defer t.Stop()
+	select {
+	case <-ch:
+	case <-t.C:
+		c.pollInterval = min(2*c.pollInterval, changefeedMaxPollInterval)
+	case <-c.d.closedCh:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Close closes the changefeed, allowing the WALs it has not read to be
+// deleted.
+func (c *Changefeed) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.closeSegment()
+	d := c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.mu.changefeeds.feeds, c)
+	if d.closed.Load() == nil {
+		d.deleteObsoleteFiles(d.newJobIDLocked())
+	}
+	return nil
+}
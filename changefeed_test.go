@@ -0,0 +1,125 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangefeed(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	startSeqNum := d.mu.versions.visibleSeqNum.Load()
+	set := func(keys ...string) {
+		b := d.NewBatch()
+		for _, k := range keys {
+			require.NoError(t, b.Set([]byte(k), []byte(k), nil))
+		}
+		require.NoError(t, b.Commit(nil))
+	}
+	next := func(c *Changefeed) string {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		b, err := c.Next(ctx)
+		require.NoError(t, err)
+		defer b.Close()
+		s := fmt.Sprintf("%d:", b.SeqNum()-startSeqNum)
+		for r := b.Reader(); ; {
+			kind, k, _, ok, err := r.Next()
+			require.NoError(t, err)
+			if !ok {
+				return s
+			}
+			s += fmt.Sprintf(" %s(%s)", kind, k)
+		}
+	}
+
+	set("a", "b")
+	set("c")
+	c, err := d.NewChangefeed(startSeqNum)
+	require.NoError(t, err)
+	// The changefeed reads across WALs, and the flushed WAL is retained for it.
+	require.NoError(t, d.Flush())
+	set("d", "dd")
+
+	require.Equal(t, "0: SET(a) SET(b)", next(c))
+	require.Equal(t, "2: SET(c)", next(c))
+	require.Equal(t, "3: SET(d) SET(dd)", next(c))
+
+	// Next blocks until a batch is committed.
+	done := make(chan string)
+	go func() { done <- next(c) }()
+	select {
+	case s := <-done:
+		t.Fatalf("unexpected batch %s", s)
+	case <-time.After(10 * time.Millisecond):
+	}
+	set("e")
+	require.Equal(t, "5: SET(e)", <-done)
+
+	// A changefeed may start in the middle of a batch. The flushed WAL that
+	// the first changefeed is reading is retained, but the WAL it has read
+	// past has been deleted.
+	require.NoError(t, d.Flush())
+	c2, err := d.NewChangefeed(startSeqNum + 4)
+	require.NoError(t, err)
+	require.Equal(t, "3: SET(d) SET(dd)", next(c2))
+	require.NoError(t, c2.Close())
+	_, err = d.NewChangefeed(startSeqNum)
+	require.ErrorIs(t, err, ErrChangefeedTooOld)
+
+	// Once the changefeeds are closed, the flushed WALs are deleted.
+	require.NoError(t, c.Close())
+	_, err = d.NewChangefeed(startSeqNum + 4)
+	require.ErrorIs(t, err, ErrChangefeedTooOld)
+	c, err = d.NewChangefeed(startSeqNum + 6)
+	require.NoError(t, err)
+	set("f")
+	require.Equal(t, "6: SET(f)", next(c))
+	require.NoError(t, c.Close())
+}
+
+func TestChangefeedRetention(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                     vfs.NewMem(),
+		ChangefeedWALRetention: time.Hour,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	now := time.Now()
+	d.mu.Lock()
+	d.timeNow = func() time.Time { return now }
+	d.mu.Unlock()
+
+	startSeqNum := d.mu.versions.visibleSeqNum.Load()
+	require.NoError(t, d.Set([]byte("a"), nil, nil))
+	c, err := d.NewChangefeed(startSeqNum)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, c.Close()) }()
+
+	// The flushed WAL is retained for the changefeed until the retention
+	// period elapses.
+	require.NoError(t, d.Flush())
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, d.Set([]byte("b"), nil, nil))
+	require.NoError(t, d.Flush())
+
+	now = now.Add(time.Hour)
+	require.NoError(t, d.Set([]byte("c"), nil, nil))
+	require.NoError(t, d.Flush())
+	_, err = c.Next(context.Background())
+	require.ErrorIs(t, err, ErrChangefeedTooOld)
+
+	_, err = d.NewChangefeed(startSeqNum + 1)
+	require.ErrorIs(t, err, ErrChangefeedTooOld)
+}
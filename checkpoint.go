@@ -26,6 +26,9 @@ type checkpointOptions struct {
 
 	// If set, any SSTs that don't overlap with these spans are excluded from a checkpoint.
 	restrictToSpans []CheckpointSpan
+
+	// If set, only these keyspaces are checkpointed.
+	keyspaces []string
 }
 
 // CheckpointOption set optional parameters used by `DB.Checkpoint`.
@@ -59,6 +62,18 @@ func WithRestrictToSpans(spans []CheckpointSpan) CheckpointOption {
 	}
 }
 
+// WithKeyspaces restricts the checkpoint to the named keyspaces: the LSMs of
+// other keyspaces are excluded from the checkpoint. See Options.Keyspaces.
+//
+// Note that the checkpoint still holds the WAL, which may hold writes to other
+// keyspaces. When the checkpoint is opened, these keyspaces are recreated
+// with only the writes replayed from the WAL.
+func WithKeyspaces(names ...string) CheckpointOption {
+	return func(opt *checkpointOptions) {
+		opt.keyspaces = append(opt.keyspaces, names...)
+	}
+}
+
 // CheckpointSpan is a key range [Start, End) (inclusive on Start, exclusive on
 // End) of interest for a checkpoint.
 type CheckpointSpan struct {
@@ -149,6 +164,11 @@ func (d *DB) Checkpoint(
 	for _, fn := range opts {
 		fn(opt)
 	}
+	for _, name := range opt.keyspaces {
+		if _, err := d.Keyspace(name); err != nil {
+			return err
+		}
+	}
 
 	if _, err := d.opts.FS.Stat(destDir); !oserror.IsNotExist(err) {
 		if err == nil {
@@ -177,6 +197,17 @@ func (d *DB) Checkpoint(
 		d.enableFileDeletions()
 	}()
 
+	// Checkpoint the keyspaces before the WAL is listed below, so that the
+	// checkpoint holds every WAL that a keyspace has not flushed.
+	if len(d.keyspaces) > 0 {
+		d.mu.Unlock()
+		if err := d.checkpointKeyspaces(destDir, opt.keyspaces); err != nil {
+			_ = d.opts.FS.RemoveAll(destDir)
+			return err
+		}
+		d.mu.Lock()
+	}
+
 	// TODO(peter): RocksDB provides the option to roll the manifest if the
 	// MANIFEST size is too large. Should we do this too?
 
@@ -196,6 +227,7 @@ func (d *DB) Checkpoint(
 	d.mu.versions.virtualBackings.ForEach(func(backing *fileBacking) {
 		virtualBackingFiles[backing.DiskFileNum] = struct{}{}
 	})
+	blobFiles := d.mu.versions.liveBlobFiles()
 
 	// Acquire the logs while holding mutexes to ensure we don't race with a
 	// flush that might mark a log that's relevant to `current` as obsolete
@@ -264,9 +296,12 @@ func (d *DB) Checkpoint(
 
 	var excludedTables map[deletedFileEntry]*tableMetadata
 	var remoteFiles []base.DiskFileNum
+	var remoteBlobFiles []base.DiskFileNum
 	// Set of FileBacking.DiskFileNum which will be required by virtual sstables
 	// in the checkpoint.
 	requiredVirtualBackingFiles := make(map[base.DiskFileNum]struct{})
+	// Set of blob files which will be referenced by sstables in the checkpoint.
+	requiredBlobFiles := make(map[base.DiskFileNum]struct{})
 	// Link or copy the sstables.
 	for l := range current.Levels {
 		iter := current.Levels[l].Iter()
@@ -282,6 +317,29 @@ func (d *DB) Checkpoint(
 				continue
 			}
 
+			// Link or copy the blob files referenced by the sstable.
+			for _, ref := range f.BlobReferences {
+				if _, ok := requiredBlobFiles[ref.FileNum]; ok {
+					continue
+				}
+				requiredBlobFiles[ref.FileNum] = struct{}{}
+				meta, err := d.objProvider.Lookup(base.FileTypeBlob, ref.FileNum)
+				if err != nil {
+					ckErr = err
+					return ckErr
+				}
+				if meta.IsRemote() {
+					remoteBlobFiles = append(remoteBlobFiles, ref.FileNum)
+					continue
+				}
+				srcPath := base.MakeFilepath(fs, d.dirname, base.FileTypeBlob, ref.FileNum)
+				destPath := fs.PathJoin(destDir, fs.PathBase(srcPath))
+				ckErr = vfs.LinkOrCopy(fs, srcPath, destPath)
+				if ckErr != nil {
+					return ckErr
+				}
+			}
+
 			fileBacking := f.FileBacking
 			if f.Virtual {
 				if _, ok := requiredVirtualBackingFiles[fileBacking.DiskFileNum]; ok {
@@ -322,10 +380,17 @@ func (d *DB) Checkpoint(
 			removeBackingTables = append(removeBackingTables, diskFileNum)
 		}
 	}
+	var removeBlobFiles []base.DiskFileNum
+	for _, bf := range blobFiles {
+		if _, ok := requiredBlobFiles[bf.FileNum]; !ok {
+			// The blob file is only referenced by excluded sstables.
+			removeBlobFiles = append(removeBlobFiles, bf.FileNum)
+		}
+	}
 
 	ckErr = d.writeCheckpointManifest(
 		fs, formatVers, destDir, dir, manifestFileNum, manifestSize,
-		excludedTables, removeBackingTables,
+		excludedTables, removeBackingTables, removeBlobFiles,
 	)
 	if ckErr != nil {
 		return ckErr
@@ -336,6 +401,12 @@ func (d *DB) Checkpoint(
 			return ckErr
 		}
 	}
+	if len(remoteBlobFiles) > 0 {
+		ckErr = d.objProvider.CheckpointState(fs, destDir, base.FileTypeBlob, remoteBlobFiles)
+		if ckErr != nil {
+			return ckErr
+		}
+	}
 
 	// Copy the WAL files. We copy rather than link because WAL file recycling
 	// will cause the WAL files to be reused which would invalidate the
@@ -429,6 +500,7 @@ func (d *DB) writeCheckpointManifest(
 	manifestSize int64,
 	excludedTables map[deletedFileEntry]*tableMetadata,
 	removeBackingTables []base.DiskFileNum,
+	removeBlobFiles []base.DiskFileNum,
 ) error {
 	// Copy the MANIFEST, and create a pointer to it. We copy rather
 	// than link because additional version edits added to the
@@ -478,10 +550,12 @@ func (d *DB) writeCheckpointManifest(
 		}
 
 		if len(excludedTables) > 0 {
-			// Write out an additional VersionEdit that deletes the excluded SST files.
+			// Write out an additional VersionEdit that deletes the excluded SST
+			// files and any blob files that only they referenced.
 			ve := versionEdit{
 				DeletedTables:        excludedTables,
 				RemovedBackingTables: removeBackingTables,
+				DeletedBlobFiles:     removeBlobFiles,
 			}
 
 			rw, err := w.Next()
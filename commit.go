@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 
 	"github.com/cockroachdb/crlib/crtime"
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/batchrepr"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/record"
@@ -308,6 +309,18 @@ func (p *commitPipeline) Commit(b *Batch, syncWAL bool, noSyncWait bool) error {
 	}
 	b.commitStats.SemaphoreWaitDuration = commitStartTime.Elapsed()
 
+	// Validate the batch, if required. On success, commitPipeline.mu remains
+	// held and is released by prepare.
+	if b.validateCommit != nil {
+		if err := p.validate(b); err != nil {
+			<-p.commitQueueSem
+			if syncWAL {
+				<-p.logSyncQSem
+			}
+			return err
+		}
+	}
+
 	// Prepare the batch for committing: enqueuing the batch in the pending
 	// queue, determining the batch sequence number and writing the data to the
 	// WAL.
@@ -426,6 +439,60 @@ func (p *commitPipeline) AllocateSeqNum(
 	<-p.commitQueueSem
 }
 
+// errCommitValidation marks errors returned by a batch's validateCommit
+// function. Such errors leave the commit pipeline intact.
+var errCommitValidation = errors.New("pebble: commit validation failed")
+
+// commitValidationAttempts is the number of times a batch is validated
+// without holding commitPipeline.mu before it is validated with the mutex held.
+const commitValidationAttempts = 4
+
+// validate invokes the batch's validateCommit function. The validation runs
+// without holding commitPipeline.mu, against the state of the DB at the
+// visible sequence number. The pipeline is then drained, and if a batch was
+// sequenced during the validation, the validation is retried. After
+// commitValidationAttempts attempts, the validation runs with the mutex held
+// so that the batch isn't starved by a steady stream of commits. If validation
+// succeeds, commitPipeline.mu is left held so that no other batch may be
+// sequenced before b; the caller must then call prepare, which releases it.
+func (p *commitPipeline) validate(b *Batch) error {
+	for attempt := 1; ; attempt++ {
+		locked := attempt == commitValidationAttempts
+		if locked {
+			p.mu.Lock()
+			p.drainLocked()
+		}
+		seqNum := p.env.visibleSeqNum.Load()
+		if err := b.validateCommit(seqNum); err != nil {
+			if locked {
+				p.mu.Unlock()
+			}
+			return errors.Mark(err, errCommitValidation)
+		}
+		if locked {
+			return nil
+		}
+		p.mu.Lock()
+		p.drainLocked()
+		if p.env.visibleSeqNum.Load() == seqNum {
+			// No batch was committed since the validation.
+			return nil
+		}
+		p.mu.Unlock()
+	}
+}
+
+// drainLocked waits for any outstanding writes to the memtable to complete,
+// as in AllocateSeqNum.
+//
+// Requires commitPipeline.mu to be held.
+func (p *commitPipeline) drainLocked() {
+	logSeqNum := p.env.logSeqNum.Load()
+	for p.env.visibleSeqNum.Load() != logSeqNum {
+		runtime.Gosched()
+	}
+}
+
 func (p *commitPipeline) prepare(b *Batch, syncWAL bool, noSyncWait bool) (*memTable, error) {
 	n := uint64(b.Count())
 	if n == invalidBatchCount {
@@ -452,7 +519,11 @@ func (p *commitPipeline) prepare(b *Batch, syncWAL bool, noSyncWait bool) (*memT
 		b.commit.Add(2)
 	}
 
-	p.mu.Lock()
+	// A batch requiring validation was validated with commitPipeline.mu held,
+	// which must remain held until the batch is assigned its sequence number.
+	if b.validateCommit == nil {
+		p.mu.Lock()
+	}
 
 	// Enqueue the batch in the pending queue. Note that while the pending queue
 	// is lock-free, we want the order of batches to be the same as the sequence
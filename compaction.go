@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider/objiotracing"
 	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/blob"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/vfs"
 )
@@ -140,7 +141,15 @@ const (
 	compactionKindRead
 	compactionKindTombstoneDensity
 	compactionKindRewrite
+	// compactionKindBlobFileRewrite denotes a compaction that rewrites sstables
+	// in place in order to move the values they reference out of blob files
+	// that are mostly garbage. Once no sstable references such a blob file, it
+	// may be deleted.
+	compactionKindBlobFileRewrite
 	compactionKindIngestedFlushable
+	// compactionKindUpload denotes a compaction that moves a local sstable to
+	// shared storage, in the same level. See upload.go.
+	compactionKindUpload
 )
 
 func (k compactionKind) String() string {
@@ -161,10 +170,14 @@ func (k compactionKind) String() string {
 		return "tombstone-density"
 	case compactionKindRewrite:
 		return "rewrite"
+	case compactionKindBlobFileRewrite:
+		return "blob-rewrite"
 	case compactionKindIngestedFlushable:
 		return "ingested-flushable"
 	case compactionKindCopy:
 		return "copy"
+	case compactionKindUpload:
+		return "upload"
 	}
 	return "?"
 }
@@ -222,6 +235,11 @@ type compaction struct {
 	// single output table with the tables in the grandparent level.
 	maxOverlapBytes uint64
 
+	// encryptionKeyID is the ID of the encryption key that was active when the
+	// compaction started writing output tables (see
+	// TableMetadata.EncryptionKeyID).
+	encryptionKeyID string
+
 	// flushing contains the flushables (aka memtables) that are being flushed.
 	flushing flushableList
 	// bytesWritten contains the number of bytes that have been written to outputs.
@@ -263,6 +281,16 @@ type compaction struct {
 	// this compaction is allowed to excise files.
 	exciseEnabled bool
 
+	// evictInputs is set to true if this is a compactionKindDeleteOnly that
+	// deletes all of its inputs regardless of their contents, rather than the
+	// inputs covered by deletionHints. See FIFOCompactionPicker.
+	evictInputs bool
+
+	// rewriteBlobFiles is set if this is a compactionKindBlobFileRewrite. It
+	// holds the blob files whose values referenced by the compaction's inputs
+	// must be rewritten into new blob files.
+	rewriteBlobFiles []base.DiskFileNum
+
 	metrics map[int]*LevelMetrics
 
 	pickerMetrics compactionPickerMetrics
@@ -338,6 +366,16 @@ func newCompaction(
 	provider objstorage.Provider,
 	slot base.CompactionSlot,
 ) *compaction {
+	if pc.kind == compactionKindDeleteOnly {
+		c := newDeleteOnlyCompaction(opts, pc.version, pc.inputs, beganAt, nil /* hints */, false /* exciseEnabled */)
+		c.evictInputs = pc.evictInputs
+		c.pickerMetrics = pc.pickerMetrics
+		c.slot = slot
+		if c.slot == nil {
+			c.slot = opts.Experimental.CompactionLimiter.TookWithoutPermission(context.TODO())
+		}
+		return c
+	}
 	c := &compaction{
 		kind:              compactionKindDefault,
 		cmp:               pc.cmp,
@@ -352,6 +390,7 @@ func newCompaction(
 		beganAt:           beganAt,
 		maxOutputFileSize: pc.maxOutputFileSize,
 		maxOverlapBytes:   pc.maxOverlapBytes,
+		rewriteBlobFiles:  pc.rewriteBlobFiles,
 		pickerMetrics:     pc.pickerMetrics,
 		slot:              slot,
 	}
@@ -1026,12 +1065,18 @@ func (d *DB) addInProgressCompaction(c *compaction) {
 				} else {
 					isBase = true
 				}
+			} else if c.evictInputs && cl.level == 0 {
+				// Evicted L0 tables are removed from L0 like the inputs of an
+				// L0 compaction into the base level.
+				isBase = true
 			}
 		}
 	}
 
 	if (isIntraL0 || isBase) && c.version.L0Sublevels != nil {
-		l0Inputs := []manifest.LevelSlice{c.startLevel.files}
+		// NB: c.inputs[0] is the start level of the compaction, or the L0
+		// inputs of an eviction.
+		l0Inputs := []manifest.LevelSlice{c.inputs[0].files}
 		if isIntraL0 {
 			l0Inputs = append(l0Inputs, c.outputLevel.files)
 		}
@@ -1470,6 +1515,7 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 		InputBytes: inputBytes,
 		Ingest:     ingest,
 	})
+	_, span := d.startJobSpan(context.Background(), FlushSpanName, jobID)
 	startTime := d.timeNow()
 
 	var ve *manifest.VersionEdit
@@ -1612,6 +1658,8 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 	// IterOptions.OnlyReadGuaranteedDurable.
 	info.TotalDuration = d.timeNow().Sub(startTime)
 	d.opts.EventListener.FlushEnd(info)
+	span.SetAttributes(info.spanAttributes()...)
+	span.End(err)
 
 	// The order of these operations matters here for ease of testing.
 	// Removing the reader reference first allows tests to be guaranteed that
@@ -1623,6 +1671,9 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 	}
 
 	d.deleteObsoleteFiles(jobID)
+	if err == nil {
+		d.keyspaceFlushedLocked()
+	}
 
 	// Mark all the memtables we flushed as flushed.
 	for i := range flushed {
@@ -1753,7 +1804,8 @@ func (d *DB) maybeScheduleCompactionPicker(
 	maxDownloads := d.opts.MaxConcurrentDownloads()
 
 	if d.mu.compact.compactingCount >= maxCompactions &&
-		(len(d.mu.compact.downloads) == 0 || d.mu.compact.downloadingCount >= maxDownloads) {
+		(len(d.mu.compact.downloads) == 0 || d.mu.compact.downloadingCount >= maxDownloads) &&
+		!d.shouldScheduleUploadLocked() {
 		if len(d.mu.compact.manual) > 0 {
 			// Inability to run head blocks later manual compactions.
 			d.mu.compact.manual[0].retries++
@@ -1778,7 +1830,9 @@ func (d *DB) maybeScheduleCompactionPicker(
 		diskAvailBytes:          d.diskAvailBytes.Load(),
 		earliestSnapshotSeqNum:  d.mu.snapshots.earliest(),
 		earliestUnflushedSeqNum: d.getEarliestUnflushedSeqNumLocked(),
+		now:                     d.timeNow(),
 	}
+	d.maybeRefreshExpiredBytesLocked(env.now)
 
 	if d.mu.compact.compactingCount < maxCompactions {
 		// Check for delete-only compactions first, because they're expected to be
@@ -1806,6 +1860,10 @@ func (d *DB) maybeScheduleCompactionPicker(
 	for len(d.mu.compact.downloads) > 0 && d.mu.compact.downloadingCount < maxDownloads &&
 		d.tryScheduleDownloadCompaction(env, maxDownloads) {
 	}
+
+	if d.shouldScheduleUploadLocked() {
+		d.tryScheduleUploadCompaction(env)
+	}
 }
 
 // tryScheduleDeleteOnlyCompaction tries to kick off a delete-only compaction
@@ -2268,12 +2326,16 @@ func (d *DB) compact(c *compaction, errChannel chan error) {
 	pprof.Do(context.Background(), d.compactionPprofLabels(c), func(context.Context) {
 		d.mu.Lock()
 		defer d.mu.Unlock()
-		if err := d.compact1(c, errChannel); err != nil {
+		err := d.compact1(c, errChannel)
+		if err != nil {
 			// TODO(peter): count consecutive compaction errors and backoff.
 			d.opts.EventListener.BackgroundError(err)
 		}
 		if c.isDownload {
 			d.mu.compact.downloadingCount--
+		} else if c.kind == compactionKindUpload {
+			d.mu.compact.uploadingCount--
+			d.uploadCompleted(c, err)
 		} else {
 			d.mu.compact.compactingCount--
 		}
@@ -2354,6 +2416,7 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 	jobID := d.newJobIDLocked()
 	info := c.makeInfo(jobID)
 	d.opts.EventListener.CompactionBegin(info)
+	_, span := d.startJobSpan(context.Background(), CompactionSpanName, jobID)
 	startTime := d.timeNow()
 
 	ve, stats, err := d.runCompaction(jobID, c)
@@ -2383,9 +2446,25 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 				d.mu.versions.logUnlock()
 				return err
 			}
-			return d.mu.versions.logAndApply(jobID, ve, c.metrics, false /* forceRotation */, func() []compactionInfo {
+			if c.evictInputs {
+				// Record the evicted tables before applying the version edit,
+				// which may make them obsolete. Virtual tables are not
+				// recorded, as their backings may still be in use.
+				for _, f := range ve.DeletedTables {
+					if !f.Virtual {
+						d.mu.versions.evictedTables[f.FileBacking.DiskFileNum] = struct{}{}
+					}
+				}
+			}
+			err = d.mu.versions.logAndApply(jobID, ve, c.metrics, false /* forceRotation */, func() []compactionInfo {
 				return d.getInProgressCompactionInfoLocked(c)
 			})
+			if err != nil && c.evictInputs {
+				for _, f := range ve.DeletedTables {
+					delete(d.mu.versions.evictedTables, f.FileBacking.DiskFileNum)
+				}
+			}
+			return err
 		}()
 	}
 
@@ -2413,6 +2492,8 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 
 	info.TotalDuration = d.timeNow().Sub(c.beganAt)
 	d.opts.EventListener.CompactionEnd(info)
+	span.SetAttributes(info.spanAttributes()...)
+	span.End(err)
 
 	// Update the read state before deleting obsolete files because the
 	// read-state update will cause the previous version to be unref'd and if
@@ -2484,6 +2565,8 @@ func (d *DB) runCopyCompaction(
 		Stats:                    inputMeta.Stats,
 		Virtual:                  inputMeta.Virtual,
 		SyntheticPrefixAndSuffix: inputMeta.SyntheticPrefixAndSuffix,
+		BlobReferences:           inputMeta.BlobReferences,
+		BlobReferenceDepth:       inputMeta.BlobReferenceDepth,
 	}
 	if inputMeta.HasPointKeys {
 		newMeta.ExtendPointKeyBounds(c.cmp, inputMeta.SmallestPointKey, inputMeta.LargestPointKey)
@@ -2496,6 +2579,7 @@ func (d *DB) runCopyCompaction(
 		// external -> local/shared copy. File must be virtual.
 		// We will update this size later after we produce the new backing file.
 		newMeta.InitProviderBacking(base.DiskFileNum(newMeta.FileNum), inputMeta.FileBacking.Size)
+		newMeta.EncryptionKeyID = d.activeEncryptionKeyID()
 	} else {
 		// local -> shared copy. New file is guaranteed to not be virtual.
 		newMeta.InitPhysicalBacking()
@@ -2522,7 +2606,7 @@ func (d *DB) runCopyCompaction(
 
 	// If the src obj is external, we're doing an external to local/shared copy.
 	if objMeta.IsExternal() {
-		ctx := context.TODO()
+		ctx := base.WithCategory(context.TODO(), categoryCompaction)
 		src, err := d.objProvider.OpenForReading(
 			ctx, base.FileTypeTable, inputMeta.FileBacking.DiskFileNum, objstorage.OpenOptions{},
 		)
@@ -2798,7 +2882,13 @@ func (d *DB) runDeleteOnlyCompaction(
 	}
 	for _, cl := range c.inputs {
 		levelMetrics := &LevelMetrics{}
-		if err := d.runDeleteOnlyCompactionForLevel(cl, levelMetrics, ve, snapshots, fragments, c.exciseEnabled); err != nil {
+		if c.evictInputs {
+			iter := cl.files.Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				ve.DeletedTables[deletedFileEntry{Level: cl.level, FileNum: f.FileNum}] = f
+				levelMetrics.TablesDeleted++
+			}
+		} else if err := d.runDeleteOnlyCompactionForLevel(cl, levelMetrics, ve, snapshots, fragments, c.exciseEnabled); err != nil {
 			return nil, stats, err
 		}
 		c.metrics[cl.level] = levelMetrics
@@ -2897,6 +2987,16 @@ func (d *DB) runCompaction(
 		return d.runMoveCompaction(jobID, c)
 	case compactionKindCopy:
 		return d.runCopyCompaction(jobID, c)
+	case compactionKindUpload:
+		if err := d.paceUpload(c); err != nil {
+			return nil, stats, err
+		}
+		if iter := c.startLevel.files.Iter(); c.startLevel.files.Len() == 1 && !iter.First().Virtual {
+			return d.runCopyCompaction(jobID, c)
+		}
+		// Virtual sstables (and sets of overlapping L0 sstables) are rewritten
+		// like in a rewrite compaction; the output sstables are created on
+		// shared storage.
 	case compactionKindIngestedFlushable:
 		panic("pebble: runCompaction cannot handle compactionKindIngestedFlushable.")
 	}
@@ -2930,11 +3030,28 @@ func (d *DB) runCompaction(
 		ve, result.Err = c.makeVersionEdit(result)
 	}
 	if result.Err != nil {
-		// Delete any created tables.
+		// Delete any created tables or blob files.
 		obsoleteFiles := manifest.ObsoleteFiles{
 			FileBackings: make([]*fileBacking, 0, len(result.Tables)),
+			BlobFiles:    make([]*manifest.BlobFileMetadata, 0, len(result.Blobs)),
 		}
 		d.mu.Lock()
+		for i := range result.Blobs {
+			meta := &manifest.BlobFileMetadata{
+				FileNum: result.Blobs[i].ObjMeta.DiskFileNum,
+				Size:    result.Blobs[i].Stats.FileLen,
+			}
+			obsoleteFiles.AddBlob(meta)
+			// Add this file to zombie blobs as well, as the versionSet asserts
+			// on whether every obsolete file was at one point marked zombie.
+			d.mu.versions.zombieBlobs[meta.FileNum] = objectInfo{
+				fileInfo: fileInfo{
+					FileNum:  meta.FileNum,
+					FileSize: meta.Size,
+				},
+				isLocal: true,
+			}
+		}
 		for i := range result.Tables {
 			backing := &fileBacking{
 				DiskFileNum: result.Tables[i].ObjMeta.DiskFileNum,
@@ -2991,6 +3108,8 @@ func (d *DB) compactAndWrite(
 	// translate to 3 MiB per compaction.
 	c.bufferPool.Init(12)
 	defer c.bufferPool.Release()
+	// Record the active encryption key before creating any output table.
+	c.encryptionKeyID = d.activeEncryptionKeyID()
 	iiopts := internalIterOpts{
 		compaction: true,
 		readEnv: block.ReadEnv{
@@ -3000,8 +3119,21 @@ func (d *DB) compactAndWrite(
 				uint64(uintptr(unsafe.Pointer(c))),
 				categoryCompaction,
 			),
+			Category: categoryCompaction,
 		},
 	}
+	// Input tables may reference values stored in blob files. The compaction
+	// iterator propagates these values lazily, and the value separation policy
+	// decides whether to preserve the references or retrieve the values. Note
+	// that the fetcher must be closed only once all values have been written.
+	var blobValueFetcher blob.ValueFetcher
+	blobValueFetcher.Init(d.fileCache, iiopts.readEnv)
+	iiopts.blobValueFetcher = &blobValueFetcher
+	defer func() {
+		if err := blobValueFetcher.Close(); err != nil && result.Err == nil {
+			result.Err = err
+		}
+	}()
 
 	pointIter, rangeDelIter, rangeKeyIter, err := c.newInputIters(d.newIters, d.tableNewRangeKeyIter, iiopts)
 	defer func() {
@@ -3033,6 +3165,19 @@ func (d *DB) compactAndWrite(
 			})
 		},
 	}
+	if ttl := d.newTTLChecker(); ttl.enabled() {
+		cfg.IsExpired = ttl.expired
+	}
+	if filter := d.opts.CompactionFilter; filter != nil {
+		filterCtx := CompactionFilterContext{
+			OutputLevel:  c.outputLevel.level,
+			IsFlush:      len(c.flushing) > 0,
+			IsBottommost: c.delElision.ElidesEverything(),
+		}
+		cfg.Filter = func(userKey, value []byte) (CompactionFilterDecision, []byte) {
+			return filter.Filter(filterCtx, userKey, value)
+		}
+	}
 	iter := compact.NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter)
 
 	runnerCfg := compact.RunnerConfig{
@@ -3043,8 +3188,10 @@ func (d *DB) compactAndWrite(
 		TargetOutputFileSize:       c.maxOutputFileSize,
 		Slot:                       c.slot,
 		IteratorStats:              &c.stats,
+		ExpiresAt:                  d.opts.Experimental.TTLExtractor,
 	}
 	runner := compact.NewRunner(runnerCfg, iter)
+	valueSeparation := d.determineCompactionValueSeparation(jobID, c, tableFormat)
 	for runner.MoreDataToWrite() {
 		if c.cancel.Load() {
 			return runner.Finish().WithError(ErrCancelledCompaction)
@@ -3055,7 +3202,7 @@ func (d *DB) compactAndWrite(
 		if err != nil {
 			return runner.Finish().WithError(err)
 		}
-		runner.WriteTable(objMeta, tw)
+		runner.WriteTable(objMeta, tw, valueSeparation)
 		d.opts.Experimental.CPUWorkPermissionGranter.CPUWorkDone(cpuWorkHandle)
 	}
 	result = runner.Finish()
@@ -3104,17 +3251,33 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		outputMetrics.MultiLevel.BytesRead = outputMetrics.BytesRead
 	}
 
+	// Add any newly constructed blob files to the version edit.
+	if len(result.Blobs) > 0 {
+		ve.NewBlobFiles = make([]*manifest.BlobFileMetadata, len(result.Blobs))
+		for i := range result.Blobs {
+			ve.NewBlobFiles[i] = result.Blobs[i].Metadata
+			if c.flushing == nil {
+				outputMetrics.BlobBytesCompacted += result.Blobs[i].Stats.FileLen
+			} else {
+				outputMetrics.BlobBytesFlushed += result.Blobs[i].Stats.FileLen
+			}
+		}
+	}
+
 	inputLargestSeqNumAbsolute := c.inputLargestSeqNumAbsolute()
 	ve.NewTables = make([]newTableEntry, len(result.Tables))
 	for i := range result.Tables {
 		t := &result.Tables[i]
 
 		fileMeta := &tableMetadata{
-			FileNum:        base.PhysicalTableFileNum(t.ObjMeta.DiskFileNum),
-			CreationTime:   t.CreationTime.Unix(),
-			Size:           t.WriterMeta.Size,
-			SmallestSeqNum: t.WriterMeta.SmallestSeqNum,
-			LargestSeqNum:  t.WriterMeta.LargestSeqNum,
+			FileNum:            base.PhysicalTableFileNum(t.ObjMeta.DiskFileNum),
+			CreationTime:       t.CreationTime.Unix(),
+			EncryptionKeyID:    c.encryptionKeyID,
+			Size:               t.WriterMeta.Size,
+			SmallestSeqNum:     t.WriterMeta.SmallestSeqNum,
+			LargestSeqNum:      t.WriterMeta.LargestSeqNum,
+			BlobReferences:     t.BlobReferences,
+			BlobReferenceDepth: t.BlobReferenceDepth,
 		}
 		if c.flushing == nil {
 			// Set the file's LargestSeqNumAbsolute to be the maximum value of any
@@ -3160,6 +3323,13 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		outputMetrics.NumFiles++
 		outputMetrics.Additional.BytesWrittenDataBlocks += t.WriterMeta.Properties.DataSize
 		outputMetrics.Additional.BytesWrittenValueBlocks += t.WriterMeta.Properties.ValueBlocksSize
+		if props := &t.WriterMeta.Properties; props.CompressionDictSize > 0 {
+			outputMetrics.Additional.TablesWithCompressionDict++
+			outputMetrics.Additional.CompressionDictSize += props.CompressionDictSize
+			outputMetrics.Additional.CompressionDictSampledSize += props.CompressionDictSampledSize
+			outputMetrics.Additional.CompressionDictSampledWithDictSize += props.CompressionDictSampledWithDictSize
+			outputMetrics.Additional.CompressionDictSampledWithoutDictSize += props.CompressionDictSampledWithoutDictSize
+		}
 	}
 
 	// Sanity check that the tables are ordered and don't overlap.
@@ -3201,7 +3371,13 @@ func (d *DB) newCompactionOutput(
 		reason = "compacting"
 	}
 
-	ctx := context.TODO()
+	// The category is used to schedule the output's writes (see
+	// Options.Local.IOScheduler).
+	category := categoryCompaction
+	if c.kind == compactionKindFlush {
+		category = categoryFlush
+	}
+	ctx := base.WithCategory(context.TODO(), category)
 	if objiotracing.Enabled {
 		ctx = objiotracing.WithLevel(ctx, c.outputLevel.level)
 		if c.kind == compactionKindFlush {
@@ -3256,6 +3432,56 @@ func (d *DB) newCompactionOutput(
 	return objMeta, tw, cpuWorkHandle, nil
 }
 
+// newCompactionOutputBlob creates an object for a new blob file produced by a
+// compaction or flush.
+func (d *DB) newCompactionOutputBlob(
+	jobID JobID, c *compaction,
+) (objstorage.Writable, objstorage.ObjectMetadata, error) {
+	diskFileNum := d.mu.versions.getNextDiskFileNum()
+
+	var writeCategory vfs.DiskWriteCategory
+	if d.opts.EnableSQLRowSpillMetrics {
+		writeCategory = "sql-row-spill"
+	} else if c.kind == compactionKindFlush {
+		writeCategory = "pebble-memtable-flush"
+	} else {
+		writeCategory = "pebble-compaction"
+	}
+
+	// The category is used to schedule the output's writes (see
+	// Options.Local.IOScheduler).
+	category := categoryCompaction
+	if c.kind == compactionKindFlush {
+		category = categoryFlush
+	}
+	ctx := base.WithCategory(context.TODO(), category)
+	if objiotracing.Enabled {
+		ctx = objiotracing.WithLevel(ctx, c.outputLevel.level)
+		if c.kind == compactionKindFlush {
+			ctx = objiotracing.WithReason(ctx, objiotracing.ForFlush)
+		} else {
+			ctx = objiotracing.WithReason(ctx, objiotracing.ForCompaction)
+		}
+	}
+
+	// Blob files are always created locally.
+	createOpts := objstorage.CreateOptions{
+		WriteCategory: writeCategory,
+	}
+	writable, objMeta, err := d.objProvider.Create(ctx, base.FileTypeBlob, diskFileNum, createOpts)
+	if err != nil {
+		return nil, objstorage.ObjectMetadata{}, err
+	}
+	if c.kind != compactionKindFlush {
+		writable = &compactionWritable{
+			Writable: writable,
+			versions: d.mu.versions,
+			written:  &c.bytesWritten,
+		}
+	}
+	return writable, objMeta, nil
+}
+
 // validateVersionEdit validates that start and end keys across new and deleted
 // files in a versionEdit pass the given validation function.
 func validateVersionEdit(
@@ -0,0 +1,54 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import "github.com/cockroachdb/pebble/internal/compact"
+
+// CompactionFilterDecision exports the compact.FilterDecision type.
+type CompactionFilterDecision = compact.FilterDecision
+
+const (
+	// CompactionFilterKeep retains the key and its value.
+	CompactionFilterKeep = compact.FilterKeep
+	// CompactionFilterRemove removes the key. The key is treated as if it had
+	// been deleted at its sequence number, so older versions of the key are
+	// not exposed.
+	CompactionFilterRemove = compact.FilterRemove
+	// CompactionFilterChangeValue retains the key, replacing its value.
+	CompactionFilterChangeValue = compact.FilterChangeValue
+)
+
+// CompactionFilterContext describes the flush or compaction in which a
+// CompactionFilter is invoked.
+type CompactionFilterContext struct {
+	// OutputLevel is the level to which the flush or compaction writes.
+	OutputLevel int
+	// IsFlush is true if the filter is invoked by a flush.
+	IsFlush bool
+	// IsBottommost is true if no sstables in lower levels overlap the
+	// compaction's key range, i.e. the compaction's output holds the oldest
+	// data within the key range.
+	IsBottommost bool
+}
+
+// CompactionFilter is invoked by flushes and compactions for point keys,
+// allowing keys to be removed or their values to be rewritten in the
+// background. It is only invoked for keys written with Set that are the most
+// recent version of their user key and that are not visible to any open
+// Snapshot or EventuallyFileOnlySnapshot; keys visible to snapshots are
+// always retained so that snapshots continue to observe consistent data.
+// Merge operands and tombstones are never filtered.
+//
+// A CompactionFilter must be safe for concurrent use by multiple compactions,
+// and must not retain the provided key or value. The effects of a filter are
+// not observed by reads until the affected keys are compacted, so a filter
+// should only remove keys or change values in ways that the application
+// tolerates observing lazily.
+type CompactionFilter interface {
+	// Filter returns the decision for the provided key. If it returns
+	// CompactionFilterChangeValue, newValue is the key's new value. The filter
+	// may return a subslice of value as the new value.
+	Filter(ctx CompactionFilterContext, userKey, value []byte) (decision CompactionFilterDecision, newValue []byte)
+}
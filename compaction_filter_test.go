@@ -0,0 +1,100 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// testCompactionFilter removes keys with the prefix "purge/" and rewrites the
+// value "old" to "new".
+type testCompactionFilter struct {
+	mu       sync.Mutex
+	contexts []CompactionFilterContext
+}
+
+func (f *testCompactionFilter) Filter(
+	ctx CompactionFilterContext, userKey, value []byte,
+) (CompactionFilterDecision, []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.contexts = append(f.contexts, ctx)
+	switch {
+	case bytes.HasPrefix(userKey, []byte("purge/")):
+		return CompactionFilterRemove, nil
+	case string(value) == "old":
+		return CompactionFilterChangeValue, []byte("new")
+	default:
+		return CompactionFilterKeep, nil
+	}
+}
+
+func TestCompactionFilter(t *testing.T) {
+	filter := &testCompactionFilter{}
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		CompactionFilter:            filter,
+		DisableAutomaticCompactions: true,
+		Logger:                      testLogger{t},
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	check := func(r Reader, expected map[string]string) {
+		t.Helper()
+		for _, k := range []string{"a", "purge/a", "purge/b"} {
+			v, closer, err := r.Get([]byte(k))
+			if exp, ok := expected[k]; ok {
+				require.NoError(t, err, k)
+				require.Equal(t, exp, string(v), k)
+				require.NoError(t, closer.Close())
+			} else {
+				require.True(t, errors.Is(err, ErrNotFound), "%s: %v", k, err)
+			}
+		}
+	}
+
+	require.NoError(t, d.Set([]byte("a"), []byte("old"), nil))
+	require.NoError(t, d.Set([]byte("purge/a"), []byte("1"), nil))
+	snap := d.NewSnapshot()
+	require.NoError(t, d.Set([]byte("purge/a"), []byte("2"), nil))
+	require.NoError(t, d.Set([]byte("purge/b"), []byte("3"), nil))
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false /* parallelize */))
+
+	// The keys written before the snapshot are visible to it, and are not
+	// filtered. The removal of purge/a#2 must not expose purge/a#1.
+	check(snap, map[string]string{"a": "old", "purge/a": "1"})
+	check(d, map[string]string{"a": "old"})
+	require.NoError(t, snap.Close())
+
+	filter.mu.Lock()
+	require.NotEmpty(t, filter.contexts)
+	require.True(t, filter.contexts[0].IsFlush)
+	filter.contexts = filter.contexts[:0]
+	filter.mu.Unlock()
+
+	// Once the snapshot is closed, all keys are filtered. Write an overlapping
+	// key so that the compaction rewrites the existing sstable.
+	require.NoError(t, d.Set([]byte("b"), []byte("x"), nil))
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false /* parallelize */))
+	check(d, map[string]string{"a": "new"})
+	filter.mu.Lock()
+	defer filter.mu.Unlock()
+	var compactionContexts int
+	for _, ctx := range filter.contexts {
+		if !ctx.IsFlush {
+			compactionContexts++
+			require.Equal(t, CompactionFilterContext{OutputLevel: numLevels - 1, IsBottommost: true}, ctx)
+		}
+	}
+	require.NotZero(t, compactionContexts)
+}
@@ -6,10 +6,13 @@ package pebble
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
@@ -38,6 +41,8 @@ type compactionEnv struct {
 	earliestSnapshotSeqNum  base.SeqNum
 	inProgressCompactions   []compactionInfo
 	readCompactionEnv       readCompactionEnv
+	// now is the current time. It is used to determine the age of tables.
+	now time.Time
 }
 
 type compactionPicker interface {
@@ -51,6 +56,75 @@ type compactionPicker interface {
 	forceBaseLevel1()
 }
 
+// CompactionPicker determines the shape of the LSM by picking the automatic
+// compactions of a DB (see Options.Experimental.CompactionPicker). It is
+// created by LeveledCompactionPicker, TieredCompactionPicker or
+// FIFOCompactionPicker; the zero value is the leveled picker. Manual
+// compactions, download compactions and ingestions target the base level
+// chosen by the picker.
+type CompactionPicker struct {
+	kind   compactionPickerKind
+	tiered TieredCompactionOptions
+	fifo   FIFOCompactionOptions
+}
+
+type compactionPickerKind int8
+
+const (
+	compactionPickerKindLeveled compactionPickerKind = iota
+	compactionPickerKindTiered
+	compactionPickerKindFIFO
+)
+
+// LeveledCompactionPicker returns the default compaction picker. It maintains
+// a leveled LSM, compacting levels which exceed their dynamically adjusted
+// target sizes into the next level.
+func LeveledCompactionPicker() CompactionPicker {
+	return CompactionPicker{kind: compactionPickerKindLeveled}
+}
+
+// TieredCompactionPicker returns a compaction picker that maintains a tiered
+// LSM (see TieredCompactionOptions).
+func TieredCompactionPicker(opts TieredCompactionOptions) CompactionPicker {
+	return CompactionPicker{kind: compactionPickerKindTiered, tiered: opts}
+}
+
+// FIFOCompactionPicker returns a compaction picker that evicts the oldest
+// tables instead of merging them (see FIFOCompactionOptions).
+func FIFOCompactionPicker(opts FIFOCompactionOptions) CompactionPicker {
+	return CompactionPicker{kind: compactionPickerKindFIFO, fifo: opts}
+}
+
+// String implements fmt.Stringer.
+func (p CompactionPicker) String() string {
+	switch p.kind {
+	case compactionPickerKindTiered:
+		return p.tiered.String()
+	case compactionPickerKindFIFO:
+		return p.fifo.String()
+	default:
+		return "leveled"
+	}
+}
+
+// newCompactionPicker creates the picker configured by opts for the newest
+// version. A new picker is created every time a new version is installed.
+func newCompactionPicker(
+	v *version,
+	virtualBackings *manifest.VirtualBackings,
+	opts *Options,
+	inProgressCompactions []compactionInfo,
+) compactionPicker {
+	switch p := opts.Experimental.CompactionPicker; p.kind {
+	case compactionPickerKindTiered:
+		return p.tiered.newPicker(v, virtualBackings, opts, inProgressCompactions)
+	case compactionPickerKindFIFO:
+		return p.fifo.newPicker(v, virtualBackings, opts)
+	default:
+		return newCompactionPickerByScore(v, virtualBackings, opts, inProgressCompactions)
+	}
+}
+
 // readCompactionEnv is used to hold data required to perform read compactions
 type readCompactionEnv struct {
 	rescheduleReadCompaction *bool
@@ -220,6 +294,13 @@ type pickedCompaction struct {
 	// overlap in its output level with. If the overlap is greater than
 	// maxReadCompaction bytes, then we don't proceed with the compaction.
 	maxReadCompactionBytes uint64
+	// rewriteBlobFiles holds the blob files that a blob-rewrite compaction
+	// should rewrite.
+	rewriteBlobFiles []base.DiskFileNum
+	// evictInputs is set by the FIFO compaction picker on the delete-only
+	// compactions it picks. Such a compaction deletes its inputs outright and
+	// is reported as an eviction.
+	evictInputs bool
 
 	// The boundaries of the input data.
 	smallest      InternalKey
@@ -623,14 +704,15 @@ func (c *candidateLevelInfo) shouldCompact() bool {
 }
 
 func fileCompensation(f *tableMetadata) uint64 {
-	return uint64(f.Stats.PointDeletionsBytesEstimate) + f.Stats.RangeDeletionsBytesEstimate
+	return uint64(f.Stats.PointDeletionsBytesEstimate) + f.Stats.RangeDeletionsBytesEstimate +
+		f.Stats.ExpiredBytesEstimate
 }
 
 // compensatedSize returns f's file size, inflated according to compaction
 // priorities.
 func compensatedSize(f *tableMetadata) uint64 {
 	// Add in the estimate of disk space that may be reclaimed by compacting the
-	// file's tombstones.
+	// file's tombstones and expired keys.
 	return f.Size + fileCompensation(f)
 }
 
@@ -1153,6 +1235,23 @@ func responsibleForGarbageBytes(
 	return uint64(totalGarbage) / uint64(useCount)
 }
 
+// allowConcurrentCompaction returns true if another automatic compaction may
+// be started while n compactions are in progress.
+func allowConcurrentCompaction(opts *Options, vers *version, n int, compactionDebt uint64) bool {
+	// Compaction concurrency is controlled by L0 read-amp. We allow one
+	// additional compaction per L0CompactionConcurrency sublevels, as well as
+	// one additional compaction per CompactionDebtConcurrency bytes of
+	// compaction debt. Compaction concurrency is tied to L0 sublevels as that
+	// signal is independent of the database size. We tack on the compaction
+	// debt as a second signal to prevent compaction concurrency from dropping
+	// significantly right after a base compaction finishes, and before those
+	// bytes have been compacted further down the LSM.
+	l0ReadAmp := vers.L0Sublevels.MaxDepthAfterOngoingCompactions()
+	ccSignal1 := n * opts.Experimental.L0CompactionConcurrency
+	ccSignal2 := uint64(n) * opts.Experimental.CompactionDebtConcurrency
+	return l0ReadAmp >= ccSignal1 || compactionDebt >= ccSignal2
+}
+
 // pickAuto picks the best compaction, if any.
 //
 // On each call, pickAuto computes per-level size adjustments based on
@@ -1163,22 +1262,9 @@ func responsibleForGarbageBytes(
 // If a score-based compaction cannot be found, pickAuto falls back to looking
 // for an elision-only compaction to remove obsolete keys.
 func (p *compactionPickerByScore) pickAuto(env compactionEnv) (pc *pickedCompaction) {
-	// Compaction concurrency is controlled by L0 read-amp. We allow one
-	// additional compaction per L0CompactionConcurrency sublevels, as well as
-	// one additional compaction per CompactionDebtConcurrency bytes of
-	// compaction debt. Compaction concurrency is tied to L0 sublevels as that
-	// signal is independent of the database size. We tack on the compaction
-	// debt as a second signal to prevent compaction concurrency from dropping
-	// significantly right after a base compaction finishes, and before those
-	// bytes have been compacted further down the LSM.
-	if n := len(env.inProgressCompactions); n > 0 {
-		l0ReadAmp := p.vers.L0Sublevels.MaxDepthAfterOngoingCompactions()
-		compactionDebt := p.estimatedCompactionDebt(0)
-		ccSignal1 := n * p.opts.Experimental.L0CompactionConcurrency
-		ccSignal2 := uint64(n) * p.opts.Experimental.CompactionDebtConcurrency
-		if l0ReadAmp < ccSignal1 && compactionDebt < ccSignal2 {
-			return nil
-		}
+	if n := len(env.inProgressCompactions); n > 0 &&
+		!allowConcurrentCompaction(p.opts, p.vers, n, p.estimatedCompactionDebt(0)) {
+		return nil
 	}
 
 	scores := p.calculateLevelScores(env.inProgressCompactions)
@@ -1299,6 +1385,12 @@ func (p *compactionPickerByScore) pickAuto(env compactionEnv) (pc *pickedCompact
 		return pc
 	}
 
+	// Check for blob files that are mostly garbage. Like elision-only
+	// compactions, these compactions only reclaim disk space.
+	if pc := p.pickBlobFileRewriteCompaction(env); pc != nil {
+		return pc
+	}
+
 	if pc := p.pickReadTriggeredCompaction(env); pc != nil {
 		return pc
 	}
@@ -1384,8 +1476,9 @@ var elisionOnlyAnnotator = &manifest.Annotator[tableMetadata]{
 			}
 			// Bottommost files are large and not worthwhile to compact just
 			// to remove a few tombstones. Consider a file eligible only if
-			// either its own range deletions delete at least 10% of its data or
-			// its deletion tombstones make at least 10% of its entries.
+			// either its own range deletions delete at least 10% of its data,
+			// its deletion tombstones make at least 10% of its entries or its
+			// expired keys make up at least 10% of its data.
 			//
 			// TODO(jackson): This does not account for duplicate user keys
 			// which may be collapsed. Ideally, we would have 'obsolete keys'
@@ -1396,7 +1489,9 @@ var elisionOnlyAnnotator = &manifest.Annotator[tableMetadata]{
 			// `NumEntries` and `RangeDeletionsBytesEstimate` are both zero) are excluded
 			// from elision-only compactions.
 			// TODO(travers): Consider an alternative heuristic for elision of range-keys.
-			return f.Stats.RangeDeletionsBytesEstimate*10 >= f.Size || f.Stats.NumDeletions*10 > f.Stats.NumEntries, true
+			return f.Stats.RangeDeletionsBytesEstimate*10 >= f.Size ||
+				f.Stats.NumDeletions*10 > f.Stats.NumEntries ||
+				(f.Stats.ExpiredBytesEstimate > 0 && f.Stats.ExpiredBytesEstimate*10 >= f.Size), true
 		},
 		Compare: func(f1 *tableMetadata, f2 *tableMetadata) bool {
 			return f1.LargestSeqNum < f2.LargestSeqNum
@@ -1503,6 +1598,69 @@ func (p *compactionPickerByScore) pickRewriteCompaction(env compactionEnv) (pc *
 	return nil
 }
 
+// pickBlobFileRewriteCompaction looks for blob files in which the fraction of
+// values no longer referenced by the current version is at least
+// ValueSeparationPolicy.GarbageRatioThreshold. If it finds one, it constructs a
+// compaction that rewrites in place a sstable referencing the blob file,
+// prioritizing the blob files with the highest garbage ratios. The compaction
+// copies the sstable's referenced values into a new blob file; once all the
+// sstables referencing the original blob file have been rewritten, the blob
+// file is deleted.
+func (p *compactionPickerByScore) pickBlobFileRewriteCompaction(
+	env compactionEnv,
+) (pc *pickedCompaction) {
+	if len(p.vers.BlobFileGarbageRatios) == 0 || p.opts.Experimental.ValueSeparationPolicy == nil {
+		return nil
+	}
+	policy := p.opts.Experimental.ValueSeparationPolicy()
+	if !policy.Enabled || policy.GarbageRatioThreshold <= 0 {
+		return nil
+	}
+
+	type candidate struct {
+		fileNum      base.DiskFileNum
+		garbageRatio float64
+	}
+	var candidates []candidate
+	for fileNum, r := range p.vers.BlobFileGarbageRatios {
+		if r >= policy.GarbageRatioThreshold {
+			candidates = append(candidates, candidate{fileNum: fileNum, garbageRatio: r})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if v := cmp.Compare(b.garbageRatio, a.garbageRatio); v != 0 {
+			return v
+		}
+		return cmp.Compare(a.fileNum, b.fileNum)
+	})
+	rewriteBlobFiles := make([]base.DiskFileNum, len(candidates))
+	for i := range candidates {
+		rewriteBlobFiles[i] = candidates[i].fileNum
+	}
+	slices.Sort(rewriteBlobFiles)
+
+	for _, c := range candidates {
+		for l := numLevels - 1; l >= 0; l-- {
+			iter := p.vers.Levels[l].Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				if f.IsCompacting() || !slices.ContainsFunc(f.BlobReferences, func(ref manifest.BlobReference) bool {
+					return ref.FileNum == c.fileNum
+				}) {
+					continue
+				}
+				if pc := p.pickedCompactionFromCandidateFile(f, env, l, l, compactionKindBlobFileRewrite); pc != nil {
+					pc.rewriteBlobFiles = rewriteBlobFiles
+					return pc
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // pickTombstoneDensityCompaction looks for a compaction that eliminates
 // regions of extremely high point tombstone density. For each level, it picks
 // a file where the ratio of tombstone-dense blocks is at least
@@ -1821,6 +1979,8 @@ func pickManualCompaction(
 // pickDownloadCompaction picks a download compaction for the downloadSpan,
 // which could be specified as being performed either by a copy compaction of
 // the backing file or a rewrite compaction.
+// It is also used to pick upload compactions, which (like download
+// compactions) have a single input file and output to the same level.
 func pickDownloadCompaction(
 	vers *version,
 	opts *Options,
@@ -1834,8 +1994,8 @@ func pickDownloadCompaction(
 	if file.CompactionState == manifest.CompactionStateCompacting {
 		return nil
 	}
-	if kind != compactionKindCopy && kind != compactionKindRewrite {
-		panic("invalid download/rewrite compaction kind")
+	if kind != compactionKindCopy && kind != compactionKindRewrite && kind != compactionKindUpload {
+		panic("invalid download/rewrite/upload compaction kind")
 	}
 	pc = newPickedCompaction(opts, vers, level, level, baseLevel)
 	pc.kind = kind
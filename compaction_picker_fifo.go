@@ -0,0 +1,223 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+// FIFOEvictionOrder determines the order in which a FIFOCompactionPicker
+// evicts tables.
+type FIFOEvictionOrder int8
+
+const (
+	// FIFOEvictByLargestSeqNum evicts the tables with the smallest largest
+	// sequence number first, i.e. the tables whose newest key is the oldest.
+	FIFOEvictByLargestSeqNum FIFOEvictionOrder = iota
+	// FIFOEvictByCreationTime evicts the tables with the oldest creation time
+	// first. Note that the creation time of a table is reset when it is
+	// rewritten by a compaction.
+	FIFOEvictByCreationTime
+)
+
+// String implements fmt.Stringer.
+func (o FIFOEvictionOrder) String() string {
+	switch o {
+	case FIFOEvictByLargestSeqNum:
+		return "largest-seqnum"
+	case FIFOEvictByCreationTime:
+		return "creation-time"
+	default:
+		return fmt.Sprintf("FIFOEvictionOrder(%d)", int8(o))
+	}
+}
+
+// FIFOCompactionOptions configures a FIFOCompactionPicker, which is intended
+// for data that is only retained for a limited time or up to a limited size,
+// such as time-series or log data. The picker never merges tables: flushed
+// tables stay in L0, and once the total size of the tables exceeds MaxSize or
+// the oldest tables are older than MaxAge, the oldest tables are evicted by
+// delete-only compactions. It is similar to RocksDB's FIFO compaction.
+//
+// Evicted tables are deleted regardless of open snapshots and of newer
+// tables, so the keys of an evicted table disappear from the DB, and keys
+// shadowed by the evicted keys (e.g. by a deletion) may reappear. Evictions
+// are reported through EventListener.TableDeleted with the "evicted" reason.
+//
+// Since tables are never compacted out of L0, the read amplification of L0
+// grows with the number of tables whose key ranges overlap. Workloads with
+// overlapping keys should raise Options.L0StopWritesThreshold to avoid
+// stalling writes.
+type FIFOCompactionOptions struct {
+	// MaxSize is the maximum total size of the tables in the LSM. The oldest
+	// tables are evicted once it is exceeded. No size limit is enforced if
+	// zero.
+	MaxSize uint64
+
+	// MaxAge is the maximum age of a table, based on its creation time. Tables
+	// are evicted in EvictionOrder, so with FIFOEvictByLargestSeqNum a table
+	// that is older than MaxAge is only evicted once all the tables before it
+	// are evicted. The age of the tables is checked whenever compactions are
+	// scheduled (e.g. after flushes). No age limit is enforced if zero.
+	MaxAge time.Duration
+
+	// EvictionOrder is the order in which tables are evicted.
+	EvictionOrder FIFOEvictionOrder
+}
+
+// newPicker returns the compactionPicker for the provided version.
+func (f FIFOCompactionOptions) newPicker(
+	v *version, virtualBackings *manifest.VirtualBackings, opts *Options,
+) compactionPicker {
+	return &compactionPickerFIFO{
+		compactionPickerByScore: &compactionPickerByScore{
+			opts:            opts,
+			vers:            v,
+			virtualBackings: virtualBackings,
+			// Ingestions and manual compactions target the last level.
+			baseLevel: numLevels - 1,
+		},
+		config: f,
+	}
+}
+
+// String implements fmt.Stringer.
+func (f FIFOCompactionOptions) String() string {
+	return fmt.Sprintf("fifo(%d, %s, %s)", f.MaxSize, f.MaxAge, f.EvictionOrder)
+}
+
+// compactionPickerFIFO is the compactionPicker of a FIFOCompactionPicker. It
+// embeds a compactionPickerByScore for the compactions that rewrite tables in
+// place: blob file rewrite and rewrite compactions.
+type compactionPickerFIFO struct {
+	*compactionPickerByScore
+	config FIFOCompactionOptions
+}
+
+var _ compactionPicker = &compactionPickerFIFO{}
+
+// getScores implements compactionPicker. Levels are never compacted into
+// other levels, so all scores are zero.
+func (p *compactionPickerFIFO) getScores([]compactionInfo) [numLevels]float64 {
+	return [numLevels]float64{}
+}
+
+// estimatedCompactionDebt implements compactionPicker. Evictions don't write
+// any data, so there is no compaction debt.
+func (p *compactionPickerFIFO) estimatedCompactionDebt(uint64) uint64 {
+	return 0
+}
+
+// pickElisionOnlyCompaction implements compactionPicker. Tombstones are never
+// elided, as evictions may expose the keys they delete.
+func (p *compactionPickerFIFO) pickElisionOnlyCompaction(compactionEnv) *pickedCompaction {
+	return nil
+}
+
+// pickReadTriggeredCompaction implements compactionPicker.
+func (p *compactionPickerFIFO) pickReadTriggeredCompaction(compactionEnv) *pickedCompaction {
+	return nil
+}
+
+// pickAuto implements compactionPicker. Evictions take priority over rewrite
+// compactions.
+func (p *compactionPickerFIFO) pickAuto(env compactionEnv) *pickedCompaction {
+	if pc := p.pickEviction(env); pc != nil {
+		return pc
+	}
+	if pc := p.pickBlobFileRewriteCompaction(env); pc != nil {
+		return pc
+	}
+	if p.vers.Stats.MarkedForCompaction > 0 {
+		if pc := p.pickRewriteCompaction(env); pc != nil {
+			return pc
+		}
+	}
+	return nil
+}
+
+// pickEviction picks a delete-only compaction that evicts the oldest tables,
+// or returns nil if no tables need to be evicted. Tables that are being
+// compacted are skipped; their size doesn't count towards MaxSize, as they
+// are either being evicted or being rewritten in place.
+func (p *compactionPickerFIFO) pickEviction(env compactionEnv) *pickedCompaction {
+	type levelTable struct {
+		level int
+		meta  *tableMetadata
+	}
+	var tables []levelTable
+	var totalSize uint64
+	for level := range p.vers.Levels {
+		iter := p.vers.Levels[level].Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			if f.IsCompacting() {
+				continue
+			}
+			tables = append(tables, levelTable{level: level, meta: f})
+			totalSize += f.Size
+		}
+	}
+	slices.SortFunc(tables, func(a, b levelTable) int {
+		var c int
+		switch p.config.EvictionOrder {
+		case FIFOEvictByCreationTime:
+			c = cmp.Compare(a.meta.CreationTime, b.meta.CreationTime)
+		default:
+			c = cmp.Compare(a.meta.LargestSeqNum, b.meta.LargestSeqNum)
+		}
+		if c != 0 {
+			return c
+		}
+		return cmp.Compare(a.meta.FileNum, b.meta.FileNum)
+	})
+
+	var evicted [numLevels][]*tableMetadata
+	var evictedAny bool
+	for _, t := range tables {
+		overSize := p.config.MaxSize > 0 && totalSize > p.config.MaxSize
+		expired := p.config.MaxAge > 0 && !env.now.IsZero() &&
+			env.now.Sub(time.Unix(t.meta.CreationTime, 0)) > p.config.MaxAge
+		if !overSize && !expired {
+			break
+		}
+		evicted[t.level] = append(evicted[t.level], t.meta)
+		evictedAny = true
+		totalSize -= t.meta.Size
+	}
+	if !evictedAny {
+		return nil
+	}
+
+	pc := &pickedCompaction{
+		cmp:         p.opts.Comparer.Compare,
+		kind:        compactionKindDeleteOnly,
+		version:     p.vers,
+		baseLevel:   p.baseLevel,
+		evictInputs: true,
+	}
+	iters := make([]manifest.LevelIterator, 0, numLevels)
+	for level, files := range evicted {
+		if len(files) == 0 {
+			continue
+		}
+		cl := compactionLevel{level: level}
+		if level == 0 {
+			cl.files = manifest.NewLevelSliceSeqSorted(files)
+		} else {
+			cl.files = manifest.NewLevelSliceKeySorted(p.opts.Comparer.Compare, files)
+		}
+		pc.inputs = append(pc.inputs, cl)
+		iters = append(iters, cl.files.Iter())
+	}
+	pc.startLevel = &pc.inputs[0]
+	pc.outputLevel = &pc.inputs[len(pc.inputs)-1]
+	pc.smallest, pc.largest = manifest.KeyRange(p.opts.Comparer.Compare, iters...)
+	return pc
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIFOCompactionPicker(t *testing.T) {
+	const maxSize = 8 << 10
+	var mu sync.Mutex
+	var deleted []TableDeleteInfo
+	opts := &Options{
+		FS:                    vfs.NewMem(),
+		L0StopWritesThreshold: 1000,
+		FormatMajorVersion:    FormatNewest,
+		EventListener: &EventListener{
+			TableDeleted: func(info TableDeleteInfo) {
+				mu.Lock()
+				defer mu.Unlock()
+				deleted = append(deleted, info)
+			},
+		},
+	}
+	opts.Experimental.CompactionPicker = FIFOCompactionPicker(FIFOCompactionOptions{
+		MaxSize: maxSize,
+		MaxAge:  time.Hour,
+	})
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	waitForCompactions := func() {
+		d.mu.Lock()
+		for d.mu.compact.compactingCount > 0 {
+			d.mu.compact.cond.Wait()
+		}
+		d.mu.Unlock()
+		d.cleanupManager.Wait()
+	}
+	// writeBatch writes a batch of keys prefixed by i into its own table.
+	writeBatch := func(i int) {
+		for j := 0; j < 100; j++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("%03d-%03d", i, j)), make([]byte, 32), nil))
+		}
+		require.NoError(t, d.Flush())
+		waitForCompactions()
+	}
+	// batches returns the batches of the keys in the DB.
+	batches := func() (first, last, n int) {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, iter.Close()) }()
+		first = -1
+		for valid := iter.First(); valid; valid = iter.Next() {
+			var i, j int
+			_, err := fmt.Sscanf(string(iter.Key()), "%03d-%03d", &i, &j)
+			require.NoError(t, err)
+			if first < 0 {
+				first = i
+			}
+			last = i
+			n++
+		}
+		return first, last, n
+	}
+
+	const numBatches = 20
+	for i := 0; i < numBatches; i++ {
+		writeBatch(i)
+	}
+	m := d.Metrics()
+	var size, compacted uint64
+	for l := range m.Levels {
+		size += uint64(m.Levels[l].Size)
+		compacted += m.Levels[l].BytesCompacted
+	}
+	require.LessOrEqual(t, size, uint64(maxSize))
+	require.Zero(t, compacted)
+	require.NotZero(t, m.Compact.DeleteOnlyCount)
+
+	// Only the oldest batches are evicted.
+	first, last, n := batches()
+	require.Greater(t, first, 0)
+	require.Equal(t, numBatches-1, last)
+	require.Equal(t, (last-first+1)*100, n)
+
+	mu.Lock()
+	require.NotEmpty(t, deleted)
+	for _, info := range deleted {
+		require.Equal(t, "evicted", info.Reason)
+		require.Contains(t, info.String(), "sstable evicted")
+	}
+	deleted = nil
+	mu.Unlock()
+
+	// Once all the tables are older than MaxAge, they are evicted the next
+	// time compactions are scheduled.
+	d.mu.Lock()
+	d.timeNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	d.mu.Unlock()
+	writeBatch(numBatches)
+	_, _, n = batches()
+	require.Zero(t, n)
+	mu.Lock()
+	require.Len(t, deleted, numBatches-first+1)
+	mu.Unlock()
+}
+
+func TestFIFOCompactionPickerParse(t *testing.T) {
+	for _, o := range []FIFOCompactionOptions{
+		{MaxSize: 1 << 30},
+		{MaxSize: 1 << 20, MaxAge: 24 * time.Hour, EvictionOrder: FIFOEvictByCreationTime},
+	} {
+		opts := &Options{}
+		p := FIFOCompactionPicker(o)
+		opts.Experimental.CompactionPicker = p
+		opts.EnsureDefaults()
+		var parsed Options
+		require.NoError(t, parsed.Parse(opts.String(), nil))
+		require.Equal(t, p, parsed.Experimental.CompactionPicker)
+	}
+
+	var opts Options
+	require.Error(t, opts.Parse("[Options]\n  compaction_picker=fifo(1, 1h, newest)\n", nil))
+}
+
+func TestDeleteOnlyCompactionEvictInputs(t *testing.T) {
+	opts := &Options{}
+	opts.EnsureDefaults()
+	for _, evict := range []bool{false, true} {
+		pc := &pickedCompaction{
+			cmp:         opts.Comparer.Compare,
+			kind:        compactionKindDeleteOnly,
+			version:     manifest.TestingNewVersion(opts.Comparer),
+			evictInputs: evict,
+		}
+		c := newCompaction(pc, opts, time.Now(), nil /* provider */, &base.DefaultCompactionSlot{})
+		require.Equal(t, evict, c.evictInputs)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+const (
+	defaultTieredSizeRatio                   = 1
+	defaultTieredMaxSizeAmplificationPercent = 200
+)
+
+// TieredCompactionOptions configures a TieredCompactionPicker, which
+// maintains a tiered LSM, trading read and space amplification for lower
+// write amplification. It is similar to RocksDB's universal compaction.
+//
+// Each non-empty level below L0 holds a sorted run; the level number orders
+// the runs from newest to oldest. L0 is compacted into a new run in the level
+// above the newest run (or into L1, once the newest run is in L1), using the
+// same L0 sublevel heuristics as the leveled picker. A run is merged into the
+// next older run when it is nearly as large as that run (see SizeRatio), or
+// when the runs other than the oldest one take up too much space relative to
+// the oldest run (see MaxSizeAmplificationPercent). Runs are merged as a
+// whole, so merging compactions may be large.
+//
+// Runs are held in the levels below L0, so there are at most numLevels-1 (six)
+// runs besides L0. Once L1 holds a run, L0 is compacted into that run rather
+// than into a new one, and the runs are merged more often than SizeRatio
+// alone would require.
+type TieredCompactionOptions struct {
+	// SizeRatio is the percentage by which the size of a run is inflated when
+	// comparing it to the size of the next older run: a run is merged into the
+	// next older run if size(run) * (100 + SizeRatio) / 100 >= size(older run).
+	// Defaults to 1 if zero.
+	SizeRatio int
+
+	// MaxSizeAmplificationPercent is the maximum size of L0 and all runs other
+	// than the oldest one, as a percentage of the size of the oldest run.
+	// Once it is exceeded, the second oldest run is merged into the oldest
+	// run. Defaults to 200 if zero.
+	MaxSizeAmplificationPercent int
+}
+
+// newPicker returns the compactionPicker for the provided version.
+func (t TieredCompactionOptions) newPicker(
+	v *version,
+	virtualBackings *manifest.VirtualBackings,
+	opts *Options,
+	inProgressCompactions []compactionInfo,
+) compactionPicker {
+	if t.SizeRatio <= 0 {
+		t.SizeRatio = defaultTieredSizeRatio
+	}
+	if t.MaxSizeAmplificationPercent <= 0 {
+		t.MaxSizeAmplificationPercent = defaultTieredMaxSizeAmplificationPercent
+	}
+	p := &compactionPickerTiered{
+		compactionPickerByScore: &compactionPickerByScore{
+			opts:            opts,
+			vers:            v,
+			virtualBackings: virtualBackings,
+		},
+		config: t,
+	}
+	p.initBaseLevel(inProgressCompactions)
+	return p
+}
+
+// String implements fmt.Stringer.
+func (t TieredCompactionOptions) String() string {
+	return fmt.Sprintf("tiered(%d, %d)", t.SizeRatio, t.MaxSizeAmplificationPercent)
+}
+
+// compactionPickerTiered is the compactionPicker of a TieredCompactionPicker.
+// It embeds a compactionPickerByScore for the compactions that don't depend
+// on the shape of the LSM: elision-only, blob file rewrite, rewrite and
+// read-triggered compactions.
+type compactionPickerTiered struct {
+	*compactionPickerByScore
+	config TieredCompactionOptions
+}
+
+var _ compactionPicker = &compactionPickerTiered{}
+
+// initBaseLevel sets the level that L0 is compacted into: the level above the
+// newest run, or the level of an ongoing L0 compaction into a new run.
+func (p *compactionPickerTiered) initBaseLevel(inProgressCompactions []compactionInfo) {
+	p.baseLevel = numLevels - 1
+	for level := 1; level < numLevels; level++ {
+		if !p.vers.Levels[level].Empty() {
+			p.baseLevel = max(level-1, 1)
+			break
+		}
+	}
+	for _, c := range inProgressCompactions {
+		if c.inputs[0].level == 0 && c.outputLevel > 0 && c.outputLevel < p.baseLevel {
+			p.baseLevel = c.outputLevel
+		}
+	}
+}
+
+// runLevels returns the non-empty levels below L0, from newest to oldest.
+func (p *compactionPickerTiered) runLevels() []int {
+	var levels []int
+	for level := 1; level < numLevels; level++ {
+		if !p.vers.Levels[level].Empty() {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// sizeRatioScore returns the score for merging the run in level into the run
+// in the next older level. The runs should be merged if the score is at least
+// compactionScoreThreshold.
+func (p *compactionPickerTiered) sizeRatioScore(level, olderLevel int) float64 {
+	size := float64(p.vers.Levels[level].Size()) * float64(100+p.config.SizeRatio) / 100
+	return size / float64(max(p.vers.Levels[olderLevel].Size(), 1))
+}
+
+// spaceAmpScore returns the score for merging the second oldest run into the
+// oldest run in levels[len(levels)-1].
+func (p *compactionPickerTiered) spaceAmpScore(levels []int) float64 {
+	oldest := levels[len(levels)-1]
+	var newerSize uint64
+	for level := 0; level < oldest; level++ {
+		newerSize += p.vers.Levels[level].Size()
+	}
+	maxSize := float64(p.vers.Levels[oldest].Size()) * float64(p.config.MaxSizeAmplificationPercent) / 100
+	return float64(newerSize) / max(maxSize, 1)
+}
+
+// getScores implements compactionPicker. The score of a level is the score
+// for compacting it into the next run.
+func (p *compactionPickerTiered) getScores(inProgress []compactionInfo) [numLevels]float64 {
+	var scores [numLevels]float64
+	scores[0] = calculateL0UncompensatedScore(p.vers, p.opts, inProgress)
+	levels := p.runLevels()
+	for i := 0; i+1 < len(levels); i++ {
+		scores[levels[i]] = p.sizeRatioScore(levels[i], levels[i+1])
+	}
+	if n := len(levels); n >= 2 {
+		scores[levels[n-2]] = max(scores[levels[n-2]], p.spaceAmpScore(levels))
+	}
+	return scores
+}
+
+// estimatedCompactionDebt implements compactionPicker. It estimates the
+// number of bytes which need to be compacted by the compactions that the
+// picker would pick for the current version.
+func (p *compactionPickerTiered) estimatedCompactionDebt(l0ExtraSize uint64) uint64 {
+	if p == nil {
+		return 0
+	}
+	var compactionDebt uint64
+	l0Size := l0ExtraSize + p.vers.Levels[0].Size()
+	if baseSize := p.vers.Levels[p.baseLevel].Size(); l0Size > 0 && baseSize > 0 {
+		// As in the leveled picker, an L0 compaction into an empty level is
+		// expected to be cheap.
+		compactionDebt += l0Size + baseSize
+	}
+	scores := p.getScores(nil)
+	levels := p.runLevels()
+	for i := 0; i+1 < len(levels); i++ {
+		if scores[levels[i]] >= compactionScoreThreshold {
+			compactionDebt += p.vers.Levels[levels[i]].Size() + p.vers.Levels[levels[i+1]].Size()
+		}
+	}
+	return compactionDebt
+}
+
+// pickAuto implements compactionPicker. L0 compactions take priority over
+// compactions that reduce space amplification, which take priority over size
+// ratio compactions of the newest runs.
+func (p *compactionPickerTiered) pickAuto(env compactionEnv) (pc *pickedCompaction) {
+	if n := len(env.inProgressCompactions); n > 0 &&
+		!allowConcurrentCompaction(p.opts, p.vers, n, p.estimatedCompactionDebt(0)) {
+		return nil
+	}
+
+	if score := calculateL0UncompensatedScore(p.vers, p.opts, env.inProgressCompactions); score >= compactionScoreThreshold {
+		pc = pickL0(env, p.opts, p.vers, p.baseLevel)
+		// Fail-safe to protect against compacting the same sstable
+		// concurrently.
+		if pc != nil && !inputRangeAlreadyCompacting(env, pc) {
+			pc.score = score
+			return pc
+		}
+	}
+
+	levels := p.runLevels()
+	if n := len(levels); n >= 2 {
+		if score := p.spaceAmpScore(levels); score >= compactionScoreThreshold {
+			if pc := p.pickMerge(env, levels[n-2], levels[n-1]); pc != nil {
+				pc.score = score
+				return pc
+			}
+		}
+	}
+	for i := 0; i+1 < len(levels); i++ {
+		if score := p.sizeRatioScore(levels[i], levels[i+1]); score >= compactionScoreThreshold {
+			if pc := p.pickMerge(env, levels[i], levels[i+1]); pc != nil {
+				pc.score = score
+				return pc
+			}
+		}
+	}
+
+	// The remaining compactions only reclaim disk space or rewrite files in
+	// place, and are picked as they are by the leveled picker.
+	if pc := p.pickElisionOnlyCompaction(env); pc != nil {
+		return pc
+	}
+	if pc := p.pickBlobFileRewriteCompaction(env); pc != nil {
+		return pc
+	}
+	if p.vers.Stats.MarkedForCompaction > 0 {
+		if pc := p.pickRewriteCompaction(env); pc != nil {
+			return pc
+		}
+	}
+	return nil
+}
+
+// pickMerge picks a compaction merging the run in level into the run in
+// olderLevel. The levels in between must be empty.
+func (p *compactionPickerTiered) pickMerge(
+	env compactionEnv, level, olderLevel int,
+) *pickedCompaction {
+	// The output of the compaction skips the levels in between. Don't pick the
+	// compaction while a concurrent compaction writes to these levels, as the
+	// merged data could end up below newer data.
+	for _, c := range env.inProgressCompactions {
+		if c.outputLevel > level && c.outputLevel < olderLevel {
+			return nil
+		}
+	}
+	pc := newPickedCompaction(p.opts, p.vers, level, olderLevel, p.baseLevel)
+	pc.startLevel.files = p.vers.Levels[level].Slice()
+	if !pc.setupInputs(p.opts, env.diskAvailBytes, pc.startLevel) {
+		return nil
+	}
+	// Fail-safe to protect against compacting the same sstable concurrently.
+	if inputRangeAlreadyCompacting(env, pc) {
+		return nil
+	}
+	return pc
+}
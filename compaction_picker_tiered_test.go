@@ -0,0 +1,167 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/datadriven"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactionPickerTiered(t *testing.T) {
+	var vers *version
+	var opts *Options
+	config := TieredCompactionOptions{}
+
+	datadriven.RunTest(t, "testdata/compaction_picker_tiered",
+		func(t *testing.T, d *datadriven.TestData) string {
+			switch d.Cmd {
+			case "init":
+				// See TestCompactionPickerTargetLevel for the format of the
+				// input.
+				var errMsg string
+				vers, opts, errMsg = loadVersion(t, d)
+				if errMsg != "" {
+					return errMsg
+				}
+				return runVersionFileSizes(vers)
+
+			case "config":
+				config = TieredCompactionOptions{}
+				d.MaybeScanArgs(t, "size-ratio", &config.SizeRatio)
+				d.MaybeScanArgs(t, "max-size-amp", &config.MaxSizeAmplificationPercent)
+				return config.String()
+
+			case "pick":
+				// Picks compactions until none are picked, treating the
+				// picked compactions as in progress.
+				var ongoing []int
+				d.MaybeScanArgs(t, "ongoing-l0", &ongoing)
+				var inProgress []compactionInfo
+				for _, l := range ongoing {
+					inProgress = append(inProgress, compactionInfo{
+						inputs:      []compactionLevel{{level: 0}, {level: l}},
+						outputLevel: l,
+					})
+				}
+				vb := manifest.MakeVirtualBackings()
+				p := config.newPicker(vers, &vb, opts, inProgress).(*compactionPickerTiered)
+
+				var b strings.Builder
+				fmt.Fprintf(&b, "base: %d\n", p.getBaseLevel())
+				fmt.Fprintf(&b, "scores:")
+				for l, score := range p.getScores(inProgress) {
+					if score > 0 {
+						fmt.Fprintf(&b, " L%d=%.2f", l, score)
+					}
+				}
+				fmt.Fprintf(&b, "\ndebt: %d\n", p.estimatedCompactionDebt(0))
+				for {
+					pc := p.pickAuto(compactionEnv{
+						diskAvailBytes:          math.MaxUint64,
+						earliestUnflushedSeqNum: base.SeqNumMax,
+						inProgressCompactions:   inProgress,
+					})
+					if pc == nil {
+						break
+					}
+					fmt.Fprintf(&b, "L%d->L%d: %.2f\n", pc.startLevel.level, pc.outputLevel.level, pc.score)
+					inProgress = append(inProgress, compactionInfo{
+						inputs:      pc.inputs,
+						outputLevel: pc.outputLevel.level,
+						smallest:    pc.smallest,
+						largest:     pc.largest,
+					})
+					if pc.outputLevel.level == 0 {
+						break
+					}
+					for _, cl := range pc.inputs {
+						cl.files.Each(func(f *tableMetadata) {
+							f.CompactionState = manifest.CompactionStateCompacting
+						})
+					}
+				}
+				for _, files := range vers.Levels {
+					files.Slice().Each(func(f *tableMetadata) {
+						f.CompactionState = manifest.CompactionStateNotCompacting
+					})
+				}
+				return b.String()
+
+			default:
+				return fmt.Sprintf("unknown command: %s", d.Cmd)
+			}
+		})
+}
+
+func TestTieredCompactionPickerDB(t *testing.T) {
+	const batches = 20
+	key := func(i, j int) []byte { return []byte(fmt.Sprintf("%03d-%03d", j, i)) }
+	run := func(picker CompactionPicker) *Metrics {
+		opts := &Options{
+			FS:                    vfs.NewMem(),
+			L0CompactionThreshold: 2,
+			FormatMajorVersion:    FormatNewest,
+		}
+		opts.Experimental.CompactionPicker = picker
+		d, err := Open("", opts)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, d.Close()) }()
+
+		for i := 0; i < batches; i++ {
+			for j := 0; j < 100; j++ {
+				require.NoError(t, d.Set(key(i, j), []byte(fmt.Sprint(i)), nil))
+			}
+			require.NoError(t, d.Flush())
+		}
+		d.mu.Lock()
+		for d.mu.compact.compactingCount > 0 {
+			d.mu.compact.cond.Wait()
+		}
+		d.mu.Unlock()
+		m := d.Metrics()
+
+		check := func() {
+			iter, err := d.NewIter(nil)
+			require.NoError(t, err)
+			var n int
+			for valid := iter.First(); valid; valid = iter.Next() {
+				n++
+			}
+			require.Equal(t, batches*100, n)
+			require.NoError(t, iter.Close())
+		}
+		check()
+
+		// A manual compaction of the whole keyspace compacts all of the data
+		// into the last level.
+		require.NoError(t, d.Compact([]byte("0"), []byte("9"), false))
+		after := d.Metrics()
+		for l := 0; l < numLevels-1; l++ {
+			require.Zero(t, after.Levels[l].NumFiles, "L%d", l)
+		}
+		require.NotZero(t, after.Levels[numLevels-1].NumFiles)
+		check()
+		return m
+	}
+
+	compacted := func(m *Metrics) (n uint64) {
+		for l := range m.Levels {
+			n += m.Levels[l].BytesCompacted
+		}
+		return n
+	}
+	leveled := run(LeveledCompactionPicker())
+	tiered := run(TieredCompactionPicker(TieredCompactionOptions{}))
+	t.Logf("leveled:\n%s\ntiered:\n%s", leveled, tiered)
+	require.Less(t, compacted(tiered), compacted(leveled))
+}
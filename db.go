@@ -321,6 +321,9 @@ type DB struct {
 
 	// objProvider is used to access and manage SSTs.
 	objProvider objstorage.Provider
+	// secondaryBlockCache is set if Options.Experimental.SecondaryBlockCache is
+	// configured; it is the secondary cache of cacheHandle.
+	secondaryBlockCache *secondaryBlockCache
 
 	fileLock *Lock
 	dataDir  vfs.File
@@ -341,6 +344,28 @@ type DB struct {
 	closed   *atomic.Value
 	closedCh chan struct{}
 
+	changefeedNotifier changefeedNotifier
+
+	// follower is set if the DB was opened with OpenFollower.
+	follower *follower
+
+	// keyspaces holds the keyspaces of the DB, in the order of
+	// Options.Keyspaces. See keyspace.go.
+	keyspaces []*Keyspace
+
+	// keyspace is set on the DB of a keyspace, whose writes are logged to the
+	// WAL of its parent DB.
+	keyspace struct {
+		// parent is the DB whose WAL logs the keyspace's writes. It is set once
+		// the parent has recovered the keyspace's unflushed writes.
+		parent atomic.Pointer[DB]
+		// minUnflushedLogNum is the number of the oldest WAL of the parent
+		// that holds writes not yet flushed by the keyspace. It mirrors
+		// versionSet.minUnflushedLogNum so that the parent can read it
+		// without acquiring the keyspace's mutex.
+		minUnflushedLogNum atomic.Uint64
+	}
+
 	cleanupManager *cleanupManager
 
 	// During an iterator close, we may asynchronously schedule read compactions.
@@ -413,6 +438,9 @@ type DB struct {
 			}
 		}
 
+		// changefeeds holds the open changefeeds, and the WALs they may read.
+		changefeeds changefeedState
+
 		mem struct {
 			// The current mutable memTable. Readers of the pointer may hold
 			// either DB.mu or commitPipeline.mu.
@@ -444,10 +472,14 @@ type DB struct {
 			cond sync.Cond
 			// True when a flush is in progress.
 			flushing bool
-			// The number of ongoing non-download compactions.
+			// The number of ongoing non-download, non-upload compactions.
 			compactingCount int
 			// The number of download compactions.
 			downloadingCount int
+			// The number of upload compactions (at most one).
+			uploadingCount int
+			// upload is the state of upload compactions. See upload.go.
+			upload uploadState
 			// The list of deletion hints, suggesting ranges for delete-only
 			// compactions.
 			deletionHints []deleteCompactionHint
@@ -513,6 +545,10 @@ type DB struct {
 			// active stat collection goroutine clears the list and processes
 			// them.
 			pending []manifest.NewTableEntry
+			// expiryRefreshAt is the earliest time at which the
+			// ExpiredBytesEstimate stats of the tables are refreshed again
+			// (see DB.maybeRefreshExpiredBytesLocked).
+			expiryRefreshAt time.Time
 		}
 
 		tableValidation struct {
@@ -593,6 +629,23 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 		seqNum = d.mu.versions.visibleSeqNum.Load()
 	}
 
+	i := d.newGetIterator(key, b, readState, seqNum)
+	if !i.First() {
+		err := i.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrNotFound
+	}
+	return i.Value(), i, nil
+}
+
+// newGetIterator returns an Iterator that looks up the provided key, reading
+// the provided batch, if non-nil, and readState at seqNum. The Iterator takes
+// over the caller's reference on readState.
+func (d *DB) newGetIterator(
+	key []byte, b *Batch, readState *readState, seqNum base.SeqNum,
+) *Iterator {
 	buf := getIterAllocPool.Get().(*getIterAlloc)
 
 	get := &buf.get
@@ -634,18 +687,13 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 		pointIter:    pointIter,
 		merge:        d.merge,
 		comparer:     *d.opts.Comparer,
+		ttl:          d.newTTLChecker(),
 		readState:    readState,
 		keyBuf:       buf.keyBuf,
 	}
-
-	if !i.First() {
-		err := i.Close()
-		if err != nil {
-			return nil, nil, err
-		}
-		return nil, nil, ErrNotFound
-	}
-	return i.Value(), i, nil
+	i.blobValueFetcher.Init(d.fileCache, block.ReadEnv{})
+	get.blobValueFetcher = &i.blobValueFetcher
+	return i
 }
 
 // Set sets the value for the given key. It overwrites any previous value
@@ -877,6 +925,9 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 			return errNoSplit
 		}
 	}
+	if batch.keyspaces != nil && batch.validateCommit != nil {
+		return errors.New("pebble: batches writing to keyspaces cannot be validated")
+	}
 	batch.committing = true
 
 	if batch.db == nil {
@@ -884,18 +935,32 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 			return err
 		}
 	}
-	if batch.memTableSize >= d.largeBatchThreshold {
-		var err error
+	var err error
+	// The writes to keyspaces are appended to the batch while it commits, so a
+	// batch writing to keyspaces is never flushed as a large batch.
+	if batch.memTableSize >= d.largeBatchThreshold && batch.keyspaces == nil {
 		batch.flushable, err = newFlushableBatch(batch, d.opts.Comparer)
 		if err != nil {
 			return err
 		}
 	}
-	if err := d.commit.Commit(batch, sync, noSyncWait); err != nil {
+	if batch.keyspaces != nil {
+		err = d.commitKeyspaces(batch, sync, noSyncWait)
+	} else {
+		err = d.commit.Commit(batch, sync, noSyncWait)
+	}
+	if err != nil {
+		if errors.Is(err, errCommitValidation) {
+			// The batch failed validation and was not committed.
+			batch.committing = false
+			batch.flushable = nil
+			return err
+		}
 		// There isn't much we can do on an error here. The commit pipeline will be
 		// horked at this point.
 		d.opts.Logger.Fatalf("pebble: fatal commit error: %v", err)
 	}
+	d.changefeedNotifier.notify()
 	// If this is a large batch, we need to clear the batch contents as the
 	// flushable batch may still be present in the flushables queue.
 	//
@@ -1113,15 +1178,22 @@ func (d *DB) newIter(
 		newIterRangeKey = d.tableNewRangeKeyIter
 	}
 
+	var tracing *iterTracing
+	if !newIterOpts.batch.batchOnly {
+		ctx, tracing = d.maybeStartIterSpan(ctx)
+	}
+
 	// Bundle various structures under a single umbrella in order to allocate
 	// them together.
 	buf := iterAllocPool.Get().(*iterAlloc)
 	dbi := &buf.dbi
 	*dbi = Iterator{
 		ctx:                 ctx,
+		tracing:             tracing,
 		alloc:               buf,
 		merge:               d.merge,
 		comparer:            *d.opts.Comparer,
+		ttl:                 d.newTTLChecker(),
 		readState:           readState,
 		version:             newIterOpts.snapshot.vers,
 		keyBuf:              buf.keyBuf,
@@ -1380,6 +1452,9 @@ type internalIterOpts struct {
 	compaction         bool
 	readEnv            block.ReadEnv
 	boundLimitedFilter sstable.BoundLimitedBlockPropertyFilter
+	// blobValueFetcher is the base.ValueFetcher to use when constructing
+	// internal values to represent values stored externally in blob files.
+	blobValueFetcher base.ValueFetcher
 }
 
 func finishInitializingInternalIter(
@@ -1443,11 +1518,16 @@ func (i *Iterator) constructPointIter(
 				uint64(uintptr(unsafe.Pointer(i))),
 				i.opts.Category,
 			),
+			Category: i.opts.Category,
 		},
 	}
 	if i.opts.RangeKeyMasking.Filter != nil {
 		internalOpts.boundLimitedFilter = &i.rangeKeyMasking
 	}
+	if i.fc != nil {
+		i.blobValueFetcher.Init(i.fc, internalOpts.readEnv)
+		internalOpts.blobValueFetcher = &i.blobValueFetcher
+	}
 
 	// Merging levels and levels from iterAlloc.
 	mlevels := buf.mlevels[:0]
@@ -1531,7 +1611,11 @@ func (i *Iterator) constructPointIter(
 		addLevelIterForFiles := func(files manifest.LevelIterator, level manifest.Layer) {
 			li := &levels[levelsIndex]
 
-			li.init(ctx, i.opts, &i.comparer, i.newIters, files, level, internalOpts)
+			levelOpts := internalOpts
+			if i.tracing != nil {
+				levelOpts.readEnv.LevelStats = &i.tracing.levels[level.Level()]
+			}
+			li.init(ctx, i.opts, &i.comparer, i.newIters, files, level, levelOpts)
 			li.initRangeDel(&mlevels[mlevelsIndex])
 			li.initCombinedIterState(&i.lazyCombinedIter.combinedIterState)
 			mlevels[mlevelsIndex].levelIter = li
@@ -1666,6 +1750,13 @@ func (d *DB) NewEventuallyFileOnlySnapshot(keyRanges []KeyRange) *EventuallyFile
 // or to call Close concurrently with any other DB method. It is not valid
 // to call any of a DB's methods after the DB has been closed.
 func (d *DB) Close() error {
+	// Close the keyspaces first, as their flushes acquire DB.mu to delete the
+	// obsolete WALs of the DB.
+	var keyspacesErr error
+	for _, ks := range d.keyspaces {
+		keyspacesErr = firstError(keyspacesErr, ks.db.Close())
+	}
+
 	// Lock the commit pipeline for the duration of Close. This prevents a race
 	// with makeRoomForWrite. Rotating the WAL in makeRoomForWrite requires
 	// dropping d.mu several times for I/O. If Close only holds d.mu, an
@@ -1696,7 +1787,15 @@ func (d *DB) Close() error {
 
 	defer d.cacheHandle.Close()
 
-	for d.mu.compact.compactingCount > 0 || d.mu.compact.downloadingCount > 0 || d.mu.compact.flushing {
+	if d.follower != nil {
+		// Wait for the follower's refreshes to stop.
+		d.mu.Unlock()
+		<-d.follower.done
+		d.mu.Lock()
+	}
+
+	for d.mu.compact.compactingCount > 0 || d.mu.compact.downloadingCount > 0 ||
+		d.mu.compact.uploadingCount > 0 || d.mu.compact.flushing {
 		d.mu.compact.cond.Wait()
 	}
 	for d.mu.tableStats.loading {
@@ -1706,7 +1805,7 @@ func (d *DB) Close() error {
 		d.mu.tableValidation.cond.Wait()
 	}
 
-	var err error
+	err := keyspacesErr
 	if n := len(d.mu.compact.inProgress); n > 0 {
 		err = errors.Errorf("pebble: %d unexpected in-progress compactions", errors.Safe(n))
 	}
@@ -1720,7 +1819,9 @@ func (d *DB) Close() error {
 		panic("pebble: log-writer should be nil in read-only mode")
 	}
 	err = firstError(err, d.mu.log.manager.Close())
-	err = firstError(err, d.fileLock.Close())
+	if d.fileLock != nil {
+		err = firstError(err, d.fileLock.Close())
+	}
 
 	// Note that versionSet.close() only closes the MANIFEST. The versions list
 	// is still valid for the checks below.
@@ -1787,8 +1888,15 @@ func (d *DB) Close() error {
 	if ztbls := len(d.mu.versions.zombieTables); ztbls > 0 {
 		err = firstError(err, errors.Errorf("non-zero zombie file count: %d", ztbls))
 	}
+	if zblobs := len(d.mu.versions.zombieBlobs); zblobs > 0 {
+		err = firstError(err, errors.Errorf("non-zero zombie blob file count: %d", zblobs))
+	}
 
 	err = firstError(err, d.objProvider.Close())
+	if d.secondaryBlockCache != nil {
+		d.cacheHandle.SetSecondaryCache(nil)
+		err = firstError(err, d.secondaryBlockCache.Close())
+	}
 
 	// If the options include a closer to 'close' the filesystem, close it.
 	if d.opts.private.fsCloser != nil {
@@ -2004,9 +2112,11 @@ func (d *DB) Metrics() *Metrics {
 	metrics.Compact.EstimatedDebt = d.mu.versions.picker.estimatedCompactionDebt(0)
 	metrics.Compact.InProgressBytes = d.mu.versions.atomicInProgressBytes.Load()
 	// TODO(radu): split this to separate the download compactions.
-	metrics.Compact.NumInProgress = int64(d.mu.compact.compactingCount + d.mu.compact.downloadingCount)
+	metrics.Compact.NumInProgress = int64(d.mu.compact.compactingCount + d.mu.compact.downloadingCount +
+		d.mu.compact.uploadingCount)
 	metrics.Compact.MarkedFiles = vers.Stats.MarkedForCompaction
 	metrics.Compact.Duration = d.mu.compact.duration
+	metrics.Upload = d.uploadMetricsLocked()
 	for c := range d.mu.compact.inProgress {
 		if c.kind != compactionKindFlush && c.kind != compactionKindIngestedFlushable {
 			metrics.Compact.Duration += d.timeNow().Sub(c.beganAt)
@@ -2056,7 +2166,22 @@ func (d *DB) Metrics() *Metrics {
 			metrics.Table.Local.ZombieSize += info.FileSize
 		}
 	}
+	if metrics.BlobFiles.ValueSize > metrics.BlobFiles.ReferencedValueSize {
+		metrics.BlobFiles.GarbageRatio = 1 - float64(metrics.BlobFiles.ReferencedValueSize)/float64(metrics.BlobFiles.ValueSize)
+	}
+	metrics.BlobFiles.ZombieCount = int64(len(d.mu.versions.zombieBlobs))
+	for _, info := range d.mu.versions.zombieBlobs {
+		metrics.BlobFiles.ZombieSize += info.FileSize
+		if info.isLocal {
+			metrics.BlobFiles.Local.ZombieSize += info.FileSize
+		}
+	}
 	metrics.private.optionsFileSize = d.optionsFileSize
+	if f := d.follower; f != nil {
+		metrics.Follower.Lag = d.timeNow().Sub(f.mu.caughtUpAt)
+		metrics.Follower.RefreshCount = f.mu.refreshCount
+		metrics.Follower.RefreshErrorCount = f.mu.refreshErrorCount
+	}
 
 	// TODO(jackson): Consider making these metrics optional.
 	metrics.Keys.RangeKeySetsCount = *rangeKeySetsAnnotator.MultiLevelAnnotation(vers.RangeKeyLevels[:])
@@ -2067,6 +2192,9 @@ func (d *DB) Metrics() *Metrics {
 	backingCount, backingTotalSize := d.mu.versions.virtualBackings.Stats()
 	metrics.Table.BackingTableCount = uint64(backingCount)
 	metrics.Table.BackingTableSize = backingTotalSize
+	for _, bf := range d.mu.versions.blobFiles {
+		metrics.BlobFiles.MaxGarbageRatio = max(metrics.BlobFiles.MaxGarbageRatio, bf.GarbageRatio())
+	}
 	d.mu.versions.logUnlock()
 
 	metrics.LogWriter.FsyncLatency = d.mu.log.metrics.fsyncLatency
@@ -2083,6 +2211,8 @@ func (d *DB) Metrics() *Metrics {
 		metrics.Table.CompressedCountUnknown += int64(compressionTypes.unknown)
 		metrics.Table.CompressedCountSnappy += int64(compressionTypes.snappy)
 		metrics.Table.CompressedCountZstd += int64(compressionTypes.zstd)
+		metrics.Table.CompressedCountLz4 += int64(compressionTypes.lz4)
+		metrics.Table.CompressedCountMinLZ += int64(compressionTypes.minlz)
 		metrics.Table.CompressedCountNone += int64(compressionTypes.none)
 	}
 
@@ -2094,8 +2224,12 @@ func (d *DB) Metrics() *Metrics {
 	metrics.CategoryStats = d.fileCache.SSTStatsCollector().GetStats()
 
 	metrics.SecondaryCacheMetrics = d.objProvider.Metrics()
+	if d.secondaryBlockCache != nil {
+		metrics.SecondaryBlockCache = d.secondaryBlockCache.metrics()
+	}
 
 	metrics.Uptime = d.timeNow().Sub(d.openedAt)
+	metrics.Keyspaces = d.keyspaceMetrics()
 
 	metrics.manualMemory = manual.GetMetrics()
 
@@ -2471,6 +2605,12 @@ func (d *DB) newFlushableEntry(
 		deleteFn:       d.mu.versions.addObsolete,
 		deleteFnLocked: d.mu.versions.addObsoleteLocked,
 	}
+	if d.opts.private.follower {
+		// The sstables of a follower's ingested flushables are owned by the
+		// primary, which adds them to its LSM when it flushes them.
+		fe.deleteFn = func(manifest.ObsoleteFiles) {}
+		fe.deleteFnLocked = fe.deleteFn
+	}
 	fe.readerRefs.Store(1)
 	return fe
 }
@@ -2578,9 +2718,16 @@ func (d *DB) makeRoomForWrite(b *Batch) error {
 			b.commitStats.WALRotationDuration += beforeRotate.Elapsed()
 		}
 	}
+	if parent := d.keyspace.parent.Load(); parent != nil {
+		// The keyspace's writes are logged to the parent's WAL. Rotate it, so
+		// that the writes to the new memtable are logged to WALs that hold no
+		// writes of the previous memtables.
+		newLogNum = parent.rotateWALForKeyspace()
+		d.mu.versions.markFileNumUsed(newLogNum)
+	}
 	immMem := d.mu.mem.mutable
 	imm := d.mu.mem.queue[len(d.mu.mem.queue)-1]
-	imm.logSize = prevLogSize
+	imm.logSize += prevLogSize
 
 	var logSeqNum base.SeqNum
 	var minSize uint64
@@ -2659,6 +2806,7 @@ func (d *DB) rotateMemtable(
 	var entry *flushableEntry
 	d.mu.mem.mutable, entry = d.newMemTable(newLogNum, logSeqNum, minSize)
 	d.mu.mem.queue = append(d.mu.mem.queue, entry)
+	d.recordWALLocked(newLogNum, logSeqNum)
 	// d.logSize tracks the log size of the WAL file corresponding to the most
 	// recent flushable. The log size of the previous mutable memtable no longer
 	// applies to the current mutable memtable.
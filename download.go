@@ -63,12 +63,14 @@ func (d *DB) Download(ctx context.Context, spans []DownloadSpan) error {
 	if d.opts.ReadOnly {
 		return ErrReadOnly
 	}
+	jobID := d.newJobID()
 	info := DownloadInfo{
-		JobID: int(d.newJobID()),
+		JobID: int(jobID),
 		Spans: spans,
 	}
 	startTime := d.timeNow()
 	d.opts.EventListener.DownloadBegin(info)
+	ctx, span := d.startJobSpan(ctx, DownloadSpanName, jobID)
 
 	for info.RestartCount = 0; ; info.RestartCount++ {
 		tasks := d.createDownloadTasks(spans)
@@ -77,6 +79,8 @@ func (d *DB) Download(ctx context.Context, spans []DownloadSpan) error {
 			// We are done.
 			info.Done = true
 			d.opts.EventListener.DownloadEnd(info)
+			span.SetAttributes(info.spanAttributes()...)
+			span.End(nil)
 			return nil
 		}
 		if info.RestartCount > 0 {
@@ -98,6 +102,8 @@ func (d *DB) Download(ctx context.Context, spans []DownloadSpan) error {
 			info.Err = err
 			info.Duration = d.timeNow().Sub(startTime)
 			d.opts.EventListener.DownloadEnd(info)
+			span.SetAttributes(info.spanAttributes()...)
+			span.End(err)
 			return err
 		}
 	}
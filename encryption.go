@@ -0,0 +1,120 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/vfs/encfs"
+)
+
+// RotateEncryptionKey re-encrypts the DB's files with the active key of the
+// encfs.FS that Options.FS wraps. The memtable is flushed so that a new WAL is
+// created, sstables encrypted with any other key are rewritten through rewrite
+// compactions, and the MANIFEST is rotated if necessary. RotateEncryptionKey
+// blocks until all such sstables have been rewritten.
+//
+// Values stored in blob files are not rewritten, and remain encrypted with the
+// key they were written with until the blob file is rewritten by a blob-rewrite
+// compaction.
+func (d *DB) RotateEncryptionKey() error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	fs := encfs.Find(d.opts.FS)
+	if fs == nil {
+		return errors.New("pebble: Options.FS does not encrypt files")
+	}
+	activeKeyID, err := fs.ActiveKeyID()
+	if err != nil {
+		return err
+	}
+	if err := d.Flush(); err != nil {
+		return err
+	}
+
+	// Read the key ID of the MANIFEST without holding d.mu.
+	d.mu.Lock()
+	manifestFileNum := d.mu.versions.manifestFileNum
+	d.mu.Unlock()
+	manifestPath := base.MakeFilepath(d.opts.FS, d.dirname, base.FileTypeManifest, manifestFileNum)
+	manifestKeyID, err := fs.FileKeyID(manifestPath)
+	if err != nil && !oserror.IsNotExist(err) {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// If the MANIFEST was rotated in the meantime (possibly removing the file
+	// before we read it), the new MANIFEST was created with the active key.
+	if manifestKeyID != activeKeyID && manifestFileNum == d.mu.versions.manifestFileNum {
+		// Marking files for compaction rotates the MANIFEST, but only if any
+		// files are marked. Rotate it explicitly.
+		d.mu.versions.logLock()
+		if err := d.mu.versions.logAndApply(
+			d.newJobIDLocked(),
+			&manifest.VersionEdit{},
+			map[int]*LevelMetrics{},
+			true, /* forceRotation */
+			func() []compactionInfo { return d.getInProgressCompactionInfoLocked(nil) }); err != nil {
+			return err
+		}
+	}
+
+	// NB: markFilesLocked calls the function without holding d.mu, on a
+	// version that it keeps referenced.
+	err = d.markFilesLocked(func(v *version) (found bool, files [numLevels][]*tableMetadata, _ error) {
+		for l := 0; l < numLevels; l++ {
+			iter := v.Levels[l].Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				objMeta, err := d.objProvider.Lookup(base.FileTypeTable, f.FileBacking.DiskFileNum)
+				if err != nil {
+					return false, files, err
+				}
+				if objMeta.IsRemote() {
+					// Remote objects are not stored on Options.FS.
+					continue
+				}
+				// Use the key ID recorded in the table's metadata if available,
+				// which avoids reading the file's header.
+				keyID := f.EncryptionKeyID
+				if keyID == "" {
+					if keyID, err = fs.FileKeyID(d.objProvider.Path(objMeta)); err != nil {
+						return false, files, err
+					}
+				}
+				if keyID != activeKeyID {
+					files[l] = append(files[l], f)
+					found = true
+				}
+			}
+		}
+		return found, files, nil
+	})
+	if err != nil {
+		return err
+	}
+	return d.compactMarkedFilesLocked()
+}
+
+// activeEncryptionKeyID returns the ID of the key with which Options.FS
+// encrypts new files, or the empty string if Options.FS does not encrypt files
+// or the active key is unavailable.
+func (d *DB) activeEncryptionKeyID() string {
+	fs := encfs.Find(d.opts.FS)
+	if fs == nil {
+		return ""
+	}
+	keyID, err := fs.ActiveKeyID()
+	if err != nil {
+		return ""
+	}
+	return keyID
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/encfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateEncryptionKey(t *testing.T) {
+	mem := vfs.NewMem()
+	keys := &encfs.StaticKeyProvider{
+		ActiveKeyID: "k1",
+		Keys: map[string][]byte{
+			"k1": bytes.Repeat([]byte{1}, 32),
+			"k2": bytes.Repeat([]byte{2}, 32),
+		},
+	}
+	fs := encfs.New(mem, keys)
+	opts := &Options{FS: fs, Logger: testLogger{t}}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+
+	const numKeys = 100
+	value := bytes.Repeat([]byte("secret-value."), 10)
+	for i := 0; i < numKeys; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key%03d", i)), value, nil))
+		if i%25 == 24 {
+			require.NoError(t, d.Flush())
+		}
+	}
+
+	// checkFiles verifies that every sstable is encrypted with the provided
+	// key, and that no plaintext values are visible on the underlying FS.
+	checkFiles := func(keyID string) {
+		t.Helper()
+		ls, err := mem.List("")
+		require.NoError(t, err)
+		var tables int
+		for _, name := range ls {
+			if !encfs.IsEncrypted(name) {
+				continue
+			}
+			f, err := mem.Open(name)
+			require.NoError(t, err)
+			b, err := io.ReadAll(f)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			require.False(t, bytes.Contains(b, []byte("secret-value")), name)
+			if strings.HasSuffix(name, ".sst") {
+				id, err := fs.FileKeyID(name)
+				require.NoError(t, err)
+				require.Equal(t, keyID, id, name)
+				tables++
+			}
+		}
+		require.NotZero(t, tables)
+	}
+	checkValues := func(d *DB) {
+		t.Helper()
+		for i := 0; i < numKeys; i++ {
+			v, closer, err := d.Get([]byte(fmt.Sprintf("key%03d", i)))
+			require.NoError(t, err)
+			require.Equal(t, value, v)
+			require.NoError(t, closer.Close())
+		}
+	}
+	// checkMetadata verifies that the key of every sstable is recorded in its
+	// metadata.
+	checkMetadata := func(d *DB, keyID string) {
+		t.Helper()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for _, l := range d.mu.versions.currentVersion().Levels {
+			iter := l.Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				require.Equal(t, keyID, f.EncryptionKeyID, f.FileNum)
+			}
+		}
+	}
+	checkFiles("k1")
+	checkMetadata(d, "k1")
+	checkValues(d)
+
+	// Forget the key of one of the sstables, as for an ingested sstable. Its
+	// key is read from the file instead.
+	d.mu.Lock()
+	for _, l := range d.mu.versions.currentVersion().Levels {
+		if iter := l.Iter(); iter.First() != nil {
+			iter.First().EncryptionKeyID = ""
+			break
+		}
+	}
+	d.mu.Unlock()
+
+	keys.ActiveKeyID = "k2"
+	require.NoError(t, d.RotateEncryptionKey())
+	checkMetadata(d, "k2")
+	checkValues(d)
+	require.NoError(t, d.Close())
+
+	// Once all files have been rotated, the old key is no longer required to
+	// open the DB.
+	checkFiles("k2")
+	delete(keys.Keys, "k1")
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	checkMetadata(d, "k2")
+	checkValues(d)
+	require.NoError(t, d.Close())
+}
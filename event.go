@@ -282,6 +282,11 @@ func (i FlushInfo) SafeFormat(w redact.SafePrinter, _ rune) {
 	}
 }
 
+// RemoteStorageEventInfo contains the info for a retry, a hedged read or a
+// circuit breaker state change of a remote storage wrapped according to
+// Options.Experimental.RemoteStorageResilience.
+type RemoteStorageEventInfo = remote.ResilienceEvent
+
 // DownloadInfo contains the info for a DB.Download() event.
 type DownloadInfo struct {
 	// JobID is the ID of the download job.
@@ -394,7 +399,11 @@ func (i TableCreateInfo) SafeFormat(w redact.SafePrinter, _ rune) {
 
 // TableDeleteInfo contains the info for a table deletion event.
 type TableDeleteInfo struct {
-	JobID   int
+	JobID int
+	// Reason is the reason for the table deletion: "obsolete" for tables that
+	// are no longer referenced by the LSM, or "evicted" for tables that were
+	// evicted by a FIFOCompactionPicker.
+	Reason  string
 	Path    string
 	FileNum base.DiskFileNum
 	Err     error
@@ -411,6 +420,10 @@ func (i TableDeleteInfo) SafeFormat(w redact.SafePrinter, _ rune) {
 			redact.Safe(i.JobID), i.FileNum, i.Err)
 		return
 	}
+	if i.Reason == "evicted" {
+		w.Printf("[JOB %d] sstable evicted %s", redact.Safe(i.JobID), i.FileNum)
+		return
+	}
 	w.Printf("[JOB %d] sstable deleted %s", redact.Safe(i.JobID), i.FileNum)
 }
 
@@ -772,6 +785,12 @@ type EventListener struct {
 	// ManifestDeleted is invoked after a manifest has been deleted.
 	ManifestDeleted func(ManifestDeleteInfo)
 
+	// RemoteStorageEvent is invoked when an operation on remote storage is
+	// retried or hedged, or when the circuit breaker for a remote storage
+	// locator opens or closes. Only invoked when
+	// Options.Experimental.RemoteStorageResilience is set.
+	RemoteStorageEvent func(RemoteStorageEventInfo)
+
 	// TableCreated is invoked when a table has been created.
 	TableCreated func(TableCreateInfo)
 
@@ -874,6 +893,9 @@ func (l *EventListener) EnsureDefaults(logger Logger) {
 	if l.TableCreated == nil {
 		l.TableCreated = func(info TableCreateInfo) {}
 	}
+	if l.RemoteStorageEvent == nil {
+		l.RemoteStorageEvent = func(info RemoteStorageEventInfo) {}
+	}
 	if l.TableDeleted == nil {
 		l.TableDeleted = func(info TableDeleteInfo) {}
 	}
@@ -953,6 +975,9 @@ func MakeLoggingEventListener(logger Logger) EventListener {
 		TableCreated: func(info TableCreateInfo) {
 			logger.Infof("%s", info)
 		},
+		RemoteStorageEvent: func(info RemoteStorageEventInfo) {
+			logger.Infof("%s", info)
+		},
 		TableDeleted: func(info TableDeleteInfo) {
 			logger.Infof("%s", info)
 		},
@@ -1043,6 +1068,10 @@ func TeeEventListener(a, b EventListener) EventListener {
 			a.TableCreated(info)
 			b.TableCreated(info)
 		},
+		RemoteStorageEvent: func(info RemoteStorageEventInfo) {
+			a.RemoteStorageEvent(info)
+			b.RemoteStorageEvent(info)
+		},
 		TableDeleted: func(info TableDeleteInfo) {
 			a.TableDeleted(info)
 			b.TableDeleted(info)
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"runtime"
 	"slices"
@@ -23,6 +24,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/testutils"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/redact"
@@ -539,6 +541,60 @@ func (fs *mockDiskUsageFS) GetDiskUsage(path string) (vfs.DiskUsage, error) {
 	return fs.usage.Load().(vfs.DiskUsage), nil
 }
 
+// failingCreateStorage fails the next CreateObject calls.
+type failingCreateStorage struct {
+	remote.Storage
+	failures atomic.Int32
+}
+
+func (s *failingCreateStorage) CreateObject(objName string) (io.WriteCloser, error) {
+	if s.failures.Add(-1) >= 0 {
+		return nil, errors.New("injected create error")
+	}
+	return s.Storage.CreateObject(objName)
+}
+
+func TestRemoteStorageEvent(t *testing.T) {
+	var mu sync.Mutex
+	var events []RemoteStorageEventInfo
+	storage := &failingCreateStorage{Storage: remote.NewInMem()}
+	opts := &Options{
+		FS: vfs.NewMem(),
+		EventListener: &EventListener{
+			RemoteStorageEvent: func(info RemoteStorageEventInfo) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, info)
+			},
+		},
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": storage,
+	})
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+	opts.Experimental.RemoteStorageResilience = &remote.ResilienceOptions{
+		RetryBaseDelay: time.Millisecond,
+	}
+
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.SetCreatorID(1))
+
+	storage.failures.Store(2)
+	require.NoError(t, d.Set([]byte("a"), []byte("avalue"), nil))
+	require.NoError(t, d.Flush())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, events, 2)
+	for i, info := range events {
+		require.Equal(t, remote.ResilienceRetry, info.Kind)
+		require.Equal(t, "create-object", info.Op)
+		require.Equal(t, i+1, info.Attempt)
+	}
+}
+
 func TestSSTCorruptionEvent(t *testing.T) {
 	for _, test := range []string{"missing-file", "missing-before-open", "meta-block-corruption", "data-block-corruption"} {
 		t.Run(test, func(t *testing.T) {
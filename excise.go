@@ -119,7 +119,12 @@ func (d *DB) excise(
 			SmallestSeqNum:           m.SmallestSeqNum,
 			LargestSeqNum:            m.LargestSeqNum,
 			LargestSeqNumAbsolute:    m.LargestSeqNumAbsolute,
+			EncryptionKeyID:          m.EncryptionKeyID,
 			SyntheticPrefixAndSuffix: m.SyntheticPrefixAndSuffix,
+			// The virtual table may contain any of the original table's
+			// references to values in blob files.
+			BlobReferences:     m.BlobReferences,
+			BlobReferenceDepth: m.BlobReferenceDepth,
 		}
 		if m.HasPointKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.SmallestPointKey) {
 			// This file will probably contain point keys.
@@ -215,7 +220,10 @@ func (d *DB) excise(
 		SmallestSeqNum:           m.SmallestSeqNum,
 		LargestSeqNum:            m.LargestSeqNum,
 		LargestSeqNumAbsolute:    m.LargestSeqNumAbsolute,
+		EncryptionKeyID:          m.EncryptionKeyID,
 		SyntheticPrefixAndSuffix: m.SyntheticPrefixAndSuffix,
+		BlobReferences:           m.BlobReferences,
+		BlobReferenceDepth:       m.BlobReferenceDepth,
 	}
 	if m.HasPointKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.LargestPointKey) {
 		// This file will probably contain point keys
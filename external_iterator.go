@@ -177,7 +177,7 @@ func createExternalPointIter(
 			seqNum--
 			pointIter, err = r.NewPointIter(
 				ctx, transforms, it.opts.LowerBound, it.opts.UpperBound, nil, /* BlockPropertiesFilterer */
-				sstable.NeverUseFilterBlock, readEnv, sstable.MakeTrivialReaderProvider(r))
+				sstable.NeverUseFilterBlock, readEnv, sstable.MakeTrivialReaderProvider(r), sstable.AssertNoBlobHandles)
 			if err != nil {
 				return nil, err
 			}
@@ -356,6 +356,24 @@ func (h *fileCacheHandle) withReader(
 	return fn(ref.Value().reader.(*sstable.Reader), env)
 }
 
+// mayContainPrefixes checks the filter of the provided table for the provided
+// key prefixes (see sstable.Reader.MayContainPrefixes). It also returns whether
+// the table contains range deletions.
+func (h *fileCacheHandle) mayContainPrefixes(
+	ctx context.Context, env block.ReadEnv, file *tableMetadata, prefixes [][]byte, mayContain []bool,
+) (hasRangeDels bool, _ error) {
+	ref, err := h.findOrCreateTable(ctx, file)
+	if err != nil {
+		return false, err
+	}
+	defer ref.Unref()
+	env.ReportCorruptionFn = h.reportCorruptionFn
+	env.ReportCorruptionArg = file
+	r := ref.Value().mustSSTableReader()
+	err = r.MayContainPrefixes(ctx, env, file.IterTransforms(), prefixes, mayContain)
+	return r.Properties.NumRangeDeletions > 0, err
+}
+
 // withVirtualReader fetches a VirtualReader associated with a virtual sstable.
 func (h *fileCacheHandle) withVirtualReader(
 	ctx context.Context,
@@ -674,13 +692,17 @@ func (h *fileCacheHandle) newPointIter(
 	transforms.HideObsoletePoints = hideObsoletePoints
 	if internalOpts.readEnv.IterStats == nil && opts != nil {
 		internalOpts.readEnv.IterStats = handle.SSTStatsCollector().Accumulator(uint64(uintptr(unsafe.Pointer(r))), opts.Category)
+		internalOpts.readEnv.Category = opts.Category
+	}
+	blobContext := sstable.TableBlobContext{
+		ValueFetcher: internalOpts.blobValueFetcher,
 	}
 	if internalOpts.compaction {
-		iter, err = cr.NewCompactionIter(transforms, internalOpts.readEnv, &v.readerProvider)
+		iter, err = cr.NewCompactionIter(transforms, internalOpts.readEnv, &v.readerProvider, blobContext)
 	} else {
 		iter, err = cr.NewPointIter(
 			ctx, transforms, opts.GetLowerBound(), opts.GetUpperBound(), filterer,
-			filterBlockSizeLimit, internalOpts.readEnv, &v.readerProvider)
+			filterBlockSizeLimit, internalOpts.readEnv, &v.readerProvider, blobContext)
 	}
 	if err != nil {
 		return nil, err
@@ -0,0 +1,644 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/batchrepr"
+	"github.com/cockroachdb/pebble/internal/arenaskl"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/record"
+	"github.com/cockroachdb/pebble/vfs/atomicfs"
+	"github.com/cockroachdb/pebble/wal"
+)
+
+// OpenFollower opens a read-only follower of the DB whose files live in the
+// given directory. The DB, called the primary, is written by another process,
+// or by another DB in the same process, that shares the directory with the
+// follower, for example through a shared filesystem.
+//
+// A follower is opened like a DB with Options.ReadOnly set. Every
+// Options.FollowerRefreshInterval, it reads the version edits appended to the
+// primary's MANIFEST and the batches appended to its WALs, and applies them to
+// its own LSM and memtables, so that readers see a lagging but consistent
+// state of the primary. The replication lag is reported by
+// Metrics.Follower.Lag.
+//
+// A follower does not take the directory's lock, and never deletes files: the
+// primary deletes the files it no longer needs, regardless of the follower.
+// Reads of a follower that has fallen behind may therefore fail to find
+// sstables the primary has deleted; a follower that falls more than
+// Options.NumPrevManifest MANIFEST rotations behind stops refreshing. Followers
+// of DBs that store sstables on remote storage are not supported.
+func OpenFollower(dirname string, opts *Options) (*DB, error) {
+	opts = opts.Clone()
+	opts.ReadOnly = true
+	opts.private.follower = true
+	d, err := Open(dirname, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range d.objProvider.List() {
+		if obj.IsRemote() {
+			return nil, errors.CombineErrors(
+				errors.Newf("pebble: cannot follow DB %q with remote object %s", dirname, obj.DiskFileNum),
+				d.Close())
+		}
+	}
+
+	walDirname := d.opts.WALDir
+	if walDirname == "" {
+		walDirname = dirname
+	}
+	walDirs := []wal.Dir{{FS: d.opts.FS, Dirname: walDirname}}
+	if d.opts.WALFailover != nil {
+		walDirs = append(walDirs, d.opts.WALFailover.Secondary)
+	}
+	d.mu.Lock()
+	f := &follower{
+		d:              d,
+		walDirs:        walDirs,
+		manifestNum:    d.mu.versions.manifestFileNum,
+		manifestOffset: d.mu.versions.manifestReadOffset,
+		nextSeqNum:     d.mu.versions.logSeqNum.Load(),
+		done:           make(chan struct{}),
+	}
+	// Open replayed the WALs that the primary had not flushed. The follower
+	// reads them again, skipping the batches that were replayed.
+	f.tail.walNum = wal.NumWAL(d.mu.versions.minUnflushedLogNum)
+	f.mu.caughtUpAt = d.openedAt
+	d.follower = f
+	d.mu.Unlock()
+
+	go f.run()
+	return d, nil
+}
+
+// follower holds the state of a DB opened with OpenFollower.
+type follower struct {
+	d       *DB
+	walDirs []wal.Dir
+
+	// refreshMu serializes refreshes, which are performed by the follower's
+	// goroutine and by tests. The following fields are protected by it.
+	refreshMu sync.Mutex
+	// manifestNum is the file number of the primary's MANIFEST being read,
+	// and manifestOffset is the offset of the next version edit in it. They
+	// only advance past the edits that have been applied.
+	manifestNum    base.DiskFileNum
+	manifestOffset int64
+	// err is set when a version edit fails to apply. The version set may then
+	// have been partially updated, so the follower stops refreshing.
+	err error
+	// tail is positioned at the next record of the primary's WALs.
+	tail walTail
+	// nextSeqNum is the sequence number following the last batch read from the
+	// WALs. Batches that end before nextSeqNum were already applied.
+	nextSeqNum base.SeqNum
+
+	mu struct {
+		// The following fields are protected by DB.mu.
+
+		// caughtUpAt is the start time of the last successful refresh.
+		caughtUpAt        time.Time
+		refreshCount      int64
+		refreshErrorCount int64
+	}
+
+	// done is closed when the follower's goroutine exits.
+	done chan struct{}
+}
+
+// followedEdit is a version edit read from the primary's MANIFEST, along with
+// the position of the next record in the MANIFEST.
+type followedEdit struct {
+	ve          *versionEdit
+	manifestNum base.DiskFileNum
+	offset      int64
+}
+
+// errFollowerFailed marks errors that stop a follower from refreshing.
+var errFollowerFailed = errors.New("pebble: follower failed")
+
+// followedBatch is a batch read from the WAL logNum of the primary.
+type followedBatch struct {
+	logNum base.DiskFileNum
+	repr   []byte
+}
+
+func (f *follower) run() {
+	defer close(f.done)
+	t := time.NewTicker(f.d.opts.FollowerRefreshInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-f.d.closedCh:
+			return
+		case <-t.C:
+		}
+		if err := f.refresh(); err != nil {
+			f.d.opts.Logger.Errorf("pebble: follower refresh failed: %s", err)
+			if errors.Is(err, errFollowerFailed) {
+				return
+			}
+		}
+	}
+}
+
+// refresh reads the version edits and batches written by the primary since
+// the previous refresh, and applies them. If an error occurs, the edits and
+// batches read before the error are applied. If an edit fails to apply, the
+// edits preceding it remain applied, and the follower stops refreshing.
+func (f *follower) refresh() error {
+	f.refreshMu.Lock()
+	defer f.refreshMu.Unlock()
+	d := f.d
+	if err := d.closed.Load(); err != nil {
+		return err.(error)
+	}
+	if f.err != nil {
+		return f.err
+	}
+	start := d.timeNow()
+
+	// The MANIFEST is read first, so that the WALs the primary flushed and may
+	// have deleted are skipped.
+	edits, manifestNum, manifestOffset, err := f.readManifest()
+	minUnflushedLogNum := d.mu.versions.minUnflushedLogNum
+	for _, fe := range edits {
+		if fe.ve.MinUnflushedLogNum != 0 {
+			minUnflushedLogNum = fe.ve.MinUnflushedLogNum
+		}
+	}
+	var batches []followedBatch
+	nextSeqNum := f.nextSeqNum
+	if err == nil {
+		batches, err = f.readWALs(minUnflushedLogNum)
+	}
+	// The sstables referenced by the edits and by ingestions in the WALs were
+	// created before they were read.
+	err = errors.CombineErrors(err, d.objProvider.ScanLocalObjects())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var lastSeqNum base.SeqNum
+	for _, fe := range edits {
+		if applyErr := d.mu.versions.applyFollowedEdit(fe.ve); applyErr != nil {
+			f.err = errors.Mark(errors.Wrap(applyErr, "pebble: follower failed to apply MANIFEST edit"), errFollowerFailed)
+			err = errors.CombineErrors(err, f.err)
+			// The batches were read following the edits that failed to apply.
+			batches, f.nextSeqNum = nil, nextSeqNum
+			break
+		}
+		f.manifestNum, f.manifestOffset = fe.manifestNum, fe.offset
+		lastSeqNum = max(lastSeqNum, fe.ve.LastSeqNum)
+	}
+	if f.err == nil {
+		// Records that aren't followed, like the snapshot at the start of a
+		// MANIFEST the primary rotated to, may follow the last edit.
+		f.manifestNum, f.manifestOffset = manifestNum, manifestOffset
+	}
+	dropped := d.dropFlushedMemTablesLocked()
+	for _, b := range batches {
+		if applyErr := d.applyFollowedBatchLocked(b); applyErr != nil {
+			err = errors.CombineErrors(err, applyErr)
+			break
+		}
+	}
+	if d.mu.mem.mutable == nil {
+		var entry *flushableEntry
+		d.mu.mem.mutable, entry = d.newMemTable(base.DiskFileNum(f.tail.walNum), f.nextSeqNum, 0 /* minSize */)
+		d.mu.mem.queue = append(d.mu.mem.queue, entry)
+	}
+
+	// Sstables ingested by the primary without being written to its WALs
+	// consume sequence numbers that are only recorded in the MANIFEST.
+	seqNum := max(f.nextSeqNum, lastSeqNum+1, d.mu.versions.logSeqNum.Load())
+	d.mu.versions.logSeqNum.Store(seqNum)
+	d.mu.versions.visibleSeqNum.Store(seqNum)
+	d.updateReadStateLocked(d.opts.DebugCheck)
+	for _, e := range dropped {
+		e.readerUnrefLocked(true)
+	}
+
+	if err != nil {
+		f.mu.refreshErrorCount++
+	} else {
+		f.mu.caughtUpAt = start
+		f.mu.refreshCount++
+	}
+	d.deleteObsoleteFiles(d.newJobIDLocked())
+	return err
+}
+
+// readManifest reads the version edits appended to the primary's MANIFEST
+// since the previous refresh, and returns them along with the position
+// following the last record read. If the primary has rotated its MANIFEST, the
+// remainder of the previous MANIFEST is read first. The version snapshot at
+// the start of the new MANIFEST is then skipped, as the follower has reached
+// that version by reading the previous MANIFEST.
+func (f *follower) readManifest() (
+	edits []followedEdit,
+	manifestNum base.DiskFileNum,
+	offset int64,
+	err error,
+) {
+	manifestNum, offset = f.manifestNum, f.manifestOffset
+	fs := f.d.opts.FS
+	filename, err := atomicfs.ReadMarker(fs, f.d.dirname, manifestMarkerName)
+	if err != nil {
+		return nil, manifestNum, offset, err
+	}
+	_, current, ok := base.ParseFilename(fs, filename)
+	if !ok {
+		return nil, manifestNum, offset, base.CorruptionErrorf("pebble: MANIFEST name %q is malformed", errors.Safe(filename))
+	}
+	for {
+		edits, offset, err = f.readManifestEdits(edits, manifestNum, offset)
+		if err != nil || manifestNum == current {
+			return edits, manifestNum, offset, err
+		}
+		// Continue with the MANIFEST that the primary rotated to.
+		ls, err := fs.List(f.d.dirname)
+		if err != nil {
+			return edits, manifestNum, offset, err
+		}
+		next := current
+		for _, name := range ls {
+			if ft, fn, ok := base.ParseFilename(fs, name); ok && ft == base.FileTypeManifest && fn > manifestNum && fn < next {
+				next = fn
+			}
+		}
+		manifestNum, offset = next, 0
+	}
+}
+
+// readManifestEdits appends the version edits following the given offset in
+// the MANIFEST manifestNum to edits, and returns the offset following the last
+// record read.
+func (f *follower) readManifestEdits(
+	edits []followedEdit, manifestNum base.DiskFileNum, offset int64,
+) ([]followedEdit, int64, error) {
+	fs := f.d.opts.FS
+	file, err := fs.Open(base.MakeFilepath(fs, f.d.dirname, base.FileTypeManifest, manifestNum))
+	if err != nil {
+		if oserror.IsNotExist(err) {
+			err = errors.Wrapf(err, "pebble: follower fell behind the MANIFEST rotations of DB %q", f.d.dirname)
+		}
+		return edits, offset, err
+	}
+	defer file.Close()
+	// The record reader seeks to the offset, which vfs.File doesn't support.
+	rr := record.NewReader(io.NewSectionReader(file, 0, math.MaxInt64), 0 /* logNum */)
+	if offset > 0 {
+		if err := rr.SeekRecord(offset); err != nil {
+			return edits, offset, ignoreIncompleteRecord(err)
+		}
+	}
+	var buf bytes.Buffer
+	for {
+		r, err := rr.Next()
+		if err == nil {
+			buf.Reset()
+			_, err = io.Copy(&buf, r)
+		}
+		if err != nil {
+			// The primary may be writing the next edit.
+			return edits, offset, ignoreIncompleteRecord(err)
+		}
+		ve := &versionEdit{}
+		if err := ve.Decode(&buf); err != nil {
+			return edits, offset, err
+		}
+		// The first record of a MANIFEST the primary rotated to is a snapshot.
+		first := offset == 0
+		offset = rr.Offset()
+		if !first {
+			edits = append(edits, followedEdit{ve: ve, manifestNum: manifestNum, offset: offset})
+		}
+	}
+}
+
+// readWALs reads the batches appended to the primary's WALs since the
+// previous refresh. WALs older than minUnflushedLogNum have been flushed by the
+// primary, and are skipped.
+func (f *follower) readWALs(minUnflushedLogNum base.DiskFileNum) ([]followedBatch, error) {
+	if n := wal.NumWAL(minUnflushedLogNum); f.tail.walNum < n {
+		f.tail.closeSegment()
+		f.tail.walNum, f.tail.segment, f.tail.offset = n, 0, 0
+	}
+	logs, err := wal.Scan(f.walDirs...)
+	if err != nil {
+		return nil, err
+	}
+	var batches []followedBatch
+	for {
+		ll, ok := logs.Get(f.tail.walNum)
+		if !ok {
+			// If the WAL is the oldest one the primary hasn't flushed, and
+			// nothing was read from it, the primary may have written nothing
+			// to it. Otherwise the WAL has been flushed and deleted; the
+			// follower must read the flush from the MANIFEST before reading
+			// the next WAL.
+			if f.tail.walNum != wal.NumWAL(minUnflushedLogNum) || f.tail.segment != 0 || f.tail.offset != 0 {
+				return batches, nil
+			}
+			i := 0
+			for i < len(logs) && logs[i].Num < f.tail.walNum {
+				i++
+			}
+			if i == len(logs) {
+				return batches, nil
+			}
+			f.tail.walNum = logs[i].Num
+			continue
+		}
+		if f.tail.rr == nil && f.tail.segment < ll.NumSegments() {
+			fs, path := ll.SegmentLocation(f.tail.segment)
+			if err := f.tail.openSegment(fs, path); err != nil {
+				if oserror.IsNotExist(err) {
+					return batches, nil
+				}
+				return batches, err
+			}
+		}
+		for {
+			repr, err := f.tail.readSegmentRecord()
+			if err != nil {
+				return batches, err
+			}
+			if repr == nil {
+				break
+			}
+			h, ok := batchrepr.ReadHeader(repr)
+			if !ok {
+				return batches, base.CorruptionErrorf("pebble: corrupt log file %s: invalid batch", f.tail.walNum)
+			}
+			// Skip batches containing only LogData, and batches that have
+			// already been applied, which may be repeated in the WAL segments
+			// written during a failover.
+			if h.Count == 0 || h.SeqNum+base.SeqNum(h.Count) <= f.nextSeqNum {
+				continue
+			}
+			batches = append(batches, followedBatch{
+				logNum: base.DiskFileNum(f.tail.walNum),
+				repr:   slices.Clone(repr),
+			})
+			f.nextSeqNum = h.SeqNum + base.SeqNum(h.Count)
+		}
+		switch {
+		case f.tail.segment < ll.NumSegments()-1:
+			// The end of a segment that is followed by another may contain an
+			// invalid record, written when the WAL failed over.
+			f.tail.segment++
+			f.tail.offset = 0
+		case logs[len(logs)-1].Num > f.tail.walNum:
+			// A newer WAL exists, so the primary no longer writes to this one.
+			for i := range logs {
+				if logs[i].Num > f.tail.walNum {
+					f.tail.walNum = logs[i].Num
+					break
+				}
+			}
+			f.tail.segment, f.tail.offset = 0, 0
+		default:
+			return batches, nil
+		}
+	}
+}
+
+// applyFollowedBatchLocked applies a batch read from the primary's WALs to the
+// follower's memtables, as replayWAL does when a DB is opened.
+//
+// DB.mu must be held when calling this method.
+func (d *DB) applyFollowedBatchLocked(fb followedBatch) error {
+	// Specify Batch.db so that Batch.SetRepr will compute Batch.memTableSize.
+	b := &Batch{}
+	b.db = d
+	if err := b.SetRepr(fb.repr); err != nil {
+		return err
+	}
+	seqNum := b.SeqNum()
+	br := b.Reader()
+	if kind, _, _, ok, err := br.Next(); err != nil {
+		return err
+	} else if ok && (kind == InternalKeyKindIngestSST || kind == InternalKeyKindExcise) {
+		d.sealFollowedMemTableLocked()
+		entry, err := d.replayIngestedFlushable(b, fb.logNum)
+		if err != nil {
+			return err
+		}
+		d.mu.mem.queue = append(d.mu.mem.queue, entry)
+		return nil
+	}
+
+	if b.memTableSize >= uint64(d.largeBatchThreshold) {
+		d.sealFollowedMemTableLocked()
+		var err error
+		b.flushable, err = newFlushableBatch(b, d.opts.Comparer)
+		if err != nil {
+			return err
+		}
+		entry := d.newFlushableEntry(b.flushable, fb.logNum, seqNum)
+		entry.releaseMemAccounting = func() {}
+		d.mu.mem.queue = append(d.mu.mem.queue, entry)
+		return nil
+	}
+
+	mem := d.mu.mem.mutable
+	if mem == nil || d.mu.mem.queue[len(d.mu.mem.queue)-1].logNum != fb.logNum {
+		mem = d.newFollowedMemTableLocked(fb.logNum, seqNum)
+	}
+	err := mem.prepare(b)
+	// DB.newMemTable() slowly grows the size of allocated memtables, so the
+	// batch may not initially fit, but will eventually fit (since it is
+	// smaller than largeBatchThreshold).
+	for err == arenaskl.ErrArenaFull {
+		mem = d.newFollowedMemTableLocked(fb.logNum, seqNum)
+		err = mem.prepare(b)
+	}
+	if err != nil {
+		return err
+	}
+	if err := mem.apply(b, seqNum); err != nil {
+		return err
+	}
+	mem.writerUnref()
+	return nil
+}
+
+// newFollowedMemTableLocked seals the mutable memtable, and replaces it with
+// a new memtable holding batches read from the WAL logNum.
+//
+// DB.mu must be held when calling this method.
+func (d *DB) newFollowedMemTableLocked(logNum base.DiskFileNum, seqNum base.SeqNum) *memTable {
+	d.sealFollowedMemTableLocked()
+	var entry *flushableEntry
+	d.mu.mem.mutable, entry = d.newMemTable(logNum, seqNum, 0 /* minSize */)
+	d.mu.mem.queue = append(d.mu.mem.queue, entry)
+	return d.mu.mem.mutable
+}
+
+// sealFollowedMemTableLocked stops applying batches to the mutable memtable.
+//
+// DB.mu must be held when calling this method.
+func (d *DB) sealFollowedMemTableLocked() {
+	if d.mu.mem.mutable != nil {
+		d.mu.mem.mutable.writerUnref()
+		d.mu.mem.mutable = nil
+	}
+}
+
+// dropFlushedMemTablesLocked removes the memtables holding batches read from
+// WALs that the primary has flushed from the queue of memtables, and returns
+// them. The caller must unref them once the read state has been updated.
+//
+// DB.mu must be held when calling this method.
+func (d *DB) dropFlushedMemTablesLocked() flushableList {
+	var dropped flushableList
+	// The queue is shared with the current read state, and is not modified in
+	// place.
+	queue := make(flushableList, 0, len(d.mu.mem.queue))
+	for _, e := range d.mu.mem.queue {
+		if e.logNum >= d.mu.versions.minUnflushedLogNum {
+			queue = append(queue, e)
+			continue
+		}
+		if e.flushable == d.mu.mem.mutable {
+			d.sealFollowedMemTableLocked()
+		}
+		dropped = append(dropped, e)
+	}
+	if len(dropped) > 0 {
+		d.mu.mem.queue = queue
+	}
+	return dropped
+}
+
+// forgetObsoleteFilesLocked discards the obsolete sstables and blob files of a
+// follower, which are deleted by the primary rather than the follower, and
+// evicts them from the file cache.
+//
+// d.mu must be held when calling this. The function will release and re-aquire
+// the mutex.
+func (d *DB) forgetObsoleteFilesLocked() {
+	vs := d.mu.versions
+	tables, blobs := vs.obsoleteTables, vs.obsoleteBlobs
+	vs.obsoleteTables, vs.obsoleteBlobs = nil, nil
+	for _, tbl := range tables {
+		delete(vs.zombieTables, tbl.FileNum)
+	}
+	for _, blob := range blobs {
+		delete(vs.zombieBlobs, blob.FileNum)
+	}
+	vs.updateObsoleteObjectMetricsLocked()
+
+	d.mu.Unlock()
+	defer d.mu.Lock()
+	for _, f := range tables {
+		d.fileCache.Evict(f.FileNum, base.FileTypeTable)
+	}
+	for _, f := range blobs {
+		d.fileCache.Evict(f.FileNum, base.FileTypeBlob)
+	}
+}
+
+// applyFollowedEdit applies a version edit read from the MANIFEST of the DB
+// being followed to the current version, and installs the new version. Unlike
+// logAndApply, the edit is not logged, and its RemovedBackingTables and
+// DeletedBlobFiles are recomputed.
+//
+// A decoded edit lacks the metadata of its deleted tables, which is resolved
+// from the current version. The backings of its tables are resolved from the
+// current version too, so that tables that move between levels or become
+// virtual share their backing with the tables they replace, as they do in the
+// primary.
+//
+// DB.mu must be held when calling this method.
+func (vs *versionSet) applyFollowedEdit(ve *versionEdit) error {
+	current := vs.currentVersion()
+	tables := make(map[base.FileNum]*tableMetadata)
+	backings := make(map[base.DiskFileNum]*fileBacking)
+	for _, l := range current.Levels {
+		iter := l.Iter()
+		for m := iter.First(); m != nil; m = iter.Next() {
+			tables[m.FileNum] = m
+			backings[m.FileBacking.DiskFileNum] = m.FileBacking
+		}
+	}
+	for e, m := range ve.DeletedTables {
+		if m == nil {
+			if m = tables[e.FileNum]; m == nil {
+				return base.CorruptionErrorf("pebble: deleted table L%d.%s not found", e.Level, e.FileNum)
+			}
+			ve.DeletedTables[e] = m
+		}
+	}
+	for i, b := range ve.CreatedBackingTables {
+		if existing, ok := backings[b.DiskFileNum]; ok {
+			ve.CreatedBackingTables[i] = existing
+		} else {
+			backings[b.DiskFileNum] = b
+		}
+	}
+	for _, nf := range ve.NewTables {
+		m := nf.Meta
+		if m.Virtual && m.FileBacking == nil {
+			if m.FileBacking = backings[nf.BackingFileNum]; m.FileBacking == nil {
+				return base.CorruptionErrorf("pebble: backing %s of table L%d.%s not found",
+					nf.BackingFileNum, nf.Level, m.FileNum)
+			}
+		} else if b, ok := backings[m.FileBacking.DiskFileNum]; ok {
+			m.FileBacking = b
+		}
+		for i := range m.BlobReferences {
+			if m.BlobReferences[i].Metadata == nil {
+				// Blob files created by the edit are resolved by Accumulate.
+				m.BlobReferences[i].Metadata = vs.blobFiles[m.BlobReferences[i].FileNum]
+			}
+		}
+	}
+	ve.RemovedBackingTables = nil
+	ve.DeletedBlobFiles = nil
+
+	zombieBackings, removedVirtualBackings, localLiveSizeDelta :=
+		getZombiesAndUpdateVirtualBackings(ve, &vs.virtualBackings, vs.provider)
+	zombieBlobs := vs.updateBlobFiles(ve)
+	var b bulkVersionEdit
+	err := b.Accumulate(ve)
+	var newVersion *version
+	if err == nil {
+		newVersion, err = b.Apply(current, vs.cmp, vs.opts.FlushSplitBytes, vs.opts.Experimental.ReadCompactionRate)
+	}
+	if err != nil {
+		// As in logAndApply, the virtual backings and blob files have already
+		// been updated, and can't be unwound. The follower stops refreshing.
+		return err
+	}
+	newVersion.L0Sublevels.InitCompactingFileInfo(nil /* in-progress compactions */)
+	vs.installLocked(newVersion, zombieBackings, removedVirtualBackings, zombieBlobs)
+
+	if ve.MinUnflushedLogNum != 0 {
+		vs.minUnflushedLogNum = ve.MinUnflushedLogNum
+	}
+	if ve.NextFileNum != 0 {
+		vs.markFileNumUsed(base.DiskFileNum(ve.NextFileNum - 1))
+	}
+	vs.updateLevelMetricsLocked(newVersion)
+	vs.metrics.Table.Local.LiveSize = uint64(int64(vs.metrics.Table.Local.LiveSize) + localLiveSizeDelta)
+	vs.picker = newCompactionPicker(newVersion, &vs.virtualBackings, vs.opts, nil /* inProgress */)
+	if !vs.dynamicBaseLevel {
+		vs.picker.forceBaseLevel1()
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollower(t *testing.T) {
+	fs := vfs.NewMem()
+	d, err := Open("db", &Options{
+		FS:                          fs,
+		DisableAutomaticCompactions: true,
+		// Rotate the MANIFEST frequently, but keep enough previous MANIFESTs
+		// for the follower to catch up after a compaction.
+		MaxManifestFileSize: 1,
+		NumPrevManifest:     8,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	set := func(keys ...string) {
+		for _, k := range keys {
+			require.NoError(t, d.Set([]byte(k), []byte(k+"-"+fmt.Sprint(d.mu.versions.visibleSeqNum.Load())), Sync))
+		}
+	}
+	contents := func(r Reader) string {
+		iter, err := r.NewIter(nil)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, iter.Close()) }()
+		var kvs []string
+		for valid := iter.First(); valid; valid = iter.Next() {
+			kvs = append(kvs, fmt.Sprintf("%s=%s", iter.Key(), iter.Value()))
+		}
+		require.NoError(t, iter.Error())
+		return strings.Join(kvs, " ")
+	}
+
+	set("a", "b", "c")
+	require.NoError(t, d.Flush())
+	set("d")
+
+	// The follower doesn't take the lock held by the primary.
+	f, err := OpenFollower("db", &Options{FS: fs, FollowerRefreshInterval: time.Hour})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+	require.Equal(t, contents(d), contents(f))
+	require.ErrorIs(t, f.Set([]byte("a"), nil, nil), ErrReadOnly)
+
+	// The follower sees nothing new until it refreshes. An iterator opened
+	// before a refresh keeps reading the previous state.
+	before := contents(d)
+	set("a", "e")
+	require.NoError(t, d.Delete([]byte("b"), Sync))
+	iter, err := f.NewIter(nil)
+	require.NoError(t, err)
+	require.Equal(t, before, contents(f))
+	require.NoError(t, f.follower.refresh())
+	require.Equal(t, contents(d), contents(f))
+
+	// Flushes and compactions, which rotate the MANIFEST and delete the flushed
+	// WALs and compacted sstables, are followed.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.Flush())
+		set(fmt.Sprintf("f%d", i))
+		require.NoError(t, f.follower.refresh())
+		require.Equal(t, contents(d), contents(f))
+	}
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+	set("a", "g")
+	require.NoError(t, f.follower.refresh())
+	require.Equal(t, contents(d), contents(f))
+	var n int
+	for valid := iter.First(); valid; valid = iter.Next() {
+		n++
+	}
+	require.Equal(t, 4, n)
+	require.NoError(t, iter.Close())
+
+	// Ingestions are followed, including ingestions that overlap the
+	// memtable, which are written to the WAL.
+	ingest := func(keys ...string) {
+		file, err := fs.Create("ext", vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(file), sstable.WriterOptions{})
+		for _, k := range keys {
+			require.NoError(t, w.Set([]byte(k), []byte(k+"-ingested")))
+		}
+		require.NoError(t, w.Close())
+		require.NoError(t, d.Ingest(context.Background(), []string{"ext"}))
+	}
+	ingest("h")
+	set("i")
+	ingest("g", "i")
+	require.NoError(t, f.follower.refresh())
+	require.Equal(t, contents(d), contents(f))
+	require.NoError(t, d.Flush())
+	set("j")
+	require.NoError(t, f.follower.refresh())
+	require.Equal(t, contents(d), contents(f))
+
+	m := f.Metrics()
+	require.Equal(t, int64(7), m.Follower.RefreshCount)
+	require.Zero(t, m.Follower.RefreshErrorCount)
+	for i, l := range d.Metrics().Levels {
+		require.Equal(t, l.NumFiles, m.Levels[i].NumFiles)
+		require.Equal(t, l.Size, m.Levels[i].Size)
+	}
+}
+
+func TestFollowerApplyError(t *testing.T) {
+	fs := vfs.NewMem()
+	d, err := Open("db", &Options{FS: fs, DisableAutomaticCompactions: true})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	f, err := OpenFollower("db", &Options{FS: fs, FollowerRefreshInterval: time.Hour})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+
+	// Append an edit that deletes a table that doesn't exist after a flush.
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), Sync))
+	require.NoError(t, d.Flush())
+	d.mu.Lock()
+	w, err := d.mu.versions.manifest.Next()
+	require.NoError(t, err)
+	ve := &versionEdit{DeletedTables: map[deletedFileEntry]*tableMetadata{{Level: 6, FileNum: 1000}: nil}}
+	require.NoError(t, ve.Encode(w))
+	require.NoError(t, d.mu.versions.manifest.Flush())
+	d.mu.Unlock()
+
+	// The flush is applied, and the follower stops at the edit that fails to
+	// apply.
+	require.True(t, errors.Is(f.follower.refresh(), errFollowerFailed))
+	require.Equal(t, int64(1), f.Metrics().Levels[0].NumFiles)
+	v, closer, err := f.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, "a", string(v))
+	require.NoError(t, closer.Close())
+	require.True(t, errors.Is(f.follower.refresh(), errFollowerFailed))
+	require.Equal(t, int64(1), f.Metrics().Follower.RefreshErrorCount)
+}
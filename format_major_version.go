@@ -212,6 +212,24 @@ const (
 	// Experimental versions, which are excluded by FormatNewest (but can be used
 	// in tests) can be defined here.
 
+	// FormatExperimentalValueSeparation is a format major version that adds
+	// support for value separation, separating values into external blob files
+	// that do not participate in every compaction.
+	//
+	// Note that value separation is currently experimental, and this format may
+	// be modified in backwards-incompatible ways.
+	FormatExperimentalValueSeparation
+
+	// FormatExperimentalLZ4Compression is a format major version enabling use
+	// of the TableFormatPebblev7 table format, which permits blocks compressed
+	// with LZ4 or MinLZ.
+	FormatExperimentalLZ4Compression
+
+	// FormatExperimentalCompressionDictionaries is a format major version
+	// enabling use of the TableFormatPebblev8 table format, which permits data
+	// blocks compressed with a zstd dictionary stored in the table.
+	FormatExperimentalCompressionDictionaries
+
 	// -- Add experimental versions here --
 
 	// internalFormatNewest is the most recent, possibly experimental format major
@@ -244,6 +262,12 @@ func (v FormatMajorVersion) MaxTableFormat() sstable.TableFormat {
 		return sstable.TableFormatPebblev4
 	case FormatColumnarBlocks, FormatWALSyncChunks:
 		return sstable.TableFormatPebblev5
+	case FormatExperimentalValueSeparation:
+		return sstable.TableFormatPebblev6
+	case FormatExperimentalLZ4Compression:
+		return sstable.TableFormatPebblev7
+	case FormatExperimentalCompressionDictionaries:
+		return sstable.TableFormatPebblev8
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
 	}
@@ -255,7 +279,9 @@ func (v FormatMajorVersion) MinTableFormat() sstable.TableFormat {
 	switch v {
 	case FormatDefault, FormatFlushableIngest, FormatPrePebblev1MarkedCompacted,
 		FormatDeleteSizedAndObsolete, FormatVirtualSSTables, FormatSyntheticPrefixSuffix,
-		FormatFlushableIngestExcises, FormatColumnarBlocks, FormatWALSyncChunks:
+		FormatFlushableIngestExcises, FormatColumnarBlocks, FormatWALSyncChunks,
+		FormatExperimentalValueSeparation, FormatExperimentalLZ4Compression,
+		FormatExperimentalCompressionDictionaries:
 		return sstable.TableFormatPebblev1
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
@@ -301,6 +327,15 @@ var formatMajorVersionMigrations = map[FormatMajorVersion]func(*DB) error{
 	FormatWALSyncChunks: func(d *DB) error {
 		return d.finalizeFormatVersUpgrade(FormatWALSyncChunks)
 	},
+	FormatExperimentalValueSeparation: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatExperimentalValueSeparation)
+	},
+	FormatExperimentalLZ4Compression: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatExperimentalLZ4Compression)
+	},
+	FormatExperimentalCompressionDictionaries: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatExperimentalCompressionDictionaries)
+	},
 }
 
 const formatVersionMarkerName = `format-version`
@@ -363,7 +398,11 @@ func (d *DB) TableFormat() sstable.TableFormat {
 		if d.opts.Experimental.EnableValueBlocks == nil || !d.opts.Experimental.EnableValueBlocks() {
 			f = sstable.TableFormatPebblev2
 		}
-	case sstable.TableFormatPebblev5:
+	case sstable.TableFormatPebblev5, sstable.TableFormatPebblev6, sstable.TableFormatPebblev7,
+		sstable.TableFormatPebblev8:
+		// Blob value handles (TableFormatPebblev6) are only supported by
+		// columnar blocks, so these formats are conditional on the columnar
+		// blocks setting.
 		if d.opts.Experimental.EnableColumnarBlocks == nil || !d.opts.Experimental.EnableColumnarBlocks() {
 			f = sstable.TableFormatPebblev4
 		}
@@ -499,10 +538,6 @@ func (d *DB) compactMarkedFilesLocked() error {
 // level.
 type findFilesFunc func(v *version) (found bool, files [numLevels][]*tableMetadata, _ error)
 
-// This method is not used currently, but it will be useful the next time we need
-// to mark files for compaction.
-var _ = (*DB)(nil).markFilesLocked
-
 // markFilesLocked durably marks the files that match the given findFilesFunc for
 // compaction.
 func (d *DB) markFilesLocked(findFn findFilesFunc) error {
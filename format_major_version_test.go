@@ -26,11 +26,15 @@ func TestFormatMajorVersionStableValues(t *testing.T) {
 	require.Equal(t, FormatSyntheticPrefixSuffix, FormatMajorVersion(17))
 	require.Equal(t, FormatFlushableIngestExcises, FormatMajorVersion(18))
 	require.Equal(t, FormatColumnarBlocks, FormatMajorVersion(19))
+	require.Equal(t, FormatWALSyncChunks, FormatMajorVersion(20))
+	require.Equal(t, FormatExperimentalValueSeparation, FormatMajorVersion(21))
+	require.Equal(t, FormatExperimentalLZ4Compression, FormatMajorVersion(22))
+	require.Equal(t, FormatExperimentalCompressionDictionaries, FormatMajorVersion(23))
 
 	// When we add a new version, we should add a check for the new version in
 	// addition to updating these expected values.
 	require.Equal(t, FormatNewest, FormatMajorVersion(20))
-	require.Equal(t, internalFormatNewest, FormatMajorVersion(20))
+	require.Equal(t, internalFormatNewest, FormatMajorVersion(23))
 }
 
 func TestFormatMajorVersion_MigrationDefined(t *testing.T) {
@@ -63,6 +67,12 @@ func TestRatchetFormat(t *testing.T) {
 	require.Equal(t, FormatColumnarBlocks, d.FormatMajorVersion())
 	require.NoError(t, d.RatchetFormatMajorVersion(FormatWALSyncChunks))
 	require.Equal(t, FormatWALSyncChunks, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatExperimentalValueSeparation))
+	require.Equal(t, FormatExperimentalValueSeparation, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatExperimentalLZ4Compression))
+	require.Equal(t, FormatExperimentalLZ4Compression, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatExperimentalCompressionDictionaries))
+	require.Equal(t, FormatExperimentalCompressionDictionaries, d.FormatMajorVersion())
 
 	require.NoError(t, d.Close())
 
@@ -212,15 +222,18 @@ func TestFormatMajorVersions_TableFormat(t *testing.T) {
 	// fixture is intentionally verbose.
 
 	m := map[FormatMajorVersion][2]sstable.TableFormat{
-		FormatDefault:                    {sstable.TableFormatPebblev1, sstable.TableFormatPebblev3},
-		FormatFlushableIngest:            {sstable.TableFormatPebblev1, sstable.TableFormatPebblev3},
-		FormatPrePebblev1MarkedCompacted: {sstable.TableFormatPebblev1, sstable.TableFormatPebblev3},
-		FormatDeleteSizedAndObsolete:     {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
-		FormatVirtualSSTables:            {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
-		FormatSyntheticPrefixSuffix:      {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
-		FormatFlushableIngestExcises:     {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
-		FormatColumnarBlocks:             {sstable.TableFormatPebblev1, sstable.TableFormatPebblev5},
-		FormatWALSyncChunks:              {sstable.TableFormatPebblev1, sstable.TableFormatPebblev5},
+		FormatDefault:                             {sstable.TableFormatPebblev1, sstable.TableFormatPebblev3},
+		FormatFlushableIngest:                     {sstable.TableFormatPebblev1, sstable.TableFormatPebblev3},
+		FormatPrePebblev1MarkedCompacted:          {sstable.TableFormatPebblev1, sstable.TableFormatPebblev3},
+		FormatDeleteSizedAndObsolete:              {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
+		FormatVirtualSSTables:                     {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
+		FormatSyntheticPrefixSuffix:               {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
+		FormatFlushableIngestExcises:              {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
+		FormatColumnarBlocks:                      {sstable.TableFormatPebblev1, sstable.TableFormatPebblev5},
+		FormatWALSyncChunks:                       {sstable.TableFormatPebblev1, sstable.TableFormatPebblev5},
+		FormatExperimentalValueSeparation:         {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
+		FormatExperimentalLZ4Compression:          {sstable.TableFormatPebblev1, sstable.TableFormatPebblev7},
+		FormatExperimentalCompressionDictionaries: {sstable.TableFormatPebblev1, sstable.TableFormatPebblev8},
 	}
 
 	// Valid versions.
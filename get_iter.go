@@ -32,6 +32,8 @@ type getIter struct {
 	l0       []manifest.LevelSlice
 	version  *version
 	iterKV   *base.InternalKV
+	// blobValueFetcher is used to retrieve values stored in blob files.
+	blobValueFetcher base.ValueFetcher
 	// tombstoned and tombstonedSeqNum track whether the key has been deleted by
 	// a range delete tombstone. The first visible (at getIter.snapshot) range
 	// deletion encounterd transitions tombstoned to true. The tombstonedSeqNum
@@ -271,7 +273,9 @@ func (g *getIter) getSSTableIterators(
 	}
 	// m may possibly contain point (or range deletion) keys relevant to g.key.
 	g.iterOpts.layer = level
-	iters, err := g.newIters(context.Background(), m, &g.iterOpts, internalIterOpts{}, iterPointKeys|iterRangeDeletions)
+	iters, err := g.newIters(context.Background(), m, &g.iterOpts, internalIterOpts{
+		blobValueFetcher: g.blobValueFetcher,
+	}, iterPointKeys|iterRangeDeletions)
 	if err != nil {
 		return emptyIter, nil, err
 	}
@@ -14,9 +14,11 @@ require (
 	github.com/ghemawat/stream v0.0.0-20171120220530-696b145b53b9
 	github.com/golang/snappy v0.0.5-0.20231225225746-43d5d4cd4e0e
 	github.com/guptarohit/asciigraph v0.5.5
-	github.com/klauspost/compress v1.16.7
+	github.com/klauspost/compress v1.17.11
 	github.com/kr/pretty v0.3.1
+	github.com/minio/minlz v1.0.0
 	github.com/olekukonko/tablewriter v0.0.5
+	github.com/pierrec/lz4/v4 v4.1.21
 	github.com/pkg/errors v0.9.1
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/prometheus/client_golang v1.12.0
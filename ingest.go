@@ -1428,7 +1428,7 @@ func (d *DB) ingest(
 	shared []SharedSSTMeta,
 	exciseSpan KeyRange,
 	external []ExternalFile,
-) (IngestOperationStats, error) {
+) (stats IngestOperationStats, err error) {
 	if len(shared) > 0 && d.opts.Experimental.RemoteStorage == nil {
 		panic("cannot ingest shared sstables with nil SharedStorage")
 	}
@@ -1456,6 +1456,16 @@ func (d *DB) ingest(
 	}
 
 	jobID := d.newJobID()
+	spanName := IngestSpanName
+	if len(pendingOutputs) == 0 {
+		spanName = ExciseSpanName
+	}
+	ctx, span := d.startJobSpan(ctx, spanName, jobID)
+	startTime := d.timeNow()
+	defer func() {
+		span.SetAttributes(SpanAttribute{Key: "duration", Value: d.timeNow().Sub(startTime).Seconds()})
+		span.End(err)
+	}()
 
 	// Load the metadata for all the files being ingested. This step detects
 	// and elides empty sstables.
@@ -1714,7 +1724,6 @@ func (d *DB) ingest(
 	// TODO(jackson): Refactor this so that the case where there are no files
 	// but a valid excise span is not so exceptional.
 
-	var stats IngestOperationStats
 	if loadResult.fileCount() > 0 {
 		info := TableIngestInfo{
 			JobID:     int(jobID),
@@ -1770,6 +1779,10 @@ func (d *DB) ingest(
 			}
 		}
 		d.opts.EventListener.TableIngested(info)
+		span.SetAttributes(info.spanAttributes(stats)...)
+	}
+	if exciseSpan.Valid() && ve != nil {
+		span.SetAttributes(SpanAttribute{Key: "excised_tables", Value: int64(len(ve.DeletedTables))})
 	}
 
 	return stats, err
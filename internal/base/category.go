@@ -0,0 +1,153 @@
+// Copyright 2018 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package base
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
+)
+
+// Category is a user-understandable string, where stats are aggregated for
+// each category, and by which I/O is scheduled. The cardinality of this should be low, say < 20. The prefix
+// "pebble-" is reserved for internal Pebble categories.
+//
+// Examples of categories that can be useful in the CockroachDB context are:
+// sql-user, sql-stats, raft, rangefeed, mvcc-gc, range-snapshot.
+type Category uint8
+
+// CategoryUnknown is the unknown category. It has the latency-sensitive QoS
+// level.
+const CategoryUnknown Category = 0
+
+// CategoryMax is the maximum value of a category, and is also the maximum
+// number of categories that can be registered.
+const CategoryMax = 30
+
+func (c Category) String() string {
+	return categories[c].name
+}
+
+// QoSLevel returns the QoSLevel associated with this Category.
+func (c Category) QoSLevel() QoSLevel {
+	return categories[c].qosLevel
+}
+
+// SafeFormat implements the redact.SafeFormatter interface.
+func (c Category) SafeFormat(p redact.SafePrinter, verb rune) {
+	p.SafeString(redact.SafeString(c.String()))
+}
+
+// RegisterCategory registers a new category. Each category has a name and an
+// associated QoS level. The category name must be unique.
+//
+// Only CategoryMax categories can be registered in total.
+func RegisterCategory(name string, qosLevel QoSLevel) Category {
+	if categoriesList != nil {
+		panic("ReigsterCategory called after Categories()")
+	}
+	c := Category(numRegisteredCategories.Add(1))
+	if c > CategoryMax {
+		panic("too many categories")
+	}
+	categories[c].name = name
+	categories[c].qosLevel = qosLevel
+	return c
+}
+
+// Categories returns all registered categories, including CategoryUnknown.
+//
+// Can only be called after all categories have been registered. Calling
+// RegisterCategory() after Categories() will result in a panic.
+func Categories() []Category {
+	categoriesListOnce.Do(func() {
+		categoriesList = make([]Category, numRegisteredCategories.Load()+1)
+		for i := range categoriesList {
+			categoriesList[i] = Category(i)
+		}
+	})
+	return categoriesList
+}
+
+var categories = [CategoryMax + 1]struct {
+	name     string
+	qosLevel QoSLevel
+}{
+	CategoryUnknown: {name: "unknown", qosLevel: LatencySensitiveQoSLevel},
+}
+
+var numRegisteredCategories atomic.Uint32
+
+var categoriesList []Category
+var categoriesListOnce sync.Once
+
+// StringToCategoryForTesting returns the Category for the string, or panics if
+// the string is not known.
+func StringToCategoryForTesting(s string) Category {
+	for i := range categories {
+		if categories[i].name == s {
+			return Category(i)
+		}
+	}
+	panic(errors.AssertionFailedf("unknown Category %s", s))
+}
+
+// QoSLevel describes whether the read is latency-sensitive or not. Each
+// category must map to a single QoSLevel. While category strings are opaque
+// to Pebble, the QoSLevel may be internally utilized in Pebble to better
+// optimize future reads.
+type QoSLevel uint8
+
+const (
+	// LatencySensitiveQoSLevel is the default when QoSLevel is not specified,
+	// and represents reads that are latency-sensitive.
+	LatencySensitiveQoSLevel QoSLevel = iota
+	// NonLatencySensitiveQoSLevel represents reads that are not
+	// latency-sensitive.
+	NonLatencySensitiveQoSLevel
+)
+
+// SafeFormat implements the redact.SafeFormatter interface.
+func (q QoSLevel) SafeFormat(p redact.SafePrinter, verb rune) {
+	switch q {
+	case LatencySensitiveQoSLevel:
+		p.Printf("latency")
+	case NonLatencySensitiveQoSLevel:
+		p.Printf("non-latency")
+	default:
+		p.Printf("<unknown-qos>")
+	}
+}
+
+// StringToQoSForTesting returns the QoSLevel for the string, or panics if the
+// string is not known.
+func StringToQoSForTesting(s string) QoSLevel {
+	switch s {
+	case "latency":
+		return LatencySensitiveQoSLevel
+	case "non-latency":
+		return NonLatencySensitiveQoSLevel
+	}
+	panic(errors.AssertionFailedf("unknown QoS %s", s))
+}
+
+type categoryKey struct{}
+
+// WithCategory returns a context that has an associated Category. The I/O
+// performed with the context is scheduled according to the category (see
+// objstorageprovider.IOScheduler).
+func WithCategory(ctx context.Context, c Category) context.Context {
+	return context.WithValue(ctx, categoryKey{}, c)
+}
+
+// CategoryFromContext returns the Category associated with the context, or
+// CategoryUnknown if there is none.
+func CategoryFromContext(ctx context.Context) Category {
+	c, _ := ctx.Value(categoryKey{}).(Category)
+	return c
+}
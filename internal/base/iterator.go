@@ -417,6 +417,10 @@ type InternalIteratorStats struct {
 		// blocks) that were retrieved.
 		ValueBytesFetched uint64
 	}
+	// The number of sstables whose point keys were not read because a range
+	// filter proved that the table contains no keys within the iterator's
+	// bounds.
+	RangeFilterSkippedTables uint64
 }
 
 // Merge merges the stats in from into the given stats.
@@ -431,6 +435,7 @@ func (s *InternalIteratorStats) Merge(from InternalIteratorStats) {
 	s.SeparatedPointValue.Count += from.SeparatedPointValue.Count
 	s.SeparatedPointValue.ValueBytes += from.SeparatedPointValue.ValueBytes
 	s.SeparatedPointValue.ValueBytesFetched += from.SeparatedPointValue.ValueBytesFetched
+	s.RangeFilterSkippedTables += from.RangeFilterSkippedTables
 }
 
 func (s *InternalIteratorStats) String() string {
@@ -463,6 +468,9 @@ func (s *InternalIteratorStats) SafeFormat(p redact.SafePrinter, verb rune) {
 			humanize.Bytes.Uint64(s.SeparatedPointValue.ValueBytes),
 			humanize.Bytes.Uint64(s.SeparatedPointValue.ValueBytesFetched))
 	}
+	if s.RangeFilterSkippedTables != 0 {
+		p.Printf("; range filter skipped tables: %s", humanize.Count.Uint64(s.RangeFilterSkippedTables))
+	}
 }
 
 // IteratorDebug is an interface implemented by all internal iterators and
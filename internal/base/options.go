@@ -12,18 +12,26 @@ const (
 	SizeClassAwareBlockSizeThreshold = 60
 )
 
-// FilterType is the level at which to apply a filter: block or table.
+// FilterType is the kind of filter to build for a table.
 type FilterType int
 
 // The available filter types.
 const (
+	// TableFilter is a filter over the key prefixes of a table that answers
+	// point queries.
 	TableFilter FilterType = iota
+	// RangeFilter is a filter over the key prefixes of a table that answers
+	// both point queries and range queries. It requires a FilterPolicy that
+	// implements RangeFilterPolicy.
+	RangeFilter
 )
 
 func (t FilterType) String() string {
 	switch t {
 	case TableFilter:
 		return "table"
+	case RangeFilter:
+		return "range"
 	}
 	return "unknown"
 }
@@ -65,6 +73,24 @@ type FilterPolicy interface {
 	NewWriter(ftype FilterType) FilterWriter
 }
 
+// RangeFilterPolicy is a FilterPolicy that also supports the RangeFilter filter
+// type. A range filter can determine that a table contains no keys within a
+// range, allowing short bounded scans to skip the table.
+//
+// The keys added to a range filter are key prefixes (see Split), which are
+// added in increasing order. Since prefixes are ordered bytewise, a range
+// filter may rely on the bytewise ordering of the keys it is built from.
+type RangeFilterPolicy interface {
+	FilterPolicy
+
+	// MayContainRange returns whether the encoded RangeFilter filter may
+	// contain a key k such that lower <= k <= upper, compared bytewise. Both
+	// bounds are inclusive, since they are typically the prefixes of the
+	// iteration bounds. A nil bound indicates the range is unbounded on that
+	// side. False positives are possible.
+	MayContainRange(filter, lower, upper []byte) bool
+}
+
 // BlockPropertyFilter is used in an Iterator to filter sstables and blocks
 // within the sstable. It should not maintain any per-sstable state, and must
 // be thread-safe.
@@ -78,6 +78,11 @@ type Cache struct {
 	idAlloc atomic.Uint64
 	shards  []shard
 
+	// secondary maps the handleIDs of the handles that have a SecondaryCache to
+	// their SecondaryCache; numSecondary is the number of such handles.
+	secondary    sync.Map
+	numSecondary atomic.Int32
+
 	// Traces recorded by Cache.trace. Used for debugging.
 	tr struct {
 		sync.Mutex
@@ -133,6 +138,7 @@ func newCache(size int64, shards int) *Cache {
 	c.trace("alloc", c.refs.Load())
 	for i := range c.shards {
 		c.shards[i].init(size / int64(len(c.shards)))
+		c.shards[i].cache = c
 	}
 
 	// Note: this is a no-op if invariants are disabled or race is enabled.
@@ -239,6 +245,34 @@ func (c *Cache) getShard(k key) *shard {
 	return &c.shards[idx]
 }
 
+// SecondaryCache is a second cache tier, typically backed by local flash, that
+// receives the blocks evicted from the cache because of memory pressure.
+// Blocks removed from the cache for other reasons (e.g. because their file was
+// deleted) are not offered to the secondary cache.
+//
+// The cache values are opaque to the cache, and a SecondaryCache may store
+// only a part of them, as agreed with the users of Get. In Pebble, the values
+// are sstable blocks and only the block data (without the block metadata) is
+// stored.
+type SecondaryCache interface {
+	// Spill offers a block evicted from the cache to the secondary cache. It is
+	// invoked after the cache shard is unlocked, on the goroutine that caused
+	// the eviction; buf is the buffer of the evicted value and must not be
+	// retained after Spill returns.
+	Spill(fileNum base.DiskFileNum, offset uint64, buf []byte)
+	// Get returns the stored contents of the block at the given file and offset
+	// if it is in the secondary cache.
+	Get(fileNum base.DiskFileNum, offset uint64) ([]byte, bool)
+}
+
+// spill offers the value of an entry evicted from the cache to the
+// SecondaryCache of its handle, if any.
+func (c *Cache) spill(k key, v *Value) {
+	if sc, ok := c.secondary.Load(k.id); ok {
+		sc.(SecondaryCache).Spill(k.fileNum, k.offset, v.RawBuffer())
+	}
+}
+
 // Handle is the interface through which a store uses the cache. Each store uses
 // a separate "handle". A handle corresponds to a separate "namespace" inside
 // the cache; a handle cannot see another handle's blocks.
@@ -321,6 +355,34 @@ func (c *Handle) Delete(fileNum base.DiskFileNum, offset uint64) {
 	c.cache.getShard(k).delete(k)
 }
 
+// SetSecondaryCache sets the SecondaryCache that receives the blocks of this
+// handle that are evicted from the cache. It replaces any previously set
+// SecondaryCache; a nil SecondaryCache disables spilling.
+func (c *Handle) SetSecondaryCache(sc SecondaryCache) {
+	var loaded bool
+	if sc == nil {
+		_, loaded = c.cache.secondary.LoadAndDelete(c.id)
+		if loaded {
+			c.cache.numSecondary.Add(-1)
+		}
+		return
+	}
+	if _, loaded = c.cache.secondary.Swap(c.id, sc); !loaded {
+		c.cache.numSecondary.Add(1)
+	}
+}
+
+// SecondaryCache returns the SecondaryCache set on the handle, or nil.
+func (c *Handle) SecondaryCache() SecondaryCache {
+	if c.cache.numSecondary.Load() == 0 {
+		return nil
+	}
+	if sc, ok := c.cache.secondary.Load(c.id); ok {
+		return sc.(SecondaryCache)
+	}
+	return nil
+}
+
 // EvictFile evicts all cache values for the specified file.
 func (c *Handle) EvictFile(fileNum base.DiskFileNum) {
 	for i := range c.cache.shards {
@@ -329,6 +391,7 @@ func (c *Handle) EvictFile(fileNum base.DiskFileNum) {
 }
 
 func (c *Handle) Close() {
+	c.SetSecondaryCache(nil)
 	c.cache.Unref()
 	*c = Handle{}
 }
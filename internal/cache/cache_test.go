@@ -12,6 +12,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -168,6 +169,62 @@ func TestMultipleDBs(t *testing.T) {
 	v.Release()
 }
 
+type testSecondaryCache struct {
+	t       *testing.T
+	cache   *Cache
+	spilled map[key]string
+}
+
+func (sc *testSecondaryCache) Spill(fileNum base.DiskFileNum, offset uint64, buf []byte) {
+	// Values are spilled after the shard is unlocked.
+	for i := range sc.cache.shards {
+		mu := &sc.cache.shards[i].mu
+		require.True(sc.t, mu.TryLock())
+		mu.Unlock()
+	}
+	sc.spilled[key{fileNum: fileNum, offset: offset}] = string(buf)
+}
+
+func (sc *testSecondaryCache) Get(fileNum base.DiskFileNum, offset uint64) ([]byte, bool) {
+	v, ok := sc.spilled[key{fileNum: fileNum, offset: offset}]
+	return []byte(v), ok
+}
+
+func TestSecondaryCache(t *testing.T) {
+	cache := newCache(100, 1)
+	defer cache.Unref()
+	h1 := cache.NewHandle()
+	defer h1.Close()
+	h2 := cache.NewHandle()
+	defer h2.Close()
+	sc := &testSecondaryCache{t: t, cache: cache, spilled: make(map[key]string)}
+	h1.SetSecondaryCache(sc)
+	require.Equal(t, SecondaryCache(sc), h1.SecondaryCache())
+	require.Nil(t, h2.SecondaryCache())
+
+	// Blocks removed from the cache because their file is evicted are not
+	// spilled.
+	setTestValue(h1, 0, 0, "a", 5)
+	h1.EvictFile(base.DiskFileNum(0))
+	require.Empty(t, sc.spilled)
+
+	// Blocks evicted because of memory pressure are spilled, but only for the
+	// handle with a secondary cache.
+	for i := 0; i < 10; i++ {
+		setTestValue(h1, base.DiskFileNum(i), 0, fmt.Sprint(i), 20)
+		setTestValue(h2, base.DiskFileNum(i), 0, "b", 20)
+	}
+	require.NotEmpty(t, sc.spilled)
+	for k, v := range sc.spilled {
+		require.Equal(t, strings.Repeat(fmt.Sprint(uint64(k.fileNum)), 20), v)
+		require.Nil(t, h1.Get(k.fileNum, k.offset))
+	}
+
+	h1.SetSecondaryCache(nil)
+	require.Nil(t, h1.SecondaryCache())
+	require.Zero(t, cache.numSecondary.Load())
+}
+
 func TestZeroSize(t *testing.T) {
 	c := New(0)
 	defer c.Unref()
@@ -80,8 +80,17 @@ type shard struct {
 	hits   atomic.Int64
 	misses atomic.Int64
 
+	// cache is the Cache containing the shard; it is used to spill evicted
+	// values to a SecondaryCache.
+	cache *Cache
+
 	mu sync.RWMutex
 
+	// spills holds the values evicted while mu was held that must be offered to
+	// a SecondaryCache. They are spilled once mu is released (see
+	// unlockAndSpill), and hold a reference until then.
+	spills []spilledValue
+
 	reservedSize int64
 	maxSize      int64
 	coldTarget   int64
@@ -164,7 +173,7 @@ func (c *shard) set(k key, value *Value) {
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndSpill()
 
 	e, _ := c.blocks.Get(k)
 
@@ -236,6 +245,25 @@ func (c *shard) set(k key, value *Value) {
 	c.checkConsistency()
 }
 
+// spilledValue is a value evicted from a shard that is waiting to be offered to
+// a SecondaryCache.
+type spilledValue struct {
+	key key
+	val *Value
+}
+
+// unlockAndSpill releases c.mu and then offers the values evicted while it was
+// held to the SecondaryCache, so that spilling doesn't block the shard.
+func (c *shard) unlockAndSpill() {
+	spills := c.spills
+	c.spills = nil
+	c.mu.Unlock()
+	for _, s := range spills {
+		c.cache.spill(s.key, s.val)
+		s.val.Release()
+	}
+}
+
 func (c *shard) checkConsistency() {
 	// See the comment above the count{Hot,Cold,Test} fields.
 	switch {
@@ -351,7 +379,7 @@ func (c *shard) Free() {
 
 func (c *shard) Reserve(n int) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndSpill()
 	c.reservedSize += int64(n)
 
 	// Changing c.reservedSize will either increase or decrease
@@ -570,6 +598,10 @@ func (c *shard) runHandCold(countColdDebug, sizeColdDebug int64) {
 			c.sizeHot += e.size
 			c.countHot++
 		} else {
+			if c.cache != nil && e.val != nil && c.cache.numSecondary.Load() != 0 {
+				e.val.acquire()
+				c.spills = append(c.spills, spilledValue{key: e.key, val: e.val})
+			}
 			e.setValue(nil)
 			e.ptype = etTest
 			c.sizeCold -= e.size
@@ -194,7 +194,13 @@ type Iter struct {
 	// advanced.
 	valueBuf []byte
 	// valueFetcher is used by saveValue when Cloning InternalValues.
-	valueFetcher     base.LazyFetcher
+	valueFetcher base.LazyFetcher
+	// filterBuf is used to retrieve values passed to cfg.IsExpired and
+	// cfg.Filter.
+	filterBuf []byte
+	// filteredValueBuf holds the value returned by cfg.Filter when it changes
+	// the value of the current key.
+	filteredValueBuf []byte
 	iterKV           *base.InternalKV
 	iterStripeChange stripeChangeType
 	// skip indicates whether the remaining entries in the current snapshot
@@ -285,8 +291,39 @@ type IterConfig struct {
 	// Set/SetWithDelete/Merge. False positives are rare but possible (because of
 	// delete-only compactions).
 	NondeterministicSingleDeleteCallback func(userKey []byte)
+
+	// IsExpired, if non-nil, is called with the user key and value of SET and
+	// SETWITHDEL keys. If it returns true, the key has expired and is treated
+	// as a DEL with the same sequence number: it shadows older versions of the
+	// key within its snapshot stripe and is elided along with them whenever a
+	// DEL would be.
+	IsExpired func(userKey, value []byte) bool
+
+	// Filter, if non-nil, is called with the user key and value of SET and
+	// SETWITHDEL keys that are the most recent version of their user key and
+	// are not visible to any snapshot (i.e. they belong to the most recent
+	// snapshot stripe). Keys visible to snapshots are never filtered, so that
+	// snapshots continue to observe consistent data.
+	//
+	// If the filter returns FilterRemove, the key is treated as a DEL with the
+	// same sequence number. If it returns FilterChangeValue, the key is emitted
+	// with the returned value.
+	Filter func(userKey, value []byte) (FilterDecision, []byte)
 }
 
+// FilterDecision is the outcome of applying IterConfig.Filter to a key.
+type FilterDecision int8
+
+const (
+	// FilterKeep retains the key and its value.
+	FilterKeep FilterDecision = iota
+	// FilterRemove removes the key, deleting it along with the older versions
+	// of the key within its snapshot stripe.
+	FilterRemove
+	// FilterChangeValue retains the key, replacing its value.
+	FilterChangeValue
+)
+
 func (c *IterConfig) ensureDefaults() {
 	if c.IneffectualSingleDeleteCallback == nil {
 		c.IneffectualSingleDeleteCallback = func(userKey []byte) {}
@@ -524,7 +561,19 @@ func (i *Iter) Next() *base.InternalKV {
 			i.forceObsoleteDueToRangeDel = false
 		}
 
-		switch i.iterKV.Kind() {
+		kind := i.iterKV.Kind()
+		decision := FilterKeep
+		if kind == base.InternalKeyKindSet || kind == base.InternalKeyKindSetWithDelete {
+			// An expired or filtered out SET or SETWITHDEL is processed as if
+			// it were a DEL.
+			if decision = i.filter(); i.err != nil {
+				return nil
+			} else if decision == FilterRemove {
+				kind = base.InternalKeyKindDelete
+			}
+		}
+
+		switch kind {
 		case base.InternalKeyKindDelete, base.InternalKeyKindSingleDelete, base.InternalKeyKindDeleteSized:
 			if i.delElider.ShouldElide(i.iterKV.K.UserKey) {
 				if i.curSnapshotIdx == 0 {
@@ -551,9 +600,11 @@ func (i *Iter) Next() *base.InternalKV {
 				}
 			}
 
-			switch i.iterKV.Kind() {
+			switch kind {
 			case base.InternalKeyKindDelete:
 				i.saveKey()
+				// The key may be a removed SET or SETWITHDEL.
+				i.kv.K.SetKind(base.InternalKeyKindDelete)
 				i.kv.V = base.InternalValue{} // DELs are value-less.
 				i.skip = true
 				return &i.kv
@@ -587,6 +638,9 @@ func (i *Iter) Next() *base.InternalKV {
 			if i.err != nil {
 				return nil
 			}
+			if decision == FilterChangeValue {
+				i.kv.V = base.MakeInPlaceValue(i.filteredValueBuf)
+			}
 			return &i.kv
 
 		case base.InternalKeyKindMerge:
@@ -892,6 +946,14 @@ func (i *Iter) mergeNext(valueMerger base.ValueMerger) {
 			if callerOwned && cap(v) > cap(i.valueBuf) {
 				i.valueBuf = v
 			}
+			if i.cfg.IsExpired != nil && i.cfg.IsExpired(i.iterKV.K.UserKey, v) {
+				// The expired value is deleted, so the merge result must not
+				// include it, but must still shadow older keys: MERGE+expired
+				// SET -> SETWITHDEL.
+				i.kv.K.SetKind(base.InternalKeyKindSetWithDelete)
+				i.skip = true
+				return
+			}
 			i.err = valueMerger.MergeOlder(v)
 			if i.err != nil {
 				return
@@ -917,6 +979,36 @@ func (i *Iter) mergeNext(valueMerger base.ValueMerger) {
 	}
 }
 
+// filter applies cfg.IsExpired and cfg.Filter to the current SET or SETWITHDEL
+// key. If it returns FilterChangeValue, the new value is stored in
+// i.filteredValueBuf. It sets i.err if the value cannot be retrieved.
+func (i *Iter) filter() FilterDecision {
+	// Keys visible to a snapshot must not be filtered.
+	applyFilter := i.cfg.Filter != nil && i.curSnapshotIdx == len(i.cfg.Snapshots)
+	if i.cfg.IsExpired == nil && !applyFilter {
+		return FilterKeep
+	}
+	v, callerOwned, err := i.iterKV.Value(i.filterBuf[:0])
+	if err != nil {
+		i.err = err
+		return FilterKeep
+	}
+	if callerOwned && cap(v) > cap(i.filterBuf) {
+		i.filterBuf = v[:0]
+	}
+	if i.cfg.IsExpired != nil && i.cfg.IsExpired(i.iterKV.K.UserKey, v) {
+		return FilterRemove
+	}
+	if !applyFilter {
+		return FilterKeep
+	}
+	decision, newValue := i.cfg.Filter(i.iterKV.K.UserKey, v)
+	if decision == FilterChangeValue {
+		i.filteredValueBuf = append(i.filteredValueBuf[:0], newValue...)
+	}
+	return decision
+}
+
 // singleDeleteNext processes a SingleDelete point tombstone. A SingleDelete, or
 // SINGLEDEL, is unique in that it deletes exactly 1 internal key. It's a
 // performance optimization when the client knows a user key has not been
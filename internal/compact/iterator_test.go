@@ -56,6 +56,8 @@ func TestCompactionIter(t *testing.T) {
 	var snapshots Snapshots
 	var elideTombstones bool
 	var allowZeroSeqnum bool
+	var expiredValues []string
+	var filterRemoveValues, filterChangeValues []string
 	var ineffectualSingleDeleteKeys []string
 	var invariantViolationSingleDeleteKeys []string
 	resetSingleDelStats := func() {
@@ -90,6 +92,23 @@ func TestCompactionIter(t *testing.T) {
 				invariantViolationSingleDeleteKeys = append(invariantViolationSingleDeleteKeys, string(userKey))
 			},
 		}
+		if len(expiredValues) > 0 {
+			cfg.IsExpired = func(userKey, value []byte) bool {
+				return slices.Contains(expiredValues, string(value))
+			}
+		}
+		if len(filterRemoveValues) > 0 || len(filterChangeValues) > 0 {
+			cfg.Filter = func(userKey, value []byte) (FilterDecision, []byte) {
+				switch {
+				case slices.Contains(filterRemoveValues, string(value)):
+					return FilterRemove, nil
+				case slices.Contains(filterChangeValues, string(value)):
+					return FilterChangeValue, append(value, "-changed"...)
+				default:
+					return FilterKeep, nil
+				}
+			}
+		}
 		pointIter, rangeDelIter, rangeKeyIter := makeInputIters(kvs, rangeDels, rangeKeys)
 		return NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter)
 	}
@@ -155,6 +174,9 @@ func TestCompactionIter(t *testing.T) {
 				snapshots = snapshots[:0]
 				elideTombstones = false
 				allowZeroSeqnum = false
+				expiredValues = expiredValues[:0]
+				filterRemoveValues = filterRemoveValues[:0]
+				filterChangeValues = filterChangeValues[:0]
 				printSnapshotPinned := false
 				printMissizedDels := false
 				printForceObsolete := false
@@ -176,6 +198,12 @@ func TestCompactionIter(t *testing.T) {
 						if err != nil {
 							return err.Error()
 						}
+					case "expired":
+						expiredValues = append(expiredValues, arg.Vals...)
+					case "filter-remove":
+						filterRemoveValues = append(filterRemoveValues, arg.Vals...)
+					case "filter-change":
+						filterChangeValues = append(filterChangeValues, arg.Vals...)
 					case "print-snapshot-pinned":
 						printSnapshotPinned = true
 					case "print-missized-dels":
@@ -275,6 +303,8 @@ func TestCompactionIter(t *testing.T) {
 	runTest(t, "testdata/iter")
 	runTest(t, "testdata/iter_set_with_del")
 	runTest(t, "testdata/iter_delete_sized")
+	runTest(t, "testdata/iter_expired")
+	runTest(t, "testdata/iter_filter")
 }
 
 // mockBlobValueFetcher is a dummy ValueFetcher implementation which produces
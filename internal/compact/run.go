@@ -14,17 +14,22 @@ import (
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/blob"
 )
 
 // Result stores the result of a compaction - more specifically, the "data" part
 // where we use the compaction iterator to write output tables.
 type Result struct {
 	// Err is the result of the compaction. On success, Err is nil and Tables
-	// stores the output tables. On failure, Err is set and Tables stores the
-	// tables created so far (and which need to be cleaned up).
+	// stores the output tables and blob files. On failure, Err is set and
+	// Tables and Blobs store the objects created so far (and which need to be
+	// cleaned up).
 	Err    error
 	Tables []OutputTable
-	Stats  Stats
+	// Blobs stores the blob files created so far. Like Tables, on failure these
+	// need to be cleaned up.
+	Blobs []OutputBlob
+	Stats Stats
 }
 
 // WithError returns a modified Result which has the Err field set.
@@ -32,6 +37,7 @@ func (r Result) WithError(err error) Result {
 	return Result{
 		Err:    errors.CombineErrors(r.Err, err),
 		Tables: r.Tables,
+		Blobs:  r.Blobs,
 		Stats:  r.Stats,
 	}
 }
@@ -44,6 +50,23 @@ type OutputTable struct {
 	// WriterMeta is populated once the table is fully written. On compaction
 	// failure (see Result), WriterMeta might not be set.
 	WriterMeta sstable.WriterMetadata
+	// BlobReferences is the list of blob files referenced by the table.
+	BlobReferences []manifest.BlobReference
+	// BlobReferenceDepth is the depth of the blob references for the table.
+	BlobReferenceDepth int
+}
+
+// OutputBlob contains metadata about a blob file that was created during a
+// compaction.
+type OutputBlob struct {
+	// ObjMeta is metadata for the object backing the blob file.
+	ObjMeta objstorage.ObjectMetadata
+	// Stats holds statistics about the content of the blob file. On compaction
+	// failure (see Result), Stats might not be set.
+	Stats blob.FileWriterStats
+	// Metadata is the metadata for the blob file, to be installed in the
+	// version edit. On compaction failure (see Result), Metadata might be nil.
+	Metadata *manifest.BlobFileMetadata
 }
 
 // Stats describes stats collected during the compaction.
@@ -52,6 +75,71 @@ type Stats struct {
 	CumulativePinnedSize  uint64
 	CumulativeWrittenSize uint64
 	CountMissizedDels     uint64
+	// CumulativeBlobFileSize is the total size of the blob files written
+	// during the compaction. It is included in CumulativeWrittenSize.
+	CumulativeBlobFileSize uint64
+}
+
+// ValueSeparation defines an interface for writing some values to separate blob
+// files.
+type ValueSeparation interface {
+	// EstimatedFileSize returns an estimate of the disk space consumed by the
+	// current, pending blob file if it were closed now.
+	EstimatedFileSize() uint64
+	// EstimatedReferenceSize returns an estimate of the disk space consumed by
+	// the values referenced by the current output sstable. It's used to
+	// account for the blob values when deciding where to split output tables.
+	EstimatedReferenceSize() uint64
+	// Add adds the provided key-value pair to the provided sstable writer,
+	// possibly separating the value into a blob file.
+	Add(tw sstable.RawWriter, kv *base.InternalKV, forceObsolete bool) error
+	// FinishOutput is called when a compaction is finishing an output sstable.
+	// It returns the blob references for the sstable and, if a new blob file
+	// was written, the metadata describing it.
+	FinishOutput() (ValueSeparationMetadata, error)
+}
+
+// ValueSeparationMetadata describes metadata about a table's blob references,
+// and optionally a newly constructed blob file.
+type ValueSeparationMetadata struct {
+	BlobReferences     []manifest.BlobReference
+	BlobReferenceSize  uint64
+	BlobReferenceDepth int
+
+	// The below fields are only populated if a new blob file was created.
+	BlobFileStats    blob.FileWriterStats
+	BlobFileObject   objstorage.ObjectMetadata
+	BlobFileMetadata *manifest.BlobFileMetadata
+}
+
+// NeverSeparateValues is a ValueSeparation implementation that never separates
+// values into external blob files. Any values that are already stored in blob
+// files are retrieved and written in-place to the sstable.
+type NeverSeparateValues struct{}
+
+// Assert that NeverSeparateValues implements the ValueSeparation interface.
+var _ ValueSeparation = NeverSeparateValues{}
+
+// EstimatedFileSize implements the ValueSeparation interface.
+func (NeverSeparateValues) EstimatedFileSize() uint64 { return 0 }
+
+// EstimatedReferenceSize implements the ValueSeparation interface.
+func (NeverSeparateValues) EstimatedReferenceSize() uint64 { return 0 }
+
+// Add implements the ValueSeparation interface.
+func (NeverSeparateValues) Add(
+	tw sstable.RawWriter, kv *base.InternalKV, forceObsolete bool,
+) error {
+	v, _, err := kv.Value(nil)
+	if err != nil {
+		return err
+	}
+	return tw.AddWithForceObsolete(kv.K, v, forceObsolete)
+}
+
+// FinishOutput implements the ValueSeparation interface.
+func (NeverSeparateValues) FinishOutput() (ValueSeparationMetadata, error) {
+	return ValueSeparationMetadata{}, nil
 }
 
 // RunnerConfig contains the parameters needed for the Runner.
@@ -86,6 +174,11 @@ type RunnerConfig struct {
 
 	// IteratorStats is the stats collected by the compaction iterator.
 	IteratorStats *base.InternalIteratorStats
+
+	// ExpiresAt, if non-nil, extracts the expiration time of SET and SETWITHDEL
+	// keys. It is used to record a manifest.ExpiryHistogram in the properties
+	// of each output table.
+	ExpiresAt func(userKey, value []byte) (expiresAt time.Time, ok bool)
 }
 
 // Runner is a helper for running the "data" part of a compaction (where we use
@@ -96,7 +189,7 @@ type RunnerConfig struct {
 //	r := NewRunner(cfg, iter)
 //	for r.MoreDataToWrite() {
 //	  objMeta, tw := ... // Create object and table writer.
-//	  r.WriteTable(objMeta, tw)
+//	  r.WriteTable(objMeta, tw, NeverSeparateValues{})
 //	}
 //	result := r.Finish()
 type Runner struct {
@@ -105,6 +198,7 @@ type Runner struct {
 	iter *Iter
 
 	tables []OutputTable
+	blobs  []OutputBlob
 	// Stores any error encountered.
 	err error
 	// Last key/value returned by the compaction iterator.
@@ -114,6 +208,8 @@ type Runner struct {
 	// Last range key span (or portion of it) that was not yet written to a table.
 	lastRangeKeySpan keyspan.Span
 	stats            Stats
+	// expiryBuf is used to retrieve the values passed to cfg.ExpiresAt.
+	expiryBuf []byte
 }
 
 // NewRunner creates a new Runner.
@@ -138,8 +234,14 @@ func (r *Runner) MoreDataToWrite() bool {
 // WriteTable writes a new output table. This table will be part of
 // Result.Tables. Should only be called if MoreDataToWrite() returned true.
 //
+// Values are written to the table through the provided ValueSeparation, which
+// may write some of them to a blob file instead. Any blob file created while
+// writing the table will be part of Result.Blobs.
+//
 // WriteTable always closes the Writer.
-func (r *Runner) WriteTable(objMeta objstorage.ObjectMetadata, tw sstable.RawWriter) {
+func (r *Runner) WriteTable(
+	objMeta objstorage.ObjectMetadata, tw sstable.RawWriter, valueSeparation ValueSeparation,
+) {
 	if r.err != nil {
 		panic("error already encountered")
 	}
@@ -147,7 +249,21 @@ func (r *Runner) WriteTable(objMeta objstorage.ObjectMetadata, tw sstable.RawWri
 		CreationTime: time.Now(),
 		ObjMeta:      objMeta,
 	})
-	splitKey, err := r.writeKeysToTable(tw)
+	splitKey, err := r.writeKeysToTable(tw, valueSeparation)
+
+	// Inform the value separation policy that the table is finished. If it
+	// created a blob file, it's closed here.
+	valSepMeta, valSepErr := valueSeparation.FinishOutput()
+	if valSepMeta.BlobFileMetadata != nil {
+		r.blobs = append(r.blobs, OutputBlob{
+			ObjMeta:  valSepMeta.BlobFileObject,
+			Stats:    valSepMeta.BlobFileStats,
+			Metadata: valSepMeta.BlobFileMetadata,
+		})
+		r.stats.CumulativeWrittenSize += valSepMeta.BlobFileStats.FileLen
+		r.stats.CumulativeBlobFileSize += valSepMeta.BlobFileStats.FileLen
+	}
+	err = errors.CombineErrors(err, valSepErr)
 	err = errors.CombineErrors(err, tw.Close())
 	if err != nil {
 		r.err = err
@@ -163,11 +279,16 @@ func (r *Runner) WriteTable(objMeta objstorage.ObjectMetadata, tw sstable.RawWri
 		r.err = err
 		return
 	}
-	r.tables[len(r.tables)-1].WriterMeta = *writerMeta
+	t := &r.tables[len(r.tables)-1]
+	t.WriterMeta = *writerMeta
+	t.BlobReferences = valSepMeta.BlobReferences
+	t.BlobReferenceDepth = valSepMeta.BlobReferenceDepth
 	r.stats.CumulativeWrittenSize += writerMeta.Size
 }
 
-func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ error) {
+func (r *Runner) writeKeysToTable(
+	tw sstable.RawWriter, valueSeparation ValueSeparation,
+) (splitKey []byte, _ error) {
 	const updateSlotEveryNKeys = 1024
 	firstKey := base.MinUserKey(r.cmp, spanStartOrNil(&r.lastRangeDelSpan), spanStartOrNil(&r.lastRangeKeySpan))
 	if r.kv != nil && firstKey == nil {
@@ -183,15 +304,22 @@ func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ erro
 	equalPrev := func(k []byte) bool {
 		return tw.ComparePrev(k) == 0
 	}
+	// estimatedSize returns the estimated size of the output table, including
+	// the values it references in blob files.
+	estimatedSize := func() uint64 {
+		return tw.EstimatedSize() + valueSeparation.EstimatedReferenceSize()
+	}
 	var pinnedKeySize, pinnedValueSize, pinnedCount uint64
 	var iteratedKeys uint64
+	var expiry manifest.ExpiryHistogram
 	kv := r.kv
 	for ; kv != nil; kv = r.iter.Next() {
 		iteratedKeys++
 		if iteratedKeys%updateSlotEveryNKeys == 0 {
-			r.cfg.Slot.UpdateMetrics(r.cfg.IteratorStats.BlockBytes, r.stats.CumulativeWrittenSize+tw.EstimatedSize())
+			r.cfg.Slot.UpdateMetrics(r.cfg.IteratorStats.BlockBytes,
+				r.stats.CumulativeWrittenSize+tw.EstimatedSize()+valueSeparation.EstimatedFileSize())
 		}
-		if splitter.ShouldSplitBefore(kv.K.UserKey, tw.EstimatedSize(), equalPrev) {
+		if splitter.ShouldSplitBefore(kv.K.UserKey, estimatedSize(), equalPrev) {
 			break
 		}
 
@@ -214,12 +342,13 @@ func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ erro
 			r.lastRangeKeySpan.CopyFrom(r.iter.Span())
 			continue
 		}
-		v, _, err := kv.Value(nil)
-		if err != nil {
+		if err := valueSeparation.Add(tw, kv, r.iter.ForceObsoleteDueToRangeDel()); err != nil {
 			return nil, err
 		}
-		if err := tw.AddWithForceObsolete(kv.K, v, r.iter.ForceObsoleteDueToRangeDel()); err != nil {
-			return nil, err
+		if r.cfg.ExpiresAt != nil {
+			if err := r.addExpiry(&expiry, kv); err != nil {
+				return nil, err
+			}
 		}
 		if r.iter.SnapshotPinned() {
 			// The kv pair we just added to the sstable was only surfaced by
@@ -227,7 +356,7 @@ func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ erro
 			// its elision. Increment the stats.
 			pinnedCount++
 			pinnedKeySize += uint64(len(kv.K.UserKey)) + base.InternalTrailerLen
-			pinnedValueSize += uint64(len(v))
+			pinnedValueSize += uint64(kv.V.Len())
 		}
 	}
 	r.kv = kv
@@ -240,12 +369,40 @@ func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ erro
 	}
 	// Set internal sstable properties.
 	tw.SetSnapshotPinnedProperties(pinnedCount, pinnedKeySize, pinnedValueSize)
+	if expiry.ExpiringBytes > 0 {
+		tw.SetExpiryHistogram(expiry.Encode(nil))
+	}
 	r.stats.CumulativePinnedKeys += pinnedCount
 	r.stats.CumulativePinnedSize += pinnedKeySize + pinnedValueSize
-	r.cfg.Slot.UpdateMetrics(r.cfg.IteratorStats.BlockBytes, r.stats.CumulativeWrittenSize+tw.EstimatedSize())
+	r.cfg.Slot.UpdateMetrics(r.cfg.IteratorStats.BlockBytes,
+		r.stats.CumulativeWrittenSize+tw.EstimatedSize()+valueSeparation.EstimatedFileSize())
 	return splitKey, nil
 }
 
+// addExpiry records the given point key in the expiry histogram.
+func (r *Runner) addExpiry(h *manifest.ExpiryHistogram, kv *base.InternalKV) error {
+	size := uint64(len(kv.K.UserKey)) + uint64(kv.V.Len())
+	switch kv.Kind() {
+	case base.InternalKeyKindSet, base.InternalKeyKindSetWithDelete:
+	default:
+		h.Add(size)
+		return nil
+	}
+	v, callerOwned, err := kv.Value(r.expiryBuf[:0])
+	if err != nil {
+		return err
+	}
+	if callerOwned && cap(v) > cap(r.expiryBuf) {
+		r.expiryBuf = v[:0]
+	}
+	if expiresAt, ok := r.cfg.ExpiresAt(kv.K.UserKey, v); ok {
+		h.AddExpiring(expiresAt.Unix(), size)
+	} else {
+		h.Add(size)
+	}
+	return nil
+}
+
 // Finish closes the compaction iterator and returns the result of the
 // compaction.
 func (r *Runner) Finish() Result {
@@ -256,6 +413,7 @@ func (r *Runner) Finish() Result {
 	return Result{
 		Err:    r.err,
 		Tables: r.tables,
+		Blobs:  r.blobs,
 		Stats:  r.stats,
 	}
 }
@@ -105,6 +105,32 @@ func (r *BlobFileActiveRefs) RemoveRef(valueSize uint64) {
 	r.valueSize -= valueSize
 }
 
+// Count returns the number of tables in the latest Version that reference the
+// blob file.
+func (r *BlobFileActiveRefs) Count() int32 {
+	return r.count
+}
+
+// ValueSize returns the sum of the lengths of the uncompressed values in the
+// blob file that are referenced by tables in the latest Version.
+func (r *BlobFileActiveRefs) ValueSize() uint64 {
+	return r.valueSize
+}
+
+// GarbageRatio returns the fraction of the blob file's values that are no
+// longer referenced by any table in the latest Version.
+//
+// Requires the manifest logLock be held.
+func (m *BlobFileMetadata) GarbageRatio() float64 {
+	if m.ValueSize == 0 || m.ActiveRefs.valueSize >= m.ValueSize {
+		// NB: The referenced value size may exceed the blob file's value size
+		// when a table referencing the blob file is split into multiple
+		// virtual tables, each of which inherits the original reference.
+		return 0
+	}
+	return 1 - float64(m.ActiveRefs.valueSize)/float64(m.ValueSize)
+}
+
 // SafeFormat implements redact.SafeFormatter.
 func (m *BlobFileMetadata) SafeFormat(w redact.SafePrinter, _ rune) {
 	w.Printf("%s size:[%d (%s)] vals:[%d (%s)]",
@@ -0,0 +1,143 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package manifest
+
+import (
+	"encoding/binary"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+const (
+	// expiryHistogramBuckets is the number of buckets of an ExpiryHistogram.
+	expiryHistogramBuckets = 16
+	// maxExpiry bounds the expiration times recorded by an ExpiryHistogram, in
+	// seconds since the Unix epoch, so that the buckets never overflow.
+	maxExpiry = 1 << 40
+)
+
+// ExpiryHistogram describes when the point keys of a table expire. It is built
+// while the table is written and stored in the table's properties, which allows
+// estimating the size of the table's expired keys at any point in time without
+// reading the table.
+//
+// Expiration times, in seconds since the Unix epoch, are counted in
+// equal-width buckets. The width of the buckets is a power of two and the
+// first bucket starts at a multiple of it. Whenever an expiration time falls
+// outside of the buckets, the width is doubled until it fits.
+type ExpiryHistogram struct {
+	// TotalBytes is the logical size (the length of the user key and of the
+	// value) of all the point keys of the table, whether they expire or not.
+	TotalBytes uint64
+	// ExpiringBytes is the logical size of the point keys that expire.
+	ExpiringBytes uint64
+	// MinExpiry and MaxExpiry are the earliest and latest expiration times of
+	// the point keys that expire.
+	MinExpiry, MaxExpiry int64
+
+	start   int64
+	width   int64
+	buckets [expiryHistogramBuckets]uint64
+}
+
+// Add records a point key of the given logical size that never expires.
+func (h *ExpiryHistogram) Add(size uint64) {
+	h.TotalBytes += size
+}
+
+// AddExpiring records a point key of the given logical size that expires at
+// the given time, in seconds since the Unix epoch.
+func (h *ExpiryHistogram) AddExpiring(expiresAt int64, size uint64) {
+	expiresAt = min(max(expiresAt, 0), maxExpiry)
+	h.TotalBytes += size
+	if h.ExpiringBytes == 0 {
+		h.MinExpiry, h.MaxExpiry = expiresAt, expiresAt
+		h.start, h.width = expiresAt, 1
+	}
+	h.ExpiringBytes += size
+	h.MinExpiry = min(h.MinExpiry, expiresAt)
+	h.MaxExpiry = max(h.MaxExpiry, expiresAt)
+	for expiresAt < h.start || expiresAt >= h.start+h.width*expiryHistogramBuckets {
+		h.widen()
+	}
+	h.buckets[(expiresAt-h.start)/h.width] += size
+}
+
+// widen doubles the width of the buckets.
+func (h *ExpiryHistogram) widen() {
+	width := 2 * h.width
+	start := h.start / width * width
+	var buckets [expiryHistogramBuckets]uint64
+	for i, b := range h.buckets {
+		buckets[(h.start+int64(i)*h.width-start)/width] += b
+	}
+	h.start, h.width, h.buckets = start, width, buckets
+}
+
+// ExpiredBytes estimates the logical size of the point keys that have expired
+// at the given time, in seconds since the Unix epoch. Expiration times are
+// assumed to be uniformly distributed within each bucket.
+func (h *ExpiryHistogram) ExpiredBytes(now int64) uint64 {
+	switch {
+	case h.ExpiringBytes == 0 || now < h.MinExpiry:
+		return 0
+	case now >= h.MaxExpiry:
+		return h.ExpiringBytes
+	}
+	var expired uint64
+	for i, b := range h.buckets {
+		start := h.start + int64(i)*h.width
+		if now >= start+h.width {
+			expired += b
+		} else if now > start {
+			expired += uint64(float64(b) * float64(now-start) / float64(h.width))
+		}
+	}
+	return expired
+}
+
+// Encode appends the encoding of the histogram to buf.
+func (h *ExpiryHistogram) Encode(buf []byte) []byte {
+	buf = binary.AppendUvarint(buf, h.TotalBytes)
+	buf = binary.AppendUvarint(buf, h.ExpiringBytes)
+	buf = binary.AppendUvarint(buf, uint64(h.MinExpiry))
+	buf = binary.AppendUvarint(buf, uint64(h.MaxExpiry))
+	buf = binary.AppendUvarint(buf, uint64(h.start))
+	buf = binary.AppendUvarint(buf, uint64(h.width))
+	for _, b := range h.buckets {
+		buf = binary.AppendUvarint(buf, b)
+	}
+	return buf
+}
+
+// DecodeExpiryHistogram decodes a histogram encoded by ExpiryHistogram.Encode.
+func DecodeExpiryHistogram(buf []byte) (*ExpiryHistogram, error) {
+	var vals [6 + expiryHistogramBuckets]uint64
+	for i := range vals {
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, base.CorruptionErrorf("pebble: invalid expiry histogram")
+		}
+		vals[i], buf = v, buf[n:]
+	}
+	if len(buf) != 0 {
+		return nil, base.CorruptionErrorf("pebble: invalid expiry histogram")
+	}
+	h := &ExpiryHistogram{
+		TotalBytes:    vals[0],
+		ExpiringBytes: vals[1],
+		MinExpiry:     int64(vals[2]),
+		MaxExpiry:     int64(vals[3]),
+		start:         int64(vals[4]),
+		width:         int64(vals[5]),
+	}
+	copy(h.buckets[:], vals[6:])
+	if h.width <= 0 || h.MinExpiry > h.MaxExpiry || h.MaxExpiry > maxExpiry {
+		return nil, base.CorruptionErrorf("pebble: invalid expiry histogram: width %d, expiry [%d, %d]",
+			errors.Safe(h.width), errors.Safe(h.MinExpiry), errors.Safe(h.MaxExpiry))
+	}
+	return h, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package manifest
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryHistogram(t *testing.T) {
+	var h ExpiryHistogram
+	h.Add(100)
+	require.Zero(t, h.ExpiredBytes(1000))
+
+	// Keys expiring uniformly over [1000, 2000).
+	for i := int64(0); i < 1000; i++ {
+		h.AddExpiring(1000+i, 10)
+	}
+	require.Equal(t, uint64(10100), h.TotalBytes)
+	require.Equal(t, uint64(10000), h.ExpiringBytes)
+	require.Equal(t, int64(1000), h.MinExpiry)
+	require.Equal(t, int64(1999), h.MaxExpiry)
+	require.Zero(t, h.ExpiredBytes(999))
+	require.Equal(t, uint64(10000), h.ExpiredBytes(1999))
+	require.Equal(t, uint64(10000), h.ExpiredBytes(5000))
+	// The estimate grows over time, and is close to the actual value.
+	var prev uint64
+	for now := int64(1000); now < 2000; now += 50 {
+		expired := h.ExpiredBytes(now)
+		require.GreaterOrEqual(t, expired, prev)
+		require.InDelta(t, float64(10*(now-1000)), float64(expired), 1000, "now=%d", now)
+		prev = expired
+	}
+
+	// The histogram round-trips through its encoding.
+	decoded, err := DecodeExpiryHistogram(h.Encode(nil))
+	require.NoError(t, err)
+	require.Equal(t, h, *decoded)
+	buf := h.Encode(nil)
+	_, err = DecodeExpiryHistogram(buf[:len(buf)-1])
+	require.Error(t, err)
+
+	// Expiration times that are far apart and out of order are accounted for.
+	rng := rand.New(rand.NewPCG(0, 0))
+	var g ExpiryHistogram
+	var sum uint64
+	for i := 0; i < 1000; i++ {
+		size := uint64(rng.IntN(100))
+		g.AddExpiring(rng.Int64N(1<<35), size)
+		sum += size
+	}
+	require.Equal(t, sum, g.ExpiredBytes(g.MaxExpiry))
+	require.Equal(t, sum, g.ExpiredBytes(g.start+g.width*expiryHistogramBuckets))
+}
@@ -85,6 +85,14 @@ type TableStats struct {
 	// This statistic is used to determine eligibility for a tombstone density
 	// compaction.
 	TombstoneDenseBlocksRatio float64
+	// Expiry describes when the point keys of this table expire, according to
+	// the configured TTL extractor. It is nil if none of the table's keys
+	// expire.
+	Expiry *ExpiryHistogram
+	// Estimate of the total disk space occupied by point keys in this table
+	// that have expired, derived from Expiry. It is refreshed periodically as
+	// keys expire. Compacting the table drops these keys.
+	ExpiredBytesEstimate uint64
 }
 
 // boundType represents the type of key (point or range) present as the smallest
@@ -199,6 +207,12 @@ type TableMetadata struct {
 	// ingested. For virtual sstables, this corresponds to the wall clock time
 	// when the TableMetadata for the virtual sstable was first created.
 	CreationTime int64
+	// EncryptionKeyID is the ID of the key that was active in the encrypting
+	// vfs.FS (see vfs/encfs) when the table's backing file was created, or the
+	// empty string if unknown (e.g. for ingested tables). If the active key
+	// changed while the file was being created, the file may be encrypted with
+	// a newer key.
+	EncryptionKeyID string
 	// LargestSeqNumAbsolute is an upper bound for the largest sequence number
 	// in the table. This upper bound is guaranteed to be higher than any
 	// sequence number any of the table's keys have held at any point in time
@@ -1299,6 +1313,13 @@ type Version struct {
 		MarkedForCompaction int
 	}
 
+	// BlobFileGarbageRatios holds the garbage ratio (see
+	// BlobFileMetadata.GarbageRatio) of every blob file referenced by the
+	// version that contains garbage. It is computed when the version is
+	// built, as the active references of a BlobFileMetadata may only be read
+	// under the manifest log lock, and is immutable afterwards.
+	BlobFileGarbageRatios map[base.DiskFileNum]float64
+
 	cmp *base.Comparer
 
 	// The list the version is linked into.
@@ -11,6 +11,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"maps"
 	"slices"
 	"strings"
 	"time"
@@ -66,6 +67,7 @@ const (
 	customTagTerminate         = 1
 	customTagNeedsCompaction   = 2
 	customTagCreationTime      = 6
+	customTagEncryptionKeyID   = 7
 	customTagPathID            = 65
 	customTagNonSafeIgnoreMask = 1 << 6
 	customTagVirtual           = 66
@@ -358,6 +360,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			var syntheticSuffix sstable.SyntheticSuffix
 			var blobReferences []BlobReference
 			var blobReferenceDepth uint64
+			var encryptionKeyID string
 			if tag == tagNewFile4 || tag == tagNewFile5 {
 				for {
 					customTag, err := d.readUvarint()
@@ -389,6 +392,13 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 							return base.CorruptionErrorf("new-file4: invalid file creation time")
 						}
 
+					case customTagEncryptionKeyID:
+						field, err := d.readBytes()
+						if err != nil {
+							return err
+						}
+						encryptionKeyID = string(field)
+
 					case customTagPathID:
 						return base.CorruptionErrorf("new-file4: path-id field not supported")
 
@@ -452,6 +462,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 				FileNum:                  fileNum,
 				Size:                     size,
 				CreationTime:             int64(creationTime),
+				EncryptionKeyID:          encryptionKeyID,
 				SmallestSeqNum:           smallestSeqNum,
 				LargestSeqNum:            largestSeqNum,
 				LargestSeqNumAbsolute:    largestSeqNum,
@@ -734,7 +745,8 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 		e.writeUvarint(uint64(x.FileNum))
 	}
 	for _, x := range v.NewTables {
-		customFields := x.Meta.MarkedForCompaction || x.Meta.CreationTime != 0 || x.Meta.Virtual || len(x.Meta.BlobReferences) > 0
+		customFields := x.Meta.MarkedForCompaction || x.Meta.CreationTime != 0 || x.Meta.Virtual ||
+			len(x.Meta.BlobReferences) > 0 || x.Meta.EncryptionKeyID != ""
 		var tag uint64
 		switch {
 		case x.Meta.HasRangeKeys:
@@ -783,6 +795,10 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 				n := binary.PutUvarint(buf[:], uint64(x.Meta.CreationTime))
 				e.writeBytes(buf[:n])
 			}
+			if x.Meta.EncryptionKeyID != "" {
+				e.writeUvarint(customTagEncryptionKeyID)
+				e.writeBytes([]byte(x.Meta.EncryptionKeyID))
+			}
 			if x.Meta.MarkedForCompaction {
 				e.writeUvarint(customTagNeedsCompaction)
 				e.writeBytes([]byte{1})
@@ -1341,6 +1357,31 @@ func (b *BulkVersionEdit) Apply(
 		}
 	}
 
+	// Snapshot the garbage ratios of the blob files whose references changed,
+	// carrying over the ratios of the other blob files from curr.
+	v.BlobFileGarbageRatios = make(map[base.DiskFileNum]float64)
+	if curr != nil {
+		maps.Copy(v.BlobFileGarbageRatios, curr.BlobFileGarbageRatios)
+	}
+	updateGarbageRatios := func(tables map[base.FileNum]*TableMetadata) {
+		for _, f := range tables {
+			for _, ref := range f.BlobReferences {
+				if r := ref.Metadata.GarbageRatio(); r > 0 {
+					v.BlobFileGarbageRatios[ref.FileNum] = r
+				} else {
+					delete(v.BlobFileGarbageRatios, ref.FileNum)
+				}
+			}
+		}
+	}
+	for level := range v.Levels {
+		updateGarbageRatios(b.DeletedTables[level])
+		updateGarbageRatios(b.AddedTables[level])
+	}
+	for _, fileNum := range b.BlobFiles.Deleted {
+		delete(v.BlobFileGarbageRatios, fileNum)
+	}
+
 	// We maintain stats about active references in blob files and can infer
 	// when a blob file has become a 'zombie,' and is no longer referenced in
 	// the resulting version. However, we expect the caller to explicitly list
@@ -162,6 +162,7 @@ func TestVersionEditRoundTrip(t *testing.T) {
 		FileNum:               809,
 		Size:                  8090,
 		CreationTime:          809060,
+		EncryptionKeyID:       "key-1",
 		SmallestSeqNum:        9,
 		LargestSeqNum:         11,
 		LargestSeqNumAbsolute: 11,
@@ -464,6 +465,44 @@ func TestVersionEditApply(t *testing.T) {
 		})
 }
 
+func TestVersionEditApplyBlobFileGarbageRatios(t *testing.T) {
+	apply := func(curr *Version, edit string) *Version {
+		bve := BulkVersionEdit{AddedTablesByFileNum: make(map[base.FileNum]*TableMetadata)}
+		if curr != nil {
+			for _, l := range curr.Levels {
+				it := l.Iter()
+				for f := it.First(); f != nil; f = it.Next() {
+					bve.AddedTablesByFileNum[f.FileNum] = f
+				}
+			}
+		}
+		ve, err := ParseVersionEditDebug(edit)
+		require.NoError(t, err)
+		require.NoError(t, bve.Accumulate(ve))
+		v, err := bve.Apply(curr, base.DefaultComparer, 10<<20 /* flushSplitBytes */, 32000 /* readCompactionRate */)
+		require.NoError(t, err)
+		return v
+	}
+
+	v1 := apply(nil, `
+  add-blob-file: 000003 size:[500 (500B)] vals:[1000 (1000B)]
+  add-table:     L6 000004:[a#1,SET-b#1,SET] seqnums:[1-1] points:[a#1,SET-b#1,SET] blobrefs:[(000003: 600); depth:1]
+  add-table:     L6 000005:[c#1,SET-d#1,SET] seqnums:[1-1] points:[c#1,SET-d#1,SET] blobrefs:[(000003: 400); depth:1]`)
+	require.Empty(t, v1.BlobFileGarbageRatios)
+
+	v2 := apply(v1, `
+  del-table: L6 000005`)
+	require.Equal(t, map[base.DiskFileNum]float64{3: 0.4}, v2.BlobFileGarbageRatios)
+	// The ratios of a version don't change once it is built.
+	require.Empty(t, v1.BlobFileGarbageRatios)
+
+	v3 := apply(v2, `
+  del-table: L6 000004
+  del-blob-file: 000003`)
+	require.Empty(t, v3.BlobFileGarbageRatios)
+	require.Equal(t, map[base.DiskFileNum]float64{3: 0.4}, v2.BlobFileGarbageRatios)
+}
+
 func TestParseVersionEditDebugRoundTrip(t *testing.T) {
 	testCases := []struct {
 		input  string
@@ -22,6 +22,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/internal/rangekeystack"
 	"github.com/cockroachdb/pebble/internal/treeprinter"
+	"github.com/cockroachdb/pebble/sstable/blob"
 	"github.com/cockroachdb/redact"
 )
 
@@ -194,15 +195,19 @@ type Iterator struct {
 	// short-lived (since they pin memtables and sstables), (b) plumbing a
 	// context into every method is very painful, (c) they do not (yet) respect
 	// context cancellation and are only used for tracing.
-	ctx       context.Context
-	opts      IterOptions
-	merge     Merge
-	comparer  base.Comparer
+	ctx      context.Context
+	opts     IterOptions
+	merge    Merge
+	comparer base.Comparer
+	// ttl determines whether keys have expired as of the Iterator's creation.
+	ttl       ttlChecker
 	iter      internalIterator
 	pointIter topLevelIterator
 	// Either readState or version is set, but not both.
 	readState *readState
 	version   *version
+	// tracing is set if the Iterator is traced. See Tracer.IsTracing.
+	tracing *iterTracing
 	// rangeKey holds iteration state specific to iteration over range keys.
 	// The range key field may be nil if the Iterator has never been configured
 	// to iterate over range keys. Its non-nilness cannot be used to determine
@@ -267,6 +272,9 @@ type Iterator struct {
 	// During SetOptions on an iterator over an indexed batch, this field is
 	// used to update the merging iterator's batch snapshot.
 	merging *mergingIter
+	// blobValueFetcher is the ValueFetcher to use when retrieving values stored
+	// externally in blob files.
+	blobValueFetcher blob.ValueFetcher
 
 	// Keeping the bools here after all the 8 byte aligned fields shrinks the
 	// sizeof this struct by 24 bytes.
@@ -599,6 +607,15 @@ func (i *Iterator) findNextEntry(limit []byte) {
 			continue
 
 		case InternalKeyKindSet, InternalKeyKindSetWithDelete:
+			if i.ttl.enabled() {
+				// An expired key is treated as deleted.
+				if expired := i.expired(); i.err != nil {
+					return
+				} else if expired {
+					i.nextUserKey()
+					continue
+				}
+			}
 			i.keyBuf = append(i.keyBuf[:0], key.UserKey...)
 			i.key = i.keyBuf
 			i.value = i.iterKV.V
@@ -697,6 +714,21 @@ func (i *Iterator) nextPointCurrentUserKey() bool {
 	}
 }
 
+// expired returns true if the SET or SETWITHDEL at i.iterKV has expired
+// according to the configured TTLExtractor. It sets i.err if the value cannot
+// be retrieved.
+func (i *Iterator) expired() bool {
+	v, callerOwned, err := i.iterKV.Value(i.lazyValueBuf[:0])
+	if err != nil {
+		i.err = err
+		return false
+	}
+	if callerOwned {
+		i.lazyValueBuf = v[:0]
+	}
+	return i.ttl.expired(i.iterKV.K.UserKey, v)
+}
+
 // mergeForward resolves a MERGE key, advancing the underlying iterator forward
 // to merge with subsequent keys with the same userkey. mergeForward returns a
 // boolean indicating whether or not the merge yielded a valid key. A merge may
@@ -1042,6 +1074,26 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 			continue
 
 		case InternalKeyKindSet, InternalKeyKindSetWithDelete:
+			if i.ttl.enabled() {
+				// An expired key is treated as deleted. See the DEL case
+				// above.
+				if expired := i.expired(); i.err != nil {
+					i.iterValidityState = IterExhausted
+					return
+				} else if expired {
+					i.value = base.InternalValue{}
+					i.iterValidityState = IterExhausted
+					valueMerger = nil
+					i.stats.ReverseStepCount[InternalIterCall]++
+					i.iterKV = i.iter.Prev()
+					if limit != nil && i.iterKV != nil && i.cmp(limit, i.iterKV.K.UserKey) > 0 && !i.rangeKeyWithinLimit(limit) {
+						i.iterValidityState = IterAtLimit
+						i.pos = iterPosCurReversePaused
+						return
+					}
+					continue
+				}
+			}
 			i.keyBuf = append(i.keyBuf[:0], key.UserKey...)
 			i.key = i.keyBuf
 			// iterValue is owned by i.iter and could change after the Prev()
@@ -1214,6 +1266,10 @@ func (i *Iterator) mergeNext(key InternalKey, valueMerger ValueMerger) {
 			if i.err != nil {
 				return
 			}
+			if i.ttl.expired(key.UserKey, iterValue) {
+				// An expired value is treated as a deletion tombstone.
+				return
+			}
 			i.err = valueMerger.MergeOlder(iterValue)
 			return
 
@@ -2358,8 +2414,16 @@ func (i *Iterator) Close() error {
 			i.rangeKey.rangeKeyIter.Close()
 		}
 	}
+	// Close the blob value fetcher before releasing the readState, because it
+	// may hold open readers on blob files referenced by the readState.
+	i.err = firstError(i.err, i.blobValueFetcher.Close())
 	err := i.err
 
+	if i.tracing != nil {
+		i.tracing.end(&i.stats, err)
+		i.tracing = nil
+	}
+
 	if i.readState != nil {
 		if i.readSampling.pendingCompactions.size > 0 {
 			// Copy pending read compactions using db.mu.Lock()
@@ -2869,6 +2933,7 @@ func (i *Iterator) CloneWithContext(ctx context.Context, opts CloneOptions) (*It
 		alloc:               buf,
 		merge:               i.merge,
 		comparer:            i.comparer,
+		ttl:                 i.ttl,
 		readState:           readState,
 		version:             vers,
 		keyBuf:              buf.keyBuf,
@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/internal/testkeys"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/rangefilter"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/stretchr/testify/require"
@@ -535,6 +536,58 @@ func TestIteratorTableFilter(t *testing.T) {
 	})
 }
 
+func TestIteratorRangeFilter(t *testing.T) {
+	opts := &Options{
+		FS: vfs.NewMem(),
+		Levels: []LevelOptions{{
+			FilterPolicy: rangefilter.FilterPolicy(1),
+			FilterType:   RangeFilter,
+		}},
+		DisableAutomaticCompactions: true,
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// The first table spans [a, z], but contains no keys in [b, y].
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	require.NoError(t, d.Set([]byte("z"), []byte("1"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("c"), []byte("2"), nil))
+	require.NoError(t, d.Flush())
+
+	scan := func(lower, upper string) (keys []string, skipped uint64) {
+		iter, err := d.NewIter(&IterOptions{LowerBound: []byte(lower), UpperBound: []byte(upper)})
+		require.NoError(t, err)
+		for valid := iter.First(); valid; valid = iter.Next() {
+			keys = append(keys, string(iter.Key()))
+		}
+		skipped = iter.Stats().InternalStats.RangeFilterSkippedTables
+		require.NoError(t, iter.Close())
+		return keys, skipped
+	}
+	keys, skipped := scan("b", "d")
+	require.Equal(t, []string{"c"}, keys)
+	require.Equal(t, uint64(1), skipped)
+
+	keys, skipped = scan("a", "d")
+	require.Equal(t, []string{"a", "c"}, keys)
+	require.Zero(t, skipped)
+
+	// Range filters compare keys bytewise, so they may not be used with other
+	// comparers.
+	opts = &Options{
+		FS:       vfs.NewMem(),
+		Comparer: testkeys.Comparer,
+		Levels: []LevelOptions{{
+			FilterPolicy: rangefilter.FilterPolicy(1),
+			FilterType:   RangeFilter,
+		}},
+	}
+	_, err = Open("", opts)
+	require.ErrorContains(t, err, "requires the leveldb.BytewiseComparator Comparer")
+}
+
 func TestIteratorNextPrev(t *testing.T) {
 	var mem vfs.FS
 	var d *DB
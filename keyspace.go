@@ -0,0 +1,650 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/arenaskl"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/wal"
+)
+
+// Each keyspace of a DB (see Options.Keyspaces) is stored by a DB of its own,
+// in the keyspaces/<name> subdirectory of the DB's directory, and has its own
+// memtables, version, MANIFEST and compactions. The keyspace's DB is opened
+// with its WAL disabled: its writes are logged to the WAL of the parent DB
+// instead, which is what allows a single Batch to write atomically across
+// keyspaces.
+//
+// A batch writing to keyspaces holds a batch for each keyspace written. When
+// it commits, each keyspace's batch is assigned sequence numbers by the
+// keyspace's commit pipeline, and reserves room in the keyspace's mutable
+// memtable. The keyspaces' batches are then appended to the batch as LogData
+// records, and the batch commits through the parent's commit pipeline,
+// writing them all to the WAL in a single record. The keyspaces' pipelines are
+// held until the batch is written to the WAL, so the writes to a keyspace are
+// logged in the order of their sequence numbers.
+//
+// The memtables of a keyspace are tagged with the number of the parent's WAL
+// that was current when they were created, and the parent rotates its WAL
+// whenever a keyspace rotates its memtable. The writes to a memtable are thus
+// logged to WALs that hold no writes of older memtables, and the
+// MinUnflushedLogNum that a keyspace records in its MANIFEST when it flushes
+// is the number of the oldest parent WAL holding writes that it has not
+// flushed. The parent retains its WALs until every keyspace has flushed their
+// writes, and replays the writes of each keyspace from the WALs it has not
+// flushed when it is opened.
+//
+// The locks of a keyspace's DB are acquired before those of the parent DB:
+// once it is open, the parent never acquires the mutexes of a keyspace while
+// holding its own.
+
+// keyspacesDirname is the name of the subdirectory of a DB's directory that
+// holds the directories of its keyspaces.
+const keyspacesDirname = "keyspaces"
+
+// keyspaceLogDataPrefix prefixes the LogData records that hold the batches of
+// keyspaces. The prefix is followed by the varint-prefixed name of the
+// keyspace and the representation of the keyspace's batch.
+var keyspaceLogDataPrefix = []byte("\xffpebble.keyspace\x00")
+
+// ErrKeyspaceNotFound is returned by DB.Keyspace when the DB was not
+// configured with a keyspace of the provided name.
+var ErrKeyspaceNotFound = errors.New("pebble: keyspace not found")
+
+// KeyspaceOptions configures a named keyspace. See Options.Keyspaces.
+type KeyspaceOptions struct {
+	// Name identifies the keyspace, and names the directory that holds its
+	// files. Names must be unique and valid directory names.
+	Name string
+
+	// Comparer orders the keys of the keyspace.
+	//
+	// The default value uses the same ordering as bytes.Compare.
+	Comparer *Comparer
+
+	// Merger defines the merge operation applied to values written to the
+	// keyspace with Merge.
+	//
+	// The default merger concatenates values.
+	Merger *Merger
+
+	// Levels configures the LSM levels of the keyspace, in the same way as
+	// Options.Levels configures those of the DB.
+	//
+	// The default value uses Options.Levels.
+	Levels []LevelOptions
+}
+
+// keyspaceOptions returns the options of the DB of the provided keyspace. The
+// keyspace shares the block cache, file cache and event listener of the DB,
+// and inherits its other options, except for those configuring the WAL.
+func (o *Options) keyspaceOptions(ks *KeyspaceOptions) *Options {
+	opts := o.Clone()
+	opts.Comparer = ks.Comparer
+	opts.Merger = ks.Merger
+	if len(ks.Levels) > 0 {
+		opts.Levels = slices.Clone(ks.Levels)
+	} else {
+		opts.Levels = slices.Clone(o.Levels)
+	}
+	opts.DisableWAL = true
+	opts.WALDir = ""
+	opts.WALFailover = nil
+	opts.WALRecoveryDirs = nil
+	opts.WALArchive = nil
+	opts.Keyspaces = nil
+	opts.Lock = nil
+	opts.ErrorIfExists = false
+	opts.ErrorIfNotExists = false
+	opts.private.keyspace = true
+	opts.private.replayTargetSeqNum = 0
+	opts.private.fsCloser = nil
+	return opts
+}
+
+// Keyspace is a handle to a named keyspace of a DB. See Options.Keyspaces.
+type Keyspace struct {
+	parent *DB
+	// db stores the keyspace.
+	db    *DB
+	name  string
+	index int
+}
+
+// openKeyspacesLocked opens the DBs of the keyspaces of the DB, which is being
+// opened in dirname.
+//
+// d.mu must be held.
+func (d *DB) openKeyspacesLocked(dirname string) error {
+	if len(d.opts.Keyspaces) == 0 {
+		return nil
+	}
+	if d.opts.private.follower {
+		return errors.New("pebble: keyspaces are not supported by followers")
+	}
+	for i := range d.opts.Keyspaces {
+		ksOpts := &d.opts.Keyspaces[i]
+		db, err := Open(d.opts.FS.PathJoin(dirname, keyspacesDirname, ksOpts.Name), d.opts.keyspaceOptions(ksOpts))
+		if err != nil {
+			return errors.Wrapf(err, "pebble: opening keyspace %q", errors.Safe(ksOpts.Name))
+		}
+		db.mu.Lock()
+		db.keyspace.minUnflushedLogNum.Store(uint64(db.mu.versions.minUnflushedLogNum))
+		db.mu.Unlock()
+		d.keyspaces = append(d.keyspaces, &Keyspace{parent: d, db: db, name: ksOpts.Name, index: i})
+	}
+	return nil
+}
+
+// keyspacesMinUnflushedLogNumLocked returns the number of the oldest WAL that
+// holds writes not yet flushed by the DB or by one of its keyspaces.
+//
+// d.mu must be held.
+func (d *DB) keyspacesMinUnflushedLogNumLocked() wal.NumWAL {
+	minNum := wal.NumWAL(d.mu.versions.minUnflushedLogNum)
+	if len(d.keyspaces) == 0 {
+		return minNum
+	}
+	// The DB's own memtables may have been tagged with a newer WAL than its
+	// MinUnflushedLogNum by rotateWALForKeyspace.
+	if len(d.mu.mem.queue) > 0 {
+		minNum = max(minNum, wal.NumWAL(d.mu.mem.queue[0].logNum))
+	}
+	for _, ks := range d.keyspaces {
+		minNum = min(minNum, wal.NumWAL(ks.db.keyspace.minUnflushedLogNum.Load()))
+	}
+	return minNum
+}
+
+// replayKeyspaceLogData replays the batches of keyspaces held by the LogData
+// records of a batch read from the WAL logNum. The batch of a keyspace is
+// skipped if the keyspace has flushed the writes of the WAL.
+func (d *DB) replayKeyspaceLogData(b *Batch, logNum base.DiskFileNum) error {
+	r := b.Reader()
+	for {
+		kind, data, _, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if kind != InternalKeyKindLogData || !bytes.HasPrefix(data, keyspaceLogDataPrefix) {
+			continue
+		}
+		name, repr, ok := decodeKeyspaceLogData(data)
+		if !ok {
+			return base.CorruptionErrorf("pebble: corrupt keyspace batch in wal %s", logNum)
+		}
+		ks, err := d.Keyspace(name)
+		if err != nil {
+			return errors.Wrapf(err, "pebble: wal %s holds writes to keyspace %q", logNum, errors.Safe(name))
+		}
+		if logNum < base.DiskFileNum(ks.db.keyspace.minUnflushedLogNum.Load()) {
+			continue
+		}
+		if err := ks.db.replayKeyspaceBatch(repr, logNum); err != nil {
+			return err
+		}
+	}
+}
+
+// appendKeyspaceLogData appends a LogData record holding the batch of the
+// keyspace with the provided name to the batch.
+func (b *Batch) appendKeyspaceLogData(name string, kb *Batch) {
+	repr := kb.Repr()
+	n := len(keyspaceLogDataPrefix) + binary.PutUvarint(make([]byte, binary.MaxVarintLen64), uint64(len(name))) +
+		len(name) + len(repr)
+	// The record is appended while the batch commits, once the keyspace's
+	// batch has been assigned its sequence numbers.
+	committing := b.committing
+	b.committing = false
+	defer func() { b.committing = committing }()
+	origCount, origMemTableSize := b.count, b.memTableSize
+	b.prepareDeferredKeyRecord(n, InternalKeyKindLogData)
+	data := append(b.deferredOp.Key[:0], keyspaceLogDataPrefix...)
+	data = binary.AppendUvarint(data, uint64(len(name)))
+	data = append(data, name...)
+	_ = append(data, repr...)
+	// See Batch.LogData.
+	b.count, b.memTableSize = origCount, origMemTableSize
+}
+
+// decodeKeyspaceLogData decodes a LogData record written by
+// appendKeyspaceLogData.
+func decodeKeyspaceLogData(data []byte) (name string, repr []byte, ok bool) {
+	data, ok = bytes.CutPrefix(data, keyspaceLogDataPrefix)
+	if !ok {
+		return "", nil, false
+	}
+	n, w := binary.Uvarint(data)
+	if w <= 0 || n > uint64(len(data)-w) {
+		return "", nil, false
+	}
+	return string(data[w : w+int(n)]), data[w+int(n):], true
+}
+
+// beginKeyspaceReplay prepares the DB of a keyspace for its writes to be
+// replayed from the WALs of its parent. The mutable memtable is retired, so
+// that the replayed writes are applied to memtables tagged with the WALs they
+// are read from.
+func (d *DB) beginKeyspaceReplay() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if mem := d.mu.mem.mutable; mem != nil {
+		d.mu.mem.queue[len(d.mu.mem.queue)-1].flushForced = !d.opts.ReadOnly
+		mem.writerUnref()
+		d.mu.mem.mutable = nil
+	}
+}
+
+// replayKeyspaceBatch applies a batch of the keyspace, read from the parent's
+// WAL logNum, to the keyspace's memtables.
+func (d *DB) replayKeyspaceBatch(repr []byte, logNum base.DiskFileNum) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Specify Batch.db so that Batch.SetRepr computes Batch.memTableSize.
+	b := Batch{}
+	b.db = d
+	if err := b.SetRepr(repr); err != nil {
+		return err
+	}
+	seqNum := b.SeqNum()
+	d.mu.versions.markFileNumUsed(logNum)
+	// Apply the batch to a memtable tagged with the WAL it was read from,
+	// rotating the mutable memtable if it is tagged with an older WAL or full.
+	mem := d.mu.mem.mutable
+	err := arenaskl.ErrArenaFull
+	if mem != nil && d.mu.mem.queue[len(d.mu.mem.queue)-1].logNum == logNum {
+		err = mem.prepare(&b)
+	}
+	if err == arenaskl.ErrArenaFull {
+		if mem != nil {
+			d.mu.mem.queue[len(d.mu.mem.queue)-1].flushForced = !d.opts.ReadOnly
+			mem.writerUnref()
+		}
+		var entry *flushableEntry
+		mem, entry = d.newMemTable(logNum, seqNum, b.memTableSize)
+		d.mu.mem.mutable = mem
+		d.mu.mem.queue = append(d.mu.mem.queue, entry)
+		err = mem.prepare(&b)
+	}
+	if err != nil {
+		return err
+	}
+	if err := mem.apply(&b, seqNum); err != nil {
+		return err
+	}
+	mem.writerUnref()
+	if maxSeqNum := seqNum + base.SeqNum(b.Count()); d.mu.versions.logSeqNum.Load() < maxSeqNum {
+		d.mu.versions.logSeqNum.Store(maxSeqNum)
+	}
+	return nil
+}
+
+// finishKeyspaceReplay completes the recovery of a keyspace's writes from the
+// WALs of its parent, whose new WAL is newLogNum. The memtables holding the
+// replayed writes are flushed, and the keyspace's subsequent writes are
+// applied to a new mutable memtable tagged with the new WAL.
+func (d *DB) finishKeyspaceReplay(newLogNum base.DiskFileNum) {
+	d.commit.mu.Lock()
+	defer d.commit.mu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if mem := d.mu.mem.mutable; mem != nil {
+		d.mu.mem.queue[len(d.mu.mem.queue)-1].flushForced = !d.opts.ReadOnly
+		mem.writerUnref()
+	}
+	d.mu.versions.markFileNumUsed(newLogNum)
+	var entry *flushableEntry
+	d.mu.mem.mutable, entry = d.newMemTable(newLogNum, d.mu.versions.logSeqNum.Load(), 0 /* minSize */)
+	d.mu.mem.queue = append(d.mu.mem.queue, entry)
+	d.mu.versions.visibleSeqNum.Store(d.mu.versions.logSeqNum.Load())
+	d.updateReadStateLocked(d.opts.DebugCheck)
+	if !d.opts.ReadOnly {
+		d.maybeScheduleFlush()
+		for d.mu.compact.flushing {
+			d.mu.compact.cond.Wait()
+		}
+	}
+}
+
+// keyspaceFlushedLocked is called when the DB has flushed memtables. If the DB
+// stores a keyspace, the parent DB may delete the WALs that were only retained
+// for the flushed writes.
+//
+// d.mu must be held.
+func (d *DB) keyspaceFlushedLocked() {
+	if !d.opts.private.keyspace {
+		return
+	}
+	d.keyspace.minUnflushedLogNum.Store(uint64(d.mu.versions.minUnflushedLogNum))
+	if parent := d.keyspace.parent.Load(); parent != nil {
+		parent.mu.Lock()
+		parent.deleteObsoleteFiles(parent.newJobIDLocked())
+		parent.mu.Unlock()
+	}
+}
+
+// rotateWALForKeyspace rotates the WAL of the DB on behalf of a keyspace that
+// is rotating its memtable, and returns the number of the new WAL. The DB's
+// own memtable is not rotated.
+func (d *DB) rotateWALForKeyspace() base.DiskFileNum {
+	if d.opts.DisableWAL {
+		return 0
+	}
+	d.commit.mu.Lock()
+	defer d.commit.mu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	newLogNum, prevLogSize := d.rotateWAL()
+	d.mu.mem.queue[len(d.mu.mem.queue)-1].logSize += prevLogSize
+	d.logSize.Store(0)
+	// If the DB has no unflushed writes of its own, tag its mutable memtable
+	// with the new WAL, so that the older WALs are only retained for the
+	// keyspaces that have not flushed their writes.
+	if mem := d.mu.mem.mutable; len(d.mu.mem.queue) == 1 && mem.empty() && mem.writerRefs.Load() == 1 {
+		d.mu.mem.queue[0].logNum = newLogNum
+	}
+	d.recordWALLocked(newLogNum, d.mu.versions.logSeqNum.Load())
+	return newLogNum
+}
+
+// commitKeyspaces commits a batch that writes to keyspaces. See the comment at
+// the top of this file.
+func (d *DB) commitKeyspaces(b *Batch, sync bool, noSyncWait bool) error {
+	var indexes []int
+	for i, kb := range b.keyspaces {
+		if kb == nil || kb.Empty() {
+			continue
+		}
+		if kb.db != d.keyspaces[i].db {
+			panic(fmt.Sprintf("pebble: batch keyspace mismatch: %p != %p", kb.db, d.keyspaces[i].db))
+		}
+		indexes = append(indexes, i)
+	}
+
+	// When the batch is synced, the keyspaces' writes are published only once
+	// the WAL has been synced, unless the caller opted out of waiting for the
+	// sync. The sync is waited for once, before the first keyspace is applied.
+	waitSync := sync && !noSyncWait
+	var synced bool
+	var syncErr error
+	syncWait := func() error {
+		if !synced {
+			synced = true
+			syncErr = b.SyncWait()
+		}
+		return syncErr
+	}
+
+	var commit func(i int) error
+	commit = func(i int) error {
+		if i == len(indexes) {
+			for _, j := range indexes {
+				b.appendKeyspaceLogData(d.keyspaces[j].name, b.keyspaces[j])
+			}
+			// Don't wait for the WAL sync while the keyspaces' commit pipelines
+			// are held: the sync is waited for by the apply callbacks below,
+			// which run after the pipelines' mutexes are released.
+			return d.commit.Commit(b, sync, sync /* noSyncWait */)
+		}
+		ks, kb := d.keyspaces[indexes[i]], b.keyspaces[indexes[i]]
+		var mem *memTable
+		var err error
+		ks.db.commit.AllocateSeqNum(int(kb.Count()), func(seqNum base.SeqNum) {
+			kb.setSeqNum(seqNum)
+			if mem, err = ks.db.commitWrite(kb, nil /* syncWG */, nil /* syncErr */); err == nil {
+				err = commit(i + 1)
+			}
+		}, func(base.SeqNum) {
+			if err != nil {
+				return
+			}
+			if waitSync {
+				// A sync error is returned below. Like the DB's own writes, the
+				// keyspace's writes are applied regardless.
+				_ = syncWait()
+			}
+			err = ks.db.commitApply(kb, mem)
+		})
+		return err
+	}
+	if err := commit(0); err != nil {
+		return err
+	}
+	if waitSync {
+		return syncWait()
+	}
+	return nil
+}
+
+// Keyspace returns a handle to the keyspace with the provided name. It returns
+// ErrKeyspaceNotFound if the DB was not configured with such a keyspace.
+func (d *DB) Keyspace(name string) (*Keyspace, error) {
+	for _, ks := range d.keyspaces {
+		if ks.name == name {
+			return ks, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrKeyspaceNotFound, "%q", errors.Safe(name))
+}
+
+// keyspaceMetrics returns the metrics of every keyspace of the DB, or nil if
+// the DB was not configured with keyspaces.
+func (d *DB) keyspaceMetrics() map[string]*Metrics {
+	if len(d.keyspaces) == 0 {
+		return nil
+	}
+	m := make(map[string]*Metrics, len(d.keyspaces))
+	for _, ks := range d.keyspaces {
+		m[ks.name] = ks.db.Metrics()
+	}
+	return m
+}
+
+// checkpointKeyspaces checkpoints the keyspaces of the DB into the keyspaces
+// subdirectory of destDir. If names is non-empty, only the named keyspaces are
+// checkpointed.
+func (d *DB) checkpointKeyspaces(destDir string, names []string) error {
+	for _, ks := range d.keyspaces {
+		if len(names) > 0 && !slices.Contains(names, ks.name) {
+			continue
+		}
+		if err := ks.db.Checkpoint(d.opts.FS.PathJoin(destDir, keyspacesDirname, ks.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the keyspace.
+func (k *Keyspace) Name() string {
+	return k.name
+}
+
+// Get gets the value for the given key within the keyspace. It returns
+// ErrNotFound if the keyspace does not contain the key. See DB.Get.
+func (k *Keyspace) Get(key []byte) ([]byte, io.Closer, error) {
+	return k.db.Get(key)
+}
+
+// NewIter returns an iterator over the keys of the keyspace. See DB.NewIter.
+func (k *Keyspace) NewIter(o *IterOptions) (*Iterator, error) {
+	return k.db.NewIter(o)
+}
+
+// NewSnapshot returns a point-in-time view of the keyspace. See
+// DB.NewSnapshot.
+func (k *Keyspace) NewSnapshot() *Snapshot {
+	return k.db.NewSnapshot()
+}
+
+// Set sets the value for the given key within the keyspace.
+//
+// It is safe to modify the contents of the arguments after Set returns.
+func (k *Keyspace) Set(key, value []byte, opts *WriteOptions) error {
+	return k.apply(func(kb KeyspaceBatch) error { return kb.Set(key, value, opts) }, opts)
+}
+
+// Merge merges the value for the given key within the keyspace, using the
+// keyspace's Merger.
+//
+// It is safe to modify the contents of the arguments after Merge returns.
+func (k *Keyspace) Merge(key, value []byte, opts *WriteOptions) error {
+	return k.apply(func(kb KeyspaceBatch) error { return kb.Merge(key, value, opts) }, opts)
+}
+
+// Delete deletes the value for the given key within the keyspace.
+//
+// It is safe to modify the contents of the arguments after Delete returns.
+func (k *Keyspace) Delete(key []byte, opts *WriteOptions) error {
+	return k.apply(func(kb KeyspaceBatch) error { return kb.Delete(key, opts) }, opts)
+}
+
+// SingleDelete single deletes the entry for key within the keyspace. See
+// Writer.SingleDelete for more details on the semantics of SingleDelete.
+//
+// It is safe to modify the contents of the arguments after SingleDelete
+// returns.
+func (k *Keyspace) SingleDelete(key []byte, opts *WriteOptions) error {
+	return k.apply(func(kb KeyspaceBatch) error { return kb.SingleDelete(key, opts) }, opts)
+}
+
+// DeleteRange deletes all of the keys in the range [start,end) within the
+// keyspace.
+//
+// It is safe to modify the contents of the arguments after DeleteRange
+// returns.
+func (k *Keyspace) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	return k.apply(func(kb KeyspaceBatch) error { return kb.DeleteRange(start, end, opts) }, opts)
+}
+
+func (k *Keyspace) apply(fn func(KeyspaceBatch) error, opts *WriteOptions) error {
+	b := newBatch(k.parent)
+	if err := fn(k.InBatch(b)); err != nil {
+		return err
+	}
+	if err := k.parent.Apply(b, opts); err != nil {
+		return err
+	}
+	// Only release the batch on success.
+	return b.Close()
+}
+
+// Flush flushes the keyspace's memtable to stable storage. See DB.Flush.
+func (k *Keyspace) Flush() error {
+	return k.db.Flush()
+}
+
+// Compact the specified range of keys in the keyspace. See DB.Compact.
+func (k *Keyspace) Compact(start, end []byte, parallelize bool) error {
+	return k.db.Compact(start, end, parallelize)
+}
+
+// EstimateDiskUsage returns the estimated filesystem space used in bytes for
+// storing the range [start, end] of the keyspace. See DB.EstimateDiskUsage.
+func (k *Keyspace) EstimateDiskUsage(start, end []byte) (uint64, error) {
+	return k.db.EstimateDiskUsage(start, end)
+}
+
+// Metrics returns the metrics of the keyspace's LSM. See DB.Metrics.
+func (k *Keyspace) Metrics() *Metrics {
+	return k.db.Metrics()
+}
+
+// InBatch returns a writer that adds operations on the keyspace to the
+// provided batch. A single batch may hold operations on the DB and on several
+// keyspaces, which are committed atomically.
+//
+// The operations on the keyspace are not reflected by the batch's Count, Len
+// and Repr until the batch is committed.
+func (k *Keyspace) InBatch(b *Batch) KeyspaceBatch {
+	return KeyspaceBatch{ks: k, b: b}
+}
+
+// KeyspaceBatch adds operations on a keyspace to a Batch. See
+// Keyspace.InBatch.
+type KeyspaceBatch struct {
+	ks *Keyspace
+	b  *Batch
+}
+
+// batch returns the batch holding the operations on the keyspace, creating it
+// if necessary. The batch is indexed if the Batch is.
+func (kb KeyspaceBatch) batch() *Batch {
+	if kb.b.keyspaces == nil {
+		kb.b.keyspaces = make([]*Batch, len(kb.ks.parent.keyspaces))
+	}
+	if kb.b.keyspaces[kb.ks.index] == nil {
+		if kb.b.index != nil {
+			kb.b.keyspaces[kb.ks.index] = kb.ks.db.NewIndexedBatch()
+		} else {
+			kb.b.keyspaces[kb.ks.index] = kb.ks.db.NewBatch()
+		}
+	}
+	return kb.b.keyspaces[kb.ks.index]
+}
+
+// Set adds an action to the batch that sets the key to map to the value within
+// the keyspace.
+//
+// It is safe to modify the contents of the arguments after Set returns.
+func (kb KeyspaceBatch) Set(key, value []byte, opts *WriteOptions) error {
+	return kb.batch().Set(key, value, opts)
+}
+
+// Merge adds an action to the batch that merges the value at key within the
+// keyspace with the new value.
+//
+// It is safe to modify the contents of the arguments after Merge returns.
+func (kb KeyspaceBatch) Merge(key, value []byte, opts *WriteOptions) error {
+	return kb.batch().Merge(key, value, opts)
+}
+
+// Delete adds an action to the batch that deletes the entry for key within the
+// keyspace.
+//
+// It is safe to modify the contents of the arguments after Delete returns.
+func (kb KeyspaceBatch) Delete(key []byte, opts *WriteOptions) error {
+	return kb.batch().Delete(key, opts)
+}
+
+// SingleDelete adds an action to the batch that single deletes the entry for
+// key within the keyspace.
+//
+// It is safe to modify the contents of the arguments after SingleDelete
+// returns.
+func (kb KeyspaceBatch) SingleDelete(key []byte, opts *WriteOptions) error {
+	return kb.batch().SingleDelete(key, opts)
+}
+
+// DeleteRange adds an action to the batch that deletes all of the keys in the
+// range [start,end) within the keyspace.
+//
+// It is safe to modify the contents of the arguments after DeleteRange
+// returns.
+func (kb KeyspaceBatch) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	return kb.batch().DeleteRange(start, end, opts)
+}
+
+// Get gets the value for the given key within the keyspace, reading through
+// the batch. The batch must be indexed. See Batch.Get.
+func (kb KeyspaceBatch) Get(key []byte) ([]byte, io.Closer, error) {
+	return kb.batch().Get(key)
+}
+
+// NewIter returns an iterator over the keys of the keyspace, reading through
+// the batch. The batch must be indexed. See Batch.NewIter.
+func (kb KeyspaceBatch) NewIter(o *IterOptions) (*Iterator, error) {
+	return kb.batch().NewIter(o)
+}
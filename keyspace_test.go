@@ -0,0 +1,329 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// maxMerger is a Merger that retains the greatest operand.
+var maxMerger = &Merger{
+	Merge: func(key, value []byte) (ValueMerger, error) {
+		return &maxValueMerger{value: append([]byte(nil), value...)}, nil
+	},
+	Name: "max",
+}
+
+type maxValueMerger struct {
+	value []byte
+}
+
+func (m *maxValueMerger) MergeNewer(value []byte) error {
+	if string(value) > string(m.value) {
+		m.value = append(m.value[:0], value...)
+	}
+	return nil
+}
+
+func (m *maxValueMerger) MergeOlder(value []byte) error {
+	return m.MergeNewer(value)
+}
+
+func (m *maxValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	return m.value, nil, nil
+}
+
+func keyspaceGet(t *testing.T, k *Keyspace, key string) string {
+	t.Helper()
+	v, closer, err := k.Get([]byte(key))
+	if errors.Is(err, ErrNotFound) {
+		return "<not found>"
+	}
+	require.NoError(t, err)
+	defer closer.Close()
+	return string(v)
+}
+
+func keyspaceScan(t *testing.T, k *Keyspace, o *IterOptions) string {
+	t.Helper()
+	iter, err := k.NewIter(o)
+	require.NoError(t, err)
+	var keys []string
+	for valid := iter.First(); valid; valid = iter.Next() {
+		keys = append(keys, string(iter.Key())+"="+string(iter.Value()))
+	}
+	require.NoError(t, iter.Close())
+	return strings.Join(keys, " ")
+}
+
+func openKeyspaces(t *testing.T, dirname string, opts *Options) (d *DB, versions, meta *Keyspace) {
+	t.Helper()
+	d, err := Open(dirname, opts)
+	require.NoError(t, err)
+	versions, err = d.Keyspace("versions")
+	require.NoError(t, err)
+	meta, err = d.Keyspace("meta")
+	require.NoError(t, err)
+	return d, versions, meta
+}
+
+func testKeyspaceOptions(t *testing.T, fs vfs.FS) *Options {
+	return &Options{
+		FS:     fs,
+		Logger: testLogger{t},
+		Keyspaces: []KeyspaceOptions{
+			{Name: "versions", Comparer: testkeys.Comparer, Merger: maxMerger},
+			{Name: "meta", Levels: []LevelOptions{{
+				Compression: func() Compression { return NoCompression },
+			}}},
+		},
+	}
+}
+
+func TestKeyspaces(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := testKeyspaceOptions(t, mem)
+	d, versions, meta := openKeyspaces(t, "", opts)
+	_, err := d.Keyspace("missing")
+	require.True(t, errors.Is(err, ErrKeyspaceNotFound))
+
+	// A single batch writes to the DB and to both keyspaces.
+	b := d.NewIndexedBatch()
+	require.NoError(t, b.Set([]byte("a"), []byte("db"), nil))
+	require.NoError(t, versions.InBatch(b).Set([]byte("a@2"), []byte("v2"), nil))
+	require.NoError(t, versions.InBatch(b).Set([]byte("a@10"), []byte("v10"), nil))
+	require.NoError(t, versions.InBatch(b).Set([]byte("b@1"), []byte("v1"), nil))
+	require.NoError(t, meta.InBatch(b).Set([]byte("a@2"), []byte("m"), nil))
+	require.NoError(t, meta.InBatch(b).Set([]byte("z"), []byte("m"), nil))
+	v, closer, err := meta.InBatch(b).Get([]byte("z"))
+	require.NoError(t, err)
+	require.Equal(t, "m", string(v))
+	require.NoError(t, closer.Close())
+	require.Equal(t, "<not found>", keyspaceGet(t, meta, "z"))
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, b.Close())
+
+	check := func() {
+		t.Helper()
+		// The versions keyspace orders versions of a key newest first, while
+		// the meta keyspace orders keys bytewise.
+		require.Equal(t, "a@10=v10 a@2=v2 b@1=v1", keyspaceScan(t, versions, nil))
+		require.Equal(t, "a@2=m z=m", keyspaceScan(t, meta, nil))
+		require.Equal(t, "a@2=v2 b@1=v1", keyspaceScan(t, versions, &IterOptions{LowerBound: []byte("a@2")}))
+		require.Equal(t, "m", keyspaceGet(t, meta, "a@2"))
+		require.Equal(t, "<not found>", keyspaceGet(t, meta, "b@1"))
+		_, _, err := d.Get([]byte("a@2"))
+		require.True(t, errors.Is(err, ErrNotFound))
+	}
+	check()
+	require.NoError(t, versions.Flush())
+	require.NoError(t, meta.Flush())
+	check()
+
+	// Each keyspace writes its sstables with its own LevelOptions.
+	for _, k := range []*Keyspace{versions, meta} {
+		tables, err := k.db.SSTables(WithProperties())
+		require.NoError(t, err)
+		var compression []string
+		for _, level := range tables {
+			for _, table := range level {
+				compression = append(compression, table.Properties.CompressionName)
+			}
+		}
+		expected := map[string]string{"versions": "Snappy", "meta": "NoCompression"}[k.Name()]
+		require.Equal(t, []string{expected}, compression)
+	}
+
+	// Merges use the keyspace's Merger.
+	require.NoError(t, versions.Merge([]byte("m@1"), []byte("3"), nil))
+	require.NoError(t, versions.Merge([]byte("m@1"), []byte("1"), nil))
+	require.NoError(t, meta.Merge([]byte("m"), []byte("3"), nil))
+	require.NoError(t, meta.Merge([]byte("m"), []byte("1"), nil))
+	require.Equal(t, "3", keyspaceGet(t, versions, "m@1"))
+	require.Equal(t, "31", keyspaceGet(t, meta, "m"))
+	require.NoError(t, versions.Delete([]byte("m@1"), nil))
+	require.NoError(t, meta.DeleteRange([]byte("m"), []byte("n"), nil))
+	require.NoError(t, versions.Flush())
+
+	m := d.Metrics()
+	require.Len(t, m.Keyspaces, 2)
+	require.Equal(t, int64(2), m.Keyspaces["versions"].Total().NumFiles)
+	require.Equal(t, int64(1), m.Keyspaces["meta"].Total().NumFiles)
+	require.Zero(t, m.Total().NumFiles)
+
+	// A checkpoint restricted to the meta keyspace excludes the LSM of the
+	// versions keyspace.
+	require.NoError(t, d.Checkpoint("checkpoint", WithKeyspaces("meta")))
+	require.Error(t, d.Checkpoint("checkpoint2", WithKeyspaces("missing")))
+	require.NoError(t, d.Close())
+	_, err = mem.Stat(mem.PathJoin("checkpoint", keyspacesDirname, "versions"))
+	require.True(t, oserror.IsNotExist(err))
+
+	d, _, meta = openKeyspaces(t, "checkpoint", opts)
+	require.Equal(t, "a@2=m z=m", keyspaceScan(t, meta, nil))
+	v, closer, err = d.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, "db", string(v))
+	require.NoError(t, closer.Close())
+	require.NoError(t, d.Close())
+}
+
+func TestKeyspacesRecovery(t *testing.T) {
+	mem := vfs.NewCrashableMem()
+	opts := testKeyspaceOptions(t, mem)
+	d, versions, meta := openKeyspaces(t, "", opts)
+
+	// Unflushed writes to the keyspaces are recovered from the DB's WAL.
+	b := d.NewBatch()
+	require.NoError(t, versions.InBatch(b).Set([]byte("a@1"), []byte("1"), nil))
+	require.NoError(t, meta.InBatch(b).Merge([]byte("m"), []byte("a"), nil))
+	require.NoError(t, b.Commit(Sync))
+	require.NoError(t, b.Close())
+	require.NoError(t, d.Close())
+
+	d, versions, meta = openKeyspaces(t, "", opts)
+	require.Equal(t, "a@1=1", keyspaceScan(t, versions, nil))
+	require.Equal(t, "m=a", keyspaceScan(t, meta, nil))
+
+	// Writes that a keyspace has flushed aren't replayed again, while those
+	// of other keyspaces in the same WAL are.
+	require.NoError(t, meta.Merge([]byte("m"), []byte("b"), Sync))
+	require.NoError(t, versions.Set([]byte("b@1"), []byte("1"), Sync))
+	require.NoError(t, meta.Flush())
+	require.NoError(t, meta.Merge([]byte("m"), []byte("c"), Sync))
+	require.NoError(t, versions.Set([]byte("c@1"), []byte("1"), Sync))
+
+	crashFS := mem.CrashClone(vfs.CrashCloneCfg{})
+	require.NoError(t, d.Close())
+	for _, fs := range []vfs.FS{mem, crashFS} {
+		d, versions, meta = openKeyspaces(t, "", testKeyspaceOptions(t, fs))
+		require.Equal(t, "a@1=1 b@1=1 c@1=1", keyspaceScan(t, versions, nil))
+		require.Equal(t, "m=abc", keyspaceScan(t, meta, nil))
+		require.NoError(t, d.Close())
+	}
+
+	// The WAL holds writes to the meta keyspace, which must be configured.
+	d, _, meta = openKeyspaces(t, "", opts)
+	require.NoError(t, meta.Set([]byte("n"), []byte("1"), Sync))
+	crashFS = mem.CrashClone(vfs.CrashCloneCfg{})
+	require.NoError(t, d.Close())
+	_, err := Open("", &Options{
+		FS:        crashFS,
+		Logger:    testLogger{t},
+		Keyspaces: opts.Keyspaces[:1],
+	})
+	require.True(t, errors.Is(err, ErrKeyspaceNotFound))
+}
+
+func TestKeyspacesValidate(t *testing.T) {
+	for _, names := range [][]string{{""}, {"a", "a"}, {"."}, {"a/b"}} {
+		opts := &Options{FS: vfs.NewMem()}
+		for _, name := range names {
+			opts.Keyspaces = append(opts.Keyspaces, KeyspaceOptions{Name: name})
+		}
+		_, err := Open("", opts)
+		require.Error(t, err, "%q", names)
+	}
+}
+
+func TestKeyspacesMemTableRotation(t *testing.T) {
+	mem := vfs.NewCrashableMem()
+	opts := testKeyspaceOptions(t, mem)
+	opts.MemTableSize = 1 << 20
+	d, versions, meta := openKeyspaces(t, "", opts)
+
+	// Rotating the memtables of the keyspaces rotates the DB's WAL. The writes
+	// of the flushed memtables are not replayed, and their WALs are deleted.
+	const n = 2000
+	value := make([]byte, 1<<10)
+	for i := 0; i < n; i++ {
+		b := d.NewBatch()
+		require.NoError(t, versions.InBatch(b).Set([]byte(fmt.Sprintf("k%04d@1", i)), value, nil))
+		require.NoError(t, meta.InBatch(b).Merge([]byte("count"), []byte("x"), nil))
+		require.NoError(t, b.Commit(nil))
+		require.NoError(t, b.Close())
+	}
+	require.NoError(t, d.LogData(nil, Sync))
+	require.Greater(t, versions.Metrics().Flush.Count, int64(0))
+
+	crashFS := mem.CrashClone(vfs.CrashCloneCfg{})
+	require.NoError(t, d.Close())
+	for _, fs := range []vfs.FS{mem, crashFS} {
+		d, versions, meta = openKeyspaces(t, "", testKeyspaceOptions(t, fs))
+		iter, err := versions.NewIter(nil)
+		require.NoError(t, err)
+		var count int
+		for valid := iter.First(); valid; valid = iter.Next() {
+			count++
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, n, count)
+		require.Equal(t, strings.Repeat("x", n), keyspaceGet(t, meta, "count"))
+		require.NoError(t, d.Close())
+	}
+}
+
+// walSyncBlockingFS is a vfs.FS whose WAL files block in SyncData while block
+// is set, until block is closed.
+type walSyncBlockingFS struct {
+	vfs.FS
+	block atomic.Pointer[chan struct{}]
+}
+
+func (fs *walSyncBlockingFS) Create(name string, category vfs.DiskWriteCategory) (vfs.File, error) {
+	f, err := fs.FS.Create(name, category)
+	if err != nil || !strings.HasSuffix(name, ".log") {
+		return f, err
+	}
+	return &walSyncBlockingFile{File: f, fs: fs}, nil
+}
+
+type walSyncBlockingFile struct {
+	vfs.File
+	fs *walSyncBlockingFS
+}
+
+func (f *walSyncBlockingFile) SyncData() error {
+	if ch := f.fs.block.Load(); ch != nil {
+		<-*ch
+	}
+	return f.File.SyncData()
+}
+
+func TestKeyspacesSyncVisibility(t *testing.T) {
+	fs := &walSyncBlockingFS{FS: vfs.NewMem()}
+	d, versions, _ := openKeyspaces(t, "", testKeyspaceOptions(t, fs))
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// A synced write to a keyspace is not visible until the WAL is synced.
+	block := make(chan struct{})
+	fs.block.Store(&block)
+	// Unblock the WAL before the DB is closed if the test fails.
+	unblock := sync.OnceFunc(func() {
+		fs.block.Store(nil)
+		close(block)
+	})
+	defer unblock()
+	errCh := make(chan error, 1)
+	go func() { errCh <- versions.Set([]byte("a@1"), []byte("v1"), Sync) }()
+	require.Never(t, func() bool {
+		return keyspaceGet(t, versions, "a@1") != "<not found>"
+	}, 100*time.Millisecond, 5*time.Millisecond)
+	unblock()
+	require.NoError(t, <-errCh)
+	require.Equal(t, "v1", keyspaceGet(t, versions, "a@1"))
+}
@@ -77,6 +77,12 @@ type LevelMetrics struct {
 	// metrics for tables is TablesFlushed. This metric is always
 	// zero for all levels other than L0.
 	BytesFlushed uint64
+	// The number of bytes written to blob files during compactions into the
+	// level. Blob files are only written when value separation is enabled.
+	BlobBytesCompacted uint64
+	// The number of bytes written to blob files during flushes. This metric is
+	// always zero for all levels other than L0.
+	BlobBytesFlushed uint64
 	// The number of sstables compacted to this level.
 	TablesCompacted uint64
 	// The number of sstables flushed to this level.
@@ -112,6 +118,17 @@ type LevelMetrics struct {
 		// LevelMetrics.format, but are available to sophisticated clients.
 		BytesWrittenDataBlocks  uint64
 		BytesWrittenValueBlocks uint64
+		// Cumulative metrics about compression dictionaries of sstables written
+		// via compactions or flushes. A sample of the data blocks compressed with
+		// a dictionary are also compressed without it: the ratio of
+		// CompressionDictSampledWithoutDictSize to
+		// CompressionDictSampledWithDictSize measures the benefit of the
+		// dictionaries. Not printed by LevelMetrics.format.
+		TablesWithCompressionDict             uint64
+		CompressionDictSize                   uint64
+		CompressionDictSampledSize            uint64
+		CompressionDictSampledWithDictSize    uint64
+		CompressionDictSampledWithoutDictSize uint64
 	}
 }
 
@@ -127,6 +144,8 @@ func (m *LevelMetrics) Add(u *LevelMetrics) {
 	m.BytesRead += u.BytesRead
 	m.BytesCompacted += u.BytesCompacted
 	m.BytesFlushed += u.BytesFlushed
+	m.BlobBytesCompacted += u.BlobBytesCompacted
+	m.BlobBytesFlushed += u.BlobBytesFlushed
 	m.TablesCompacted += u.TablesCompacted
 	m.TablesFlushed += u.TablesFlushed
 	m.TablesIngested += u.TablesIngested
@@ -137,18 +156,31 @@ func (m *LevelMetrics) Add(u *LevelMetrics) {
 	m.Additional.BytesWrittenDataBlocks += u.Additional.BytesWrittenDataBlocks
 	m.Additional.BytesWrittenValueBlocks += u.Additional.BytesWrittenValueBlocks
 	m.Additional.ValueBlocksSize += u.Additional.ValueBlocksSize
+	m.Additional.TablesWithCompressionDict += u.Additional.TablesWithCompressionDict
+	m.Additional.CompressionDictSize += u.Additional.CompressionDictSize
+	m.Additional.CompressionDictSampledSize += u.Additional.CompressionDictSampledSize
+	m.Additional.CompressionDictSampledWithDictSize += u.Additional.CompressionDictSampledWithDictSize
+	m.Additional.CompressionDictSampledWithoutDictSize += u.Additional.CompressionDictSampledWithoutDictSize
 }
 
 // WriteAmp computes the write amplification for compactions at this
-// level. Computed as (BytesFlushed + BytesCompacted) / BytesIn.
+// level. Computed as (BytesFlushed + BytesCompacted + BlobBytesFlushed +
+// BlobBytesCompacted) / BytesIn.
 func (m *LevelMetrics) WriteAmp() float64 {
 	if m.BytesIn == 0 {
 		return 0
 	}
-	return float64(m.BytesFlushed+m.BytesCompacted) / float64(m.BytesIn)
+	return float64(m.bytesWritten()) / float64(m.BytesIn)
+}
+
+// bytesWritten returns the number of bytes written to sstables and blob files
+// by flushes and compactions into the level.
+func (m *LevelMetrics) bytesWritten() uint64 {
+	return m.BytesFlushed + m.BytesCompacted + m.BlobBytesFlushed + m.BlobBytesCompacted
 }
 
 var categoryCompaction = block.RegisterCategory("pebble-compaction", block.NonLatencySensitiveQoSLevel)
+var categoryFlush = block.RegisterCategory("pebble-flush", block.LatencySensitiveQoSLevel)
 var categoryIngest = block.RegisterCategory("pebble-ingest", block.LatencySensitiveQoSLevel)
 var categoryGet = block.RegisterCategory("pebble-get", block.LatencySensitiveQoSLevel)
 
@@ -172,6 +204,8 @@ type Metrics struct {
 		ReadCount             int64
 		TombstoneDensityCount int64
 		RewriteCount          int64
+		BlobFileRewriteCount  int64
+		UploadCount           int64
 		MultiLevelCount       int64
 		CounterLevelCount     int64
 		// An estimate of the number of bytes that need to be compacted for the LSM
@@ -202,6 +236,10 @@ type Metrics struct {
 		Count uint64
 	}
 
+	// Upload contains the metrics of upload compactions (see
+	// Options.Experimental.UploadLocalTables).
+	Upload UploadMetrics
+
 	Flush struct {
 		// The total number of flushes.
 		Count           int64
@@ -287,8 +325,12 @@ type Metrics struct {
 		// The number of sstables that are compressed with the default compression
 		// algorithm, snappy.
 		CompressedCountSnappy int64
-		// The number of sstables that are compressed with zstd.
+		// The number of sstables that are compressed with zstd, at any level.
 		CompressedCountZstd int64
+		// The number of sstables that are compressed with LZ4.
+		CompressedCountLz4 int64
+		// The number of sstables that are compressed with MinLZ.
+		CompressedCountMinLZ int64
 		// The number of sstables that are uncompressed.
 		CompressedCountNone int64
 
@@ -303,8 +345,56 @@ type Metrics struct {
 		}
 	}
 
+	BlobFiles struct {
+		// The number of blob files referenced by the current version.
+		LiveCount uint64
+		// The physical size of the blob files referenced by the current
+		// version.
+		LiveSize uint64
+		// ValueSize is the sum of the uncompressed lengths of all values stored
+		// in the blob files referenced by the current version.
+		ValueSize uint64
+		// ReferencedValueSize is the sum of the uncompressed lengths of the
+		// values stored in live blob files that are still referenced by
+		// sstables in the current version. ValueSize - ReferencedValueSize is
+		// an estimate of the garbage contained within live blob files.
+		ReferencedValueSize uint64
+		// GarbageRatio is the fraction of the values stored in live blob files
+		// that are no longer referenced by the current version, computed as
+		// 1 - ReferencedValueSize/ValueSize.
+		GarbageRatio float64
+		// MaxGarbageRatio is the highest garbage ratio of any individual live
+		// blob file.
+		MaxGarbageRatio float64
+		// The number of bytes present in obsolete blob files which are no
+		// longer referenced by the current DB state or any open iterators.
+		ObsoleteSize uint64
+		// The count of obsolete blob files.
+		ObsoleteCount int64
+		// The number of bytes present in zombie blob files which are no longer
+		// referenced by the current DB state but are still in use by an
+		// iterator.
+		ZombieSize uint64
+		// The count of zombie blob files.
+		ZombieCount int64
+
+		// Local file sizes.
+		Local struct {
+			// LiveSize is the number of bytes in live blob files.
+			LiveSize uint64
+			// ObsoleteSize is the number of bytes in obsolete blob files.
+			ObsoleteSize uint64
+			// ZombieSize is the number of bytes in zombie blob files.
+			ZombieSize uint64
+		}
+	}
+
 	FileCache CacheMetrics
 
+	// Keyspaces holds the metrics of each keyspace, keyed by name. It is nil
+	// if the DB was not configured with keyspaces. See Options.Keyspaces.
+	Keyspaces map[string]*Metrics
+
 	// Count of the number of open sstable iterators.
 	TableIters int64
 	// Uptime is the total time since this DB was opened.
@@ -334,6 +424,20 @@ type Metrics struct {
 		Failover wal.FailoverStats
 	}
 
+	// Follower contains the metrics of a DB opened with OpenFollower. It is
+	// empty for other DBs.
+	Follower struct {
+		// Lag is the replication lag of the follower: the time since the start
+		// of its last successful refresh. Every batch written to the primary's
+		// WAL before then is visible to the follower.
+		Lag time.Duration
+		// Number of successful refreshes.
+		RefreshCount int64
+		// Number of refreshes that failed. The error of a failed refresh is
+		// logged, and the refresh is retried at the next refresh interval.
+		RefreshErrorCount int64
+	}
+
 	LogWriter struct {
 		FsyncLatency prometheus.Histogram
 		record.LogWriterMetrics
@@ -343,6 +447,10 @@ type Metrics struct {
 
 	SecondaryCacheMetrics SecondaryCacheMetrics
 
+	// SecondaryBlockCache contains the metrics of each device of the secondary
+	// block cache (see Options.Experimental.SecondaryBlockCache).
+	SecondaryBlockCache []SecondaryBlockCacheMetrics
+
 	private struct {
 		optionsFileSize  uint64
 		manifestFileSize uint64
@@ -377,6 +485,9 @@ func (m *Metrics) DiskSpaceUsage() uint64 {
 	usageBytes += m.Table.Local.LiveSize
 	usageBytes += m.Table.Local.ObsoleteSize
 	usageBytes += m.Table.Local.ZombieSize
+	usageBytes += m.BlobFiles.Local.LiveSize
+	usageBytes += m.BlobFiles.Local.ObsoleteSize
+	usageBytes += m.BlobFiles.Local.ZombieSize
 	usageBytes += m.private.optionsFileSize
 	usageBytes += m.private.manifestFileSize
 	// TODO(sumeer): InProgressBytes does not distinguish between local and
@@ -535,7 +646,7 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 			humanize.Count.Uint64(m.TablesMoved),
 			humanize.Bytes.Uint64(m.BytesMoved),
 			humanize.Count.Uint64(m.TablesFlushed+m.TablesCompacted),
-			humanize.Bytes.Uint64(m.BytesFlushed+m.BytesCompacted),
+			humanize.Bytes.Uint64(m.bytesWritten()),
 			humanize.Bytes.Uint64(m.BytesRead),
 			redact.Safe(m.Sublevels),
 			redact.Safe(wampStr))
@@ -598,7 +709,7 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 		redact.Safe(m.Compact.NumInProgress),
 		humanize.Bytes.Int64(m.Compact.InProgressBytes))
 
-	w.Printf("             default: %d  delete: %d  elision: %d  move: %d  read: %d  tombstone-density: %d  rewrite: %d  copy: %d  multi-level: %d\n",
+	w.Printf("             default: %d  delete: %d  elision: %d  move: %d  read: %d  tombstone-density: %d  rewrite: %d  blob-rewrite: %d  copy: %d  multi-level: %d\n",
 		redact.Safe(m.Compact.DefaultCount),
 		redact.Safe(m.Compact.DeleteOnlyCount),
 		redact.Safe(m.Compact.ElisionOnlyCount),
@@ -606,8 +717,20 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 		redact.Safe(m.Compact.ReadCount),
 		redact.Safe(m.Compact.TombstoneDensityCount),
 		redact.Safe(m.Compact.RewriteCount),
+		redact.Safe(m.Compact.BlobFileRewriteCount),
 		redact.Safe(m.Compact.CopyCount),
 		redact.Safe(m.Compact.MultiLevelCount))
+	if m.Upload.Enabled {
+		w.Printf("Uploads: %d (%s)  pending: %d (%s)  in progress: %d  failed: %d  failing: %d  done: %t\n",
+			redact.Safe(m.Upload.Count),
+			humanize.Bytes.Uint64(m.Upload.Bytes),
+			redact.Safe(m.Upload.PendingCount),
+			humanize.Bytes.Uint64(m.Upload.PendingBytes),
+			redact.Safe(m.Upload.NumInProgress),
+			redact.Safe(m.Upload.FailedCount),
+			redact.Safe(m.Upload.FailingCount),
+			redact.Safe(m.Upload.Done))
+	}
 
 	w.Printf("MemTables: %d (%s)  zombie: %d (%s)\n",
 		redact.Safe(m.MemTable.Count),
@@ -627,6 +750,19 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 		redact.Safe(m.NumVirtual()),
 		humanize.Bytes.Uint64(m.VirtualSize()))
 	w.Printf("Local tables size: %s\n", humanize.Bytes.Uint64(m.Table.Local.LiveSize))
+	if m.BlobFiles.LiveCount > 0 || m.BlobFiles.ZombieCount > 0 || m.BlobFiles.ObsoleteCount > 0 {
+		w.Printf("Blob files: %d (%s)  values: %s  referenced: %s  garbage: %.1f%% (max %.1f%%)  zombie: %d (%s)  obsolete: %d (%s)\n",
+			redact.Safe(m.BlobFiles.LiveCount),
+			humanize.Bytes.Uint64(m.BlobFiles.LiveSize),
+			humanize.Bytes.Uint64(m.BlobFiles.ValueSize),
+			humanize.Bytes.Uint64(m.BlobFiles.ReferencedValueSize),
+			redact.Safe(100*m.BlobFiles.GarbageRatio),
+			redact.Safe(100*m.BlobFiles.MaxGarbageRatio),
+			redact.Safe(m.BlobFiles.ZombieCount),
+			humanize.Bytes.Uint64(m.BlobFiles.ZombieSize),
+			redact.Safe(m.BlobFiles.ObsoleteCount),
+			humanize.Bytes.Uint64(m.BlobFiles.ObsoleteSize))
+	}
 	w.SafeString("Compression types:")
 	if count := m.Table.CompressedCountSnappy; count > 0 {
 		w.Printf(" snappy: %d", redact.Safe(count))
@@ -634,6 +770,12 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 	if count := m.Table.CompressedCountZstd; count > 0 {
 		w.Printf(" zstd: %d", redact.Safe(count))
 	}
+	if count := m.Table.CompressedCountLz4; count > 0 {
+		w.Printf(" lz4: %d", redact.Safe(count))
+	}
+	if count := m.Table.CompressedCountMinLZ; count > 0 {
+		w.Printf(" minlz: %d", redact.Safe(count))
+	}
 	if count := m.Table.CompressedCountNone; count > 0 {
 		w.Printf(" none: %d", redact.Safe(count))
 	}
@@ -662,6 +804,9 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 	if m.SecondaryCacheMetrics.Size > 0 || m.SecondaryCacheMetrics.ReadsWithFullHit > 0 {
 		formatSharedCacheMetrics(w, &m.SecondaryCacheMetrics, "Secondary cache")
 	}
+	for i := range m.SecondaryBlockCache {
+		formatSharedCacheMetrics(w, &m.SecondaryBlockCache[i].SecondaryCacheMetrics, "Secondary block cache")
+	}
 
 	w.Printf("Snapshots: %d  earliest seq num: %d\n",
 		redact.Safe(m.Snapshots.Count),
@@ -0,0 +1,492 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package prometheus exports the metrics of a pebble.DB to Prometheus.
+//
+// The metrics are named pebble_<name>, and are labeled by level, compaction
+// kind, cache, compression algorithm or block category where the DB's metrics
+// are broken down by them. The names and labels are stable: metrics may be
+// added, but existing metrics are not renamed or relabeled. Byte quantities
+// are exported in bytes and durations in seconds, as recommended by the
+// Prometheus naming conventions.
+//
+// The latency histograms recorded by the DB (the WAL fsync latency, the WAL
+// failover write and sync latency and the secondary cache latencies) are
+// exported as histograms with the buckets they were recorded with.
+package prometheus
+
+import (
+	"strconv"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/prometheus/client_golang/prometheus"
+	prometheusgo "github.com/prometheus/client_model/go"
+)
+
+const namespace = "pebble"
+
+// Collector is a prometheus.Collector that exports the metrics of a DB. The
+// metrics are retrieved from the DB with DB.Metrics every time the collector
+// is collected.
+type Collector struct {
+	metrics    func() *pebble.Metrics
+	descs      []*prometheus.Desc
+	histDescs  []*prometheus.Desc
+	levelNames [len(pebble.Metrics{}.Levels)]string
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a Collector exporting the metrics of d. The constant
+// labels are added to every metric, and may be used to distinguish the
+// metrics of multiple DBs registered with the same registry.
+func NewCollector(d *pebble.DB, constLabels prometheus.Labels) *Collector {
+	return newCollector(d.Metrics, constLabels)
+}
+
+// Register registers a Collector exporting the metrics of d with r. See
+// NewCollector.
+func Register(r prometheus.Registerer, d *pebble.DB, constLabels prometheus.Labels) error {
+	return r.Register(NewCollector(d, constLabels))
+}
+
+func newCollector(metrics func() *pebble.Metrics, constLabels prometheus.Labels) *Collector {
+	c := &Collector{
+		metrics:   metrics,
+		descs:     make([]*prometheus.Desc, len(metricDefs)),
+		histDescs: make([]*prometheus.Desc, len(histogramDefs)),
+	}
+	for i, def := range metricDefs {
+		c.descs[i] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", def.name), def.help, def.labels, constLabels)
+	}
+	for i, def := range histogramDefs {
+		c.histDescs[i] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", def.name), def.help, nil, constLabels)
+	}
+	for i := range c.levelNames {
+		c.levelNames[i] = strconv.Itoa(i)
+	}
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+	for _, desc := range c.histDescs {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	m := c.metrics()
+	for i, def := range metricDefs {
+		desc := c.descs[i]
+		def.collect(c, m, func(v float64, labelValues ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, def.valueType, v, labelValues...)
+		})
+	}
+	for i, def := range histogramDefs {
+		// The histograms are nil if the DB does not record them, for example if
+		// WAL failover or the secondary cache are not configured.
+		if h := def.histogram(m); h != nil {
+			ch <- constHistogram(c.histDescs[i], h)
+		}
+	}
+}
+
+// constHistogram returns a snapshot of h, whose observations are durations in
+// nanoseconds, as a histogram of seconds.
+func constHistogram(desc *prometheus.Desc, h prometheus.Histogram) prometheus.Metric {
+	var pm prometheusgo.Metric
+	if err := h.Write(&pm); err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	buckets := make(map[float64]uint64, len(pm.Histogram.GetBucket()))
+	for _, b := range pm.Histogram.GetBucket() {
+		buckets[b.GetUpperBound()/nanosPerSecond] = b.GetCumulativeCount()
+	}
+	m, err := prometheus.NewConstHistogram(
+		desc, pm.Histogram.GetSampleCount(), pm.Histogram.GetSampleSum()/nanosPerSecond, buckets)
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	return m
+}
+
+const nanosPerSecond = 1e9
+
+// metricDef defines an exported metric with a constant value.
+type metricDef struct {
+	name      string
+	help      string
+	valueType prometheus.ValueType
+	labels    []string
+	// collect emits the values of the metric, one per combination of label
+	// values.
+	collect func(c *Collector, m *pebble.Metrics, emit func(v float64, labelValues ...string))
+}
+
+// histogramDef defines an exported histogram.
+type histogramDef struct {
+	name      string
+	help      string
+	histogram func(m *pebble.Metrics) prometheus.Histogram
+}
+
+func gauge(name, help string, value func(m *pebble.Metrics) float64) metricDef {
+	return metricDef{
+		name:      name,
+		help:      help,
+		valueType: prometheus.GaugeValue,
+		collect: func(_ *Collector, m *pebble.Metrics, emit func(float64, ...string)) {
+			emit(value(m))
+		},
+	}
+}
+
+func counter(name, help string, value func(m *pebble.Metrics) float64) metricDef {
+	def := gauge(name, help, value)
+	def.valueType = prometheus.CounterValue
+	return def
+}
+
+// levelMetric defines a metric labeled by level.
+func levelMetric(
+	name, help string, valueType prometheus.ValueType, value func(l *pebble.LevelMetrics) float64,
+) metricDef {
+	return metricDef{
+		name:      name,
+		help:      help,
+		valueType: valueType,
+		labels:    []string{"level"},
+		collect: func(c *Collector, m *pebble.Metrics, emit func(float64, ...string)) {
+			for i := range m.Levels {
+				emit(value(&m.Levels[i]), c.levelNames[i])
+			}
+		},
+	}
+}
+
+// labeledMetric defines a metric with a single label, whose values are the
+// keys of the map returned by values.
+func labeledMetric(
+	name, help string,
+	valueType prometheus.ValueType,
+	label string,
+	values func(m *pebble.Metrics) map[string]float64,
+) metricDef {
+	return metricDef{
+		name:      name,
+		help:      help,
+		valueType: valueType,
+		labels:    []string{label},
+		collect: func(_ *Collector, m *pebble.Metrics, emit func(float64, ...string)) {
+			for labelValue, v := range values(m) {
+				emit(v, labelValue)
+			}
+		},
+	}
+}
+
+// categoryMetric defines a metric labeled by block category.
+func categoryMetric(name, help string, value func(s *block.CategoryStats) float64) metricDef {
+	return metricDef{
+		name:      name,
+		help:      help,
+		valueType: prometheus.CounterValue,
+		labels:    []string{"category"},
+		collect: func(_ *Collector, m *pebble.Metrics, emit func(float64, ...string)) {
+			for i := range m.CategoryStats {
+				emit(value(&m.CategoryStats[i].CategoryStats), m.CategoryStats[i].Category.String())
+			}
+		},
+	}
+}
+
+var metricDefs = []metricDef{
+	// Per-level metrics.
+	levelMetric("level_sublevels", "Number of sublevels within the level.",
+		prometheus.GaugeValue, func(l *pebble.LevelMetrics) float64 { return float64(l.Sublevels) }),
+	levelMetric("level_tables", "Number of sstables in the level.",
+		prometheus.GaugeValue, func(l *pebble.LevelMetrics) float64 { return float64(l.NumFiles) }),
+	levelMetric("level_virtual_tables", "Number of virtual sstables in the level.",
+		prometheus.GaugeValue, func(l *pebble.LevelMetrics) float64 { return float64(l.NumVirtualFiles) }),
+	levelMetric("level_size_bytes", "Total size of the sstables in the level.",
+		prometheus.GaugeValue, func(l *pebble.LevelMetrics) float64 { return float64(l.Size) }),
+	levelMetric("level_virtual_size_bytes", "Total size of the virtual sstables in the level.",
+		prometheus.GaugeValue, func(l *pebble.LevelMetrics) float64 { return float64(l.VirtualSize) }),
+	levelMetric("level_score", "Compaction score of the level.",
+		prometheus.GaugeValue, func(l *pebble.LevelMetrics) float64 { return l.Score }),
+	levelMetric("level_bytes_in_total", "Bytes read from other levels by compactions into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BytesIn) }),
+	levelMetric("level_bytes_ingested_total", "Bytes ingested into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BytesIngested) }),
+	levelMetric("level_bytes_moved_total", "Bytes moved into the level by move compactions.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BytesMoved) }),
+	levelMetric("level_bytes_read_total", "Bytes read by compactions at the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BytesRead) }),
+	levelMetric("level_bytes_compacted_total", "Bytes written to sstables by compactions into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BytesCompacted) }),
+	levelMetric("level_bytes_flushed_total", "Bytes written to sstables by flushes into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BytesFlushed) }),
+	levelMetric("level_blob_bytes_compacted_total", "Bytes written to blob files by compactions into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BlobBytesCompacted) }),
+	levelMetric("level_blob_bytes_flushed_total", "Bytes written to blob files by flushes into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.BlobBytesFlushed) }),
+	levelMetric("level_tables_compacted_total", "Number of sstables written by compactions into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.TablesCompacted) }),
+	levelMetric("level_tables_flushed_total", "Number of sstables written by flushes into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.TablesFlushed) }),
+	levelMetric("level_tables_ingested_total", "Number of sstables ingested into the level.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.TablesIngested) }),
+	levelMetric("level_tables_moved_total", "Number of sstables moved into the level by move compactions.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.TablesMoved) }),
+	levelMetric("level_tables_deleted_total", "Number of sstables deleted from the level by delete-only compactions.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.TablesDeleted) }),
+	levelMetric("level_tables_excised_total", "Number of sstables excised in the level by delete-only compactions.",
+		prometheus.CounterValue, func(l *pebble.LevelMetrics) float64 { return float64(l.TablesExcised) }),
+
+	// Compactions.
+	counter("compactions_total", "Number of compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Compact.Count) }),
+	labeledMetric("compactions_by_kind_total", "Number of compactions, by kind.",
+		prometheus.CounterValue, "kind", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{
+				"default":           float64(m.Compact.DefaultCount),
+				"delete-only":       float64(m.Compact.DeleteOnlyCount),
+				"elision-only":      float64(m.Compact.ElisionOnlyCount),
+				"copy":              float64(m.Compact.CopyCount),
+				"move":              float64(m.Compact.MoveCount),
+				"read":              float64(m.Compact.ReadCount),
+				"tombstone-density": float64(m.Compact.TombstoneDensityCount),
+				"rewrite":           float64(m.Compact.RewriteCount),
+				"blob-rewrite":      float64(m.Compact.BlobFileRewriteCount),
+				"upload":            float64(m.Compact.UploadCount),
+				"multi-level":       float64(m.Compact.MultiLevelCount),
+				"counter-level":     float64(m.Compact.CounterLevelCount),
+			}
+		}),
+	gauge("compaction_estimated_debt_bytes", "Estimate of the bytes that need to be compacted for the LSM to reach a stable state.",
+		func(m *pebble.Metrics) float64 { return float64(m.Compact.EstimatedDebt) }),
+	gauge("compactions_in_progress", "Number of in-progress compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Compact.NumInProgress) }),
+	gauge("compaction_in_progress_bytes", "Bytes in sstables being written by in-progress compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Compact.InProgressBytes) }),
+	counter("compactions_cancelled_total", "Number of cancelled compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Compact.CancelledCount) }),
+	counter("compaction_cancelled_bytes_total", "Bytes written by cancelled compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Compact.CancelledBytes) }),
+	gauge("compaction_marked_tables", "Number of sstables marked for compaction.",
+		func(m *pebble.Metrics) float64 { return float64(m.Compact.MarkedFiles) }),
+	counter("compaction_duration_seconds_total", "Cumulative duration of compactions.",
+		func(m *pebble.Metrics) float64 { return m.Compact.Duration.Seconds() }),
+
+	// Uploads of local sstables to shared storage.
+	counter("uploaded_tables_total", "Number of sstables moved to shared storage by upload compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Upload.Count) }),
+	counter("uploaded_bytes_total", "Bytes moved to shared storage by upload compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Upload.Bytes) }),
+	gauge("upload_pending_tables", "Number of local sstables that remain to be moved to shared storage.",
+		func(m *pebble.Metrics) float64 { return float64(m.Upload.PendingCount) }),
+	gauge("upload_pending_bytes", "Bytes in local sstables that remain to be moved to shared storage.",
+		func(m *pebble.Metrics) float64 { return float64(m.Upload.PendingBytes) }),
+	counter("upload_failures_total", "Number of failed upload compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Upload.FailedCount) }),
+	gauge("upload_failing_tables", "Number of local sstables whose upload to shared storage failed.",
+		func(m *pebble.Metrics) float64 { return float64(m.Upload.FailingCount) }),
+
+	// Flushes and ingestions.
+	counter("flushes_total", "Number of flushes.",
+		func(m *pebble.Metrics) float64 { return float64(m.Flush.Count) }),
+	gauge("flushes_in_progress", "Number of in-progress flushes.",
+		func(m *pebble.Metrics) float64 { return float64(m.Flush.NumInProgress) }),
+	counter("flush_bytes_total", "Bytes written by flushes.",
+		func(m *pebble.Metrics) float64 { return float64(m.Flush.WriteThroughput.Bytes) }),
+	counter("flush_work_seconds_total", "Cumulative duration flushes spent writing.",
+		func(m *pebble.Metrics) float64 { return m.Flush.WriteThroughput.WorkDuration.Seconds() }),
+	counter("flush_idle_seconds_total", "Cumulative duration flushes spent waiting for work.",
+		func(m *pebble.Metrics) float64 { return m.Flush.WriteThroughput.IdleDuration.Seconds() }),
+	counter("flushes_as_ingest_total", "Number of flushes of ingested sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Flush.AsIngestCount) }),
+	counter("flush_as_ingest_tables_total", "Number of sstables ingested as flushables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Flush.AsIngestTableCount) }),
+	counter("flush_as_ingest_bytes_total", "Bytes flushed for flushables that originated as ingestions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Flush.AsIngestBytes) }),
+	counter("ingestions_total", "Number of ingestions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Ingest.Count) }),
+
+	// Block and file caches.
+	labeledMetric("cache_size_bytes", "Bytes in use by the cache.",
+		prometheus.GaugeValue, "cache", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{"block": float64(m.BlockCache.Size), "file": float64(m.FileCache.Size)}
+		}),
+	labeledMetric("cache_entries", "Number of entries in the cache.",
+		prometheus.GaugeValue, "cache", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{"block": float64(m.BlockCache.Count), "file": float64(m.FileCache.Count)}
+		}),
+	labeledMetric("cache_hits_total", "Number of cache hits.",
+		prometheus.CounterValue, "cache", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{"block": float64(m.BlockCache.Hits), "file": float64(m.FileCache.Hits)}
+		}),
+	labeledMetric("cache_misses_total", "Number of cache misses.",
+		prometheus.CounterValue, "cache", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{"block": float64(m.BlockCache.Misses), "file": float64(m.FileCache.Misses)}
+		}),
+	counter("filter_hits_total", "Number of data block accesses avoided by the filter policy.",
+		func(m *pebble.Metrics) float64 { return float64(m.Filter.Hits) }),
+	counter("filter_misses_total", "Number of filter policy checks that did not avoid a data block access.",
+		func(m *pebble.Metrics) float64 { return float64(m.Filter.Misses) }),
+
+	// Memtables.
+	gauge("memtable_size_bytes", "Bytes allocated by memtables and large batches.",
+		func(m *pebble.Metrics) float64 { return float64(m.MemTable.Size) }),
+	gauge("memtables", "Number of memtables.",
+		func(m *pebble.Metrics) float64 { return float64(m.MemTable.Count) }),
+	gauge("memtable_zombie_size_bytes", "Bytes in zombie memtables.",
+		func(m *pebble.Metrics) float64 { return float64(m.MemTable.ZombieSize) }),
+	gauge("memtable_zombies", "Number of zombie memtables.",
+		func(m *pebble.Metrics) float64 { return float64(m.MemTable.ZombieCount) }),
+
+	// Keys and snapshots.
+	gauge("range_key_sets", "Approximate number of range key sets.",
+		func(m *pebble.Metrics) float64 { return float64(m.Keys.RangeKeySetsCount) }),
+	gauge("tombstones", "Approximate number of point and range tombstones.",
+		func(m *pebble.Metrics) float64 { return float64(m.Keys.TombstoneCount) }),
+	counter("missized_tombstones_total", "Number of missized DELSIZED keys encountered by compactions.",
+		func(m *pebble.Metrics) float64 { return float64(m.Keys.MissizedTombstonesCount) }),
+	gauge("snapshots", "Number of open snapshots.",
+		func(m *pebble.Metrics) float64 { return float64(m.Snapshots.Count) }),
+	counter("snapshot_pinned_keys_total", "Number of keys written that would have been elided if not for open snapshots.",
+		func(m *pebble.Metrics) float64 { return float64(m.Snapshots.PinnedKeys) }),
+	counter("snapshot_pinned_bytes_total", "Bytes of keys and values written that would have been elided if not for open snapshots.",
+		func(m *pebble.Metrics) float64 { return float64(m.Snapshots.PinnedSize) }),
+
+	// Sstables.
+	gauge("table_obsolete_size_bytes", "Bytes in obsolete sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Table.ObsoleteSize) }),
+	gauge("table_obsolete", "Number of obsolete sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Table.ObsoleteCount) }),
+	gauge("table_zombie_size_bytes", "Bytes in zombie sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Table.ZombieSize) }),
+	gauge("table_zombies", "Number of zombie sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Table.ZombieCount) }),
+	gauge("table_backing", "Number of sstables backing virtual sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Table.BackingTableCount) }),
+	gauge("table_backing_size_bytes", "Bytes in sstables backing virtual sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.Table.BackingTableSize) }),
+	labeledMetric("tables_by_compression", "Number of sstables, by compression algorithm.",
+		prometheus.GaugeValue, "compression", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{
+				"unknown": float64(m.Table.CompressedCountUnknown),
+				"snappy":  float64(m.Table.CompressedCountSnappy),
+				"zstd":    float64(m.Table.CompressedCountZstd),
+				"lz4":     float64(m.Table.CompressedCountLz4),
+				"minlz":   float64(m.Table.CompressedCountMinLZ),
+				"none":    float64(m.Table.CompressedCountNone),
+			}
+		}),
+	gauge("table_iterators", "Number of open sstable iterators.",
+		func(m *pebble.Metrics) float64 { return float64(m.TableIters) }),
+
+	// Blob files.
+	gauge("blob_files", "Number of live blob files.",
+		func(m *pebble.Metrics) float64 { return float64(m.BlobFiles.LiveCount) }),
+	gauge("blob_file_size_bytes", "Bytes in live blob files.",
+		func(m *pebble.Metrics) float64 { return float64(m.BlobFiles.LiveSize) }),
+	gauge("blob_file_value_bytes", "Uncompressed bytes of the values in live blob files.",
+		func(m *pebble.Metrics) float64 { return float64(m.BlobFiles.ValueSize) }),
+	gauge("blob_file_referenced_value_bytes", "Uncompressed bytes of the values in live blob files that are referenced by sstables.",
+		func(m *pebble.Metrics) float64 { return float64(m.BlobFiles.ReferencedValueSize) }),
+	gauge("blob_file_obsolete_size_bytes", "Bytes in obsolete blob files.",
+		func(m *pebble.Metrics) float64 { return float64(m.BlobFiles.ObsoleteSize) }),
+	gauge("blob_file_zombie_size_bytes", "Bytes in zombie blob files.",
+		func(m *pebble.Metrics) float64 { return float64(m.BlobFiles.ZombieSize) }),
+
+	// WAL.
+	gauge("wal_files", "Number of live WAL files.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.Files) }),
+	gauge("wal_obsolete_files", "Number of obsolete WAL files.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.ObsoleteFiles) }),
+	gauge("wal_size_bytes", "Bytes of live data in the WAL files.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.Size) }),
+	gauge("wal_physical_size_bytes", "On-disk size of the WAL files.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.PhysicalSize) }),
+	gauge("wal_obsolete_physical_size_bytes", "On-disk size of the obsolete WAL files.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.ObsoletePhysicalSize) }),
+	counter("wal_bytes_in_total", "Logical bytes written to the WAL.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.BytesIn) }),
+	counter("wal_bytes_written_total", "Bytes written to the WAL files.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.BytesWritten) }),
+	counter("wal_failover_switches_total", "Number of times WAL writing switched to a different directory.",
+		func(m *pebble.Metrics) float64 { return float64(m.WAL.Failover.DirSwitchCount) }),
+	labeledMetric("wal_failover_write_seconds_total", "Cumulative duration WAL writes used the directory.",
+		prometheus.CounterValue, "dir", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{
+				"primary":   m.WAL.Failover.PrimaryWriteDuration.Seconds(),
+				"secondary": m.WAL.Failover.SecondaryWriteDuration.Seconds(),
+			}
+		}),
+
+	// Secondary cache.
+	gauge("secondary_cache_size_bytes", "Bytes of sstables stored in the secondary cache.",
+		func(m *pebble.Metrics) float64 { return float64(m.SecondaryCacheMetrics.Size) }),
+	gauge("secondary_cache_blocks", "Number of cache blocks in the secondary cache.",
+		func(m *pebble.Metrics) float64 { return float64(m.SecondaryCacheMetrics.Count) }),
+	labeledMetric("secondary_cache_reads_total", "Number of reads from the secondary cache, by how much of the data was in the cache.",
+		prometheus.CounterValue, "hit", func(m *pebble.Metrics) map[string]float64 {
+			return map[string]float64{
+				"full":    float64(m.SecondaryCacheMetrics.ReadsWithFullHit),
+				"partial": float64(m.SecondaryCacheMetrics.ReadsWithPartialHit),
+				"none":    float64(m.SecondaryCacheMetrics.ReadsWithNoHit),
+			}
+		}),
+	counter("secondary_cache_multi_shard_reads_total", "Number of reads from the secondary cache spanning multiple shards.",
+		func(m *pebble.Metrics) float64 { return float64(m.SecondaryCacheMetrics.MultiShardReads) }),
+	counter("secondary_cache_multi_block_reads_total", "Number of reads from the secondary cache spanning multiple cache blocks.",
+		func(m *pebble.Metrics) float64 { return float64(m.SecondaryCacheMetrics.MultiBlockReads) }),
+	counter("secondary_cache_evictions_total", "Number of cache blocks evicted from the secondary cache.",
+		func(m *pebble.Metrics) float64 { return float64(m.SecondaryCacheMetrics.Evictions) }),
+	counter("secondary_cache_write_back_failures_total", "Number of failed writes of cache blocks to the secondary cache.",
+		func(m *pebble.Metrics) float64 { return float64(m.SecondaryCacheMetrics.WriteBackFailures) }),
+
+	// Block categories.
+	categoryMetric("category_block_bytes_total", "Bytes in the blocks loaded, by category.",
+		func(s *block.CategoryStats) float64 { return float64(s.BlockBytes) }),
+	categoryMetric("category_block_bytes_in_cache_total", "Bytes in the blocks loaded that were in the block cache, by category.",
+		func(s *block.CategoryStats) float64 { return float64(s.BlockBytesInCache) }),
+	categoryMetric("category_block_bytes_in_secondary_cache_total", "Bytes in the blocks loaded that missed the block cache but were in the secondary block cache, by category.",
+		func(s *block.CategoryStats) float64 { return float64(s.BlockBytesInSecondaryCache) }),
+	categoryMetric("category_block_read_seconds_total", "Cumulative duration of the block reads that missed the block cache, by category.",
+		func(s *block.CategoryStats) float64 { return s.BlockReadDuration.Seconds() }),
+
+	// Miscellaneous.
+	gauge("read_amplification", "Read amplification of the LSM.",
+		func(m *pebble.Metrics) float64 { return float64(m.ReadAmp()) }),
+	gauge("disk_space_usage_bytes", "Local disk space used by the DB, including obsolete files.",
+		func(m *pebble.Metrics) float64 { return float64(m.DiskSpaceUsage()) }),
+	gauge("uptime_seconds", "Time since the DB was opened.",
+		func(m *pebble.Metrics) float64 { return m.Uptime.Seconds() }),
+}
+
+var histogramDefs = []histogramDef{
+	{"wal_fsync_latency_seconds", "Latency of WAL fsyncs.",
+		func(m *pebble.Metrics) prometheus.Histogram { return m.LogWriter.FsyncLatency }},
+	{"wal_failover_write_and_sync_latency_seconds", "Latency of writing and syncing a set of WAL writes synced together, with WAL failover.",
+		func(m *pebble.Metrics) prometheus.Histogram { return m.WAL.Failover.FailoverWriteAndSyncLatency }},
+	{"secondary_cache_get_latency_seconds", "Latency of reads from the secondary cache.",
+		func(m *pebble.Metrics) prometheus.Histogram { return m.SecondaryCacheMetrics.GetLatency }},
+	{"secondary_cache_disk_read_latency_seconds", "Latency of reads of a cache block from the secondary cache's disk.",
+		func(m *pebble.Metrics) prometheus.Histogram { return m.SecondaryCacheMetrics.DiskReadLatency }},
+	{"secondary_cache_queue_put_latency_seconds", "Latency of queueing data to be written to the secondary cache.",
+		func(m *pebble.Metrics) prometheus.Histogram { return m.SecondaryCacheMetrics.QueuePutLatency }},
+	{"secondary_cache_put_latency_seconds", "Latency of writes of data read from remote storage to the secondary cache.",
+		func(m *pebble.Metrics) prometheus.Histogram { return m.SecondaryCacheMetrics.PutLatency }},
+	{"secondary_cache_disk_write_latency_seconds", "Latency of writes of a cache block to the secondary cache's disk.",
+		func(m *pebble.Metrics) prometheus.Histogram { return m.SecondaryCacheMetrics.DiskWriteLatency }},
+}
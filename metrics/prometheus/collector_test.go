@@ -0,0 +1,115 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package prometheus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	prometheusgo "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	d, err := pebble.Open("", &pebble.Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprint(i)), []byte("value"), pebble.Sync))
+		require.NoError(t, d.Flush())
+	}
+
+	r := prometheus.NewRegistry()
+	require.NoError(t, Register(r, d, prometheus.Labels{"store": "1"}))
+	// Registering a second collector for the same DB with the same labels
+	// fails, as the metrics would collide.
+	require.Error(t, Register(r, d, prometheus.Labels{"store": "1"}))
+
+	families, err := r.Gather()
+	require.NoError(t, err)
+	byName := make(map[string]*prometheusgo.MetricFamily)
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	labels := func(m *prometheusgo.Metric) map[string]string {
+		l := make(map[string]string)
+		for _, p := range m.GetLabel() {
+			l[p.GetName()] = p.GetValue()
+		}
+		return l
+	}
+
+	// Every metric has the constant labels.
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			require.Equal(t, "1", labels(m)["store"], f.GetName())
+		}
+	}
+
+	levelTables := byName["pebble_level_tables"]
+	require.NotNil(t, levelTables)
+	require.Equal(t, prometheusgo.MetricType_GAUGE, levelTables.GetType())
+	require.Len(t, levelTables.GetMetric(), 7)
+	for _, m := range levelTables.GetMetric() {
+		expected := 0.0
+		if labels(m)["level"] == "0" {
+			expected = 3
+		}
+		require.Equal(t, expected, m.GetGauge().GetValue())
+	}
+
+	flushes := byName["pebble_flushes_total"]
+	require.NotNil(t, flushes)
+	require.Equal(t, prometheusgo.MetricType_COUNTER, flushes.GetType())
+	require.Equal(t, 3.0, flushes.GetMetric()[0].GetCounter().GetValue())
+
+	kinds := byName["pebble_compactions_by_kind_total"]
+	require.NotNil(t, kinds)
+	var kindNames []string
+	for _, m := range kinds.GetMetric() {
+		kindNames = append(kindNames, labels(m)["kind"])
+	}
+	require.Contains(t, kindNames, "default")
+	require.Contains(t, kindNames, "delete-only")
+
+	// Every compression algorithm is labeled, and the flushed tables use the
+	// default snappy compression.
+	byCompression := byName["pebble_tables_by_compression"]
+	require.NotNil(t, byCompression)
+	compressionCounts := make(map[string]float64)
+	for _, m := range byCompression.GetMetric() {
+		compressionCounts[labels(m)["compression"]] = m.GetGauge().GetValue()
+	}
+	require.Equal(t, map[string]float64{
+		"unknown": 0,
+		"snappy":  3,
+		"zstd":    0,
+		"lz4":     0,
+		"minlz":   0,
+		"none":    0,
+	}, compressionCounts)
+
+	// The fsync latencies are exported in seconds.
+	fsync := byName["pebble_wal_fsync_latency_seconds"]
+	require.NotNil(t, fsync)
+	require.Equal(t, prometheusgo.MetricType_HISTOGRAM, fsync.GetType())
+	h := fsync.GetMetric()[0].GetHistogram()
+	require.NotZero(t, h.GetSampleCount())
+	buckets := h.GetBucket()
+	require.Equal(t, 0.0, buckets[0].GetUpperBound())
+	require.InDelta(t, 100e-6, buckets[1].GetUpperBound(), 1e-12)
+
+	// The secondary cache is not configured, so its histograms are not
+	// exported.
+	require.Nil(t, byName["pebble_secondary_cache_get_latency_seconds"])
+
+	problems, err := testutil.GatherAndLint(r)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+}
@@ -0,0 +1,431 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/sstable/blob"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// multiGetKeysPerWorker is the minimum number of keys looked up by each of the
+// goroutines of a MultiGet.
+const multiGetKeysPerWorker = 16
+
+// MultiGetResult is the result of looking up a key with MultiGet.
+type MultiGetResult struct {
+	// Value is the value of the key. The caller should not modify its
+	// contents. It remains valid until Closer is closed.
+	Value []byte
+	// Closer is non-nil if the key was found, in which case the caller MUST
+	// call Closer.Close() when it no longer needs Value, or a memory leak will
+	// occur. The results of a MultiGet may share a Closer, which must then be
+	// closed once for each result.
+	Closer io.Closer
+	// Err is ErrNotFound if the key was not found.
+	Err error
+}
+
+// MultiGet gets the values of the provided keys. The result at index i holds
+// the value of keys[i], or ErrNotFound if the DB does not contain it. It is
+// equivalent to calling Get for each key, but reads the keys at the same
+// sequence number and is more efficient.
+//
+// Before any key is looked up, the filter of each sstable that may contain
+// some of the keys, in L0 to L5, is read once and checked for all of them, so
+// that the sstables the filters exclude are skipped without reading their data
+// blocks. The keys are then looked up in sorted order, split among multiple
+// goroutines, which read blocks in parallel. The iterators a goroutine opens
+// over an sstable, and the file cache handle they hold, are reused by the
+// lookups of its following keys within the sstable. The returned stats
+// aggregate the stats of the lookups.
+//
+// It is safe to modify the contents of the keys after MultiGet returns. An
+// error is returned only if the keys could not be looked up, in which case no
+// results are returned.
+func (d *DB) MultiGet(keys [][]byte) ([]MultiGetResult, IteratorStats, error) {
+	return d.multiGetInternal(keys, nil /* batch */, nil /* snapshot */)
+}
+
+// MultiGet gets the values of the provided keys, like DB.MultiGet, from the
+// batch overlaid on top of the DB. Keys not contained in either have an
+// ErrNotFound result.
+func (b *Batch) MultiGet(keys [][]byte) ([]MultiGetResult, IteratorStats, error) {
+	if b.index == nil {
+		return nil, IteratorStats{}, ErrNotIndexed
+	}
+	return b.db.multiGetInternal(keys, b, nil /* snapshot */)
+}
+
+// MultiGet gets the values of the provided keys, like DB.MultiGet, from the
+// snapshot. Keys the snapshot does not contain have an ErrNotFound result.
+func (s *Snapshot) MultiGet(keys [][]byte) ([]MultiGetResult, IteratorStats, error) {
+	if s.db == nil {
+		panic(ErrClosed)
+	}
+	return s.db.multiGetInternal(keys, nil /* batch */, s)
+}
+
+func (d *DB) multiGetInternal(
+	keys [][]byte, b *Batch, s *Snapshot,
+) ([]MultiGetResult, IteratorStats, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	results := make([]MultiGetResult, len(keys))
+	if len(keys) == 0 {
+		return results, IteratorStats{}, nil
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return d.cmp(keys[a], keys[b])
+	})
+
+	sortedKeys := make([][]byte, len(keys))
+	for i, idx := range order {
+		sortedKeys[i] = keys[idx]
+	}
+
+	// Every goroutine reads the same read state, at the same sequence number.
+	readState := d.loadReadState()
+	defer readState.unref()
+	var seqNum base.SeqNum
+	if s != nil {
+		seqNum = s.seqNum
+	} else {
+		seqNum = d.mu.versions.visibleSeqNum.Load()
+	}
+
+	workers := max(min(runtime.GOMAXPROCS(0), len(keys)/multiGetKeysPerWorker), 1)
+	filters, err := d.multiGetFilterTables(readState.current, sortedKeys, workers)
+	if err != nil {
+		return nil, IteratorStats{}, err
+	}
+
+	// A batch is not safe for concurrent use, including by the iterators
+	// reading it.
+	if b != nil {
+		workers = 1
+	}
+	ws := make([]multiGetWorker, workers)
+	var wg sync.WaitGroup
+	for i := range ws {
+		w := &ws[i]
+		*w = multiGetWorker{d: d, filters: filters}
+		lo, hi := i*len(order)/workers, (i+1)*len(order)/workers
+		if i == workers-1 {
+			w.run(keys, order, lo, hi, results, b, readState, seqNum)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(keys, order, lo, hi, results, b, readState, seqNum)
+		}()
+	}
+	wg.Wait()
+
+	var stats IteratorStats
+	for i := range ws {
+		stats.Merge(ws[i].stats)
+		err = errors.CombineErrors(err, ws[i].err)
+	}
+	if err != nil {
+		for i := range results {
+			if results[i].Closer != nil {
+				_ = results[i].Closer.Close()
+			}
+		}
+		return nil, stats, err
+	}
+	return results, stats, nil
+}
+
+// multiGetWorker looks up a sorted subset of the keys of a MultiGet. The
+// sstable iterators it opens are reused by the lookups of the following keys
+// within the same tables, so that each table is looked up in the file cache
+// once per worker rather than once per key.
+type multiGetWorker struct {
+	d       *DB
+	filters map[*tableMetadata]*multiGetTableFilter
+	// pos is the index, among the sorted keys, of the key being looked up.
+	pos int
+	// tables holds the iterators over the last table read in each layer.
+	tables           map[manifest.Layer]*multiGetTableIters
+	newIters         tableNewIters
+	blobValueFetcher blob.ValueFetcher
+	stats            IteratorStats
+	err              error
+}
+
+// multiGetTableIters holds the iterators over a table shared by the lookups
+// of a multiGetWorker. The lookups are handed the wrapped iterators, whose
+// Close is a no-op; the worker closes iters once the table is no longer read.
+type multiGetTableIters struct {
+	file     *tableMetadata
+	iters    iterSet
+	point    multiGetPointIter
+	rangeDel multiGetRangeDelIter
+}
+
+type multiGetPointIter struct {
+	internalIterator
+}
+
+// Close implements base.InternalIterator.
+func (*multiGetPointIter) Close() error { return nil }
+
+type multiGetRangeDelIter struct {
+	keyspan.FragmentIterator
+}
+
+// Close implements keyspan.FragmentIterator.
+func (*multiGetRangeDelIter) Close() {}
+
+func (w *multiGetWorker) run(
+	keys [][]byte,
+	order []int,
+	lo, hi int,
+	results []MultiGetResult,
+	b *Batch,
+	readState *readState,
+	seqNum base.SeqNum,
+) {
+	w.tables = make(map[manifest.Layer]*multiGetTableIters)
+	w.newIters = w.newTableIters
+	w.blobValueFetcher.Init(w.d.fileCache, block.ReadEnv{})
+	vals := multiGetValuesPool.Get().(*multiGetValues)
+	// The values are appended to vals.buf, which may be reallocated, so the
+	// results are only pointed at the values once every value is appended.
+	type valueSpan struct {
+		idx        int
+		start, end int
+	}
+	var spans []valueSpan
+	for w.pos = lo; w.pos < hi; w.pos++ {
+		idx := order[w.pos]
+		readState.ref()
+		i := w.d.newGetIterator(keys[idx], b, readState, seqNum)
+		get := &i.getIterAlloc.get
+		get.newIters = w.newIters
+		get.blobValueFetcher = &w.blobValueFetcher
+		if i.First() {
+			v, err := i.ValueAndErr()
+			if err == nil {
+				start := len(vals.buf)
+				vals.buf = append(vals.buf, v...)
+				spans = append(spans, valueSpan{idx: idx, start: start, end: len(vals.buf)})
+			}
+			w.err = err
+		} else {
+			results[idx].Err = ErrNotFound
+		}
+		w.stats.Merge(i.Stats())
+		w.err = errors.CombineErrors(w.err, i.Close())
+		if w.err != nil {
+			break
+		}
+	}
+	for _, t := range w.tables {
+		w.err = errors.CombineErrors(w.err, t.iters.CloseAll())
+	}
+	w.err = errors.CombineErrors(w.err, w.blobValueFetcher.Close())
+	if len(spans) == 0 {
+		vals.release()
+		return
+	}
+	vals.refs.Store(int32(len(spans)))
+	for _, s := range spans {
+		results[s.idx] = MultiGetResult{
+			Value:  vals.buf[s.start:s.end:s.end],
+			Closer: vals,
+		}
+	}
+}
+
+// newTableIters is the tableNewIters of the lookups of the worker. It returns
+// no point iterator if the table's filter excludes the key being looked up,
+// and otherwise the shared iterators over the table.
+func (w *multiGetWorker) newTableIters(
+	ctx context.Context,
+	file *tableMetadata,
+	opts *IterOptions,
+	internalOpts internalIterOpts,
+	kinds iterKinds,
+) (iterSet, error) {
+	f := w.filters[file]
+	excluded := f != nil && w.pos >= f.start && w.pos < f.start+len(f.mayContain) &&
+		!f.mayContain[w.pos-f.start]
+	if excluded && !f.hasRangeDels {
+		return iterSet{}, nil
+	}
+	t := w.tables[opts.layer]
+	if t == nil || t.file != file {
+		if t == nil {
+			t = &multiGetTableIters{}
+			w.tables[opts.layer] = t
+		} else if err := t.iters.CloseAll(); err != nil {
+			return iterSet{}, err
+		}
+		iters, err := w.d.newIters(ctx, file, opts, internalOpts, iterPointKeys|iterRangeDeletions)
+		if err != nil {
+			t.file = nil
+			return iterSet{}, err
+		}
+		*t = multiGetTableIters{
+			file:     file,
+			iters:    iters,
+			point:    multiGetPointIter{iters.point},
+			rangeDel: multiGetRangeDelIter{iters.rangeDeletion},
+		}
+	}
+	var iters iterSet
+	if t.iters.point != nil && !excluded {
+		iters.point = &t.point
+	}
+	if t.iters.rangeDeletion != nil {
+		// The table's range deletions may delete the key in lower levels even if
+		// the table does not contain the key.
+		iters.rangeDeletion = &t.rangeDel
+	}
+	return iters, nil
+}
+
+// multiGetTableFilter holds the result of checking the filter of a table for
+// the keys of a MultiGet within the table's bounds.
+type multiGetTableFilter struct {
+	file *tableMetadata
+	// start is the index, among the sorted keys, of the first key within the
+	// table's bounds. mayContain[i] is false if the filter excludes the key at
+	// index start+i.
+	start        int
+	mayContain   []bool
+	hasRangeDels bool
+}
+
+// multiGetFilterTables checks the filters of the tables of the version that
+// the sorted keys of a MultiGet may be read from, before any of the keys is
+// looked up. The filter of each table is read once for all the keys within its
+// bounds, and the tables are split among the provided number of goroutines.
+//
+// L6 is skipped, as Get does not read the filters of L6 tables by default
+// (see IterOptions.UseL6Filters).
+func (d *DB) multiGetFilterTables(
+	vers *manifest.Version, sortedKeys [][]byte, workers int,
+) (map[*tableMetadata]*multiGetTableFilter, error) {
+	var filters []multiGetTableFilter
+	addTables := func(files manifest.LevelIterator) {
+		files = files.Filter(manifest.KeyTypePoint)
+		for i := 0; i < len(sortedKeys); {
+			f := files.SeekGE(d.cmp, sortedKeys[i])
+			if f == nil {
+				return
+			}
+			rest := sortedKeys[i:]
+			start := i + sort.Search(len(rest), func(j int) bool {
+				return d.cmp(rest[j], f.SmallestPointKey.UserKey) >= 0
+			})
+			end := i + sort.Search(len(rest), func(j int) bool {
+				return d.cmp(rest[j], f.LargestPointKey.UserKey) > 0
+			})
+			if start < end {
+				filters = append(filters, multiGetTableFilter{
+					file:       f,
+					start:      start,
+					mayContain: make([]bool, end-start),
+				})
+			}
+			// SeekGE positions files at a table whose largest key is at least
+			// sortedKeys[i], so end > i.
+			i = end
+		}
+	}
+	for _, sublevel := range vers.L0SublevelFiles {
+		addTables(sublevel.Iter())
+	}
+	for level := 1; level < numLevels-1; level++ {
+		addTables(vers.Levels[level].Iter())
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	prefixes := make([][]byte, len(sortedKeys))
+	for i, key := range sortedKeys {
+		prefixes[i] = key[:d.opts.Comparer.Split(key)]
+	}
+	workers = min(workers, len(filters))
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env := block.ReadEnv{Category: categoryGet}
+			for j := w; j < len(filters); j += workers {
+				f := &filters[j]
+				var err error
+				f.hasRangeDels, err = d.fileCache.mayContainPrefixes(context.Background(), env, f.file,
+					prefixes[f.start:f.start+len(f.mayContain)], f.mayContain)
+				if err != nil {
+					errs[w] = err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	m := make(map[*tableMetadata]*multiGetTableFilter, len(filters))
+	for j := range filters {
+		m[filters[j].file] = &filters[j]
+	}
+	return m, nil
+}
+
+// multiGetValues holds the values found by a multiGetWorker. It is closed once
+// for each value.
+type multiGetValues struct {
+	refs atomic.Int32
+	buf  []byte
+}
+
+var multiGetValuesPool = sync.Pool{
+	New: func() interface{} {
+		return &multiGetValues{}
+	},
+}
+
+// Close implements io.Closer.
+func (v *multiGetValues) Close() error {
+	switch n := v.refs.Add(-1); {
+	case n == 0:
+		v.release()
+	case n < 0:
+		panic(base.AssertionFailedf("pebble: MultiGet result closed too many times"))
+	}
+	return nil
+}
+
+func (v *multiGetValues) release() {
+	v.buf = v.buf[:0]
+	multiGetValuesPool.Put(v)
+}
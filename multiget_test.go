@@ -0,0 +1,111 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiGet(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem()}
+	opts.EnsureDefaults()
+	opts.Levels[0].FilterPolicy = bloom.FilterPolicy(10)
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	key := func(i int) []byte { return fmt.Appendf(nil, "key%04d", i) }
+	// Keys divisible by 3 are written to sstables in L6, and keys divisible by
+	// 11 to an sstable in L0, along with a range deletion. Keys divisible by 5
+	// are then overwritten or, if also divisible by 2, deleted.
+	for i := 0; i < 1000; i += 3 {
+		require.NoError(t, d.Set(key(i), []byte("table"), nil))
+	}
+	require.NoError(t, d.Compact(key(0), key(1000), false /* parallelize */))
+	require.NoError(t, d.DeleteRange(key(100), key(200), nil))
+	for i := 0; i < 1000; i += 11 {
+		require.NoError(t, d.Set(key(i), []byte("l0"), nil))
+	}
+	require.NoError(t, d.Flush())
+	for i := 0; i < 1000; i += 5 {
+		if i%2 == 0 {
+			require.NoError(t, d.Delete(key(i), nil))
+		} else {
+			require.NoError(t, d.Set(key(i), []byte("mem"), nil))
+		}
+	}
+	snap := d.NewSnapshot()
+	defer func() { require.NoError(t, snap.Close()) }()
+	b := d.NewIndexedBatch()
+	defer func() { require.NoError(t, b.Close()) }()
+	for i := 0; i < 1000; i += 7 {
+		require.NoError(t, b.Set(key(i), []byte("batch"), nil))
+		require.NoError(t, d.Set(key(i), []byte("later"), nil))
+	}
+
+	// The filter of the L0 sstable is checked for the keys within its bounds,
+	// and excludes some of them.
+	readState := d.loadReadState()
+	filters, err := d.multiGetFilterTables(readState.current, [][]byte{key(0), key(11), key(12)}, 1)
+	readState.unref()
+	require.NoError(t, err)
+	require.Len(t, filters, 1)
+	for _, f := range filters {
+		require.Equal(t, []bool{true, true, false}, f.mayContain)
+		require.True(t, f.hasRangeDels)
+	}
+
+	rng := rand.New(rand.NewPCG(0, 1))
+	// 100 keys are split among multiple goroutines if GOMAXPROCS allows it.
+	for _, n := range []int{1, 100, 500} {
+		keys := make([][]byte, n)
+		for i := range keys {
+			keys[i] = key(rng.IntN(1100))
+		}
+		testMultiGet(t, keys, d, snap, b)
+	}
+
+	results, _, err := d.MultiGet(nil)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func testMultiGet(t *testing.T, keys [][]byte, readers ...Reader) {
+	for _, r := range readers {
+		var results []MultiGetResult
+		var stats IteratorStats
+		var err error
+		switch r := r.(type) {
+		case *DB:
+			results, stats, err = r.MultiGet(keys)
+		case *Snapshot:
+			results, stats, err = r.MultiGet(keys)
+		case *Batch:
+			results, stats, err = r.MultiGet(keys)
+		}
+		require.NoError(t, err)
+		require.Equal(t, len(keys), stats.ForwardSeekCount[InterfaceCall])
+		for i, k := range keys {
+			v, closer, err := r.Get(k)
+			if err == ErrNotFound {
+				require.Equal(t, ErrNotFound, results[i].Err, "%T %s", r, k)
+				require.Nil(t, results[i].Closer)
+				continue
+			}
+			require.NoError(t, err)
+			require.NoError(t, results[i].Err)
+			require.Equal(t, string(v), string(results[i].Value), "%T %s", r, k)
+			require.NoError(t, closer.Close())
+			require.NoError(t, results[i].Closer.Close())
+		}
+	}
+
+}
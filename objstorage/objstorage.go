@@ -329,6 +329,12 @@ type Provider interface {
 	// crashes) until Sync is called.
 	AttachRemoteObjects(objs []RemoteObjectToAttach) ([]ObjectMetadata, error)
 
+	// ScanLocalObjects lists the local directory, registering the objects that
+	// were created in it by another writer and forgetting the local objects
+	// that were removed from it. It is used when following a DB that is
+	// written by another process.
+	ScanLocalObjects() error
+
 	Close() error
 
 	// IsNotExistError indicates whether the error is known to report that a file or
@@ -402,6 +408,13 @@ func IsLocalTable(provider Provider, fileNum base.DiskFileNum) bool {
 	return err == nil && !meta.IsRemote()
 }
 
+// IsLocalBlobFile returns true if a blob file with the given fileNum exists and
+// is local.
+func IsLocalBlobFile(provider Provider, fileNum base.DiskFileNum) bool {
+	meta, err := provider.Lookup(base.FileTypeBlob, fileNum)
+	return err == nil && !meta.IsRemote()
+}
+
 // IsExternalTable returns true if a table with the given fileNum exists and is
 // external.
 func IsExternalTable(provider Provider, fileNum base.DiskFileNum) bool {
@@ -0,0 +1,457 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package objstorageprovider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/tokenbucket"
+)
+
+// numIOCategories is the number of base.Category values.
+const numIOCategories = base.CategoryMax + 1
+
+// Default values of IOSchedulerOptions fields.
+const (
+	defaultIOSchedulerAdjustInterval = time.Second
+	defaultMinBackgroundBytesPerSec  = 1 << 20 // 1 MB/s
+)
+
+// ioSchedulerBurst is the duration of the I/O allowed by the burst of the
+// token buckets.
+const ioSchedulerBurst = 100 * time.Millisecond
+
+// IOSchedulerOptions configures an IOScheduler.
+type IOSchedulerOptions struct {
+	// BytesPerSec limits the rate at which the I/O performed for each
+	// block.Category reads and writes bytes. A category without a limit is not
+	// limited.
+	BytesPerSec map[base.Category]int64
+
+	// ForegroundReadLatencyTarget, if non-zero, is the target average latency
+	// of foreground reads, which are the reads of latency-sensitive categories
+	// (see block.QoSLevel). While the average latency over an AdjustInterval
+	// exceeds the target, the I/O of the other categories, called background
+	// I/O, is limited. The limit is halved every AdjustInterval, down to
+	// MinBackgroundBytesPerSec, and the compaction limiter returned by
+	// WrapCompactionLimiter does not grant new compaction slots. Once the
+	// latency falls below the target, the limit is doubled every
+	// AdjustInterval until it no longer limits background I/O.
+	ForegroundReadLatencyTarget time.Duration
+
+	// MinBackgroundBytesPerSec is the lower bound of the limit on background
+	// I/O while foreground reads exceed their latency target. The default is 1
+	// MB/s.
+	MinBackgroundBytesPerSec int64
+
+	// AdjustInterval is the interval over which the latency of foreground
+	// reads is measured. The default is 1 second.
+	AdjustInterval time.Duration
+}
+
+// IOSchedulerMetrics holds metrics of an IOScheduler.
+type IOSchedulerMetrics struct {
+	// Bytes is the number of bytes read and written for each block.Category.
+	Bytes [numIOCategories]int64
+	// WaitDuration is the total time the I/O performed for each block.Category
+	// waited on its limits.
+	WaitDuration [numIOCategories]time.Duration
+	// ForegroundReadLatency is the average latency of foreground reads over
+	// the last AdjustInterval.
+	ForegroundReadLatency time.Duration
+	// BackgroundBytesPerSec is the current limit on background I/O, or 0 if it
+	// is not limited.
+	BackgroundBytesPerSec int64
+	// Overloaded is true while the foreground reads exceed their latency
+	// target.
+	Overloaded bool
+	// DeniedCompactionSlots is the number of compaction slots that were not
+	// granted because the foreground reads exceeded their latency target.
+	DeniedCompactionSlots int64
+}
+
+// IOScheduler schedules the I/O of local objects, prioritizing foreground
+// reads over background I/O, like the I/O of compactions. The I/O is
+// classified by the block.Category of the context it is performed with (see
+// base.WithCategory):
+//   - writes have the category of the context passed to Provider.Create;
+//   - reads have the category of the context passed to ReadAt, which block
+//     reads set from their block.ReadEnv. Reads without a category are
+//     foreground reads.
+//
+// The I/O of each category may be limited by a token bucket (see
+// IOSchedulerOptions.BytesPerSec), and background I/O backs off while the
+// latency of foreground reads exceeds a target.
+//
+// Foreground reads of categories without a limit don't take any lock: they
+// only update atomic counters. The latency of foreground reads is aggregated
+// once per adjust interval, by the first I/O that observes the end of the
+// interval.
+//
+// An IOScheduler may be shared by multiple providers, which then share its
+// limits.
+type IOScheduler struct {
+	opts IOSchedulerOptions
+
+	// nowFn and sleepFn are replaced by tests.
+	nowFn   func() time.Time
+	sleepFn func(time.Duration)
+
+	categories [numIOCategories]ioSchedulerCategory
+
+	// foregroundReads and foregroundReadNanos are the number and total
+	// duration of the foreground reads of the current adjust interval.
+	foregroundReads     atomic.Int64
+	foregroundReadNanos atomic.Int64
+	// backgroundBytes is the number of bytes read and written by background
+	// I/O during the current adjust interval.
+	backgroundBytes atomic.Int64
+	// nextAdjust is the end of the current adjust interval, in nanoseconds
+	// since the Unix epoch.
+	nextAdjust atomic.Int64
+	// backoffRate mirrors mu.backoff.rate, so that background I/O only locks
+	// mu while it is limited.
+	backoffRate           atomic.Int64
+	overloaded            atomic.Bool
+	deniedCompactionSlots atomic.Int64
+
+	mu struct {
+		sync.Mutex
+		// backoff limits background I/O while foreground reads exceed their
+		// latency target.
+		backoff ioSchedulerBucket
+		// lastAdjust is the start of the current adjust interval.
+		lastAdjust            time.Time
+		foregroundReadLatency time.Duration
+	}
+}
+
+// ioSchedulerCategory holds the state of the I/O of a category.
+type ioSchedulerCategory struct {
+	bytes     atomic.Int64
+	waitNanos atomic.Int64
+	// limited is set if the category has a limit; it is immutable.
+	limited bool
+	mu      sync.Mutex
+	// bucket is protected by mu.
+	bucket ioSchedulerBucket
+}
+
+type ioSchedulerBucket struct {
+	// rate is the current limit, in bytes per second, or 0 if the I/O is not
+	// limited.
+	rate int64
+	tb   tokenbucket.TokenBucket
+}
+
+// NewIOScheduler creates an IOScheduler.
+func NewIOScheduler(opts IOSchedulerOptions) *IOScheduler {
+	return newIOScheduler(opts, time.Now, time.Sleep)
+}
+
+func newIOScheduler(
+	opts IOSchedulerOptions, nowFn func() time.Time, sleepFn func(time.Duration),
+) *IOScheduler {
+	s := &IOScheduler{
+		opts:    opts,
+		nowFn:   nowFn,
+		sleepFn: sleepFn,
+	}
+	if s.opts.AdjustInterval <= 0 {
+		s.opts.AdjustInterval = defaultIOSchedulerAdjustInterval
+	}
+	if s.opts.MinBackgroundBytesPerSec <= 0 {
+		s.opts.MinBackgroundBytesPerSec = defaultMinBackgroundBytesPerSec
+	}
+	now := nowFn()
+	s.mu.lastAdjust = now
+	s.nextAdjust.Store(now.Add(s.opts.AdjustInterval).UnixNano())
+	for c, rate := range s.opts.BytesPerSec {
+		if int(c) < numIOCategories && rate > 0 {
+			s.categories[c].limited = true
+			s.setRate(&s.categories[c].bucket, rate)
+		}
+	}
+	return s
+}
+
+// Metrics returns the metrics of the scheduler.
+func (s *IOScheduler) Metrics() IOSchedulerMetrics {
+	var m IOSchedulerMetrics
+	for c := range s.categories {
+		m.Bytes[c] = s.categories[c].bytes.Load()
+		m.WaitDuration[c] = time.Duration(s.categories[c].waitNanos.Load())
+	}
+	s.mu.Lock()
+	m.ForegroundReadLatency = s.mu.foregroundReadLatency
+	m.BackgroundBytesPerSec = s.mu.backoff.rate
+	s.mu.Unlock()
+	m.Overloaded = s.overloaded.Load()
+	m.DeniedCompactionSlots = s.deniedCompactionSlots.Load()
+	return m
+}
+
+// isOverloaded returns true while the foreground reads exceed their latency
+// target.
+func (s *IOScheduler) isOverloaded() bool {
+	s.maybeAdjust(s.nowFn())
+	return s.overloaded.Load()
+}
+
+// setRate sets the limit of the I/O of the provided token bucket. A rate of 0
+// removes the limit.
+//
+// The token bucket is reinitialized rather than reconfigured, as lowering the
+// burst of a token bucket puts it into debt. The burst allows for
+// ioSchedulerBurst of I/O.
+func (s *IOScheduler) setRate(b *ioSchedulerBucket, rate int64) {
+	b.rate = max(rate, 0)
+	if b.rate > 0 {
+		burst := max(float64(rate)*ioSchedulerBurst.Seconds(), 1)
+		b.tb.InitWithNowFn(tokenbucket.TokensPerSecond(rate), tokenbucket.Tokens(burst), s.nowFn)
+	}
+}
+
+// maybeAdjust measures the latency of the foreground reads and adjusts the
+// limit of background I/O once per adjust interval.
+func (s *IOScheduler) maybeAdjust(now time.Time) {
+	if now.UnixNano() < s.nextAdjust.Load() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elapsed := now.Sub(s.mu.lastAdjust)
+	if elapsed < s.opts.AdjustInterval {
+		// Another goroutine adjusted concurrently.
+		return
+	}
+	s.mu.lastAdjust = now
+	s.nextAdjust.Store(now.Add(s.opts.AdjustInterval).UnixNano())
+	var latency time.Duration
+	if reads, nanos := s.foregroundReads.Swap(0), s.foregroundReadNanos.Swap(0); reads > 0 {
+		latency = time.Duration(nanos / reads)
+	}
+	throughput := int64(float64(s.backgroundBytes.Swap(0)) / elapsed.Seconds())
+	s.mu.foregroundReadLatency = latency
+
+	target := s.opts.ForegroundReadLatencyTarget
+	overloaded := target > 0 && latency > target
+	s.overloaded.Store(overloaded)
+	rate := s.mu.backoff.rate
+	switch {
+	case overloaded:
+		if rate == 0 {
+			rate = throughput
+		}
+		rate = max(rate/2, s.opts.MinBackgroundBytesPerSec)
+	case rate == 0:
+		return
+	default:
+		rate *= 2
+		if throughput < rate/4 {
+			// Background I/O doesn't use the bandwidth it is allowed.
+			rate = 0
+		}
+	}
+	s.setRate(&s.mu.backoff, rate)
+	s.backoffRate.Store(s.mu.backoff.rate)
+}
+
+// waitBucket waits until the I/O of n bytes is allowed by the token bucket b,
+// which is protected by mu, and returns the time waited.
+func (s *IOScheduler) waitBucket(mu *sync.Mutex, b *ioSchedulerBucket, n int) time.Duration {
+	var waited time.Duration
+	for {
+		mu.Lock()
+		ok, d := true, time.Duration(0)
+		if b.rate > 0 {
+			ok, d = b.tb.TryToFulfill(tokenbucket.Tokens(n))
+		}
+		mu.Unlock()
+		if ok {
+			return waited
+		}
+		s.sleepFn(d)
+		waited += d
+	}
+}
+
+// wait waits until the I/O of n bytes for the provided category is allowed by
+// its limits, and accounts for it.
+func (s *IOScheduler) wait(c base.Category, n int) {
+	s.maybeAdjust(s.nowFn())
+	cs := &s.categories[c]
+	var waited time.Duration
+	if cs.limited {
+		waited += s.waitBucket(&cs.mu, &cs.bucket, n)
+	}
+	if c.QoSLevel() == base.NonLatencySensitiveQoSLevel {
+		if s.backoffRate.Load() > 0 {
+			waited += s.waitBucket(&s.mu.Mutex, &s.mu.backoff, n)
+		}
+		s.backgroundBytes.Add(int64(n))
+	}
+	cs.bytes.Add(int64(n))
+	if waited > 0 {
+		cs.waitNanos.Add(int64(waited))
+	}
+}
+
+// read performs a read for the provided category.
+func (s *IOScheduler) read(c base.Category, n int, readFn func() error) error {
+	if c.QoSLevel() == base.NonLatencySensitiveQoSLevel {
+		s.wait(c, n)
+		return readFn()
+	}
+	// Foreground reads only update atomic counters, unless their category is
+	// limited.
+	if s.categories[c].limited {
+		s.wait(c, n)
+	} else {
+		s.categories[c].bytes.Add(int64(n))
+	}
+	start := s.nowFn()
+	err := readFn()
+	end := s.nowFn()
+	s.foregroundReads.Add(1)
+	s.foregroundReadNanos.Add(int64(end.Sub(start)))
+	s.maybeAdjust(end)
+	return err
+}
+
+// WrapReadable wraps an objstorage.Readable with one whose reads are
+// scheduled.
+func (s *IOScheduler) WrapReadable(r objstorage.Readable) objstorage.Readable {
+	return &scheduledReadable{r: r, s: s}
+}
+
+// WrapWritable wraps an objstorage.Writable with one whose writes are
+// scheduled with the category of the provided context.
+func (s *IOScheduler) WrapWritable(ctx context.Context, w objstorage.Writable) objstorage.Writable {
+	return &scheduledWritable{w: w, s: s, category: base.CategoryFromContext(ctx)}
+}
+
+type scheduledReadable struct {
+	r objstorage.Readable
+	s *IOScheduler
+}
+
+var _ objstorage.Readable = (*scheduledReadable)(nil)
+
+// ReadAt is part of the objstorage.Readable interface.
+func (r *scheduledReadable) ReadAt(ctx context.Context, p []byte, off int64) error {
+	return r.s.read(base.CategoryFromContext(ctx), len(p), func() error {
+		return r.r.ReadAt(ctx, p, off)
+	})
+}
+
+// Close is part of the objstorage.Readable interface.
+func (r *scheduledReadable) Close() error {
+	return r.r.Close()
+}
+
+// Size is part of the objstorage.Readable interface.
+func (r *scheduledReadable) Size() int64 {
+	return r.r.Size()
+}
+
+// NewReadHandle is part of the objstorage.Readable interface.
+func (r *scheduledReadable) NewReadHandle(
+	readBeforeSize objstorage.ReadBeforeSize,
+) objstorage.ReadHandle {
+	return &scheduledReadHandle{rh: r.r.NewReadHandle(readBeforeSize), s: r.s}
+}
+
+type scheduledReadHandle struct {
+	rh objstorage.ReadHandle
+	s  *IOScheduler
+}
+
+var _ objstorage.ReadHandle = (*scheduledReadHandle)(nil)
+
+// ReadAt is part of the objstorage.ReadHandle interface.
+func (rh *scheduledReadHandle) ReadAt(ctx context.Context, p []byte, off int64) error {
+	return rh.s.read(base.CategoryFromContext(ctx), len(p), func() error {
+		return rh.rh.ReadAt(ctx, p, off)
+	})
+}
+
+// Close is part of the objstorage.ReadHandle interface.
+func (rh *scheduledReadHandle) Close() error {
+	return rh.rh.Close()
+}
+
+// SetupForCompaction is part of the objstorage.ReadHandle interface.
+func (rh *scheduledReadHandle) SetupForCompaction() {
+	rh.rh.SetupForCompaction()
+}
+
+// RecordCacheHit is part of the objstorage.ReadHandle interface.
+func (rh *scheduledReadHandle) RecordCacheHit(ctx context.Context, offset, size int64) {
+	rh.rh.RecordCacheHit(ctx, offset, size)
+}
+
+type scheduledWritable struct {
+	w        objstorage.Writable
+	s        *IOScheduler
+	category base.Category
+}
+
+var _ objstorage.Writable = (*scheduledWritable)(nil)
+
+// Write is part of the objstorage.Writable interface.
+func (w *scheduledWritable) Write(p []byte) error {
+	w.s.wait(w.category, len(p))
+	return w.w.Write(p)
+}
+
+// Finish is part of the objstorage.Writable interface.
+func (w *scheduledWritable) Finish() error {
+	return w.w.Finish()
+}
+
+// Abort is part of the objstorage.Writable interface.
+func (w *scheduledWritable) Abort() {
+	w.w.Abort()
+}
+
+// WrapCompactionLimiter wraps a compaction limiter with one that does not
+// grant compaction slots while the foreground reads exceed their latency
+// target (see IOSchedulerOptions.ForegroundReadLatencyTarget). A limiter that
+// was already wrapped by the scheduler is returned as is.
+func (s *IOScheduler) WrapCompactionLimiter(l base.CompactionLimiter) base.CompactionLimiter {
+	if sl, ok := l.(*scheduledCompactionLimiter); ok && sl.s == s {
+		return l
+	}
+	return &scheduledCompactionLimiter{l: l, s: s}
+}
+
+type scheduledCompactionLimiter struct {
+	l base.CompactionLimiter
+	s *IOScheduler
+}
+
+var _ base.CompactionLimiter = (*scheduledCompactionLimiter)(nil)
+
+// TookWithoutPermission is part of the base.CompactionLimiter interface.
+func (l *scheduledCompactionLimiter) TookWithoutPermission(
+	ctx context.Context,
+) base.CompactionSlot {
+	return l.l.TookWithoutPermission(ctx)
+}
+
+// RequestSlot is part of the base.CompactionLimiter interface.
+func (l *scheduledCompactionLimiter) RequestSlot(ctx context.Context) (base.CompactionSlot, error) {
+	if l.s.isOverloaded() {
+		l.s.deniedCompactionSlots.Add(1)
+		return nil, nil
+	}
+	return l.l.RequestSlot(ctx)
+}
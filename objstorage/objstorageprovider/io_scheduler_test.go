@@ -0,0 +1,179 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package objstorageprovider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testForegroundCategory = base.RegisterCategory("test-foreground", base.LatencySensitiveQoSLevel)
+	testBackgroundCategory = base.RegisterCategory("test-background", base.NonLatencySensitiveQoSLevel)
+)
+
+// testClock is a clock that is only advanced by sleeping and by slowReadable.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Sleep(d time.Duration) { c.now = c.now.Add(d) }
+
+// slowReadable is a Readable whose reads take latency.
+type slowReadable struct {
+	*objstorage.MemObj
+	clock   *testClock
+	latency time.Duration
+}
+
+func (r *slowReadable) ReadAt(ctx context.Context, p []byte, off int64) error {
+	r.clock.Sleep(r.latency)
+	return r.MemObj.ReadAt(ctx, p, off)
+}
+
+func TestIOSchedulerClassification(t *testing.T) {
+	ctx := context.Background()
+	st := DefaultSettings(vfs.NewMem(), "")
+	s := NewIOScheduler(IOSchedulerOptions{})
+	st.Local.IOScheduler = s
+	p, err := Open(st)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// Writes are classified by the category of the context passed to Create.
+	data := make([]byte, 1000)
+	for i, c := range []base.Category{testForegroundCategory, testBackgroundCategory} {
+		w, _, err := p.Create(base.WithCategory(ctx, c), base.FileTypeTable, base.DiskFileNum(i+1), objstorage.CreateOptions{})
+		require.NoError(t, err)
+		require.NoError(t, w.Write(data))
+		require.NoError(t, w.Finish())
+	}
+
+	// Reads are classified by the category of the context passed to ReadAt.
+	// Reads without a category are foreground reads.
+	r, err := p.OpenForReading(ctx, base.FileTypeTable, 1, objstorage.OpenOptions{})
+	require.NoError(t, err)
+	require.NoError(t, r.ReadAt(ctx, data[:10], 0))
+	rh := r.NewReadHandle(0)
+	require.NoError(t, rh.ReadAt(ctx, data[:100], 0))
+	require.NoError(t, rh.ReadAt(base.WithCategory(ctx, testBackgroundCategory), data[:200], 0))
+	require.NoError(t, rh.Close())
+	require.NoError(t, r.Close())
+
+	m := s.Metrics()
+	require.Equal(t, int64(110), m.Bytes[base.CategoryUnknown])
+	require.Equal(t, int64(1000), m.Bytes[testForegroundCategory])
+	require.Equal(t, int64(1200), m.Bytes[testBackgroundCategory])
+}
+
+func TestIOSchedulerLimits(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	s := newIOScheduler(IOSchedulerOptions{
+		BytesPerSec: map[base.Category]int64{
+			testForegroundCategory: 1000,
+		},
+	}, clock.Now, clock.Sleep)
+
+	// The burst allows for 100ms of I/O, after which the writes are limited to
+	// 1000 bytes per second.
+	w := s.WrapWritable(base.WithCategory(context.Background(), testForegroundCategory), &objstorage.MemObj{})
+	for i := 0; i < 50; i++ {
+		require.NoError(t, w.Write(make([]byte, 100)))
+	}
+	require.NoError(t, w.Finish())
+	require.Equal(t, 4900*time.Millisecond, clock.now.Sub(time.Unix(0, 0)))
+	m := s.Metrics()
+	require.Equal(t, int64(5000), m.Bytes[testForegroundCategory])
+	require.Equal(t, 4900*time.Millisecond, m.WaitDuration[testForegroundCategory])
+
+	// The I/O of other categories is not limited.
+	w = s.WrapWritable(base.WithCategory(context.Background(), testBackgroundCategory), &objstorage.MemObj{})
+	require.NoError(t, w.Write(make([]byte, 1<<20)))
+	require.NoError(t, w.Finish())
+	require.Equal(t, 4900*time.Millisecond, clock.now.Sub(time.Unix(0, 0)))
+}
+
+func TestIOSchedulerBackoff(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	s := newIOScheduler(IOSchedulerOptions{
+		ForegroundReadLatencyTarget: time.Millisecond,
+		MinBackgroundBytesPerSec:    1 << 20,
+		AdjustInterval:              time.Second,
+	}, clock.Now, clock.Sleep)
+	limiter := s.WrapCompactionLimiter(&base.DefaultCompactionLimiter{})
+	obj := &objstorage.MemObj{}
+	require.NoError(t, obj.Write(make([]byte, 100)))
+	r := &slowReadable{MemObj: obj, clock: clock}
+	foreground := s.WrapReadable(r)
+	ctx := base.WithCategory(context.Background(), testBackgroundCategory)
+	background := s.WrapWritable(ctx, &objstorage.MemObj{})
+
+	// run interleaves foreground reads with the provided latency and
+	// background writes for one adjust interval.
+	run := func(latency time.Duration) {
+		r.latency = latency
+		for start := clock.now; clock.now.Sub(start) < time.Second; {
+			require.NoError(t, foreground.ReadAt(context.Background(), make([]byte, 10), 0))
+			require.NoError(t, background.Write(make([]byte, 64<<10)))
+			clock.Sleep(time.Millisecond)
+		}
+	}
+
+	// Background I/O is not limited while the foreground reads meet their
+	// latency target.
+	run(100 * time.Microsecond)
+	run(100 * time.Microsecond)
+	slot, err := limiter.RequestSlot(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, slot)
+	slot.Release(0)
+	m := s.Metrics()
+	require.False(t, m.Overloaded)
+	require.Equal(t, 100*time.Microsecond, m.ForegroundReadLatency)
+	require.Equal(t, int64(0), m.BackgroundBytesPerSec)
+
+	// Once the foreground reads exceed their target, background I/O is limited
+	// to half of its throughput, and compaction slots are denied.
+	run(5 * time.Millisecond)
+	run(5 * time.Millisecond)
+	slot, err = limiter.RequestSlot(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, slot)
+	m = s.Metrics()
+	require.True(t, m.Overloaded)
+	require.Equal(t, 5*time.Millisecond, m.ForegroundReadLatency)
+	require.Equal(t, int64(1), m.DeniedCompactionSlots)
+	require.Greater(t, m.BackgroundBytesPerSec, int64(1<<20))
+	require.Less(t, m.BackgroundBytesPerSec, int64(10<<20))
+
+	// The limit is halved every interval, down to the minimum, and the
+	// background writes wait on the limit.
+	for i := 0; i < 5; i++ {
+		run(5 * time.Millisecond)
+	}
+	m = s.Metrics()
+	require.Equal(t, int64(1<<20), m.BackgroundBytesPerSec)
+	require.Greater(t, m.WaitDuration[testBackgroundCategory], time.Duration(0))
+
+	// Once the foreground reads meet their target, the limit is doubled every
+	// interval until it no longer limits background I/O.
+	for i := 0; i < 10 && s.Metrics().BackgroundBytesPerSec != 0; i++ {
+		run(0)
+	}
+	m = s.Metrics()
+	require.False(t, m.Overloaded)
+	require.Equal(t, int64(0), m.BackgroundBytesPerSec)
+	slot, err = limiter.RequestSlot(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, slot)
+}
@@ -12,6 +12,7 @@ import (
 	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/errors/oserror"
@@ -101,6 +102,9 @@ type Settings struct {
 		// ReadaheadConfig is used to retrieve the current readahead mode; it is
 		// consulted whenever a read handle is initialized.
 		ReadaheadConfig *ReadaheadConfig
+
+		// IOScheduler, if set, schedules the reads and writes of local objects.
+		IOScheduler *IOScheduler
 	}
 
 	// Fields here are set only if the provider is to support remote objects
@@ -135,6 +139,13 @@ type Settings struct {
 		// 2*runtime.GOMAXPROCS is used as the shard count.
 		CacheShardCount int
 
+		// CacheMetadataPersistInterval is the interval at which the placement of
+		// the blocks in the cache is persisted, allowing the cache contents to
+		// survive restarts. The placement is always persisted when the cache is
+		// closed. If it is 0, the default of 1 minute is used; if it is negative,
+		// the placement is only persisted when the cache is closed.
+		CacheMetadataPersistInterval time.Duration
+
 		// TODO(radu): allow the cache to live on another FS/location (e.g. to use
 		// instance-local SSD).
 	}
@@ -302,6 +313,9 @@ func (p *provider) OpenForReading(
 	var r objstorage.Readable
 	if !meta.IsRemote() {
 		r, err = p.vfsOpenForReading(ctx, fileType, fileNum, opts)
+		if err == nil && p.st.Local.IOScheduler != nil {
+			r = p.st.Local.IOScheduler.WrapReadable(r)
+		}
 	} else {
 		r, err = p.remoteOpenForReading(ctx, meta, opts)
 		if err != nil && p.isNotExistError(meta, err) {
@@ -339,6 +353,9 @@ func (p *provider) Create(
 			category = vfs.WriteCategoryUnspecified
 		}
 		w, meta, err = p.vfsCreate(ctx, fileType, fileNum, category)
+		if err == nil && p.st.Local.IOScheduler != nil {
+			w = p.st.Local.IOScheduler.WrapWritable(ctx, w)
+		}
 	}
 	if err != nil {
 		err = errors.Wrapf(err, "creating object %s", fileNum)
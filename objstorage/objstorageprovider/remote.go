@@ -11,6 +11,7 @@ import (
 	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
@@ -122,8 +123,17 @@ func (p *provider) remoteInit() error {
 			numShards = 2 * runtime.GOMAXPROCS(0)
 		}
 
+		const defaultPersistInterval = time.Minute
+		persistInterval := p.st.Remote.CacheMetadataPersistInterval
+		if persistInterval == 0 {
+			persistInterval = defaultPersistInterval
+		} else if persistInterval < 0 {
+			persistInterval = 0
+		}
+
 		p.remote.cache, err = sharedcache.Open(
-			p.st.FS, p.st.Logger, p.st.FSDirName, blockSize, shardingBlockSize, p.st.Remote.CacheSizeBytes, numShards)
+			p.st.FS, p.st.Logger, p.st.FSDirName, blockSize, shardingBlockSize, p.st.Remote.CacheSizeBytes, numShards,
+			persistInterval)
 		if err != nil {
 			return errors.Wrapf(err, "pebble: could not open remote object cache")
 		}
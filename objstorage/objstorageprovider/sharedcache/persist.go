@@ -0,0 +1,293 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sharedcache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/crc"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// The placement of the blocks of each shard (the where map) is persisted in a
+// metadata file next to the shard's data file, so that the cache contents
+// survive restarts. The metadata file has the following format (all integers
+// are little-endian):
+//
+//	magic             (8 bytes)
+//	version           (uint32)
+//	blockSize         (uint32)
+//	shardingBlockSize (uint64)
+//	numShards         (uint32)
+//	numEntries        (uint64)
+//	entries           (numEntries times)
+//	checksum          (uint32, of everything above)
+//
+// Each entry contains the file number, the logical block index and the value
+// key (as uvarints, see logicalBlockID), the cache block index (uvarint) and
+// the checksum of the cache block contents (uint32). Entries are ordered from
+// the least recently used to the most recently used block.
+//
+// The metadata can be stale: cache blocks can be overwritten after the
+// metadata was persisted, and data file writes are only synced when the
+// metadata is persisted. The checksum of a recovered block is verified when the
+// block is first read, and blocks that don't match are discarded (see
+// shard.verifyBlock).
+
+const (
+	persistMagic   = "\xf0pbsccm\x01"
+	persistVersion = 2
+
+	persistHeaderLen = len(persistMagic) + 4 + 4 + 8 + 4 + 8
+)
+
+func metaFilename(fs vfs.FS, fsDir string, shardIdx int) string {
+	return fs.PathJoin(fsDir, fmt.Sprintf("SHARED-CACHE-META-%03d", shardIdx))
+}
+
+// RemoveMetadata removes the persisted metadata of the cache stored in fsDir,
+// so that the cache is empty when it is next opened. The cache must not be
+// open.
+func RemoveMetadata(fs vfs.FS, fsDir string) error {
+	names, err := fs.List(fsDir)
+	if err != nil {
+		if oserror.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "SHARED-CACHE-META-") {
+			if err := fs.Remove(fs.PathJoin(fsDir, name)); err != nil && !oserror.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// persistedBlock is an entry of the persisted metadata of a shard.
+type persistedBlock struct {
+	logical  logicalBlockID
+	index    cacheBlockIndex
+	checksum uint32
+}
+
+// persistLoop persists the metadata of the cache every interval, until
+// stopPersisting is called.
+func (c *Cache) persistLoop(interval time.Duration) {
+	defer c.persister.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.persister.stopCh:
+			return
+		case <-ticker.C:
+			if err := c.persist(); err != nil {
+				c.logger.Errorf("persisting shared cache metadata failed: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Cache) stopPersisting() {
+	if c.persister.stopCh != nil {
+		close(c.persister.stopCh)
+		c.persister.wg.Wait()
+		c.persister.stopCh = nil
+	}
+}
+
+// persist writes out the metadata of all the shards.
+func (c *Cache) persist() error {
+	c.persister.mu.Lock()
+	defer c.persister.mu.Unlock()
+	var retErr error
+	for i := range c.shards {
+		if err := c.shards[i].persist(c.persister.fs, c.persister.fsDir, i, len(c.shards)); err != nil && retErr == nil {
+			retErr = err
+		}
+	}
+	return retErr
+}
+
+// persist syncs the data file of the shard and atomically replaces its
+// metadata file.
+func (s *shard) persist(fs vfs.FS, fsDir string, shardIdx, numShards int) error {
+	s.mu.Lock()
+	var blocks []persistedBlock
+	if s.mu.lruHead != invalidBlockIndex {
+		// Walk the LRU list from the back, so that the most recently used block is
+		// inserted last (and ends up at the front) on recovery.
+		for idx := s.lruPrev(s.mu.lruHead); ; idx = s.lruPrev(idx) {
+			// Blocks that are being written are skipped, since their contents (and
+			// checksums) are not final. Recovered blocks that are being verified
+			// hold the write lock too, but their contents are final.
+			if b := &s.mu.blocks[idx]; b.lock != writeLockTaken || b.unverified {
+				blocks = append(blocks, persistedBlock{
+					logical:  b.logical,
+					index:    idx,
+					checksum: b.checksum,
+				})
+			}
+			if idx == s.mu.lruHead {
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	buf := encodeShardMeta(s.bm.BlockSize(), s.shardingBlockSize, numShards, blocks)
+	filename := metaFilename(fs, fsDir, shardIdx)
+	tmpFilename := filename + ".tmp"
+	f, err := fs.Create(tmpFilename, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := fs.Rename(tmpFilename, filename); err != nil {
+		return err
+	}
+	dir, err := fs.OpenDir(fsDir)
+	if err != nil {
+		return err
+	}
+	return errors.CombineErrors(dir.Sync(), dir.Close())
+}
+
+func encodeShardMeta(
+	blockSize int, shardingBlockSize int64, numShards int, blocks []persistedBlock,
+) []byte {
+	buf := make([]byte, 0, persistHeaderLen+len(blocks)*(4*binary.MaxVarintLen64+4)+4)
+	buf = append(buf, persistMagic...)
+	buf = binary.LittleEndian.AppendUint32(buf, persistVersion)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(blockSize))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(shardingBlockSize))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(numShards))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(blocks)))
+	for _, b := range blocks {
+		buf = binary.AppendUvarint(buf, uint64(b.logical.filenum))
+		buf = binary.AppendUvarint(buf, uint64(b.logical.cacheBlockIdx))
+		buf = binary.AppendUvarint(buf, b.logical.valueKey)
+		buf = binary.AppendUvarint(buf, uint64(b.index))
+		buf = binary.LittleEndian.AppendUint32(buf, b.checksum)
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc.New(buf).Value())
+}
+
+var errCorruptMeta = errors.New("corrupt shared cache metadata")
+
+func decodeShardMeta(
+	buf []byte, blockSize int, shardingBlockSize int64, numShards int,
+) ([]persistedBlock, error) {
+	if len(buf) < persistHeaderLen+4 {
+		return nil, errCorruptMeta
+	}
+	data, checksum := buf[:len(buf)-4], binary.LittleEndian.Uint32(buf[len(buf)-4:])
+	if crc.New(data).Value() != checksum || string(data[:len(persistMagic)]) != persistMagic {
+		return nil, errCorruptMeta
+	}
+	data = data[len(persistMagic):]
+	if v := binary.LittleEndian.Uint32(data); v != persistVersion {
+		return nil, errors.Newf("unsupported shared cache metadata version %d", v)
+	}
+	if binary.LittleEndian.Uint32(data[4:]) != uint32(blockSize) ||
+		binary.LittleEndian.Uint64(data[8:]) != uint64(shardingBlockSize) ||
+		binary.LittleEndian.Uint32(data[16:]) != uint32(numShards) {
+		return nil, errors.New("shared cache configuration changed")
+	}
+	n := binary.LittleEndian.Uint64(data[20:])
+	data = data[28:]
+	var blocks []persistedBlock
+	for i := uint64(0); i < n; i++ {
+		var vals [4]uint64
+		for j := range vals {
+			v, l := binary.Uvarint(data)
+			if l <= 0 {
+				return nil, errCorruptMeta
+			}
+			vals[j], data = v, data[l:]
+		}
+		if len(data) < 4 {
+			return nil, errCorruptMeta
+		}
+		blocks = append(blocks, persistedBlock{
+			logical: logicalBlockID{
+				filenum:       base.DiskFileNum(vals[0]),
+				cacheBlockIdx: cacheBlockIndex(vals[1]),
+				valueKey:      vals[2],
+			},
+			index:    cacheBlockIndex(vals[3]),
+			checksum: binary.LittleEndian.Uint32(data),
+		})
+		data = data[4:]
+	}
+	if len(data) != 0 {
+		return nil, errCorruptMeta
+	}
+	return blocks, nil
+}
+
+// recover reads the persisted metadata of the shard and returns the blocks
+// that can be reused. A block is discarded if it is out of range or
+// duplicated. The contents of the blocks are not read here: they are verified
+// against the persisted checksum when they are first read.
+func (s *shard) recover(fs vfs.FS, fsDir string, shardIdx, numShards int) []persistedBlock {
+	filename := metaFilename(fs, fsDir, shardIdx)
+	f, err := fs.Open(filename)
+	if err != nil {
+		if !oserror.IsNotExist(err) {
+			s.cache.logger.Infof("ignoring shared cache metadata %s: %v", filename, err)
+		}
+		return nil
+	}
+	buf, err := io.ReadAll(f)
+	_ = f.Close()
+	var blocks []persistedBlock
+	if err == nil {
+		blocks, err = decodeShardMeta(buf, s.bm.BlockSize(), s.shardingBlockSize, numShards)
+	}
+	if err != nil {
+		s.cache.logger.Infof("ignoring shared cache metadata %s: %v", filename, err)
+		return nil
+	}
+
+	used := make(map[cacheBlockIndex]struct{}, len(blocks))
+	logical := make(map[logicalBlockID]struct{}, len(blocks))
+	recovered := blocks[:0]
+	for _, b := range blocks {
+		_, dupIndex := used[b.index]
+		_, dupLogical := logical[b.logical]
+		if b.index < 0 || int64(b.index) >= s.sizeInBlocks || dupIndex || dupLogical {
+			s.cache.metrics.discardedBlocks.Add(1)
+			continue
+		}
+		used[b.index] = struct{}{}
+		logical[b.logical] = struct{}{}
+		recovered = append(recovered, b)
+	}
+	return recovered
+}
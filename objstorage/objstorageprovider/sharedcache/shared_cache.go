@@ -15,6 +15,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/crc"
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/vfs"
@@ -40,6 +41,15 @@ type Cache struct {
 
 	logger  base.Logger
 	metrics internalMetrics
+
+	persister struct {
+		fs    vfs.FS
+		fsDir string
+		// mu serializes persisting the metadata.
+		mu     sync.Mutex
+		stopCh chan struct{}
+		wg     sync.WaitGroup
+	}
 }
 
 // Metrics is a struct containing metrics exported by the secondary cache.
@@ -70,6 +80,23 @@ type Metrics struct {
 	Evictions int64
 	// The number of times writing a cache block to the cache failed.
 	WriteBackFailures int64
+	// The number of values passed to TrySetValue that were dropped because the
+	// write queue was full.
+	WritesDropped int64
+
+	// The number of cache blocks recovered from the persisted metadata when the
+	// cache was opened.
+	RecoveredBlocks int64
+	// The number of recovered cache blocks that were discarded, e.g. because
+	// their contents didn't match the persisted checksum when they were first
+	// read.
+	DiscardedBlocks int64
+	// The number of cache block reads that hit in the cache.
+	BlockHits int64
+	// The number of cache block reads that hit a block recovered when the cache
+	// was opened. WarmBlockHits / BlockHits is the fraction of hits that are
+	// due to the cache surviving a restart.
+	WarmBlockHits int64
 
 	// The latency of calls to get some data from the cache.
 	GetLatency prometheus.Histogram
@@ -97,6 +124,12 @@ type internalMetrics struct {
 
 	evictions         atomic.Int64
 	writeBackFailures atomic.Int64
+	writesDropped     atomic.Int64
+
+	recoveredBlocks atomic.Int64
+	discardedBlocks atomic.Int64
+	blockHits       atomic.Int64
+	warmBlockHits   atomic.Int64
 
 	getLatency       prometheus.Histogram
 	diskReadLatency  prometheus.Histogram
@@ -115,7 +148,11 @@ const (
 )
 
 // Open opens a cache. If there is no existing cache at fsDir, a new one
-// is created.
+// is created. Otherwise, the blocks of the existing cache are recovered from
+// its persisted metadata.
+//
+// The metadata is persisted on Close and, if persistInterval is non-zero,
+// every persistInterval.
 func Open(
 	fs vfs.FS,
 	logger base.Logger,
@@ -129,6 +166,7 @@ func Open(
 	shardingBlockSize int64,
 	sizeBytes int64,
 	numShards int,
+	persistInterval time.Duration,
 ) (*Cache, error) {
 	if minSize := shardingBlockSize * int64(numShards); sizeBytes < minSize {
 		// Up the size so that we have one block per shard. In practice, this should
@@ -141,15 +179,22 @@ func Open(
 		bm:                makeBlockMath(blockSize),
 		shardingBlockSize: shardingBlockSize,
 	}
+	c.persister.fs = fs
+	c.persister.fsDir = fsDir
 	c.shards = make([]shard, numShards)
 	blocksPerShard := sizeBytes / int64(numShards) / int64(blockSize)
 	for i := range c.shards {
-		if err := c.shards[i].init(c, fs, fsDir, i, blocksPerShard, blockSize, shardingBlockSize); err != nil {
+		if err := c.shards[i].init(c, fs, fsDir, i, numShards, blocksPerShard, blockSize, shardingBlockSize); err != nil {
 			return nil, err
 		}
 	}
 
 	c.writeWorkers.Start(c, numShards*writeWorkersPerShard)
+	if persistInterval > 0 {
+		c.persister.stopCh = make(chan struct{})
+		c.persister.wg.Add(1)
+		go c.persistLoop(persistInterval)
+	}
 
 	c.metrics.getLatency = prometheus.NewHistogram(prometheus.HistogramOpts{Buckets: IOBuckets})
 	c.metrics.diskReadLatency = prometheus.NewHistogram(prometheus.HistogramOpts{Buckets: IOBuckets})
@@ -162,12 +207,13 @@ func Open(
 	return c, nil
 }
 
-// Close closes the cache. Methods such as ReadAt should not be called after Close is
-// called.
+// Close persists the metadata of the cache and closes the cache. Methods such
+// as ReadAt should not be called after Close is called.
 func (c *Cache) Close() error {
 	c.writeWorkers.Stop()
+	c.stopPersisting()
 
-	var retErr error
+	retErr := c.persist()
 	for i := range c.shards {
 		if err := c.shards[i].close(); err != nil && retErr == nil {
 			retErr = err
@@ -191,6 +237,11 @@ func (c *Cache) Metrics() Metrics {
 		ReadsWithNoHit:      c.metrics.readsWithNoHit.Load(),
 		Evictions:           c.metrics.evictions.Load(),
 		WriteBackFailures:   c.metrics.writeBackFailures.Load(),
+		WritesDropped:       c.metrics.writesDropped.Load(),
+		RecoveredBlocks:     c.metrics.recoveredBlocks.Load(),
+		DiscardedBlocks:     c.metrics.discardedBlocks.Load(),
+		BlockHits:           c.metrics.blockHits.Load(),
+		WarmBlockHits:       c.metrics.warmBlockHits.Load(),
 		GetLatency:          c.metrics.getLatency,
 		DiskReadLatency:     c.metrics.diskReadLatency,
 		QueuePutLatency:     c.metrics.queuePutLatency,
@@ -395,6 +446,16 @@ type shard struct {
 type cacheBlockState struct {
 	lock    lockState
 	logical logicalBlockID
+	// checksum is the checksum of the block contents; it is persisted along with
+	// the where map.
+	checksum uint32
+	// recovered is set if the block was recovered when the cache was opened,
+	// and has not been overwritten since.
+	recovered bool
+	// unverified is set if the block was recovered and its contents have not
+	// been checked against the persisted checksum yet. The first read of the
+	// block verifies it, see verifyBlock.
+	unverified bool
 
 	// next is the next block in the LRU or free list (or invalidBlockIndex if it
 	// is the last block in the free list).
@@ -412,6 +473,10 @@ type whereMap map[logicalBlockID]cacheBlockIndex
 type logicalBlockID struct {
 	filenum       base.DiskFileNum
 	cacheBlockIdx cacheBlockIndex
+	// valueKey is zero for the blocks cached by ReadAt. For the blocks of a
+	// value stored with TrySetValue, it is the offset of the value plus one, and
+	// cacheBlockIdx is the index of the block within the value.
+	valueKey uint64
 }
 
 type lockState int64
@@ -433,6 +498,7 @@ func (s *shard) init(
 	fs vfs.FS,
 	fsDir string,
 	shardIdx int,
+	numShards int,
 	sizeInBlocks int64,
 	blockSize int,
 	shardingBlockSize int64,
@@ -457,16 +523,26 @@ func (s *shard) init(
 	}
 	s.file = file
 
-	// TODO(josh): Right now, the secondary cache is not persistent. All existing
-	// cache contents will be over-written, since all metadata is only stored in
-	// memory.
+	recovered := s.recover(fs, fsDir, shardIdx, numShards)
 	s.mu.where = make(whereMap)
 	s.mu.blocks = make([]cacheBlockState, sizeInBlocks)
 	s.mu.lruHead = invalidBlockIndex
 	s.mu.freeHead = invalidBlockIndex
+	for _, b := range recovered {
+		s.mu.where[b.logical] = b.index
+		s.mu.blocks[b.index].logical = b.logical
+		s.mu.blocks[b.index].checksum = b.checksum
+		s.mu.blocks[b.index].recovered = true
+		s.mu.blocks[b.index].unverified = true
+		s.lruInsertFront(b.index)
+	}
 	for i := range s.mu.blocks {
-		s.freePush(cacheBlockIndex(i))
+		if !s.mu.blocks[i].recovered {
+			s.freePush(cacheBlockIndex(i))
+		}
 	}
+	cache.metrics.count.Add(int64(len(recovered)))
+	cache.metrics.recoveredBlocks.Add(int64(len(recovered)))
 
 	return nil
 }
@@ -580,7 +656,22 @@ func (s *shard) get(fileNum base.DiskFileNum, p []byte, ofs int64) (n int, _ err
 			s.mu.Unlock()
 			return n, nil
 		}
+		var verified []byte
+		if s.mu.blocks[cacheBlockIdx].unverified {
+			// Hold the write lock while the block is verified, so that it can't be
+			// evicted (or read) in the meantime.
+			s.mu.blocks[cacheBlockIdx].lock = writeLockTaken
+			s.mu.Unlock()
+			if verified = s.verifyBlock(cacheBlockIdx); verified == nil {
+				return n, nil
+			}
+			// verifyBlock returns with s.mu held.
+		}
 		s.mu.blocks[cacheBlockIdx].lock += readLockTakenInc
+		s.cache.metrics.blockHits.Add(1)
+		if s.mu.blocks[cacheBlockIdx].recovered {
+			s.cache.metrics.warmBlockHits.Add(1)
+		}
 		// Move to front of the LRU list.
 		s.lruUnlink(cacheBlockIdx)
 		s.lruInsertFront(cacheBlockIdx)
@@ -594,16 +685,22 @@ func (s *shard) get(fileNum base.DiskFileNum, p []byte, ofs int64) (n int, _ err
 			readSize -= int(rem)
 		}
 
-		if len(p[n:]) <= readSize {
+		read := func(dst []byte) (int, error) {
+			if verified != nil {
+				// The block was just read to verify it.
+				return copy(dst, verified[readAt-s.bm.BlockOffset(cacheBlockIdx):]), nil
+			}
 			start := time.Now()
-			numRead, err := s.file.ReadAt(p[n:], readAt)
+			numRead, err := s.file.ReadAt(dst, readAt)
 			s.cache.metrics.diskReadLatency.Observe(float64(time.Since(start)))
+			return numRead, err
+		}
+		if len(p[n:]) <= readSize {
+			numRead, err := read(p[n:])
 			s.dropReadLock(cacheBlockIdx)
 			return n + numRead, err
 		}
-		start := time.Now()
-		numRead, err := s.file.ReadAt(p[n:n+readSize], readAt)
-		s.cache.metrics.diskReadLatency.Observe(float64(time.Since(start)))
+		numRead, err := read(p[n : n+readSize])
 		s.dropReadLock(cacheBlockIdx)
 		if err != nil {
 			return 0, err
@@ -619,6 +716,40 @@ func (s *shard) get(fileNum base.DiskFileNum, p []byte, ofs int64) (n int, _ err
 	}
 }
 
+// verifyBlock reads a recovered block and checks its contents against the
+// persisted checksum. The caller must hold the write lock of the block (but not
+// s.mu).
+//
+// If the block is intact, verifyBlock releases the write lock and returns the
+// contents of the block with s.mu held. Otherwise, the block is freed and
+// verifyBlock returns nil.
+func (s *shard) verifyBlock(cacheBlockIdx cacheBlockIndex) []byte {
+	buf := make([]byte, s.bm.BlockSize())
+	start := time.Now()
+	_, err := s.file.ReadAt(buf, s.bm.BlockOffset(cacheBlockIdx))
+	s.cache.metrics.diskReadLatency.Observe(float64(time.Since(start)))
+
+	s.mu.Lock()
+	b := &s.mu.blocks[cacheBlockIdx]
+	if invariants.Enabled && b.lock != writeLockTaken {
+		panic(fmt.Sprintf("unexpected lock state %v in verifyBlock", b.lock))
+	}
+	if err == nil && crc.New(buf).Value() == b.checksum {
+		b.unverified = false
+		b.lock = unlocked
+		return buf
+	}
+	delete(s.mu.where, b.logical)
+	s.lruUnlink(cacheBlockIdx)
+	b.lock = unlocked
+	b.recovered, b.unverified = false, false
+	s.freePush(cacheBlockIdx)
+	s.cache.metrics.count.Add(-1)
+	s.cache.metrics.discardedBlocks.Add(1)
+	s.mu.Unlock()
+	return nil
+}
+
 // set attempts to write the requested data to the shard. The data must not
 // cross a shard boundary, and both ofs & len(p) must be multiples of the
 // block size.
@@ -649,76 +780,88 @@ func (s *shard) set(fileNum base.DiskFileNum, p []byte, ofs int64) error {
 			}
 		}
 
-		// If the logical block is already in the cache, we should skip doing a set.
 		k := logicalBlockID{
 			filenum:       fileNum,
 			cacheBlockIdx: s.bm.Block(ofs + int64(n)),
 		}
-		s.mu.Lock()
-		if _, ok := s.mu.where[k]; ok {
-			s.mu.Unlock()
-			n += s.bm.BlockSize()
-			continue
+		writeSize := s.bm.BlockSize()
+		if len(p[n:]) <= writeSize {
+			writeSize = len(p[n:])
 		}
-
-		var cacheBlockIdx cacheBlockIndex
-		if s.mu.freeHead == invalidBlockIndex {
-			if invariants.Enabled && s.mu.lruHead == invalidBlockIndex {
-				panic("both LRU and free lists empty")
-			}
-
-			// Find the last element in the LRU list which is not locked.
-			for idx := s.lruPrev(s.mu.lruHead); ; idx = s.lruPrev(idx) {
-				if lock := s.mu.blocks[idx].lock; lock == unlocked {
-					cacheBlockIdx = idx
-					break
-				}
-				if idx == s.mu.lruHead {
-					// No unlocked block to evict.
-					//
-					// TODO(josh): We may want to block until a block frees up, instead of returning
-					// an error here. But I think we can do that later on, e.g. after running some production
-					// experiments.
-					s.mu.Unlock()
-					return errors.New("no block to evict so skipping write to cache")
-				}
-			}
-			s.cache.metrics.evictions.Add(1)
-			s.lruUnlink(cacheBlockIdx)
-			delete(s.mu.where, s.mu.blocks[cacheBlockIdx].logical)
-		} else {
-			s.cache.metrics.count.Add(1)
-			cacheBlockIdx = s.freePop()
+		if err := s.setBlock(k, p[n:n+writeSize]); err != nil {
+			return err
 		}
+		n += writeSize
+	}
+}
 
-		s.lruInsertFront(cacheBlockIdx)
-		s.mu.where[k] = cacheBlockIdx
-		s.mu.blocks[cacheBlockIdx].logical = k
-		s.mu.blocks[cacheBlockIdx].lock = writeLockTaken
-		s.mu.Unlock()
+// setBlock writes the contents of the given logical block to the shard, unless
+// the logical block is already in the cache. p must not be larger than the
+// block size.
+func (s *shard) setBlock(k logicalBlockID, p []byte) error {
+	checksum := crc.New(p).Value()
 
-		writeAt := s.bm.BlockOffset(cacheBlockIdx)
+	s.mu.Lock()
+	if _, ok := s.mu.where[k]; ok {
+		s.mu.Unlock()
+		return nil
+	}
 
-		writeSize := s.bm.BlockSize()
-		if len(p[n:]) <= writeSize {
-			writeSize = len(p[n:])
+	var cacheBlockIdx cacheBlockIndex
+	if s.mu.freeHead == invalidBlockIndex {
+		if invariants.Enabled && s.mu.lruHead == invalidBlockIndex {
+			panic("both LRU and free lists empty")
 		}
 
-		start := time.Now()
-		_, err := s.file.WriteAt(p[n:n+writeSize], writeAt)
-		s.cache.metrics.diskWriteLatency.Observe(float64(time.Since(start)))
-		if err != nil {
-			// Free the block.
-			s.mu.Lock()
-			delete(s.mu.where, k)
-			s.lruUnlink(cacheBlockIdx)
-			s.freePush(cacheBlockIdx)
-			s.mu.Unlock()
-			return err
+		// Find the last element in the LRU list which is not locked.
+		for idx := s.lruPrev(s.mu.lruHead); ; idx = s.lruPrev(idx) {
+			if lock := s.mu.blocks[idx].lock; lock == unlocked {
+				cacheBlockIdx = idx
+				break
+			}
+			if idx == s.mu.lruHead {
+				// No unlocked block to evict.
+				//
+				// TODO(josh): We may want to block until a block frees up, instead of returning
+				// an error here. But I think we can do that later on, e.g. after running some production
+				// experiments.
+				s.mu.Unlock()
+				return errors.New("no block to evict so skipping write to cache")
+			}
 		}
-		s.dropWriteLock(cacheBlockIdx)
-		n += writeSize
+		s.cache.metrics.evictions.Add(1)
+		s.lruUnlink(cacheBlockIdx)
+		delete(s.mu.where, s.mu.blocks[cacheBlockIdx].logical)
+	} else {
+		s.cache.metrics.count.Add(1)
+		cacheBlockIdx = s.freePop()
 	}
+
+	s.lruInsertFront(cacheBlockIdx)
+	s.mu.where[k] = cacheBlockIdx
+	s.mu.blocks[cacheBlockIdx].logical = k
+	s.mu.blocks[cacheBlockIdx].checksum = checksum
+	s.mu.blocks[cacheBlockIdx].recovered = false
+	s.mu.blocks[cacheBlockIdx].unverified = false
+	s.mu.blocks[cacheBlockIdx].lock = writeLockTaken
+	s.mu.Unlock()
+
+	writeAt := s.bm.BlockOffset(cacheBlockIdx)
+
+	start := time.Now()
+	_, err := s.file.WriteAt(p, writeAt)
+	s.cache.metrics.diskWriteLatency.Observe(float64(time.Since(start)))
+	if err != nil {
+		// Free the block.
+		s.mu.Lock()
+		delete(s.mu.where, k)
+		s.lruUnlink(cacheBlockIdx)
+		s.freePush(cacheBlockIdx)
+		s.mu.Unlock()
+		return err
+	}
+	s.dropWriteLock(cacheBlockIdx)
+	return nil
 }
 
 // Doesn't inline currently. This might be okay, but something to keep in mind.
@@ -839,6 +982,9 @@ type writeTask struct {
 	fileNum base.DiskFileNum
 	p       []byte
 	offset  int64
+	// value is set if p is a value (including its header) passed to
+	// TrySetValue.
+	value bool
 }
 
 // Start starts the worker goroutines.
@@ -866,7 +1012,12 @@ func (w *writeWorkers) Start(c *Cache, numWorkers int) {
 					// TODO(radu): set() can perform multiple writes; perhaps each one
 					// should be its own task.
 					start := time.Now()
-					err := c.set(task.fileNum, task.p, task.offset)
+					var err error
+					if task.value {
+						err = c.setValue(task.fileNum, uint64(task.offset), task.p)
+					} else {
+						err = c.set(task.fileNum, task.p, task.offset)
+					}
 					c.metrics.putLatency.Observe(float64(time.Since(start)))
 					if err != nil {
 						c.metrics.writeBackFailures.Add(1)
@@ -897,3 +1048,13 @@ func (w *writeWorkers) QueueWrite(fileNum base.DiskFileNum, p []byte, offset int
 		offset:  offset,
 	}
 }
+
+// TryQueueWrite adds a write task to the queue, unless the queue is full.
+func (w *writeWorkers) TryQueueWrite(task writeTask) bool {
+	select {
+	case w.tasksCh <- task:
+		return true
+	default:
+		return false
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand/v2"
+	"slices"
 	"strconv"
 	"sync"
 	"testing"
@@ -54,7 +55,7 @@ func TestSharedCache(t *testing.T) {
 					)
 				}
 				cache, err = sharedcache.Open(
-					fs, base.DefaultLogger, "", blockSize, int64(shardingBlockSize), int64(size), numShards,
+					fs, base.DefaultLogger, "", blockSize, int64(shardingBlockSize), int64(size), numShards, 0,
 				)
 				require.NoError(t, err)
 				return fmt.Sprintf("initialized with block-size=%d size=%d num-shards=%d", blockSize, size, numShards)
@@ -143,7 +144,11 @@ func TestSharedCacheRandomized(t *testing.T) {
 					numShards := rng.IntN(maxShards) + 1
 					cacheSize := shardingBlockSize * int64(numShards) // minimum allowed cache size
 
-					cache, err := sharedcache.Open(fs, base.DefaultLogger, "", blockSize, shardingBlockSize, cacheSize, numShards)
+					// Each cache uses its own directory, since the object is rewritten
+					// with different contents below.
+					cacheDir := fmt.Sprintf("cache-%d", rng.Uint64())
+					require.NoError(t, fs.MkdirAll(cacheDir, 0755))
+					cache, err := sharedcache.Open(fs, base.DefaultLogger, cacheDir, blockSize, shardingBlockSize, cacheSize, numShards, 0)
 					require.NoError(t, err)
 					defer cache.Close()
 
@@ -213,6 +218,148 @@ func TestSharedCacheRandomized(t *testing.T) {
 	}
 }
 
+func TestSharedCachePersistence(t *testing.T) {
+	ctx := context.Background()
+	fs := vfs.NewMem()
+	provider, err := objstorageprovider.Open(objstorageprovider.DefaultSettings(fs, ""))
+	require.NoError(t, err)
+	defer provider.Close()
+
+	const blockSize = 32 * 1024
+	const shardingBlockSize = 1024 * 1024
+	const numShards = 4
+	const size = 512 * 1024
+	objData := make([]byte, size)
+	for i := range objData {
+		objData[i] = byte(i * 7)
+	}
+	writable, _, err := provider.Create(ctx, base.FileTypeTable, base.DiskFileNum(1), objstorage.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, writable.Write(slices.Clone(objData)))
+	require.NoError(t, writable.Finish())
+	readable, err := provider.OpenForReading(ctx, base.FileTypeTable, base.DiskFileNum(1), objstorage.OpenOptions{})
+	require.NoError(t, err)
+	defer readable.Close()
+
+	open := func(numShards int) *sharedcache.Cache {
+		cache, err := sharedcache.Open(
+			fs, base.DefaultLogger, "", blockSize, shardingBlockSize, int64(numShards*shardingBlockSize), numShards, 0,
+		)
+		require.NoError(t, err)
+		return cache
+	}
+	read := func(cache *sharedcache.Cache) {
+		got := make([]byte, size)
+		require.NoError(t, cache.ReadAt(ctx, base.DiskFileNum(1), got, 0, readable, readable.Size(), sharedcache.ReadFlags{}))
+		require.Equal(t, objData, got)
+		cache.WaitForWritesToComplete()
+	}
+
+	cache := open(numShards)
+	read(cache)
+	m := cache.Metrics()
+	require.Equal(t, int64(1), m.ReadsWithNoHit)
+	require.Equal(t, int64(size/blockSize), m.Count)
+	require.NoError(t, cache.Close())
+
+	// The cache contents survive a restart.
+	cache = open(numShards)
+	m = cache.Metrics()
+	require.Equal(t, int64(size/blockSize), m.RecoveredBlocks)
+	require.Equal(t, int64(size/blockSize), m.Count)
+	read(cache)
+	m = cache.Metrics()
+	require.Equal(t, int64(1), m.ReadsWithFullHit)
+	require.Equal(t, int64(size/blockSize), m.WarmBlockHits)
+	require.Equal(t, m.BlockHits, m.WarmBlockHits)
+	require.NoError(t, cache.Close())
+
+	// Blocks whose contents don't match their checksum are discarded. The last
+	// block of a shard is the first one to be used, so we corrupt the last block
+	// of each shard (only one of the shards contains the object's blocks).
+	for i := 0; i < numShards; i++ {
+		f, err := fs.OpenReadWrite(fmt.Sprintf("SHARED-CACHE-%03d", i), vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		_, err = f.WriteAt([]byte("corruption"), shardingBlockSize-blockSize)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	// The blocks are only verified when they are first read.
+	cache = open(numShards)
+	m = cache.Metrics()
+	require.Zero(t, m.DiscardedBlocks)
+	require.Equal(t, int64(size/blockSize), m.RecoveredBlocks)
+	read(cache)
+	m = cache.Metrics()
+	require.Equal(t, int64(1), m.DiscardedBlocks)
+	require.Equal(t, int64(1), m.ReadsWithPartialHit+m.ReadsWithNoHit)
+	require.Equal(t, int64(size/blockSize), m.Count)
+	require.NoError(t, cache.Close())
+
+	// The cache starts cold if its configuration changes.
+	cache = open(numShards * 2)
+	require.Zero(t, cache.Metrics().RecoveredBlocks)
+	read(cache)
+	require.NoError(t, cache.Close())
+}
+
+func TestSharedCacheValues(t *testing.T) {
+	fs := vfs.NewMem()
+	const blockSize = 4 * 1024
+	const shardingBlockSize = 64 * 1024
+	const numShards = 4
+	open := func() *sharedcache.Cache {
+		cache, err := sharedcache.Open(
+			fs, base.DefaultLogger, "", blockSize, shardingBlockSize, 4*numShards*shardingBlockSize, numShards, 0,
+		)
+		require.NoError(t, err)
+		return cache
+	}
+	value := func(offset, size int) []byte {
+		v := make([]byte, size)
+		for i := range v {
+			v[i] = byte(offset + i)
+		}
+		return v
+	}
+	// Values that fit in a block, that fill a block exactly (once the header is
+	// added) and that span multiple blocks.
+	sizes := map[int]int{0: 100, 1000: blockSize - 8, 10000: 3*blockSize + 17}
+
+	cache := open()
+	for offset, size := range sizes {
+		require.True(t, cache.TrySetValue(base.DiskFileNum(1), uint64(offset), value(offset, size)))
+	}
+	require.False(t, cache.TrySetValue(base.DiskFileNum(1), 1, make([]byte, 8<<20)))
+	cache.WaitForWritesToComplete()
+	for offset, size := range sizes {
+		v, ok := cache.GetValue(base.DiskFileNum(1), uint64(offset))
+		require.True(t, ok)
+		require.Equal(t, value(offset, size), v)
+		// Values don't alias the byte ranges cached by ReadAt, nor the values of
+		// other files.
+		_, ok = cache.GetValue(base.DiskFileNum(2), uint64(offset))
+		require.False(t, ok)
+	}
+	_, ok := cache.GetValue(base.DiskFileNum(1), 1)
+	require.False(t, ok)
+	m := cache.Metrics()
+	require.Equal(t, int64(len(sizes)), m.ReadsWithFullHit)
+	require.Equal(t, int64(len(sizes)+1), m.ReadsWithNoHit)
+	require.NoError(t, cache.Close())
+
+	// Values survive a restart.
+	cache = open()
+	require.Equal(t, int64(1+1+4), cache.Metrics().RecoveredBlocks)
+	for offset, size := range sizes {
+		v, ok := cache.GetValue(base.DiskFileNum(1), uint64(offset))
+		require.True(t, ok)
+		require.Equal(t, value(offset, size), v)
+	}
+	require.Equal(t, int64(1+1+4), cache.Metrics().WarmBlockHits)
+	require.NoError(t, cache.Close())
+}
+
 // parseBytesArg parses an optional argument that specifies a byte size; if the
 // argument is not specified the default value is used. K/M/G suffixes are
 // supported.
@@ -0,0 +1,158 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sharedcache
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/crc"
+)
+
+// In addition to caching byte ranges of objects (see ReadAt), the cache can
+// store values keyed by a file number and an offset (see TrySetValue and
+// GetValue). This is used to store blocks evicted from the in-memory block
+// cache, whose contents (e.g. decompressed blocks) don't correspond to a byte
+// range of the object.
+//
+// A value is stored in one or more cache blocks, which can live in different
+// shards and are evicted independently. The first block starts with a header
+// containing the length and the checksum of the value:
+//
+//	length   (uint32)
+//	checksum (uint32)
+//
+// A value is only returned if all its blocks are in the cache and its
+// checksum matches.
+
+const (
+	valueHeaderLen = 8
+	// maxValueSize is the maximum size of a value that can be stored in the
+	// cache.
+	maxValueSize = 4 << 20
+)
+
+// TrySetValue queues the write of a value to the cache. It copies v and does
+// not block: it returns false if the value is too large or the write queue is
+// full.
+func (c *Cache) TrySetValue(fileNum base.DiskFileNum, offset uint64, v []byte) bool {
+	if len(v) > maxValueSize {
+		return false
+	}
+	// The value is padded to a multiple of the block size, so that the block
+	// checksums verified when the cache is opened cover entire blocks.
+	buf := make([]byte, c.bm.RoundUp(int64(valueHeaderLen+len(v))))
+	binary.LittleEndian.PutUint32(buf, uint32(len(v)))
+	binary.LittleEndian.PutUint32(buf[4:], crc.New(v).Value())
+	copy(buf[valueHeaderLen:], v)
+	if !c.writeWorkers.TryQueueWrite(writeTask{fileNum: fileNum, p: buf, offset: int64(offset), value: true}) {
+		c.metrics.writesDropped.Add(1)
+		return false
+	}
+	return true
+}
+
+// GetValue returns the value stored with TrySetValue for the given file and
+// offset, if it is in the cache.
+func (c *Cache) GetValue(fileNum base.DiskFileNum, offset uint64) ([]byte, bool) {
+	c.metrics.totalReads.Add(1)
+	start := time.Now()
+	v, ok := c.getValue(fileNum, offset)
+	c.metrics.getLatency.Observe(float64(time.Since(start)))
+	if ok {
+		c.metrics.readsWithFullHit.Add(1)
+	} else {
+		c.metrics.readsWithNoHit.Add(1)
+	}
+	return v, ok
+}
+
+func (c *Cache) getValue(fileNum base.DiskFileNum, offset uint64) ([]byte, bool) {
+	blockSize := c.bm.BlockSize()
+	first := make([]byte, blockSize)
+	if !c.getValueShard(fileNum, offset, 0).getBlock(valueBlockID(fileNum, offset, 0), first) {
+		return nil, false
+	}
+	n := int(binary.LittleEndian.Uint32(first))
+	checksum := binary.LittleEndian.Uint32(first[4:])
+	if n > maxValueSize {
+		return nil, false
+	}
+	buf := first
+	if size := int(c.bm.RoundUp(int64(valueHeaderLen + n))); size > blockSize {
+		buf = make([]byte, size)
+		copy(buf, first)
+		for i := 1; i*blockSize < size; i++ {
+			part := cacheBlockIndex(i)
+			if !c.getValueShard(fileNum, offset, part).getBlock(valueBlockID(fileNum, offset, part), buf[i*blockSize:(i+1)*blockSize]) {
+				return nil, false
+			}
+		}
+	}
+	v := buf[valueHeaderLen : valueHeaderLen+n]
+	if crc.New(v).Value() != checksum {
+		return nil, false
+	}
+	return v, true
+}
+
+// setValue writes a value, including its header and padding, to the cache.
+func (c *Cache) setValue(fileNum base.DiskFileNum, offset uint64, buf []byte) error {
+	blockSize := c.bm.BlockSize()
+	for i := 0; i*blockSize < len(buf); i++ {
+		part := cacheBlockIndex(i)
+		if err := c.getValueShard(fileNum, offset, part).setBlock(valueBlockID(fileNum, offset, part), buf[i*blockSize:(i+1)*blockSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func valueBlockID(fileNum base.DiskFileNum, offset uint64, part cacheBlockIndex) logicalBlockID {
+	return logicalBlockID{
+		filenum:       fileNum,
+		cacheBlockIdx: part,
+		valueKey:      offset + 1,
+	}
+}
+
+func (c *Cache) getValueShard(
+	fileNum base.DiskFileNum, offset uint64, part cacheBlockIndex,
+) *shard {
+	const prime64 = 1099511628211
+	hash := (uint64(fileNum)*prime64^offset)*prime64 + uint64(part)
+	return &c.shards[hash%uint64(len(c.shards))]
+}
+
+// getBlock reads the contents of the given logical block into p, which must
+// have the size of a block. Returns false if the block is not in the cache.
+func (s *shard) getBlock(k logicalBlockID, p []byte) bool {
+	s.mu.Lock()
+	cacheBlockIdx, ok := s.mu.where[k]
+	if !ok || s.mu.blocks[cacheBlockIdx].lock == writeLockTaken {
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.blocks[cacheBlockIdx].lock += readLockTakenInc
+	s.cache.metrics.blockHits.Add(1)
+	if s.mu.blocks[cacheBlockIdx].recovered {
+		s.cache.metrics.warmBlockHits.Add(1)
+	}
+	// Move to front of the LRU list.
+	s.lruUnlink(cacheBlockIdx)
+	s.lruInsertFront(cacheBlockIdx)
+	s.mu.Unlock()
+
+	start := time.Now()
+	_, err := s.file.ReadAt(p, s.bm.BlockOffset(cacheBlockIdx))
+	s.cache.metrics.diskReadLatency.Observe(float64(time.Since(start)))
+	s.dropReadLock(cacheBlockIdx)
+	if err != nil {
+		s.cache.logger.Errorf("reading from cache failed: %v", err)
+		return false
+	}
+	return true
+}
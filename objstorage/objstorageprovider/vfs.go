@@ -88,6 +88,38 @@ func (p *provider) vfsInit() error {
 	return nil
 }
 
+// ScanLocalObjects is part of the objstorage.Provider interface.
+func (p *provider) ScanLocalObjects() error {
+	listing, err := p.st.FS.List(p.st.FSDirName)
+	if err != nil {
+		return errors.Wrapf(err, "pebble: could not list store directory")
+	}
+	found := make(map[base.DiskFileNum]base.FileType, len(listing))
+	for _, filename := range listing {
+		fileType, fileNum, ok := base.ParseFilename(p.st.FS, filename)
+		if ok && (fileType == base.FileTypeTable || fileType == base.FileTypeBlob) {
+			found[fileNum] = fileType
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for fileNum, meta := range p.mu.knownObjects {
+		if _, ok := found[fileNum]; !ok && !meta.IsRemote() {
+			delete(p.mu.knownObjects, fileNum)
+		}
+	}
+	for fileNum, fileType := range found {
+		if _, ok := p.mu.knownObjects[fileNum]; !ok {
+			p.mu.knownObjects[fileNum] = objstorage.ObjectMetadata{
+				FileType:    fileType,
+				DiskFileNum: fileNum,
+			}
+		}
+	}
+	return nil
+}
+
 func (p *provider) vfsSync() error {
 	p.mu.Lock()
 	counterVal := p.mu.localObjectsChangeCounter
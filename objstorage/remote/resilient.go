@@ -0,0 +1,549 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package remote
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
+)
+
+// ErrCircuitOpen is returned by a Storage created by WithResilience while its
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("remote storage unavailable: circuit breaker open")
+
+const (
+	defaultResilienceMaxRetries     = 3
+	defaultResilienceRetryBaseDelay = 10 * time.Millisecond
+	defaultResilienceRetryMaxDelay  = time.Second
+	defaultCircuitBreakerCooldown   = 5 * time.Second
+
+	// hedgeLatencySamples is the number of recent ReadAt latencies used to
+	// compute the hedging threshold. The threshold is recomputed every
+	// hedgeRecomputeInterval samples, once hedgeLatencySamples samples have
+	// been collected.
+	hedgeLatencySamples    = 256
+	hedgeRecomputeInterval = 32
+)
+
+// ResilienceOptions configures a Storage created by WithResilience.
+type ResilienceOptions struct {
+	// MaxRetries is the number of times a failed operation is retried. Errors
+	// for objects that don't exist are not retried. Defaults to 3; retries are
+	// disabled if it is negative.
+	MaxRetries int
+	// RetryBaseDelay and RetryMaxDelay bound the exponential backoff, with
+	// jitter, between retries. They default to 10ms and 1s.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// HedgePercentile enables hedged reads: once a ReadAt takes longer than
+	// this percentile (e.g. 0.95) of the latencies of recent reads, a second,
+	// identical read is issued and the first read to complete wins. Hedged
+	// reads are not issued while MaxConcurrency requests are in flight.
+	// Hedging is disabled if zero.
+	HedgePercentile float64
+	// HedgeMinDelay is the minimum latency after which a read is hedged.
+	HedgeMinDelay time.Duration
+
+	// MaxConcurrency is the maximum number of concurrent operations on the
+	// storage. Operations block once the limit is reached. Unlimited if zero.
+	MaxConcurrency int
+
+	// CircuitBreakerThreshold is the number of consecutive failed operations
+	// (after retries) that open the circuit breaker. While the circuit
+	// breaker is open, operations fail immediately with ErrCircuitOpen. After
+	// CircuitBreakerCooldown, a single operation is let through to probe the
+	// storage; the circuit breaker closes if it succeeds. The circuit breaker
+	// is disabled if zero.
+	CircuitBreakerThreshold int
+	// CircuitBreakerCooldown defaults to 5s.
+	CircuitBreakerCooldown time.Duration
+
+	// OnEvent, if set, is invoked synchronously for every ResilienceEvent.
+	OnEvent func(ResilienceEvent)
+}
+
+// ResilienceEventKind identifies the kind of a ResilienceEvent.
+type ResilienceEventKind int8
+
+const (
+	// ResilienceRetry is reported before a failed operation is retried.
+	ResilienceRetry ResilienceEventKind = iota
+	// ResilienceHedge is reported when a hedged read is issued.
+	ResilienceHedge
+	// ResilienceCircuitOpen is reported when the circuit breaker opens.
+	ResilienceCircuitOpen
+	// ResilienceCircuitClose is reported when the circuit breaker closes.
+	ResilienceCircuitClose
+)
+
+// String implements fmt.Stringer.
+func (k ResilienceEventKind) String() string {
+	switch k {
+	case ResilienceRetry:
+		return "retry"
+	case ResilienceHedge:
+		return "hedge"
+	case ResilienceCircuitOpen:
+		return "circuit open"
+	case ResilienceCircuitClose:
+		return "circuit close"
+	default:
+		return "unknown"
+	}
+}
+
+// ResilienceEvent describes a retry, a hedged read or a state change of the
+// circuit breaker of a Storage created by WithResilience.
+type ResilienceEvent struct {
+	Locator Locator
+	Kind    ResilienceEventKind
+	// Op is the operation, e.g. "read" or "list".
+	Op string
+	// Attempt is the number of the retry, starting at 1, for ResilienceRetry
+	// events.
+	Attempt int
+	// Delay is the backoff before a retry, or the latency after which a read
+	// was hedged.
+	Delay time.Duration
+	// Err is the error that caused a retry or opened the circuit breaker.
+	Err error
+}
+
+func (e ResilienceEvent) String() string {
+	return redact.StringWithoutMarkers(e)
+}
+
+// SafeFormat implements redact.SafeFormatter.
+func (e ResilienceEvent) SafeFormat(w redact.SafePrinter, _ rune) {
+	switch e.Kind {
+	case ResilienceRetry:
+		w.Printf("remote storage %s: retrying %s (attempt %d) in %s: %v",
+			e.Locator, redact.SafeString(e.Op), redact.Safe(e.Attempt), redact.Safe(e.Delay), e.Err)
+	case ResilienceHedge:
+		w.Printf("remote storage %s: hedging %s after %s",
+			e.Locator, redact.SafeString(e.Op), redact.Safe(e.Delay))
+	case ResilienceCircuitOpen:
+		w.Printf("remote storage %s: circuit breaker opened after %s failure: %v",
+			e.Locator, redact.SafeString(e.Op), e.Err)
+	case ResilienceCircuitClose:
+		w.Printf("remote storage %s: circuit breaker closed", e.Locator)
+	default:
+		w.Printf("remote storage %s: unknown event", e.Locator)
+	}
+}
+
+// WithResilience wraps the given Storage implementation, adding bounded
+// retries, hedged reads, a concurrency limit and a circuit breaker (see
+// ResilienceOptions). The locator is only used to identify the storage in
+// events.
+//
+// Writes to objects created by CreateObject are not retried, as the written
+// data is not buffered by the wrapper.
+func WithResilience(wrapped Storage, locator Locator, opts ResilienceOptions) Storage {
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultResilienceMaxRetries
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = defaultResilienceRetryBaseDelay
+	}
+	if opts.RetryMaxDelay <= 0 {
+		opts.RetryMaxDelay = defaultResilienceRetryMaxDelay
+	}
+	if opts.CircuitBreakerCooldown <= 0 {
+		opts.CircuitBreakerCooldown = defaultCircuitBreakerCooldown
+	}
+	s := &resilientStore{
+		wrapped: wrapped,
+		locator: locator,
+		opts:    opts,
+	}
+	if opts.MaxConcurrency > 0 {
+		s.sem = make(chan struct{}, opts.MaxConcurrency)
+	}
+	return s
+}
+
+// MakeResilientFactory returns a StorageFactory that wraps the Storage
+// implementations produced by the given factory with WithResilience. Each
+// locator has its own concurrency limit and circuit breaker.
+func MakeResilientFactory(factory StorageFactory, opts ResilienceOptions) StorageFactory {
+	return &resilientFactory{wrapped: factory, opts: opts}
+}
+
+type resilientFactory struct {
+	wrapped StorageFactory
+	opts    ResilienceOptions
+}
+
+var _ StorageFactory = (*resilientFactory)(nil)
+
+// CreateStorage is part of the StorageFactory interface.
+func (f *resilientFactory) CreateStorage(locator Locator) (Storage, error) {
+	s, err := f.wrapped.CreateStorage(locator)
+	if err != nil {
+		return nil, err
+	}
+	return WithResilience(s, locator, f.opts), nil
+}
+
+// resilientStore wraps a remote.Storage implementation, retrying failed
+// operations, hedging slow reads, limiting the concurrency of operations and
+// failing fast while the wrapped storage is unavailable.
+type resilientStore struct {
+	wrapped Storage
+	locator Locator
+	opts    ResilienceOptions
+	// sem limits the number of concurrent operations. It is nil if the
+	// concurrency is unlimited.
+	sem chan struct{}
+
+	breaker struct {
+		sync.Mutex
+		open bool
+		// probing is set while an operation probes the storage after the
+		// circuit breaker was open for the cooldown.
+		probing  bool
+		failures int
+		openedAt time.Time
+	}
+
+	latencies struct {
+		sync.Mutex
+		samples [hedgeLatencySamples]time.Duration
+		n       int
+	}
+	// hedgeDelay is the latency after which a read is hedged, or zero if not
+	// enough latencies have been sampled.
+	hedgeDelay atomic.Int64
+}
+
+var _ Storage = (*resilientStore)(nil)
+
+func (s *resilientStore) event(e ResilienceEvent) {
+	if s.opts.OnEvent != nil {
+		e.Locator = s.locator
+		s.opts.OnEvent(e)
+	}
+}
+
+// do runs fn, retrying it with exponential backoff if it fails, and records
+// the outcome in the circuit breaker.
+func (s *resilientStore) do(ctx context.Context, op string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		probe, err := s.allow()
+		if err != nil {
+			return err
+		}
+		if err = s.acquire(ctx); err != nil {
+			s.abortProbe(probe)
+			return err
+		}
+		err = fn()
+		s.release()
+		if err != nil && ctx.Err() != nil {
+			s.abortProbe(probe)
+			return err
+		}
+		if err == nil || s.wrapped.IsNotExistError(err) {
+			// Errors for objects that don't exist don't indicate a problem with
+			// the storage.
+			s.record(op, probe, nil)
+			return err
+		}
+		s.record(op, probe, err)
+		if attempt >= s.opts.MaxRetries {
+			return err
+		}
+		delay := s.opts.RetryMaxDelay
+		if attempt < 30 {
+			delay = min(delay, s.opts.RetryBaseDelay<<attempt)
+		}
+		delay = time.Duration(rand.Int63n(int64(delay)) + 1)
+		s.event(ResilienceEvent{Kind: ResilienceRetry, Op: op, Attempt: attempt + 1, Delay: delay, Err: err})
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.CombineErrors(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// allow returns ErrCircuitOpen if the circuit breaker is open. It returns
+// probe=true if the operation is the probe of an open circuit breaker.
+func (s *resilientStore) allow() (probe bool, _ error) {
+	if s.opts.CircuitBreakerThreshold <= 0 {
+		return false, nil
+	}
+	s.breaker.Lock()
+	defer s.breaker.Unlock()
+	if !s.breaker.open {
+		return false, nil
+	}
+	if s.breaker.probing || time.Since(s.breaker.openedAt) < s.opts.CircuitBreakerCooldown {
+		return false, ErrCircuitOpen
+	}
+	s.breaker.probing = true
+	return true, nil
+}
+
+// record records the outcome of an operation in the circuit breaker.
+func (s *resilientStore) record(op string, probe bool, err error) {
+	if s.opts.CircuitBreakerThreshold <= 0 {
+		return
+	}
+	var event *ResilienceEvent
+	func() {
+		s.breaker.Lock()
+		defer s.breaker.Unlock()
+		if probe {
+			s.breaker.probing = false
+		}
+		if err == nil {
+			s.breaker.failures = 0
+			if s.breaker.open && probe {
+				s.breaker.open = false
+				event = &ResilienceEvent{Kind: ResilienceCircuitClose, Op: op}
+			}
+			return
+		}
+		s.breaker.failures++
+		if probe || (!s.breaker.open && s.breaker.failures >= s.opts.CircuitBreakerThreshold) {
+			s.breaker.openedAt = time.Now()
+			if !s.breaker.open {
+				s.breaker.open = true
+				event = &ResilienceEvent{Kind: ResilienceCircuitOpen, Op: op, Err: err}
+			}
+		}
+	}()
+	if event != nil {
+		s.event(*event)
+	}
+}
+
+// abortProbe is called if a probe of the circuit breaker was interrupted
+// before it could determine whether the storage is available.
+func (s *resilientStore) abortProbe(probe bool) {
+	if probe {
+		s.breaker.Lock()
+		defer s.breaker.Unlock()
+		s.breaker.probing = false
+	}
+}
+
+func (s *resilientStore) acquire(ctx context.Context) error {
+	if s.sem == nil {
+		return nil
+	}
+	select {
+	case s.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *resilientStore) tryAcquire() bool {
+	if s.sem == nil {
+		return true
+	}
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *resilientStore) release() {
+	if s.sem != nil {
+		<-s.sem
+	}
+}
+
+// recordLatency records the latency of a successful read, periodically
+// recomputing the hedging threshold.
+func (s *resilientStore) recordLatency(latency time.Duration) {
+	if s.opts.HedgePercentile <= 0 {
+		return
+	}
+	s.latencies.Lock()
+	defer s.latencies.Unlock()
+	s.latencies.samples[s.latencies.n%hedgeLatencySamples] = latency
+	s.latencies.n++
+	if s.latencies.n < hedgeLatencySamples || s.latencies.n%hedgeRecomputeInterval != 0 {
+		return
+	}
+	samples := s.latencies.samples
+	slices.Sort(samples[:])
+	i := min(int(s.opts.HedgePercentile*hedgeLatencySamples), hedgeLatencySamples-1)
+	s.hedgeDelay.Store(int64(max(samples[i], s.opts.HedgeMinDelay)))
+}
+
+// Close is part of the remote.Storage interface.
+func (s *resilientStore) Close() error {
+	return s.wrapped.Close()
+}
+
+// ReadObject is part of the remote.Storage interface.
+func (s *resilientStore) ReadObject(
+	ctx context.Context, objName string,
+) (_ ObjectReader, objSize int64, _ error) {
+	var r ObjectReader
+	err := s.do(ctx, "read-object", func() error {
+		var err error
+		r, objSize, err = s.wrapped.ReadObject(ctx, objName)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return &resilientReader{s: s, wrapped: r}, objSize, nil
+}
+
+type resilientReader struct {
+	s       *resilientStore
+	wrapped ObjectReader
+}
+
+var _ ObjectReader = (*resilientReader)(nil)
+
+// ReadAt is part of the remote.ObjectReader interface.
+func (r *resilientReader) ReadAt(ctx context.Context, p []byte, offset int64) error {
+	return r.s.do(ctx, "read", func() error {
+		start := time.Now()
+		var err error
+		if hedgeDelay := time.Duration(r.s.hedgeDelay.Load()); hedgeDelay > 0 {
+			err = r.hedgedReadAt(ctx, p, offset, hedgeDelay)
+		} else {
+			err = r.wrapped.ReadAt(ctx, p, offset)
+		}
+		if err == nil {
+			r.s.recordLatency(time.Since(start))
+		}
+		return err
+	})
+}
+
+// hedgedReadAt reads into p, issuing a second read into its own buffer if the
+// first read doesn't complete within hedgeDelay. Whichever read completes first
+// cancels the other one. If the hedge wins, the first read is waited for before
+// the hedge's buffer is copied into p, so the wrapped ReadAt must return
+// promptly once its context is canceled.
+func (r *resilientReader) hedgedReadAt(
+	ctx context.Context, p []byte, offset int64, hedgeDelay time.Duration,
+) error {
+	readCtx, cancelRead := context.WithCancel(ctx)
+	defer cancelRead()
+	var hedge struct {
+		sync.Mutex
+		// stopped is set once the first read completes; no hedge is issued
+		// after that.
+		stopped bool
+		// cancel and done are set once the hedge is issued.
+		cancel context.CancelFunc
+		buf    []byte
+		done   chan error
+	}
+	timer := time.AfterFunc(hedgeDelay, func() {
+		hedge.Lock()
+		defer hedge.Unlock()
+		if hedge.stopped || !r.s.tryAcquire() {
+			return
+		}
+		r.s.event(ResilienceEvent{Kind: ResilienceHedge, Op: "read", Delay: hedgeDelay})
+		var hedgeCtx context.Context
+		hedgeCtx, hedge.cancel = context.WithCancel(ctx)
+		hedge.buf = make([]byte, len(p))
+		hedge.done = make(chan error, 1)
+		go func(buf []byte, done chan<- error) {
+			err := r.wrapped.ReadAt(hedgeCtx, buf, offset)
+			r.s.release()
+			if err == nil {
+				cancelRead()
+			}
+			done <- err
+		}(hedge.buf, hedge.done)
+	})
+	err := r.wrapped.ReadAt(readCtx, p, offset)
+	timer.Stop()
+	hedge.Lock()
+	hedge.stopped = true
+	hedge.Unlock()
+	if hedge.done == nil {
+		return err
+	}
+	defer hedge.cancel()
+	if err == nil {
+		// The hedge keeps reading into its own buffer until it notices the
+		// cancellation.
+		return nil
+	}
+	if hedgeErr := <-hedge.done; hedgeErr != nil {
+		return err
+	}
+	copy(p, hedge.buf)
+	return nil
+}
+
+// Close is part of the remote.ObjectReader interface.
+func (r *resilientReader) Close() error {
+	return r.wrapped.Close()
+}
+
+// CreateObject is part of the remote.Storage interface.
+func (s *resilientStore) CreateObject(objName string) (io.WriteCloser, error) {
+	var w io.WriteCloser
+	err := s.do(context.Background(), "create-object", func() error {
+		var err error
+		w, err = s.wrapped.CreateObject(objName)
+		return err
+	})
+	return w, err
+}
+
+// List is part of the remote.Storage interface.
+func (s *resilientStore) List(prefix, delimiter string) ([]string, error) {
+	var res []string
+	err := s.do(context.Background(), "list", func() error {
+		var err error
+		res, err = s.wrapped.List(prefix, delimiter)
+		return err
+	})
+	return res, err
+}
+
+// Delete is part of the remote.Storage interface.
+func (s *resilientStore) Delete(objName string) error {
+	return s.do(context.Background(), "delete", func() error {
+		return s.wrapped.Delete(objName)
+	})
+}
+
+// Size is part of the remote.Storage interface.
+func (s *resilientStore) Size(objName string) (int64, error) {
+	var size int64
+	err := s.do(context.Background(), "size", func() error {
+		var err error
+		size, err = s.wrapped.Size(objName)
+		return err
+	})
+	return size, err
+}
+
+// IsNotExistError is part of the remote.Storage interface.
+func (s *resilientStore) IsNotExistError(err error) bool {
+	return s.wrapped.IsNotExistError(err)
+}
@@ -0,0 +1,260 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package remote
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var errFlaky = errors.New("flaky storage error")
+
+// flakyStorage wraps an in-memory Storage, failing the next operations and
+// delaying reads on demand.
+type flakyStorage struct {
+	Storage
+	// failures is the number of upcoming operations that fail.
+	failures atomic.Int32
+	// slowReads is the number of upcoming reads that block until their context
+	// is canceled.
+	slowReads atomic.Int32
+	// delay is the duration of every operation.
+	delay time.Duration
+
+	calls       atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (f *flakyStorage) op() error {
+	f.calls.Add(1)
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for m := f.maxInFlight.Load(); n > m && !f.maxInFlight.CompareAndSwap(m, n); m = f.maxInFlight.Load() {
+	}
+	time.Sleep(f.delay)
+	if f.failures.Add(-1) >= 0 {
+		return errFlaky
+	}
+	f.failures.Store(0)
+	return nil
+}
+
+func (f *flakyStorage) ReadObject(
+	ctx context.Context, objName string,
+) (_ ObjectReader, objSize int64, _ error) {
+	if err := f.op(); err != nil {
+		return nil, 0, err
+	}
+	r, size, err := f.Storage.ReadObject(ctx, objName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &flakyReader{f: f, wrapped: r}, size, nil
+}
+
+func (f *flakyStorage) Size(objName string) (int64, error) {
+	if err := f.op(); err != nil {
+		return 0, err
+	}
+	return f.Storage.Size(objName)
+}
+
+type flakyReader struct {
+	f       *flakyStorage
+	wrapped ObjectReader
+}
+
+func (r *flakyReader) ReadAt(ctx context.Context, p []byte, offset int64) error {
+	if r.f.slowReads.Add(-1) >= 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r.f.slowReads.Store(0)
+	if err := r.f.op(); err != nil {
+		return err
+	}
+	return r.wrapped.ReadAt(ctx, p, offset)
+}
+
+func (r *flakyReader) Close() error {
+	return r.wrapped.Close()
+}
+
+func newFlakyStorage(t *testing.T) *flakyStorage {
+	f := &flakyStorage{Storage: NewInMem()}
+	w, err := f.CreateObject("obj")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("hello world"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return f
+}
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []ResilienceEvent
+}
+
+func (r *eventRecorder) record(e ResilienceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) kinds() []ResilienceEventKind {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kinds []ResilienceEventKind
+	for _, e := range r.events {
+		kinds = append(kinds, e.Kind)
+	}
+	r.events = nil
+	return kinds
+}
+
+func TestResilientStorageRetries(t *testing.T) {
+	f := newFlakyStorage(t)
+	var events eventRecorder
+	s := WithResilience(f, "loc", ResilienceOptions{
+		MaxRetries:     2,
+		RetryBaseDelay: time.Millisecond,
+		OnEvent:        events.record,
+	})
+
+	f.failures.Store(2)
+	size, err := s.Size("obj")
+	require.NoError(t, err)
+	require.Equal(t, int64(11), size)
+	require.Equal(t, []ResilienceEventKind{ResilienceRetry, ResilienceRetry}, events.kinds())
+
+	f.failures.Store(3)
+	_, err = s.Size("obj")
+	require.ErrorIs(t, err, errFlaky)
+	require.Len(t, events.kinds(), 2)
+
+	// Errors for objects that don't exist are not retried.
+	f.calls.Store(0)
+	_, err = s.Size("missing")
+	require.True(t, s.IsNotExistError(err))
+	require.Equal(t, int32(1), f.calls.Load())
+	require.Empty(t, events.kinds())
+
+	f.failures.Store(1)
+	r, _, err := s.ReadObject(context.Background(), "obj")
+	require.NoError(t, err)
+	f.failures.Store(2)
+	p := make([]byte, 5)
+	require.NoError(t, r.ReadAt(context.Background(), p, 6))
+	require.Equal(t, "world", string(p))
+	require.NoError(t, r.Close())
+
+	var e ResilienceEvent
+	func() {
+		events.mu.Lock()
+		defer events.mu.Unlock()
+		e = events.events[0]
+	}()
+	require.Equal(t, Locator("loc"), e.Locator)
+	require.Equal(t, "read-object", e.Op)
+	require.Contains(t, e.String(), "remote storage loc: retrying read-object (attempt 1)")
+}
+
+func TestResilientStorageCircuitBreaker(t *testing.T) {
+	f := newFlakyStorage(t)
+	var events eventRecorder
+	const cooldown = 20 * time.Millisecond
+	s := WithResilience(f, "", ResilienceOptions{
+		MaxRetries:              -1,
+		CircuitBreakerThreshold: 2,
+		CircuitBreakerCooldown:  cooldown,
+		OnEvent:                 events.record,
+	})
+
+	// The circuit breaker opens after two consecutive failures.
+	f.failures.Store(1)
+	_, err := s.Size("obj")
+	require.ErrorIs(t, err, errFlaky)
+	_, err = s.Size("obj")
+	require.NoError(t, err)
+	f.failures.Store(2)
+	for i := 0; i < 2; i++ {
+		_, err = s.Size("obj")
+		require.ErrorIs(t, err, errFlaky)
+	}
+	require.Equal(t, []ResilienceEventKind{ResilienceCircuitOpen}, events.kinds())
+
+	// Operations fail fast while the circuit breaker is open.
+	f.calls.Store(0)
+	_, err = s.Size("obj")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Zero(t, f.calls.Load())
+
+	// A failed probe keeps the circuit breaker open.
+	time.Sleep(cooldown)
+	f.failures.Store(1)
+	_, err = s.Size("obj")
+	require.ErrorIs(t, err, errFlaky)
+	_, err = s.Size("obj")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	// A successful probe closes the circuit breaker.
+	time.Sleep(cooldown)
+	_, err = s.Size("obj")
+	require.NoError(t, err)
+	_, err = s.Size("obj")
+	require.NoError(t, err)
+	require.Equal(t, []ResilienceEventKind{ResilienceCircuitClose}, events.kinds())
+}
+
+func TestResilientStorageHedgedReads(t *testing.T) {
+	f := newFlakyStorage(t)
+	var events eventRecorder
+	s := WithResilience(f, "", ResilienceOptions{
+		HedgePercentile: 0.9,
+		HedgeMinDelay:   time.Millisecond,
+		OnEvent:         events.record,
+	})
+	ctx := context.Background()
+	r, _, err := s.ReadObject(ctx, "obj")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, r.Close()) }()
+
+	// Reads are only hedged once enough latencies have been sampled.
+	p := make([]byte, 5)
+	for i := 0; i < hedgeLatencySamples; i++ {
+		require.NoError(t, r.ReadAt(ctx, p, 0))
+	}
+	require.Empty(t, events.kinds())
+
+	// A read that doesn't complete is hedged.
+	f.slowReads.Store(1)
+	require.NoError(t, r.ReadAt(ctx, p, 6))
+	require.Equal(t, "world", string(p))
+	require.Equal(t, []ResilienceEventKind{ResilienceHedge}, events.kinds())
+}
+
+func TestResilientStorageConcurrency(t *testing.T) {
+	f := newFlakyStorage(t)
+	f.delay = time.Millisecond
+	s := WithResilience(f, "", ResilienceOptions{MaxConcurrency: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Size("obj")
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.LessOrEqual(t, f.maxInFlight.Load(), int32(2))
+}
@@ -0,0 +1,654 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	defaultS3PartSize       = 16 << 20
+	defaultS3MaxRetries     = 5
+	defaultS3RetryBaseDelay = 50 * time.Millisecond
+	defaultS3RetryMaxDelay  = 5 * time.Second
+)
+
+// S3Options configures a Storage that uses the S3 API (see NewS3).
+type S3Options struct {
+	// Endpoint is the URL of the S3 API, e.g.
+	// "https://s3.us-east-1.amazonaws.com". Objects are addressed path-style,
+	// as <Endpoint>/<Bucket>/<Prefix><object name>, which is supported by AWS
+	// and most S3-compatible services.
+	Endpoint string
+	// Region is the region used to sign requests, e.g. "us-east-1".
+	Region string
+	// Bucket is the bucket which holds the objects.
+	Bucket string
+	// Prefix is prepended to the object names. It typically ends with a "/".
+	Prefix string
+
+	// AccessKeyID, SecretAccessKey and SessionToken are the credentials used to
+	// sign requests with AWS Signature Version 4. Requests are not signed if
+	// AccessKeyID is empty. SessionToken is only required for temporary
+	// credentials.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// HTTPClient is the client used to send requests. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// PartSize is the size of the parts of multipart uploads; objects that are
+	// not larger than PartSize are uploaded with a single request. S3 requires
+	// parts to be at least 5MB. Defaults to 16MB.
+	PartSize int
+	// MaxRetries is the number of times a request that failed with a network
+	// error, a throttling error or a server error is retried. Defaults to 5;
+	// retries are disabled if it is negative.
+	MaxRetries int
+	// RetryBaseDelay and RetryMaxDelay bound the exponential backoff between
+	// retries. They default to 50ms and 5s.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// NewS3 returns a Storage implementation that stores objects in a bucket of
+// an S3-compatible object store. Multipart uploads are used for large
+// objects, and failed requests are retried with exponential backoff.
+func NewS3(opts S3Options) (Storage, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("pebble: S3 bucket not specified")
+	}
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pebble: invalid S3 endpoint %q", opts.Endpoint)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, errors.Errorf("pebble: invalid S3 endpoint %q", opts.Endpoint)
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.PartSize <= 0 {
+		opts.PartSize = defaultS3PartSize
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultS3MaxRetries
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = defaultS3RetryBaseDelay
+	}
+	if opts.RetryMaxDelay <= 0 {
+		opts.RetryMaxDelay = defaultS3RetryMaxDelay
+	}
+	return &s3Store{opts: opts, endpoint: endpoint}, nil
+}
+
+// s3Store is an implementation of the remote.Storage interface that uses the
+// S3 API.
+type s3Store struct {
+	opts     S3Options
+	endpoint *url.URL
+}
+
+var _ Storage = (*s3Store)(nil)
+
+// s3Error is an error response of the S3 API.
+type s3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("s3: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// errS3Retryable marks errors of requests that can be retried.
+var errS3Retryable = errors.New("retryable S3 error")
+
+func isS3Retryable(err error) bool {
+	var s3Err *s3Error
+	if errors.As(err, &s3Err) {
+		return s3Err.StatusCode >= 500 || s3Err.StatusCode == http.StatusTooManyRequests ||
+			s3Err.Code == "InternalError" || s3Err.Code == "SlowDown"
+	}
+	return errors.Is(err, errS3Retryable)
+}
+
+// s3Request describes a request to the S3 API.
+type s3Request struct {
+	method string
+	// key is the key of the object, or empty for requests on the bucket.
+	key    string
+	query  url.Values
+	header http.Header
+	body   []byte
+}
+
+// Close is part of the remote.Storage interface.
+func (s *s3Store) Close() error {
+	s.opts.HTTPClient.CloseIdleConnections()
+	return nil
+}
+
+// ReadObject is part of the remote.Storage interface.
+func (s *s3Store) ReadObject(
+	ctx context.Context, objName string,
+) (_ ObjectReader, objSize int64, _ error) {
+	size, err := s.size(ctx, objName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &s3Reader{s: s, key: s.opts.Prefix + objName, size: size}, size, nil
+}
+
+type s3Reader struct {
+	s    *s3Store
+	key  string
+	size int64
+}
+
+var _ ObjectReader = (*s3Reader)(nil)
+
+// ReadAt is part of the remote.ObjectReader interface.
+func (r *s3Reader) ReadAt(ctx context.Context, p []byte, offset int64) error {
+	if len(p) == 0 {
+		return nil
+	}
+	if offset < 0 || offset+int64(len(p)) > r.size {
+		return errors.Errorf("s3: read of [%d, %d) past the end of object %q of size %d",
+			offset, offset+int64(len(p)), r.key, r.size)
+	}
+	return r.s.retry(ctx, func() error {
+		header := http.Header{}
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+int64(len(p))-1))
+		resp, err := r.s.roundTrip(ctx, s3Request{method: http.MethodGet, key: r.key, header: header})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			// The server ignored the range and returned the whole object.
+			if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+				return errors.Mark(err, errS3Retryable)
+			}
+		}
+		if _, err := io.ReadFull(resp.Body, p); err != nil {
+			return errors.Mark(errors.Wrapf(err, "s3: reading %q", r.key), errS3Retryable)
+		}
+		return nil
+	})
+}
+
+// Close is part of the remote.ObjectReader interface.
+func (r *s3Reader) Close() error {
+	r.s = nil
+	return nil
+}
+
+// CreateObject is part of the remote.Storage interface. The data is buffered
+// in memory until a part is complete; the object is created when the writer is
+// closed.
+func (s *s3Store) CreateObject(objName string) (io.WriteCloser, error) {
+	return &s3Writer{s: s, key: s.opts.Prefix + objName}, nil
+}
+
+type s3Writer struct {
+	s   *s3Store
+	key string
+	buf []byte
+	// uploadID is set once a multipart upload is started.
+	uploadID string
+	parts    []s3CompletedPart
+	err      error
+	closed   bool
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// Write is part of the io.Writer interface.
+func (w *s3Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("s3: write to closed object writer")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) > w.s.opts.PartSize {
+		if w.err = w.uploadPart(w.buf[:w.s.opts.PartSize]); w.err != nil {
+			return 0, w.err
+		}
+		w.buf = append(w.buf[:0], w.buf[w.s.opts.PartSize:]...)
+	}
+	return len(p), nil
+}
+
+// Close is part of the io.Closer interface. It completes the upload of the
+// object, or aborts it if an error occurred.
+func (w *s3Writer) Close() error {
+	if w.closed {
+		return errors.New("s3: object writer already closed")
+	}
+	w.closed = true
+	ctx := context.Background()
+	if w.err == nil {
+		if w.uploadID == "" {
+			w.err = w.s.retry(ctx, func() error {
+				return w.s.doAndClose(ctx, s3Request{method: http.MethodPut, key: w.key, body: w.buf})
+			})
+		} else if w.err = w.uploadPart(w.buf); w.err == nil {
+			w.err = w.completeUpload(ctx)
+		}
+	}
+	if w.err != nil && w.uploadID != "" {
+		// Abort the multipart upload so that the uploaded parts don't linger.
+		_ = w.s.doAndClose(ctx, s3Request{
+			method: http.MethodDelete,
+			key:    w.key,
+			query:  url.Values{"uploadId": {w.uploadID}},
+		})
+	}
+	w.buf = nil
+	return w.err
+}
+
+// uploadPart uploads the next part of a multipart upload, starting the upload
+// if necessary.
+func (w *s3Writer) uploadPart(part []byte) error {
+	ctx := context.Background()
+	if w.uploadID == "" {
+		var result struct {
+			UploadID string `xml:"UploadId"`
+		}
+		err := w.s.retry(ctx, func() error {
+			return w.s.doXML(ctx, s3Request{
+				method: http.MethodPost,
+				key:    w.key,
+				query:  url.Values{"uploads": {""}},
+			}, &result)
+		})
+		if err != nil {
+			return err
+		}
+		w.uploadID = result.UploadID
+	}
+	partNumber := len(w.parts) + 1
+	var etag string
+	err := w.s.retry(ctx, func() error {
+		resp, err := w.s.roundTrip(ctx, s3Request{
+			method: http.MethodPut,
+			key:    w.key,
+			query: url.Values{
+				"partNumber": {strconv.Itoa(partNumber)},
+				"uploadId":   {w.uploadID},
+			},
+			body: part,
+		})
+		if err != nil {
+			return err
+		}
+		etag = resp.Header.Get("ETag")
+		return drainAndClose(resp)
+	})
+	if err != nil {
+		return err
+	}
+	w.parts = append(w.parts, s3CompletedPart{PartNumber: partNumber, ETag: etag})
+	return nil
+}
+
+func (w *s3Writer) completeUpload(ctx context.Context) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: w.parts})
+	if err != nil {
+		return err
+	}
+	return w.s.retry(ctx, func() error {
+		// NB: CompleteMultipartUpload can fail after the response status was
+		// sent, in which case the body holds an error.
+		return w.s.doXML(ctx, s3Request{
+			method: http.MethodPost,
+			key:    w.key,
+			query:  url.Values{"uploadId": {w.uploadID}},
+			body:   body,
+		}, nil)
+	})
+}
+
+// List is part of the remote.Storage interface. It follows the semantics of
+// the S3 API: the names that contain the delimiter after the prefix are
+// grouped by their part up to the first delimiter after the prefix, and the
+// groups are returned without the delimiter. For example, if the storage
+// contains objects a, b/4, b/5 and b/6:
+//
+//	List("", "/") -> ["a", "b"]
+//	List("b/", "/") -> ["4", "5", "6"]
+func (s *s3Store) List(prefix, delimiter string) ([]string, error) {
+	ctx := context.Background()
+	fullPrefix := s.opts.Prefix + prefix
+	var res []string
+	var continuationToken string
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {fullPrefix},
+		}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		var result struct {
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			CommonPrefixes []struct {
+				Prefix string `xml:"Prefix"`
+			} `xml:"CommonPrefixes"`
+		}
+		err := s.retry(ctx, func() error {
+			return s.doXML(ctx, s3Request{method: http.MethodGet, query: query}, &result)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			res = append(res, strings.TrimPrefix(c.Key, fullPrefix))
+		}
+		for _, p := range result.CommonPrefixes {
+			res = append(res, strings.TrimSuffix(strings.TrimPrefix(p.Prefix, fullPrefix), delimiter))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return res, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// Delete is part of the remote.Storage interface.
+func (s *s3Store) Delete(objName string) error {
+	ctx := context.Background()
+	return s.retry(ctx, func() error {
+		return s.doAndClose(ctx, s3Request{method: http.MethodDelete, key: s.opts.Prefix + objName})
+	})
+}
+
+// Size is part of the remote.Storage interface.
+func (s *s3Store) Size(objName string) (int64, error) {
+	return s.size(context.Background(), objName)
+}
+
+func (s *s3Store) size(ctx context.Context, objName string) (int64, error) {
+	var size int64
+	err := s.retry(ctx, func() error {
+		resp, err := s.roundTrip(ctx, s3Request{method: http.MethodHead, key: s.opts.Prefix + objName})
+		if err != nil {
+			return err
+		}
+		size = resp.ContentLength
+		if err := drainAndClose(resp); err != nil {
+			return err
+		}
+		if size < 0 {
+			return errors.Errorf("s3: unknown size of object %q", objName)
+		}
+		return nil
+	})
+	return size, err
+}
+
+// IsNotExistError is part of the remote.Storage interface.
+func (s *s3Store) IsNotExistError(err error) bool {
+	var s3Err *s3Error
+	return errors.As(err, &s3Err) && (s3Err.StatusCode == http.StatusNotFound || s3Err.Code == "NoSuchKey")
+}
+
+// retry calls fn until it succeeds, fails with an error that can't be
+// retried, or the retries are exhausted. It backs off exponentially, with
+// jitter, between attempts.
+func (s *s3Store) retry(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= s.opts.MaxRetries || !isS3Retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		delay := s.opts.RetryMaxDelay
+		if attempt < 30 {
+			delay = min(delay, s.opts.RetryBaseDelay<<attempt)
+		}
+		timer := time.NewTimer(time.Duration(rand.Int63n(int64(delay)) + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.CombineErrors(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// doAndClose sends a request and discards the response.
+func (s *s3Store) doAndClose(ctx context.Context, req s3Request) error {
+	resp, err := s.roundTrip(ctx, req)
+	if err != nil {
+		return err
+	}
+	return drainAndClose(resp)
+}
+
+// doXML sends a request and decodes the XML response into result, if it is
+// not nil. It returns an s3Error if the response holds an error.
+func (s *s3Store) doXML(ctx context.Context, req s3Request, result interface{}) error {
+	resp, err := s.roundTrip(ctx, req)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return errors.Mark(err, errS3Retryable)
+	}
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(body, &root); err == nil && root.XMLName.Local == "Error" {
+		s3Err := &s3Error{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(body, s3Err)
+		return s3Err
+	}
+	if result == nil {
+		return nil
+	}
+	return errors.Wrap(xml.Unmarshal(body, result), "s3: decoding response")
+}
+
+func drainAndClose(resp *http.Response) error {
+	_, err := io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return errors.Mark(err, errS3Retryable)
+}
+
+// roundTrip sends a single request. It returns an s3Error if the response
+// status is not successful.
+func (s *s3Store) roundTrip(ctx context.Context, req s3Request) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.opts.Bucket
+	if req.key != "" {
+		u.Path += "/" + req.key
+	}
+	u.RawPath = s3URIEncode(u.Path, false /* encodeSlash */)
+	u.RawQuery = s3CanonicalQuery(req.query)
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), bytes.NewReader(req.body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	httpReq.ContentLength = int64(len(req.body))
+	s.sign(httpReq, req.body, time.Now())
+
+	resp, err := s.opts.HTTPClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, errors.Mark(err, errS3Retryable)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	s3Err := &s3Error{StatusCode: resp.StatusCode}
+	if body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil {
+		_ = xml.Unmarshal(body, s3Err)
+	}
+	return nil, s3Err
+}
+
+const (
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	s3AmzDateFormat    = "20060102T150405Z"
+)
+
+// sign signs the request with AWS Signature Version 4. The signed headers are
+// Host, Range and the X-Amz-* headers.
+func (s *s3Store) sign(req *http.Request, body []byte, now time.Time) {
+	if s.opts.AccessKeyID == "" {
+		return
+	}
+	now = now.UTC()
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", now.Format(s3AmzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if s.opts.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.opts.SessionToken)
+	}
+	req.Header.Set("Authorization", s3Authorization(req, s.opts.Region, s.opts.AccessKeyID, s.opts.SecretAccessKey, now))
+}
+
+// s3Authorization returns the value of the Authorization header of a request
+// signed with AWS Signature Version 4. The X-Amz-Date and X-Amz-Content-Sha256
+// headers of the request must be set.
+func s3Authorization(
+	req *http.Request, region, accessKeyID, secretAccessKey string, now time.Time,
+) string {
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "range" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		now.Format(s3AmzDateFormat),
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, v := range []string{date, region, "s3", "aws4_request", stringToSign} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(v))
+		key = h.Sum(nil)
+	}
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, accessKeyID, scope, signedHeaders, hex.EncodeToString(key))
+}
+
+// s3CanonicalQuery encodes the query parameters in the canonical form of AWS
+// Signature Version 4, which is also a valid query string.
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range query[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(s3URIEncode(k, true /* encodeSlash */))
+			b.WriteByte('=')
+			b.WriteString(s3URIEncode(v, true /* encodeSlash */))
+		}
+	}
+	return b.String()
+}
+
+// s3URIEncode percent-encodes all the bytes of s other than the unreserved
+// characters of RFC 3986, as required by AWS Signature Version 4.
+func s3URIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
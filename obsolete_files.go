@@ -40,6 +40,10 @@ type cleanupManager struct {
 	jobsCh chan *cleanupJob
 	// waitGroup is used to wait for the background goroutine to exit.
 	waitGroup sync.WaitGroup
+	// unarchivedWALs holds the obsolete WALs that could not be archived (see
+	// Options.WALArchive). They are retried before each subsequent job. It is
+	// only used by the background goroutine.
+	unarchivedWALs []wal.DeletableLog
 
 	mu struct {
 		sync.Mutex
@@ -60,6 +64,8 @@ type deletableFile struct {
 	fileNum  base.DiskFileNum
 	fileSize uint64
 	isLocal  bool
+	// evicted is set for tables that were evicted by a FIFOCompactionPicker.
+	evicted bool
 }
 
 // obsoleteFile holds information about a file that needs to be deleted soon.
@@ -162,18 +168,18 @@ func (cm *cleanupManager) mainLoop() {
 	// Use a token bucket with 1 token / second refill rate and 1 token burst.
 	tb.Init(1.0, 1.0)
 	for job := range cm.jobsCh {
+		cm.retryUnarchivedWALs(job.jobID)
 		for _, of := range job.obsoleteFiles {
 			switch of.fileType {
 			case base.FileTypeTable:
 				cm.maybePace(&tb, of.fileType, of.nonLogFile.fileNum, of.nonLogFile.fileSize)
 				cm.onTableDeleteFn(of.nonLogFile.fileSize, of.nonLogFile.isLocal)
-				cm.deleteObsoleteObject(of.fileType, job.jobID, of.nonLogFile.fileNum)
+				cm.deleteObsoleteObject(of.fileType, job.jobID, of.nonLogFile)
 			case base.FileTypeBlob:
 				cm.maybePace(&tb, of.fileType, of.nonLogFile.fileNum, of.nonLogFile.fileSize)
-				cm.deleteObsoleteObject(of.fileType, job.jobID, of.nonLogFile.fileNum)
+				cm.deleteObsoleteObject(of.fileType, job.jobID, of.nonLogFile)
 			case base.FileTypeLog:
-				cm.deleteObsoleteFile(of.logFile.FS, base.FileTypeLog, job.jobID, of.logFile.Path,
-					base.DiskFileNum(of.logFile.NumWAL))
+				cm.deleteObsoleteWAL(job.jobID, of.logFile)
 			default:
 				path := base.MakeFilepath(cm.opts.FS, of.nonLogFile.dir, of.fileType, of.nonLogFile.fileNum)
 				cm.deleteObsoleteFile(
@@ -230,6 +236,32 @@ func (cm *cleanupManager) maybePace(
 	}
 }
 
+// deleteObsoleteWAL archives an obsolete WAL file if Options.WALArchive is set,
+// and deletes it. If the file could not be archived, it is not deleted, and
+// archiving it is retried before the next job. WALs that have not been
+// archived when the DB is closed are found obsolete again when it is reopened.
+func (cm *cleanupManager) deleteObsoleteWAL(jobID JobID, log wal.DeletableLog) {
+	if cm.opts.WALArchive != nil {
+		if err := cm.opts.WALArchive.Archive(log.FS, log.Path); err != nil {
+			cm.opts.Logger.Errorf("[JOB %d] WAL %s could not be archived and was not deleted: %v",
+				jobID, log.Path, err)
+			cm.unarchivedWALs = append(cm.unarchivedWALs, log)
+			return
+		}
+	}
+	cm.deleteObsoleteFile(log.FS, base.FileTypeLog, jobID, log.Path, base.DiskFileNum(log.NumWAL))
+}
+
+// retryUnarchivedWALs retries archiving and deleting the obsolete WALs that
+// could not be archived.
+func (cm *cleanupManager) retryUnarchivedWALs(jobID JobID) {
+	logs := cm.unarchivedWALs
+	cm.unarchivedWALs = nil
+	for _, log := range logs {
+		cm.deleteObsoleteWAL(jobID, log)
+	}
+}
+
 // deleteObsoleteFile deletes a (non-object) file that is no longer needed.
 func (cm *cleanupManager) deleteObsoleteFile(
 	fs vfs.FS, fileType base.FileType, jobID JobID, path string, fileNum base.DiskFileNum,
@@ -262,11 +294,12 @@ func (cm *cleanupManager) deleteObsoleteFile(
 }
 
 func (cm *cleanupManager) deleteObsoleteObject(
-	fileType base.FileType, jobID JobID, fileNum base.DiskFileNum,
+	fileType base.FileType, jobID JobID, file deletableFile,
 ) {
 	if fileType != base.FileTypeTable && fileType != base.FileTypeBlob {
 		panic("not an object")
 	}
+	fileNum := file.fileNum
 
 	var path string
 	meta, err := cm.objProvider.Lookup(fileType, fileNum)
@@ -282,8 +315,13 @@ func (cm *cleanupManager) deleteObsoleteObject(
 
 	switch fileType {
 	case base.FileTypeTable:
+		reason := "obsolete"
+		if file.evicted {
+			reason = "evicted"
+		}
 		cm.opts.EventListener.TableDeleted(TableDeleteInfo{
 			JobID:   int(jobID),
+			Reason:  reason,
 			Path:    path,
 			FileNum: fileNum,
 			Err:     err,
@@ -353,7 +391,8 @@ func (d *DB) scanObsoleteFiles(list []string, flushableIngests []*ingestedFlusha
 	d.opts.DisableAutomaticCompactions = true
 
 	// Wait for any ongoing compaction to complete before continuing.
-	for d.mu.compact.compactingCount > 0 || d.mu.compact.downloadingCount > 0 || d.mu.compact.flushing {
+	for d.mu.compact.compactingCount > 0 || d.mu.compact.downloadingCount > 0 ||
+		d.mu.compact.uploadingCount > 0 || d.mu.compact.flushing {
 		d.mu.compact.cond.Wait()
 	}
 
@@ -435,7 +474,7 @@ func (d *DB) scanObsoleteFiles(list []string, flushableIngests []*ingestedFlusha
 
 	d.mu.versions.obsoleteTables = mergeObjectInfos(d.mu.versions.obsoleteTables, obsoleteTables)
 	d.mu.versions.obsoleteBlobs = mergeObjectInfos(d.mu.versions.obsoleteBlobs, obsoleteBlobs)
-	d.mu.versions.updateObsoleteTableMetricsLocked()
+	d.mu.versions.updateObsoleteObjectMetricsLocked()
 	d.mu.versions.obsoleteManifests = merge(d.mu.versions.obsoleteManifests, obsoleteManifests)
 	d.mu.versions.obsoleteOptions = merge(d.mu.versions.obsoleteOptions, obsoleteOptions)
 }
@@ -482,11 +521,20 @@ func (d *DB) deleteObsoleteFiles(jobID JobID) {
 	if d.mu.disableFileDeletions > 0 {
 		return
 	}
+	if d.opts.private.follower {
+		d.forgetObsoleteFilesLocked()
+		return
+	}
 	_, noRecycle := d.opts.Cleaner.(base.NeedsFileContents)
+	// A recycled WAL file is overwritten, so it cannot be archived.
+	noRecycle = noRecycle || d.opts.WALArchive != nil
 
 	// NB: d.mu.versions.minUnflushedLogNum is the log number of the earliest
-	// log that has not had its contents flushed to an sstable.
-	obsoleteLogs, err := d.mu.log.manager.Obsolete(wal.NumWAL(d.mu.versions.minUnflushedLogNum), noRecycle)
+	// log that has not had its contents flushed to an sstable. Logs that have
+	// been flushed may still be retained for keyspaces that have not flushed
+	// their writes, and for changefeeds.
+	minLogNum := d.retainWALsForChangefeedsLocked(d.keyspacesMinUnflushedLogNumLocked())
+	obsoleteLogs, err := d.mu.log.manager.Obsolete(minLogNum, noRecycle)
 	if err != nil {
 		panic(err)
 	}
@@ -496,8 +544,16 @@ func (d *DB) deleteObsoleteFiles(jobID JobID) {
 	obsoleteBlobs := slices.Clone(d.mu.versions.obsoleteBlobs)
 	d.mu.versions.obsoleteBlobs = d.mu.versions.obsoleteBlobs[:0]
 
+	evictedTables := make(map[base.DiskFileNum]bool)
 	for _, tbl := range obsoleteTables {
 		delete(d.mu.versions.zombieTables, tbl.FileNum)
+		if _, ok := d.mu.versions.evictedTables[tbl.FileNum]; ok {
+			evictedTables[tbl.FileNum] = true
+			delete(d.mu.versions.evictedTables, tbl.FileNum)
+		}
+	}
+	for _, blob := range obsoleteBlobs {
+		delete(d.mu.versions.zombieBlobs, blob.FileNum)
 	}
 
 	// Sort the manifests cause we want to delete some contiguous prefix
@@ -545,6 +601,7 @@ func (d *DB) deleteObsoleteFiles(jobID JobID) {
 				fileNum:  f.FileNum,
 				fileSize: f.FileSize,
 				isLocal:  f.isLocal,
+				evicted:  evictedTables[f.FileNum],
 			},
 		})
 	}
@@ -89,6 +89,12 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	} else {
 		opts.Logger = opts.LoggerAndTracer
 	}
+	if opts.Local.IOScheduler != nil {
+		// Compactions are not started while the scheduler backs off background
+		// I/O.
+		opts.Experimental.CompactionLimiter = opts.Local.IOScheduler.WrapCompactionLimiter(
+			opts.Experimental.CompactionLimiter)
+	}
 
 	if invariants.Sometimes(5) {
 		assertComparer := base.MakeAssertComparer(*opts.Comparer)
@@ -109,9 +115,12 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		}
 	}()
 
-	// Lock the database directory.
+	// Lock the database directory. A follower doesn't take the lock, which is
+	// held by the primary.
 	var fileLock *Lock
-	if opts.Lock != nil {
+	if opts.private.follower {
+		// No lock.
+	} else if opts.Lock != nil {
 		// The caller already acquired the database lock. Ensure that the
 		// directory matches.
 		if err := opts.Lock.pathMatches(dirname); err != nil {
@@ -128,7 +137,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		}
 	}
 	defer func() {
-		if db == nil {
+		if db == nil && fileLock != nil {
 			fileLock.Close()
 		}
 	}()
@@ -244,6 +253,9 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 				_ = d.fileCache.Close()
 			}
 			d.cacheHandle.Close()
+			if d.secondaryBlockCache != nil {
+				_ = d.secondaryBlockCache.Close()
+			}
 
 			for _, mem := range d.mu.mem.queue {
 				switch t := mem.flushable.(type) {
@@ -252,6 +264,9 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 					t.arenaBuf = manual.Buf{}
 				}
 			}
+			for _, ks := range d.keyspaces {
+				_ = ks.db.Close()
+			}
 			if d.cleanupManager != nil {
 				d.cleanupManager.Close()
 			}
@@ -305,7 +320,19 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		BytesPerSync:        opts.BytesPerSync,
 	}
 	providerSettings.Local.ReadaheadConfig = opts.Local.ReadaheadConfig
+	providerSettings.Local.IOScheduler = opts.Local.IOScheduler
 	providerSettings.Remote.StorageFactory = opts.Experimental.RemoteStorage
+	if f, ro := opts.Experimental.RemoteStorage, opts.Experimental.RemoteStorageResilience; f != nil && ro != nil {
+		resilienceOpts := *ro
+		onEvent := resilienceOpts.OnEvent
+		resilienceOpts.OnEvent = func(info remote.ResilienceEvent) {
+			opts.EventListener.RemoteStorageEvent(info)
+			if onEvent != nil {
+				onEvent(info)
+			}
+		}
+		providerSettings.Remote.StorageFactory = remote.MakeResilientFactory(f, resilienceOpts)
+	}
 	providerSettings.Remote.CreateOnShared = opts.Experimental.CreateOnShared
 	providerSettings.Remote.CreateOnSharedLocator = opts.Experimental.CreateOnSharedLocator
 	providerSettings.Remote.CacheSizeBytes = opts.Experimental.SecondaryCacheSizeBytes
@@ -343,6 +370,10 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 			}
 		}
 	}
+	d.mu.compact.upload.init(opts, d.mu.versions.currentVersion(), d.objProvider)
+	if d.mu.compact.upload.pending != nil {
+		d.mu.versions.uploads = &d.mu.compact.upload
+	}
 
 	// In read-only mode, we replay directly into the mutable memtable but never
 	// flush it. We need to delay creation of the memtable until we know the
@@ -409,8 +440,19 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		opts.FileCache = NewFileCache(opts.Experimental.FileCacheShards, fileCacheSize)
 		defer opts.FileCache.Unref()
 	}
+	if len(opts.Experimental.SecondaryBlockCache) > 0 {
+		d.secondaryBlockCache, err = openSecondaryBlockCache(
+			opts.Experimental.SecondaryBlockCache, opts.Logger, opts.FS, dirname, opts.ReadOnly)
+		if err != nil {
+			return nil, err
+		}
+		d.cacheHandle.SetSecondaryCache(d.secondaryBlockCache)
+	}
 	d.fileCache = opts.FileCache.newHandle(d.cacheHandle, d.objProvider, d.opts.LoggerAndTracer, d.opts.MakeReaderOptions(), d.reportCorruption)
 	d.newIters = d.fileCache.newIters
+	if err := d.openKeyspacesLocked(dirname); err != nil {
+		return nil, err
+	}
 	d.tableNewRangeKeyIter = tableNewRangeKeyIter(d.newIters)
 
 	d.mu.annotators.totalSize = d.makeFileSizeAnnotator(func(f *manifest.TableMetadata) bool {
@@ -489,14 +531,22 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		}
 	}
 
-	// Replay any newer log files than the ones named in the manifest.
+	// Replay any newer log files than the ones named in the manifest, and
+	// those holding writes that keyspaces have not flushed. The writes of a
+	// keyspace are logged to the WAL of its parent, which replays them.
 	var replayWALs wal.Logs
 	for i, w := range wals {
-		if base.DiskFileNum(w.Num) >= d.mu.versions.minUnflushedLogNum {
+		if opts.private.keyspace {
+			break
+		}
+		if wal.NumWAL(w.Num) >= d.keyspacesMinUnflushedLogNumLocked() {
 			replayWALs = wals[i:]
 			break
 		}
 	}
+	for _, ks := range d.keyspaces {
+		ks.db.beginKeyspaceReplay()
+	}
 	var flushableIngests []*ingestedFlushable
 	for i, lf := range replayWALs {
 		// WALs other than the last one would have been closed cleanly.
@@ -531,16 +581,32 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 			d.mu.compact.cond.Wait()
 		}
 
-		// Create an empty .log file for the mutable memtable.
-		newLogNum := d.mu.versions.getNextDiskFileNum()
-		d.mu.log.writer, err = d.mu.log.manager.Create(wal.NumWAL(newLogNum), int(jobID))
-		if err != nil {
-			return nil, err
-		}
+		// Create an empty .log file for the mutable memtable. The writes of a
+		// keyspace are logged to the WAL of its parent instead.
+		if !d.opts.private.keyspace {
+			// The memtables of the keyspaces are tagged with the numbers of the
+			// DB's WALs, which must be greater than the keyspaces' file numbers.
+			for _, ks := range d.keyspaces {
+				d.mu.versions.markFileNumUsed(base.DiskFileNum(ks.db.mu.versions.nextFileNum.Load()))
+			}
+			newLogNum := d.mu.versions.getNextDiskFileNum()
+			d.mu.log.writer, err = d.mu.log.manager.Create(wal.NumWAL(newLogNum), int(jobID))
+			if err != nil {
+				return nil, err
+			}
 
-		// This isn't strictly necessary as we don't use the log number for
-		// memtables being flushed, only for the next unflushed memtable.
-		d.mu.mem.queue[len(d.mu.mem.queue)-1].logNum = newLogNum
+			// This isn't strictly necessary as we don't use the log number for
+			// memtables being flushed, only for the next unflushed memtable.
+			d.mu.mem.queue[len(d.mu.mem.queue)-1].logNum = newLogNum
+			d.recordWALLocked(newLogNum, d.mu.versions.logSeqNum.Load())
+			for _, ks := range d.keyspaces {
+				ks.db.finishKeyspaceReplay(newLogNum)
+			}
+		}
+	} else {
+		for _, ks := range d.keyspaces {
+			ks.db.finishKeyspaceReplay(0 /* newLogNum */)
+		}
 	}
 	d.updateReadStateLocked(d.opts.DebugCheck)
 
@@ -645,6 +711,10 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		}
 	})
 
+	// Link the keyspaces to the DB, whose WAL now logs their writes.
+	for _, ks := range d.keyspaces {
+		ks.db.keyspace.parent.Store(d)
+	}
 	return d, nil
 }
 
@@ -977,6 +1047,23 @@ func (d *DB) replayWAL(
 		b.db = d
 		b.SetRepr(buf.Bytes())
 		seqNum := b.SeqNum()
+		if target := d.opts.private.replayTargetSeqNum; target != 0 && b.Count() > 0 &&
+			seqNum+base.SeqNum(b.Count()) > target+1 {
+			// The batch, and every later batch, is newer than the target of
+			// RecoverFromWALArchive.
+			break
+		}
+		if len(d.keyspaces) > 0 {
+			if err := d.replayKeyspaceLogData(&b, base.DiskFileNum(ll.Num)); err != nil {
+				return nil, 0, err
+			}
+			if base.DiskFileNum(ll.Num) < d.mu.versions.minUnflushedLogNum {
+				// The DB has flushed the batch, which was only replayed for the
+				// keyspaces that have not.
+				buf.Reset()
+				continue
+			}
+		}
 		maxSeqNum = seqNum + base.SeqNum(b.Count())
 		keysReplayed += int64(b.Count())
 		batchesReplayed++
@@ -38,6 +38,7 @@ import (
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
 	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/vfs/atomicfs"
 	"github.com/cockroachdb/pebble/vfs/errorfs"
@@ -332,7 +333,7 @@ func TestNewDBFilenames(t *testing.T) {
 			"LOCK",
 			"MANIFEST-000001",
 			"OPTIONS-000003",
-			"marker.format-version.000007.020",
+			"marker.format-version.000010.023",
 			"marker.manifest.000001.MANIFEST-000001",
 		},
 	}
@@ -1538,6 +1539,35 @@ func TestOpenRatchetsNextFileNum(t *testing.T) {
 
 }
 
+func TestOpenIOScheduler(t *testing.T) {
+	s := objstorageprovider.NewIOScheduler(objstorageprovider.IOSchedulerOptions{})
+	opts := &Options{FS: vfs.NewMem(), Logger: testLogger{t}}
+	opts.Local.IOScheduler = s
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	_, ok := d.opts.Experimental.CompactionLimiter.(*base.DefaultCompactionLimiter)
+	require.False(t, ok)
+
+	// The reads and writes of flushes, compactions and iterators are scheduled.
+	for i := 0; i < 2; i++ {
+		require.NoError(t, d.Set([]byte("foo"), []byte("value"), nil))
+		require.NoError(t, d.Set([]byte("bar"), []byte("value"), nil))
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	for valid := iter.First(); valid; valid = iter.Next() {
+	}
+	require.NoError(t, iter.Close())
+	require.NoError(t, d.Close())
+
+	m := s.Metrics()
+	for _, c := range []block.Category{block.CategoryUnknown, categoryFlush, categoryCompaction} {
+		require.Greater(t, m.Bytes[c], int64(0), "%s", c)
+	}
+}
+
 func TestMkdirAllAndSyncParents(t *testing.T) {
 	if filepath.Separator != '/' {
 		t.Skip("skipping due to path separator")
@@ -34,8 +34,10 @@ import (
 )
 
 const (
-	cacheDefaultSize       = 8 << 20 // 8 MB
-	defaultLevelMultiplier = 10
+	cacheDefaultSize               = 8 << 20 // 8 MB
+	defaultLevelMultiplier         = 10
+	defaultChangefeedWALRetention  = 10 * time.Minute
+	defaultFollowerRefreshInterval = time.Second
 )
 
 // Compression exports the base.Compression type.
@@ -47,14 +49,52 @@ const (
 	NoCompression      = block.NoCompression
 	SnappyCompression  = block.SnappyCompression
 	ZstdCompression    = block.ZstdCompression
+	Lz4Compression     = block.Lz4Compression
+	MinLZCompression   = block.MinLZCompression
 )
 
+// ZstdCompressionLevel returns a Compression that compresses blocks with zstd
+// at the provided level. See block.ZstdCompressionLevel.
+func ZstdCompressionLevel(level int) Compression {
+	return block.ZstdCompressionLevel(level)
+}
+
+// TieredCompression returns the compression to use for the provided level in
+// a scheme that favors compression speed in L0-L2, where data is short-lived
+// and compactions are frequent, and compression ratio in the lower levels,
+// which hold most of the data:
+//
+//	L0-L2: LZ4
+//	L3-L4: zstd at the default level
+//	L5-L6: zstd at level 7
+//
+// It is intended to be used as LevelOptions.Compression:
+//
+//	for i := range opts.Levels {
+//		compression := pebble.TieredCompression(i)
+//		opts.Levels[i].Compression = func() pebble.Compression { return compression }
+//	}
+//
+// LZ4 requires FormatExperimentalLZ4Compression; Snappy is used in L0-L2
+// until the DB is ratcheted to that format major version.
+func TieredCompression(level int) Compression {
+	switch {
+	case level <= 2:
+		return Lz4Compression
+	case level <= 4:
+		return ZstdCompression
+	default:
+		return ZstdCompressionLevel(7)
+	}
+}
+
 // FilterType exports the base.FilterType type.
 type FilterType = base.FilterType
 
-// Exported TableFilter constants.
+// Exported FilterType constants.
 const (
 	TableFilter = base.TableFilter
+	RangeFilter = base.RangeFilter
 )
 
 // FilterWriter exports the base.FilterWriter type.
@@ -63,6 +103,9 @@ type FilterWriter = base.FilterWriter
 // FilterPolicy exports the base.FilterPolicy type.
 type FilterPolicy = base.FilterPolicy
 
+// RangeFilterPolicy exports the base.RangeFilterPolicy type.
+type RangeFilterPolicy = base.RangeFilterPolicy
+
 // KeySchema exports the colblk.KeySchema type.
 type KeySchema = colblk.KeySchema
 
@@ -410,11 +453,23 @@ type LevelOptions struct {
 	// The default value is 90
 	BlockSizeThreshold int
 
-	// Compression defines the per-block compression to use.
+	// Compression defines the per-block compression to use. See
+	// TieredCompression for a configuration that varies compression by level.
 	//
-	// The default value (DefaultCompression) uses snappy compression.
+	// The default value (DefaultCompression) uses snappy compression. Snappy
+	// is also used if the compression requires a newer table format than the
+	// DB's format major version permits.
 	Compression func() Compression
 
+	// CompressionDictSize is the maximum size in bytes of the zstd compression
+	// dictionary trained for each table written to the level. The dictionary is
+	// trained from a sample of the table's first data blocks and used to
+	// compress the remaining data blocks. Dictionaries are only used with zstd
+	// compression, and require FormatExperimentalCompressionDictionaries.
+	//
+	// The default value (0) disables compression dictionaries.
+	CompressionDictSize int
+
 	// FilterPolicy defines a filter algorithm (such as a Bloom filter) that can
 	// reduce disk reads for Get calls.
 	//
@@ -431,6 +486,13 @@ type LevelOptions struct {
 	// memory proportional to the number of keys in an sstable to create, but
 	// avoids the index lookup when determining if a key is present. Table-level
 	// filters should be preferred except under constrained memory situations.
+	//
+	// A range filter (RangeFilter) is a table-level filter that can also prove
+	// that a table contains no keys within an iterator's bounds, allowing
+	// iterators with bounds to skip the table entirely. Range filters require
+	// a FilterPolicy that implements RangeFilterPolicy, such as
+	// rangefilter.FilterPolicy(1) from the pebble/rangefilter package, and
+	// the bytewise DefaultComparer.
 	FilterType FilterType
 
 	// IndexBlockSize is the target uncompressed size in bytes of each index
@@ -444,6 +506,12 @@ type LevelOptions struct {
 
 	// The target file size for the level.
 	TargetFileSize int64
+
+	// DisableValueSeparation disables the separation of values into blob files
+	// for tables written to the level, even if Experimental.ValueSeparationPolicy
+	// enables value separation. Values stored in blob files are retrieved and
+	// written in-place when compacted into the level.
+	DisableValueSeparation bool
 }
 
 // EnsureDefaults ensures that the default values for all of the options have
@@ -496,6 +564,14 @@ type Options struct {
 	// unit from the semaphore for the duration of the read.
 	LoadBlockSema *fifo.Semaphore
 
+	// ChangefeedWALRetention is the maximum duration for which WALs are
+	// retained for open changefeeds (see DB.NewChangefeed) that have not yet
+	// read them, after the WALs' contents have been flushed. A changefeed that
+	// falls further behind fails with ErrChangefeedTooOld.
+	//
+	// The default value is 10 minutes.
+	ChangefeedWALRetention time.Duration
+
 	// Cleaner cleans obsolete files.
 	//
 	// The default cleaner uses the DeleteCleaner.
@@ -507,6 +583,13 @@ type Options struct {
 		// consulted whenever a read handle is initialized.
 		ReadaheadConfig *ReadaheadConfig
 
+		// IOScheduler, if set, schedules the reads and writes of local sstables
+		// and blob files by their block.Category, prioritizing the reads of
+		// latency-sensitive categories over background I/O, like the I/O of
+		// compactions. Compactions are not started while the scheduler backs
+		// off background I/O (see IOSchedulerOptions).
+		IOScheduler *IOScheduler
+
 		// TODO(radu): move BytesPerSync, LoadBlockSema, Cleaner here.
 	}
 
@@ -517,6 +600,11 @@ type Options struct {
 	// The default value uses the same ordering as bytes.Compare.
 	Comparer *Comparer
 
+	// CompactionFilter, if non-nil, is invoked by flushes and compactions to
+	// remove keys or change their values. See CompactionFilter for the keys
+	// to which the filter is applied.
+	CompactionFilter CompactionFilter
+
 	// DebugCheck is invoked, if non-nil, whenever a new version is being
 	// installed. Typically, this is set to pebble.DebugCheckLevels in tests
 	// or tools only, to check invariants over all the data in the database.
@@ -666,6 +754,14 @@ type Options struct {
 		// compaction will never get triggered.
 		MultiLevelCompactionHeuristic MultiLevelHeuristic
 
+		// CompactionPicker determines how automatic compactions are picked, and
+		// thus the shape of the LSM. The default is LeveledCompactionPicker().
+		// TieredCompactionPicker lowers write amplification at the expense of
+		// read and space amplification. FIFOCompactionPicker never merges
+		// tables, and instead evicts the oldest tables once the LSM exceeds a
+		// size or age limit.
+		CompactionPicker CompactionPicker
+
 		// MaxWriterConcurrency is used to indicate the maximum number of
 		// compression workers the compression queue is allowed to use. If
 		// MaxWriterConcurrency > 0, then the Writer will use parallelism, to
@@ -705,6 +801,13 @@ type Options struct {
 		// value and stored with the key, when the value is stored elsewhere.
 		ShortAttributeExtractor ShortAttributeExtractor
 
+		// TTLExtractor, if non-nil, is used to extract the expiration time of
+		// keys written with Set. Expired keys are hidden from iterators and Get,
+		// and are dropped by compactions. Flushes and compactions record when
+		// the keys of each sstable expire in its properties, which allows
+		// compactions to target sstables containing expired keys.
+		TTLExtractor TTLExtractor
+
 		// RequiredInPlaceValueBound specifies an optional span of user key
 		// prefixes that are not-MVCC, but have a suffix. For these the values
 		// must be stored with the key, since the concept of "older versions" is
@@ -736,6 +839,13 @@ type Options struct {
 		// allows ingestion of external files.
 		RemoteStorage remote.StorageFactory
 
+		// RemoteStorageResilience, if set, wraps every remote.Storage created by
+		// RemoteStorage with remote.WithResilience, adding retries, hedged reads,
+		// concurrency limits and a circuit breaker. Retries, hedged reads and
+		// circuit breaker state changes are reported through
+		// EventListener.RemoteStorageEvent, in addition to the OnEvent callback.
+		RemoteStorageResilience *remote.ResilienceOptions
+
 		// If CreateOnShared is non-zero, new sstables are created on remote storage
 		// (using CreateOnSharedLocator and with the appropriate
 		// CreateOnSharedStrategy). These sstables can be shared between different
@@ -747,10 +857,29 @@ type Options struct {
 		CreateOnShared        remote.CreateOnSharedStrategy
 		CreateOnSharedLocator remote.Locator
 
+		// UploadLocalTables enables upload compactions, which move the local
+		// sstables of the levels that are created on shared storage (see
+		// CreateOnShared and remote.SharedLevelsStart) to shared storage in the
+		// background. CreateOnShared only applies to new sstables, so this is
+		// useful when shared storage is enabled on an existing store. Progress is
+		// reported in Metrics.Upload; see also DB.WaitForUploads.
+		UploadLocalTables bool
+
+		// UploadBytesPerSecond limits the rate at which upload compactions move
+		// data to shared storage. If it is 0, uploads are not rate-limited.
+		UploadBytesPerSecond int64
+
 		// CacheSizeBytesBytes is the size of the on-disk block cache for objects
 		// on shared storage in bytes. If it is 0, no cache is used.
 		SecondaryCacheSizeBytes int64
 
+		// SecondaryBlockCache configures secondary block caches, typically on
+		// local flash drives, into which the blocks evicted from the block cache
+		// are spilled. Blocks are distributed across the devices according to
+		// their file and offset. Unlike SecondaryCacheSizeBytes, this applies to
+		// both local and remote sstables. See SecondaryBlockCacheDevice.
+		SecondaryBlockCache []SecondaryBlockCacheDevice
+
 		// EnableDeleteOnlyCompactionExcises enables delete-only compactions to also
 		// apply delete-only compaction hints on sstables that partially overlap
 		// with it. This application happens through an excise, similar to
@@ -764,6 +893,13 @@ type Options struct {
 		CompactionLimiter CompactionLimiter
 
 		UserKeyCategories UserKeyCategories
+
+		// ValueSeparationPolicy controls the policy for separating values into
+		// external blob files. If nil, value separation defaults to disabled.
+		// The value separation policy is ignored if EnableColumnarBlocks() is
+		// false or the format major version is less than
+		// FormatExperimentalValueSeparation.
+		ValueSeparationPolicy func() ValueSeparationPolicy
 	}
 
 	// Filters is a map from filter policy name to filter policy. It is used for
@@ -835,6 +971,20 @@ type Options struct {
 	// to Open for perpetuity.
 	KeySchemas sstable.KeySchemas
 
+	// Keyspaces, if set, configures named keyspaces that are stored alongside
+	// the DB and accessed through the handles returned by DB.Keyspace. Each
+	// keyspace is an LSM of its own, with its own Comparer, Merger,
+	// LevelOptions, memtables, version and compactions, stored in the
+	// keyspaces/<name> subdirectory of the DB's directory. The keyspaces share
+	// the DB's commit pipeline and WAL, so a single Batch may write atomically
+	// to the DB and to several keyspaces (see Keyspace.InBatch).
+	//
+	// The DB retains its WALs until every keyspace has flushed the writes they
+	// hold. Keyspaces may be added when the DB is reopened, but a keyspace may
+	// not be removed while the WAL holds writes to it that it has not flushed.
+	// Keyspaces are not supported by followers.
+	Keyspaces []KeyspaceOptions
+
 	// Lock, if set, must be a database lock acquired through LockDirectory for
 	// the same directory passed to Open. If provided, Open will skip locking
 	// the directory. Closing the database will not release the lock, and it's
@@ -877,6 +1027,11 @@ type Options struct {
 	// LoggerAndTracer is used for writing log messages and traces.
 	LoggerAndTracer LoggerAndTracer
 
+	// Tracer, if non-nil, is used to create structured tracing spans for the
+	// flush, compaction, ingest, excise and download jobs run by the DB, and
+	// for iterators created with a traced context. See Tracer.
+	Tracer Tracer
+
 	// MaxManifestFileSize is the maximum size the MANIFEST file is allowed to
 	// become. When the MANIFEST exceeds this size it is rolled over and a new
 	// MANIFEST is created.
@@ -978,6 +1133,11 @@ type Options struct {
 	// disabled.
 	ReadOnly bool
 
+	// FollowerRefreshInterval is the interval at which a DB opened with
+	// OpenFollower reads the version edits and WAL records written by the
+	// primary DB since its previous refresh. The default is 1 second.
+	FollowerRefreshInterval time.Duration
+
 	// FileCache is an initialized FileCache which should be set as an
 	// option if the DB needs to be initialized with a pre-existing file cache.
 	// If FileCache is nil, then a file cache which is unique to the DB instance
@@ -1025,6 +1185,15 @@ type Options struct {
 	// is not a corresponding entry in WALRecoveryDirs, Open will error.
 	WALRecoveryDirs []wal.Dir
 
+	// WALArchive, if set, archives each WAL file, including the segments
+	// written to the WALFailover secondary, before it is deleted. Together
+	// with a checkpoint, the archived WALs allow a DB to be recovered to any
+	// later sequence number (see RecoverFromWALArchive). WAL files are not
+	// recycled when WALArchive is set. A WAL file that could not be archived
+	// is not deleted; archiving it is retried when other obsolete files are
+	// next deleted, and when the DB is next opened.
+	WALArchive WALArchive
+
 	// WALMinSyncInterval is the minimum duration between syncs of the WAL. If
 	// WAL syncs are requested faster than this interval, they will be
 	// artificially delayed. Introducing a small artificial delay (500us) between
@@ -1081,6 +1250,21 @@ type Options struct {
 		// do not want to allow users to actually configure.
 		disableLazyCombinedIteration bool
 
+		// follower is set by OpenFollower. A follower opens the DB without
+		// taking its lock, and never deletes its files, which are owned by the
+		// primary DB writing to the directory.
+		follower bool
+
+		// keyspace is set on the options of the DB storing a keyspace (see
+		// Options.Keyspaces). The keyspace's writes are logged to the WAL of
+		// its parent DB, which replays them when it is opened.
+		keyspace bool
+
+		// replayTargetSeqNum is set by RecoverFromWALArchive. If non-zero,
+		// Open stops replaying the WALs at the first batch containing a
+		// sequence number greater than replayTargetSeqNum.
+		replayTargetSeqNum base.SeqNum
+
 		// testingAlwaysWaitForCleanup is set by some tests to force waiting for
 		// obsolete file deletion (to make events deterministic).
 		testingAlwaysWaitForCleanup bool
@@ -1097,6 +1281,38 @@ type Options struct {
 	}
 }
 
+// ValueSeparationPolicy configures the policy for separating values into
+// external blob files.
+type ValueSeparationPolicy struct {
+	// Enabled controls whether value separation is enabled.
+	Enabled bool
+	// MinimumSize imposes a lower bound on the size of values that can be
+	// separated into a blob file. Values smaller than this are always written
+	// to the sstable (but may still be written to a value block within the
+	// sstable).
+	//
+	// MinimumSize must be > 0.
+	MinimumSize int
+	// MaxBlobReferenceDepth limits the number of potentially overlapping blob
+	// files referenced by a single sstable. When a compaction would produce
+	// an sstable that references more blob files than this, the values that
+	// would push the depth beyond the limit are rewritten into the
+	// compaction's new blob file instead.
+	//
+	// MaxBlobReferenceDepth must be > 0.
+	MaxBlobReferenceDepth int
+	// GarbageRatioThreshold is the fraction of a blob file's values that must
+	// be unreferenced by the latest version before the blob file is rewritten.
+	// Blob files are rewritten by blob-rewrite compactions, which rewrite the
+	// sstables referencing a blob file and copy the referenced values into a
+	// new blob file. Once no sstable references the original blob file, it is
+	// deleted and its space is reclaimed.
+	//
+	// GarbageRatioThreshold must be in [0, 1]. A value of 0 disables blob file
+	// rewrites.
+	GarbageRatioThreshold float64
+}
+
 // WALFailoverOptions configures the WAL failover mechanics to use during
 // transient write unavailability on the primary WAL volume.
 type WALFailoverOptions struct {
@@ -1112,6 +1328,12 @@ type WALFailoverOptions struct {
 // ReadaheadConfig controls the use of read-ahead.
 type ReadaheadConfig = objstorageprovider.ReadaheadConfig
 
+// IOScheduler exports the objstorageprovider.IOScheduler type.
+type IOScheduler = objstorageprovider.IOScheduler
+
+// IOSchedulerOptions exports the objstorageprovider.IOSchedulerOptions type.
+type IOSchedulerOptions = objstorageprovider.IOSchedulerOptions
+
 // JemallocSizeClasses exports sstable.JemallocSizeClasses.
 var JemallocSizeClasses = sstable.JemallocSizeClasses
 
@@ -1133,6 +1355,9 @@ func (o *Options) EnsureDefaults() {
 	if o.BytesPerSync <= 0 {
 		o.BytesPerSync = 512 << 10 // 512 KB
 	}
+	if o.ChangefeedWALRetention <= 0 {
+		o.ChangefeedWALRetention = defaultChangefeedWALRetention
+	}
 	if o.Cleaner == nil {
 		o.Cleaner = DeleteCleaner{}
 	}
@@ -1149,6 +1374,9 @@ func (o *Options) EnsureDefaults() {
 	if o.Experimental.CompactionLimiter == nil {
 		o.Experimental.CompactionLimiter = &base.DefaultCompactionLimiter{}
 	}
+	if o.FollowerRefreshInterval <= 0 {
+		o.FollowerRefreshInterval = defaultFollowerRefreshInterval
+	}
 	if o.KeySchema == "" && len(o.KeySchemas) == 0 {
 		ks := colblk.DefaultKeySchema(o.Comparer, 16 /* bundleSize */)
 		o.KeySchema = ks.Name
@@ -1371,8 +1599,14 @@ func (o *Options) String() string {
 	fmt.Fprintf(&buf, "[Options]\n")
 	fmt.Fprintf(&buf, "  bytes_per_sync=%d\n", o.BytesPerSync)
 	fmt.Fprintf(&buf, "  cache_size=%d\n", cacheSize)
+	if o.ChangefeedWALRetention > 0 && o.ChangefeedWALRetention != defaultChangefeedWALRetention {
+		fmt.Fprintf(&buf, "  changefeed_wal_retention=%s\n", o.ChangefeedWALRetention)
+	}
 	fmt.Fprintf(&buf, "  cleaner=%s\n", o.Cleaner)
 	fmt.Fprintf(&buf, "  compaction_debt_concurrency=%d\n", o.Experimental.CompactionDebtConcurrency)
+	if o.Experimental.CompactionPicker != LeveledCompactionPicker() {
+		fmt.Fprintf(&buf, "  compaction_picker=%s\n", o.Experimental.CompactionPicker)
+	}
 	fmt.Fprintf(&buf, "  comparer=%s\n", o.Comparer.Name)
 	fmt.Fprintf(&buf, "  disable_wal=%t\n", o.DisableWAL)
 	if o.Experimental.DisableIngestAsFlushable != nil && o.Experimental.DisableIngestAsFlushable() {
@@ -1451,6 +1685,18 @@ func (o *Options) String() string {
 		fmt.Fprintf(&buf, "  elevated_write_stall_threshold_lag=%s\n", o.WALFailover.FailoverOptions.ElevatedWriteStallThresholdLag)
 	}
 
+	if o.Experimental.ValueSeparationPolicy != nil {
+		policy := o.Experimental.ValueSeparationPolicy()
+		if policy.Enabled {
+			fmt.Fprintf(&buf, "\n")
+			fmt.Fprintf(&buf, "[Value Separation]\n")
+			fmt.Fprintf(&buf, "  enabled=%t\n", policy.Enabled)
+			fmt.Fprintf(&buf, "  minimum_size=%d\n", policy.MinimumSize)
+			fmt.Fprintf(&buf, "  max_blob_reference_depth=%d\n", policy.MaxBlobReferenceDepth)
+			fmt.Fprintf(&buf, "  garbage_ratio_threshold=%f\n", policy.GarbageRatioThreshold)
+		}
+	}
+
 	for i := range o.Levels {
 		l := &o.Levels[i]
 		fmt.Fprintf(&buf, "\n")
@@ -1459,10 +1705,16 @@ func (o *Options) String() string {
 		fmt.Fprintf(&buf, "  block_size=%d\n", l.BlockSize)
 		fmt.Fprintf(&buf, "  block_size_threshold=%d\n", l.BlockSizeThreshold)
 		fmt.Fprintf(&buf, "  compression=%s\n", resolveDefaultCompression(l.Compression()))
+		if l.CompressionDictSize > 0 {
+			fmt.Fprintf(&buf, "  compression_dict_size=%d\n", l.CompressionDictSize)
+		}
 		fmt.Fprintf(&buf, "  filter_policy=%s\n", filterPolicyName(l.FilterPolicy))
 		fmt.Fprintf(&buf, "  filter_type=%s\n", l.FilterType)
 		fmt.Fprintf(&buf, "  index_block_size=%d\n", l.IndexBlockSize)
 		fmt.Fprintf(&buf, "  target_file_size=%d\n", l.TargetFileSize)
+		if l.DisableValueSeparation {
+			fmt.Fprintf(&buf, "  disable_value_separation=%t\n", true)
+		}
 	}
 
 	return buf.String()
@@ -1605,6 +1857,8 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.BytesPerSync, err = strconv.Atoi(value)
 			case "cache_size":
 				o.CacheSize, err = strconv.ParseInt(value, 10, 64)
+			case "changefeed_wal_retention":
+				o.ChangefeedWALRetention, err = time.ParseDuration(value)
 			case "cleaner":
 				switch value {
 				case "archive":
@@ -1624,6 +1878,61 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				}
 			case "compaction_debt_concurrency":
 				o.Experimental.CompactionDebtConcurrency, err = strconv.ParseUint(value, 10, 64)
+			case "compaction_picker":
+				switch {
+				case value == "leveled":
+					o.Experimental.CompactionPicker = LeveledCompactionPicker()
+				case strings.HasPrefix(value, "tiered"):
+					fields := strings.FieldsFunc(strings.TrimPrefix(value, "tiered"), func(r rune) bool {
+						return unicode.IsSpace(r) || r == ',' || r == '(' || r == ')'
+					})
+					if len(fields) != 2 {
+						err = errors.Newf("require 2 arguments")
+					}
+					var p TieredCompactionOptions
+					if err == nil {
+						p.SizeRatio, err = strconv.Atoi(fields[0])
+					}
+					if err == nil {
+						p.MaxSizeAmplificationPercent, err = strconv.Atoi(fields[1])
+					}
+					if err == nil {
+						o.Experimental.CompactionPicker = TieredCompactionPicker(p)
+					} else {
+						err = errors.Wrapf(err, "unexpected tiered compaction picker arguments: %s", value)
+					}
+				case strings.HasPrefix(value, "fifo"):
+					fields := strings.FieldsFunc(strings.TrimPrefix(value, "fifo"), func(r rune) bool {
+						return unicode.IsSpace(r) || r == ',' || r == '(' || r == ')'
+					})
+					if len(fields) != 3 {
+						err = errors.Newf("require 3 arguments")
+					}
+					var p FIFOCompactionOptions
+					if err == nil {
+						p.MaxSize, err = strconv.ParseUint(fields[0], 10, 64)
+					}
+					if err == nil {
+						p.MaxAge, err = time.ParseDuration(fields[1])
+					}
+					if err == nil {
+						switch fields[2] {
+						case FIFOEvictByLargestSeqNum.String():
+							p.EvictionOrder = FIFOEvictByLargestSeqNum
+						case FIFOEvictByCreationTime.String():
+							p.EvictionOrder = FIFOEvictByCreationTime
+						default:
+							err = errors.Newf("unrecognized eviction order: %s", fields[2])
+						}
+					}
+					if err == nil {
+						o.Experimental.CompactionPicker = FIFOCompactionPicker(p)
+					} else {
+						err = errors.Wrapf(err, "unexpected fifo compaction picker arguments: %s", value)
+					}
+				default:
+					err = errors.Newf("unrecognized compaction picker: %s", value)
+				}
 			case "delete_range_flush_delay":
 				// NB: This is a deprecated serialization of the
 				// `flush_delay_delete_range`.
@@ -1868,6 +2177,31 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 			}
 			return err
 
+		case section == "Value Separation":
+			var policy ValueSeparationPolicy
+			if o.Experimental.ValueSeparationPolicy != nil {
+				policy = o.Experimental.ValueSeparationPolicy()
+			}
+			var err error
+			switch key {
+			case "enabled":
+				policy.Enabled, err = strconv.ParseBool(value)
+			case "minimum_size":
+				policy.MinimumSize, err = strconv.Atoi(value)
+			case "max_blob_reference_depth":
+				policy.MaxBlobReferenceDepth, err = strconv.Atoi(value)
+			case "garbage_ratio_threshold":
+				policy.GarbageRatioThreshold, err = strconv.ParseFloat(value, 64)
+			default:
+				if hooks != nil && hooks.SkipUnknown != nil && hooks.SkipUnknown(section+"."+key, value) {
+					return nil
+				}
+				return errors.Errorf("pebble: unknown option: %s.%s",
+					errors.Safe(section), errors.Safe(key))
+			}
+			o.Experimental.ValueSeparationPolicy = func() ValueSeparationPolicy { return policy }
+			return err
+
 		case strings.HasPrefix(section, "Level "):
 			var index int
 			if n, err := fmt.Sscanf(section, `Level "%d"`, &index); err != nil {
@@ -1895,18 +2229,13 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 			case "block_size_threshold":
 				l.BlockSizeThreshold, err = strconv.Atoi(value)
 			case "compression":
-				switch value {
-				case "Default":
-					l.Compression = func() Compression { return DefaultCompression }
-				case "NoCompression":
-					l.Compression = func() Compression { return NoCompression }
-				case "Snappy":
-					l.Compression = func() Compression { return SnappyCompression }
-				case "ZSTD":
-					l.Compression = func() Compression { return ZstdCompression }
-				default:
+				c := block.CompressionFromString(value)
+				if c == DefaultCompression && value != "Default" {
 					return errors.Errorf("pebble: unknown compression: %q", errors.Safe(value))
 				}
+				l.Compression = func() Compression { return c }
+			case "compression_dict_size":
+				l.CompressionDictSize, err = strconv.Atoi(value)
 			case "filter_policy":
 				if hooks != nil && hooks.NewFilterPolicy != nil {
 					l.FilterPolicy, err = hooks.NewFilterPolicy(value)
@@ -1915,6 +2244,8 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				switch value {
 				case "table":
 					l.FilterType = TableFilter
+				case "range":
+					l.FilterType = RangeFilter
 				default:
 					return errors.Errorf("pebble: unknown filter type: %q", errors.Safe(value))
 				}
@@ -1922,6 +2253,8 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				l.IndexBlockSize, err = strconv.Atoi(value)
 			case "target_file_size":
 				l.TargetFileSize, err = strconv.ParseInt(value, 10, 64)
+			case "disable_value_separation":
+				l.DisableValueSeparation, err = strconv.ParseBool(value)
 			default:
 				if hooks != nil && hooks.SkipUnknown != nil && hooks.SkipUnknown(section+"."+key, value) {
 					return nil
@@ -2025,6 +2358,40 @@ func (o *Options) Validate() error {
 		fmt.Fprintf(&buf, "FormatMajorVersion (%d) when CreateOnShared is set must be at least %d\n",
 			o.FormatMajorVersion, FormatMinForSharedObjects)
 	}
+	if o.Experimental.ValueSeparationPolicy != nil {
+		if policy := o.Experimental.ValueSeparationPolicy(); policy.Enabled {
+			if policy.MinimumSize <= 0 {
+				fmt.Fprintf(&buf, "ValueSeparationPolicy.MinimumSize (%d) must be > 0\n", policy.MinimumSize)
+			}
+			if policy.MaxBlobReferenceDepth <= 0 {
+				fmt.Fprintf(&buf, "ValueSeparationPolicy.MaxBlobReferenceDepth (%d) must be > 0\n", policy.MaxBlobReferenceDepth)
+			}
+			if policy.GarbageRatioThreshold < 0 || policy.GarbageRatioThreshold > 1 {
+				fmt.Fprintf(&buf, "ValueSeparationPolicy.GarbageRatioThreshold (%f) must be in [0, 1]\n", policy.GarbageRatioThreshold)
+			}
+		}
+	}
+	names := make(map[string]struct{}, len(o.Keyspaces))
+	for i := range o.Keyspaces {
+		name := o.Keyspaces[i].Name
+		if _, ok := names[name]; ok || name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			fmt.Fprintf(&buf, "Keyspaces name %q must be unique, non-empty and a valid directory name\n", name)
+		}
+		names[name] = struct{}{}
+	}
+	for i := range o.Levels {
+		if o.Levels[i].FilterType != RangeFilter {
+			continue
+		}
+		if _, ok := o.Levels[i].FilterPolicy.(RangeFilterPolicy); !ok {
+			fmt.Fprintf(&buf, "Levels[%d].FilterPolicy must implement RangeFilterPolicy when FilterType is %s\n",
+				i, RangeFilter)
+		}
+		if o.Comparer.Name != DefaultComparer.Name {
+			fmt.Fprintf(&buf, "Levels[%d].FilterType %s requires the %s Comparer\n",
+				i, RangeFilter, DefaultComparer.Name)
+		}
+	}
 	if len(o.KeySchemas) > 0 {
 		if o.KeySchema == "" {
 			fmt.Fprintf(&buf, "KeySchemas is set but KeySchema is not\n")
@@ -2078,6 +2445,10 @@ func (o *Options) MakeWriterOptions(level int, format sstable.TableFormat) sstab
 	writerOpts.BlockSize = levelOpts.BlockSize
 	writerOpts.BlockSizeThreshold = levelOpts.BlockSizeThreshold
 	writerOpts.Compression = resolveDefaultCompression(levelOpts.Compression())
+	if !format.SupportsCompression(writerOpts.Compression) {
+		writerOpts.Compression = SnappyCompression
+	}
+	writerOpts.CompressionDictSize = levelOpts.CompressionDictSize
 	writerOpts.FilterPolicy = levelOpts.FilterPolicy
 	writerOpts.FilterType = levelOpts.FilterType
 	writerOpts.IndexBlockSize = levelOpts.IndexBlockSize
@@ -2089,7 +2460,7 @@ func (o *Options) MakeWriterOptions(level int, format sstable.TableFormat) sstab
 }
 
 func resolveDefaultCompression(c Compression) Compression {
-	if c <= DefaultCompression || c >= block.NCompression {
+	if c.Algorithm() <= DefaultCompression || c.Algorithm() >= block.NCompression {
 		c = SnappyCompression
 	}
 	return c
@@ -283,6 +283,7 @@ func TestOptionsParse(t *testing.T) {
 			opts.Levels[1].BlockSize = 2048
 			opts.Levels[2].BlockSize = 4096
 			opts.Experimental.CompactionDebtConcurrency = 100
+			opts.Experimental.CompactionPicker = TieredCompactionPicker(TieredCompactionOptions{SizeRatio: 5, MaxSizeAmplificationPercent: 150})
 			opts.FlushDelayDeleteRange = 10 * time.Second
 			opts.FlushDelayRangeKey = 11 * time.Second
 			opts.Experimental.LevelMultiplier = 5
@@ -309,6 +310,7 @@ func TestOptionsParse(t *testing.T) {
 				t.Fatalf("expected\n%s\nbut found\n%s", str, parsedStr)
 			}
 			require.Nil(t, parsedOptions.Cache)
+			require.Equal(t, opts.Experimental.CompactionPicker, parsedOptions.Experimental.CompactionPicker)
 		})
 	}
 }
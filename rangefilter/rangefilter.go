@@ -0,0 +1,266 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package rangefilter implements range filters, which can determine that a
+// table contains no keys within a range.
+//
+// The filter is a truncated trie over the keys added to it, in the style of
+// SuRF (Zhang et al., "SuRF: Practical Range Query Filtering with Fast
+// Succinct Tries", SIGMOD 2018). Each key is truncated to the shortest prefix
+// that distinguishes it from its neighbors, followed by a configurable number
+// of additional "real suffix" bytes that reduce the false positive rate. Keys
+// short enough to be stored in full are marked as such, allowing queries to
+// compare against them exactly. Rather
+// than SuRF's succinct trie encoding, the truncated keys are stored in sorted
+// order and prefix-compressed, with restart points that allow binary search.
+//
+// Truncated keys are compared bytewise, so range filters may only be used
+// with a Comparer that orders key prefixes bytewise.
+package rangefilter // import "github.com/cockroachdb/pebble/rangefilter"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// restartInterval is the number of entries between restart points. Entries at
+// restart points store their key in full.
+const restartInterval = 16
+
+// FilterPolicy implements the RangeFilterPolicy interface from the pebble
+// package.
+//
+// The integer value is the number of bytes of each key stored beyond the
+// prefix that distinguishes it from its neighbors. Larger values reduce the
+// false positive rate of queries whose bounds share a prefix with keys in the
+// filter, at the cost of a larger filter. A good value is 1.
+type FilterPolicy int
+
+var _ base.RangeFilterPolicy = FilterPolicy(0)
+
+// Name implements the pebble.FilterPolicy interface.
+func (p FilterPolicy) Name() string {
+	return "pebble.TruncatedTrieRangeFilter"
+}
+
+// MayContain implements the pebble.FilterPolicy interface.
+func (p FilterPolicy) MayContain(ftype base.FilterType, f, key []byte) bool {
+	switch ftype {
+	case base.RangeFilter:
+		t, full, ok := rangeFilter(f).seek(key)
+		if full {
+			return ok && bytes.Equal(key, t)
+		}
+		return ok && bytes.HasPrefix(key, t)
+	default:
+		panic(fmt.Sprintf("unknown filter type: %v", ftype))
+	}
+}
+
+// MayContainRange implements the pebble.RangeFilterPolicy interface.
+func (p FilterPolicy) MayContainRange(f, lower, upper []byte) bool {
+	t, _, ok := rangeFilter(f).seek(lower)
+	// Every key with the prefix t is >= t, so if t > upper, no key in the
+	// filter is within the range.
+	return ok && (upper == nil || bytes.Compare(t, upper) <= 0)
+}
+
+// NewWriter implements the pebble.FilterPolicy interface.
+func (p FilterPolicy) NewWriter(ftype base.FilterType) base.FilterWriter {
+	switch ftype {
+	case base.RangeFilter:
+		return &filterWriter{suffixLen: int(p)}
+	default:
+		panic(fmt.Sprintf("unknown filter type: %v", ftype))
+	}
+}
+
+// rangeFilter is an encoded range filter. It is a sequence of entries followed
+// by the offsets of the restart points and the number of restart points, each
+// a little-endian uint32. Each entry is encoded as a header, the length of the
+// remainder of the key and the remainder of the key. The header holds the
+// length of the prefix shared with the previous entry's key, shifted left by
+// one, and a low bit that is set if the entry is a full (untruncated) key.
+type rangeFilter []byte
+
+// seek returns the first truncated key t in the filter that may be the prefix
+// of a key >= lower, and whether t is a full key. If lower is nil, seek returns
+// the first truncated key. It returns false if there is no such key.
+//
+// A corrupt filter is treated as containing all keys.
+func (f rangeFilter) seek(lower []byte) (t []byte, full bool, ok bool) {
+	if len(f) == 0 {
+		return nil, false, false
+	}
+	if len(f) < 4 {
+		return nil, false, true
+	}
+	numRestarts := int(binary.LittleEndian.Uint32(f[len(f)-4:]))
+	restartsOff := len(f) - 4 - 4*numRestarts
+	if numRestarts == 0 || restartsOff < 0 {
+		return nil, false, true
+	}
+	restartOffset := func(i int) int {
+		return int(binary.LittleEndian.Uint32(f[restartsOff+4*i:]))
+	}
+	entries := f[:restartsOff]
+
+	// Find the first restart point whose key is not before lower. The key we
+	// are looking for is either in the preceding restart interval, or is the
+	// key at the restart point.
+	var corrupt bool
+	i := sort.Search(numRestarts, func(i int) bool {
+		off := restartOffset(i)
+		if off >= len(entries) {
+			corrupt = true
+			return true
+		}
+		// Restart entries share no prefix with the previous entry.
+		shared, key, full, _, ok := decodeEntry(entries[off:])
+		if !ok || shared != 0 {
+			corrupt = true
+			return true
+		}
+		return !before(key, full, lower)
+	})
+	if corrupt {
+		return nil, false, true
+	}
+	off := 0
+	if i > 0 {
+		off = restartOffset(i - 1)
+	}
+	var key []byte
+	for off < len(entries) {
+		shared, unshared, full, n, ok := decodeEntry(entries[off:])
+		if !ok || shared > len(key) {
+			return nil, false, true
+		}
+		key = append(key[:shared], unshared...)
+		if !before(key, full, lower) {
+			return key, full, true
+		}
+		off += n
+	}
+	return nil, false, false
+}
+
+// before returns true if every key with the prefix t is < lower. If full is
+// true, t is the only such key.
+func before(t []byte, full bool, lower []byte) bool {
+	c := bytes.Compare(t, lower)
+	if full {
+		return c < 0
+	}
+	return c < 0 && !bytes.HasPrefix(lower, t)
+}
+
+// decodeEntry decodes the entry at the beginning of b. It returns the length of
+// the prefix the entry's key shares with the previous entry's key, the
+// remainder of the key, whether the key is a full key and the encoded length of
+// the entry.
+func decodeEntry(b []byte) (shared int, unshared []byte, full bool, n int, ok bool) {
+	h, n1 := binary.Uvarint(b)
+	if n1 <= 0 {
+		return 0, nil, false, 0, false
+	}
+	u, n2 := binary.Uvarint(b[n1:])
+	if n2 <= 0 || u > uint64(len(b)-n1-n2) || h>>1 > math.MaxInt32 {
+		return 0, nil, false, 0, false
+	}
+	n = n1 + n2 + int(u)
+	return int(h >> 1), b[n1+n2 : n], h&1 == 1, n, true
+}
+
+// filterWriter builds a range filter. Keys must be added in increasing order.
+type filterWriter struct {
+	suffixLen int
+	// cur is the most recently added key, which is not yet written since its
+	// truncated length depends on the next key.
+	cur    []byte
+	hasCur bool
+	// curShared is the length of the prefix cur shares with the previous key.
+	curShared int
+
+	buf        []byte
+	lastEntry  []byte
+	restarts   []uint32
+	numEntries int
+}
+
+var _ base.FilterWriter = (*filterWriter)(nil)
+
+// AddKey implements the base.FilterWriter interface.
+func (w *filterWriter) AddKey(key []byte) {
+	if w.hasCur {
+		if bytes.Equal(key, w.cur) {
+			return
+		}
+		shared := sharedPrefixLen(w.cur, key)
+		w.writeEntry(w.cur, max(w.curShared, shared))
+		w.curShared = shared
+	}
+	w.cur = append(w.cur[:0], key...)
+	w.hasCur = true
+}
+
+// writeEntry writes key, truncated to the shortest prefix that distinguishes
+// it from its neighbors, followed by the suffix bytes. The key shares a prefix
+// of length shared with one of its neighbors, and no longer prefix with the
+// other.
+func (w *filterWriter) writeEntry(key []byte, shared int) {
+	t := key[:min(len(key), shared+1+w.suffixLen)]
+	var entryShared int
+	if w.numEntries%restartInterval == 0 {
+		w.restarts = append(w.restarts, uint32(len(w.buf)))
+	} else {
+		entryShared = sharedPrefixLen(w.lastEntry, t)
+	}
+	h := uint64(entryShared) << 1
+	if len(t) == len(key) {
+		h |= 1
+	}
+	w.buf = binary.AppendUvarint(w.buf, h)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(t)-entryShared))
+	w.buf = append(w.buf, t[entryShared:]...)
+	w.lastEntry = append(w.lastEntry[:0], t...)
+	w.numEntries++
+}
+
+// Finish implements the base.FilterWriter interface.
+func (w *filterWriter) Finish(dst []byte) []byte {
+	if w.hasCur {
+		w.writeEntry(w.cur, w.curShared)
+	}
+	if w.numEntries > 0 {
+		dst = append(dst, w.buf...)
+		for _, r := range w.restarts {
+			dst = binary.LittleEndian.AppendUint32(dst, r)
+		}
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(len(w.restarts)))
+	}
+	*w = filterWriter{
+		suffixLen: w.suffixLen,
+		cur:       w.cur[:0],
+		buf:       w.buf[:0],
+		lastEntry: w.lastEntry[:0],
+		restarts:  w.restarts[:0],
+	}
+	return dst
+}
+
+func sharedPrefixLen(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
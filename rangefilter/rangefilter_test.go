@@ -0,0 +1,149 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package rangefilter
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/stretchr/testify/require"
+)
+
+func buildFilter(p FilterPolicy, keys [][]byte) []byte {
+	w := p.NewWriter(base.RangeFilter)
+	for _, k := range keys {
+		w.AddKey(k)
+	}
+	return w.Finish(nil)
+}
+
+func TestRangeFilter(t *testing.T) {
+	keys := [][]byte{
+		[]byte("apple"), []byte("apple"), []byte("applesauce"), []byte("banana"),
+		[]byte("blueberry"), []byte("cherry"),
+	}
+	f := buildFilter(FilterPolicy(0), keys)
+	testCases := []struct {
+		lower, upper string
+		want         bool
+	}{
+		{"a", "apple", true},
+		{"apple", "apple", true},
+		{"applet", "az", false},
+		{"b", "b", false},
+		{"ba", "bz", true},
+		{"bc", "bk", false},
+		{"bc", "bz", true},
+		{"c", "cz", true},
+		{"d", "", false},
+		{"", "a", false},
+	}
+	for _, tc := range testCases {
+		var lower, upper []byte
+		if tc.lower != "" {
+			lower = []byte(tc.lower)
+		}
+		if tc.upper != "" {
+			upper = []byte(tc.upper)
+		}
+		require.Equal(t, tc.want, FilterPolicy(0).MayContainRange(f, lower, upper),
+			"[%q, %q]", tc.lower, tc.upper)
+	}
+	for _, k := range keys {
+		require.True(t, FilterPolicy(0).MayContain(base.RangeFilter, f, k))
+	}
+	require.False(t, FilterPolicy(0).MayContain(base.RangeFilter, f, []byte("app")))
+	require.False(t, FilterPolicy(0).MayContain(base.RangeFilter, f, []byte("date")))
+
+	// Entries may share more bytes with the previous entry than remain in
+	// the filter.
+	keys = keys[:0]
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Appendf(nil, "cherry%03d", i))
+	}
+	f = buildFilter(FilterPolicy(1), keys)
+	require.True(t, FilterPolicy(1).MayContainRange(f, []byte("cherry099"), nil))
+	require.False(t, FilterPolicy(1).MayContainRange(f, []byte("cherry100"), nil))
+
+	// An empty filter contains nothing.
+	empty := buildFilter(FilterPolicy(0), nil)
+	require.Empty(t, empty)
+	require.False(t, FilterPolicy(0).MayContainRange(empty, nil, nil))
+}
+
+// TestRangeFilterRandomized checks that range and point queries against
+// filters of random keys never return false negatives.
+func TestRangeFilterRandomized(t *testing.T) {
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("seed %d", seed)
+	rng := rand.New(rand.NewPCG(0, seed))
+
+	randKey := func() []byte {
+		k := make([]byte, 1+rng.IntN(8))
+		for i := range k {
+			k[i] = byte('a' + rng.IntN(4))
+		}
+		return k
+	}
+	var falsePositives, negatives int
+	for iter := 0; iter < 100; iter++ {
+		keys := make([][]byte, rng.IntN(200))
+		for i := range keys {
+			keys[i] = randKey()
+		}
+		slices.SortFunc(keys, bytes.Compare)
+		p := FilterPolicy(rng.IntN(3))
+		f := buildFilter(p, keys)
+
+		for q := 0; q < 200; q++ {
+			lower, upper := randKey(), randKey()
+			if bytes.Compare(lower, upper) > 0 {
+				lower, upper = upper, lower
+			}
+			want := false
+			for _, k := range keys {
+				if bytes.Compare(k, lower) >= 0 && bytes.Compare(k, upper) <= 0 {
+					want = true
+					break
+				}
+			}
+			got := p.MayContainRange(f, lower, upper)
+			if want {
+				require.True(t, got, "keys %q, range [%q, %q]", keys, lower, upper)
+			} else if got {
+				falsePositives++
+			} else {
+				negatives++
+			}
+
+			k := randKey()
+			_, found := slices.BinarySearchFunc(keys, k, bytes.Compare)
+			if found {
+				require.True(t, p.MayContain(base.RangeFilter, f, k), "keys %q, key %q", keys, k)
+			}
+		}
+	}
+	t.Logf("false positive rate: %.2f", float64(falsePositives)/float64(falsePositives+negatives))
+}
+
+func BenchmarkMayContainRange(b *testing.B) {
+	rng := rand.New(rand.NewPCG(0, 1))
+	keys := make([][]byte, 10000)
+	for i := range keys {
+		keys[i] = fmt.Appendf(nil, "user%010d", rng.IntN(1e9))
+	}
+	slices.SortFunc(keys, bytes.Compare)
+	f := buildFilter(FilterPolicy(1), keys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lower := fmt.Appendf(nil, "user%010d", rng.IntN(1e9))
+		FilterPolicy(1).MayContainRange(f, lower, append(lower, 0xff))
+	}
+}
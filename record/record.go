@@ -508,7 +508,7 @@ func (r *Reader) Offset() int64 {
 	return int64(r.blockNum)*blockSize + int64(r.end)
 }
 
-// seekRecord seeks in the underlying io.Reader such that calling r.Next
+// SeekRecord seeks in the underlying io.Reader such that calling r.Next
 // returns the record whose first chunk header starts at the provided offset.
 // Its behavior is undefined if the argument given is not such an offset, as
 // the bytes at that offset may coincidentally appear to be a valid header.
@@ -516,12 +516,12 @@ func (r *Reader) Offset() int64 {
 // It returns ErrNotAnIOSeeker if the underlying io.Reader does not implement
 // io.Seeker.
 //
-// seekRecord will fail and return an error if the Reader previously
+// SeekRecord will fail and return an error if the Reader previously
 // encountered an error, including io.EOF.
 //
 // The offset is always relative to the start of the underlying io.Reader, so
 // negative values will result in an error as per io.Seeker.
-func (r *Reader) seekRecord(offset int64) error {
+func (r *Reader) SeekRecord(offset int64) error {
 	r.seq++
 	if r.err != nil {
 		return r.err
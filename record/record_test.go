@@ -493,7 +493,7 @@ func TestSeekRecordInvalidChunk(t *testing.T) {
 	r := NewReader(bytes.NewReader(recs.buf), 0 /* logNum */)
 
 	// Seek to the FIRST/FULL chunk of the second block in the first record.
-	err = r.seekRecord(blockSize)
+	err = r.SeekRecord(blockSize)
 	if err != nil {
 		t.Fatalf("SeekRecord: %v", err)
 	}
@@ -508,7 +508,7 @@ func TestSeekRecordInvalidChunk(t *testing.T) {
 
 	// Seek 3 bytes into the second block, which is still in the middle of the first record, but not
 	// at a valid chunk boundary. Should result in an error upon calling r.Next.
-	err = r.seekRecord(blockSize + 3)
+	err = r.SeekRecord(blockSize + 3)
 	if err != nil {
 		t.Fatalf("SeekRecord: %v", err)
 	}
@@ -536,7 +536,7 @@ func TestSeekRecordEndOfFile(t *testing.T) {
 
 	r := NewReader(bytes.NewReader(recs.buf), 0 /* logNum */)
 	// Seek to the FIRST/FULL chunk of the second block in the first record.
-	err = r.seekRecord(blockSize)
+	err = r.SeekRecord(blockSize)
 	if err != nil {
 		t.Fatalf("SeekRecord: %v", err)
 	}
@@ -550,7 +550,7 @@ func TestSeekRecordEndOfFile(t *testing.T) {
 	}
 
 	// Seek to the fifth block and verify all records can be read as appropriate.
-	err = r.seekRecord(blockSize * 4)
+	err = r.SeekRecord(blockSize * 4)
 	if err != nil {
 		t.Fatalf("SeekRecord: %v", err)
 	}
@@ -571,14 +571,14 @@ func TestSeekRecordEndOfFile(t *testing.T) {
 	check(2)
 
 	// Seek back to the fourth block, and read all subsequent records and verify them.
-	err = r.seekRecord(blockSize * 3)
+	err = r.SeekRecord(blockSize * 3)
 	if err != nil {
 		t.Fatalf("SeekRecord: %v", err)
 	}
 	check(1)
 
 	// Now seek past the end of the file and verify it causes an error.
-	err = r.seekRecord(1 << 20)
+	err = r.SeekRecord(1 << 20)
 	if err == nil {
 		t.Fatalf("Seek past the end of a file didn't cause an error")
 	}
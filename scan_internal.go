@@ -1070,6 +1070,12 @@ func (i *scanInternalIterator) constructRangeKeyIter() error {
 	return nil
 }
 
+// first seeks this iterator to the first key.
+func (i *scanInternalIterator) first() bool {
+	i.iterKV = i.iter.First()
+	return i.iterKV != nil
+}
+
 // seekGE seeks this iterator to the first key that's greater than or equal
 // to the specified user key.
 func (i *scanInternalIterator) seekGE(key []byte) bool {
@@ -0,0 +1,331 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/cache"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider/sharedcache"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// SecondaryBlockCacheDevice configures a secondary block cache stored on a
+// device, typically a local flash drive. Blocks evicted from the block cache
+// (Options.Cache) because of memory pressure are spilled into the secondary
+// block cache, for both local and remote sstables, and are read back from it
+// on a block cache miss.
+type SecondaryBlockCacheDevice struct {
+	// FS and Dir are the filesystem and the directory where the cache is
+	// stored. Each DB must use its own directory. The cache contents survive
+	// restarts of the DB, but are discarded when the directory is used by
+	// another DB, including a recreated DB or one restored from a checkpoint.
+	FS  vfs.FS
+	Dir string
+	// SizeBytes is the size of the cache.
+	SizeBytes int64
+	// BlockSize is the size of the cache blocks, which must be a power of 2 of
+	// at least 1KB. Each block spilled from the block cache is stored in one or
+	// more cache blocks, so the cache block size should be close to the size of
+	// the (uncompressed) sstable blocks. If it is 0, 4KB is used.
+	BlockSize int
+	// ShardCount is the number of independent shards of the cache. If it is 0,
+	// 2*runtime.GOMAXPROCS is used.
+	ShardCount int
+	// Admission decides which of the spilled blocks are stored in the cache. If
+	// it is nil, all blocks are admitted.
+	Admission SecondaryBlockCacheAdmissionPolicy
+}
+
+// SecondaryBlockCacheAdmissionPolicy decides which of the blocks evicted from
+// the block cache are admitted into a secondary block cache.
+type SecondaryBlockCacheAdmissionPolicy interface {
+	// Admit returns true if the block of the given size, at the given offset of
+	// the given file, should be stored in the secondary block cache. It is
+	// invoked concurrently for every block evicted from the block cache, so it
+	// must be cheap.
+	Admit(fileNum base.DiskFileNum, offset uint64, size int) bool
+}
+
+// SecondaryBlockCacheAdmissionFunc is a SecondaryBlockCacheAdmissionPolicy
+// implemented by a function.
+type SecondaryBlockCacheAdmissionFunc func(fileNum base.DiskFileNum, offset uint64, size int) bool
+
+// Admit implements SecondaryBlockCacheAdmissionPolicy.
+func (f SecondaryBlockCacheAdmissionFunc) Admit(
+	fileNum base.DiskFileNum, offset uint64, size int,
+) bool {
+	return f(fileNum, offset, size)
+}
+
+// NewSecondaryBlockCacheReevictionAdmission returns an admission policy that
+// only admits the blocks that are evicted from the block cache a second time
+// within (approximately) the last n evictions. This keeps blocks that are read
+// only once (e.g. by a scan) out of the secondary block cache.
+func NewSecondaryBlockCacheReevictionAdmission(n int) SecondaryBlockCacheAdmissionPolicy {
+	// The recent evictions are sharded so that concurrent evictions don't
+	// contend on a single mutex. Each shard remembers at least
+	// minReevictionShardSize evictions, so that small policies (mostly used in
+	// tests) remember exactly the last n evictions.
+	const minReevictionShardSize = 1024
+	numShards := min(max(n/minReevictionShardSize, 1), 4*runtime.GOMAXPROCS(0))
+	shardSize := (n + numShards - 1) / numShards
+	a := &reevictionAdmission{shards: make([]reevictionShard, numShards)}
+	for i := range a.shards {
+		a.shards[i].mu.recent = make(map[reevictionKey]struct{}, shardSize)
+		a.shards[i].mu.ring = make([]reevictionKey, shardSize)
+	}
+	return a
+}
+
+type reevictionKey struct {
+	fileNum base.DiskFileNum
+	offset  uint64
+}
+
+type reevictionAdmission struct {
+	shards []reevictionShard
+}
+
+type reevictionShard struct {
+	mu struct {
+		sync.Mutex
+		// recent contains the keys in ring.
+		recent map[reevictionKey]struct{}
+		// ring contains the keys of the last len(ring) evictions of the shard
+		// that were not admitted.
+		ring []reevictionKey
+		next int
+	}
+	// Prevent false sharing between shards.
+	_ [64]byte
+}
+
+// Admit implements SecondaryBlockCacheAdmissionPolicy.
+func (a *reevictionAdmission) Admit(fileNum base.DiskFileNum, offset uint64, size int) bool {
+	k := reevictionKey{fileNum: fileNum, offset: offset}
+	s := &a.shards[0]
+	if len(a.shards) > 1 {
+		const m = 11400714819323198485
+		h := (uint64(fileNum)*m ^ offset) * m
+		s = &a.shards[(h>>32)%uint64(len(a.shards))]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.mu.recent[k]; ok {
+		return true
+	}
+	if len(s.mu.ring) == 0 {
+		return false
+	}
+	if len(s.mu.recent) == len(s.mu.ring) {
+		delete(s.mu.recent, s.mu.ring[s.mu.next])
+	}
+	s.mu.ring[s.mu.next] = k
+	s.mu.recent[k] = struct{}{}
+	s.mu.next = (s.mu.next + 1) % len(s.mu.ring)
+	return false
+}
+
+// SecondaryBlockCacheMetrics holds the metrics of a secondary block cache
+// device.
+type SecondaryBlockCacheMetrics struct {
+	SecondaryCacheMetrics
+	// The number of blocks evicted from the block cache that were not admitted
+	// by the admission policy.
+	AdmissionRejections int64
+}
+
+// secondaryBlockCache implements cache.SecondaryCache on top of one
+// sharedcache.Cache per device. Blocks are distributed across the devices
+// according to their file number and offset.
+type secondaryBlockCache struct {
+	devices []secondaryBlockCacheDevice
+}
+
+type secondaryBlockCacheDevice struct {
+	cache               *sharedcache.Cache
+	admission           SecondaryBlockCacheAdmissionPolicy
+	admissionRejections atomic.Int64
+}
+
+var _ cache.SecondaryCache = (*secondaryBlockCache)(nil)
+
+// secondaryBlockCacheIDFilename is the name of the file, in the DB directory
+// and in the directory of each secondary block cache device, holding the
+// identity that pairs the device with the DB. Cached blocks are keyed by file
+// number, and file numbers are reused by other DBs (including a DB that is
+// deleted and recreated, or restored from a checkpoint), so the persisted
+// contents of a device are only recovered if its identity matches the DB's.
+const secondaryBlockCacheIDFilename = "SECONDARY-BLOCK-CACHE-ID"
+
+// openSecondaryBlockCache opens the secondary block cache of the DB in
+// dirname. The persisted contents of a device are discarded if the device was
+// last used by another DB. The DB's identity is created if the DB doesn't
+// have one yet, unless the DB is read-only, in which case the persisted
+// contents of every device are discarded.
+func openSecondaryBlockCache(
+	devices []SecondaryBlockCacheDevice, logger base.Logger, fs vfs.FS, dirname string, readOnly bool,
+) (*secondaryBlockCache, error) {
+	id, err := readSecondaryBlockCacheID(fs, dirname)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" && !readOnly {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		id = hex.EncodeToString(b[:])
+		if err := writeSecondaryBlockCacheID(fs, dirname, id); err != nil {
+			return nil, err
+		}
+	}
+	c := &secondaryBlockCache{devices: make([]secondaryBlockCacheDevice, len(devices))}
+	for i, d := range devices {
+		blockSize := d.BlockSize
+		if blockSize == 0 {
+			blockSize = 4 << 10
+		}
+		if blockSize < 1<<10 || bits.OnesCount(uint(blockSize)) != 1 {
+			_ = c.Close()
+			return nil, errors.Errorf("pebble: invalid secondary block cache block size %d", blockSize)
+		}
+		numShards := d.ShardCount
+		if numShards == 0 {
+			numShards = 2 * runtime.GOMAXPROCS(0)
+		}
+		if err := d.FS.MkdirAll(d.Dir, 0755); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+		if devID, err := readSecondaryBlockCacheID(d.FS, d.Dir); err != nil || id == "" || devID != id {
+			// The metadata is removed before the identity is written, so that
+			// a crash in between still discards the contents on the next open.
+			err = firstError(err, sharedcache.RemoveMetadata(d.FS, d.Dir))
+			if err == nil && id != "" {
+				err = writeSecondaryBlockCacheID(d.FS, d.Dir, id)
+			}
+			if err != nil {
+				_ = c.Close()
+				return nil, err
+			}
+		}
+		// Values don't use the sharding block size, so we use the smallest one.
+		sc, err := sharedcache.Open(d.FS, logger, d.Dir, blockSize, int64(blockSize), d.SizeBytes, numShards, 0)
+		if err != nil {
+			_ = c.Close()
+			return nil, errors.Wrapf(err, "pebble: could not open secondary block cache in %q", d.Dir)
+		}
+		c.devices[i].cache = sc
+		c.devices[i].admission = d.Admission
+	}
+	return c, nil
+}
+
+// readSecondaryBlockCacheID returns the identity stored in dir, or "" if there
+// is none.
+func readSecondaryBlockCacheID(fs vfs.FS, dir string) (string, error) {
+	f, err := fs.Open(fs.PathJoin(dir, secondaryBlockCacheIDFilename))
+	if err != nil {
+		if oserror.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// writeSecondaryBlockCacheID atomically replaces the identity stored in dir.
+func writeSecondaryBlockCacheID(fs vfs.FS, dir string, id string) error {
+	filename := fs.PathJoin(dir, secondaryBlockCacheIDFilename)
+	tmpFilename := filename + ".tmp"
+	f, err := fs.Create(tmpFilename, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, id); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := errors.CombineErrors(f.Sync(), f.Close()); err != nil {
+		return err
+	}
+	if err := fs.Rename(tmpFilename, filename); err != nil {
+		return err
+	}
+	d, err := fs.OpenDir(dir)
+	if err != nil {
+		return err
+	}
+	return errors.CombineErrors(d.Sync(), d.Close())
+}
+
+func (c *secondaryBlockCache) device(
+	fileNum base.DiskFileNum, offset uint64,
+) *secondaryBlockCacheDevice {
+	if len(c.devices) == 1 {
+		return &c.devices[0]
+	}
+	const m = 11400714819323198485
+	h := (uint64(fileNum)*m ^ offset) * m
+	return &c.devices[(h>>32)%uint64(len(c.devices))]
+}
+
+// Spill is part of the cache.SecondaryCache interface. Only the block data is
+// stored, without the block metadata.
+func (c *secondaryBlockCache) Spill(fileNum base.DiskFileNum, offset uint64, buf []byte) {
+	if len(buf) <= block.MetadataSize {
+		return
+	}
+	data := buf[block.MetadataSize:]
+	d := c.device(fileNum, offset)
+	if d.admission != nil && !d.admission.Admit(fileNum, offset, len(data)) {
+		d.admissionRejections.Add(1)
+		return
+	}
+	d.cache.TrySetValue(fileNum, offset, data)
+}
+
+// Get is part of the cache.SecondaryCache interface.
+func (c *secondaryBlockCache) Get(fileNum base.DiskFileNum, offset uint64) ([]byte, bool) {
+	return c.device(fileNum, offset).cache.GetValue(fileNum, offset)
+}
+
+func (c *secondaryBlockCache) metrics() []SecondaryBlockCacheMetrics {
+	m := make([]SecondaryBlockCacheMetrics, len(c.devices))
+	for i := range c.devices {
+		m[i].SecondaryCacheMetrics = c.devices[i].cache.Metrics()
+		m[i].AdmissionRejections = c.devices[i].admissionRejections.Load()
+	}
+	return m
+}
+
+// Close closes the devices of the cache. The cache must no longer be set on
+// the block cache handle.
+func (c *secondaryBlockCache) Close() error {
+	var err error
+	for i := range c.devices {
+		if c.devices[i].cache != nil {
+			err = firstError(err, c.devices[i].cache.Close())
+		}
+	}
+	return err
+}
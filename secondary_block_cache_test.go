@@ -0,0 +1,123 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/cache"
+	"github.com/cockroachdb/pebble/internal/testutils"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecondaryBlockCache(t *testing.T) {
+	mem := vfs.NewMem()
+	// Memtables reserve memory in the block cache, so the block cache only has
+	// room for a fraction of the data.
+	c := cache.New(1 << 20)
+	defer c.Unref()
+	opts := &Options{
+		FS:           mem,
+		Cache:        c,
+		MemTableSize: 256 << 10,
+	}
+	opts.Experimental.SecondaryBlockCache = []SecondaryBlockCacheDevice{{
+		FS:         mem,
+		Dir:        "flash",
+		SizeBytes:  64 << 20,
+		BlockSize:  8 << 10,
+		ShardCount: 4,
+	}}
+	d, err := Open("db", opts)
+	require.NoError(t, err)
+
+	// The values are random, so that they don't compress.
+	const numKeys = 20000
+	value := func(i int) []byte {
+		return testutils.RandBytes(rand.New(rand.NewPCG(uint64(i), 0)), 100)
+	}
+	for i := 0; i < numKeys; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key-%06d", i)), value(i), nil))
+	}
+	require.NoError(t, d.Flush())
+
+	scan := func() {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		n := 0
+		for valid := iter.First(); valid; valid = iter.Next() {
+			require.Equal(t, value(n), iter.Value())
+			n++
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, numKeys, n)
+	}
+	// The first scan evicts blocks from the block cache (which is much smaller
+	// than the data), which are spilled into the secondary block cache in the
+	// background. The following scans find them there.
+	scan()
+	require.Eventually(t, func() bool {
+		scan()
+		return d.Metrics().SecondaryBlockCache[0].ReadsWithFullHit > 0
+	}, 10*time.Second, time.Millisecond)
+	m := d.Metrics()
+	require.Len(t, m.SecondaryBlockCache, 1)
+	require.NotZero(t, m.SecondaryBlockCache[0].Count)
+	require.NotZero(t, m.SecondaryBlockCache[0].ReadsWithFullHit)
+	require.Contains(t, m.String(), "Secondary block cache")
+	var inSecondaryCache uint64
+	for _, s := range m.CategoryStats {
+		inSecondaryCache += s.CategoryStats.BlockBytesInSecondaryCache
+	}
+	require.NotZero(t, inSecondaryCache)
+	require.NoError(t, d.Close())
+
+	// The secondary block cache survives a restart.
+	d, err = Open("db", opts)
+	require.NoError(t, err)
+	require.NotZero(t, d.Metrics().SecondaryBlockCache[0].RecoveredBlocks)
+	scan()
+	require.NotZero(t, d.Metrics().SecondaryBlockCache[0].WarmBlockHits)
+	require.NoError(t, d.Close())
+
+	// A freshly created DB reuses the file numbers of the first DB, so it
+	// discards the contents of the secondary block cache.
+	d, err = Open("db2", opts)
+	require.NoError(t, err)
+	require.Zero(t, d.Metrics().SecondaryBlockCache[0].RecoveredBlocks)
+	require.NoError(t, d.Close())
+
+	// The cache was last used by the second DB, so the first DB discards its
+	// contents too.
+	d, err = Open("db", opts)
+	require.NoError(t, err)
+	require.Zero(t, d.Metrics().SecondaryBlockCache[0].RecoveredBlocks)
+	scan()
+	require.NoError(t, d.Close())
+}
+
+func TestSecondaryBlockCacheReevictionAdmission(t *testing.T) {
+	a := NewSecondaryBlockCacheReevictionAdmission(2)
+	require.False(t, a.Admit(base.DiskFileNum(1), 0, 100))
+	require.True(t, a.Admit(base.DiskFileNum(1), 0, 100))
+	require.False(t, a.Admit(base.DiskFileNum(1), 10, 100))
+	require.False(t, a.Admit(base.DiskFileNum(2), 0, 100))
+	// The first eviction of the block at offset 0 was forgotten.
+	require.False(t, a.Admit(base.DiskFileNum(1), 0, 100))
+	require.True(t, a.Admit(base.DiskFileNum(2), 0, 100))
+
+	// Large policies are sharded, but still admit blocks evicted twice in a
+	// row.
+	a = NewSecondaryBlockCacheReevictionAdmission(1 << 20)
+	for i := 0; i < 1000; i++ {
+		require.False(t, a.Admit(base.DiskFileNum(i), uint64(i), 100))
+		require.True(t, a.Admit(base.DiskFileNum(i), uint64(i), 100))
+	}
+}
@@ -54,7 +54,7 @@ type FileWriterOptions struct {
 }
 
 func (o *FileWriterOptions) ensureDefaults() {
-	if o.Compression <= block.DefaultCompression || o.Compression >= block.NCompression {
+	if o.Compression.Algorithm() <= block.DefaultCompression || o.Compression.Algorithm() >= block.NCompression {
 		o.Compression = block.SnappyCompression
 	}
 	if o.ChecksumType == block.ChecksumTypeNone {
@@ -98,6 +98,53 @@ func (h Handle) SafeFormat(w redact.SafePrinter, _ rune) {
 		h.FileNum, h.BlockNum, h.OffsetInBlock, h.OffsetInBlock+h.ValueLen)
 }
 
+// MaxInlineHandleLength is the maximum length of a Handle encoded through
+// EncodeInline, excluding the value prefix byte written by the sstable writer.
+const MaxInlineHandleLength = 3*binary.MaxVarintLen32 + binary.MaxVarintLen64
+
+// EncodeInline encodes the Handle into dst in the variable-width encoding used
+// when storing a reference to a blob value within an sstable, returning the
+// number of bytes encoded. The value length and file number are encoded first,
+// followed by the block number and offset in the same encoding used by
+// valblk.EncodeHandle, permitting the remainder to be decoded with
+// valblk.DecodeRemainingHandle.
+//
+// dst must be at least MaxInlineHandleLength bytes long.
+func (h Handle) EncodeInline(dst []byte) int {
+	n := binary.PutUvarint(dst, uint64(h.ValueLen))
+	n += binary.PutUvarint(dst[n:], uint64(h.FileNum))
+	n += binary.PutUvarint(dst[n:], uint64(h.BlockNum))
+	n += binary.PutUvarint(dst[n:], uint64(h.OffsetInBlock))
+	return n
+}
+
+// DecodeInlineHandlePrefix decodes the value length and file number from the
+// beginning of a Handle encoded through EncodeInline. It returns the remainder
+// of the encoded handle, which holds the block number and offset and may be
+// passed to ValueFetcher.Fetch.
+func DecodeInlineHandlePrefix(
+	src []byte,
+) (valueLen uint32, fileNum base.DiskFileNum, remainder []byte) {
+	valueLen, src = valblk.DecodeLenFromHandle(src)
+	v, n := binary.Uvarint(src)
+	if n <= 0 {
+		panic(errors.AssertionFailedf("blob: unable to decode inline handle file number"))
+	}
+	return valueLen, base.DiskFileNum(v), src[n:]
+}
+
+// DecodeInlineHandle decodes a Handle encoded through EncodeInline.
+func DecodeInlineHandle(src []byte) Handle {
+	valueLen, fileNum, remainder := DecodeInlineHandlePrefix(src)
+	vh := valblk.DecodeRemainingHandle(remainder)
+	return Handle{
+		FileNum:       fileNum,
+		BlockNum:      vh.BlockNum,
+		OffsetInBlock: vh.OffsetInBlock,
+		ValueLen:      valueLen,
+	}
+}
+
 // A FileWriter writes a blob file.
 type FileWriter struct {
 	fileNum      base.DiskFileNum
@@ -158,6 +205,12 @@ func (w *FileWriter) AddValue(v []byte) Handle {
 	}
 }
 
+// EstimatedSize returns an estimate of the disk space consumed by the blob file
+// if it were closed now.
+func (w *FileWriter) EstimatedSize() uint64 {
+	return w.stats.FileLen + uint64(w.b.Size())
+}
+
 func (w *FileWriter) flush() {
 	pb, bh := w.b.CompressAndChecksum()
 	compressedLen := uint64(pb.LengthWithoutTrailer())
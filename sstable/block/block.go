@@ -327,6 +327,14 @@ type ReadEnv struct {
 	// is managed by the fileCacheContainer.
 	Stats     *base.InternalIteratorStats
 	IterStats *CategoryStatsShard
+	// LevelStats, if non-nil, additionally accumulates the block stats of the
+	// iterators of a single level of the LSM. It is used to attribute the
+	// blocks read by a traced iterator to levels.
+	LevelStats *CategoryStats
+	// Category is the category of the reads. The block reads are performed
+	// with a context that carries it (see base.WithCategory), so that they can
+	// be scheduled according to it.
+	Category Category
 
 	// BufferPool is not-nil if we read blocks into a buffer pool and not into the
 	// cache. This is used during compactions.
@@ -348,6 +356,24 @@ func (env *ReadEnv) BlockServedFromCache(blockLength uint64) {
 	if env.IterStats != nil {
 		env.IterStats.Accumulate(blockLength, blockLength, 0)
 	}
+	if env.LevelStats != nil {
+		env.LevelStats.aggregate(blockLength, blockLength, 0)
+	}
+}
+
+// BlockServedFromSecondaryCache updates the stats when a block was not in the
+// cache, but was found in the secondary cache.
+func (env *ReadEnv) BlockServedFromSecondaryCache(blockLength uint64) {
+	if env.Stats != nil {
+		env.Stats.BlockBytes += blockLength
+	}
+	if env.IterStats != nil {
+		env.IterStats.AccumulateSecondaryCacheHit(blockLength)
+	}
+	if env.LevelStats != nil {
+		env.LevelStats.aggregate(blockLength, 0, 0)
+		env.LevelStats.BlockBytesInSecondaryCache += blockLength
+	}
 }
 
 // BlockRead updates the stats when a block had to be read.
@@ -359,6 +385,9 @@ func (env *ReadEnv) BlockRead(blockLength uint64, readDuration time.Duration) {
 	if env.IterStats != nil {
 		env.IterStats.Accumulate(blockLength, 0, readDuration)
 	}
+	if env.LevelStats != nil {
+		env.LevelStats.aggregate(blockLength, 0, readDuration)
+	}
 }
 
 // maybeReportCorruption calls the ReportCorruptionFn if the given error
@@ -375,6 +404,7 @@ type Reader struct {
 	readable     objstorage.Readable
 	opts         ReaderOptions
 	checksumType ChecksumType
+	dict         *CompressionDict
 }
 
 // ReaderOptions configures a block reader.
@@ -397,6 +427,18 @@ func (r *Reader) Init(readable objstorage.Readable, ro ReaderOptions, checksumTy
 	r.checksumType = checksumType
 }
 
+// SetCompressionDict sets the dictionary used to decompress blocks compressed
+// with a dictionary. It must be called before any such blocks are read.
+func (r *Reader) SetCompressionDict(dict *CompressionDict) {
+	r.dict = dict
+}
+
+// CompressionDict returns the dictionary used to decompress blocks compressed
+// with a dictionary, or nil if there is none.
+func (r *Reader) CompressionDict() *CompressionDict {
+	return r.dict
+}
+
 // FileNum returns the file number of the file being read.
 func (r *Reader) FileNum() base.DiskFileNum {
 	return r.opts.CacheOpts.FileNum
@@ -461,6 +503,14 @@ func (r *Reader) Read(
 		return CacheBufferHandle(cv), nil
 	}
 
+	if sc := r.opts.CacheOpts.CacheHandle.SecondaryCache(); sc != nil {
+		if value, ok := r.readFromSecondaryCache(sc, bh, initBlockMetadataFn); ok {
+			env.BlockServedFromSecondaryCache(bh.Length)
+			crh.SetReadValue(value.v)
+			return value.MakeHandle(), nil
+		}
+	}
+
 	value, err := r.doRead(ctx, env, readHandle, bh, initBlockMetadataFn)
 	if err != nil {
 		crh.SetReadError(err)
@@ -479,6 +529,26 @@ func recordCacheHit(ctx context.Context, env ReadEnv, readHandle objstorage.Read
 	env.BlockServedFromCache(bh.Length)
 }
 
+// readFromSecondaryCache retrieves the block from the secondary cache, which
+// stores the block data of the blocks evicted from the block cache (see
+// cache.SecondaryCache).
+func (r *Reader) readFromSecondaryCache(
+	sc cache.SecondaryCache, bh Handle, initBlockMetadataFn func(*Metadata, []byte) error,
+) (Value, bool) {
+	data, ok := sc.Get(r.opts.CacheOpts.FileNum, bh.Offset)
+	if !ok {
+		return Value{}, false
+	}
+	value := Alloc(len(data), nil)
+	copy(value.BlockData(), data)
+	if err := initBlockMetadataFn(value.BlockMetadata(), value.BlockData()); err != nil {
+		// The block is read from the file instead.
+		value.Release()
+		return Value{}, false
+	}
+	return value, true
+}
+
 // TODO(sumeer): should the threshold be configurable.
 const slowReadTracingThreshold = 5 * time.Millisecond
 
@@ -500,13 +570,17 @@ func (r *Reader) doRead(
 		defer sema.Release(1)
 	}
 
+	readCtx := ctx
+	if env.Category != CategoryUnknown {
+		readCtx = base.WithCategory(ctx, env.Category)
+	}
 	compressed := Alloc(int(bh.Length+TrailerLen), env.BufferPool)
 	readStopwatch := makeStopwatch()
 	var err error
 	if readHandle != nil {
-		err = readHandle.ReadAt(ctx, compressed.BlockData(), int64(bh.Offset))
+		err = readHandle.ReadAt(readCtx, compressed.BlockData(), int64(bh.Offset))
 	} else {
-		err = r.readable.ReadAt(ctx, compressed.BlockData(), int64(bh.Offset))
+		err = r.readable.ReadAt(readCtx, compressed.BlockData(), int64(bh.Offset))
 	}
 	readDuration := readStopwatch.stop()
 	// Call IsTracingEnabled to avoid the allocations of boxing integers into an
@@ -543,7 +617,7 @@ func (r *Reader) doRead(
 			return Value{}, err
 		}
 		decompressed = Alloc(decodedLen, env.BufferPool)
-		err = DecompressInto(typ, compressed.BlockData()[prefixLen:], decompressed.BlockData())
+		err = r.dict.DecompressInto(typ, compressed.BlockData()[prefixLen:], decompressed.BlockData())
 		compressed.Release()
 		if err != nil {
 			decompressed.Release()
@@ -9,29 +9,23 @@ import (
 	"runtime"
 	"slices"
 	"sync"
-	"sync/atomic"
 	"time"
 	"unsafe"
 
-	"github.com/cockroachdb/errors"
-	"github.com/cockroachdb/redact"
+	"github.com/cockroachdb/pebble/internal/base"
 )
 
 // Category is a user-understandable string, where stats are aggregated for
-// each category. The cardinality of this should be low, say < 20. The prefix
-// "pebble-" is reserved for internal Pebble categories.
-//
-// Examples of categories that can be useful in the CockroachDB context are:
-// sql-user, sql-stats, raft, rangefeed, mvcc-gc, range-snapshot.
-type Category uint8
+// each category. See base.Category.
+type Category = base.Category
 
 // CategoryUnknown is the unknown category. It has the latency-sensitive QoS
 // level.
-const CategoryUnknown Category = 0
+const CategoryUnknown = base.CategoryUnknown
 
 // CategoryMax is the maximum value of a category, and is also the maximum
 // number of categories that can be registered.
-const CategoryMax = 30
+const CategoryMax = base.CategoryMax
 
 // shardPadding pads each shard to 64 bytes so they don't share a cache line.
 const shardPadding = 64 - unsafe.Sizeof(CategoryStatsShard{})
@@ -42,35 +36,12 @@ type paddedCategoryStatsShard struct {
 	_ [shardPadding]byte
 }
 
-func (c Category) String() string {
-	return categories[c].name
-}
-
-// QoSLevel returns the QoSLevel associated with this Category.
-func (c Category) QoSLevel() QoSLevel {
-	return categories[c].qosLevel
-}
-
-// SafeFormat implements the redact.SafeFormatter interface.
-func (c Category) SafeFormat(p redact.SafePrinter, verb rune) {
-	p.SafeString(redact.SafeString(c.String()))
-}
-
 // RegisterCategory registers a new category. Each category has a name and an
 // associated QoS level. The category name must be unique.
 //
 // Only CategoryMax categories can be registered in total.
 func RegisterCategory(name string, qosLevel QoSLevel) Category {
-	if categoriesList != nil {
-		panic("ReigsterCategory called after Categories()")
-	}
-	c := Category(numRegisteredCategories.Add(1))
-	if c > CategoryMax {
-		panic("too many categories")
-	}
-	categories[c].name = name
-	categories[c].qosLevel = qosLevel
-	return c
+	return base.RegisterCategory(name, qosLevel)
 }
 
 // Categories returns all registered categories, including CategoryUnknown.
@@ -78,75 +49,32 @@ func RegisterCategory(name string, qosLevel QoSLevel) Category {
 // Can only be called after all categories have been registered. Calling
 // RegisterCategory() after Categories() will result in a panic.
 func Categories() []Category {
-	categoriesListOnce.Do(func() {
-		categoriesList = make([]Category, numRegisteredCategories.Load()+1)
-		for i := range categoriesList {
-			categoriesList[i] = Category(i)
-		}
-	})
-	return categoriesList
+	return base.Categories()
 }
 
-var categories = [CategoryMax + 1]struct {
-	name     string
-	qosLevel QoSLevel
-}{
-	CategoryUnknown: {name: "unknown", qosLevel: LatencySensitiveQoSLevel},
-}
-
-var numRegisteredCategories atomic.Uint32
-
-var categoriesList []Category
-var categoriesListOnce sync.Once
-
 // StringToCategoryForTesting returns the Category for the string, or panics if
 // the string is not known.
 func StringToCategoryForTesting(s string) Category {
-	for i := range categories {
-		if categories[i].name == s {
-			return Category(i)
-		}
-	}
-	panic(errors.AssertionFailedf("unknown Category %s", s))
+	return base.StringToCategoryForTesting(s)
 }
 
-// QoSLevel describes whether the read is latency-sensitive or not. Each
-// category must map to a single QoSLevel. While category strings are opaque
-// to Pebble, the QoSLevel may be internally utilized in Pebble to better
-// optimize future reads.
-type QoSLevel uint8
+// QoSLevel describes whether the read is latency-sensitive or not. See
+// base.QoSLevel.
+type QoSLevel = base.QoSLevel
 
 const (
 	// LatencySensitiveQoSLevel is the default when QoSLevel is not specified,
 	// and represents reads that are latency-sensitive.
-	LatencySensitiveQoSLevel QoSLevel = iota
+	LatencySensitiveQoSLevel = base.LatencySensitiveQoSLevel
 	// NonLatencySensitiveQoSLevel represents reads that are not
 	// latency-sensitive.
-	NonLatencySensitiveQoSLevel
+	NonLatencySensitiveQoSLevel = base.NonLatencySensitiveQoSLevel
 )
 
-// SafeFormat implements the redact.SafeFormatter interface.
-func (q QoSLevel) SafeFormat(p redact.SafePrinter, verb rune) {
-	switch q {
-	case LatencySensitiveQoSLevel:
-		p.Printf("latency")
-	case NonLatencySensitiveQoSLevel:
-		p.Printf("non-latency")
-	default:
-		p.Printf("<unknown-qos>")
-	}
-}
-
 // StringToQoSForTesting returns the QoSLevel for the string, or panics if the
 // string is not known.
 func StringToQoSForTesting(s string) QoSLevel {
-	switch s {
-	case "latency":
-		return LatencySensitiveQoSLevel
-	case "non-latency":
-		return NonLatencySensitiveQoSLevel
-	}
-	panic(errors.AssertionFailedf("unknown QoS %s", s))
+	return base.StringToQoSForTesting(s)
 }
 
 // CategoryStats provides stats about a category of reads.
@@ -160,6 +88,11 @@ type CategoryStats struct {
 	// BlockBytesInCache is the subset of BlockBytes that were in the block
 	// cache.
 	BlockBytesInCache uint64
+	// BlockBytesInSecondaryCache is the subset of BlockBytes that were not in
+	// the block cache, but were found in the secondary block cache. The hit
+	// rate of the secondary cache is BlockBytesInSecondaryCache /
+	// (BlockBytes - BlockBytesInCache).
+	BlockBytesInSecondaryCache uint64
 	// BlockReadDuration is the total duration to read the bytes not in the
 	// cache, i.e., BlockBytes-BlockBytesInCache.
 	BlockReadDuration time.Duration
@@ -173,6 +106,11 @@ func (s *CategoryStats) aggregate(
 	s.BlockReadDuration += blockReadDuration
 }
 
+func (s *CategoryStats) merge(o CategoryStats) {
+	s.aggregate(o.BlockBytes, o.BlockBytesInCache, o.BlockReadDuration)
+	s.BlockBytesInSecondaryCache += o.BlockBytesInSecondaryCache
+}
+
 // CategoryStatsAggregate is the aggregate for the given category.
 type CategoryStatsAggregate struct {
 	Category      Category
@@ -208,6 +146,15 @@ func (c *CategoryStatsShard) Accumulate(
 	c.mu.Unlock()
 }
 
+// AccumulateSecondaryCacheHit accumulates the stats of a block that was found
+// in the secondary cache.
+func (c *CategoryStatsShard) AccumulateSecondaryCacheHit(blockBytes uint64) {
+	c.mu.Lock()
+	c.mu.stats.aggregate(blockBytes, 0, 0)
+	c.mu.stats.BlockBytesInSecondaryCache += blockBytes
+	c.mu.Unlock()
+}
+
 // CategoryStatsCollector collects and aggregates the stats per category.
 type CategoryStatsCollector struct {
 	// mu protects additions to statsMap.
@@ -232,7 +179,7 @@ func (s *shardedCategoryStats) getStats() CategoryStatsAggregate {
 	}
 	for i := range s.shards {
 		s.shards[i].mu.Lock()
-		agg.CategoryStats.aggregate(s.shards[i].mu.stats.BlockBytes, s.shards[i].mu.stats.BlockBytesInCache, s.shards[i].mu.stats.BlockReadDuration)
+		agg.CategoryStats.merge(s.shards[i].mu.stats)
 		s.shards[i].mu.Unlock()
 	}
 	return agg
@@ -6,8 +6,11 @@ package block
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math/rand/v2"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/cockroachdb/errors"
@@ -16,9 +19,11 @@ import (
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/golang/snappy"
+	"github.com/minio/minlz"
 )
 
-// Compression is the per-block compression algorithm to use.
+// Compression is the per-block compression algorithm to use, along with the
+// compression level for algorithms that support one.
 type Compression int
 
 // The available compression types.
@@ -27,13 +32,60 @@ const (
 	NoCompression
 	SnappyCompression
 	ZstdCompression
+	// Lz4Compression compresses blocks with LZ4, which compresses and
+	// decompresses faster than Snappy. It requires TableFormatPebblev7.
+	Lz4Compression
+	// MinLZCompression compresses blocks with MinLZ, which compresses better
+	// than Snappy at a similar speed. It requires TableFormatPebblev7.
+	MinLZCompression
 	NCompression
 )
 
+// The compression level is stored in the bits of a Compression above the
+// algorithm. A level of zero indicates the algorithm's default level.
+const (
+	compressionLevelShift = 8
+	compressionAlgoMask   = 1<<compressionLevelShift - 1
+
+	// DefaultZstdLevel is the zstd compression level used by ZstdCompression.
+	DefaultZstdLevel = 3
+	// MaxZstdLevel is the highest supported zstd compression level.
+	MaxZstdLevel = 22
+)
+
+// ZstdCompressionLevel returns a Compression that compresses blocks with zstd
+// at the provided level. Higher levels compress better but more slowly; the
+// level does not affect decompression speed. Levels outside of [1,
+// MaxZstdLevel] are clamped.
+func ZstdCompressionLevel(level int) Compression {
+	level = min(max(level, 1), MaxZstdLevel)
+	if level == DefaultZstdLevel {
+		return ZstdCompression
+	}
+	return ZstdCompression | Compression(level)<<compressionLevelShift
+}
+
+// Algorithm returns the compression algorithm of c, without its level.
+func (c Compression) Algorithm() Compression {
+	return c & compressionAlgoMask
+}
+
+// ZstdLevel returns the zstd compression level of c. It returns zero if c is
+// not a zstd compression.
+func (c Compression) ZstdLevel() int {
+	if c.Algorithm() != ZstdCompression {
+		return 0
+	}
+	if level := int(c >> compressionLevelShift); level != 0 {
+		return level
+	}
+	return DefaultZstdLevel
+}
+
 // String implements fmt.Stringer, returning a human-readable name for the
 // compression algorithm.
 func (c Compression) String() string {
-	switch c {
+	switch c.Algorithm() {
 	case DefaultCompression:
 		return "Default"
 	case NoCompression:
@@ -41,7 +93,14 @@ func (c Compression) String() string {
 	case SnappyCompression:
 		return "Snappy"
 	case ZstdCompression:
+		if level := c.ZstdLevel(); level != DefaultZstdLevel {
+			return fmt.Sprintf("ZSTD%d", level)
+		}
 		return "ZSTD"
+	case Lz4Compression:
+		return "LZ4"
+	case MinLZCompression:
+		return "MinLZ"
 	default:
 		return "Unknown"
 	}
@@ -59,7 +118,16 @@ func CompressionFromString(s string) Compression {
 		return SnappyCompression
 	case "ZSTD":
 		return ZstdCompression
+	case "LZ4":
+		return Lz4Compression
+	case "MinLZ":
+		return MinLZCompression
 	default:
+		if l, ok := strings.CutPrefix(s, "ZSTD"); ok {
+			if level, err := strconv.Atoi(l); err == nil && level >= 1 && level <= MaxZstdLevel {
+				return ZstdCompressionLevel(level)
+			}
+		}
 		return DefaultCompression
 	}
 }
@@ -86,6 +154,12 @@ const (
 	Lz4hcCompressionIndicator  CompressionIndicator = 5
 	XpressCompressionIndicator CompressionIndicator = 6
 	ZstdCompressionIndicator   CompressionIndicator = 7
+	// ZstdDictCompressionIndicator indicates a block compressed with zstd using
+	// the table's CompressionDict. It requires TableFormatPebblev8.
+	ZstdDictCompressionIndicator CompressionIndicator = 8
+	// MinLZCompressionIndicator indicates a block compressed with MinLZ. It
+	// requires TableFormatPebblev7.
+	MinLZCompressionIndicator CompressionIndicator = 9
 )
 
 // String implements fmt.Stringer.
@@ -107,6 +181,10 @@ func (i CompressionIndicator) String() string {
 		return "xpress"
 	case 7:
 		return "zstd"
+	case 8:
+		return "zstd-dict"
+	case 9:
+		return "minlz"
 	default:
 		panic(errors.Newf("sstable: unknown block type: %d", i))
 	}
@@ -126,8 +204,16 @@ func DecompressedLen(
 	case SnappyCompressionIndicator:
 		l, err := snappy.DecodedLen(b)
 		return l, 0, err
-	case ZstdCompressionIndicator:
-		// This will also be used by zlib, bzip2 and lz4 to retrieve the decodedLen
+	case MinLZCompressionIndicator:
+		// NB: minlz.Decode falls back to decoding Snappy and S2 blocks; reject
+		// them here.
+		ok, l, err := minlz.IsMinLZ(b)
+		if err == nil && !ok {
+			err = base.CorruptionErrorf("pebble/table: minlz block has invalid header")
+		}
+		return l, 0, err
+	case ZstdCompressionIndicator, Lz4CompressionIndicator, ZstdDictCompressionIndicator:
+		// This will also be used by zlib and bzip2 to retrieve the decodedLen
 		// if we implement these algorithms in the future.
 		decodedLenU64, varIntLen := binary.Uvarint(b)
 		if varIntLen <= 0 {
@@ -153,6 +239,10 @@ func DecompressInto(algo CompressionIndicator, compressed []byte, buf []byte) er
 		result, err = snappy.Decode(buf, compressed)
 	case ZstdCompressionIndicator:
 		result, err = decodeZstd(buf, compressed)
+	case Lz4CompressionIndicator:
+		result, err = decodeLZ4(buf, compressed)
+	case MinLZCompressionIndicator:
+		result, err = minlz.Decode(buf, compressed)
 	default:
 		return base.CorruptionErrorf("pebble/table: unknown block compression: %d", errors.Safe(algo))
 	}
@@ -248,7 +338,7 @@ func CompressAndChecksum(
 	// Compress the buffer, discarding the result if the improvement isn't at
 	// least 12.5%.
 	algo := NoCompressionIndicator
-	if compression != NoCompression {
+	if compression.Algorithm() != NoCompression {
 		algo, buf = compress(compression, blockData, buf)
 		if len(buf) >= len(blockData)-len(blockData)/8 {
 			algo = NoCompressionIndicator
@@ -277,7 +367,7 @@ func CompressAndChecksum(
 func compress(
 	compression Compression, b []byte, dstBuf []byte,
 ) (indicator CompressionIndicator, compressed []byte) {
-	switch compression {
+	switch compression.Algorithm() {
 	case SnappyCompression:
 		// snappy relies on the length of the buffer, and not the capacity to
 		// determine if it needs to make an allocation.
@@ -288,7 +378,21 @@ func compress(
 			dstBuf = append(dstBuf, make([]byte, binary.MaxVarintLen64-len(dstBuf))...)
 		}
 		varIntLen := binary.PutUvarint(dstBuf, uint64(len(b)))
-		return ZstdCompressionIndicator, encodeZstd(dstBuf, varIntLen, b)
+		return ZstdCompressionIndicator, encodeZstd(dstBuf, varIntLen, b, compression.ZstdLevel())
+	case Lz4Compression:
+		// Like zstd, LZ4 blocks are prefixed with the uncompressed length.
+		dstBuf = binary.AppendUvarint(dstBuf[:0], uint64(len(b)))
+		if compressed, ok := encodeLZ4(dstBuf, b); ok {
+			return Lz4CompressionIndicator, compressed
+		}
+		return NoCompressionIndicator, dstBuf[:0]
+	case MinLZCompression:
+		compressed, err := minlz.Encode(dstBuf[:cap(dstBuf)], b, minlz.LevelFastest)
+		if err != nil {
+			// The block is larger than MinLZ supports.
+			return NoCompressionIndicator, dstBuf[:0]
+		}
+		return MinLZCompressionIndicator, compressed
 	default:
 		panic("unreachable")
 	}
@@ -8,6 +8,7 @@ package block
 
 import (
 	"bytes"
+	"slices"
 
 	"github.com/DataDog/zstd"
 	"github.com/cockroachdb/errors"
@@ -41,15 +42,46 @@ func decodeZstd(dst, src []byte) ([]byte, error) {
 	return dst[:n], nil
 }
 
-// encodeZstd compresses b with the Zstandard algorithm at default compression
-// level (level 3). It reuses the preallocated capacity of compressedBuf if it
+// encodeZstd compresses b with the Zstandard algorithm at the provided
+// compression level. It reuses the preallocated capacity of compressedBuf if it
 // is sufficient. The subslice `compressedBuf[:varIntLen]` should already encode
 // the length of `b` before calling encodeZstd. It returns the encoded byte
 // slice, including the `compressedBuf[:varIntLen]` prefix.
-func encodeZstd(compressedBuf []byte, varIntLen int, b []byte) []byte {
+func encodeZstd(compressedBuf []byte, varIntLen int, b []byte, level int) []byte {
 	buf := bytes.NewBuffer(compressedBuf[:varIntLen])
-	writer := zstd.NewWriterLevel(buf, 3)
+	writer := zstd.NewWriterLevel(buf, level)
 	writer.Write(b)
 	writer.Close()
 	return buf.Bytes()
 }
+
+// zstdDict compresses and decompresses using a zstd dictionary.
+type zstdDict struct {
+	p *zstd.BulkProcessor
+}
+
+func newZstdDict(raw []byte, level int) (zstdDict, error) {
+	p, err := zstd.NewBulkProcessor(raw, level)
+	return zstdDict{p: p}, err
+}
+
+// compress appends the compressed src to dst.
+func (d zstdDict) compress(dst, src []byte) []byte {
+	n := len(dst)
+	dst = slices.Grow(dst, zstd.CompressBound(len(src)))
+	compressed, err := d.p.Compress(dst[n:], src)
+	if err != nil {
+		// Compression only errors if dst is too small, and dst was grown to
+		// the maximum compressed size.
+		panic(errors.AssertionFailedf("zstd dictionary compression failed: %v", err))
+	}
+	return dst[:n+len(compressed)]
+}
+
+// decompress decompresses src into dst, which must be sufficiently sized.
+func (d zstdDict) decompress(dst, src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errors.Errorf("decodeZstd: empty src buffer")
+	}
+	return d.p.Decompress(dst[:0], src)
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package block
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// A CompressionDict is a zstd dictionary used to compress the data blocks of a
+// table. Blocks compressed with a dictionary use the
+// ZstdDictCompressionIndicator, and can only be decompressed using the same
+// dictionary.
+//
+// The dictionary is either a formatted zstd dictionary, as produced by
+// TrainCompressionDict, or a raw content dictionary. In both cases zstd uses
+// its contents as history preceding each compressed block, allowing small
+// blocks to reference content that is common to many blocks.
+//
+// A CompressionDict is safe for concurrent use.
+type CompressionDict struct {
+	raw   []byte
+	level int
+	zstd  zstdDict
+}
+
+// NewCompressionDict returns a CompressionDict for the provided raw dictionary
+// contents, compressing at the provided zstd level. The level has no effect on
+// decompression.
+func NewCompressionDict(raw []byte, level int) (*CompressionDict, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("pebble: empty compression dictionary")
+	}
+	d := &CompressionDict{raw: raw, level: level}
+	var err error
+	if d.zstd, err = newZstdDict(raw, level); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Bytes returns the raw contents of the dictionary.
+func (d *CompressionDict) Bytes() []byte {
+	return d.raw
+}
+
+// CompressAndChecksum compresses and checksums the provided block using the
+// dictionary, returning the compressed block and its trailer. The result is
+// appended to the dst argument. As with the CompressAndChecksum function, the
+// block is stored uncompressed if compression does not sufficiently reduce its
+// size.
+func (d *CompressionDict) CompressAndChecksum(
+	dst *[]byte, blockData []byte, checksummer *Checksummer,
+) PhysicalBlock {
+	// Like zstd blocks, blocks compressed with a dictionary are prefixed with
+	// the uncompressed length.
+	buf := binary.AppendUvarint((*dst)[:0], uint64(len(blockData)))
+	buf = d.zstd.compress(buf, blockData)
+	algo := ZstdDictCompressionIndicator
+	if len(buf) >= len(blockData)-len(blockData)/8 {
+		algo = NoCompressionIndicator
+		buf = append(buf[:0], blockData...)
+	}
+	*dst = buf
+	pb := PhysicalBlock{data: buf}
+	pb.trailer = MakeTrailer(byte(algo), checksummer.Checksum(buf, byte(algo)))
+	return pb
+}
+
+// DecompressInto decompresses compressed into buf, like the DecompressInto
+// function. Blocks compressed with a dictionary are decompressed using d. The
+// receiver may be nil, in which case blocks compressed with a dictionary cannot
+// be decompressed.
+func (d *CompressionDict) DecompressInto(
+	algo CompressionIndicator, compressed []byte, buf []byte,
+) error {
+	if algo != ZstdDictCompressionIndicator {
+		return DecompressInto(algo, compressed, buf)
+	}
+	if d == nil {
+		return base.CorruptionErrorf("pebble/table: block compressed with a dictionary, but table has none")
+	}
+	result, err := d.zstd.decompress(buf, compressed)
+	if err != nil {
+		return base.MarkCorruptionError(err)
+	}
+	if len(result) != len(buf) || (len(result) > 0 && &result[0] != &buf[0]) {
+		return base.CorruptionErrorf("pebble/table: decompressed into unexpected buffer: %p != %p",
+			errors.Safe(result), errors.Safe(buf))
+	}
+	return nil
+}
+
+// The parameters of dictionary training.
+const (
+	// dictTrainingHashBytes is the minimum length of the substrings of the
+	// samples that training considers for inclusion in the dictionary.
+	dictTrainingHashBytes = 6
+	// dictTrainingDictID is the ID recorded in dictionaries built by training.
+	// IDs below 32768 are reserved by the zstd format. Since a table is only
+	// ever decompressed with its own dictionary, all tables may share the
+	// same ID, which keeps training deterministic.
+	dictTrainingDictID = 32768
+	// minCompressionDictSize is the minimum size of a useful dictionary.
+	minCompressionDictSize = 64
+)
+
+// zstdDictMagic is the magic number that begins a formatted zstd dictionary.
+// Raw content dictionaries must not begin with it.
+var zstdDictMagic = []byte{0x37, 0xa4, 0x30, 0xec}
+
+// isFormattedZstdDict returns true if the provided dictionary is a formatted
+// zstd dictionary, as opposed to a raw content dictionary.
+func isFormattedZstdDict(raw []byte) bool {
+	return bytes.HasPrefix(raw, zstdDictMagic)
+}
+
+// TrainCompressionDict trains a dictionary of at most maxSize bytes from the
+// provided samples, which are typically uncompressed data blocks, for
+// compressing at the provided zstd level. It returns nil if the samples are too
+// small to train a useful dictionary.
+//
+// Training uses dict.BuildZstdDict, producing a formatted zstd dictionary. If
+// the library rejects the samples, for example because they contain no
+// repeated content, the dictionary falls back to a raw content dictionary
+// holding the most recent samples.
+func TrainCompressionDict(samples [][]byte, maxSize int, level int) []byte {
+	var size int
+	for _, s := range samples {
+		size += len(s)
+	}
+	if size < minCompressionDictSize || maxSize < minCompressionDictSize {
+		return nil
+	}
+	// The size of the dictionary includes the entropy tables that precede its
+	// content, which is limited by MaxDictSize. If the dictionary is too
+	// large, it is rebuilt with less content.
+	contentSize := maxSize
+	for contentSize >= minCompressionDictSize {
+		d, err := buildZstdDict(samples, contentSize, level)
+		if err != nil {
+			break
+		}
+		if len(d) <= maxSize {
+			return d
+		}
+		contentSize -= len(d) - maxSize
+	}
+	return rawCompressionDict(samples, maxSize)
+}
+
+// buildZstdDict builds a formatted zstd dictionary using dict.BuildZstdDict.
+// The library panics on some inputs without repeated content, so panics are
+// returned as errors.
+func buildZstdDict(samples [][]byte, contentSize int, level int) (d []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Newf("pebble: unable to train compression dictionary: %v", r)
+		}
+	}()
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize:    contentSize,
+		HashBytes:      dictTrainingHashBytes,
+		ZstdDictID:     dictTrainingDictID,
+		ZstdDictCompat: true,
+		ZstdLevel:      zstd.EncoderLevelFromZstd(level),
+	})
+}
+
+// rawCompressionDict returns a raw content dictionary of at most maxSize bytes
+// holding the most recent samples. zstd prefers matches at smaller offsets, so
+// the most recent samples are placed at the end of the dictionary.
+func rawCompressionDict(samples [][]byte, maxSize int) []byte {
+	start, size := len(samples), 0
+	for start > 0 && size < maxSize {
+		start--
+		size += len(samples[start])
+	}
+	d := make([]byte, 0, size)
+	for _, s := range samples[start:] {
+		d = append(d, s...)
+	}
+	d = d[max(0, len(d)-maxSize):]
+	for isFormattedZstdDict(d) {
+		d = d[1:]
+	}
+	if len(d) < minCompressionDictSize {
+		return nil
+	}
+	return d
+}
@@ -27,13 +27,55 @@ func decodeZstd(dst, src []byte) ([]byte, error) {
 	return decoder.DecodeAll(src, dst[:0])
 }
 
-// encodeZstd compresses b with the Zstandard algorithm at default compression
-// level (level 3). It reuses the preallocated capacity of compressedBuf if it
+// encodeZstd compresses b with the Zstandard algorithm at the provided
+// compression level. It reuses the preallocated capacity of compressedBuf if it
 // is sufficient. The subslice `compressedBuf[:varIntLen]` should already encode
 // the length of `b` before calling encodeZstd. It returns the encoded byte
 // slice, including the `compressedBuf[:varIntLen]` prefix.
-func encodeZstd(compressedBuf []byte, varIntLen int, b []byte) []byte {
-	encoder, _ := zstd.NewWriter(nil)
+func encodeZstd(compressedBuf []byte, varIntLen int, b []byte, level int) []byte {
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
 	defer encoder.Close()
 	return encoder.EncodeAll(b, compressedBuf[:varIntLen])
 }
+
+// zstdDict compresses and decompresses using a zstd dictionary.
+type zstdDict struct {
+	raw   []byte
+	level int
+}
+
+func newZstdDict(raw []byte, level int) (zstdDict, error) {
+	if isFormattedZstdDict(raw) {
+		// Validate the dictionary, since compress and decompress cannot
+		// return errors from creating the encoder and decoder.
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderDicts(raw))
+		if err != nil {
+			return zstdDict{}, err
+		}
+		decoder.Close()
+	}
+	return zstdDict{raw: raw, level: level}, nil
+}
+
+// compress appends the compressed src to dst.
+func (d zstdDict) compress(dst, src []byte) []byte {
+	dictOpt := zstd.WithEncoderDictRaw(0, d.raw)
+	if isFormattedZstdDict(d.raw) {
+		dictOpt = zstd.WithEncoderDict(d.raw)
+	}
+	encoder, _ := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(d.level)), dictOpt)
+	defer encoder.Close()
+	return encoder.EncodeAll(src, dst)
+}
+
+// decompress decompresses src into dst, which must be sufficiently sized.
+func (d zstdDict) decompress(dst, src []byte) ([]byte, error) {
+	dictOpt := zstd.WithDecoderDictRaw(0, d.raw)
+	if isFormattedZstdDict(d.raw) {
+		dictOpt = zstd.WithDecoderDicts(d.raw)
+	}
+	decoder, _ := zstd.NewReader(nil, dictOpt)
+	defer decoder.Close()
+	return decoder.DecodeAll(src, dst[:0])
+}
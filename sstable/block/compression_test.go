@@ -23,14 +23,23 @@ func TestCompressionRoundtrip(t *testing.T) {
 	t.Logf("seed %d", seed)
 	rng := rand.New(rand.NewPCG(0, seed))
 
+	compressions := []Compression{ZstdCompressionLevel(1), ZstdCompressionLevel(19)}
 	for compression := DefaultCompression + 1; compression < NCompression; compression++ {
-		if compression == NoCompression {
-			continue
+		if compression != NoCompression {
+			compressions = append(compressions, compression)
 		}
+	}
+	for _, compression := range compressions {
 		t.Run(compression.String(), func(t *testing.T) {
 			payload := make([]byte, 1+rng.IntN(10<<10 /* 10 KiB */))
+			// Use a small alphabet half of the time so that the payload is
+			// compressible.
+			alphabet := uint32(256)
+			if rng.IntN(2) == 0 {
+				alphabet = 1 + rng.Uint32N(4)
+			}
 			for i := range payload {
-				payload[i] = byte(rng.Uint32())
+				payload[i] = byte(rng.Uint32N(alphabet))
 			}
 			// Create a randomly-sized buffer to house the compressed output. If it's
 			// not sufficient, Compress should allocate one that is.
@@ -49,6 +58,133 @@ func TestCompressionRoundtrip(t *testing.T) {
 	}
 }
 
+func TestCompressionString(t *testing.T) {
+	for _, c := range []Compression{
+		NoCompression, SnappyCompression, ZstdCompression, Lz4Compression, MinLZCompression,
+		ZstdCompressionLevel(1), ZstdCompressionLevel(19),
+	} {
+		require.Equal(t, c, CompressionFromString(c.String()))
+	}
+	require.Equal(t, ZstdCompression, ZstdCompressionLevel(DefaultZstdLevel))
+	require.Equal(t, MaxZstdLevel, ZstdCompressionLevel(100).ZstdLevel())
+	require.Equal(t, "ZSTD19", ZstdCompressionLevel(19).String())
+	require.Equal(t, 0, Lz4Compression.ZstdLevel())
+}
+
+func TestLZ4(t *testing.T) {
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("seed %d", seed)
+	rng := rand.New(rand.NewPCG(0, seed))
+
+	roundtrip := func(payload []byte) []byte {
+		encoded, ok := encodeLZ4(nil, payload)
+		if !ok {
+			return nil
+		}
+		decoded := make([]byte, len(payload))
+		_, err := decodeLZ4(decoded, encoded)
+		require.NoError(t, err)
+		require.Equal(t, payload, decoded)
+		// Truncated or mis-sized input must not decode.
+		if len(encoded) > 1 {
+			_, err = decodeLZ4(decoded, encoded[:len(encoded)-1])
+			require.Error(t, err)
+		}
+		_, err = decodeLZ4(make([]byte, len(payload)+1), encoded)
+		require.Error(t, err)
+		return encoded
+	}
+
+	// Small inputs are encoded entirely as literals.
+	for n := 0; n < 20; n++ {
+		roundtrip(make([]byte, n))
+	}
+	// Long runs produce overlapping matches and extended lengths.
+	require.Less(t, len(roundtrip(make([]byte, 100<<10))), 1<<10)
+	// Repeats further apart than the maximum offset cannot be matched.
+	random := make([]byte, 70<<10)
+	for i := range random {
+		random[i] = byte(rng.Uint32())
+	}
+	roundtrip(append(random, random...))
+	for i := 0; i < 100; i++ {
+		payload := make([]byte, rng.IntN(64<<10))
+		alphabet := 1 + rng.Uint32N(16)
+		for j := range payload {
+			payload[j] = byte(rng.Uint32N(alphabet))
+		}
+		roundtrip(payload)
+	}
+}
+
+func TestCompressionDict(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("seed %d", seed)
+	rng := rand.New(rand.NewPCG(0, seed))
+
+	// Generate small blocks of records that share structure across blocks but
+	// contain little repetition within a single block.
+	makeBlock := func() []byte {
+		var b []byte
+		for i := 0; i < 8; i++ {
+			b = fmt.Appendf(b, `{"user_id":%d,"region":"us-east-%d","status":"active","score":%d}`,
+				rng.IntN(1e6), rng.IntN(4), rng.IntN(100))
+		}
+		return b
+	}
+	var samples [][]byte
+	for i := 0; i < 100; i++ {
+		samples = append(samples, makeBlock())
+	}
+	raw := TrainCompressionDict(samples, 1<<10, DefaultZstdLevel)
+	require.NotEmpty(t, raw)
+	require.LessOrEqual(t, len(raw), 1<<10)
+	require.True(t, isFormattedZstdDict(raw))
+	require.Nil(t, TrainCompressionDict([][]byte{[]byte("tiny")}, 1<<10, DefaultZstdLevel))
+
+	// Samples without repeated content fall back to a raw content dictionary
+	// holding the most recent samples.
+	random := make([]byte, 1<<10)
+	for i := range random {
+		random[i] = byte(rng.Uint32())
+	}
+	fallback := TrainCompressionDict([][]byte{random[:512], random[512:]}, 768, DefaultZstdLevel)
+	require.False(t, isFormattedZstdDict(fallback))
+	require.Equal(t, random[256:], fallback)
+	_, err := NewCompressionDict(fallback, DefaultZstdLevel)
+	require.NoError(t, err)
+
+	d, err := NewCompressionDict(raw, DefaultZstdLevel)
+	require.NoError(t, err)
+	var withDict, withoutDict int
+	var checksummer Checksummer
+	checksummer.Type = ChecksumTypeCRC32c
+	for i := 0; i < 100; i++ {
+		b := makeBlock()
+		var buf []byte
+		pb := d.CompressAndChecksum(&buf, b, &checksummer)
+		algo := CompressionIndicator(pb.trailer[0])
+		require.Equal(t, ZstdDictCompressionIndicator, algo)
+		withDict += pb.LengthWithoutTrailer()
+		var noDictBuf []byte
+		noDict := CompressAndChecksum(&noDictBuf, b, ZstdCompression, &checksummer)
+		withoutDict += noDict.LengthWithoutTrailer()
+
+		n, prefix, err := DecompressedLen(algo, pb.data)
+		require.NoError(t, err)
+		got := make([]byte, n)
+		require.NoError(t, d.DecompressInto(algo, pb.data[prefix:], got))
+		require.Equal(t, b, got)
+
+		// Blocks compressed with a dictionary cannot be decompressed without it.
+		require.Error(t, (*CompressionDict)(nil).DecompressInto(algo, pb.data[prefix:], got))
+	}
+	t.Logf("with dict: %d, without dict: %d", withDict, withoutDict)
+	require.Less(t, withDict, withoutDict)
+}
+
 // TestDecompressionError tests that a decompressing a value that does not
 // decompress returns an error.
 func TestDecompressionError(t *testing.T) {
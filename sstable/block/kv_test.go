@@ -50,6 +50,7 @@ func TestValuePrefix(t *testing.T) {
 			isValueBlockHandle: false,
 			isBlobHandle:       true,
 			setHasSamePrefix:   true,
+			attr:               3,
 		},
 	}
 	for _, tc := range testCases {
@@ -58,14 +59,14 @@ func TestValuePrefix(t *testing.T) {
 			if tc.isValueBlockHandle {
 				prefix = ValueBlockHandlePrefix(tc.setHasSamePrefix, tc.attr)
 			} else if tc.isBlobHandle {
-				prefix = BlobValueHandlePrefix(tc.setHasSamePrefix)
+				prefix = BlobValueHandlePrefix(tc.setHasSamePrefix, tc.attr)
 			} else {
 				prefix = InPlaceValuePrefix(tc.setHasSamePrefix)
 			}
 			require.Equal(t, tc.isValueBlockHandle, prefix.IsValueBlockHandle())
 			require.Equal(t, tc.isBlobHandle, prefix.IsBlobValueHandle())
 			require.Equal(t, tc.setHasSamePrefix, prefix.SetHasSamePrefix())
-			if tc.isValueBlockHandle {
+			if tc.isValueBlockHandle || tc.isBlobHandle {
 				require.Equal(t, tc.attr, prefix.ShortAttribute())
 			}
 		})
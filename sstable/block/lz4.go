@@ -0,0 +1,49 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package block
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/pierrec/lz4/v4"
+)
+
+var errLZ4Corrupt = errors.New("pebble/table: corrupt lz4 block")
+
+// lz4Compressors is a pool of LZ4 compressors, each of which holds a hash
+// table that is too large to allocate for every block.
+var lz4Compressors = sync.Pool{
+	New: func() any { return new(lz4.Compressor) },
+}
+
+// encodeLZ4 appends the LZ4 block encoding of src to dst. It returns false if
+// src is not compressible, in which case dst is returned unchanged.
+func encodeLZ4(dst, src []byte) (_ []byte, ok bool) {
+	n := len(dst)
+	bound := lz4.CompressBlockBound(len(src))
+	dst = slices.Grow(dst, bound)[:n+bound]
+	c := lz4Compressors.Get().(*lz4.Compressor)
+	defer lz4Compressors.Put(c)
+	m, err := c.CompressBlock(src, dst[n:])
+	if err != nil || m == 0 {
+		return dst[:n], false
+	}
+	return dst[:n+m], true
+}
+
+// decodeLZ4 decompresses the LZ4 block src into dst, which must be exactly
+// sized to the decompressed length.
+func decodeLZ4(dst, src []byte) ([]byte, error) {
+	n, err := lz4.UncompressBlock(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(dst) {
+		return nil, errLZ4Corrupt
+	}
+	return dst, nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/blob"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/sstable/colblk"
 	"github.com/cockroachdb/pebble/sstable/rowblk"
@@ -57,6 +58,9 @@ type RawColumnWriter struct {
 	rangeDelBlock      colblk.KeyspanBlockWriter
 	rangeKeyBlock      colblk.KeyspanBlockWriter
 	valueBlock         *valblk.Writer // nil iff WriterOptions.DisableValueBlocks=true
+	// dataCompressor compresses data blocks, possibly with a compression
+	// dictionary trained from the table's first data blocks.
+	dataCompressor dataBlockCompressor
 	// filter accumulates the filter block. If populated, the filter ingests
 	// either the output of w.split (i.e. a prefix extractor) if w.split is not
 	// nil, or the full keys otherwise.
@@ -98,6 +102,7 @@ type RawColumnWriter struct {
 	lastKeyBuf            []byte
 	separatorBuf          []byte
 	tmp                   [blockHandleLikelyMaxLen]byte
+	blobHandleTmp         [blob.MaxInlineHandleLength]byte
 	previousUserKey       invariants.Value[[]byte]
 	validator             invariants.Value[*colblk.DataBlockValidator]
 	disableKeyOrderChecks bool
@@ -130,6 +135,7 @@ func newColumnarWriter(writable objstorage.Writable, o WriterOptions) *RawColumn
 	w.topLevelIndexBlock.Init()
 	w.rangeDelBlock.Init(w.comparer.Equal)
 	w.rangeKeyBlock.Init(w.comparer.Equal)
+	w.dataCompressor.init(&o)
 	if !o.DisableValueBlocks {
 		w.valueBlock = valblk.NewWriter(
 			block.MakeFlushGovernor(o.BlockSize, o.BlockSizeThreshold, o.SizeClassAwareThreshold, o.AllocatorSizeClasses),
@@ -137,8 +143,8 @@ func newColumnarWriter(writable objstorage.Writable, o WriterOptions) *RawColumn
 	}
 	if o.FilterPolicy != nil {
 		switch o.FilterType {
-		case TableFilter:
-			w.filterBlock = newTableFilterWriter(o.FilterPolicy)
+		case TableFilter, RangeFilter:
+			w.filterBlock = newTableFilterWriter(o.FilterPolicy, o.FilterType)
 		default:
 			panic(fmt.Sprintf("unknown filter type: %v", o.FilterType))
 		}
@@ -238,6 +244,12 @@ func (w *RawColumnWriter) SetSnapshotPinnedProperties(
 	w.props.SnapshotPinnedValueSize = pinnedValueSize
 }
 
+// SetExpiryHistogram sets the encoded expiry histogram property. Should only
+// be used internally by Pebble.
+func (w *RawColumnWriter) SetExpiryHistogram(encoded []byte) {
+	w.props.ExpiryHistogram = string(encoded)
+}
+
 // Metadata returns the metadata for the finished sstable. Only valid to call
 // after the sstable has been finished.
 func (w *RawColumnWriter) Metadata() (*WriterMetadata, error) {
@@ -379,7 +391,50 @@ func (w *RawColumnWriter) AddWithForceObsolete(
 			valuePrefix = block.InPlaceValuePrefix(eval.kcmp.PrefixEqual())
 		}
 	}
+	return w.add(key, len(value), valueStoredWithKey, valuePrefix, eval)
+}
 
+// AddWithBlobHandle implements the RawWriter interface. The key's value is
+// stored in a blob file, and the sstable only stores the encoded blob.Handle.
+func (w *RawColumnWriter) AddWithBlobHandle(
+	key InternalKey, h blob.Handle, attr base.ShortAttribute, forceObsolete bool,
+) error {
+	switch key.Kind() {
+	case base.InternalKeyKindSet, base.InternalKeyKindSetWithDelete:
+	default:
+		return errors.Newf("pebble: %s keys cannot have blob values", key.Kind())
+	}
+	if w.opts.TableFormat < TableFormatPebblev6 {
+		return errors.Newf("pebble: blob value handles are not supported in table format %s", w.opts.TableFormat)
+	}
+	eval, err := w.evaluatePoint(key, int(h.ValueLen))
+	if err != nil {
+		return err
+	}
+	eval.isObsolete = eval.isObsolete || forceObsolete
+	w.prevPointKey.trailer = key.Trailer
+	w.prevPointKey.isObsolete = eval.isObsolete
+
+	n := h.EncodeInline(w.blobHandleTmp[:])
+	valuePrefix := block.BlobValueHandlePrefix(eval.kcmp.PrefixEqual(), attr)
+	if err := w.add(key, n, w.blobHandleTmp[:n], valuePrefix, eval); err != nil {
+		return err
+	}
+	w.props.NumValuesInBlobFiles++
+	return nil
+}
+
+// add appends a point key to the current data block, flushing the block if
+// necessary, and updates the table's properties. valueStoredWithKey is either
+// the value itself or an encoded handle to the value, and valueLen is the
+// length accounted for in the table's RawValueSize property.
+func (w *RawColumnWriter) add(
+	key InternalKey,
+	valueLen int,
+	valueStoredWithKey []byte,
+	valuePrefix block.ValuePrefix,
+	eval pointKeyEvaluation,
+) error {
 	// Append the key to the data block. We have NOT yet committed to
 	// including the key in the block. The data block writer permits us to
 	// finish the block excluding the last-appended KV.
@@ -408,12 +463,13 @@ func (w *RawColumnWriter) AddWithForceObsolete(
 	}
 
 	for i := range w.blockPropCollectors {
-		v := value
-		if key.Kind() == base.InternalKeyKindSet {
+		v := valueStoredWithKey
+		if key.Kind() == base.InternalKeyKindSet || !valuePrefix.IsInPlaceValue() {
 			// Values for SET are not required to be in-place, and in the future
 			// may not even be read by the compaction, so pass nil values. Block
 			// property collectors in such Pebble DB's must not look at the
-			// value.
+			// value. Values stored in blob files are never passed to block
+			// property collectors either.
 			v = nil
 		}
 		if err := w.blockPropCollectors[i].AddPointKey(key, v); err != nil {
@@ -439,12 +495,12 @@ func (w *RawColumnWriter) AddWithForceObsolete(
 		w.dataBlock.deletionSize += len(key.UserKey)
 	case InternalKeyKindDeleteSized:
 		var size uint64
-		if len(value) > 0 {
+		if len(valueStoredWithKey) > 0 {
 			var n int
-			size, n = binary.Uvarint(value)
+			size, n = binary.Uvarint(valueStoredWithKey)
 			if n <= 0 {
 				return errors.Newf("%s key's value (%x) does not parse as uvarint",
-					errors.Safe(key.Kind().String()), value)
+					errors.Safe(key.Kind().String()), valueStoredWithKey)
 			}
 		}
 		w.props.NumDeletions++
@@ -457,7 +513,7 @@ func (w *RawColumnWriter) AddWithForceObsolete(
 		w.props.NumMergeOperands++
 	}
 	w.props.RawKeySize += uint64(key.Size())
-	w.props.RawValueSize += uint64(len(value))
+	w.props.RawValueSize += uint64(valueLen)
 	return nil
 }
 
@@ -661,10 +717,9 @@ func (w *RawColumnWriter) enqueueDataBlock(
 	// Serialize the data block, compress it and send it to the write queue.
 	cb := compressedBlockPool.Get().(*compressedBlock)
 	cb.blockBuf.checksummer.Type = w.opts.Checksum
-	cb.physical = block.CompressAndChecksum(
+	cb.physical = w.dataCompressor.compressAndChecksum(
 		&cb.blockBuf.dataBuf,
 		serializedBlock,
-		w.opts.Compression,
 		&cb.blockBuf.checksummer,
 	)
 	return w.enqueuePhysicalBlock(cb, separator)
@@ -893,6 +948,14 @@ func (w *RawColumnWriter) Close() (err error) {
 		w.props.FilterSize = bh.Length
 	}
 
+	// Write the compression dictionary block, if the data blocks were
+	// compressed using one.
+	if dict := w.dataCompressor.finish(&w.props); dict != nil {
+		if _, err := w.layout.WriteCompressionDictBlock(dict); err != nil {
+			return err
+		}
+	}
+
 	// Write the range deletion block if non-empty.
 	if w.rangeDelBlock.KeyCount() > 0 {
 		w.props.NumRangeDeletions = uint64(w.rangeDelBlock.KeyCount())
@@ -1055,7 +1118,7 @@ func (w *RawColumnWriter) rewriteSuffixes(
 	// Copy over the filter block if it exists.
 	if w.filterBlock != nil {
 		if filterBlockBH, ok := l.FilterByName(w.filterBlock.metaName()); ok {
-			filterBlock, _, err := readBlockBuf(sstBytes, filterBlockBH, r.blockReader.ChecksumType(), nil)
+			filterBlock, _, err := readBlockBuf(sstBytes, filterBlockBH, r.blockReader.ChecksumType(), nil, nil)
 			if err != nil {
 				return errors.Wrap(err, "reading filter")
 			}
@@ -1175,6 +1238,17 @@ func (w *RawColumnWriter) copyFilter(filter []byte, filterName string) error {
 	return nil
 }
 
+// copyCompressionDict sets the compression dictionary of the table to the
+// dictionary of the table being copied. It's specifically used by the sstable
+// copier that can copy parts of an sstable to a new sstable, using CopySpan().
+func (w *RawColumnWriter) copyCompressionDict(dict *block.CompressionDict) error {
+	if !w.opts.TableFormat.SupportsCompressionDict() {
+		return errors.Newf("pebble: compression dictionaries are not supported in table format %s", w.opts.TableFormat)
+	}
+	w.dataCompressor.setDict(dict)
+	return nil
+}
+
 // copyProperties copies properties from the specified props, and resets others
 // to prepare for copying data blocks from another sstable, using the copy/addDataBlock(s)
 // methods above. It's specifically used by the sstable copier that can copy parts of an
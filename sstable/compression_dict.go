@@ -0,0 +1,126 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+const (
+	// compressionDictSampleRatio is the ratio of the size of the data blocks
+	// sampled to train a compression dictionary to the size of the dictionary.
+	compressionDictSampleRatio = 8
+	// compressionDictStatsInterval is the interval, in blocks compressed with
+	// the dictionary, at which blocks are also compressed without the
+	// dictionary to measure its benefit.
+	compressionDictStatsInterval = 8
+)
+
+// dataBlockCompressor compresses the data blocks of a table. If configured, it
+// trains a compression dictionary from the first data blocks of the table and
+// compresses all subsequent data blocks using the dictionary.
+//
+// Data blocks are compressed by the Writer client goroutine, so a
+// dataBlockCompressor need not be safe for concurrent use.
+type dataBlockCompressor struct {
+	compression block.Compression
+	// sampleSize is the total size of the data blocks sampled before a
+	// dictionary is trained. Zero if dictionaries are disabled or the
+	// dictionary has already been trained.
+	sampleSize int
+	dictSize   int
+	samples    [][]byte
+	sampled    int
+	dict       *block.CompressionDict
+	// numDictBlocks is the number of blocks compressed with dict.
+	numDictBlocks int
+	stats         struct {
+		sampledSize     uint64
+		withDictSize    uint64
+		withoutDictSize uint64
+	}
+	scratch []byte
+}
+
+func (c *dataBlockCompressor) init(o *WriterOptions) {
+	*c = dataBlockCompressor{compression: o.Compression}
+	if o.CompressionDictSize > 0 && o.TableFormat.SupportsCompressionDict() &&
+		o.Compression.Algorithm() == block.ZstdCompression {
+		c.dictSize = o.CompressionDictSize
+		c.sampleSize = o.CompressionDictSize * compressionDictSampleRatio
+	}
+}
+
+// compressAndChecksum compresses and checksums the provided data block,
+// appending the result to dst.
+func (c *dataBlockCompressor) compressAndChecksum(
+	dst *[]byte, blockData []byte, checksummer *block.Checksummer,
+) block.PhysicalBlock {
+	if c.dict == nil {
+		if c.sampleSize > 0 {
+			c.sample(blockData)
+		}
+		return block.CompressAndChecksum(dst, blockData, c.compression, checksummer)
+	}
+	pb := c.dict.CompressAndChecksum(dst, blockData, checksummer)
+	if c.numDictBlocks%compressionDictStatsInterval == 0 {
+		withoutDict := block.CompressAndChecksum(&c.scratch, blockData, c.compression, checksummer)
+		c.stats.sampledSize += uint64(len(blockData))
+		c.stats.withDictSize += uint64(pb.LengthWithoutTrailer())
+		c.stats.withoutDictSize += uint64(withoutDict.LengthWithoutTrailer())
+	}
+	c.numDictBlocks++
+	return pb
+}
+
+// sample records the provided data block as a sample for training the
+// dictionary, training it once enough data blocks have been sampled.
+func (c *dataBlockCompressor) sample(blockData []byte) {
+	c.samples = append(c.samples, append([]byte(nil), blockData...))
+	c.sampled += len(blockData)
+	if c.sampled < c.sampleSize {
+		return
+	}
+	c.sampleSize = 0
+	raw := block.TrainCompressionDict(c.samples, c.dictSize, c.compression.ZstdLevel())
+	c.samples = nil
+	if raw == nil {
+		return
+	}
+	dict, err := block.NewCompressionDict(raw, c.compression.ZstdLevel())
+	if err != nil {
+		panic(base.AssertionFailedf("pebble: unable to create compression dictionary: %v", err))
+	}
+	c.dict = dict
+}
+
+// setDict sets the dictionary used to compress data blocks, disabling
+// training. It's used when copying data blocks compressed with the dictionary
+// from another table.
+func (c *dataBlockCompressor) setDict(dict *block.CompressionDict) {
+	c.dict = dict
+	c.sampleSize = 0
+	c.samples = nil
+}
+
+// finish records the compression dictionary's properties and returns the
+// dictionary, or nil if the table has no dictionary.
+func (c *dataBlockCompressor) finish(props *Properties) []byte {
+	c.samples = nil
+	if c.dict == nil {
+		return nil
+	}
+	props.CompressionDictSize = uint64(len(c.dict.Bytes()))
+	if c.numDictBlocks == 0 {
+		// The dictionary was copied from another table along with its
+		// properties.
+		return c.dict.Bytes()
+	}
+	props.CompressionDictSampledSize = c.stats.sampledSize
+	props.CompressionDictSampledWithDictSize = c.stats.withDictSize
+	props.CompressionDictSampledWithoutDictSize = c.stats.withoutDictSize
+	return c.dict.Bytes()
+}
@@ -114,6 +114,14 @@ func CopySpan(
 	// than under-counts.
 	w.copyProperties(r.Properties)
 
+	// Data blocks compressed with a compression dictionary are copied as-is,
+	// so the dictionary must be copied too.
+	if dict := r.blockReader.CompressionDict(); dict != nil {
+		if err := w.copyCompressionDict(dict); err != nil {
+			return 0, err
+		}
+	}
+
 	// Find the blocks that intersect our span.
 	blocks, err := intersectingIndexEntries(ctx, r, rh, indexH, start, end)
 	if err != nil {
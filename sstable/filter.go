@@ -43,20 +43,33 @@ type filterWriter interface {
 	policyName() string
 }
 
+// filterMetaName returns the name of the metaindex entry for a filter of the
+// provided type and policy.
+func filterMetaName(ftype FilterType, policyName string) string {
+	if ftype == RangeFilter {
+		return "rangefilter." + policyName
+	}
+	return "fullfilter." + policyName
+}
+
 type tableFilterReader struct {
 	policy  FilterPolicy
+	ftype   FilterType
 	metrics *FilterMetricsTracker
 }
 
-func newTableFilterReader(policy FilterPolicy, metrics *FilterMetricsTracker) *tableFilterReader {
+func newTableFilterReader(
+	policy FilterPolicy, ftype FilterType, metrics *FilterMetricsTracker,
+) *tableFilterReader {
 	return &tableFilterReader{
 		policy:  policy,
+		ftype:   ftype,
 		metrics: metrics,
 	}
 }
 
 func (f *tableFilterReader) mayContain(data, key []byte) bool {
-	mayContain := f.policy.MayContain(TableFilter, data, key)
+	mayContain := f.policy.MayContain(f.ftype, data, key)
 	if f.metrics != nil {
 		if mayContain {
 			f.metrics.misses.Add(1)
@@ -67,17 +80,30 @@ func (f *tableFilterReader) mayContain(data, key []byte) bool {
 	return mayContain
 }
 
+// supportsRange returns true if the filter can answer range queries.
+func (f *tableFilterReader) supportsRange() bool {
+	return f.ftype == RangeFilter
+}
+
+// mayContainRange returns whether the filter may contain a key prefix p such
+// that lower <= p <= upper. It must only be called if supportsRange is true.
+func (f *tableFilterReader) mayContainRange(data, lower, upper []byte) bool {
+	return f.policy.(RangeFilterPolicy).MayContainRange(data, lower, upper)
+}
+
 type tableFilterWriter struct {
 	policy FilterPolicy
+	ftype  FilterType
 	writer FilterWriter
 	// count is the count of the number of keys added to the filter.
 	count int
 }
 
-func newTableFilterWriter(policy FilterPolicy) *tableFilterWriter {
+func newTableFilterWriter(policy FilterPolicy, ftype FilterType) *tableFilterWriter {
 	return &tableFilterWriter{
 		policy: policy,
-		writer: policy.NewWriter(TableFilter),
+		ftype:  ftype,
+		writer: policy.NewWriter(ftype),
 	}
 }
 
@@ -94,7 +120,7 @@ func (f *tableFilterWriter) finish() ([]byte, error) {
 }
 
 func (f *tableFilterWriter) metaName() string {
-	return "fullfilter." + f.policy.Name()
+	return filterMetaName(f.ftype, f.policy.Name())
 }
 
 func (f *tableFilterWriter) policyName() string {
@@ -29,6 +29,9 @@ const (
 	TableFormatPebblev3 // Value blocks.
 	TableFormatPebblev4 // DELSIZED tombstones.
 	TableFormatPebblev5 // Columnar blocks.
+	TableFormatPebblev6 // Blob value handles.
+	TableFormatPebblev7 // LZ4 and MinLZ block compression.
+	TableFormatPebblev8 // Compression dictionaries.
 	NumTableFormats
 
 	TableFormatMax = NumTableFormats - 1
@@ -236,6 +239,12 @@ func parseTableFormat(magic []byte, version uint32) (TableFormat, error) {
 			return TableFormatPebblev4, nil
 		case 5:
 			return TableFormatPebblev5, nil
+		case 6:
+			return TableFormatPebblev6, nil
+		case 7:
+			return TableFormatPebblev7, nil
+		case 8:
+			return TableFormatPebblev8, nil
 		default:
 			return TableFormatUnspecified, base.CorruptionErrorf(
 				"(unsupported pebble format version %d)", errors.Safe(version))
@@ -252,6 +261,25 @@ func (f TableFormat) BlockColumnar() bool {
 	return f >= TableFormatPebblev5
 }
 
+// SupportsCompression returns true iff blocks compressed with the provided
+// compression may be written to tables of this format. Blocks compressed with
+// algorithms introduced by a later format must not be written to tables of an
+// older format, so that older versions of Pebble reject such tables on open
+// rather than failing to decompress individual blocks.
+func (f TableFormat) SupportsCompression(c block.Compression) bool {
+	switch c.Algorithm() {
+	case block.Lz4Compression, block.MinLZCompression:
+		return f >= TableFormatPebblev7
+	}
+	return true
+}
+
+// SupportsCompressionDict returns true iff data blocks of tables of this format
+// may be compressed with a compression dictionary.
+func (f TableFormat) SupportsCompressionDict() bool {
+	return f >= TableFormatPebblev8
+}
+
 func (f TableFormat) newIndexIter() block.IndexBlockIterator {
 	if !f.BlockColumnar() {
 		return new(rowblk.IndexIter)
@@ -276,6 +304,12 @@ func (f TableFormat) AsTuple() (string, uint32) {
 		return pebbleDBMagic, 4
 	case TableFormatPebblev5:
 		return pebbleDBMagic, 5
+	case TableFormatPebblev6:
+		return pebbleDBMagic, 6
+	case TableFormatPebblev7:
+		return pebbleDBMagic, 7
+	case TableFormatPebblev8:
+		return pebbleDBMagic, 8
 	default:
 		panic("sstable: unknown table format version tuple")
 	}
@@ -300,6 +334,12 @@ func (f TableFormat) String() string {
 		return "(Pebble,v4)"
 	case TableFormatPebblev5:
 		return "(Pebble,v5)"
+	case TableFormatPebblev6:
+		return "(Pebble,v6)"
+	case TableFormatPebblev7:
+		return "(Pebble,v7)"
+	case TableFormatPebblev8:
+		return "(Pebble,v8)"
 	default:
 		panic("sstable: unknown table format version tuple")
 	}
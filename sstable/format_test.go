@@ -65,6 +65,24 @@ func TestTableFormat_RoundTrip(t *testing.T) {
 			version: 5,
 			want:    TableFormatPebblev5,
 		},
+		{
+			name:    "PebbleDBv6",
+			magic:   pebbleDBMagic,
+			version: 6,
+			want:    TableFormatPebblev6,
+		},
+		{
+			name:    "PebbleDBv7",
+			magic:   pebbleDBMagic,
+			version: 7,
+			want:    TableFormatPebblev7,
+		},
+		{
+			name:    "PebbleDBv8",
+			magic:   pebbleDBMagic,
+			version: 8,
+			want:    TableFormatPebblev8,
+		},
 		// Invalid cases.
 		{
 			name:    "Invalid RocksDB version",
@@ -75,8 +93,8 @@ func TestTableFormat_RoundTrip(t *testing.T) {
 		{
 			name:    "Invalid PebbleDB version",
 			magic:   pebbleDBMagic,
-			version: 6,
-			wantErr: "pebble/table: invalid table 000001: (unsupported pebble format version 6)",
+			version: 9,
+			wantErr: "pebble/table: invalid table 000001: (unsupported pebble format version 9)",
 		},
 		{
 			name:    "Unknown magic string",
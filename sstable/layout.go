@@ -41,10 +41,13 @@ type Layout struct {
 	RangeKey   block.Handle
 	ValueBlock []block.Handle
 	ValueIndex block.Handle
-	Properties block.Handle
-	MetaIndex  block.Handle
-	Footer     block.Handle
-	Format     TableFormat
+	// CompressionDict is the handle of the compression dictionary block, if
+	// the table's data blocks are compressed with a dictionary.
+	CompressionDict block.Handle
+	Properties      block.Handle
+	MetaIndex       block.Handle
+	Footer          block.Handle
+	Format          TableFormat
 }
 
 // NamedBlockHandle holds a block.Handle and corresponding name.
@@ -89,6 +92,9 @@ func (l *Layout) orderedBlocks() []NamedBlockHandle {
 	if l.ValueIndex.Length != 0 {
 		blocks = append(blocks, NamedBlockHandle{l.ValueIndex, "value-index"})
 	}
+	if l.CompressionDict.Length != 0 {
+		blocks = append(blocks, NamedBlockHandle{l.CompressionDict, "compression-dict"})
+	}
 	if l.Properties.Length != 0 {
 		blocks = append(blocks, NamedBlockHandle{l.Properties, "properties"})
 	}
@@ -113,6 +119,19 @@ func (l *Layout) orderedBlocks() []NamedBlockHandle {
 // If verbose is true and fmtKV is non-nil, the output includes the KVs (as formatted by this function).
 func (l *Layout) Describe(
 	verbose bool, r *Reader, fmtKV func(key *base.InternalKey, value []byte) string,
+) string {
+	return l.describe(verbose, false /* compression */, r, fmtKV)
+}
+
+// DescribeCompression returns a description of the layout that includes the
+// compression algorithm of each block and, for compressed blocks, the ratio of
+// the block's decompressed length to its physical length.
+func (l *Layout) DescribeCompression(r *Reader) string {
+	return l.describe(false /* verbose */, true /* compression */, r, nil /* fmtKV */)
+}
+
+func (l *Layout) describe(
+	verbose, compression bool, r *Reader, fmtKV func(key *base.InternalKey, value []byte) string,
 ) string {
 	ctx := context.TODO()
 
@@ -127,6 +146,11 @@ func (l *Layout) Describe(
 
 	for i := range blocks {
 		b := &blocks[i]
+		if compression && b.Name != "footer" && b.Name != "leveldb-footer" {
+			root.Childf("%s  offset: %d  length: %d  compression: %s",
+				b.Name, b.Offset, b.Length, describeBlockCompression(ctx, r, b.Handle))
+			continue
+		}
 		tpNode := root.Childf("%s  offset: %d  length: %d", b.Name, b.Offset, b.Length)
 
 		if !verbose {
@@ -297,6 +321,29 @@ func (l *Layout) Describe(
 	return tp.String()
 }
 
+// describeBlockCompression returns a description of the compression algorithm
+// of the block with the provided handle. For compressed blocks, the description
+// includes the ratio of the block's decompressed length to its physical length.
+func describeBlockCompression(ctx context.Context, r *Reader, bh block.Handle) string {
+	trailer := make([]byte, block.TrailerLen)
+	if err := r.blockReader.Readable().ReadAt(ctx, trailer, int64(bh.Offset+bh.Length)); err != nil {
+		return fmt.Sprintf("[err: %s]", err)
+	}
+	algo := block.CompressionIndicator(trailer[0])
+	if algo == block.NoCompressionIndicator || bh.Length == 0 {
+		return algo.String()
+	}
+	data := make([]byte, bh.Length)
+	if err := r.blockReader.Readable().ReadAt(ctx, data, int64(bh.Offset)); err != nil {
+		return fmt.Sprintf("[err: %s]", err)
+	}
+	decompressedLen, _, err := block.DecompressedLen(algo, data)
+	if err != nil {
+		return fmt.Sprintf("[err: %s]", err)
+	}
+	return fmt.Sprintf("%s ratio=%.2fx", algo, float64(decompressedLen)/float64(bh.Length))
+}
+
 type blockFormatting struct {
 	formatIndexBlock   formatBlockFunc
 	formatDataBlock    formatBlockFuncKV
@@ -714,6 +761,13 @@ func (w *layoutWriter) WriteFilterBlock(f filterWriter) (bh block.Handle, err er
 	return w.writeNamedBlock(b, f.metaName())
 }
 
+// WriteCompressionDictBlock writes the provided compression dictionary
+// uncompressed. It automatically adds the dictionary block to the file's meta
+// index when the writer is finished.
+func (w *layoutWriter) WriteCompressionDictBlock(b []byte) (block.Handle, error) {
+	return w.writeNamedBlock(b, metaCompressionDictName)
+}
+
 // WritePropertiesBlock constructs a trailer for the provided properties block
 // and writes the block and trailer to the writer. It automatically adds the
 // properties block to the file's meta index when the writer is finished.
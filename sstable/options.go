@@ -41,9 +41,10 @@ var ignoredInternalProperties = map[string]struct{}{
 // FilterType exports the base.FilterType type.
 type FilterType = base.FilterType
 
-// Exported TableFilter constants.
+// Exported FilterType constants.
 const (
 	TableFilter = base.TableFilter
+	RangeFilter = base.RangeFilter
 )
 
 // FilterWriter exports the base.FilterWriter type.
@@ -52,6 +53,9 @@ type FilterWriter = base.FilterWriter
 // FilterPolicy exports the base.FilterPolicy type.
 type FilterPolicy = base.FilterPolicy
 
+// RangeFilterPolicy exports the base.RangeFilterPolicy type.
+type RangeFilterPolicy = base.RangeFilterPolicy
+
 // Comparers is a map from comparer name to comparer. It is used for debugging
 // tools which may be used on multiple databases configured with different
 // comparers.
@@ -169,9 +173,23 @@ type WriterOptions struct {
 
 	// Compression defines the per-block compression to use.
 	//
-	// The default value (DefaultCompression) uses snappy compression.
+	// The default value (DefaultCompression) uses snappy compression. Snappy
+	// compression is also used if the compression is not supported by the
+	// TableFormat.
 	Compression block.Compression
 
+	// CompressionDictSize is the maximum size in bytes of a compression
+	// dictionary trained from the table's data blocks. When non-zero, the
+	// writer samples the first data blocks of the table, trains a dictionary
+	// from them and compresses the remaining data blocks using the dictionary.
+	// The dictionary is stored in the table and loaded by readers when the table
+	// is opened. Dictionaries are most effective for tables with many small
+	// blocks containing similar content, and are only used with zstd
+	// compression and TableFormatPebblev8 or newer.
+	//
+	// The default value (0) disables compression dictionaries.
+	CompressionDictSize int
+
 	// FilterPolicy defines a filter algorithm (such as a Bloom filter) that can
 	// reduce disk reads for Get calls.
 	//
@@ -333,7 +351,7 @@ func (o WriterOptions) ensureDefaults() WriterOptions {
 	if o.Comparer == nil {
 		o.Comparer = base.DefaultComparer
 	}
-	if o.Compression <= block.DefaultCompression || o.Compression >= block.NCompression {
+	if o.Compression.Algorithm() <= block.DefaultCompression || o.Compression.Algorithm() >= block.NCompression {
 		o.Compression = block.SnappyCompression
 	}
 	if o.IndexBlockSize <= 0 {
@@ -350,6 +368,9 @@ func (o WriterOptions) ensureDefaults() WriterOptions {
 	if o.TableFormat == TableFormatUnspecified {
 		o.TableFormat = TableFormatMinSupported
 	}
+	if !o.TableFormat.SupportsCompression(o.Compression) {
+		o.Compression = block.SnappyCompression
+	}
 	if o.NumDeletionsThreshold == 0 {
 		o.NumDeletionsThreshold = DefaultNumDeletionsThreshold
 	}
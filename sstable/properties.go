@@ -108,6 +108,9 @@ type CommonProperties struct {
 	// in manifest.TableStats for the criteria used to determine if a data
 	// block is tombstone-dense.
 	NumTombstoneDenseBlocks uint64 `prop:"pebble.num.tombstone-dense-blocks"`
+	// The encoded manifest.ExpiryHistogram describing when the point keys in
+	// this table expire. Only serialized if some keys expire.
+	ExpiryHistogram string `prop:"pebble.expiry-histogram"`
 	// The compression algorithm used to compress blocks.
 	CompressionName string `prop:"rocksdb.compression"`
 	// The compression options used to compress blocks.
@@ -140,6 +143,19 @@ type Properties struct {
 
 	// The name of the comparer used in this table.
 	ComparerName string `prop:"rocksdb.comparator"`
+	// The size of the compression dictionary used to compress data blocks. Only
+	// serialized if > 0.
+	CompressionDictSize uint64 `prop:"pebble.compression-dict.size"`
+	// A sample of the data blocks compressed with the compression dictionary
+	// are also compressed without it, to measure the dictionary's benefit.
+	// CompressionDictSampledSize is the uncompressed size of the sampled
+	// blocks, and CompressionDictSampledWithDictSize and
+	// CompressionDictSampledWithoutDictSize are their sizes when compressed
+	// with and without the dictionary. Only serialized if CompressionDictSize
+	// > 0.
+	CompressionDictSampledSize            uint64 `prop:"pebble.compression-dict.sampled.size"`
+	CompressionDictSampledWithDictSize    uint64 `prop:"pebble.compression-dict.sampled.with-dict.size"`
+	CompressionDictSampledWithoutDictSize uint64 `prop:"pebble.compression-dict.sampled.without-dict.size"`
 	// The total size of all data blocks.
 	DataSize uint64 `prop:"rocksdb.data.size"`
 	// The name of the filter policy used in this table. Empty if no filter
@@ -169,6 +185,8 @@ type Properties struct {
 	NumValueBlocks uint64 `prop:"pebble.num.value-blocks"`
 	// The number of values stored in value blocks. Only serialized if > 0.
 	NumValuesInValueBlocks uint64 `prop:"pebble.num.values.in.value-blocks"`
+	// The number of values stored in blob files. Only serialized if > 0.
+	NumValuesInBlobFiles uint64 `prop:"pebble.num.values.in.blob-files"`
 	// A comma separated list of names of the property collectors used in this
 	// table.
 	PropertyCollectorNames string `prop:"rocksdb.property.collectors"`
@@ -356,6 +374,9 @@ func (p *Properties) save(tblFormat TableFormat, w *rowblk.Writer) {
 		p.saveString(m, unsafe.Offsetof(p.CompressionOptions), p.CompressionOptions)
 	}
 	p.saveUvarint(m, unsafe.Offsetof(p.DataSize), p.DataSize)
+	if p.ExpiryHistogram != "" {
+		p.saveString(m, unsafe.Offsetof(p.ExpiryHistogram), p.ExpiryHistogram)
+	}
 	if p.FilterPolicyName != "" {
 		p.saveString(m, unsafe.Offsetof(p.FilterPolicyName), p.FilterPolicyName)
 	}
@@ -406,6 +427,9 @@ func (p *Properties) save(tblFormat TableFormat, w *rowblk.Writer) {
 	if p.NumValuesInValueBlocks > 0 {
 		p.saveUvarint(m, unsafe.Offsetof(p.NumValuesInValueBlocks), p.NumValuesInValueBlocks)
 	}
+	if p.NumValuesInBlobFiles > 0 {
+		p.saveUvarint(m, unsafe.Offsetof(p.NumValuesInBlobFiles), p.NumValuesInBlobFiles)
+	}
 	if p.PropertyCollectorNames != "" {
 		p.saveString(m, unsafe.Offsetof(p.PropertyCollectorNames), p.PropertyCollectorNames)
 	}
@@ -422,6 +446,12 @@ func (p *Properties) save(tblFormat TableFormat, w *rowblk.Writer) {
 	if p.NumTombstoneDenseBlocks != 0 {
 		p.saveUvarint(m, unsafe.Offsetof(p.NumTombstoneDenseBlocks), p.NumTombstoneDenseBlocks)
 	}
+	if p.CompressionDictSize > 0 {
+		p.saveUvarint(m, unsafe.Offsetof(p.CompressionDictSize), p.CompressionDictSize)
+		p.saveUvarint(m, unsafe.Offsetof(p.CompressionDictSampledSize), p.CompressionDictSampledSize)
+		p.saveUvarint(m, unsafe.Offsetof(p.CompressionDictSampledWithDictSize), p.CompressionDictSampledWithDictSize)
+		p.saveUvarint(m, unsafe.Offsetof(p.CompressionDictSampledWithoutDictSize), p.CompressionDictSampledWithoutDictSize)
+	}
 
 	if tblFormat < TableFormatPebblev1 {
 		m["rocksdb.column.family.id"] = binary.AppendUvarint([]byte(nil), math.MaxInt32)
@@ -86,6 +86,7 @@ var testProps = Properties{
 	NumRangeKeyUnsets:      21,
 	NumValueBlocks:         22,
 	NumValuesInValueBlocks: 23,
+	NumValuesInBlobFiles:   24,
 	PropertyCollectorNames: "prefix collector names",
 	TopLevelIndexSize:      27,
 	UserProperties: map[string]string{
@@ -113,6 +113,7 @@ func runErrorInjectionTest(t *testing.T, seed int64) {
 		filterBlockSizeLimit,
 		block.ReadEnv{Stats: &stats, IterStats: nil},
 		MakeTrivialReaderProvider(r),
+		AssertNoBlobHandles,
 	)
 	require.NoError(t, err)
 	defer it.Close()
@@ -5,6 +5,7 @@
 package sstable
 
 import (
+	"bytes"
 	"cmp"
 	"context"
 	"fmt"
@@ -51,14 +52,15 @@ type Reader struct {
 
 	err error
 
-	indexBH      block.Handle
-	filterBH     block.Handle
-	rangeDelBH   block.Handle
-	rangeKeyBH   block.Handle
-	valueBIH     valblk.IndexHandle
-	propertiesBH block.Handle
-	metaindexBH  block.Handle
-	footerBH     block.Handle
+	indexBH           block.Handle
+	filterBH          block.Handle
+	rangeDelBH        block.Handle
+	rangeKeyBH        block.Handle
+	valueBIH          valblk.IndexHandle
+	compressionDictBH block.Handle
+	propertiesBH      block.Handle
+	metaindexBH       block.Handle
+	footerBH          block.Handle
 
 	Properties  Properties
 	tableFormat TableFormat
@@ -90,10 +92,11 @@ func (r *Reader) NewPointIter(
 	filterBlockSizeLimit FilterBlockSizeLimit,
 	env block.ReadEnv,
 	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
 ) (Iterator, error) {
 	return r.newPointIter(
 		ctx, transforms, lower, upper, filterer, filterBlockSizeLimit,
-		env, rp, nil)
+		env, rp, blobContext, nil)
 }
 
 // TryAddBlockPropertyFilterForHideObsoletePoints is expected to be called
@@ -120,6 +123,7 @@ func (r *Reader) newPointIter(
 	filterBlockSizeLimit FilterBlockSizeLimit,
 	env block.ReadEnv,
 	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
 	vState *virtualState,
 ) (Iterator, error) {
 	// NB: pebble.fileCache wraps the returned iterator with one which performs
@@ -131,7 +135,7 @@ func (r *Reader) newPointIter(
 		if r.tableFormat.BlockColumnar() {
 			res, err = newColumnBlockTwoLevelIterator(
 				ctx, r, vState, transforms, lower, upper, filterer, filterBlockSizeLimit,
-				env, rp)
+				env, rp, blobContext)
 		} else {
 			res, err = newRowBlockTwoLevelIterator(
 				ctx, r, vState, transforms, lower, upper, filterer, filterBlockSizeLimit,
@@ -141,7 +145,7 @@ func (r *Reader) newPointIter(
 		if r.tableFormat.BlockColumnar() {
 			res, err = newColumnBlockSingleLevelIterator(
 				ctx, r, vState, transforms, lower, upper, filterer, filterBlockSizeLimit,
-				env, rp)
+				env, rp, blobContext)
 		} else {
 			res, err = newRowBlockSingleLevelIterator(
 				ctx, r, vState, transforms, lower, upper, filterer, filterBlockSizeLimit,
@@ -167,20 +171,27 @@ func (r *Reader) NewIter(transforms IterTransforms, lower, upper []byte) (Iterat
 	// likely isn't a cache set up.
 	return r.NewPointIter(
 		context.TODO(), transforms, lower, upper, nil, AlwaysUseFilterBlock,
-		block.NoReadEnv, MakeTrivialReaderProvider(r))
+		block.NoReadEnv, MakeTrivialReaderProvider(r), AssertNoBlobHandles)
 }
 
 // NewCompactionIter returns an iterator similar to NewIter but it also increments
 // the number of bytes iterated. If an error occurs, NewCompactionIter cleans up
 // after itself and returns a nil iterator.
 func (r *Reader) NewCompactionIter(
-	transforms IterTransforms, env block.ReadEnv, rp valblk.ReaderProvider,
+	transforms IterTransforms,
+	env block.ReadEnv,
+	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
 ) (Iterator, error) {
-	return r.newCompactionIter(transforms, env, rp, nil)
+	return r.newCompactionIter(transforms, env, rp, blobContext, nil)
 }
 
 func (r *Reader) newCompactionIter(
-	transforms IterTransforms, env block.ReadEnv, rp valblk.ReaderProvider, vState *virtualState,
+	transforms IterTransforms,
+	env block.ReadEnv,
+	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
+	vState *virtualState,
 ) (Iterator, error) {
 	if vState != nil && vState.isSharedIngested {
 		transforms.HideObsoletePoints = true
@@ -201,7 +212,7 @@ func (r *Reader) newCompactionIter(
 		i, err := newColumnBlockTwoLevelIterator(
 			context.Background(),
 			r, vState, transforms, nil /* lower */, nil /* upper */, nil,
-			NeverUseFilterBlock, env, rp)
+			NeverUseFilterBlock, env, rp, blobContext)
 		if err != nil {
 			return nil, err
 		}
@@ -220,7 +231,7 @@ func (r *Reader) newCompactionIter(
 	}
 	i, err := newColumnBlockSingleLevelIterator(
 		context.Background(), r, vState, transforms, nil /* lower */, nil, /* upper */
-		nil, NeverUseFilterBlock, env, rp)
+		nil, NeverUseFilterBlock, env, rp, blobContext)
 	if err != nil {
 		return nil, err
 	}
@@ -459,10 +470,35 @@ func (r *Reader) readMetaindex(
 		r.rangeKeyBH = bh
 	}
 
+	if bh, ok := meta[metaCompressionDictName]; ok {
+		// The dictionary is loaded once, and retained for the lifetime of the
+		// Reader (which is typically cached in the file cache).
+		b, err = r.blockReader.Read(ctx, metaEnv, readHandle, bh, noInitBlockMetadataFn)
+		if err != nil {
+			return err
+		}
+		raw := slices.Clone(b.BlockData())
+		b.Release()
+		dict, err := block.NewCompressionDict(raw, block.DefaultZstdLevel)
+		if err != nil {
+			return base.MarkCorruptionError(err)
+		}
+		r.compressionDictBH = bh
+		r.blockReader.SetCompressionDict(dict)
+	}
+
 	for name, fp := range filters {
-		if bh, ok := meta["fullfilter."+name]; ok {
+		if bh, ok := meta[filterMetaName(TableFilter, name)]; ok {
 			r.filterBH = bh
-			r.tableFilter = newTableFilterReader(fp, r.filterMetricsTracker)
+			r.tableFilter = newTableFilterReader(fp, TableFilter, r.filterMetricsTracker)
+			break
+		}
+		if _, ok := fp.(RangeFilterPolicy); !ok {
+			continue
+		}
+		if bh, ok := meta[filterMetaName(RangeFilter, name)]; ok {
+			r.filterBH = bh
+			r.tableFilter = newTableFilterReader(fp, RangeFilter, r.filterMetricsTracker)
 			break
 		}
 	}
@@ -476,17 +512,21 @@ func (r *Reader) Layout() (*Layout, error) {
 	}
 
 	l := &Layout{
-		Data:       make([]block.HandleWithProperties, 0, r.Properties.NumDataBlocks),
-		RangeDel:   r.rangeDelBH,
-		RangeKey:   r.rangeKeyBH,
-		ValueIndex: r.valueBIH.Handle,
-		Properties: r.propertiesBH,
-		MetaIndex:  r.metaindexBH,
-		Footer:     r.footerBH,
-		Format:     r.tableFormat,
+		Data:            make([]block.HandleWithProperties, 0, r.Properties.NumDataBlocks),
+		RangeDel:        r.rangeDelBH,
+		RangeKey:        r.rangeKeyBH,
+		ValueIndex:      r.valueBIH.Handle,
+		CompressionDict: r.compressionDictBH,
+		Properties:      r.propertiesBH,
+		MetaIndex:       r.metaindexBH,
+		Footer:          r.footerBH,
+		Format:          r.tableFormat,
 	}
 	if r.filterBH.Length > 0 {
-		l.Filter = []NamedBlockHandle{{Name: "fullfilter." + r.tableFilter.policy.Name(), Handle: r.filterBH}}
+		l.Filter = []NamedBlockHandle{{
+			Name:   filterMetaName(r.tableFilter.ftype, r.tableFilter.policy.Name()),
+			Handle: r.filterBH,
+		}}
 	}
 	ctx := context.TODO()
 
@@ -620,6 +660,10 @@ func (r *Reader) ValidateBlockChecksums() error {
 	readNoInit := func(ctx context.Context, env block.ReadEnv, rh objstorage.ReadHandle, bh block.Handle) (block.BufferHandle, error) {
 		return r.blockReader.Read(ctx, env, rh, bh, noInitBlockMetadataFn)
 	}
+	blocks = append(blocks, blk{
+		bh:     l.CompressionDict,
+		readFn: readNoInit,
+	})
 	blocks = append(blocks, blk{
 		bh:     l.Properties,
 		readFn: readNoInit,
@@ -656,6 +700,42 @@ func (r *Reader) CommonProperties() *CommonProperties {
 	return &r.Properties.CommonProperties
 }
 
+// MayContainPrefixes sets mayContain[i] to false if the table's filter proves
+// that the table contains no point keys with the prefix prefixes[i], and to true
+// otherwise. The filter block is read once for all the prefixes. The prefixes
+// are checked after removing the synthetic prefix of the provided transforms.
+// If the table has no filter, every element of mayContain is set to true.
+func (r *Reader) MayContainPrefixes(
+	ctx context.Context,
+	env block.ReadEnv,
+	transforms IterTransforms,
+	prefixes [][]byte,
+	mayContain []bool,
+) error {
+	if r.tableFilter == nil {
+		for i := range mayContain {
+			mayContain[i] = true
+		}
+		return nil
+	}
+	dataH, err := r.readFilterBlock(ctx, env, nil /* readHandle */, r.filterBH)
+	if err != nil {
+		return err
+	}
+	defer dataH.Release()
+	for i, prefix := range prefixes {
+		if transforms.HasSyntheticPrefix() {
+			var ok bool
+			if prefix, ok = bytes.CutPrefix(prefix, transforms.SyntheticPrefix()); !ok {
+				mayContain[i] = false
+				continue
+			}
+		}
+		mayContain[i] = r.tableFilter.mayContain(dataH.BlockData(), prefix)
+	}
+	return nil
+}
+
 // EstimateDiskUsage returns the total size of data blocks overlapping the range
 // `[start, end]`. Even if a data block partially overlaps, or we cannot
 // determine overlap due to abbreviated index keys, the full data block size is
@@ -33,12 +33,14 @@ type CommonReader interface {
 		filterBlockSizeLimit FilterBlockSizeLimit,
 		env block.ReadEnv,
 		rp valblk.ReaderProvider,
+		blobContext TableBlobContext,
 	) (Iterator, error)
 
 	NewCompactionIter(
 		transforms IterTransforms,
 		env block.ReadEnv,
 		rp valblk.ReaderProvider,
+		blobContext TableBlobContext,
 	) (Iterator, error)
 
 	EstimateDiskUsage(start, end []byte) (uint64, error)
@@ -64,8 +64,8 @@ type singleLevelIterator[I any, PI indexBlockIterator[I], D any, PD dataBlockIte
 	// dataBH refers to the last data block that the iterator considered
 	// loading. It may not actually have loaded the block, due to an error or
 	// because it was considered irrelevant.
-	dataBH   block.Handle
-	vbReader valblk.Reader
+	dataBH                   block.Handle
+	internalValueConstructor defaultInternalValueConstructor
 	// vbRH is the read handle for value blocks, which are in a different
 	// part of the sstable than data blocks.
 	vbRH         objstorage.ReadHandle
@@ -167,6 +167,11 @@ type singleLevelIterator[I any, PI indexBlockIterator[I], D any, PD dataBlockIte
 	// a match is high).
 	useFilterBlock         bool
 	lastBloomFilterMatched bool
+	// rangeFilterState caches whether the range filter, if any, proved that
+	// the table contains no keys within the current bounds: +1 if the table
+	// may contain keys within the bounds, -1 if it does not, and 0 if the
+	// filter has not been consulted since the bounds were last set.
+	rangeFilterState int8
 
 	transforms IterTransforms
 
@@ -211,6 +216,7 @@ func newColumnBlockSingleLevelIterator(
 	filterBlockSizeLimit FilterBlockSizeLimit,
 	env block.ReadEnv,
 	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
 ) (*singleLevelIteratorColumnBlocks, error) {
 	if r.err != nil {
 		return nil, r.err
@@ -225,9 +231,12 @@ func newColumnBlockSingleLevelIterator(
 		env,
 	)
 	var getInternalValuer block.GetInternalValueForPrefixAndValueHandler
+	if r.Properties.NumValueBlocks > 0 || r.Properties.NumValuesInBlobFiles > 0 {
+		i.internalValueConstructor.blobContext = blobContext
+		getInternalValuer = &i.internalValueConstructor
+	}
 	if r.Properties.NumValueBlocks > 0 {
-		i.vbReader = valblk.MakeReader(i, rp, r.valueBIH, env.Stats)
-		getInternalValuer = &i.vbReader
+		i.internalValueConstructor.vbReader = valblk.MakeReader(i, rp, r.valueBIH, env.Stats)
 		i.vbRH = r.blockReader.UsePreallocatedReadHandle(objstorage.NoReadBefore, &i.vbRHPrealloc)
 	}
 	i.data.InitOnce(r.keySchema, r.Comparer, getInternalValuer)
@@ -274,8 +283,8 @@ func newRowBlockSingleLevelIterator(
 	)
 	if r.tableFormat >= TableFormatPebblev3 {
 		if r.Properties.NumValueBlocks > 0 {
-			i.vbReader = valblk.MakeReader(i, rp, r.valueBIH, env.Stats)
-			(&i.data).SetGetLazyValuer(&i.vbReader)
+			i.internalValueConstructor.vbReader = valblk.MakeReader(i, rp, r.valueBIH, env.Stats)
+			(&i.data).SetGetLazyValuer(&i.internalValueConstructor.vbReader)
 			i.vbRH = r.blockReader.UsePreallocatedReadHandle(objstorage.NoReadBefore, &i.vbRHPrealloc)
 		}
 		i.data.SetHasValuePrefix(true)
@@ -470,6 +479,7 @@ func (i *singleLevelIterator[I, PI, P, PD]) SetBounds(lower, upper []byte) {
 	}
 
 	i.positionedUsingLatestBounds = false
+	i.rangeFilterState = 0
 	i.lower = lower
 	i.upper = upper
 	i.blockLower = nil
@@ -685,6 +695,10 @@ func (i *singleLevelIterator[I, PI, D, PD]) SeekGE(
 		}
 	}
 
+	if i.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
+
 	if flags.TrySeekUsingNext() {
 		// The i.exhaustedBounds comparison indicates that the upper bound was
 		// reached. The i.data.isDataInvalidated() indicates that the sstable was
@@ -922,6 +936,55 @@ func (i *singleLevelIterator[I, PI, D, PD]) bloomFilterMayContain(prefix []byte)
 	return i.reader.tableFilter.mayContain(dataH.BlockData(), prefixToCheck), nil
 }
 
+// rangeFilterExcludesBounds returns true if the table's range filter proves
+// that the table contains no keys within the iterator bounds, or if an error
+// occurred while reading the filter. In either case the iterator is
+// invalidated, and the caller should return nil. The filter is consulted at
+// most once per SetBounds call. The useFilterBlock parameter is provided by the
+// caller, since the twoLevelIterator disables the filter block of its embedded
+// singleLevelIterator.
+func (i *singleLevelIterator[I, PI, D, PD]) rangeFilterExcludesBounds(useFilterBlock bool) bool {
+	if i.rangeFilterState == 0 {
+		i.rangeFilterState = +1
+		if !useFilterBlock || !i.reader.tableFilter.supportsRange() ||
+			(i.lower == nil && i.upper == nil) || i.transforms.HasSyntheticPrefix() {
+			return false
+		}
+		// The filter contains key prefixes. Every key within the bounds has a
+		// prefix p such that prefix(lower) <= p <= prefix(upper).
+		var lower, upper []byte
+		if i.lower != nil {
+			lower = i.lower[:i.reader.Comparer.Split(i.lower)]
+		}
+		if i.upper != nil {
+			upper = i.upper[:i.reader.Comparer.Split(i.upper)]
+		}
+		dataH, err := i.reader.readFilterBlock(i.ctx, i.readBlockEnv, i.indexFilterRH, i.reader.filterBH)
+		if err != nil {
+			i.rangeFilterState = 0
+			i.err = err
+			PD(&i.data).Invalidate()
+			return true
+		}
+		mayContain := i.reader.tableFilter.mayContainRange(dataH.BlockData(), lower, upper)
+		dataH.Release()
+		if mayContain {
+			return false
+		}
+		i.rangeFilterState = -1
+		if i.readBlockEnv.Stats != nil {
+			i.readBlockEnv.Stats.RangeFilterSkippedTables++
+		}
+	}
+	if i.rangeFilterState > 0 {
+		return false
+	}
+	i.exhaustedBounds = 0
+	i.err = nil
+	PD(&i.data).Invalidate()
+	return true
+}
+
 // virtualLast should only be called if i.vReader != nil.
 func (i *singleLevelIterator[I, PI, D, PD]) virtualLast() *base.InternalKV {
 	if i.vState == nil {
@@ -1042,6 +1105,9 @@ func (i *singleLevelIterator[I, PI, D, PD]) SeekLT(
 			return i.maybeVerifyKey(i.virtualLast())
 		}
 	}
+	if i.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
 
 	i.exhaustedBounds = 0
 	i.err = nil // clear cached iteration error
@@ -1140,6 +1206,9 @@ func (i *singleLevelIterator[I, PI, D, PD]) First() *base.InternalKV {
 	if i.lower != nil {
 		return i.SeekGE(i.lower, base.SeekGEFlagsNone)
 	}
+	if i.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
 
 	i.positionedUsingLatestBounds = true
 
@@ -1206,6 +1275,9 @@ func (i *singleLevelIterator[I, PI, D, PD]) Last() *base.InternalKV {
 	if i.upper != nil {
 		panic("singleLevelIterator.Last() used despite upper bound")
 	}
+	if i.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
 	i.positionedUsingLatestBounds = true
 	return i.lastInternal()
 }
@@ -1562,7 +1634,7 @@ func (i *singleLevelIterator[I, PI, D, PD]) closeInternal() error {
 	if i.bpfs != nil {
 		releaseBlockPropertiesFilterer(i.bpfs)
 	}
-	i.vbReader.Close()
+	i.internalValueConstructor.vbReader.Close()
 	if i.vbRH != nil {
 		err = firstError(err, i.vbRH.Close())
 		i.vbRH = nil
@@ -162,6 +162,7 @@ func newColumnBlockTwoLevelIterator(
 	filterBlockSizeLimit FilterBlockSizeLimit,
 	env block.ReadEnv,
 	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
 ) (*twoLevelIteratorColumnBlocks, error) {
 	if r.err != nil {
 		return nil, r.err
@@ -174,6 +175,10 @@ func newColumnBlockTwoLevelIterator(
 		false, // Disable the use of the filter block in the second level.
 		env)
 	var getInternalValuer block.GetInternalValueForPrefixAndValueHandler
+	if r.Properties.NumValueBlocks > 0 || r.Properties.NumValuesInBlobFiles > 0 {
+		i.secondLevel.internalValueConstructor.blobContext = blobContext
+		getInternalValuer = &i.secondLevel.internalValueConstructor
+	}
 	if r.Properties.NumValueBlocks > 0 {
 		// NB: we cannot avoid this ~248 byte allocation, since valueBlockReader
 		// can outlive the singleLevelIterator due to be being embedded in a
@@ -184,8 +189,7 @@ func newColumnBlockTwoLevelIterator(
 		// versions of keys, and therefore never expose a LazyValue that is
 		// separated to their callers, they can put this valueBlockReader into a
 		// sync.Pool.
-		i.secondLevel.vbReader = valblk.MakeReader(&i.secondLevel, rp, r.valueBIH, env.Stats)
-		getInternalValuer = &i.secondLevel.vbReader
+		i.secondLevel.internalValueConstructor.vbReader = valblk.MakeReader(&i.secondLevel, rp, r.valueBIH, env.Stats)
 		i.secondLevel.vbRH = r.blockReader.UsePreallocatedReadHandle(
 			objstorage.NoReadBefore, &i.secondLevel.vbRHPrealloc)
 	}
@@ -241,8 +245,8 @@ func newRowBlockTwoLevelIterator(
 			// versions of keys, and therefore never expose a LazyValue that is
 			// separated to their callers, they can put this valueBlockReader into a
 			// sync.Pool.
-			i.secondLevel.vbReader = valblk.MakeReader(&i.secondLevel, rp, r.valueBIH, env.Stats)
-			i.secondLevel.data.SetGetLazyValuer(&i.secondLevel.vbReader)
+			i.secondLevel.internalValueConstructor.vbReader = valblk.MakeReader(&i.secondLevel, rp, r.valueBIH, env.Stats)
+			i.secondLevel.data.SetGetLazyValuer(&i.secondLevel.internalValueConstructor.vbReader)
 			i.secondLevel.vbRH = r.blockReader.UsePreallocatedReadHandle(
 				objstorage.NoReadBefore, &i.secondLevel.vbRHPrealloc)
 		}
@@ -287,6 +291,9 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekGE(
 			key = i.secondLevel.lower
 		}
 	}
+	if i.secondLevel.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
 
 	err := i.secondLevel.err
 	i.secondLevel.err = nil // clear cached iteration error
@@ -651,6 +658,9 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekLT(
 			return i.virtualLast()
 		}
 	}
+	if i.secondLevel.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
 
 	i.secondLevel.exhaustedBounds = 0
 	i.secondLevel.err = nil // clear cached iteration error
@@ -725,6 +735,9 @@ func (i *twoLevelIterator[I, PI, D, PD]) First() *base.InternalKV {
 	if i.secondLevel.lower != nil {
 		return i.SeekGE(i.secondLevel.lower, base.SeekGEFlagsNone)
 	}
+	if i.secondLevel.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
 	i.secondLevel.exhaustedBounds = 0
 	i.secondLevel.err = nil // clear cached iteration error
 	// Seek optimization only applies until iterator is first positioned after SetBounds.
@@ -774,6 +787,9 @@ func (i *twoLevelIterator[I, PI, D, PD]) Last() *base.InternalKV {
 	if i.secondLevel.upper != nil {
 		panic("twoLevelIterator.Last() used despite upper bound")
 	}
+	if i.secondLevel.rangeFilterExcludesBounds(i.useFilterBlock) {
+		return nil
+	}
 	i.secondLevel.exhaustedBounds = 0
 	i.secondLevel.err = nil // clear cached iteration error
 	// Seek optimization only applies until iterator is first positioned after SetBounds.
@@ -32,6 +32,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/testkeys"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/rangefilter"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/sstable/valblk"
 	"github.com/cockroachdb/pebble/vfs"
@@ -235,7 +236,7 @@ func runVirtualReaderTest(t *testing.T, path string, blockSize, indexBlockSize i
 			transforms := IterTransforms{
 				SyntheticPrefixAndSuffix: block.MakeSyntheticPrefixAndSuffix(nil, syntheticSuffix),
 			}
-			iter, err := v.NewCompactionIter(transforms, block.ReadEnv{BufferPool: &bp}, rp)
+			iter, err := v.NewCompactionIter(transforms, block.ReadEnv{BufferPool: &bp}, rp, AssertNoBlobHandles)
 			if err != nil {
 				return err.Error()
 			}
@@ -362,7 +363,7 @@ func runVirtualReaderTest(t *testing.T, path string, blockSize, indexBlockSize i
 			}
 			iter, err := v.NewPointIter(
 				context.Background(), transforms, lower, upper, filterer, NeverUseFilterBlock,
-				block.ReadEnv{Stats: &stats, IterStats: nil}, MakeTrivialReaderProvider(r))
+				block.ReadEnv{Stats: &stats, IterStats: nil}, MakeTrivialReaderProvider(r), AssertNoBlobHandles)
 			if err != nil {
 				return err.Error()
 			}
@@ -773,6 +774,7 @@ func runTestReader(t *testing.T, o WriterOptions, dir string, r *Reader, printVa
 					AlwaysUseFilterBlock,
 					block.ReadEnv{Stats: &stats, IterStats: nil},
 					MakeTrivialReaderProvider(r),
+					AssertNoBlobHandles,
 				)
 				if err != nil {
 					return err.Error()
@@ -916,7 +918,7 @@ func TestCompactionIteratorSetupForCompaction(t *testing.T) {
 				var pool block.BufferPool
 				pool.Init(5)
 				citer, err := r.NewCompactionIter(
-					NoTransforms, block.ReadEnv{BufferPool: &pool}, MakeTrivialReaderProvider(r))
+					NoTransforms, block.ReadEnv{BufferPool: &pool}, MakeTrivialReaderProvider(r), AssertNoBlobHandles)
 				require.NoError(t, err)
 				switch i := citer.(type) {
 				case *singleLevelIteratorRowBlocks:
@@ -976,7 +978,7 @@ func TestReadaheadSetupForV3TablesWithMultipleVersions(t *testing.T) {
 		pool.Init(5)
 		defer pool.Release()
 		citer, err := r.NewCompactionIter(
-			NoTransforms, block.ReadEnv{BufferPool: &pool}, MakeTrivialReaderProvider(r))
+			NoTransforms, block.ReadEnv{BufferPool: &pool}, MakeTrivialReaderProvider(r), AssertNoBlobHandles)
 		require.NoError(t, err)
 		defer citer.Close()
 		i := citer.(*singleLevelIteratorRowBlocks)
@@ -1274,7 +1276,7 @@ func TestRandomizedPrefixSuffixRewriter(t *testing.T) {
 			},
 			nil, nil, nil,
 			AlwaysUseFilterBlock, block.NoReadEnv,
-			MakeTrivialReaderProvider(eReader), &virtualState{
+			MakeTrivialReaderProvider(eReader), AssertNoBlobHandles, &virtualState{
 				lower: base.MakeInternalKey([]byte("_"), base.SeqNumMax, base.InternalKeyKindSet),
 				upper: base.MakeRangeDeleteSentinelKey([]byte("~~~~~~~~~~~~~~~~")),
 			})
@@ -2448,7 +2450,7 @@ func BenchmarkIteratorScanObsolete(b *testing.B) {
 								iter, err := r.NewPointIter(
 									context.Background(), transforms, nil, nil, filterer,
 									AlwaysUseFilterBlock, block.NoReadEnv,
-									MakeTrivialReaderProvider(r))
+									MakeTrivialReaderProvider(r), AssertNoBlobHandles)
 								require.NoError(b, err)
 								b.ResetTimer()
 								for i := 0; i < b.N; i++ {
@@ -2476,6 +2478,136 @@ func BenchmarkIteratorScanObsolete(b *testing.B) {
 	}
 }
 
+func TestReaderRangeFilter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	policy := rangefilter.FilterPolicy(1)
+	for _, format := range []TableFormat{TableFormatPebblev4, TableFormatMax} {
+		for _, indexBlockSize := range []int{1 << 20, 1} {
+			t.Run(fmt.Sprintf("format=%s/two-level=%t", format, indexBlockSize == 1), func(t *testing.T) {
+				obj := &objstorage.MemObj{}
+				w := NewWriter(obj, WriterOptions{
+					TableFormat:    format,
+					BlockSize:      128,
+					IndexBlockSize: indexBlockSize,
+					FilterPolicy:   policy,
+					FilterType:     RangeFilter,
+				})
+				for _, prefix := range []string{"apple", "cherry"} {
+					for i := 0; i < 100; i++ {
+						require.NoError(t, w.Set(fmt.Appendf(nil, "%s%03d", prefix, i), []byte("v")))
+					}
+				}
+				require.NoError(t, w.Close())
+
+				r, err := NewMemReader(obj.Data(), ReaderOptions{
+					Filters: map[string]FilterPolicy{policy.Name(): policy},
+				})
+				require.NoError(t, err)
+				defer r.Close()
+				require.True(t, r.tableFilter.supportsRange())
+
+				testCases := []struct {
+					lower, upper string
+					first, last  string
+					skipped      bool
+				}{
+					{lower: "a", upper: "b", first: "apple000", last: "apple099"},
+					{lower: "b", upper: "c", skipped: true},
+					{lower: "apple100", upper: "cherry", skipped: true},
+					{lower: "apple050", upper: "cherry001", first: "apple050", last: "cherry000"},
+					{lower: "d", upper: "", skipped: true},
+					{lower: "", upper: "a", skipped: true},
+				}
+				for _, tc := range testCases {
+					var lower, upper []byte
+					if tc.lower != "" {
+						lower = []byte(tc.lower)
+					}
+					if tc.upper != "" {
+						upper = []byte(tc.upper)
+					}
+					var stats base.InternalIteratorStats
+					iter, err := r.NewPointIter(
+						context.Background(), NoTransforms, lower, upper, nil, /* filterer */
+						AlwaysUseFilterBlock, block.ReadEnv{Stats: &stats},
+						MakeTrivialReaderProvider(r), AssertNoBlobHandles)
+					require.NoError(t, err)
+					var first, last string
+					if kv := iter.First(); kv != nil {
+						first = string(kv.K.UserKey)
+					}
+					if kv := iter.SeekLT([]byte("z"), base.SeekLTFlagsNone); kv != nil && upper == nil {
+						last = string(kv.K.UserKey)
+					}
+					if upper != nil {
+						if kv := iter.SeekLT(upper, base.SeekLTFlagsNone); kv != nil {
+							last = string(kv.K.UserKey)
+						}
+					}
+					require.NoError(t, iter.Error())
+					require.Equal(t, tc.first, first, "[%q, %q)", tc.lower, tc.upper)
+					require.Equal(t, tc.last, last, "[%q, %q)", tc.lower, tc.upper)
+					if tc.skipped {
+						// The filter is consulted once per SetBounds call.
+						require.Equal(t, uint64(1), stats.RangeFilterSkippedTables, "[%q, %q)", tc.lower, tc.upper)
+					} else {
+						require.Zero(t, stats.RangeFilterSkippedTables)
+					}
+
+					// Changing the bounds consults the filter again.
+					iter.SetBounds([]byte("apple000"), []byte("apple001"))
+					require.NotNil(t, iter.First())
+					require.NoError(t, iter.Close())
+				}
+			})
+		}
+	}
+}
+
+func TestReaderMayContainPrefixes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	policy := bloom.FilterPolicy(10)
+	obj := &objstorage.MemObj{}
+	w := NewWriter(obj, WriterOptions{
+		TableFormat:  TableFormatMax,
+		FilterPolicy: policy,
+	})
+	for i := 0; i < 100; i += 2 {
+		require.NoError(t, w.Set(fmt.Appendf(nil, "key%03d", i), []byte("v")))
+	}
+	require.NoError(t, w.Close())
+	r, err := NewMemReader(obj.Data(), ReaderOptions{
+		Filters: map[string]FilterPolicy{policy.Name(): policy},
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var prefixes [][]byte
+	for i := 0; i < 100; i++ {
+		prefixes = append(prefixes, fmt.Appendf(nil, "key%03d", i))
+	}
+	mayContain := make([]bool, len(prefixes))
+	require.NoError(t, r.MayContainPrefixes(context.Background(), block.NoReadEnv, NoTransforms, prefixes, mayContain))
+	var excluded int
+	for i := range prefixes {
+		if i%2 == 0 {
+			require.True(t, mayContain[i], "%s", prefixes[i])
+		} else if !mayContain[i] {
+			excluded++
+		}
+	}
+	// With 10 bits per key, few absent prefixes are false positives.
+	require.Greater(t, excluded, 40)
+
+	// A synthetic prefix is removed before checking the filter, and prefixes
+	// without it are excluded.
+	transforms := NoTransforms
+	transforms.SyntheticPrefixAndSuffix = block.MakeSyntheticPrefixAndSuffix([]byte("p/"), nil)
+	require.NoError(t, r.MayContainPrefixes(context.Background(), block.NoReadEnv, transforms,
+		[][]byte{[]byte("p/key000"), []byte("key000")}, mayContain[:2]))
+	require.Equal(t, []bool{true, false}, mayContain[:2])
+}
+
 func newReader(r ReadableFile, o ReaderOptions) (*Reader, error) {
 	readable, err := NewSimpleReadable(r)
 	if err != nil {
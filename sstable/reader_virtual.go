@@ -90,16 +90,22 @@ func MakeVirtualReader(reader *Reader, p VirtualReaderParams) VirtualReader {
 	v.Properties.RawPointTombstoneValueSize = scale(reader.Properties.RawPointTombstoneValueSize)
 
 	v.Properties.CompressionName = reader.Properties.CompressionName
+	// The virtual sstable is assumed to have the same distribution of
+	// expiration times as its backing table.
+	v.Properties.ExpiryHistogram = reader.Properties.ExpiryHistogram
 
 	return v
 }
 
 // NewCompactionIter is the compaction iterator function for virtual readers.
 func (v *VirtualReader) NewCompactionIter(
-	transforms IterTransforms, env block.ReadEnv, rp valblk.ReaderProvider,
+	transforms IterTransforms,
+	env block.ReadEnv,
+	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
 ) (Iterator, error) {
 	return v.reader.newCompactionIter(
-		transforms, env, rp, &v.vState)
+		transforms, env, rp, blobContext, &v.vState)
 }
 
 // NewPointIter returns an iterator for the point keys in the table.
@@ -119,10 +125,11 @@ func (v *VirtualReader) NewPointIter(
 	filterBlockSizeLimit FilterBlockSizeLimit,
 	env block.ReadEnv,
 	rp valblk.ReaderProvider,
+	blobContext TableBlobContext,
 ) (Iterator, error) {
 	return v.reader.newPointIter(
 		ctx, transforms, lower, upper, filterer, filterBlockSizeLimit,
-		env, rp, &v.vState)
+		env, rp, blobContext, &v.vState)
 }
 
 // ValidateBlockChecksumsOnBacking will call ValidateBlockChecksumsOnBacking on the underlying reader.
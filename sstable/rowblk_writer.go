@@ -22,6 +22,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/rangedel"
 	"github.com/cockroachdb/pebble/internal/rangekey"
 	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/blob"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/sstable/rowblk"
 	"github.com/cockroachdb/pebble/sstable/valblk"
@@ -596,6 +597,14 @@ func (w *RawRowWriter) AddWithForceObsolete(
 	return w.addPoint(key, value, forceObsolete)
 }
 
+// AddWithBlobHandle implements the RawWriter interface. Row-oriented sstables
+// do not support blob value handles, so it always returns an error.
+func (w *RawRowWriter) AddWithBlobHandle(
+	key InternalKey, h blob.Handle, attr base.ShortAttribute, forceObsolete bool,
+) error {
+	return errors.Newf("pebble: blob value handles are not supported in table format %s", w.tableFormat)
+}
+
 func (w *RawRowWriter) makeAddPointDecisionV2(key InternalKey) error {
 	prevTrailer := w.lastPointKeyInfo.trailer
 	w.lastPointKeyInfo.trailer = key.Trailer
@@ -1779,8 +1788,8 @@ func newRowWriter(writable objstorage.Writable, o WriterOptions) *RawRowWriter {
 
 	if o.FilterPolicy != nil {
 		switch o.FilterType {
-		case TableFilter:
-			w.filter = newTableFilterWriter(o.FilterPolicy)
+		case TableFilter, RangeFilter:
+			w.filter = newTableFilterWriter(o.FilterPolicy, o.FilterType)
 		default:
 			panic(fmt.Sprintf("unknown filter type: %v", o.FilterType))
 		}
@@ -1914,7 +1923,7 @@ func (w *RawRowWriter) rewriteSuffixes(
 	// already have ensured this is valid if it exists).
 	if w.filter != nil {
 		if filterBlockBH, ok := l.FilterByName(w.filter.metaName()); ok {
-			filterBlock, _, err := readBlockBuf(sst, filterBlockBH, r.blockReader.ChecksumType(), nil)
+			filterBlock, _, err := readBlockBuf(sst, filterBlockBH, r.blockReader.ChecksumType(), nil, nil)
 			if err != nil {
 				return errors.Wrap(err, "reading filter")
 			}
@@ -2009,6 +2018,11 @@ func (w *RawRowWriter) copyFilter(filter []byte, filterName string) error {
 	return nil
 }
 
+// copyCompressionDict implements RawWriter.
+func (w *RawRowWriter) copyCompressionDict(dict *block.CompressionDict) error {
+	return errors.Newf("pebble: compression dictionaries are not supported in table format %s", w.tableFormat)
+}
+
 // SetSnapshotPinnedProperties sets the properties for pinned keys. Should only
 // be used internally by Pebble.
 func (w *RawRowWriter) SetSnapshotPinnedProperties(
@@ -2018,3 +2032,9 @@ func (w *RawRowWriter) SetSnapshotPinnedProperties(
 	w.props.SnapshotPinnedKeySize = pinnedKeySize
 	w.props.SnapshotPinnedValueSize = pinnedValueSize
 }
+
+// SetExpiryHistogram sets the encoded expiry histogram property. Should only
+// be used internally by Pebble.
+func (w *RawRowWriter) SetExpiryHistogram(encoded []byte) {
+	w.props.ExpiryHistogram = string(encoded)
+}
@@ -168,7 +168,7 @@ func rewriteDataBlocksInParallel(
 				for i := worker; i < len(input); i += concurrency {
 					bh := input[i]
 					var err error
-					inputBlock, inputBlockBuf, err = readBlockBuf(sstBytes, bh.Handle, r.blockReader.ChecksumType(), inputBlockBuf)
+					inputBlock, inputBlockBuf, err = readBlockBuf(sstBytes, bh.Handle, r.blockReader.ChecksumType(), r.blockReader.CompressionDict(), inputBlockBuf)
 					if err != nil {
 						return err
 					}
@@ -369,9 +369,14 @@ func NewMemReader(sst []byte, o ReaderOptions) (*Reader, error) {
 // readBlockBuf may return a byte slice that points directly into sstBytes. If
 // the caller is going to expect that sstBytes remain stable, it should copy the
 // returned slice before writing it out to a objstorage.Writable which may
-// mangle it.
+// mangle it. The dict is used to decompress blocks compressed with the table's
+// compression dictionary, and may be nil.
 func readBlockBuf(
-	sstBytes []byte, bh block.Handle, checksumType block.ChecksumType, buf []byte,
+	sstBytes []byte,
+	bh block.Handle,
+	checksumType block.ChecksumType,
+	dict *block.CompressionDict,
+	buf []byte,
 ) ([]byte, []byte, error) {
 	raw := sstBytes[bh.Offset : bh.Offset+bh.Length+block.TrailerLen]
 	if err := block.ValidateChecksum(checksumType, raw, bh); err != nil {
@@ -399,7 +404,7 @@ func readBlockBuf(
 		}
 	}
 	dst := buf[:decompressedLen]
-	err = block.DecompressInto(algo, raw[prefix:], dst)
+	err = dict.DecompressInto(algo, raw[prefix:], dst)
 	return dst, buf, err
 }
 
@@ -209,11 +209,12 @@ const (
 	levelDBFormatVersion  = 0
 	rocksDBFormatVersion2 = 2
 
-	metaRangeKeyName   = "pebble.range_key"
-	metaValueIndexName = "pebble.value_index"
-	metaPropertiesName = "rocksdb.properties"
-	metaRangeDelV1Name = "rocksdb.range_del"
-	metaRangeDelV2Name = "rocksdb.range_del2"
+	metaRangeKeyName        = "pebble.range_key"
+	metaValueIndexName      = "pebble.value_index"
+	metaCompressionDictName = "pebble.compression_dict"
+	metaPropertiesName      = "rocksdb.properties"
+	metaRangeDelV1Name      = "rocksdb.range_del"
+	metaRangeDelV2Name      = "rocksdb.range_del2"
 
 	// Index Types.
 	// A space efficient index block that is optimized for binary-search-based
@@ -390,7 +391,7 @@ func supportsTwoLevelIndex(format TableFormat) bool {
 	switch format {
 	case TableFormatLevelDB:
 		return false
-	case TableFormatRocksDBv2, TableFormatPebblev1, TableFormatPebblev2, TableFormatPebblev3, TableFormatPebblev4, TableFormatPebblev5:
+	case TableFormatRocksDBv2, TableFormatPebblev1, TableFormatPebblev2, TableFormatPebblev3, TableFormatPebblev4, TableFormatPebblev5, TableFormatPebblev6, TableFormatPebblev7, TableFormatPebblev8:
 		return true
 	default:
 		panic("sstable: unspecified table format version")
@@ -0,0 +1,85 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/sstable/blob"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/sstable/valblk"
+)
+
+// TableBlobContext configures how an sstable iterator retrieves values that
+// are stored out-of-band in blob files.
+type TableBlobContext struct {
+	// ValueFetcher is used to retrieve values stored in blob files. If nil,
+	// any attempt to retrieve a blob value returns an error.
+	ValueFetcher base.ValueFetcher
+}
+
+// AssertNoBlobHandles is a TableBlobContext for iterators over sstables that
+// are not expected to contain blob value handles, or whose callers never
+// retrieve blob values.
+var AssertNoBlobHandles = TableBlobContext{}
+
+// defaultInternalValueConstructor is the block.GetInternalValueForPrefixAndValueHandler
+// used by sstable iterators over columnar data blocks. It constructs lazy
+// values for values stored in value blocks (delegating to a valblk.Reader) and
+// for values stored in blob files.
+type defaultInternalValueConstructor struct {
+	blobContext TableBlobContext
+	vbReader    valblk.Reader
+	// lazyFetcher is the LazyFetcher embedded in the LazyValues we return for
+	// blob values. It is reused across calls to avoid an allocation per value.
+	lazyFetcher base.LazyFetcher
+}
+
+var _ block.GetInternalValueForPrefixAndValueHandler = (*defaultInternalValueConstructor)(nil)
+
+// GetInternalValueForPrefixAndValueHandle implements the
+// block.GetInternalValueForPrefixAndValueHandler interface.
+func (i *defaultInternalValueConstructor) GetInternalValueForPrefixAndValueHandle(
+	handle []byte,
+) base.InternalValue {
+	vp := block.ValuePrefix(handle[0])
+	if vp.IsValueBlockHandle() {
+		return i.vbReader.GetInternalValueForPrefixAndValueHandle(handle)
+	} else if !vp.IsBlobValueHandle() {
+		panic(errors.AssertionFailedf("sstable: unexpected value prefix %x", handle[0]))
+	}
+	valueLen, fileNum, remainder := blob.DecodeInlineHandlePrefix(handle[1:])
+	fetcher := i.blobContext.ValueFetcher
+	if fetcher == nil {
+		fetcher = noBlobValueFetcher{}
+	}
+	i.lazyFetcher = base.LazyFetcher{
+		Fetcher: fetcher,
+		Attribute: base.AttributeAndLen{
+			ValueLen:       valueLen,
+			ShortAttribute: vp.ShortAttribute(),
+		},
+		BlobFileNum: fileNum,
+	}
+	return base.MakeLazyValue(base.LazyValue{
+		ValueOrHandle: remainder,
+		Fetcher:       &i.lazyFetcher,
+	})
+}
+
+// noBlobValueFetcher is a base.ValueFetcher used when an iterator was not
+// configured with a blob value fetcher. It errors on every fetch.
+type noBlobValueFetcher struct{}
+
+var _ base.ValueFetcher = noBlobValueFetcher{}
+
+// Fetch implements base.ValueFetcher.
+func (noBlobValueFetcher) Fetch(
+	_ context.Context, _ []byte, fileNum base.DiskFileNum, _ uint32, _ []byte,
+) ([]byte, bool, error) {
+	return nil, false, errors.Newf("sstable: no blob value fetcher configured to retrieve value from blob file %s", fileNum)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/blob"
 	"github.com/cockroachdb/pebble/sstable/block"
 )
 
@@ -311,6 +312,15 @@ type RawWriter interface {
 	AddWithForceObsolete(
 		key InternalKey, value []byte, forceObsolete bool,
 	) error
+	// AddWithBlobHandle adds a key to the sstable, but encoding a reference to
+	// a value stored in a blob file. The key must be a SET or SETWITHDEL. The
+	// attribute is the short attribute of the value, and forceObsolete has the
+	// same meaning as in AddWithForceObsolete.
+	//
+	// AddWithBlobHandle is only supported by TableFormatPebblev6 and later.
+	AddWithBlobHandle(
+		key InternalKey, h blob.Handle, attr base.ShortAttribute, forceObsolete bool,
+	) error
 	// EncodeSpan encodes the keys in the given span. The span can contain
 	// either only RANGEDEL keys or only range keys.
 	//
@@ -331,6 +341,9 @@ type RawWriter interface {
 	// SetSnapshotPinnedProperties sets the properties for pinned keys. Should only
 	// be used internally by Pebble.
 	SetSnapshotPinnedProperties(keyCount, keySize, valueSize uint64)
+	// SetExpiryHistogram sets the encoded expiry histogram property. Should
+	// only be used internally by Pebble.
+	SetExpiryHistogram(encoded []byte)
 	// Close finishes writing the table and closes the underlying file that the
 	// table was written to.
 	Close() error
@@ -364,6 +377,12 @@ type RawWriter interface {
 	// used by the sstable copier that can copy parts of an sstable to a new sstable,
 	// using CopySpan().
 	copyProperties(props Properties)
+
+	// copyCompressionDict sets the table's compression dictionary to the
+	// dictionary of the table that data blocks are copied from. It's
+	// specifically used by the sstable copier that can copy parts of an sstable
+	// to a new sstable, using CopySpan().
+	copyCompressionDict(dict *block.CompressionDict) error
 }
 
 // WriterMetadata holds info about a finished sstable.
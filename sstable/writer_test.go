@@ -421,7 +421,7 @@ func TestWriterWithValueBlocks(t *testing.T) {
 				return err.Error()
 			}
 			forceRowIterIgnoreValueBlocks := func(i *singleLevelIteratorRowBlocks) {
-				i.vbReader = valblk.Reader{}
+				i.internalValueConstructor.vbReader = valblk.Reader{}
 				i.data.SetGetLazyValuer(nil)
 				i.data.SetHasValuePrefix(false)
 			}
@@ -1028,6 +1028,153 @@ func TestWriter_TableFormatCompatibility(t *testing.T) {
 	}
 }
 
+func TestWriterCompression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	testCases := []struct {
+		tableFormat TableFormat
+		compression block.Compression
+		want        block.CompressionIndicator
+	}{
+		{TableFormatPebblev7, block.Lz4Compression, block.Lz4CompressionIndicator},
+		{TableFormatPebblev7, block.MinLZCompression, block.MinLZCompressionIndicator},
+		{TableFormatPebblev7, block.ZstdCompressionLevel(19), block.ZstdCompressionIndicator},
+		{TableFormatPebblev6, block.ZstdCompressionLevel(1), block.ZstdCompressionIndicator},
+		// Tables of older formats fall back to snappy compression so that
+		// older readers do not encounter unknown compression algorithms.
+		{TableFormatPebblev6, block.Lz4Compression, block.SnappyCompressionIndicator},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/%s", tc.tableFormat, tc.compression), func(t *testing.T) {
+			obj := &objstorage.MemObj{}
+			w := NewWriter(obj, WriterOptions{
+				TableFormat: tc.tableFormat,
+				Compression: tc.compression,
+			})
+			for i := 0; i < 1000; i++ {
+				key := []byte(fmt.Sprintf("key%05d", i))
+				require.NoError(t, w.Set(key, bytes.Repeat(key, 10)))
+			}
+			require.NoError(t, w.Close())
+
+			r, err := NewMemReader(obj.Data(), ReaderOptions{})
+			require.NoError(t, err)
+			defer r.Close()
+			l, err := r.Layout()
+			require.NoError(t, err)
+			require.NotEmpty(t, l.Data)
+			for _, bh := range l.Data {
+				require.Equal(t, tc.want, blockCompressionIndicator(t, r, bh.Handle))
+			}
+			require.Contains(t, l.DescribeCompression(r), fmt.Sprintf("compression: %s ratio=", tc.want))
+
+			iter, err := r.NewIter(NoTransforms, nil /* lower */, nil /* upper */)
+			require.NoError(t, err)
+			var n int
+			for kv := iter.First(); kv != nil; kv = iter.Next() {
+				v, _, err := kv.Value(nil)
+				require.NoError(t, err)
+				require.Equal(t, bytes.Repeat(kv.K.UserKey, 10), v)
+				n++
+			}
+			require.NoError(t, iter.Close())
+			require.Equal(t, 1000, n)
+		})
+	}
+}
+
+// blockCompressionIndicator returns the compression indicator recorded in the
+// trailer of the block with the provided handle.
+func blockCompressionIndicator(
+	t *testing.T, r *Reader, bh block.Handle,
+) block.CompressionIndicator {
+	trailer := make([]byte, block.TrailerLen)
+	require.NoError(t, r.blockReader.Readable().ReadAt(context.Background(), trailer, int64(bh.Offset+bh.Length)))
+	return block.CompressionIndicator(trailer[0])
+}
+
+func TestWriterCompressionDict(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	const numKeys = 2000
+	value := func(i int) []byte {
+		return fmt.Appendf(nil, `{"id":%d,"region":"us-east-%d","status":"active","score":%d}`,
+			i*7919%100003, i%4, i*31%100)
+	}
+	writeTable := func(format TableFormat) *objstorage.MemObj {
+		obj := &objstorage.MemObj{}
+		w := NewWriter(obj, WriterOptions{
+			TableFormat:         format,
+			BlockSize:           512,
+			Compression:         block.ZstdCompression,
+			CompressionDictSize: 1 << 10,
+		})
+		for i := 0; i < numKeys; i++ {
+			require.NoError(t, w.Set(fmt.Appendf(nil, "key%05d", i), value(i)))
+		}
+		require.NoError(t, w.Close())
+		return obj
+	}
+	checkTable := func(data []byte, wantDict bool) {
+		r, err := NewMemReader(data, ReaderOptions{})
+		require.NoError(t, err)
+		defer r.Close()
+		l, err := r.Layout()
+		require.NoError(t, err)
+		require.Equal(t, wantDict, l.CompressionDict.Length > 0)
+		require.Equal(t, wantDict, r.Properties.CompressionDictSize > 0)
+		var dictBlocks int
+		for _, bh := range l.Data {
+			if blockCompressionIndicator(t, r, bh.Handle) == block.ZstdDictCompressionIndicator {
+				dictBlocks++
+			}
+		}
+		require.Equal(t, wantDict, dictBlocks > 0)
+		require.NoError(t, r.ValidateBlockChecksums())
+
+		iter, err := r.NewIter(NoTransforms, nil /* lower */, nil /* upper */)
+		require.NoError(t, err)
+		var n int
+		for kv := iter.First(); kv != nil; kv = iter.Next() {
+			v, _, err := kv.Value(nil)
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("key%05d", n), string(kv.K.UserKey))
+			require.Equal(t, value(n), v)
+			n++
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, numKeys, n)
+	}
+
+	// Tables of older formats do not use a dictionary.
+	checkTable(writeTable(TableFormatPebblev7).Data(), false)
+
+	obj := writeTable(TableFormatPebblev8)
+	checkTable(obj.Data(), true)
+	c := cache.New(1 << 20)
+	defer c.Unref()
+	ch := c.NewHandle()
+	defer ch.Close()
+	rOpts := ReaderOptions{
+		ReaderOptions: block.ReaderOptions{
+			CacheOpts: sstableinternal.CacheOptions{CacheHandle: ch, FileNum: 1},
+		},
+	}
+	r, err := NewMemReader(obj.Data(), rOpts)
+	require.NoError(t, err)
+	defer r.Close()
+	require.Greater(t, r.Properties.CompressionDictSampledSize, uint64(0))
+	require.Less(t, r.Properties.CompressionDictSampledWithDictSize, r.Properties.CompressionDictSampledWithoutDictSize)
+
+	// Copying a span of the table copies the dictionary along with the data
+	// blocks compressed with it.
+	copied := &objstorage.MemObj{}
+	_, err = CopySpan(context.Background(), newMemReader(obj.Data()), r, rOpts, copied,
+		WriterOptions{TableFormat: TableFormatPebblev8},
+		base.MakeInternalKey([]byte("key00000"), 0, base.InternalKeyKindSet),
+		base.MakeInternalKey([]byte("key99999"), 0, base.InternalKeyKindSet))
+	require.NoError(t, err)
+	checkTable(copied.Data(), true)
+}
+
 // Tests for races, such as https://github.com/cockroachdb/cockroach/issues/77194,
 // in the Writer.
 func TestWriterRace(t *testing.T) {
@@ -128,8 +128,10 @@ func (d *DB) collectTableStats() bool {
 	}
 
 	maybeCompact := false
+	now := d.timeNow()
 	for _, c := range collected {
 		c.tableMetadata.Stats = c.TableStats
+		c.tableMetadata.Stats.ExpiredBytesEstimate = expiredBytesEstimate(c.tableMetadata, now)
 		maybeCompact = maybeCompact || fileCompensation(c.tableMetadata) > 0
 		c.tableMetadata.StatsMarkValid()
 	}
@@ -316,6 +318,11 @@ func (d *DB) loadTableStats(
 			if props.NumDataBlocks > 0 {
 				stats.TombstoneDenseBlocksRatio = float64(props.NumTombstoneDenseBlocks) / float64(props.NumDataBlocks)
 			}
+			if props.ExpiryHistogram != "" {
+				if stats.Expiry, err = manifest.DecodeExpiryHistogram([]byte(props.ExpiryHistogram)); err != nil {
+					return
+				}
+			}
 
 			if props.NumPointDeletions() > 0 {
 				if err = d.loadTablePointKeyStats(props, v, level, meta, &stats); err != nil {
@@ -671,6 +678,15 @@ func maybeSetStatsFromProperties(meta physicalMeta, props *sstable.Properties) b
 		return false
 	}
 
+	var expiry *manifest.ExpiryHistogram
+	if props.ExpiryHistogram != "" {
+		var err error
+		if expiry, err = manifest.DecodeExpiryHistogram([]byte(props.ExpiryHistogram)); err != nil {
+			// The table stats collector will surface the error.
+			return false
+		}
+	}
+
 	var pointEstimate uint64
 	if props.NumEntries > 0 {
 		// Use the file's own average key and value sizes as an estimate. This
@@ -689,6 +705,7 @@ func maybeSetStatsFromProperties(meta physicalMeta, props *sstable.Properties) b
 	meta.Stats.RangeDeletionsBytesEstimate = 0
 	meta.Stats.ValueBlocksSize = props.ValueBlocksSize
 	meta.Stats.CompressionType = block.CompressionFromString(props.CompressionName)
+	meta.Stats.Expiry = expiry
 	meta.StatsMarkValid()
 	return true
 }
@@ -1036,7 +1053,7 @@ var compressionTypeAnnotator = manifest.Annotator[compressionTypes]{
 type compressionTypeAggregator struct{}
 
 type compressionTypes struct {
-	snappy, zstd, none, unknown uint64
+	snappy, zstd, lz4, minlz, none, unknown uint64
 }
 
 func (a compressionTypeAggregator) Zero(dst *compressionTypes) *compressionTypes {
@@ -1050,11 +1067,15 @@ func (a compressionTypeAggregator) Zero(dst *compressionTypes) *compressionTypes
 func (a compressionTypeAggregator) Accumulate(
 	f *tableMetadata, dst *compressionTypes,
 ) (v *compressionTypes, cacheOK bool) {
-	switch f.Stats.CompressionType {
+	switch f.Stats.CompressionType.Algorithm() {
 	case SnappyCompression:
 		dst.snappy++
 	case ZstdCompression:
 		dst.zstd++
+	case Lz4Compression:
+		dst.lz4++
+	case MinLZCompression:
+		dst.minlz++
 	case NoCompression:
 		dst.none++
 	default:
@@ -1068,6 +1089,8 @@ func (a compressionTypeAggregator) Merge(
 ) *compressionTypes {
 	dst.snappy += src.snappy
 	dst.zstd += src.zstd
+	dst.lz4 += src.lz4
+	dst.minlz += src.minlz
 	dst.none += src.none
 	dst.unknown += src.unknown
 	return dst
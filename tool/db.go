@@ -574,6 +574,17 @@ func (d *dbT) runSpace(cmd *cobra.Command, args []string) {
 		return
 	}
 	fmt.Fprintf(stdout, "%d\n", bytes)
+
+	// Report the liveness of any blob files. Blob files are shared by all the
+	// sstables that reference them, so they're reported for the whole
+	// database rather than for the requested span.
+	if m := db.Metrics(); m.BlobFiles.LiveCount > 0 {
+		fmt.Fprintf(stdout, "blob files: %d (%s)  values: %s  referenced: %s  garbage: %.1f%% (max %.1f%%)\n",
+			m.BlobFiles.LiveCount, humanize.Bytes.Uint64(m.BlobFiles.LiveSize),
+			humanize.Bytes.Uint64(m.BlobFiles.ValueSize),
+			humanize.Bytes.Uint64(m.BlobFiles.ReferencedValueSize),
+			100*m.BlobFiles.GarbageRatio, 100*m.BlobFiles.MaxGarbageRatio)
+	}
 }
 
 func (d *dbT) getExciseSpan() (pebble.KeyRange, error) {
@@ -0,0 +1,66 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/encfs"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptionKeys(t *testing.T) {
+	mem := vfs.NewMem()
+	keys := &encfs.StaticKeyProvider{
+		ActiveKeyID: "k1",
+		Keys:        map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)},
+	}
+	d, err := pebble.Open("db", &pebble.Options{FS: encfs.New(mem, keys)})
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("flushed"), []byte("v1"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("logged"), []byte("v2"), nil))
+	require.NoError(t, d.Close())
+
+	ls, err := mem.List("db")
+	require.NoError(t, err)
+	// The most recent WAL holds the unflushed key.
+	slices.Sort(ls)
+	var sstPath, walPath string
+	for _, name := range ls {
+		switch {
+		case strings.HasSuffix(name, ".sst"):
+			sstPath = mem.PathJoin("db", name)
+		case strings.HasSuffix(name, ".log"):
+			walPath = mem.PathJoin("db", name)
+		}
+	}
+
+	run := func(tool *T, args ...string) string {
+		var buf bytes.Buffer
+		c := &cobra.Command{}
+		c.AddCommand(tool.Commands...)
+		c.SetArgs(args)
+		c.SetOut(&buf)
+		c.SetErr(&buf)
+		require.NoError(t, c.Execute())
+		return buf.String()
+	}
+
+	// Without the keys, the files cannot be read.
+	plain := New(FS(mem))
+	require.NotContains(t, run(plain, "sstable", "scan", sstPath), "flushed")
+	require.NotContains(t, run(plain, "wal", "dump", walPath), "logged")
+
+	encrypted := New(FS(mem), EncryptionKeys(keys))
+	require.Contains(t, run(encrypted, "sstable", "scan", sstPath), "flushed#")
+	require.Contains(t, run(encrypted, "wal", "dump", walPath), "SET(logged,<2>)")
+}
@@ -232,6 +232,14 @@ func (m *manifestT) runDump(cmd *cobra.Command, args []string) {
 					}
 					fmt.Fprintf(stdout, "\n")
 				}
+				for _, bf := range ve.NewBlobFiles {
+					empty = false
+					fmt.Fprintf(stdout, "  add-blob:      %s\n", bf)
+				}
+				for _, fileNum := range ve.DeletedBlobFiles {
+					empty = false
+					fmt.Fprintf(stdout, "  del-blob:      %s\n", fileNum)
+				}
 				if empty {
 					// NB: An empty version edit can happen if we log a version edit with
 					// a zero field. RocksDB does this with a version edit that contains
@@ -251,11 +259,35 @@ func (m *manifestT) runDump(cmd *cobra.Command, args []string) {
 					return
 				}
 				m.printLevels(comparer.Compare, stdout, v)
+				printBlobFileLiveness(stdout, bve.BlobFiles.Added)
 			}
 		}()
 	}
 }
 
+// printBlobFileLiveness prints the blob files referenced by the final version,
+// along with the fraction of each blob file's values that are still
+// referenced by the version's sstables.
+func printBlobFileLiveness(
+	stdout io.Writer, blobFiles map[base.DiskFileNum]*manifest.BlobFileMetadata,
+) {
+	if len(blobFiles) == 0 {
+		return
+	}
+	fileNums := make([]base.DiskFileNum, 0, len(blobFiles))
+	for fileNum := range blobFiles {
+		fileNums = append(fileNums, fileNum)
+	}
+	slices.Sort(fileNums)
+	fmt.Fprintf(stdout, "--- blob files ---\n")
+	for _, fileNum := range fileNums {
+		bf := blobFiles[fileNum]
+		fmt.Fprintf(stdout, "  %s:%d values:%d refs:%d referenced:%d garbage:%.1f%%\n",
+			bf.FileNum, bf.Size, bf.ValueSize, bf.ActiveRefs.Count(),
+			bf.ActiveRefs.ValueSize(), 100*bf.GarbageRatio())
+	}
+}
+
 func anyOverlap(cmp base.Compare, ve *manifest.VersionEdit, start, end key) bool {
 	if start == nil && end == nil {
 		return true
@@ -44,13 +44,14 @@ type sstableT struct {
 	mergers   sstable.Mergers
 
 	// Flags.
-	fmtKey   keyFormatter
-	fmtValue valueFormatter
-	start    key
-	end      key
-	filter   key
-	count    int64
-	verbose  bool
+	fmtKey      keyFormatter
+	fmtValue    valueFormatter
+	start       key
+	end         key
+	filter      key
+	count       int64
+	verbose     bool
+	compression bool
 }
 
 func newSSTable(
@@ -80,7 +81,8 @@ func newSSTable(
 		Short: "print sstable block and record layout",
 		Long: `
 Print the layout for the sstables. The -v flag controls whether record layout
-is displayed or omitted.
+is displayed or omitted. The --compression flag displays the compression
+algorithm and compression ratio of each block instead of the record layout.
 `,
 		Args: cobra.MinimumNArgs(1),
 		Run:  s.runLayout,
@@ -126,6 +128,8 @@ inclusive-inclusive range specified by --start and --end.
 		&s.fmtKey, "key", "key formatter")
 	s.Layout.Flags().Var(
 		&s.fmtValue, "value", "value formatter")
+	s.Layout.Flags().BoolVar(
+		&s.compression, "compression", false, "show the compression of each block")
 	s.Scan.Flags().Var(
 		&s.fmtKey, "key", "key formatter")
 	s.Scan.Flags().Var(
@@ -232,6 +236,10 @@ func (s *sstableT) runLayout(cmd *cobra.Command, args []string) {
 				return strings.TrimRight(buf.String(), "\n")
 			}
 		}
+		if s.compression {
+			_, _ = stdout.Write([]byte(l.DescribeCompression(r)))
+			return
+		}
 		_, _ = stdout.Write([]byte(l.Describe(s.verbose, r, fmtRecord)))
 	})
 }
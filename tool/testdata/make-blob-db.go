@@ -0,0 +1,74 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/pebble"
+)
+
+const dbName = "blob-db"
+
+// This program generates a database whose values are separated into blob
+// files, one of which contains values that are no longer referenced.
+func main() {
+	opts := &pebble.Options{
+		FormatMajorVersion:          pebble.FormatExperimentalValueSeparation,
+		DisableAutomaticCompactions: true,
+		ErrorIfExists:               true,
+	}
+	opts.Experimental.EnableColumnarBlocks = func() bool { return true }
+	opts.Experimental.ValueSeparationPolicy = func() pebble.ValueSeparationPolicy {
+		return pebble.ValueSeparationPolicy{
+			Enabled:               true,
+			MinimumSize:           1,
+			MaxBlobReferenceDepth: 10,
+		}
+	}
+
+	path, err := filepath.Abs(dbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := pebble.Open(path, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if db != nil {
+			db.Close()
+		}
+	}()
+
+	set := func(kvs string) {
+		for _, kv := range strings.Fields(kvs) {
+			k, v, _ := strings.Cut(kv, "=")
+			if err := db.Set([]byte(k), []byte(v), pebble.Sync); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := db.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	set("a=apple b=banana c=cherry")
+	// Overwrite b, so that its first value becomes garbage once the tables are
+	// compacted together.
+	set("b=blueberry")
+	if err := db.Compact([]byte("a"), []byte("d"), false /* parallelize */); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Generated db with following LSM:\n%s\n", db.DebugString())
+	err = db.Close()
+	db = nil
+	if err != nil {
+		log.Fatal(err)
+	}
+}
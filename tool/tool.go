@@ -12,6 +12,7 @@ import (
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/colblk"
 	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/encfs"
 	"github.com/spf13/cobra"
 )
 
@@ -41,6 +42,7 @@ type T struct {
 	openErrEnhancer func(error) error
 	openOptions     []OpenOption
 	exciseSpanFn    DBExciseSpanFn
+	encryptionKeys  encfs.KeyProvider
 }
 
 // A Option configures the Pebble introspection tool.
@@ -120,6 +122,15 @@ func FS(fs vfs.FS) Option {
 	}
 }
 
+// EncryptionKeys configures the introspection tools to read files encrypted
+// at rest through an encfs.FS with the provided keys. The filesystem set by FS
+// is wrapped in an encfs.FS.
+func EncryptionKeys(keys encfs.KeyProvider) Option {
+	return func(t *T) {
+		t.encryptionKeys = keys
+	}
+}
+
 // OpenErrEnhancer sets a function that enhances an error encountered when the
 // tool opens a database; used to provide the user additional context, for
 // example that a corruption error might be caused by encryption at rest not
@@ -165,6 +176,9 @@ func New(opts ...Option) *T {
 	for _, opt := range opts {
 		opt(t)
 	}
+	if t.encryptionKeys != nil {
+		t.opts.FS = encfs.New(t.opts.FS, t.encryptionKeys)
+	}
 
 	t.db = newDB(&t.opts, t.comparers, t.mergers, t.openErrEnhancer, t.openOptions, t.exciseSpanFn)
 	t.find = newFind(&t.opts, t.comparers, t.defaultComparer, t.mergers)
@@ -0,0 +1,199 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// Tracer creates structured tracing spans (see Options.Tracer). It is
+// implemented by an adapter to a tracing library, such as OpenTelemetry.
+//
+// Spans are created for the following operations, with the following names:
+//
+//   - FlushSpanName: a flush of memtables or of ingested sstables.
+//   - CompactionSpanName: a compaction.
+//   - IngestSpanName: an ingestion of sstables, including ingestions that
+//     excise a span (see DB.IngestAndExcise).
+//   - ExciseSpanName: an excise without ingested sstables (see DB.Excise).
+//   - DownloadSpanName: a download of external sstables (see DB.Download).
+//   - IteratorSpanName: an iterator created with a traced context (see
+//     Tracer.IsTracing).
+//
+// The spans of jobs have a job_id attribute with the JobID of the job, which
+// matches the JobID of the events reported to the EventListener. The other
+// attributes of each span are set before the span ends.
+type Tracer interface {
+	// StartSpan starts a span with the provided name. The span is a child of
+	// the span in ctx, if any. The returned context contains the new span.
+	//
+	// The spans of flushes and compactions are started with a background
+	// context; the spans of ingestions, excises, downloads and iterators are
+	// started with the context passed to the DB method.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+	// IsTracing returns true if ctx is traced. Iterators are only traced if the
+	// context passed to NewIterWithContext is traced, to avoid the overhead of
+	// a span for every iterator.
+	IsTracing(ctx context.Context) bool
+}
+
+// Span is a tracing span started by a Tracer.
+type Span interface {
+	// SetAttributes sets attributes of the span.
+	SetAttributes(attrs ...SpanAttribute)
+	// End ends the span. The error is the error the operation failed with, if
+	// any.
+	End(err error)
+}
+
+// SpanAttribute is an attribute of a Span. The Value is a bool, an int64, a
+// float64, a string, or a slice of int64s or float64s. Durations are float64
+// seconds, and the attributes of slices of levels are indexed by level.
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// The names of the spans created by the DB. See Tracer.
+const (
+	FlushSpanName      = "pebble.flush"
+	CompactionSpanName = "pebble.compaction"
+	IngestSpanName     = "pebble.ingest"
+	ExciseSpanName     = "pebble.excise"
+	DownloadSpanName   = "pebble.download"
+	IteratorSpanName   = "pebble.iterator"
+)
+
+// noopSpan is the Span used when the DB is not configured with a Tracer.
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...SpanAttribute) {}
+
+func (noopSpan) End(error) {}
+
+// startJobSpan starts a span for the job with the provided ID.
+func (d *DB) startJobSpan(ctx context.Context, name string, jobID JobID) (context.Context, Span) {
+	if d.opts.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := d.opts.Tracer.StartSpan(ctx, name)
+	span.SetAttributes(SpanAttribute{Key: "job_id", Value: int64(jobID)})
+	return ctx, span
+}
+
+func (i FlushInfo) spanAttributes() []SpanAttribute {
+	attrs := []SpanAttribute{
+		{Key: "inputs", Value: int64(i.Input)},
+		{Key: "input_bytes", Value: int64(i.InputBytes)},
+		{Key: "ingest", Value: i.Ingest},
+		{Key: "output_files", Value: int64(len(i.Output))},
+		{Key: "output_bytes", Value: int64(tablesTotalSize(i.Output))},
+		{Key: "duration", Value: i.Duration.Seconds()},
+		{Key: "total_duration", Value: i.TotalDuration.Seconds()},
+	}
+	if i.Ingest {
+		attrs = append(attrs, SpanAttribute{Key: "output_levels", Value: intsToInt64s(i.IngestLevels)})
+	} else {
+		attrs = append(attrs, SpanAttribute{Key: "output_level", Value: int64(0)})
+	}
+	return attrs
+}
+
+func (i CompactionInfo) spanAttributes() []SpanAttribute {
+	inputLevels := make([]int64, len(i.Input))
+	var inputFiles int
+	var inputBytes uint64
+	for j := range i.Input {
+		inputLevels[j] = int64(i.Input[j].Level)
+		inputFiles += len(i.Input[j].Tables)
+		inputBytes += tablesTotalSize(i.Input[j].Tables)
+	}
+	return []SpanAttribute{
+		{Key: "reason", Value: i.Reason},
+		{Key: "input_levels", Value: inputLevels},
+		{Key: "input_files", Value: int64(inputFiles)},
+		{Key: "input_bytes", Value: int64(inputBytes)},
+		{Key: "output_level", Value: int64(i.Output.Level)},
+		{Key: "output_files", Value: int64(len(i.Output.Tables))},
+		{Key: "output_bytes", Value: int64(tablesTotalSize(i.Output.Tables))},
+		{Key: "duration", Value: i.Duration.Seconds()},
+		{Key: "total_duration", Value: i.TotalDuration.Seconds()},
+	}
+}
+
+func (i DownloadInfo) spanAttributes() []SpanAttribute {
+	return []SpanAttribute{
+		{Key: "spans", Value: int64(len(i.Spans))},
+		{Key: "compactions_launched", Value: int64(i.DownloadCompactionsLaunched)},
+		{Key: "restarts", Value: int64(i.RestartCount)},
+		{Key: "duration", Value: i.Duration.Seconds()},
+	}
+}
+
+func (i TableIngestInfo) spanAttributes(stats IngestOperationStats) []SpanAttribute {
+	levels := make([]int64, len(i.Tables))
+	for j := range i.Tables {
+		levels[j] = int64(i.Tables[j].Level)
+	}
+	return []SpanAttribute{
+		{Key: "files", Value: int64(len(i.Tables))},
+		{Key: "bytes", Value: int64(stats.Bytes)},
+		{Key: "l0_bytes", Value: int64(stats.ApproxIngestedIntoL0Bytes)},
+		{Key: "flushable", Value: i.flushable},
+		{Key: "output_levels", Value: levels},
+	}
+}
+
+func intsToInt64s(s []int) []int64 {
+	r := make([]int64, len(s))
+	for i := range s {
+		r[i] = int64(s[i])
+	}
+	return r
+}
+
+// iterTracing holds the tracing state of a traced Iterator.
+type iterTracing struct {
+	span Span
+	// levels accumulates the block stats of the iterators of each level.
+	levels [numLevels]block.CategoryStats
+}
+
+// maybeStartIterSpan starts a span for the iterator if ctx is traced.
+func (d *DB) maybeStartIterSpan(ctx context.Context) (context.Context, *iterTracing) {
+	if d.opts.Tracer == nil || !d.opts.Tracer.IsTracing(ctx) {
+		return ctx, nil
+	}
+	t := &iterTracing{}
+	ctx, t.span = d.opts.Tracer.StartSpan(ctx, IteratorSpanName)
+	return ctx, t
+}
+
+// end ends the span of the iterator with the provided stats.
+func (t *iterTracing) end(stats *IteratorStats, err error) {
+	var blockBytes, blockBytesInCache [numLevels]int64
+	var blockReadDuration [numLevels]float64
+	for l := range t.levels {
+		blockBytes[l] = int64(t.levels[l].BlockBytes)
+		blockBytesInCache[l] = int64(t.levels[l].BlockBytesInCache)
+		blockReadDuration[l] = t.levels[l].BlockReadDuration.Seconds()
+	}
+	t.span.SetAttributes(
+		SpanAttribute{Key: "forward_seeks", Value: int64(stats.ForwardSeekCount[InterfaceCall])},
+		SpanAttribute{Key: "reverse_seeks", Value: int64(stats.ReverseSeekCount[InterfaceCall])},
+		SpanAttribute{Key: "forward_steps", Value: int64(stats.ForwardStepCount[InterfaceCall])},
+		SpanAttribute{Key: "reverse_steps", Value: int64(stats.ReverseStepCount[InterfaceCall])},
+		SpanAttribute{Key: "points", Value: int64(stats.InternalStats.PointCount)},
+		SpanAttribute{Key: "block_bytes", Value: int64(stats.InternalStats.BlockBytes)},
+		SpanAttribute{Key: "block_bytes_in_cache", Value: int64(stats.InternalStats.BlockBytesInCache)},
+		SpanAttribute{Key: "block_read_duration", Value: stats.InternalStats.BlockReadDuration.Seconds()},
+		SpanAttribute{Key: "level_block_bytes", Value: blockBytes[:]},
+		SpanAttribute{Key: "level_block_bytes_in_cache", Value: blockBytesInCache[:]},
+		SpanAttribute{Key: "level_block_read_duration", Value: blockReadDuration[:]},
+	)
+	t.span.End(err)
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTracer is a Tracer that records the spans it starts. A context is
+// traced if it contains a span.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpanKey struct{}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	name   string
+	parent *recordingSpan
+	attrs  map[string]interface{}
+	ended  bool
+	err    error
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &recordingSpan{tracer: t, name: name, attrs: make(map[string]interface{})}
+	s.parent, _ = ctx.Value(recordingSpanKey{}).(*recordingSpan)
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, recordingSpanKey{}, s), s
+}
+
+func (t *recordingTracer) IsTracing(ctx context.Context) bool {
+	return ctx.Value(recordingSpanKey{}) != nil
+}
+
+// spansNamed returns the spans with the provided name.
+func (t *recordingTracer) spansNamed(name string) []*recordingSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []*recordingSpan
+	for _, s := range t.spans {
+		if s.name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...SpanAttribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) End(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+	s.err = err
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	fs := vfs.NewMem()
+	d, err := Open("", &Options{
+		FS:                 fs,
+		FormatMajorVersion: FormatNewest,
+		Tracer:             tracer,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	ctx, root := tracer.StartSpan(context.Background(), "test")
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("a%d", i)), []byte("value"), nil))
+	}
+	require.NoError(t, d.Flush())
+	flushes := tracer.spansNamed(FlushSpanName)
+	require.Len(t, flushes, 1)
+	require.True(t, flushes[0].ended)
+	require.NoError(t, flushes[0].err)
+	require.Nil(t, flushes[0].parent)
+	require.Equal(t, int64(1), flushes[0].attrs["output_files"])
+	require.Equal(t, int64(0), flushes[0].attrs["output_level"])
+	require.Contains(t, flushes[0].attrs, "job_id")
+
+	require.NoError(t, d.Compact([]byte("a"), []byte("b"), false))
+	var compaction *recordingSpan
+	for _, s := range tracer.spansNamed(CompactionSpanName) {
+		if s.attrs["output_level"] == int64(numLevels-1) {
+			compaction = s
+		}
+	}
+	require.NotNil(t, compaction)
+	require.True(t, compaction.ended)
+	require.Equal(t, []int64{0, numLevels - 1}, compaction.attrs["input_levels"])
+	require.Equal(t, int64(1), compaction.attrs["input_files"])
+	require.Equal(t, int64(1), compaction.attrs["output_files"])
+
+	// Ingestions and excises are traced as children of the span of the
+	// context they are called with.
+	f, err := fs.Create("ext", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+		TableFormat: d.TableFormat(),
+	})
+	require.NoError(t, w.Set([]byte("c"), []byte("value")))
+	require.NoError(t, w.Close())
+	require.NoError(t, d.Ingest(ctx, []string{"ext"}))
+	ingests := tracer.spansNamed(IngestSpanName)
+	require.Len(t, ingests, 1)
+	require.True(t, ingests[0].ended)
+	require.Equal(t, root, ingests[0].parent)
+	require.Equal(t, int64(1), ingests[0].attrs["files"])
+	require.Equal(t, []int64{int64(numLevels - 1)}, ingests[0].attrs["output_levels"])
+
+	require.NoError(t, d.Excise(ctx, KeyRange{Start: []byte("a3"), End: []byte("b")}))
+	excises := tracer.spansNamed(ExciseSpanName)
+	require.Len(t, excises, 1)
+	require.True(t, excises[0].ended)
+	require.Equal(t, root, excises[0].parent)
+	require.Equal(t, int64(1), excises[0].attrs["excised_tables"])
+
+	// Iterators are only traced if their context is traced.
+	iter, err := d.NewIterWithContext(context.Background(), nil)
+	require.NoError(t, err)
+	require.NoError(t, iter.Close())
+	require.Empty(t, tracer.spansNamed(IteratorSpanName))
+
+	iter, err = d.NewIterWithContext(ctx, nil)
+	require.NoError(t, err)
+	var n int
+	for valid := iter.First(); valid; valid = iter.Next() {
+		n++
+	}
+	require.Equal(t, 4, n)
+	require.NoError(t, iter.Close())
+	iters := tracer.spansNamed(IteratorSpanName)
+	require.Len(t, iters, 1)
+	require.True(t, iters[0].ended)
+	require.Equal(t, root, iters[0].parent)
+	require.Equal(t, int64(1), iters[0].attrs["forward_seeks"])
+	require.Equal(t, int64(4), iters[0].attrs["points"])
+	// All of the blocks read are in the last level.
+	blockBytes := iters[0].attrs["level_block_bytes"].([]int64)
+	require.Len(t, blockBytes, numLevels)
+	require.Greater(t, blockBytes[numLevels-1], int64(0))
+	require.Equal(t, iters[0].attrs["block_bytes"], blockBytes[numLevels-1])
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import "time"
+
+// expiredBytesRefreshInterval is the minimum interval between two refreshes of
+// the ExpiredBytesEstimate stats of the tables in the current version.
+const expiredBytesRefreshInterval = time.Minute
+
+// TTLExtractor extracts the expiration time of a key-value pair written with
+// Set. If ok is false, the key never expires. Once the current time is at or
+// after expiresAt, the key is treated as deleted: iterators and Get skip it,
+// and compactions drop it.
+//
+// The extractor must be deterministic and must not retain userKey or value.
+// Compactions may combine merge operands into a single value, so the extractor
+// is also applied to the results of merges. An expired value is never merged
+// into newer merge operands.
+type TTLExtractor func(userKey, value []byte) (expiresAt time.Time, ok bool)
+
+// ttlChecker determines whether keys have expired as of a fixed point in time.
+// The zero value never considers any key expired.
+type ttlChecker struct {
+	extractor TTLExtractor
+	now       time.Time
+}
+
+// newTTLChecker returns a ttlChecker evaluating expirations as of the current
+// time using the configured TTLExtractor. If no TTLExtractor is configured,
+// it returns the zero value without consulting the clock.
+func (d *DB) newTTLChecker() ttlChecker {
+	if d.opts.Experimental.TTLExtractor == nil {
+		return ttlChecker{}
+	}
+	return ttlChecker{extractor: d.opts.Experimental.TTLExtractor, now: d.timeNow()}
+}
+
+// enabled returns true if keys may expire.
+func (c ttlChecker) enabled() bool {
+	return c.extractor != nil
+}
+
+// expired returns true if the provided key-value pair has expired.
+func (c ttlChecker) expired(userKey, value []byte) bool {
+	if c.extractor == nil {
+		return false
+	}
+	expiresAt, ok := c.extractor(userKey, value)
+	return ok && !c.now.Before(expiresAt)
+}
+
+// expiredBytesEstimate estimates the disk space occupied by the point keys of
+// the given table that have expired at the given time.
+func expiredBytesEstimate(f *tableMetadata, now time.Time) uint64 {
+	h := f.Stats.Expiry
+	if h == nil || h.TotalBytes == 0 {
+		return 0
+	}
+	// Scale the logical size of the expired keys to the table's size on disk.
+	return uint64(float64(f.Size) * float64(h.ExpiredBytes(now.Unix())) / float64(h.TotalBytes))
+}
+
+// maybeRefreshExpiredBytesLocked updates the ExpiredBytesEstimate stats of the
+// tables in the current version as of the given time, unless they were
+// refreshed less than expiredBytesRefreshInterval ago. The estimates are
+// derived from the tables' expiry histograms, without reading the tables.
+//
+// Requires d.mu to be held.
+func (d *DB) maybeRefreshExpiredBytesLocked(now time.Time) {
+	if d.opts.Experimental.TTLExtractor == nil || now.Before(d.mu.tableStats.expiryRefreshAt) {
+		return
+	}
+	d.mu.tableStats.expiryRefreshAt = now.Add(expiredBytesRefreshInterval)
+	vers := d.mu.versions.currentVersion()
+	for l := range vers.Levels {
+		changed := false
+		iter := vers.Levels[l].Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			if !f.StatsValid() || f.Stats.Expiry == nil {
+				continue
+			}
+			if e := expiredBytesEstimate(f, now); e != f.Stats.ExpiredBytesEstimate {
+				f.Stats.ExpiredBytesEstimate = e
+				changed = true
+			}
+		}
+		if changed {
+			// The annotations computed from the tables' stats are out of date.
+			compensatedSizeAnnotator.InvalidateLevelAnnotation(vers.Levels[l])
+			elisionOnlyAnnotator.InvalidateLevelAnnotation(vers.Levels[l])
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTL(t *testing.T) {
+	// Values are prefixed with their expiration time in seconds since the
+	// epoch. A zero expiration time indicates the value never expires.
+	makeValue := func(expiresAt int64, v string) []byte {
+		return append(binary.BigEndian.AppendUint64(nil, uint64(expiresAt)), v...)
+	}
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+		Logger:                      testLogger{t},
+	}
+	opts.Experimental.TTLExtractor = func(userKey, value []byte) (time.Time, bool) {
+		expiresAt := int64(binary.BigEndian.Uint64(value))
+		return time.Unix(expiresAt, 0), expiresAt != 0
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	var now atomic.Int64
+	now.Store(100)
+	d.timeNow = func() time.Time { return time.Unix(now.Load(), 0) }
+
+	// Even keys expire at t=200; odd keys never expire.
+	const numKeys = 10
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%02d", i)) }
+	for i := 0; i < numKeys; i++ {
+		var expiresAt int64
+		if i%2 == 0 {
+			expiresAt = 200
+		}
+		require.NoError(t, d.Set(key(i), makeValue(expiresAt, "v"), nil))
+	}
+
+	check := func(expired bool) {
+		t.Helper()
+		var expected []string
+		for i := 0; i < numKeys; i++ {
+			v, closer, err := d.Get(key(i))
+			if i%2 == 0 && expired {
+				require.True(t, errors.Is(err, ErrNotFound), "%s: %v", key(i), err)
+				continue
+			}
+			require.NoError(t, err)
+			require.Equal(t, "v", string(v[8:]))
+			require.NoError(t, closer.Close())
+			expected = append(expected, string(key(i)))
+		}
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		var forward, backward []string
+		for valid := iter.First(); valid; valid = iter.Next() {
+			forward = append(forward, string(iter.Key()))
+		}
+		for valid := iter.Last(); valid; valid = iter.Prev() {
+			backward = append([]string{string(iter.Key())}, backward...)
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, expected, forward)
+		require.Equal(t, expected, backward)
+	}
+
+	// expiredBytes waits for the table stats to be loaded and returns the sum
+	// of the tables' expired bytes estimates.
+	expiredBytes := func() (n uint64) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for !d.mu.tableStats.loadedInitial || d.mu.tableStats.loading || len(d.mu.tableStats.pending) > 0 {
+			d.mu.tableStats.cond.Wait()
+		}
+		for _, l := range d.mu.versions.currentVersion().Levels {
+			iter := l.Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				require.NotNil(t, f.Stats.Expiry)
+				n += f.Stats.ExpiredBytesEstimate
+			}
+		}
+		return n
+	}
+
+	check(false /* expired */)
+	require.NoError(t, d.Flush())
+	check(false /* expired */)
+	require.Zero(t, expiredBytes())
+	now.Store(200)
+	check(true /* expired */)
+
+	// The estimates are derived from the tables' expiry histograms when they
+	// are refreshed.
+	d.mu.Lock()
+	// The estimates were last refreshed using the wall clock, when the
+	// database was opened.
+	d.mu.tableStats.expiryRefreshAt = time.Time{}
+	d.maybeRefreshExpiredBytesLocked(d.timeNow())
+	d.mu.Unlock()
+	refreshed := expiredBytes()
+	require.NotZero(t, refreshed)
+
+	// Reopen the database, using the wall clock. The expired keys' table stats
+	// are loaded from the tables' properties when the database is opened.
+	require.NoError(t, d.Close())
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	check(true /* expired */)
+	require.Equal(t, refreshed, expiredBytes())
+
+	// The table is moved into the bottommost level without being rewritten.
+	// Its expired keys make it eligible for an elision-only compaction, which
+	// drops them, so they remain deleted even if the clock moves backwards.
+	require.NoError(t, d.Compact(key(0), key(numKeys), false /* parallelize */))
+	d.mu.Lock()
+	d.opts.DisableAutomaticCompactions = false
+	d.maybeScheduleCompaction()
+	d.mu.Unlock()
+	require.Eventually(t, func() bool {
+		return d.Metrics().Compact.ElisionOnlyCount > 0
+	}, 10*time.Second, 10*time.Millisecond)
+	d.timeNow = func() time.Time { return time.Unix(now.Load(), 0) }
+	now.Store(100)
+	check(true /* expired */)
+}
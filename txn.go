@@ -0,0 +1,280 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"io"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// ErrTxnConflict is returned by Txn.Commit when a key read by the transaction
+// was modified after the transaction's snapshot was taken.
+var ErrTxnConflict = errors.New("pebble: transaction conflict")
+
+// Txn is an optimistic transaction. Reads observe a consistent snapshot of the
+// DB taken when the transaction was created, overlaid with the transaction's
+// own writes. Writes are buffered in an indexed batch until Commit.
+//
+// The transaction records the point keys read through Get and the key ranges
+// scanned through NewIter. At commit, the newest sequence number of each
+// recorded key and key range is compared against the sequence number of the
+// transaction's snapshot; if any of them was written after the snapshot was
+// taken, the commit fails with ErrTxnConflict and none of the transaction's
+// writes are applied. Any write conflicts, even one that leaves a key's value
+// unchanged.
+//
+// A Txn is not safe for concurrent use, and must be closed after use.
+type Txn struct {
+	db       *DB
+	batch    *Batch
+	snapshot *Snapshot
+	// reads holds the point keys read by the transaction. The keys are owned
+	// by the transaction.
+	reads [][]byte
+	// scans holds the key ranges scanned by the transaction's iterators.
+	scans []txnScan
+}
+
+// txnScan describes a key range [lower, upper) scanned by a transaction. A
+// nil bound is unbounded.
+type txnScan struct {
+	lower, upper []byte
+}
+
+var _ Reader = (*Txn)(nil)
+var _ Writer = (*Txn)(nil)
+
+// NewTxn returns a new optimistic transaction reading at the current state of
+// the DB.
+func (d *DB) NewTxn() *Txn {
+	return &Txn{
+		db:       d,
+		batch:    d.NewIndexedBatch(),
+		snapshot: d.NewSnapshot(),
+	}
+}
+
+// Get gets the value for the given key, reading the transaction's own writes
+// on top of its snapshot. It returns ErrNotFound if the key does not exist.
+// The key is added to the transaction's read set.
+//
+// The caller should not modify the contents of the returned slice, but it is
+// safe to modify the contents of the argument after Get returns. The returned
+// slice will remain valid until the returned Closer is closed. On success, the
+// caller MUST call closer.Close() or a memory leak will occur.
+func (t *Txn) Get(key []byte) ([]byte, io.Closer, error) {
+	t.reads = append(t.reads, slices.Clone(key))
+	return t.db.getInternal(key, t.batch, t.snapshot)
+}
+
+// NewIter returns an iterator over the transaction's own writes on top of its
+// snapshot. The key range [LowerBound, UpperBound) of the provided options is
+// added to the transaction's read set. Changes to the iterator's bounds made
+// through SetBounds or SetOptions are not tracked.
+func (t *Txn) NewIter(o *IterOptions) (*Iterator, error) {
+	return t.NewIterWithContext(context.Background(), o)
+}
+
+// NewIterWithContext is like NewIter, and additionally accepts a context for
+// tracing.
+func (t *Txn) NewIterWithContext(ctx context.Context, o *IterOptions) (*Iterator, error) {
+	var scan txnScan
+	if o != nil {
+		scan.lower = slices.Clone(o.LowerBound)
+		scan.upper = slices.Clone(o.UpperBound)
+	}
+	t.scans = append(t.scans, scan)
+	return t.db.newIter(ctx, t.batch, newIterOpts{
+		snapshot: snapshotIterOpts{seqNum: t.snapshot.seqNum},
+	}, o), nil
+}
+
+// Apply the operations contained in the batch to the transaction.
+//
+// It is safe to modify the contents of the arguments after Apply returns.
+func (t *Txn) Apply(batch *Batch, opts *WriteOptions) error {
+	return t.batch.Apply(batch, opts)
+}
+
+// Set adds an action to the transaction that sets the key to map to the value.
+//
+// It is safe to modify the contents of the arguments after Set returns.
+func (t *Txn) Set(key, value []byte, opts *WriteOptions) error {
+	return t.batch.Set(key, value, opts)
+}
+
+// Merge adds an action to the transaction that merges the value at key with
+// the new value.
+//
+// It is safe to modify the contents of the arguments after Merge returns.
+func (t *Txn) Merge(key, value []byte, opts *WriteOptions) error {
+	return t.batch.Merge(key, value, opts)
+}
+
+// Delete adds an action to the transaction that deletes the key.
+//
+// It is safe to modify the contents of the arguments after Delete returns.
+func (t *Txn) Delete(key []byte, opts *WriteOptions) error {
+	return t.batch.Delete(key, opts)
+}
+
+// DeleteSized behaves identically to Delete, but takes an additional argument
+// indicating the size of the value being deleted. See Batch.DeleteSized.
+//
+// It is safe to modify the contents of the arguments after DeleteSized
+// returns.
+func (t *Txn) DeleteSized(key []byte, deletedValueSize uint32, opts *WriteOptions) error {
+	return t.batch.DeleteSized(key, deletedValueSize, opts)
+}
+
+// SingleDelete adds an action to the transaction that single deletes the key.
+// See Writer.SingleDelete for more details on the semantics of SingleDelete.
+//
+// It is safe to modify the contents of the arguments after SingleDelete
+// returns.
+func (t *Txn) SingleDelete(key []byte, opts *WriteOptions) error {
+	return t.batch.SingleDelete(key, opts)
+}
+
+// DeleteRange adds an action to the transaction that deletes all keys in the
+// range [start, end).
+//
+// It is safe to modify the contents of the arguments after DeleteRange
+// returns.
+func (t *Txn) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	return t.batch.DeleteRange(start, end, opts)
+}
+
+// LogData adds the specified to the transaction. The data will be written to
+// the WAL, but not added to memtables or sstables.
+//
+// It is safe to modify the contents of the arguments after LogData returns.
+func (t *Txn) LogData(data []byte, opts *WriteOptions) error {
+	return t.batch.LogData(data, opts)
+}
+
+// RangeKeySet sets a range key mapping the key range [start, end) at the MVCC
+// timestamp suffix to value.
+//
+// It is safe to modify the contents of the arguments after RangeKeySet
+// returns.
+func (t *Txn) RangeKeySet(start, end, suffix, value []byte, opts *WriteOptions) error {
+	return t.batch.RangeKeySet(start, end, suffix, value, opts)
+}
+
+// RangeKeyUnset removes a range key mapping the key range [start, end) at the
+// MVCC timestamp suffix.
+//
+// It is safe to modify the contents of the arguments after RangeKeyUnset
+// returns.
+func (t *Txn) RangeKeyUnset(start, end, suffix []byte, opts *WriteOptions) error {
+	return t.batch.RangeKeyUnset(start, end, suffix, opts)
+}
+
+// RangeKeyDelete deletes all of the range keys in the range [start,end).
+//
+// It is safe to modify the contents of the arguments after RangeKeyDelete
+// returns.
+func (t *Txn) RangeKeyDelete(start, end []byte, opts *WriteOptions) error {
+	return t.batch.RangeKeyDelete(start, end, opts)
+}
+
+// Commit applies the transaction's writes to the DB if none of the keys in
+// its read set were modified since the transaction's snapshot was taken. It
+// returns ErrTxnConflict otherwise, in which case none of the writes are
+// applied. A transaction without writes commits trivially. The transaction
+// must be closed after Commit, regardless of the outcome.
+func (t *Txn) Commit(opts *WriteOptions) error {
+	t.batch.validateCommit = t.validate
+	return t.batch.Commit(opts)
+}
+
+// Close releases the transaction's snapshot and batch. Any uncommitted writes
+// are discarded.
+func (t *Txn) Close() error {
+	err := t.snapshot.Close()
+	return errors.CombineErrors(err, t.batch.Close())
+}
+
+// validate checks that none of the keys read by the transaction were written
+// after the transaction's snapshot was taken, as of the state of the DB at the
+// given visible sequence number.
+func (t *Txn) validate(seqNum base.SeqNum) error {
+	if seqNum == t.snapshot.seqNum {
+		// Nothing has been committed since the snapshot was taken.
+		return nil
+	}
+	d := t.db
+	slices.SortFunc(t.reads, d.cmp)
+	t.reads = slices.CompactFunc(t.reads, func(a, b []byte) bool { return d.equal(a, b) })
+	var upper []byte
+	for _, key := range t.reads {
+		upper = d.opts.Comparer.ImmediateSuccessor(upper[:0], key)
+		if changed, err := t.changed(txnScan{lower: key, upper: upper}, seqNum); err != nil {
+			return err
+		} else if changed {
+			return ErrTxnConflict
+		}
+	}
+	for _, scan := range t.scans {
+		if changed, err := t.changed(scan, seqNum); err != nil {
+			return err
+		} else if changed {
+			return ErrTxnConflict
+		}
+	}
+	return nil
+}
+
+// changed returns true if a point key, range deletion or range key within the
+// scanned range was written after the transaction's snapshot was taken, as of
+// the state of the DB at the given visible sequence number. Shadowed keys are
+// not considered: only the newest version of each key is compared against the
+// snapshot's sequence number.
+func (t *Txn) changed(scan txnScan, seqNum base.SeqNum) (_ bool, err error) {
+	iter, err := t.db.newInternalIter(context.Background(), snapshotIterOpts{seqNum: seqNum},
+		&scanInternalOptions{
+			IterOptions: IterOptions{
+				KeyTypes:   IterKeyTypePointsAndRanges,
+				LowerBound: scan.lower,
+				UpperBound: scan.upper,
+			},
+		})
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = errors.CombineErrors(err, iter.close()) }()
+
+	var valid bool
+	if scan.lower != nil {
+		valid = iter.seekGE(scan.lower)
+	} else {
+		valid = iter.first()
+	}
+	for ; valid; valid = iter.next() {
+		key := iter.unsafeKey()
+		switch key.Kind() {
+		case InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete:
+			for _, k := range iter.unsafeSpan().Keys {
+				if k.SeqNum() >= t.snapshot.seqNum {
+					return true, nil
+				}
+			}
+		case InternalKeyKindRangeDelete:
+			if iter.unsafeRangeDel().LargestSeqNum() >= t.snapshot.seqNum {
+				return true, nil
+			}
+		default:
+			if key.SeqNum() >= t.snapshot.seqNum {
+				return true, nil
+			}
+		}
+	}
+	return false, iter.error()
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxn(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem(), Logger: testLogger{t}})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	require.NoError(t, d.Set([]byte("c"), []byte("1"), nil))
+
+	get := func(r Reader, key string) string {
+		t.Helper()
+		v, closer, err := r.Get([]byte(key))
+		if errors.Is(err, ErrNotFound) {
+			return "<not found>"
+		}
+		require.NoError(t, err)
+		defer closer.Close()
+		return string(v)
+	}
+	scan := func(txn *Txn, lower, upper string) string {
+		t.Helper()
+		iter, err := txn.NewIter(&IterOptions{LowerBound: []byte(lower), UpperBound: []byte(upper)})
+		require.NoError(t, err)
+		var s string
+		for valid := iter.First(); valid; valid = iter.Next() {
+			s += string(iter.Key()) + "=" + string(iter.Value()) + " "
+		}
+		require.NoError(t, iter.Close())
+		return s
+	}
+
+	t.Run("read-your-writes", func(t *testing.T) {
+		txn := d.NewTxn()
+		defer func() { require.NoError(t, txn.Close()) }()
+		require.NoError(t, txn.Set([]byte("b"), []byte("txn"), nil))
+		require.NoError(t, txn.Delete([]byte("c"), nil))
+		// Writes committed after the transaction started are not visible.
+		require.NoError(t, d.Set([]byte("a"), []byte("2"), nil))
+		require.Equal(t, "1", get(txn, "a"))
+		require.Equal(t, "txn", get(txn, "b"))
+		require.Equal(t, "<not found>", get(txn, "c"))
+		require.Equal(t, "a=1 b=txn ", scan(txn, "a", "d"))
+		require.NoError(t, d.Set([]byte("c"), []byte("1"), nil))
+	})
+
+	t.Run("point-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		defer func() { require.NoError(t, txn.Close()) }()
+		require.Equal(t, "2", get(txn, "a"))
+		require.Equal(t, "<not found>", get(txn, "x"))
+		require.NoError(t, txn.Set([]byte("b"), []byte("from-txn"), nil))
+		require.NoError(t, d.Set([]byte("x"), []byte("1"), nil))
+		require.True(t, errors.Is(txn.Commit(nil), ErrTxnConflict))
+		// None of the transaction's writes were applied.
+		require.Equal(t, "<not found>", get(d, "b"))
+	})
+
+	t.Run("scan-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		defer func() { require.NoError(t, txn.Close()) }()
+		require.Equal(t, "a=2 c=1 ", scan(txn, "a", "d"))
+		require.NoError(t, txn.Set([]byte("y"), []byte("from-txn"), nil))
+		// A key inserted into the scanned range is a conflict, even though it
+		// was never returned by the transaction's iterator.
+		require.NoError(t, d.Set([]byte("b"), []byte("1"), nil))
+		require.True(t, errors.Is(txn.Commit(nil), ErrTxnConflict))
+		require.Equal(t, "<not found>", get(d, "y"))
+	})
+
+	t.Run("no-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		defer func() { require.NoError(t, txn.Close()) }()
+		require.Equal(t, "2", get(txn, "a"))
+		require.Equal(t, "b=1 ", scan(txn, "b", "c"))
+		require.NoError(t, txn.Set([]byte("a"), []byte("from-txn"), nil))
+		// Writes outside the transaction's read set do not conflict.
+		require.NoError(t, d.Set([]byte("c"), []byte("2"), nil))
+		require.NoError(t, d.Set([]byte("z"), []byte("1"), nil))
+		require.NoError(t, d.Flush())
+		require.NoError(t, txn.Commit(nil))
+		require.Equal(t, "from-txn", get(d, "a"))
+	})
+
+	t.Run("unchanged-value-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		defer func() { require.NoError(t, txn.Close()) }()
+		require.Equal(t, "from-txn", get(txn, "a"))
+		require.NoError(t, txn.Set([]byte("y"), []byte("from-txn"), nil))
+		// The key is overwritten and then restored to the value read by the
+		// transaction. This is still a conflict.
+		require.NoError(t, d.Set([]byte("a"), []byte("other"), nil))
+		require.NoError(t, d.Set([]byte("a"), []byte("from-txn"), nil))
+		require.NoError(t, d.Flush())
+		require.True(t, errors.Is(txn.Commit(nil), ErrTxnConflict))
+		require.Equal(t, "<not found>", get(d, "y"))
+	})
+
+	t.Run("range-delete-conflict", func(t *testing.T) {
+		txn := d.NewTxn()
+		defer func() { require.NoError(t, txn.Close()) }()
+		require.Equal(t, "<not found>", get(txn, "m"))
+		require.NoError(t, txn.Set([]byte("y"), []byte("from-txn"), nil))
+		// A range deletion covering the read key is a conflict, even though
+		// the key did not exist.
+		require.NoError(t, d.DeleteRange([]byte("l"), []byte("n"), nil))
+		require.True(t, errors.Is(txn.Commit(nil), ErrTxnConflict))
+	})
+
+	t.Run("concurrent-writes", func(t *testing.T) {
+		// Transactions commit while other goroutines keep committing writes
+		// outside of their read sets.
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; ; j++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if err := d.Set([]byte(fmt.Sprintf("w%d-%d", i, j)), nil, nil); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}(i)
+		}
+		for i := 0; i < 20; i++ {
+			txn := d.NewTxn()
+			key := fmt.Sprintf("t%02d", i)
+			require.Equal(t, "<not found>", get(txn, key))
+			require.NoError(t, txn.Set([]byte(key), []byte("from-txn"), nil))
+			require.NoError(t, txn.Commit(nil))
+			require.NoError(t, txn.Close())
+			require.Equal(t, "from-txn", get(d, key))
+		}
+		close(stop)
+		wg.Wait()
+	})
+}
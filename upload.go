@@ -0,0 +1,357 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/tokenbucket"
+)
+
+// Upload compactions copy the local sstables of the levels that are created on
+// shared storage (see Options.Experimental.CreateOnShared) to shared storage,
+// replacing them in the LSM. New sstables are created directly on shared
+// storage, so upload compactions are only needed for sstables that were created
+// before shared storage was enabled (or before SharedLevelsStart was lowered).
+//
+// Upload compactions run in the background, one at a time, and don't count
+// toward MaxConcurrentCompactions. A physical sstable is copied byte-for-byte
+// (like a copy compaction); a virtual sstable is rewritten. Blob files
+// referenced by the uploaded sstables are not uploaded.
+
+const (
+	// uploadRetryBaseDelay and uploadRetryMaxDelay bound the delay before an
+	// upload compaction that follows a failed one.
+	uploadRetryBaseDelay = time.Second
+	uploadRetryMaxDelay  = time.Minute
+)
+
+// UploadMetrics holds the metrics of upload compactions.
+type UploadMetrics struct {
+	// Enabled is true if upload compactions are enabled.
+	Enabled bool
+	// Done is true once no local sstables need to be uploaded (see
+	// DB.WaitForUploads).
+	Done bool
+	// The number and total size of the sstables uploaded since the DB was
+	// opened.
+	Count int64
+	Bytes uint64
+	// The number and total size of the local sstables that remain to be
+	// uploaded.
+	PendingCount int64
+	PendingBytes uint64
+	// The number of upload compactions that are in progress.
+	NumInProgress int64
+	// FailedCount is the number of upload compactions that failed since the
+	// DB was opened.
+	FailedCount int64
+	// FailingCount is the number of pending sstables whose upload failed at
+	// least once. These sstables are retried after the other pending
+	// sstables.
+	FailingCount int64
+}
+
+// uploadState is the state of upload compactions, protected by DB.mu.
+type uploadState struct {
+	// pending contains the local sstables of the current version that are in
+	// levels created on shared storage, i.e. the sstables that need to be
+	// uploaded. It is kept up to date as version edits are applied (see
+	// applyEdit). It is nil if there is nothing to upload to.
+	pending      map[base.FileNum]pendingUpload
+	pendingBytes uint64
+	// doneCh is closed while no local sstables need to be uploaded; done is set
+	// once it is closed. A new channel is created when a version edit adds a
+	// local sstable to a level created on shared storage. doneCh is nil if
+	// upload compactions are disabled.
+	doneCh chan struct{}
+	done   bool
+	// consecutiveFailures is the number of upload compactions that failed in a
+	// row; subsequent uploads are delayed accordingly.
+	consecutiveFailures int
+	// failedCount is the number of upload compactions that failed since the DB
+	// was opened.
+	failedCount int64
+	// count and bytes are the number and total size of the sstables uploaded
+	// since the DB was opened.
+	count int64
+	bytes uint64
+	// pacer implements Options.Experimental.UploadBytesPerSecond. It is only
+	// used (without holding DB.mu) by the running upload compaction.
+	pacer tokenbucket.TokenBucket
+}
+
+// pendingUpload is a local sstable that needs to be uploaded.
+type pendingUpload struct {
+	level int
+	meta  *tableMetadata
+	// failures is the number of upload compactions of the sstable that
+	// failed. Sstables that failed fewer times are uploaded first, so that an
+	// sstable that can't be uploaded doesn't hold back the others.
+	failures int
+}
+
+// init initializes the upload state, looking up the sstables of the given
+// (initial) version that need to be uploaded.
+func (u *uploadState) init(opts *Options, vers *version, provider objstorage.Provider) {
+	if !opts.Experimental.UploadLocalTables || opts.ReadOnly {
+		return
+	}
+	u.doneCh = make(chan struct{})
+	u.done = true
+	close(u.doneCh)
+	if opts.Experimental.RemoteStorage == nil || opts.Experimental.CreateOnShared == remote.CreateOnSharedNone {
+		// There is no shared storage to upload to.
+		return
+	}
+	if rate := opts.Experimental.UploadBytesPerSecond; rate > 0 {
+		// Allow a burst of up to one second worth of uploads.
+		u.pacer.Init(tokenbucket.TokensPerSecond(rate), tokenbucket.Tokens(rate))
+	}
+	u.pending = make(map[base.FileNum]pendingUpload)
+	for level := 0; level < numLevels; level++ {
+		if !remote.ShouldCreateShared(opts.Experimental.CreateOnShared, level) {
+			continue
+		}
+		iter := vers.Levels[level].Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			u.maybeAddPending(level, f, provider)
+		}
+	}
+	u.updateDone()
+}
+
+// applyEdit updates the pending sstables after the given version edit was
+// applied.
+//
+// Requires DB.mu to be held.
+func (u *uploadState) applyEdit(ve *versionEdit, opts *Options, provider objstorage.Provider) {
+	if u.pending == nil {
+		return
+	}
+	for e, f := range ve.DeletedTables {
+		if _, ok := u.pending[e.FileNum]; ok {
+			delete(u.pending, e.FileNum)
+			u.pendingBytes -= f.Size
+		}
+	}
+	for _, e := range ve.NewTables {
+		if remote.ShouldCreateShared(opts.Experimental.CreateOnShared, e.Level) {
+			u.maybeAddPending(e.Level, e.Meta, provider)
+		}
+	}
+	u.updateDone()
+}
+
+// maybeAddPending adds the given sstable, which is in a level created on
+// shared storage, to the pending sstables if it is stored locally.
+func (u *uploadState) maybeAddPending(level int, f *tableMetadata, provider objstorage.Provider) {
+	if !objstorage.IsLocalTable(provider, f.FileBacking.DiskFileNum) {
+		return
+	}
+	p, ok := u.pending[f.FileNum]
+	if !ok {
+		u.pendingBytes += f.Size
+	}
+	p.level, p.meta = level, f
+	u.pending[f.FileNum] = p
+}
+
+// sortedPending returns the pending sstables in the order in which they are
+// uploaded: by number of failed uploads, level and file number.
+func (u *uploadState) sortedPending() []pendingUpload {
+	pending := make([]pendingUpload, 0, len(u.pending))
+	for _, p := range u.pending {
+		pending = append(pending, p)
+	}
+	slices.SortFunc(pending, func(a, b pendingUpload) int {
+		if a.failures != b.failures {
+			return cmp.Compare(a.failures, b.failures)
+		}
+		if a.level != b.level {
+			return cmp.Compare(a.level, b.level)
+		}
+		return cmp.Compare(a.meta.FileNum, b.meta.FileNum)
+	})
+	return pending
+}
+
+// updateDone closes doneCh once there are no pending sstables, and creates a
+// new channel when sstables become pending again.
+func (u *uploadState) updateDone() {
+	switch {
+	case len(u.pending) == 0 && !u.done:
+		u.done = true
+		close(u.doneCh)
+	case len(u.pending) > 0 && u.done:
+		u.done = false
+		u.doneCh = make(chan struct{})
+	}
+}
+
+// shouldScheduleUploadLocked returns true if an upload compaction may need to
+// be scheduled.
+//
+// Requires d.mu to be held.
+func (d *DB) shouldScheduleUploadLocked() bool {
+	return d.mu.compact.upload.doneCh != nil && !d.mu.compact.upload.done &&
+		d.mu.compact.uploadingCount == 0 && !d.opts.DisableAutomaticCompactions
+}
+
+// tryScheduleUploadCompaction starts an upload compaction for the first
+// pending sstable (see uploadState.sortedPending) that is not being compacted.
+// The sstables that are being compacted will either be replaced by sstables on
+// shared storage or be uploaded later.
+//
+// Requires d.mu to be held.
+func (d *DB) tryScheduleUploadCompaction(env compactionEnv) {
+	pending := d.mu.compact.upload.sortedPending()
+	vers := d.mu.versions.currentVersion()
+	env.inProgressCompactions = d.getInProgressCompactionInfoLocked(nil)
+	baseLevel := d.mu.versions.picker.getBaseLevel()
+	for _, p := range pending {
+		pc := pickDownloadCompaction(vers, d.opts, env, baseLevel, compactionKindUpload, p.level, p.meta)
+		if pc == nil {
+			continue
+		}
+		c := newCompaction(pc, d.opts, d.timeNow(), d.objProvider, nil /* slot */)
+		d.mu.compact.uploadingCount++
+		d.addInProgressCompaction(c)
+		go d.compact(c, nil)
+		return
+	}
+}
+
+// paceUpload waits before running the given upload compaction, according to
+// Options.Experimental.UploadBytesPerSecond and to the number of upload
+// compactions that failed in a row. It returns ErrCancelledCompaction if the
+// DB is closed while waiting.
+//
+// d.mu must be held when calling this method. The mutex is released while
+// waiting.
+func (d *DB) paceUpload(c *compaction) error {
+	var delay time.Duration
+	if n := d.mu.compact.upload.consecutiveFailures; n > 0 {
+		delay = min(uploadRetryBaseDelay<<min(n-1, 16), uploadRetryMaxDelay)
+	}
+	rateLimited := d.opts.Experimental.UploadBytesPerSecond > 0
+	if delay == 0 && !rateLimited {
+		return nil
+	}
+	size := c.startLevel.files.SizeSum()
+
+	// NB: The order here is reversed, lock after unlock. This is similar to
+	// runCompaction.
+	d.mu.Unlock()
+	defer d.mu.Lock()
+
+	for {
+		if delay > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-d.closedCh:
+				t.Stop()
+				return ErrCancelledCompaction
+			case <-t.C:
+			}
+		}
+		if !rateLimited {
+			return nil
+		}
+		var ok bool
+		if ok, delay = d.mu.compact.upload.pacer.TryToFulfill(tokenbucket.Tokens(size)); ok {
+			return nil
+		}
+	}
+}
+
+// uploadCompleted updates the upload state after an upload compaction
+// finished.
+//
+// Requires d.mu to be held.
+func (d *DB) uploadCompleted(c *compaction, err error) {
+	u := &d.mu.compact.upload
+	switch {
+	case err == nil:
+		u.consecutiveFailures = 0
+		u.count += int64(c.startLevel.files.Len())
+		u.bytes += c.startLevel.files.SizeSum()
+	case !errors.Is(err, ErrCancelledCompaction):
+		u.consecutiveFailures++
+		u.failedCount++
+		iter := c.startLevel.files.Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			if p, ok := u.pending[f.FileNum]; ok {
+				p.failures++
+				u.pending[f.FileNum] = p
+			}
+		}
+	}
+}
+
+// uploadMetricsLocked returns the upload metrics.
+//
+// Requires d.mu to be held.
+func (d *DB) uploadMetricsLocked() UploadMetrics {
+	u := &d.mu.compact.upload
+	m := UploadMetrics{
+		Enabled:       u.doneCh != nil,
+		Done:          u.done,
+		Count:         u.count,
+		Bytes:         u.bytes,
+		PendingCount:  int64(len(u.pending)),
+		PendingBytes:  u.pendingBytes,
+		NumInProgress: int64(d.mu.compact.uploadingCount),
+		FailedCount:   u.failedCount,
+	}
+	for _, p := range u.pending {
+		if p.failures > 0 {
+			m.FailingCount++
+		}
+	}
+	return m
+}
+
+// WaitForUploads waits until upload compactions (see
+// Options.Experimental.UploadLocalTables) have moved all the local sstables of
+// the levels that are created on shared storage to shared storage. The
+// progress of the uploads is reported in Metrics.Upload.
+//
+// It returns an error if upload compactions are not enabled, if the context is
+// canceled or if the DB is closed. Uploads that fail are retried indefinitely,
+// so WaitForUploads doesn't return while an sstable can't be uploaded; such
+// sstables are reported in Metrics.Upload.FailingCount.
+func (d *DB) WaitForUploads(ctx context.Context) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	d.mu.Lock()
+	doneCh := d.mu.compact.upload.doneCh
+	if doneCh != nil {
+		d.maybeScheduleCompaction()
+	}
+	d.mu.Unlock()
+	if doneCh == nil {
+		return errors.New("pebble: upload compactions are not enabled")
+	}
+	select {
+	case <-doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.closedCh:
+		return ErrClosed
+	}
+}
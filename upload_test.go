@@ -0,0 +1,179 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadCompactions(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                 mem,
+		FormatMajorVersion: FormatNewest,
+		Logger:             testLogger{t},
+	}
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": remote.NewInMem(),
+	})
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.SetCreatorID(1))
+	require.ErrorContains(t, d.WaitForUploads(context.Background()), "not enabled")
+
+	// Create some local tables in L6.
+	key := func(i int) string {
+		return fmt.Sprintf("k%02d", i)
+	}
+	const numTables = 10
+	for i := 0; i < numTables; i++ {
+		require.NoError(t, d.Set([]byte(key(i)), []byte(key(i)), nil))
+		require.NoError(t, d.Compact([]byte(key(i)), []byte(key(i)+"1"), false))
+	}
+	localTables := func() (n int) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for _, l := range d.mu.versions.currentVersion().Levels {
+			iter := l.Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				meta, err := d.objProvider.Lookup(base.FileTypeTable, f.FileBacking.DiskFileNum)
+				require.NoError(t, err)
+				if !meta.IsRemote() {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	// Create two virtual tables backed by a local table.
+	for _, k := range []string{"v1", "v2", "v3"} {
+		require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+	}
+	require.NoError(t, d.Compact([]byte("v1"), []byte("v4"), false))
+	require.NoError(t, d.Excise(context.Background(), KeyRange{Start: []byte("v2"), End: []byte("v3")}))
+	require.Equal(t, numTables+2, localTables())
+	require.NoError(t, d.Close())
+
+	// Enable shared storage and upload compactions.
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedAll
+	opts.Experimental.UploadLocalTables = true
+	opts.Experimental.UploadBytesPerSecond = 100 << 20
+	opts.DisableAutomaticCompactions = true
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	m := d.Metrics()
+	require.True(t, m.Upload.Enabled)
+	require.False(t, m.Upload.Done)
+	require.Equal(t, int64(numTables+2), m.Upload.PendingCount)
+	require.NotZero(t, m.Upload.PendingBytes)
+
+	d.mu.Lock()
+	d.opts.DisableAutomaticCompactions = false
+	d.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, d.WaitForUploads(ctx))
+	require.Zero(t, localTables())
+
+	m = d.Metrics()
+	require.True(t, m.Upload.Done)
+	require.Equal(t, int64(numTables+2), m.Upload.Count)
+	require.Equal(t, int64(numTables+2), m.Compact.UploadCount)
+	require.Zero(t, m.Upload.PendingCount)
+	require.Contains(t, m.String(), fmt.Sprintf("Uploads: %d", numTables+2))
+
+	// A version edit that adds a local table to a level created on shared
+	// storage makes the uploads pending again. The table isn't in the LSM, so
+	// no upload compaction must be scheduled for it.
+	d.mu.Lock()
+	d.opts.DisableAutomaticCompactions = true
+	d.mu.Unlock()
+	fileNum := d.mu.versions.getNextDiskFileNum()
+	w, _, err := d.objProvider.Create(ctx, base.FileTypeTable, fileNum, objstorage.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, w.Finish())
+	local := &tableMetadata{FileNum: base.PhysicalTableFileNum(fileNum), Size: 100}
+	local.InitPhysicalBacking()
+	d.mu.Lock()
+	d.mu.compact.upload.applyEdit(&versionEdit{
+		NewTables: []newTableEntry{{Level: 6, Meta: local}},
+	}, d.opts, d.objProvider)
+	d.mu.Unlock()
+	m = d.Metrics()
+	require.False(t, m.Upload.Done)
+	require.Equal(t, int64(1), m.Upload.PendingCount)
+	require.Equal(t, uint64(100), m.Upload.PendingBytes)
+
+	// A failed upload is reported in the metrics, and the table is retried
+	// after the tables whose uploads haven't failed.
+	d.mu.Lock()
+	d.uploadCompleted(&compaction{startLevel: &compactionLevel{
+		level: 6,
+		files: manifest.NewLevelSliceKeySorted(d.cmp, []*tableMetadata{local}),
+	}}, errors.New("injected upload error"))
+	other := &tableMetadata{FileNum: local.FileNum + 1}
+	d.mu.compact.upload.pending[other.FileNum] = pendingUpload{level: 6, meta: other}
+	pending := d.mu.compact.upload.sortedPending()
+	require.Len(t, pending, 2)
+	require.Equal(t, other, pending[0].meta)
+	require.Equal(t, local, pending[1].meta)
+	delete(d.mu.compact.upload.pending, other.FileNum)
+	d.mu.compact.upload.consecutiveFailures = 0
+	d.mu.Unlock()
+	m = d.Metrics()
+	require.Equal(t, int64(1), m.Upload.FailedCount)
+	require.Equal(t, int64(1), m.Upload.FailingCount)
+	shortCtx, shortCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	require.ErrorIs(t, d.WaitForUploads(shortCtx), context.DeadlineExceeded)
+	shortCancel()
+	d.mu.Lock()
+	d.mu.compact.upload.applyEdit(&versionEdit{
+		DeletedTables: map[deletedFileEntry]*tableMetadata{{Level: 6, FileNum: local.FileNum}: local},
+	}, d.opts, d.objProvider)
+	d.mu.Unlock()
+	require.NoError(t, d.WaitForUploads(ctx))
+	require.True(t, d.Metrics().Upload.Done)
+	require.NoError(t, d.objProvider.Remove(base.FileTypeTable, fileNum))
+	d.mu.Lock()
+	d.opts.DisableAutomaticCompactions = false
+	d.mu.Unlock()
+	get := func(k string) string {
+		v, closer, err := d.Get([]byte(k))
+		if err == ErrNotFound {
+			return ""
+		}
+		require.NoError(t, err)
+		defer closer.Close()
+		return string(v)
+	}
+	for i := 0; i < numTables; i++ {
+		require.Equal(t, key(i), get(key(i)))
+	}
+	require.Equal(t, "v1", get("v1"))
+	require.Equal(t, "", get("v2"))
+	require.Equal(t, "v3", get("v3"))
+	require.NoError(t, d.Close())
+
+	// There is nothing left to upload after a restart.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	d.mu.Lock()
+	d.opts.DisableAutomaticCompactions = false
+	d.mu.Unlock()
+	require.NoError(t, d.WaitForUploads(ctx))
+	require.Zero(t, d.Metrics().Upload.Count)
+	require.NoError(t, d.Close())
+}
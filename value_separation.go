@@ -0,0 +1,250 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/compact"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/blob"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/sstable/valblk"
+)
+
+// determineCompactionValueSeparation determines whether a compaction should
+// separate values into blob files. It returns a compact.ValueSeparation
+// implementation that should be used for the compaction.
+//
+// If value separation is disabled for the compaction's output, all values are
+// written to the output sstables, including those read from blob files. This
+// also applies to blob-rewrite compactions.
+func (d *DB) determineCompactionValueSeparation(
+	jobID JobID, c *compaction, tableFormat sstable.TableFormat,
+) compact.ValueSeparation {
+	if tableFormat < sstable.TableFormatPebblev6 ||
+		d.FormatMajorVersion() < FormatExperimentalValueSeparation ||
+		d.opts.Experimental.ValueSeparationPolicy == nil ||
+		d.opts.Level(c.outputLevel.level).DisableValueSeparation {
+		return compact.NeverSeparateValues{}
+	}
+	policy := d.opts.Experimental.ValueSeparationPolicy()
+	if !policy.Enabled {
+		return compact.NeverSeparateValues{}
+	}
+	writerOpts := d.opts.MakeWriterOptions(c.outputLevel.level, tableFormat)
+	return &writeNewBlobFiles{
+		comparer: d.opts.Comparer,
+		newBlobObject: func() (objstorage.Writable, objstorage.ObjectMetadata, error) {
+			return d.newCompactionOutputBlob(jobID, c)
+		},
+		writerOpts: blob.FileWriterOptions{
+			Compression:  writerOpts.Compression,
+			ChecksumType: writerOpts.Checksum,
+			FlushGovernor: block.MakeFlushGovernor(
+				writerOpts.BlockSize,
+				writerOpts.BlockSizeThreshold,
+				base.SizeClassAwareBlockSizeThreshold,
+				writerOpts.AllocatorSizeClasses),
+		},
+		minimumSize:             policy.MinimumSize,
+		maxBlobReferenceDepth:   policy.MaxBlobReferenceDepth,
+		shortAttributeExtractor: d.opts.Experimental.ShortAttributeExtractor,
+		rewriteBlobFiles:        c.rewriteBlobFiles,
+	}
+}
+
+// writeNewBlobFiles implements the compact.ValueSeparation interface, writing
+// values that satisfy the value separation policy to a new blob file per
+// output sstable. Values that are already stored in blob files are left in
+// place (and the existing references are propagated to the output sstable)
+// unless doing so would exceed the maximum blob reference depth or the blob
+// file is being rewritten, in which case the values are rewritten into the new
+// blob file.
+type writeNewBlobFiles struct {
+	comparer *base.Comparer
+	// newBlobObject constructs a new blob object for use in the compaction.
+	newBlobObject func() (objstorage.Writable, objstorage.ObjectMetadata, error)
+	// writerOpts holds the options for new blob files.
+	writerOpts blob.FileWriterOptions
+	// minimumSize is the minimum size of a value that will be separated into
+	// a blob file.
+	minimumSize int
+	// maxBlobReferenceDepth is the maximum number of blob files that a single
+	// output sstable may reference.
+	maxBlobReferenceDepth int
+	// shortAttributeExtractor, if non-nil, is used to extract the short
+	// attribute of values that are newly separated.
+	shortAttributeExtractor base.ShortAttributeExtractor
+	// rewriteBlobFiles holds blob files that are being rewritten by a
+	// blob-rewrite compaction. Values stored in these files are always copied
+	// into the new blob file. The slice is sorted.
+	rewriteBlobFiles []base.DiskFileNum
+
+	// writer is the blob file writer for the current output sstable. It's
+	// created lazily when the first value is separated.
+	writer  *blob.FileWriter
+	objMeta objstorage.ObjectMetadata
+	// refs holds the blob references of the current output sstable.
+	refs []manifest.BlobReference
+	// refsSize is the sum of the ValueSize fields of refs.
+	refsSize uint64
+	// buf is used to retrieve values that must be rewritten.
+	buf []byte
+}
+
+// Assert that *writeNewBlobFiles implements the compact.ValueSeparation
+// interface.
+var _ compact.ValueSeparation = (*writeNewBlobFiles)(nil)
+
+// EstimatedFileSize implements the compact.ValueSeparation interface.
+func (vs *writeNewBlobFiles) EstimatedFileSize() uint64 {
+	if vs.writer == nil {
+		return 0
+	}
+	return vs.writer.EstimatedSize()
+}
+
+// EstimatedReferenceSize implements the compact.ValueSeparation interface.
+func (vs *writeNewBlobFiles) EstimatedReferenceSize() uint64 {
+	return vs.refsSize
+}
+
+// Add implements the compact.ValueSeparation interface.
+func (vs *writeNewBlobFiles) Add(
+	tw sstable.RawWriter, kv *base.InternalKV, forceObsolete bool,
+) error {
+	switch kv.K.Kind() {
+	case base.InternalKeyKindSet, base.InternalKeyKindSetWithDelete:
+	default:
+		// Only SET and SETWITHDEL values may be separated. Any other kinds are
+		// never stored in blob files.
+		return compact.NeverSeparateValues{}.Add(tw, kv, forceObsolete)
+	}
+
+	if kv.V.IsBlobValueHandle() {
+		lv := kv.V.LazyValue()
+		fileNum := lv.Fetcher.BlobFileNum
+		_, rewrite := slices.BinarySearch(vs.rewriteBlobFiles, fileNum)
+		if i := vs.findRef(fileNum); !rewrite && (i >= 0 || vs.preservedRefCount() < vs.maxBlobReferenceDepth) {
+			// Propagate the existing reference to the output sstable.
+			vh := valblk.DecodeRemainingHandle(lv.ValueOrHandle)
+			h := blob.Handle{
+				FileNum:       fileNum,
+				BlockNum:      vh.BlockNum,
+				OffsetInBlock: vh.OffsetInBlock,
+				ValueLen:      lv.Fetcher.Attribute.ValueLen,
+			}
+			if err := tw.AddWithBlobHandle(kv.K, h, lv.Fetcher.Attribute.ShortAttribute, forceObsolete); err != nil {
+				return err
+			}
+			vs.addRef(i, fileNum, uint64(h.ValueLen))
+			return nil
+		}
+		// Either the blob file is being rewritten, or referencing another blob
+		// file would exceed the maximum blob reference depth. Fall through to
+		// retrieve the value and rewrite it into the new blob file.
+	}
+
+	v, callerOwned, err := kv.Value(vs.buf[:0])
+	if err != nil {
+		return err
+	}
+	if callerOwned {
+		vs.buf = v[:0]
+	}
+	if len(v) < vs.minimumSize {
+		return tw.AddWithForceObsolete(kv.K, v, forceObsolete)
+	}
+
+	var attr base.ShortAttribute
+	if vs.shortAttributeExtractor != nil {
+		keyPrefixLen := vs.comparer.Split(kv.K.UserKey)
+		attr, err = vs.shortAttributeExtractor(kv.K.UserKey, keyPrefixLen, v)
+		if err != nil {
+			return err
+		}
+	}
+	if vs.writer == nil {
+		writable, objMeta, err := vs.newBlobObject()
+		if err != nil {
+			return err
+		}
+		vs.objMeta = objMeta
+		vs.writer = blob.NewFileWriter(objMeta.DiskFileNum, writable, vs.writerOpts)
+	}
+	h := vs.writer.AddValue(v)
+	if err := tw.AddWithBlobHandle(kv.K, h, attr, forceObsolete); err != nil {
+		return err
+	}
+	vs.addRef(vs.findRef(h.FileNum), h.FileNum, uint64(h.ValueLen))
+	return nil
+}
+
+// findRef returns the index of the reference to the provided blob file within
+// vs.refs, or -1 if the current output sstable does not yet reference it.
+func (vs *writeNewBlobFiles) findRef(fileNum base.DiskFileNum) int {
+	for i := range vs.refs {
+		if vs.refs[i].FileNum == fileNum {
+			return i
+		}
+	}
+	return -1
+}
+
+// preservedRefCount returns the number of pre-existing blob files referenced by
+// the current output sstable.
+func (vs *writeNewBlobFiles) preservedRefCount() int {
+	n := len(vs.refs)
+	if vs.writer != nil && vs.findRef(vs.objMeta.DiskFileNum) >= 0 {
+		n--
+	}
+	return n
+}
+
+// addRef records a reference to a value of the provided size stored in the
+// provided blob file. i is the index of the existing reference to the file
+// within vs.refs, or -1 if there is none.
+func (vs *writeNewBlobFiles) addRef(i int, fileNum base.DiskFileNum, valueSize uint64) {
+	if i < 0 {
+		vs.refs = append(vs.refs, manifest.BlobReference{FileNum: fileNum})
+		i = len(vs.refs) - 1
+	}
+	vs.refs[i].ValueSize += valueSize
+	vs.refsSize += valueSize
+}
+
+// FinishOutput implements the compact.ValueSeparation interface.
+func (vs *writeNewBlobFiles) FinishOutput() (compact.ValueSeparationMetadata, error) {
+	meta := compact.ValueSeparationMetadata{
+		BlobReferences:     vs.refs,
+		BlobReferenceSize:  vs.refsSize,
+		BlobReferenceDepth: len(vs.refs),
+	}
+	vs.refs = nil
+	vs.refsSize = 0
+	if vs.writer == nil {
+		return meta, nil
+	}
+	stats, err := vs.writer.Close()
+	vs.writer = nil
+	meta.BlobFileObject = vs.objMeta
+	meta.BlobFileStats = stats
+	meta.BlobFileMetadata = &manifest.BlobFileMetadata{
+		FileNum:      vs.objMeta.DiskFileNum,
+		Size:         stats.FileLen,
+		ValueSize:    stats.UncompressedValueBytes,
+		CreationTime: uint64(time.Now().Unix()),
+	}
+	if err != nil {
+		return meta, errors.Wrapf(err, "closing blob file %s", vs.objMeta.DiskFileNum)
+	}
+	return meta, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueSeparation(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                 mem,
+		FormatMajorVersion: FormatExperimentalValueSeparation,
+		Logger:             testLogger{t},
+	}
+	opts.Experimental.EnableColumnarBlocks = func() bool { return true }
+	opts.Experimental.ValueSeparationPolicy = func() ValueSeparationPolicy {
+		return ValueSeparationPolicy{
+			Enabled:               true,
+			MinimumSize:           64,
+			MaxBlobReferenceDepth: 5,
+		}
+	}
+	opts.DisableAutomaticCompactions = true
+	d, err := Open("", opts)
+	require.NoError(t, err)
+
+	// Each key's value is large enough to be separated iff the key index is
+	// even.
+	makeValue := func(i, gen int) []byte {
+		n := 16
+		if i%2 == 0 {
+			n = 128
+		}
+		return bytes.Repeat([]byte(fmt.Sprintf("%d-%d.", i, gen)), n)[:n]
+	}
+	const numKeys = 100
+	checkValues := func(d *DB, gen int) {
+		t.Helper()
+		for i := 0; i < numKeys; i++ {
+			key := []byte(fmt.Sprintf("key%03d", i))
+			v, closer, err := d.Get(key)
+			require.NoError(t, err)
+			require.Equal(t, makeValue(i, gen), v)
+			require.NoError(t, closer.Close())
+		}
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		i := 0
+		for valid := iter.First(); valid; valid = iter.Next() {
+			require.Equal(t, fmt.Sprintf("key%03d", i), string(iter.Key()))
+			require.Equal(t, makeValue(i, gen), iter.Value())
+			i++
+		}
+		require.Equal(t, numKeys, i)
+		require.NoError(t, iter.Close())
+	}
+
+	for gen := 0; gen < 2; gen++ {
+		for i := 0; i < numKeys; i++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("key%03d", i)), makeValue(i, gen), nil))
+		}
+		require.NoError(t, d.Flush())
+		checkValues(d, gen)
+	}
+	m := d.Metrics()
+	require.Equal(t, uint64(2), m.BlobFiles.LiveCount)
+	require.Equal(t, uint64(2*(numKeys/2)*128), m.BlobFiles.ValueSize)
+	require.Equal(t, m.BlobFiles.ValueSize, m.BlobFiles.ReferencedValueSize)
+	require.NotZero(t, m.Levels[0].BlobBytesFlushed)
+
+	// Compacting drops the shadowed values, leaving the blob file written by
+	// the first flush unreferenced.
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+	checkValues(d, 1)
+	m = d.Metrics()
+	require.Equal(t, uint64(1), m.BlobFiles.LiveCount)
+	require.Equal(t, uint64((numKeys/2)*128), m.BlobFiles.ReferencedValueSize)
+	require.Zero(t, m.BlobFiles.ZombieCount)
+	require.NoError(t, d.Close())
+
+	// The blob file references must survive manifest replay.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	checkValues(d, 1)
+	m = d.Metrics()
+	require.Equal(t, uint64(1), m.BlobFiles.LiveCount)
+	require.Equal(t, uint64((numKeys/2)*128), m.BlobFiles.ReferencedValueSize)
+	require.NoError(t, d.Close())
+}
+
+func TestBlobFileRewriteCompaction(t *testing.T) {
+	opts := &Options{
+		FS:                 vfs.NewMem(),
+		FormatMajorVersion: FormatExperimentalValueSeparation,
+		Logger:             testLogger{t},
+	}
+	opts.Experimental.EnableColumnarBlocks = func() bool { return true }
+	opts.Experimental.ValueSeparationPolicy = func() ValueSeparationPolicy {
+		return ValueSeparationPolicy{
+			Enabled:               true,
+			MinimumSize:           64,
+			MaxBlobReferenceDepth: 5,
+			GarbageRatioThreshold: 0.3,
+		}
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	const numKeys = 100
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%03d", i)) }
+	value := func(i, gen int) []byte { return bytes.Repeat([]byte{byte('a' + gen)}, 100+i) }
+
+	for i := 0; i < numKeys; i++ {
+		require.NoError(t, d.Set(key(i), value(i, 0), nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(key(0), key(numKeys), false))
+
+	// Overwrite half of the keys. Once the overwritten values are compacted
+	// away, half of the values in the first blob file are garbage, which
+	// should trigger a blob-rewrite compaction.
+	for i := 0; i < numKeys; i += 2 {
+		require.NoError(t, d.Set(key(i), value(i, 1), nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(key(0), key(numKeys), false))
+
+	require.Eventually(t, func() bool {
+		m := d.Metrics()
+		return m.Compact.BlobFileRewriteCount > 0 && m.BlobFiles.MaxGarbageRatio < 0.3
+	}, 10*time.Second, 10*time.Millisecond, "blob file was not rewritten:\n%s", d.Metrics())
+
+	for i := 0; i < numKeys; i++ {
+		v, closer, err := d.Get(key(i))
+		require.NoError(t, err)
+		require.Equal(t, value(i, 1-i%2), v)
+		require.NoError(t, closer.Close())
+	}
+}
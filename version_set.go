@@ -5,8 +5,10 @@
 package pebble
 
 import (
+	"cmp"
 	"fmt"
 	"io"
+	"slices"
 	"sync"
 	"sync/atomic"
 
@@ -86,6 +88,27 @@ type versionSet struct {
 	// Zombie tables which have been removed from the current version but are
 	// still referenced by an inuse iterator.
 	zombieTables map[base.DiskFileNum]objectInfo
+	// Tables which have been evicted by a FIFOCompactionPicker but have not
+	// been deleted yet. Their deletions are reported with the "evicted"
+	// reason.
+	evictedTables map[base.DiskFileNum]struct{}
+	// uploads is the state of upload compactions (see upload.go), if any local
+	// sstables may need to be uploaded. The sstables that need to be uploaded
+	// are updated whenever a version edit is applied.
+	uploads *uploadState
+	// Zombie blob files which have been removed from the current version but
+	// are still referenced by an inuse iterator.
+	zombieBlobs map[base.DiskFileNum]objectInfo
+
+	// blobFiles contains the metadata of every blob file referenced by the
+	// latest version. It is used to snapshot the set of blob files when
+	// rotating the manifest and to protect live blob files from the obsolete
+	// file scan.
+	//
+	// blobFiles is modified under DB.mu and the log lock. If it is accessed
+	// under DB.mu and a version update is in progress, it reflects the state of
+	// the next version.
+	blobFiles map[base.DiskFileNum]*manifest.BlobFileMetadata
 
 	// virtualBackings contains information about the FileBackings which support
 	// virtual sstables in the latest version. It is mainly used to determine when
@@ -118,7 +141,11 @@ type versionSet struct {
 
 	// The current manifest file number.
 	manifestFileNum base.DiskFileNum
-	manifestMarker  *atomicfs.Marker
+	// manifestReadOffset is the offset following the last version edit read
+	// from the manifest by load. A follower (see OpenFollower) reads the
+	// subsequent edits from it.
+	manifestReadOffset int64
+	manifestMarker     *atomicfs.Marker
 
 	manifestFile          vfs.File
 	manifest              *record.Writer
@@ -156,6 +183,9 @@ func (vs *versionSet) init(
 	vs.versions.Init(mu)
 	vs.obsoleteFn = vs.addObsoleteLocked
 	vs.zombieTables = make(map[base.DiskFileNum]objectInfo)
+	vs.evictedTables = make(map[base.DiskFileNum]struct{})
+	vs.zombieBlobs = make(map[base.DiskFileNum]objectInfo)
+	vs.blobFiles = make(map[base.DiskFileNum]*manifest.BlobFileMetadata)
 	vs.virtualBackings = manifest.MakeVirtualBackings()
 	vs.nextFileNum.Store(1)
 	vs.manifestMarker = marker
@@ -180,11 +210,11 @@ func (vs *versionSet) create(
 	}
 	vs.append(newVersion)
 
-	vs.picker = newCompactionPickerByScore(newVersion, &vs.virtualBackings, vs.opts, nil)
+	vs.picker = newCompactionPicker(newVersion, &vs.virtualBackings, vs.opts, nil)
 	// Note that a "snapshot" version edit is written to the manifest when it is
 	// created.
 	vs.manifestFileNum = vs.getNextDiskFileNum()
-	err = vs.createManifest(vs.dirname, vs.manifestFileNum, vs.minUnflushedLogNum, vs.nextFileNum.Load(), nil /* virtualBackings */)
+	err = vs.createManifest(vs.dirname, vs.manifestFileNum, vs.minUnflushedLogNum, vs.nextFileNum.Load(), nil /* virtualBackings */, nil /* blobFiles */)
 	if err == nil {
 		if err = vs.manifest.Flush(); err != nil {
 			vs.opts.Logger.Fatalf("MANIFEST flush failed: %v", err)
@@ -269,6 +299,7 @@ func (vs *versionSet) load(
 		if err := bve.Accumulate(&ve); err != nil {
 			return err
 		}
+		vs.manifestReadOffset = rr.Offset()
 		if ve.MinUnflushedLogNum != 0 {
 			vs.minUnflushedLogNum = ve.MinUnflushedLogNum
 		}
@@ -324,6 +355,12 @@ func (vs *versionSet) load(
 		}
 	}
 
+	// Populate the set of live blob files. Blob files that were deleted during
+	// replay have already been removed from bve.BlobFiles.Added.
+	for fileNum, bf := range bve.BlobFiles.Added {
+		vs.blobFiles[fileNum] = bf
+	}
+
 	if invariants.Enabled {
 		// There should be no deleted tables or backings, since we're starting from
 		// an empty state.
@@ -363,8 +400,17 @@ func (vs *versionSet) load(
 		_, localSize := sizeIfLocal(backing, vs.provider)
 		vs.metrics.Table.Local.LiveSize = uint64(int64(vs.metrics.Table.Local.LiveSize) + localSize)
 	})
+	for _, bf := range vs.blobFiles {
+		vs.metrics.BlobFiles.LiveCount++
+		vs.metrics.BlobFiles.LiveSize += bf.Size
+		vs.metrics.BlobFiles.ValueSize += bf.ValueSize
+		vs.metrics.BlobFiles.ReferencedValueSize += bf.ActiveRefs.ValueSize()
+		if objstorage.IsLocalBlobFile(vs.provider, bf.FileNum) {
+			vs.metrics.BlobFiles.Local.LiveSize += bf.Size
+		}
+	}
 
-	vs.picker = newCompactionPickerByScore(newVersion, &vs.virtualBackings, vs.opts, nil)
+	vs.picker = newCompactionPicker(newVersion, &vs.virtualBackings, vs.opts, nil)
 	return nil
 }
 
@@ -412,10 +458,11 @@ func (vs *versionSet) logUnlock() {
 // to the current version, and installs the new version.
 //
 // logAndApply fills in the following fields of the VersionEdit: NextFileNum,
-// LastSeqNum, RemovedBackingTables. The removed backing tables are those
-// backings that are no longer used (in the new version) after applying the edit
-// (as per vs.virtualBackings). Other than these fields, the VersionEdit must be
-// complete.
+// LastSeqNum, RemovedBackingTables, DeletedBlobFiles. The removed backing
+// tables are those backings that are no longer used (in the new version) after
+// applying the edit (as per vs.virtualBackings). The deleted blob files are
+// those blob files that are no longer referenced by any table in the new
+// version. Other than these fields, the VersionEdit must be complete.
 //
 // New table backing references (FileBacking.Ref) are taken as part of applying
 // the version edit. The state of the virtual backings (vs.virtualBackings) is
@@ -532,14 +579,16 @@ func (vs *versionSet) logAndApply(
 	var newManifestFileNum base.DiskFileNum
 	var prevManifestFileSize uint64
 	var newManifestVirtualBackings []*fileBacking
+	var newManifestBlobFiles []*manifest.BlobFileMetadata
 	if requireRotation {
 		newManifestFileNum = vs.getNextDiskFileNum()
 		prevManifestFileSize = uint64(vs.manifest.Size())
 
-		// We want the virtual backings *before* applying the version edit, because
-		// the new manifest will contain the pre-apply version plus the last version
-		// edit.
+		// We want the virtual backings and blob files *before* applying the
+		// version edit, because the new manifest will contain the pre-apply
+		// version plus the last version edit.
 		newManifestVirtualBackings = vs.virtualBackings.Backings()
+		newManifestBlobFiles = vs.liveBlobFiles()
 	}
 
 	// Grab certain values before releasing vs.mu, in case createManifest() needs
@@ -550,6 +599,8 @@ func (vs *versionSet) logAndApply(
 	// Note: this call populates ve.RemovedBackingTables.
 	zombieBackings, removedVirtualBackings, localLiveSizeDelta :=
 		getZombiesAndUpdateVirtualBackings(ve, &vs.virtualBackings, vs.provider)
+	// Note: this call populates ve.DeletedBlobFiles.
+	zombieBlobs := vs.updateBlobFiles(ve)
 
 	if err := func() error {
 		vs.mu.Unlock()
@@ -571,7 +622,7 @@ func (vs *versionSet) logAndApply(
 		}
 
 		if newManifestFileNum != 0 {
-			if err := vs.createManifest(vs.dirname, newManifestFileNum, minUnflushedLogNum, nextFileNum, newManifestVirtualBackings); err != nil {
+			if err := vs.createManifest(vs.dirname, newManifestFileNum, minUnflushedLogNum, nextFileNum, newManifestVirtualBackings, newManifestBlobFiles); err != nil {
 				vs.opts.EventListener.ManifestCreated(ManifestCreateInfo{
 					JobID:   int(jobID),
 					Path:    base.MakeFilepath(vs.fs, vs.dirname, base.FileTypeManifest, newManifestFileNum),
@@ -634,9 +685,48 @@ func (vs *versionSet) logAndApply(
 
 	newVersion.L0Sublevels.InitCompactingFileInfo(inProgressL0Compactions(inProgress))
 
-	// Update the zombie tables set first, as installation of the new version
-	// will unref the previous version which could result in addObsoleteLocked
-	// being called.
+	vs.installLocked(newVersion, zombieBackings, removedVirtualBackings, zombieBlobs)
+
+	if ve.MinUnflushedLogNum != 0 {
+		vs.minUnflushedLogNum = ve.MinUnflushedLogNum
+	}
+	if newManifestFileNum != 0 {
+		if vs.manifestFileNum != 0 {
+			vs.obsoleteManifests = append(vs.obsoleteManifests, fileInfo{
+				FileNum:  vs.manifestFileNum,
+				FileSize: prevManifestFileSize,
+			})
+		}
+		vs.manifestFileNum = newManifestFileNum
+	}
+
+	for level, update := range metrics {
+		vs.metrics.Levels[level].Add(update)
+	}
+	vs.updateLevelMetricsLocked(newVersion)
+	vs.metrics.Table.Local.LiveSize = uint64(int64(vs.metrics.Table.Local.LiveSize) + localLiveSizeDelta)
+	if vs.uploads != nil {
+		vs.uploads.applyEdit(ve, vs.opts, vs.provider)
+	}
+
+	vs.picker = newCompactionPicker(newVersion, &vs.virtualBackings, vs.opts, inProgress)
+	if !vs.dynamicBaseLevel {
+		vs.picker.forceBaseLevel1()
+	}
+	return nil
+}
+
+// installLocked installs a new version produced by applying a version edit.
+// The zombie backings and blob files of the edit are recorded first, as
+// installing the new version unrefs the previous version, which could result
+// in addObsoleteLocked being called. The removed virtual backings are unrefed.
+//
+// DB.mu must be held when calling this method.
+func (vs *versionSet) installLocked(
+	newVersion *version,
+	zombieBackings, removedVirtualBackings []fileBackingInfo,
+	zombieBlobs []objectInfo,
+) {
 	for _, b := range zombieBackings {
 		vs.zombieTables[b.backing.DiskFileNum] = objectInfo{
 			fileInfo: fileInfo{
@@ -646,6 +736,9 @@ func (vs *versionSet) logAndApply(
 			isLocal: b.isLocal,
 		}
 	}
+	for _, info := range zombieBlobs {
+		vs.zombieBlobs[info.FileNum] = info
+	}
 
 	// Unref the removed backings and report those that already became obsolete.
 	// Note that the only case where we report obsolete tables here is when
@@ -661,23 +754,13 @@ func (vs *versionSet) logAndApply(
 
 	// Install the new version.
 	vs.append(newVersion)
+}
 
-	if ve.MinUnflushedLogNum != 0 {
-		vs.minUnflushedLogNum = ve.MinUnflushedLogNum
-	}
-	if newManifestFileNum != 0 {
-		if vs.manifestFileNum != 0 {
-			vs.obsoleteManifests = append(vs.obsoleteManifests, fileInfo{
-				FileNum:  vs.manifestFileNum,
-				FileSize: prevManifestFileSize,
-			})
-		}
-		vs.manifestFileNum = newManifestFileNum
-	}
-
-	for level, update := range metrics {
-		vs.metrics.Levels[level].Add(update)
-	}
+// updateLevelMetricsLocked updates the per-level file counts and sizes to
+// reflect the provided newly installed version.
+//
+// DB.mu must be held when calling this method.
+func (vs *versionSet) updateLevelMetricsLocked(newVersion *version) {
 	for i := range vs.metrics.Levels {
 		l := &vs.metrics.Levels[i]
 		l.NumFiles = int64(newVersion.Levels[i].Len())
@@ -709,13 +792,6 @@ func (vs *versionSet) logAndApply(
 		}
 	}
 	vs.metrics.Levels[0].Sublevels = int32(len(newVersion.L0SublevelFiles))
-	vs.metrics.Table.Local.LiveSize = uint64(int64(vs.metrics.Table.Local.LiveSize) + localLiveSizeDelta)
-
-	vs.picker = newCompactionPickerByScore(newVersion, &vs.virtualBackings, vs.opts, inProgress)
-	if !vs.dynamicBaseLevel {
-		vs.picker.forceBaseLevel1()
-	}
-	return nil
 }
 
 type fileBackingInfo struct {
@@ -821,6 +897,96 @@ func sizeIfLocal(
 	return false, 0
 }
 
+// updateBlobFiles updates vs.blobFiles and the live blob file metrics with the
+// changes in the versionEdit, and populates ve.DeletedBlobFiles with the blob
+// files that are no longer referenced by any table once the version edit is
+// applied. It returns the blob files that became zombies.
+//
+// A blob file is no longer referenced if the number of references removed by
+// the edit's deleted tables equals the file's active reference count, less
+// any references added back by the edit's new tables. Note that the active
+// reference counts themselves are only updated when the edit is applied.
+func (vs *versionSet) updateBlobFiles(ve *versionEdit) (zombieBlobs []objectInfo) {
+	m := &vs.metrics.BlobFiles
+	for _, bf := range ve.NewBlobFiles {
+		vs.blobFiles[bf.FileNum] = bf
+		m.LiveCount++
+		m.LiveSize += bf.Size
+		m.ValueSize += bf.ValueSize
+		if objstorage.IsLocalBlobFile(vs.provider, bf.FileNum) {
+			m.Local.LiveSize += bf.Size
+		}
+	}
+	if len(ve.DeletedTables) == 0 {
+		for _, nf := range ve.NewTables {
+			for _, ref := range nf.Meta.BlobReferences {
+				m.ReferencedValueSize += ref.ValueSize
+			}
+		}
+		return nil
+	}
+	// Note that for the common case where there are very few elements, the map
+	// will stay on the stack.
+	refDelta := make(map[base.DiskFileNum]int32)
+	for _, dt := range ve.DeletedTables {
+		for _, ref := range dt.BlobReferences {
+			refDelta[ref.FileNum]--
+			m.ReferencedValueSize -= ref.ValueSize
+		}
+	}
+	for _, nf := range ve.NewTables {
+		for _, ref := range nf.Meta.BlobReferences {
+			if _, ok := refDelta[ref.FileNum]; ok {
+				refDelta[ref.FileNum]++
+			}
+			m.ReferencedValueSize += ref.ValueSize
+		}
+	}
+	for fileNum, delta := range refDelta {
+		bf, ok := vs.blobFiles[fileNum]
+		if !ok {
+			vs.opts.Logger.Fatalf("MANIFEST deleted table references unknown blob file %s", fileNum)
+		}
+		if bf.ActiveRefs.Count()+delta > 0 {
+			continue
+		}
+		if !slices.Contains(ve.DeletedBlobFiles, fileNum) {
+			ve.DeletedBlobFiles = append(ve.DeletedBlobFiles, fileNum)
+		}
+		delete(vs.blobFiles, fileNum)
+		isLocal := objstorage.IsLocalBlobFile(vs.provider, fileNum)
+		m.LiveCount--
+		m.LiveSize -= bf.Size
+		m.ValueSize -= bf.ValueSize
+		if isLocal {
+			m.Local.LiveSize -= bf.Size
+		}
+		zombieBlobs = append(zombieBlobs, objectInfo{
+			fileInfo: fileInfo{
+				FileNum:  fileNum,
+				FileSize: bf.Size,
+			},
+			isLocal: isLocal,
+		})
+	}
+	// Sort the deleted blob files so that the version edit is deterministic.
+	slices.Sort(ve.DeletedBlobFiles)
+	return zombieBlobs
+}
+
+// liveBlobFiles returns the metadata of the blob files referenced by the latest
+// version, sorted by file number.
+func (vs *versionSet) liveBlobFiles() []*manifest.BlobFileMetadata {
+	files := make([]*manifest.BlobFileMetadata, 0, len(vs.blobFiles))
+	for _, bf := range vs.blobFiles {
+		files = append(files, bf)
+	}
+	slices.SortFunc(files, func(a, b *manifest.BlobFileMetadata) int {
+		return cmp.Compare(a.FileNum, b.FileNum)
+	})
+	return files
+}
+
 func (vs *versionSet) incrementCompactions(
 	kind compactionKind, extraLevels []*compactionLevel, pickerMetrics compactionPickerMetrics,
 ) {
@@ -856,10 +1022,18 @@ func (vs *versionSet) incrementCompactions(
 		vs.metrics.Compact.Count++
 		vs.metrics.Compact.RewriteCount++
 
+	case compactionKindBlobFileRewrite:
+		vs.metrics.Compact.Count++
+		vs.metrics.Compact.BlobFileRewriteCount++
+
 	case compactionKindCopy:
 		vs.metrics.Compact.Count++
 		vs.metrics.Compact.CopyCount++
 
+	case compactionKindUpload:
+		vs.metrics.Compact.Count++
+		vs.metrics.Compact.UploadCount++
+
 	default:
 		if invariants.Enabled {
 			panic("unhandled compaction kind")
@@ -880,6 +1054,7 @@ func (vs *versionSet) createManifest(
 	fileNum, minUnflushedLogNum base.DiskFileNum,
 	nextFileNum uint64,
 	virtualBackings []*fileBacking,
+	blobFiles []*manifest.BlobFileMetadata,
 ) (err error) {
 	var (
 		filename     = base.MakeFilepath(vs.fs, dirname, base.FileTypeManifest, fileNum)
@@ -918,6 +1093,7 @@ func (vs *versionSet) createManifest(
 	}
 
 	snapshot.CreatedBackingTables = virtualBackings
+	snapshot.NewBlobFiles = blobFiles
 
 	// When creating a version snapshot for an existing DB, this snapshot VersionEdit will be
 	// immediately followed by another VersionEdit (being written in logAndApply()). That
@@ -1025,12 +1201,32 @@ func (vs *versionSet) addLiveFileNums(m map[base.DiskFileNum]struct{}) {
 	vs.virtualBackings.ForEach(func(b *fileBacking) {
 		m[b.DiskFileNum] = struct{}{}
 	})
+	// Blob files referenced by tables in any live version must not be
+	// deleted. The blob files referenced by the latest version are also in
+	// vs.blobFiles.
+	for v := vs.versions.Front(); true; v = v.Next() {
+		for _, lm := range v.Levels {
+			iter := lm.Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				for _, ref := range f.BlobReferences {
+					m[ref.FileNum] = struct{}{}
+				}
+			}
+		}
+		if v == current {
+			break
+		}
+	}
+	for fileNum := range vs.blobFiles {
+		m[fileNum] = struct{}{}
+	}
 }
 
 // addObsoleteLocked will add the fileInfo associated with obsolete backing
-// sstables to the obsolete tables list.
+// sstables and blob files to the obsolete tables and blob files lists.
 //
-// The file backings in the obsolete list must not appear more than once.
+// The file backings and blob files in the obsolete list must not appear more
+// than once.
 //
 // DB.mu must be held when addObsoleteLocked is called.
 func (vs *versionSet) addObsoleteLocked(obsolete manifest.ObsoleteFiles) {
@@ -1070,7 +1266,17 @@ func (vs *versionSet) addObsoleteLocked(obsolete manifest.ObsoleteFiles) {
 	}
 
 	vs.obsoleteTables = append(vs.obsoleteTables, obsoleteFileInfo...)
-	vs.updateObsoleteTableMetricsLocked()
+
+	for _, bf := range obsolete.BlobFiles {
+		// As with tables, obsolete blob files remain in the zombie blob files
+		// map until they are deleted from disk.
+		info, ok := vs.zombieBlobs[bf.FileNum]
+		if !ok {
+			vs.opts.Logger.Fatalf("MANIFEST obsolete blob file %s not marked as zombie", bf.FileNum)
+		}
+		vs.obsoleteBlobs = append(vs.obsoleteBlobs, info)
+	}
+	vs.updateObsoleteObjectMetricsLocked()
 }
 
 // addObsolete will acquire DB.mu, so DB.mu must not be held when this is
@@ -1081,7 +1287,7 @@ func (vs *versionSet) addObsolete(obsolete manifest.ObsoleteFiles) {
 	vs.addObsoleteLocked(obsolete)
 }
 
-func (vs *versionSet) updateObsoleteTableMetricsLocked() {
+func (vs *versionSet) updateObsoleteObjectMetricsLocked() {
 	vs.metrics.Table.ObsoleteCount = int64(len(vs.obsoleteTables))
 	vs.metrics.Table.ObsoleteSize = 0
 	vs.metrics.Table.Local.ObsoleteSize = 0
@@ -1091,6 +1297,15 @@ func (vs *versionSet) updateObsoleteTableMetricsLocked() {
 			vs.metrics.Table.Local.ObsoleteSize += fi.FileSize
 		}
 	}
+	vs.metrics.BlobFiles.ObsoleteCount = int64(len(vs.obsoleteBlobs))
+	vs.metrics.BlobFiles.ObsoleteSize = 0
+	vs.metrics.BlobFiles.Local.ObsoleteSize = 0
+	for _, fi := range vs.obsoleteBlobs {
+		vs.metrics.BlobFiles.ObsoleteSize += fi.FileSize
+		if fi.isLocal {
+			vs.metrics.BlobFiles.Local.ObsoleteSize += fi.FileSize
+		}
+	}
 }
 
 func findCurrentManifest(
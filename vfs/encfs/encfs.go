@@ -0,0 +1,399 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package encfs provides a vfs.FS that encrypts the contents of Pebble's data
+// files at rest.
+//
+// Sstables, blob files, WALs and MANIFESTs are encrypted with AES in counter
+// mode. Every file begins with a fixed-size header recording the ID of the key
+// the file was encrypted with and a random initialization vector. Since the
+// keystream at any offset can be computed independently, reads at arbitrary
+// offsets (for example, of individual sstable blocks) and appends (for
+// example, of WAL and MANIFEST records) do not require decrypting or
+// re-encrypting any other part of the file. All other files, such as OPTIONS
+// files and markers, are stored in plaintext.
+//
+// Encryption does not authenticate the contents of files; Pebble relies on
+// the checksums of blocks and records to detect corruption.
+package encfs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// KeyProvider provides the keys used to encrypt and decrypt files. Keys are
+// AES keys, and must be 16, 24 or 32 bytes long.
+type KeyProvider interface {
+	// ActiveKey returns the ID and the secret of the key with which new files
+	// are encrypted.
+	ActiveKey() (id string, secret []byte, err error)
+	// Key returns the secret of the key with the provided ID.
+	Key(id string) (secret []byte, err error)
+}
+
+// StaticKeyProvider is a KeyProvider backed by a fixed set of keys.
+type StaticKeyProvider struct {
+	// ActiveKeyID is the ID of the key with which new files are encrypted.
+	ActiveKeyID string
+	// Keys maps key IDs to secrets.
+	Keys map[string][]byte
+}
+
+var _ KeyProvider = (*StaticKeyProvider)(nil)
+
+// ActiveKey implements KeyProvider.
+func (p *StaticKeyProvider) ActiveKey() (id string, secret []byte, err error) {
+	secret, err = p.Key(p.ActiveKeyID)
+	return p.ActiveKeyID, secret, err
+}
+
+// Key implements KeyProvider.
+func (p *StaticKeyProvider) Key(id string) ([]byte, error) {
+	secret, ok := p.Keys[id]
+	if !ok {
+		return nil, errors.Newf("encfs: unknown key %q", errors.Safe(id))
+	}
+	return secret, nil
+}
+
+const (
+	// headerMagic begins the header of every encrypted file.
+	headerMagic = "pebbleEF"
+	// headerVersion is the version of the header format.
+	headerVersion = 1
+	// headerSize is the size of the header of every encrypted file. The header
+	// consists of headerMagic, headerVersion, the length of the key ID, the key
+	// ID padded to maxKeyIDLen bytes and the initialization vector.
+	headerSize = 64
+	// maxKeyIDLen is the maximum length of a key ID.
+	maxKeyIDLen = headerSize - len(headerMagic) - 2 - aes.BlockSize
+)
+
+// IsEncrypted returns true if files with the provided base name are encrypted.
+func IsEncrypted(name string) bool {
+	return strings.HasPrefix(name, "MANIFEST-") ||
+		strings.HasSuffix(name, ".sst") ||
+		strings.HasSuffix(name, ".blob") ||
+		strings.HasSuffix(name, ".log")
+}
+
+// FS is a vfs.FS that encrypts the files it creates. See the package
+// documentation.
+type FS struct {
+	vfs.FS
+	keys KeyProvider
+}
+
+var _ vfs.FS = (*FS)(nil)
+
+// New returns an FS that encrypts files created on the provided FS with the
+// keys of the provided KeyProvider.
+func New(fs vfs.FS, keys KeyProvider) *FS {
+	return &FS{FS: fs, keys: keys}
+}
+
+// Find returns the FS within the chain of FSes reachable from fs through
+// vfs.FS.Unwrap, or nil if there is none.
+func Find(fs vfs.FS) *FS {
+	for ; fs != nil; fs = fs.Unwrap() {
+		if e, ok := fs.(*FS); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// Unwrap implements vfs.FS.
+func (fs *FS) Unwrap() vfs.FS {
+	return fs.FS
+}
+
+// ActiveKeyID returns the ID of the key with which new files are encrypted.
+func (fs *FS) ActiveKeyID() (string, error) {
+	id, _, err := fs.keys.ActiveKey()
+	return id, err
+}
+
+// CheckKey returns an error if the key with the provided ID is not available.
+func (fs *FS) CheckKey(id string) error {
+	_, err := fs.keys.Key(id)
+	return err
+}
+
+// FileKeyID returns the ID of the key with which the named file is encrypted.
+// It returns the empty string if the file is not encrypted.
+func (fs *FS) FileKeyID(name string) (string, error) {
+	if !IsEncrypted(fs.PathBase(name)) {
+		return "", nil
+	}
+	f, err := fs.FS.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var hdr [headerSize]byte
+	if n, err := f.ReadAt(hdr[:], 0); n < headerSize {
+		if err == nil || err == io.EOF {
+			return "", nil
+		}
+		return "", err
+	}
+	id, _, err := decodeHeader(hdr[:])
+	return id, err
+}
+
+// Create implements vfs.FS.
+func (fs *FS) Create(name string, category vfs.DiskWriteCategory) (vfs.File, error) {
+	f, err := fs.FS.Create(name, category)
+	if err != nil || !IsEncrypted(fs.PathBase(name)) {
+		return f, err
+	}
+	return fs.initFile(f)
+}
+
+// Open implements vfs.FS.
+func (fs *FS) Open(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	f, err := fs.FS.Open(name, opts...)
+	if err != nil || !IsEncrypted(fs.PathBase(name)) {
+		return f, err
+	}
+	return fs.openFile(f)
+}
+
+// OpenReadWrite implements vfs.FS.
+func (fs *FS) OpenReadWrite(
+	name string, category vfs.DiskWriteCategory, opts ...vfs.OpenOption,
+) (vfs.File, error) {
+	f, err := fs.FS.OpenReadWrite(name, category, opts...)
+	if err != nil || !IsEncrypted(fs.PathBase(name)) {
+		return f, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	if stat.Size() == 0 {
+		return fs.initFile(f)
+	}
+	return fs.openFile(f)
+}
+
+// ReuseForWrite implements vfs.FS. Encrypted files are not reused, since
+// reusing a file's initialization vector for new contents would compromise
+// the encryption. Instead, the old file is removed and a new file is created.
+func (fs *FS) ReuseForWrite(
+	oldname, newname string, category vfs.DiskWriteCategory,
+) (vfs.File, error) {
+	if !IsEncrypted(fs.PathBase(newname)) {
+		return fs.FS.ReuseForWrite(oldname, newname, category)
+	}
+	if err := fs.FS.Remove(oldname); err != nil {
+		return nil, err
+	}
+	return fs.Create(newname, category)
+}
+
+// Stat implements vfs.FS.
+func (fs *FS) Stat(name string) (vfs.FileInfo, error) {
+	info, err := fs.FS.Stat(name)
+	if err != nil || !IsEncrypted(fs.PathBase(name)) {
+		return info, err
+	}
+	return fileInfo{FileInfo: info}, nil
+}
+
+// initFile writes a new header to the provided empty file, returning a file
+// that encrypts its contents with the active key.
+func (fs *FS) initFile(f vfs.File) (vfs.File, error) {
+	id, secret, err := fs.keys.ActiveKey()
+	if err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	if len(id) > maxKeyIDLen {
+		err := errors.Newf("encfs: key ID %q is longer than %d bytes", errors.Safe(id), maxKeyIDLen)
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	ef := &file{File: f}
+	if _, err := rand.Read(ef.iv[:]); err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	if ef.block, err = aes.NewCipher(secret); err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	if _, err := f.WriteAt(encodeHeader(id, ef.iv), 0); err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	return ef, nil
+}
+
+// openFile reads the header of the provided existing file, returning a file
+// that decrypts its contents. A file too short to contain a header is
+// considered empty.
+func (fs *FS) openFile(f vfs.File) (vfs.File, error) {
+	ef := &file{File: f}
+	var hdr [headerSize]byte
+	if n, err := f.ReadAt(hdr[:], 0); n < headerSize {
+		if err == nil || err == io.EOF {
+			return ef, nil
+		}
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	id, iv, err := decodeHeader(hdr[:])
+	if err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	secret, err := fs.keys.Key(id)
+	if err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	if ef.block, err = aes.NewCipher(secret); err != nil {
+		return nil, errors.CombineErrors(err, f.Close())
+	}
+	ef.iv = iv
+	return ef, nil
+}
+
+func encodeHeader(keyID string, iv [aes.BlockSize]byte) []byte {
+	hdr := make([]byte, headerSize)
+	copy(hdr, headerMagic)
+	hdr[len(headerMagic)] = headerVersion
+	hdr[len(headerMagic)+1] = byte(len(keyID))
+	copy(hdr[len(headerMagic)+2:], keyID)
+	copy(hdr[headerSize-aes.BlockSize:], iv[:])
+	return hdr
+}
+
+func decodeHeader(hdr []byte) (keyID string, iv [aes.BlockSize]byte, err error) {
+	if !bytes.HasPrefix(hdr, []byte(headerMagic)) {
+		return "", iv, errors.New("encfs: file is not encrypted")
+	}
+	if v := hdr[len(headerMagic)]; v != headerVersion {
+		return "", iv, errors.Newf("encfs: unsupported header version %d", v)
+	}
+	n := int(hdr[len(headerMagic)+1])
+	if n > maxKeyIDLen {
+		return "", iv, errors.New("encfs: corrupt header")
+	}
+	keyID = string(hdr[len(headerMagic)+2 : len(headerMagic)+2+n])
+	copy(iv[:], hdr[headerSize-aes.BlockSize:])
+	return keyID, iv, nil
+}
+
+// file is an encrypted vfs.File. Offsets within the file are translated to
+// offsets within the underlying file, past the header.
+type file struct {
+	vfs.File
+	// block is the cipher of the file's key. It's nil if the file is too
+	// short to contain a header, in which case the file is considered empty.
+	block cipher.Block
+	iv    [aes.BlockSize]byte
+	// readOffset and writeOffset are the offsets of the next Read and Write.
+	readOffset  int64
+	writeOffset int64
+	// buf holds the ciphertext of a Write.
+	buf []byte
+}
+
+var _ vfs.File = (*file)(nil)
+
+// xorKeyStream XORs src with the keystream at the provided offset of the
+// file's plaintext, storing the result in dst.
+func (f *file) xorKeyStream(dst, src []byte, offset int64) {
+	var counter [aes.BlockSize]byte
+	hi := binary.BigEndian.Uint64(f.iv[:8])
+	lo := binary.BigEndian.Uint64(f.iv[8:])
+	n := uint64(offset / aes.BlockSize)
+	if lo+n < lo {
+		hi++
+	}
+	binary.BigEndian.PutUint64(counter[:8], hi)
+	binary.BigEndian.PutUint64(counter[8:], lo+n)
+	stream := cipher.NewCTR(f.block, counter[:])
+	if skip := offset % aes.BlockSize; skip > 0 {
+		var discard [aes.BlockSize]byte
+		stream.XORKeyStream(discard[:skip], discard[:skip])
+	}
+	stream.XORKeyStream(dst, src)
+}
+
+// Read implements io.Reader.
+func (f *file) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.readOffset)
+	f.readOffset += int64(n)
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.block == nil {
+		return 0, io.EOF
+	}
+	n, err := f.File.ReadAt(p, off+headerSize)
+	f.xorKeyStream(p[:n], p[:n], off)
+	return n, err
+}
+
+// Write implements io.Writer.
+func (f *file) Write(p []byte) (int, error) {
+	f.buf = append(f.buf[:0], p...)
+	n, err := f.writeAt(f.buf, p, f.writeOffset)
+	f.writeOffset += int64(n)
+	return n, err
+}
+
+// WriteAt implements io.WriterAt.
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	return f.writeAt(make([]byte, len(p)), p, off)
+}
+
+func (f *file) writeAt(buf, p []byte, off int64) (int, error) {
+	if f.block == nil {
+		return 0, errors.New("encfs: cannot write to a file without a header")
+	}
+	f.xorKeyStream(buf, p, off)
+	return f.File.WriteAt(buf, off+headerSize)
+}
+
+// Preallocate implements vfs.File.
+func (f *file) Preallocate(offset, length int64) error {
+	return f.File.Preallocate(offset+headerSize, length)
+}
+
+// Stat implements vfs.File.
+func (f *file) Stat() (vfs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{FileInfo: info}, nil
+}
+
+// SyncTo implements vfs.File.
+func (f *file) SyncTo(length int64) (fullSync bool, err error) {
+	return f.File.SyncTo(length + headerSize)
+}
+
+// Prefetch implements vfs.File.
+func (f *file) Prefetch(offset, length int64) error {
+	return f.File.Prefetch(offset+headerSize, length)
+}
+
+// fileInfo reports the size of an encrypted file's plaintext.
+type fileInfo struct {
+	vfs.FileInfo
+}
+
+// Size implements os.FileInfo.
+func (fi fileInfo) Size() int64 {
+	return max(fi.FileInfo.Size()-headerSize, 0)
+}
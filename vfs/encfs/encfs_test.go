@@ -0,0 +1,109 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package encfs
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	mem := vfs.NewMem()
+	keys := &StaticKeyProvider{
+		ActiveKeyID: "k1",
+		Keys: map[string][]byte{
+			"k1": bytes.Repeat([]byte{1}, 16),
+			"k2": bytes.Repeat([]byte{2}, 32),
+		},
+	}
+	fs := New(mem, keys)
+
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	write := func(name string) {
+		f, err := fs.Create(name, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		// Write in uneven chunks to exercise offsets that are not aligned to
+		// the cipher's block size.
+		for b := data; len(b) > 0; {
+			n := min(len(b), 1+rand.IntN(100))
+			_, err := f.Write(b[:n])
+			require.NoError(t, err)
+			b = b[n:]
+		}
+		require.NoError(t, f.Close())
+	}
+	read := func(name string) {
+		f, err := fs.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+		stat, err := f.Stat()
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), stat.Size())
+		got, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+		for i := 0; i < 100; i++ {
+			off := rand.IntN(len(data))
+			buf := make([]byte, rand.IntN(len(data)-off+1))
+			_, err := f.ReadAt(buf, int64(off))
+			require.NoError(t, err)
+			require.Equal(t, data[off:off+len(buf)], buf)
+		}
+	}
+
+	write("000001.sst")
+	write("OPTIONS-000002")
+	read("000001.sst")
+	read("OPTIONS-000002")
+	stat, err := fs.Stat("000001.sst")
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), stat.Size())
+
+	// Only data files are encrypted.
+	raw := func(name string) []byte {
+		f, err := mem.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return b
+	}
+	require.Equal(t, data, raw("OPTIONS-000002"))
+	require.Len(t, raw("000001.sst"), headerSize+len(data))
+	require.False(t, bytes.Contains(raw("000001.sst"), data[:32]))
+
+	// Files remain readable after the active key changes, and new files are
+	// encrypted with the new active key.
+	keys.ActiveKeyID = "k2"
+	write("000003.log")
+	read("000001.sst")
+	read("000003.log")
+	for name, keyID := range map[string]string{"000001.sst": "k1", "000003.log": "k2", "OPTIONS-000002": ""} {
+		id, err := fs.FileKeyID(name)
+		require.NoError(t, err)
+		require.Equal(t, keyID, id)
+	}
+
+	// Reused files are re-encrypted from scratch.
+	f, err := fs.ReuseForWrite("000003.log", "000004.log", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	stat, err = fs.Stat("000004.log")
+	require.NoError(t, err)
+	require.Zero(t, stat.Size())
+
+	// Files cannot be read without their key.
+	delete(keys.Keys, "k1")
+	_, err = fs.Open("000001.sst")
+	require.Error(t, err)
+}
@@ -234,6 +234,12 @@ type virtualWALReader struct {
 	// ever observe a batch encoding a sequence number <= lastSeqNum, we must
 	// have already returned the batch and should skip it.
 	lastSeqNum base.SeqNum
+	// logDataRecords holds the records returned to the user that contain
+	// batches with a zero count at the sequence number logDataSeqNum. These
+	// batches (only containing LogData) repeat the sequence number of the next
+	// batch, so repeats of them are detected by their contents instead.
+	logDataSeqNum  base.SeqNum
+	logDataRecords [][]byte
 	// recordBuf is a buffer used to hold the latest record read from a physical
 	// file, and then returned to the user. A pointer to this buffer is returned
 	// directly to the caller of NextRecord.
@@ -321,13 +327,31 @@ func (r *virtualWALReader) NextRecord() (io.Reader, Offset, error) {
 		// There's a subtlety necessitated by LogData operations. A LogData
 		// applied to a batch results in data appended to the WAL in a batch
 		// format, but the data is never applied to the memtable or LSM. A batch
-		// only containing LogData will repeat a sequence number. We skip these
-		// batches because they're not relevant for recovery and we do not want
+		// only containing LogData will repeat a sequence number. We do not want
 		// to mistakenly deduplicate the batch containing KVs at the same
-		// sequence number. We can differentiate LogData-only batches through
-		// their batch headers: they'll encode a count of zero.
+		// sequence number, so these batches don't advance lastSeqNum. We can
+		// differentiate LogData-only batches through their batch headers:
+		// they'll encode a count of zero.
+		//
+		// LogData-only batches are still returned, because the LogData of the
+		// keyspaces of a DB is relevant for recovery. A LogData-only batch
+		// precedes the batch with KVs at the same sequence number, so it has
+		// already been returned if that batch has.
 		if h.Count == 0 {
-			continue
+			if h.SeqNum <= r.lastSeqNum {
+				continue
+			}
+			if h.SeqNum != r.logDataSeqNum {
+				r.logDataSeqNum = h.SeqNum
+				r.logDataRecords = r.logDataRecords[:0]
+			}
+			if slices.ContainsFunc(r.logDataRecords, func(rec []byte) bool {
+				return bytes.Equal(rec, r.recordBuf.Bytes())
+			}) {
+				continue
+			}
+			r.logDataRecords = append(r.logDataRecords, slices.Clone(r.recordBuf.Bytes()))
+			return &r.recordBuf, r.off, nil
 		}
 
 		// If we've already observed a sequence number >= this batch's sequence
@@ -0,0 +1,270 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/batchrepr"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/wal"
+)
+
+// WALArchive stores the WAL files archived by a DB before they are deleted
+// (see Options.WALArchive). An archived file is identified by its file name,
+// which is unique among the WAL files of a DB, including the segments of a WAL
+// written to the WAL failover secondary.
+type WALArchive interface {
+	// Archive copies the WAL file at the provided path to the archive,
+	// replacing any archived file with the same name. The file must be durably
+	// archived when Archive returns, as it is deleted afterwards.
+	Archive(fs vfs.FS, path string) error
+	// List returns the names of the archived WAL files.
+	List(ctx context.Context) ([]string, error)
+	// Restore copies the archived WAL file with the provided name to path.
+	Restore(ctx context.Context, name string, fs vfs.FS, path string) error
+}
+
+// NewWALDirArchive returns a WALArchive that copies the WAL files into a
+// directory, which is created if it does not exist.
+func NewWALDirArchive(fs vfs.FS, dirname string) WALArchive {
+	return &walDirArchive{fs: fs, dirname: dirname}
+}
+
+// NewWALRemoteArchive returns a WALArchive that uploads the WAL files to
+// remote storage. The names of the objects are the names of the files
+// prefixed by prefix and a slash, or the names of the files if prefix is
+// empty.
+func NewWALRemoteArchive(storage remote.Storage, prefix string) WALArchive {
+	return &walRemoteArchive{storage: storage, prefix: prefix}
+}
+
+type walDirArchive struct {
+	fs      vfs.FS
+	dirname string
+}
+
+// Archive implements WALArchive.
+func (a *walDirArchive) Archive(fs vfs.FS, path string) error {
+	if err := a.fs.MkdirAll(a.dirname, 0755); err != nil {
+		return err
+	}
+	if err := vfs.CopyAcrossFS(fs, path, a.fs, a.fs.PathJoin(a.dirname, fs.PathBase(path))); err != nil {
+		return err
+	}
+	dir, err := a.fs.OpenDir(a.dirname)
+	if err != nil {
+		return err
+	}
+	return errors.CombineErrors(dir.Sync(), dir.Close())
+}
+
+// List implements WALArchive.
+func (a *walDirArchive) List(context.Context) ([]string, error) {
+	names, err := a.fs.List(a.dirname)
+	if err != nil {
+		if oserror.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return filterWALFilenames(names), nil
+}
+
+// Restore implements WALArchive.
+func (a *walDirArchive) Restore(_ context.Context, name string, fs vfs.FS, path string) error {
+	return vfs.CopyAcrossFS(a.fs, a.fs.PathJoin(a.dirname, name), fs, path)
+}
+
+type walRemoteArchive struct {
+	storage remote.Storage
+	prefix  string
+}
+
+// Archive implements WALArchive.
+func (a *walRemoteArchive) Archive(fs vfs.FS, path string) error {
+	return uploadBackupFile(a.storage, backupObjectName(a.prefix, fs.PathBase(path)), fs, path)
+}
+
+// List implements WALArchive.
+func (a *walRemoteArchive) List(context.Context) ([]string, error) {
+	prefix := a.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+	names, err := a.storage.List(prefix, "")
+	if err != nil {
+		return nil, err
+	}
+	// Some implementations return the names of the objects including the
+	// prefix.
+	for i := range names {
+		names[i] = strings.TrimPrefix(names[i], prefix)
+	}
+	return filterWALFilenames(names), nil
+}
+
+// Restore implements WALArchive.
+func (a *walRemoteArchive) Restore(ctx context.Context, name string, fs vfs.FS, path string) error {
+	objName := backupObjectName(a.prefix, name)
+	size, err := a.storage.Size(objName)
+	if err != nil {
+		return err
+	}
+	return downloadBackupFile(ctx, a.storage, objName, size, fs, path)
+}
+
+// filterWALFilenames returns the WAL file names among names, sorted.
+func filterWALFilenames(names []string) []string {
+	var walNames []string
+	for _, name := range names {
+		if _, _, ok := wal.ParseLogFilename(name); ok {
+			walNames = append(walNames, name)
+		}
+	}
+	slices.Sort(walNames)
+	return walNames
+}
+
+// RecoverFromWALArchive recovers the DB checkpointed in dirname (see
+// DB.Checkpoint) to the state following the write with the provided sequence
+// number. The WALs archived after the checkpoint was created are restored
+// from the archive into dirname and replayed up to, and including, the last
+// batch whose sequence numbers are all less than or equal to seqNum. The
+// recovered state is flushed, and the DB may then be opened with Open.
+//
+// Only sequence numbers are supported as recovery targets: the WALs do not
+// record when their batches were committed, so the DB cannot be recovered to
+// a point in time. A caller wishing to recover to a point in time must map it
+// to a sequence number itself, for example by recording the sequence numbers
+// of the batches it commits (see Batch.SeqNum).
+//
+// The checkpoint must have been created before the write with sequence number
+// seqNum. If the checkpoint and the archived WALs do not contain every write up
+// to seqNum, the DB is recovered to the last write they contain. If the
+// sequence numbers of the batches in the WALs are not contiguous up to seqNum,
+// for example because an archived WAL is missing, an error is returned and the
+// DB is not recovered, as replaying the batches that follow the gap would
+// produce a state that never existed. Sstables ingested without being written
+// to the WAL (see DB.Ingest) consume sequence numbers without writing a batch,
+// so the DB cannot be recovered past such an ingestion either.
+//
+// The DB is opened with opts, with the WALDir, WALFailover, WALRecoveryDirs
+// and WALArchive options cleared, as the checkpoint's WALs are all stored in
+// dirname.
+func RecoverFromWALArchive(
+	ctx context.Context, dirname string, archive WALArchive, seqNum base.SeqNum, opts *Options,
+) error {
+	if seqNum < base.SeqNumStart {
+		return errors.Errorf("pebble: invalid recovery sequence number %s", seqNum)
+	}
+	opts = opts.Clone()
+	opts.EnsureDefaults()
+	opts.ReadOnly = false
+	opts.WALDir = ""
+	opts.WALFailover = nil
+	opts.WALRecoveryDirs = nil
+	opts.WALArchive = nil
+	opts.private.replayTargetSeqNum = seqNum
+	fs := opts.FS
+
+	// The checkpoint contains the WALs that were not flushed when it was
+	// created, or at least the WAL that was being written. Older archived WALs
+	// are not needed. Newer WALs may have been copied into the checkpoint while
+	// they were being written, and are replaced by the archived copies.
+	names, err := fs.List(dirname)
+	if err != nil {
+		return err
+	}
+	var minNum wal.NumWAL
+	for _, name := range names {
+		if num, _, ok := wal.ParseLogFilename(name); ok && (minNum == 0 || num < minNum) {
+			minNum = num
+		}
+	}
+	archived, err := archive.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range archived {
+		if num, _, _ := wal.ParseLogFilename(name); num < minNum {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := archive.Restore(ctx, name, fs, fs.PathJoin(dirname, name)); err != nil {
+			return err
+		}
+	}
+	dir, err := fs.OpenDir(dirname)
+	if err != nil {
+		return err
+	}
+	if err := errors.CombineErrors(dir.Sync(), dir.Close()); err != nil {
+		return err
+	}
+	if gap, ok, err := findWALSeqNumGap(fs, dirname); err != nil {
+		return err
+	} else if ok && gap.start <= seqNum {
+		return errors.Errorf("pebble: the WALs are missing the batches with sequence numbers [%s, %s); "+
+			"the DB can only be recovered up to sequence number %s", gap.start, gap.end, gap.start-1)
+	}
+
+	// Open replays the WALs up to seqNum and flushes the replayed memtables,
+	// after which the restored WALs are obsolete and deleted.
+	d, err := Open(dirname, opts)
+	if err != nil {
+		return err
+	}
+	return d.Close()
+}
+
+// seqNumGap is a range [start, end) of sequence numbers.
+type seqNumGap struct {
+	start, end base.SeqNum
+}
+
+// findWALSeqNumGap reads the headers of the batches in the WALs in dirname, in
+// WAL order, and returns the first gap between the sequence numbers of
+// consecutive batches. Reading a WAL stops at the first error; errors are
+// reported when the WALs are replayed.
+func findWALSeqNumGap(fs vfs.FS, dirname string) (_ seqNumGap, ok bool, _ error) {
+	logs, err := wal.Scan(wal.Dir{FS: fs, Dirname: dirname})
+	if err != nil {
+		return seqNumGap{}, false, err
+	}
+	var next base.SeqNum
+	var hdr [batchrepr.HeaderLen]byte
+	for _, ll := range logs {
+		rr := ll.OpenForRead()
+		for {
+			r, _, err := rr.NextRecord()
+			if err != nil {
+				break
+			}
+			if _, err := io.ReadFull(r, hdr[:]); err != nil {
+				break
+			}
+			h, _ := batchrepr.ReadHeader(hdr[:])
+			if next != 0 && h.SeqNum > next {
+				_ = rr.Close()
+				return seqNumGap{start: next, end: h.SeqNum}, true, nil
+			}
+			next = max(next, h.SeqNum+base.SeqNum(h.Count))
+		}
+		if err := rr.Close(); err != nil {
+			return seqNumGap{}, false, err
+		}
+	}
+	return seqNumGap{}, false, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALArchive(t *testing.T) {
+	for _, name := range []string{"dir", "remote"} {
+		t.Run(name, func(t *testing.T) {
+			fs := vfs.NewMem()
+			archive := NewWALDirArchive(fs, "archive")
+			if name == "remote" {
+				archive = NewWALRemoteArchive(remote.NewInMem(), "wals")
+			}
+			d, err := Open("db", &Options{FS: fs, WALArchive: archive})
+			require.NoError(t, err)
+
+			contents := func(d *DB) string {
+				iter, err := d.NewIter(nil)
+				require.NoError(t, err)
+				var kvs []string
+				for valid := iter.First(); valid; valid = iter.Next() {
+					kvs = append(kvs, fmt.Sprintf("%s=%s", iter.Key(), iter.Value()))
+				}
+				require.NoError(t, iter.Close())
+				return strings.Join(kvs, " ")
+			}
+
+			// Each batch overwrites a key and deletes another. The DB is
+			// checkpointed after the first batches, and the WALs are rotated
+			// by the flushes so that they are archived.
+			type state struct {
+				seqNum   base.SeqNum
+				contents string
+			}
+			var states []state
+			for i := 0; i < 12; i++ {
+				b := d.NewBatch()
+				require.NoError(t, b.Set([]byte(fmt.Sprintf("k%d", i%5)), []byte(fmt.Sprint(i)), nil))
+				require.NoError(t, b.Delete([]byte(fmt.Sprintf("k%d", (i+2)%5)), nil))
+				require.NoError(t, b.Commit(Sync))
+				states = append(states, state{seqNum: b.SeqNum() + base.SeqNum(b.Count()) - 1, contents: contents(d)})
+				require.NoError(t, b.Close())
+				switch {
+				case i == 2:
+					require.NoError(t, d.Checkpoint("checkpoint"))
+				case i%3 == 1:
+					require.NoError(t, d.Flush())
+				}
+			}
+			require.NoError(t, d.Flush())
+			require.NoError(t, d.Close())
+			archived, err := archive.List(context.Background())
+			require.NoError(t, err)
+			require.NotEmpty(t, archived)
+
+			// The checkpoint is recovered to each state following it, including
+			// states in the WAL that was copied into the checkpoint while it was
+			// being written.
+			for i, s := range states[3:] {
+				dir := fmt.Sprintf("recovered%d", i)
+				require.NoError(t, fs.MkdirAll(dir, 0755))
+				names, err := fs.List("checkpoint")
+				require.NoError(t, err)
+				for _, name := range names {
+					require.NoError(t, vfs.Copy(fs, fs.PathJoin("checkpoint", name), fs.PathJoin(dir, name)))
+				}
+				require.NoError(t, RecoverFromWALArchive(context.Background(), dir, archive, s.seqNum, &Options{FS: fs}))
+				d, err := Open(dir, &Options{FS: fs})
+				require.NoError(t, err)
+				require.Equal(t, s.contents, contents(d), "seqnum %s", s.seqNum)
+				require.NoError(t, d.Close())
+			}
+		})
+	}
+}
+
+// failingWALArchive is a WALArchive whose first Archive calls fail.
+type failingWALArchive struct {
+	WALArchive
+	failures int
+}
+
+func (a *failingWALArchive) Archive(fs vfs.FS, path string) error {
+	if a.failures > 0 {
+		a.failures--
+		return errors.New("injected archive error")
+	}
+	return a.WALArchive.Archive(fs, path)
+}
+
+func TestWALArchiveFailures(t *testing.T) {
+	fs := vfs.NewMem()
+	archive := &failingWALArchive{WALArchive: NewWALDirArchive(fs, "archive"), failures: 2}
+	opts := &Options{FS: fs, WALArchive: archive, Logger: testLogger{t}}
+	opts.private.testingAlwaysWaitForCleanup = true
+	d, err := Open("db", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.Checkpoint("checkpoint"))
+	var seqNums []base.SeqNum
+	for i := 0; i < 6; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("k%d", i)), nil, Sync))
+		seqNums = append(seqNums, d.mu.versions.visibleSeqNum.Load()-1)
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Close())
+
+	// The WALs that could not be archived were archived when later WALs
+	// became obsolete, so every write can be recovered.
+	require.Zero(t, archive.failures)
+	archived, err := archive.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, archived, 6)
+	recoverTo := func(seqNum base.SeqNum) error {
+		require.NoError(t, fs.RemoveAll("recovered"))
+		require.NoError(t, fs.MkdirAll("recovered", 0755))
+		names, err := fs.List("checkpoint")
+		require.NoError(t, err)
+		for _, name := range names {
+			require.NoError(t, vfs.Copy(fs, fs.PathJoin("checkpoint", name), fs.PathJoin("recovered", name)))
+		}
+		return RecoverFromWALArchive(context.Background(), "recovered", archive, seqNum, &Options{FS: fs})
+	}
+	require.NoError(t, recoverTo(seqNums[5]))
+
+	// A missing WAL prevents recovering past it.
+	require.NoError(t, fs.Remove(fs.PathJoin("archive", archived[3])))
+	require.ErrorContains(t, recoverTo(seqNums[5]), "missing the batches")
+	require.ErrorContains(t, recoverTo(seqNums[3]), "missing the batches")
+	require.NoError(t, recoverTo(seqNums[2]))
+	d, err = Open("recovered", &Options{FS: fs})
+	require.NoError(t, err)
+	_, closer, err := d.Get([]byte("k2"))
+	require.NoError(t, err)
+	require.NoError(t, closer.Close())
+	_, _, err = d.Get([]byte("k3"))
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, d.Close())
+}